@@ -3,8 +3,10 @@ package auth
 
 import (
 	"context"
+	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
@@ -12,6 +14,7 @@ import (
 	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
@@ -24,16 +27,24 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-// keystore represents the set of behaviours required by auth package to lookup private and public keys.
+// Keystore represents the set of behaviours required by auth package to sign tokens and lookup public keys,
+// signing is delegated to the keystore so backends like vault or kms never hand out private keys.
 type Keystore interface {
-	PrivateKey(kid string) (string, error)
+	Sign(ctx context.Context, kid string, digest []byte) ([]byte, error)
 	PublicKey(kid string) (string, error)
 }
 
+// kidResolver is implemented by keystores whose keys rotate, the kid a token is signed with names the version of the
+// key so the token is checked against that version after a rotation.
+type kidResolver interface {
+	SigningKid(ctx context.Context, kid string) (string, error)
+}
+
 // Auth represents the set of APIs used for authentication and authorization.
 type Auth struct {
 	keystore    Keystore
 	userService *user.Service
+	claimsOnly  bool
 }
 
 // New creates an auth instance with provided keystore.
@@ -44,42 +55,41 @@ func New(ks Keystore, userService *user.Service) *Auth {
 	}
 }
 
-// GenerateToken generates a jwt token with claims
-func (a *Auth) GenerateToken(kid string, claims Claims) (string, error) {
-	method := jwt.GetSigningMethod(jwt.SigningMethodRS256.Name)
+// EnableClaimsOnly makes ValidateToken trust the claims of a valid token instead of looking the user up, the user
+// only carries their id and roles. A disabled user, a role change or revoked tokens take effect once their token
+// expires.
+func (a *Auth) EnableClaimsOnly() {
+	a.claimsOnly = true
+}
+
+// GenerateToken generates a jwt token with claims, the RS256 signature is produced by the keystore.
+func (a *Auth) GenerateToken(ctx context.Context, kid string, claims Claims) (string, error) {
+	if r, ok := a.keystore.(kidResolver); ok {
+		resolved, err := r.SigningKid(ctx, kid)
+		if err != nil {
+			return "", fmt.Errorf("signing kid: %w", err)
+		}
+		kid = resolved
+	}
 
-	tkn := jwt.NewWithClaims(method, claims)
+	tkn := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
 
 	//save the kid
 	tkn.Header["kid"] = kid
 
-	privatePEM, err := a.keystore.PrivateKey(kid)
+	signingString, err := tkn.SigningString()
 	if err != nil {
-		return "", fmt.Errorf("fetching private key: %w", err)
+		return "", fmt.Errorf("signing string: %w", err)
 	}
 
-	// decode it
-	pemBlock, _ := pem.Decode([]byte(privatePEM))
-	if pemBlock == nil || pemBlock.Type != "PRIVATE KEY" {
-		return "", errors.New("failed to decode private key into pem block")
-	}
+	digest := sha256.Sum256([]byte(signingString))
 
-	//since we want to support multiple key types in future we use "PKC8"
-	privateKey, err := x509.ParsePKCS8PrivateKey(pemBlock.Bytes)
+	signature, err := a.keystore.Sign(ctx, kid, digest[:])
 	if err != nil {
-		return "", fmt.Errorf("parse private key: %w", err)
-	}
-
-	rsaPrivateKey, ok := privateKey.(*rsa.PrivateKey)
-	if !ok {
-		return "", errors.New("invalid key algorithm")
+		return "", fmt.Errorf("sign: %w", err)
 	}
 
-	tokenString, err := tkn.SignedString(rsaPrivateKey)
-	if err != nil {
-		return "", fmt.Errorf("signed string: %w", err)
-	}
-	return tokenString, nil
+	return signingString + "." + jwt.EncodeSegment(signature), nil
 }
 
 // ValidateToken is going to validate a jwt bearer token and return the corresponding user on success and possible errors.
@@ -139,6 +149,19 @@ func (a *Auth) ValidateToken(ctx context.Context, bearerToken string) (user.User
 		return user.User{}, fmt.Errorf("parse user id: %w", err)
 	}
 
+	if a.claimsOnly {
+		roles, err := user.ParseRoles(claims.Roles)
+		if err != nil {
+			return user.User{}, fmt.Errorf("parse roles: %w", err)
+		}
+
+		return user.User{
+			Id:      id,
+			Roles:   roles,
+			Enabled: true,
+		}, nil
+	}
+
 	usr, err := a.userService.GetUserById(ctx, id)
 	if err != nil {
 		return user.User{}, fmt.Errorf("getUserById: %w", err)
@@ -148,6 +171,13 @@ func (a *Auth) ValidateToken(ctx context.Context, bearerToken string) (user.User
 		return user.User{}, errors.New("user is disabled")
 	}
 
+	//iat only keeps seconds, tokens issued within the second of the revocation are kept
+	if !usr.TokensValidAfter.IsZero() {
+		if claims.IssuedAt == nil || claims.IssuedAt.Before(usr.TokensValidAfter.Truncate(time.Second)) {
+			return user.User{}, errors.New("token is revoked")
+		}
+	}
+
 	return usr, nil
 }
 
@@ -175,8 +205,18 @@ func NewMockKeyStore(t *testing.T) mockKeyStore {
 	}
 }
 
-func (m mockKeyStore) PrivateKey(kid string) (string, error) {
-	return m.privateKey, nil
+func (m mockKeyStore) Sign(ctx context.Context, kid string, digest []byte) ([]byte, error) {
+	pemBlock, _ := pem.Decode([]byte(m.privateKey))
+	if pemBlock == nil {
+		return nil, errors.New("failed to decode private key into pem block")
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(pemBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+
+	return rsa.SignPKCS1v15(rand.Reader, privateKey.(*rsa.PrivateKey), crypto.SHA256, digest)
 }
 
 func (m mockKeyStore) PublicKey(kid string) (string, error) {
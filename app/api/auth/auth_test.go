@@ -47,7 +47,7 @@ func TestToken(t *testing.T) {
 		},
 	}
 
-	userService := user.NewService(&userRepo)
+	userService := user.NewService(&userRepo, nil)
 	a := auth.New(ks, userService)
 
 	c := auth.Claims{
@@ -60,7 +60,7 @@ func TestToken(t *testing.T) {
 		},
 	}
 
-	tkn, err := a.GenerateToken(kid, c)
+	tkn, err := a.GenerateToken(context.Background(), kid, c)
 	if err != nil {
 		t.Fatalf("expected the jwt token to be generated: %s", err)
 	}
@@ -94,7 +94,7 @@ func TestAuthorized(t *testing.T) {
 		Users: map[uuid.UUID]user.User{},
 	}
 
-	userService := user.NewService(&userRepo)
+	userService := user.NewService(&userRepo, nil)
 	a := auth.New(ks, userService)
 
 	err := a.Authorized(usr, []user.Role{user.RoleAdmin})
@@ -107,3 +107,85 @@ func TestAuthorized(t *testing.T) {
 		t.Fatal("expected the claims to not be authorized")
 	}
 }
+
+func TestClaimsOnly(t *testing.T) {
+	ks := auth.NewMockKeyStore(t)
+
+	//the user does not exist, only the claims are used
+	userService := user.NewService(&memory.Repository{Users: map[uuid.UUID]user.User{}}, nil)
+	a := auth.New(ks, userService)
+	a.EnableClaimsOnly()
+
+	usrId := uuid.New()
+	c := auth.Claims{
+		Roles: []string{user.RoleAdmin.String()},
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "test",
+			Subject:   usrId.String(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	tkn, err := a.GenerateToken(context.Background(), kid, c)
+	if err != nil {
+		t.Fatalf("expected the jwt token to be generated: %s", err)
+	}
+
+	usr, err := a.ValidateToken(context.Background(), "Bearer "+tkn)
+	if err != nil {
+		t.Fatalf("expected the token to be valid: %s", err)
+	}
+
+	if usr.Id != usrId {
+		t.Errorf("id= %s, got %s", usrId, usr.Id)
+	}
+
+	if !reflect.DeepEqual(usr.Roles, []user.Role{user.RoleAdmin}) {
+		t.Errorf("roles= %v, got %v", []user.Role{user.RoleAdmin}, usr.Roles)
+	}
+}
+
+func TestRevokedTokens(t *testing.T) {
+	ks := auth.NewMockKeyStore(t)
+
+	usrId := uuid.New()
+	repo := memory.Repository{Users: map[uuid.UUID]user.User{
+		usrId: {Id: usrId, Roles: []user.Role{user.RoleUser}, Enabled: true},
+	}}
+	a := auth.New(ks, user.NewService(&repo, nil))
+
+	generate := func(issuedAt time.Time) string {
+		c := auth.Claims{
+			Roles: []string{user.RoleUser.String()},
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    "test",
+				Subject:   usrId.String(),
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+				IssuedAt:  jwt.NewNumericDate(issuedAt),
+			},
+		}
+
+		tkn, err := a.GenerateToken(context.Background(), kid, c)
+		if err != nil {
+			t.Fatalf("expected the jwt token to be generated: %s", err)
+		}
+		return tkn
+	}
+
+	old := generate(time.Now().Add(-time.Minute))
+	fresh := generate(time.Now())
+
+	//the tokens were revoked in between, the user is enabled again
+	usr := repo.Users[usrId]
+	usr.TokensValidAfter = time.Now().Add(-time.Second * 30)
+	repo.Users[usrId] = usr
+
+	if _, err := a.ValidateToken(context.Background(), "Bearer "+old); err == nil {
+		t.Error("expected the token issued before the revocation to be rejected")
+	}
+
+	if _, err := a.ValidateToken(context.Background(), "Bearer "+fresh); err != nil {
+		t.Errorf("expected the token issued after the revocation to be valid: %s", err)
+	}
+}
@@ -9,7 +9,10 @@ import (
 
 type ctxKey int
 
-const userKey ctxKey = 1
+const (
+	userKey ctxKey = iota + 1
+	impersonatorKey
+)
 
 // SetUser injects the user into ctx to be passed to next handler.
 func SetUser(ctx context.Context, usr user.User) context.Context {
@@ -24,3 +27,14 @@ func GetUser(ctx context.Context) (user.User, error) {
 	}
 	return usr, nil
 }
+
+// SetImpersonator injects the admin that acts as the user of ctx.
+func SetImpersonator(ctx context.Context, admin user.User) context.Context {
+	return context.WithValue(ctx, impersonatorKey, admin)
+}
+
+// GetImpersonator returns the admin that acts as the user of ctx, returns false when the user acts themselves.
+func GetImpersonator(ctx context.Context) (user.User, bool) {
+	admin, ok := ctx.Value(impersonatorKey).(user.User)
+	return admin, ok
+}
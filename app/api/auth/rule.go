@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/user"
+)
+
+// OwnerFunc resolves the id of the user that owns the resource targeted by the request.
+type OwnerFunc func(ctx context.Context, r *http.Request) (uuid.UUID, error)
+
+// Rule declares who may call a route. Callers that have any of the roles are allowed, when an owner is declared
+// the owner of the resource is allowed as well. A rule without roles and owner allows any authenticated user.
+type Rule struct {
+	Public bool
+	Roles  []user.Role
+	Owner  OwnerFunc
+	//OwnerDescription explains the ownership rule inside of the authorization matrix.
+	OwnerDescription string
+}
+
+// Route represents the authorization declaration of a single route.
+type Route struct {
+	Method        string   `json:"method"`
+	Path          string   `json:"path"`
+	Public        bool     `json:"public"`
+	Authenticated bool     `json:"authenticated"`
+	Roles         []string `json:"roles"`
+	Owner         string   `json:"owner,omitempty"`
+}
+
+// Matrix collects the authorization declarations of all of the registered routes so security reviews can
+// see who may call what.
+type Matrix struct {
+	mu     sync.RWMutex
+	routes []Route
+}
+
+// Add records the rule of a route.
+func (m *Matrix) Add(method string, path string, rule Rule) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	roles := user.EncodeRoles(rule.Roles)
+	if roles == nil {
+		roles = []string{}
+	}
+
+	m.routes = append(m.routes, Route{
+		Method:        method,
+		Path:          path,
+		Public:        rule.Public,
+		Authenticated: !rule.Public,
+		Roles:         roles,
+		Owner:         rule.OwnerDescription,
+	})
+}
+
+// Routes returns all of the recorded routes sorted by path and method.
+func (m *Matrix) Routes() []Route {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	routes := slices.Clone(m.routes)
+	slices.SortFunc(routes, func(a, b Route) int {
+		if c := strings.Compare(a.Path, b.Path); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Method, b.Method)
+	})
+	return routes
+}
@@ -0,0 +1,23 @@
+// Package debug provides the mux for the debug server which must never be exposed publicly.
+package debug
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+)
+
+// Mux registers pprof and expvar endpoints into a new mux, the default mux is avoided so
+// no third party package can register endpoints into it.
+func Mux() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	return mux
+}
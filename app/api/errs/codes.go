@@ -0,0 +1,111 @@
+package errs
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+)
+
+// ErrorCode is the machine readable reason of an error, clients branch on it instead of parsing the message.
+type ErrorCode string
+
+// generic codes, every error without a specific code carries the one of its status.
+const (
+	CodeBadRequest      ErrorCode = "BAD_REQUEST"
+	CodeUnauthorized    ErrorCode = "UNAUTHORIZED"
+	CodeForbidden       ErrorCode = "FORBIDDEN"
+	CodeNotFound        ErrorCode = "NOT_FOUND"
+	CodeConflict        ErrorCode = "CONFLICT"
+	CodeLocked          ErrorCode = "LOCKED"
+	CodeTooManyRequests ErrorCode = "TOO_MANY_REQUESTS"
+	CodeUnavailable     ErrorCode = "SERVICE_UNAVAILABLE"
+	CodeInternal        ErrorCode = "INTERNAL_ERROR"
+)
+
+// specific codes.
+const (
+	CodeValidationFailed    ErrorCode = "VALIDATION_FAILED"
+	CodeInvalidJSON         ErrorCode = "INVALID_JSON"
+	CodeInvalidId           ErrorCode = "INVALID_ID"
+	CodeInvalidQuery        ErrorCode = "INVALID_QUERY"
+	CodeInvalidCursor       ErrorCode = "INVALID_CURSOR"
+	CodeInvalidEmail        ErrorCode = "INVALID_EMAIL"
+	CodeInvalidRole         ErrorCode = "INVALID_ROLE"
+	CodeInvalidView         ErrorCode = "INVALID_VIEW"
+	CodeInvalidRule         ErrorCode = "INVALID_RULE"
+	CodeInvalidSecret       ErrorCode = "INVALID_SECRET"
+	CodeInvalidPreset       ErrorCode = "INVALID_PRESET"
+	CodeInvalidBlackout     ErrorCode = "INVALID_BLACKOUT"
+	CodeInvalidQuota        ErrorCode = "INVALID_QUOTA"
+	CodeInvalidBudget       ErrorCode = "INVALID_BUDGET"
+	CodeTaskNotFinished     ErrorCode = "TASK_NOT_FINISHED"
+	CodeNotAuthenticated    ErrorCode = "NOT_AUTHENTICATED"
+	CodeMissingToken        ErrorCode = "MISSING_TOKEN"
+	CodeInvalidToken        ErrorCode = "INVALID_TOKEN"
+	CodeNotPermitted        ErrorCode = "NOT_PERMITTED"
+	CodeTaskNotFound        ErrorCode = "TASK_NOT_FOUND"
+	CodeRunNotFound         ErrorCode = "RUN_NOT_FOUND"
+	CodeUserNotFound        ErrorCode = "USER_NOT_FOUND"
+	CodeSecretNotFound      ErrorCode = "SECRET_NOT_FOUND"
+	CodeBlackoutNotFound    ErrorCode = "BLACKOUT_NOT_FOUND"
+	CodeRuleNotFound        ErrorCode = "RULE_NOT_FOUND"
+	CodeViewNotFound        ErrorCode = "VIEW_NOT_FOUND"
+	CodePresetNotFound      ErrorCode = "PRESET_NOT_FOUND"
+	CodeQuotaExceeded       ErrorCode = "QUOTA_EXCEEDED"
+	CodeBudgetExceeded      ErrorCode = "BUDGET_EXCEEDED"
+	CodeEmailInUse          ErrorCode = "EMAIL_IN_USE"
+	CodeEmailVerified       ErrorCode = "EMAIL_ALREADY_VERIFIED"
+	CodeViewExists          ErrorCode = "VIEW_EXISTS"
+	CodeLimitReached        ErrorCode = "LIMIT_REACHED"
+	CodeLoginFailed         ErrorCode = "LOGIN_FAILED"
+	CodeAccountLocked       ErrorCode = "ACCOUNT_LOCKED"
+	CodeLoginThrottled      ErrorCode = "LOGIN_THROTTLED"
+	CodeInvalidOneTimeToken ErrorCode = "INVALID_ONE_TIME_TOKEN"
+	CodeFeatureDisabled     ErrorCode = "FEATURE_DISABLED"
+)
+
+var statusCodes = map[int]ErrorCode{
+	http.StatusBadRequest:          CodeBadRequest,
+	http.StatusUnauthorized:        CodeUnauthorized,
+	http.StatusForbidden:           CodeForbidden,
+	http.StatusNotFound:            CodeNotFound,
+	http.StatusConflict:            CodeConflict,
+	http.StatusLocked:              CodeLocked,
+	http.StatusTooManyRequests:     CodeTooManyRequests,
+	http.StatusServiceUnavailable:  CodeUnavailable,
+	http.StatusInternalServerError: CodeInternal,
+}
+
+// codeOf returns the generic code of the status.
+func codeOf(status int) ErrorCode {
+	if code, ok := statusCodes[status]; ok {
+		return code
+	}
+	return CodeInternal
+}
+
+// NewAppCodeError creates an *AppError with a specific error code.
+func NewAppCodeError(code int, errCode ErrorCode, message string) error {
+	pc, filename, line, _ := runtime.Caller(1)
+	funcName := runtime.FuncForPC(pc).Name()
+	return &AppError{
+		Code:      code,
+		ErrorCode: errCode,
+		Message:   message,
+		FuncName:  funcName,
+		FileName:  fmt.Sprintf("%s:%d", filename, line),
+	}
+}
+
+// NewAppCodeErrorf creates an *AppError with a specific error code and a formatted message.
+func NewAppCodeErrorf(code int, errCode ErrorCode, format string, v ...any) error {
+	pc, filename, line, _ := runtime.Caller(1)
+	funcName := runtime.FuncForPC(pc).Name()
+	return &AppError{
+		Code:      code,
+		ErrorCode: errCode,
+		Message:   fmt.Sprintf(format, v...),
+		FuncName:  funcName,
+		FileName:  fmt.Sprintf("%s:%d", filename, line),
+	}
+}
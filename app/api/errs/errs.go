@@ -10,11 +10,13 @@ import (
 
 // AppError represents a trusted error inside the system
 type AppError struct {
-	Code     int               `json:"code"`
-	Message  string            `json:"message"`
-	FuncName string            `json:"-"`
-	FileName string            `json:"-"`
-	Fields   map[string]string `json:"fields,omitempty"`
+	Code int `json:"code"`
+	//ErrorCode is the machine readable reason of the error.
+	ErrorCode ErrorCode         `json:"errorCode"`
+	Message   string            `json:"message"`
+	FuncName  string            `json:"-"`
+	FileName  string            `json:"-"`
+	Fields    map[string]string `json:"fields,omitempty"`
 }
 
 func (err *AppError) Error() string {
@@ -28,10 +30,11 @@ func NewAppError(code int, message string) error {
 	funcName := runtime.FuncForPC(pc).Name()
 
 	return &AppError{
-		Code:     code,
-		Message:  message,
-		FuncName: funcName,
-		FileName: fmt.Sprintf("%s:%d", filename, line),
+		Code:      code,
+		ErrorCode: codeOf(code),
+		Message:   message,
+		FuncName:  funcName,
+		FileName:  fmt.Sprintf("%s:%d", filename, line),
 	}
 }
 
@@ -40,10 +43,11 @@ func NewAppErrorf(code int, format string, v ...any) error {
 	pc, filename, line, _ := runtime.Caller(1)
 	funcName := runtime.FuncForPC(pc).Name()
 	return &AppError{
-		Code:     code,
-		Message:  fmt.Sprintf(format, v...),
-		FuncName: funcName,
-		FileName: fmt.Sprintf("%s:%d", filename, line),
+		Code:      code,
+		ErrorCode: codeOf(code),
+		Message:   fmt.Sprintf(format, v...),
+		FuncName:  funcName,
+		FileName:  fmt.Sprintf("%s:%d", filename, line),
 	}
 }
 
@@ -52,11 +56,12 @@ func NewAppValidationError(code int, message string, fields map[string]string) e
 	pc, filename, line, _ := runtime.Caller(1)
 	funcName := runtime.FuncForPC(pc).Name()
 	return &AppError{
-		Code:     code,
-		Message:  message,
-		FuncName: funcName,
-		FileName: fmt.Sprintf("%s:%d", filename, line),
-		Fields:   fields,
+		Code:      code,
+		ErrorCode: CodeValidationFailed,
+		Message:   message,
+		FuncName:  funcName,
+		FileName:  fmt.Sprintf("%s:%d", filename, line),
+		Fields:    fields,
 	}
 }
 
@@ -65,9 +70,10 @@ func NewAppInternalErr(err error) error {
 	pc, filename, line, _ := runtime.Caller(1)
 	funcName := runtime.FuncForPC(pc).Name()
 	return &AppError{
-		Code:     http.StatusInternalServerError,
-		Message:  err.Error(),
-		FuncName: funcName,
-		FileName: fmt.Sprintf("%s:%d", filename, line),
+		Code:      http.StatusInternalServerError,
+		ErrorCode: CodeInternal,
+		Message:   err.Error(),
+		FuncName:  funcName,
+		FileName:  fmt.Sprintf("%s:%d", filename, line),
 	}
 }
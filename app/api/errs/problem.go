@@ -0,0 +1,56 @@
+package errs
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ProblemContentType is the media type of the problem details of RFC 7807.
+const ProblemContentType = "application/problem+json"
+
+// Problem represents the problem details of an error as described by RFC 7807.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	//extension members.
+	ErrorCode     ErrorCode      `json:"errorCode"`
+	InvalidParams []InvalidParam `json:"invalid-params,omitempty"`
+}
+
+// InvalidParam is a field that failed the validation.
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// Problem returns the problem details of the error, instance is the path of the request that failed.
+func (err *AppError) Problem(instance string) Problem {
+	p := Problem{
+		Type:      problemType(err.ErrorCode),
+		Title:     http.StatusText(err.Code),
+		Status:    err.Code,
+		Detail:    err.Message,
+		Instance:  instance,
+		ErrorCode: err.ErrorCode,
+	}
+
+	for name, reason := range err.Fields {
+		p.InvalidParams = append(p.InvalidParams, InvalidParam{Name: name, Reason: reason})
+	}
+	sort.Slice(p.InvalidParams, func(i, j int) bool {
+		return p.InvalidParams[i].Name < p.InvalidParams[j].Name
+	})
+	return p
+}
+
+// problemType turns the code into a urn, "TASK_NOT_FOUND" becomes "urn:task-scheduler:task-not-found".
+func problemType(code ErrorCode) string {
+	if code == "" {
+		return "about:blank"
+	}
+	return "urn:task-scheduler:" + strings.ToLower(strings.ReplaceAll(string(code), "_", "-"))
+}
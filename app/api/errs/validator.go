@@ -3,8 +3,11 @@ package errs
 import (
 	"errors"
 	"fmt"
+	"path"
 	"reflect"
+	"regexp"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -44,6 +47,8 @@ func NewAppValidator() (*AppValidator, error) {
 	v.RegisterValidation("commonCommands", commonCommands)
 	v.RegisterValidation("commonArgs", validCommandArgs)
 	v.RegisterValidation("validScheduledAt", validScheduledAt)
+	v.RegisterValidation("workDir", validWorkDir)
+	v.RegisterValidation("runAsUser", validRunAsUser)
 
 	return &AppValidator{
 		validate:   v,
@@ -68,6 +73,8 @@ func (av *AppValidator) Check(val any) (map[string]string, bool) {
 			"Command.commonCommands":       "command is not supported in this system",
 			"Args.commonArgs":              "provided args contains invalid chars",
 			"ScheduledAt.validScheduledAt": "scheduledAt most be greater or equal to current time",
+			"WorkDir.workDir":              "workDir must be an absolute path without ..",
+			"RunAsUser.runAsUser":          "runAsUser must be a user other than root like 1000:1000 or nobody",
 		}
 
 		fields := make(map[string]string, len(vErrs))
@@ -112,3 +119,28 @@ func validScheduledAt(fl validator.FieldLevel) bool {
 	now := time.Now()
 	return scheduledAt.After(now) || scheduledAt.Equal(now)
 }
+
+func validWorkDir(fl validator.FieldLevel) bool {
+	dir := fl.Field().String()
+	if !path.IsAbs(dir) || strings.ContainsAny(dir, "\x00\n\r\t") {
+		return false
+	}
+	return !slices.Contains(strings.Split(dir, "/"), "..")
+}
+
+// userPattern matches "user[:group]" where both are names or ids.
+var userPattern = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,31}(:[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,31})?$`)
+
+func validRunAsUser(fl validator.FieldLevel) bool {
+	user := fl.Field().String()
+	if !userPattern.MatchString(user) {
+		return false
+	}
+
+	//containers must not be able to run as root by asking for it
+	name, _, _ := strings.Cut(user, ":")
+	if uid, err := strconv.Atoi(name); err == nil {
+		return uid != 0
+	}
+	return name != "root"
+}
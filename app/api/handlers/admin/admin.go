@@ -0,0 +1,76 @@
+// Package admin provides the handlers used by administrators to inspect the service.
+package admin
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/app/api/auth"
+	"github.com/hamidoujand/task-scheduler/app/api/errs"
+	"github.com/hamidoujand/task-scheduler/business/domain/blackout"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	"github.com/hamidoujand/task-scheduler/business/domain/worker"
+	"github.com/hamidoujand/task-scheduler/foundation/web"
+)
+
+// Handler represents set of admin handlers.
+type Handler struct {
+	Matrix          *auth.Matrix
+	Validator       *errs.AppValidator
+	WorkerService   *worker.Service
+	BlackoutService *blackout.Service
+	Scheduler       localScheduler
+	TaskService     *task.Service
+	//EffectiveConfig returns the settings of this instance with the secrets masked.
+	EffectiveConfig func() (map[string]string, error)
+}
+
+// Authorization responds with the authorization matrix of all of the registered routes.
+func (h *Handler) Authorization(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	data := struct {
+		Routes []auth.Route `json:"routes"`
+	}{
+		Routes: h.Matrix.Routes(),
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, data)
+}
+
+// Worker represents a worker process that goes to client.
+type Worker struct {
+	Id          string    `json:"id"`
+	Hostname    string    `json:"hostname"`
+	Build       string    `json:"build"`
+	MaxRunning  int       `json:"maxRunning"`
+	Running     int       `json:"running"`
+	Load        int       `json:"load"`
+	Status      string    `json:"status"`
+	StartedAt   time.Time `json:"startedAt"`
+	HeartbeatAt time.Time `json:"heartbeatAt"`
+}
+
+// Workers responds with every worker that is still sending heartbeats.
+func (h *Handler) Workers(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	workers, err := h.WorkerService.GetAll(ctx)
+	if err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+
+	data := make([]Worker, len(workers))
+	for i, wrk := range workers {
+		data[i] = Worker{
+			Id:          wrk.Id,
+			Hostname:    wrk.Hostname,
+			Build:       wrk.Build,
+			MaxRunning:  wrk.MaxRunning,
+			Running:     wrk.Running,
+			Load:        wrk.Load,
+			Status:      string(wrk.Status),
+			StartedAt:   wrk.StartedAt,
+			HeartbeatAt: wrk.HeartbeatAt,
+		}
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, data)
+}
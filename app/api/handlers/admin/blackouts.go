@@ -0,0 +1,203 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/app/api/errs"
+	"github.com/hamidoujand/task-scheduler/business/domain/blackout"
+	"github.com/hamidoujand/task-scheduler/foundation/web"
+)
+
+// Window represents a blackout window that goes to client, times are "HH:MM" in UTC.
+type Window struct {
+	Id        string    `json:"id"`
+	Days      []string  `json:"days"`
+	Start     string    `json:"start"`
+	End       string    `json:"end"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Pause represents the global pause that goes to client.
+type Pause struct {
+	Paused    bool      `json:"paused"`
+	Reason    string    `json:"reason"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Blackouts represents every blackout window together with the global pause.
+type Blackouts struct {
+	Windows []Window `json:"windows"`
+	Pause   Pause    `json:"pause"`
+}
+
+// NewWindow represents a blackout window sent by client, an empty days means every day.
+type NewWindow struct {
+	Days   []string `json:"days" validate:"omitempty,max=7,dive,oneof=sunday monday tuesday wednesday thursday friday saturday"`
+	Start  string   `json:"start" validate:"required"`
+	End    string   `json:"end" validate:"required"`
+	Reason string   `json:"reason" validate:"max=256"`
+}
+
+// SetPause represents the global pause sent by client.
+type SetPause struct {
+	Paused bool   `json:"paused"`
+	Reason string `json:"reason" validate:"max=256"`
+}
+
+// GetBlackouts responds with all of the blackout windows and the global pause.
+func (h *Handler) GetBlackouts(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	windows, err := h.BlackoutService.GetWindows(ctx)
+	if err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+
+	pause, err := h.BlackoutService.GetPause(ctx)
+	if err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+
+	data := Blackouts{
+		Windows: make([]Window, len(windows)),
+		Pause:   fromDomainPause(pause),
+	}
+	for i, win := range windows {
+		data.Windows[i] = fromDomainWindow(win)
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, data)
+}
+
+// CreateBlackout creates a blackout window.
+func (h *Handler) CreateBlackout(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var nw NewWindow
+	if err := web.Decode(r, &nw); err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidJSON, "invalid json: %s", err)
+	}
+
+	fields, ok := h.Validator.Check(nw)
+	if !ok {
+		return errs.NewAppValidationError(http.StatusBadRequest, "invalid input", fields)
+	}
+
+	start, err := parseClock(nw.Start)
+	if err != nil {
+		return errs.NewAppValidationError(http.StatusBadRequest, "invalid input", map[string]string{"start": err.Error()})
+	}
+
+	end, err := parseClock(nw.End)
+	if err != nil {
+		return errs.NewAppValidationError(http.StatusBadRequest, "invalid input", map[string]string{"end": err.Error()})
+	}
+
+	days := make([]time.Weekday, len(nw.Days))
+	for i, d := range nw.Days {
+		days[i] = weekdays[d]
+	}
+
+	win, err := h.BlackoutService.CreateWindow(ctx, blackout.NewWindow{
+		Days:   days,
+		Start:  start,
+		End:    end,
+		Reason: nw.Reason,
+	})
+	if err != nil {
+		if errors.Is(err, blackout.ErrInvalidWindow) {
+			return errs.NewAppCodeError(http.StatusBadRequest, errs.CodeInvalidBlackout, err.Error())
+		}
+		return errs.NewAppInternalErr(err)
+	}
+
+	return web.Respond(ctx, w, http.StatusCreated, fromDomainWindow(win))
+}
+
+// DeleteBlackout deletes the blackout window in the "id" path value.
+func (h *Handler) DeleteBlackout(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	windowId, err := uuid.Parse(id)
+	if err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidId, "%q not a valid uuid", id)
+	}
+
+	if err := h.BlackoutService.DeleteWindow(ctx, windowId); err != nil {
+		if errors.Is(err, blackout.ErrWindowNotFound) {
+			return errs.NewAppCodeErrorf(http.StatusNotFound, errs.CodeBlackoutNotFound, "blackout window %q not found", id)
+		}
+		return errs.NewAppInternalErr(err)
+	}
+
+	return web.Respond(ctx, w, http.StatusNoContent, nil)
+}
+
+// SetPause pauses or resumes the execution of all tasks.
+func (h *Handler) SetPause(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var sp SetPause
+	if err := web.Decode(r, &sp); err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidJSON, "invalid json: %s", err)
+	}
+
+	fields, ok := h.Validator.Check(sp)
+	if !ok {
+		return errs.NewAppValidationError(http.StatusBadRequest, "invalid input", fields)
+	}
+
+	pause, err := h.BlackoutService.SetPause(ctx, sp.Paused, sp.Reason)
+	if err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, fromDomainPause(pause))
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// parseClock parses "HH:MM" into an offset from midnight.
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a HH:MM time", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func formatClock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d/time.Hour), int(d%time.Hour/time.Minute))
+}
+
+func fromDomainWindow(w blackout.Window) Window {
+	days := make([]string, len(w.Days))
+	for i, d := range w.Days {
+		days[i] = strings.ToLower(d.String())
+	}
+
+	return Window{
+		Id:        w.Id.String(),
+		Days:      days,
+		Start:     formatClock(w.Start),
+		End:       formatClock(w.End),
+		Reason:    w.Reason,
+		CreatedAt: w.CreatedAt,
+	}
+}
+
+func fromDomainPause(p blackout.Pause) Pause {
+	return Pause{
+		Paused:    p.Paused,
+		Reason:    p.Reason,
+		UpdatedAt: p.UpdatedAt,
+	}
+}
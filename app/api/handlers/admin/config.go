@@ -0,0 +1,26 @@
+package admin
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/hamidoujand/task-scheduler/app/api/errs"
+	"github.com/hamidoujand/task-scheduler/foundation/web"
+)
+
+// Config responds with the settings this instance runs with by the name of their environment variable, including
+// the ones reloaded since it started. Secrets are masked.
+func (h *Handler) Config(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	settings, err := h.EffectiveConfig()
+	if err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+
+	data := struct {
+		Settings map[string]string `json:"settings"`
+	}{
+		Settings: settings,
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, data)
+}
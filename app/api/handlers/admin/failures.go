@@ -0,0 +1,57 @@
+package admin
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/app/api/errs"
+	"github.com/hamidoujand/task-scheduler/foundation/web"
+)
+
+// maxFailures is the most failed tasks a single request returns.
+const maxFailures = 100
+
+// FailedTask represents a task that failed that goes to client.
+type FailedTask struct {
+	Id          string    `json:"id"`
+	UserId      string    `json:"userId"`
+	Image       string    `json:"image"`
+	Command     string    `json:"command"`
+	ErrMessage  string    `json:"errMessage"`
+	ErrCategory string    `json:"errCategory,omitempty"`
+	FailedAt    time.Time `json:"failedAt"`
+}
+
+// RecentFailures responds with the tasks of all of the users that failed last, "rows" limits how many are returned.
+func (h *Handler) RecentFailures(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	rows := 20
+	if rowsString := r.URL.Query().Get("rows"); rowsString != "" {
+		var err error
+		rows, err = strconv.Atoi(rowsString)
+		if err != nil || rows <= 0 || rows > maxFailures {
+			return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidQuery, "rows must be between 1 and %d", maxFailures)
+		}
+	}
+
+	tasks, err := h.TaskService.RecentFailures(ctx, rows)
+	if err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+
+	data := make([]FailedTask, len(tasks))
+	for i, tsk := range tasks {
+		data[i] = FailedTask{
+			Id:          tsk.Id.String(),
+			UserId:      tsk.UserId.String(),
+			Image:       tsk.Image,
+			Command:     tsk.Command,
+			ErrMessage:  tsk.ErrMessage,
+			ErrCategory: string(tsk.ErrCategory),
+			FailedAt:    tsk.UpdatedAt,
+		}
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, data)
+}
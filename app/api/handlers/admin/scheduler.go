@@ -0,0 +1,119 @@
+package admin
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/app/api/errs"
+	"github.com/hamidoujand/task-scheduler/business/domain/scheduler"
+	"github.com/hamidoujand/task-scheduler/foundation/web"
+)
+
+// localScheduler represents the scheduler of this instance.
+type localScheduler interface {
+	Drain() error
+	Resume() error
+	DrainState() string
+	Status() scheduler.Status
+}
+
+// Executer represents a task the scheduler is executing or waiting to execute that goes to client.
+type Executer struct {
+	TaskId    string    `json:"taskId"`
+	StartedAt time.Time `json:"startedAt"`
+	Deadline  time.Time `json:"deadline"`
+	Remaining string    `json:"remaining"`
+}
+
+// Queue represents the depth of a queue that goes to client.
+type Queue struct {
+	Name      string `json:"name"`
+	Messages  int    `json:"messages"`
+	Consumers int    `json:"consumers"`
+	Error     string `json:"error,omitempty"`
+}
+
+// SchedulerStatus represents the runtime state of the scheduler that goes to client.
+type SchedulerStatus struct {
+	Instance         string     `json:"instance"`
+	Mode             string     `json:"mode"`
+	Active           bool       `json:"active"`
+	Intake           string     `json:"intake"`
+	Executers        []Executer `json:"executers"`
+	SlotsUsed        int        `json:"slotsUsed"`
+	SlotsCapacity    int        `json:"slotsCapacity"`
+	Queues           []Queue    `json:"queues"`
+	Retried          int64      `json:"retried"`
+	RetriesExhausted int64      `json:"retriesExhausted"`
+}
+
+// SchedulerStatus responds with the running executers, the semaphore utilization, the queue depths and the retry
+// counts of the scheduler of this instance.
+func (h *Handler) SchedulerStatus(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	st := h.Scheduler.Status()
+
+	data := SchedulerStatus{
+		Instance:         st.Instance,
+		Mode:             st.Mode.String(),
+		Active:           st.Active,
+		Intake:           st.Intake,
+		Executers:        make([]Executer, len(st.Executers)),
+		SlotsUsed:        st.SlotsUsed,
+		SlotsCapacity:    st.SlotsCapacity,
+		Queues:           make([]Queue, len(st.Queues)),
+		Retried:          st.Retried,
+		RetriesExhausted: st.RetriesExhausted,
+	}
+
+	for i, ex := range st.Executers {
+		data.Executers[i] = Executer{
+			TaskId:    ex.TaskId.String(),
+			StartedAt: ex.StartedAt,
+			Deadline:  ex.Deadline,
+			Remaining: ex.Remaining.Round(time.Second).String(),
+		}
+	}
+
+	for i, q := range st.Queues {
+		data.Queues[i] = Queue{
+			Name:      q.Name,
+			Messages:  q.Messages,
+			Consumers: q.Consumers,
+		}
+		if q.Err != nil {
+			data.Queues[i].Error = q.Err.Error()
+		}
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, data)
+}
+
+// PauseScheduler stops this instance from taking new tasks off of the task queues, executing tasks are left to
+// finish. It only affects the instance that serves the request.
+func (h *Handler) PauseScheduler(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if err := h.Scheduler.Drain(); err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+
+	return h.schedulerState(ctx, w)
+}
+
+// ResumeScheduler makes this instance take new tasks off of the task queues again.
+func (h *Handler) ResumeScheduler(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if err := h.Scheduler.Resume(); err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+
+	return h.schedulerState(ctx, w)
+}
+
+func (h *Handler) schedulerState(ctx context.Context, w http.ResponseWriter) error {
+	data := struct {
+		Intake string `json:"intake"`
+	}{
+		Intake: h.Scheduler.DrainState(),
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, data)
+}
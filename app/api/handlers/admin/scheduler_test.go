@@ -0,0 +1,92 @@
+package admin_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hamidoujand/task-scheduler/app/api/errs"
+	"github.com/hamidoujand/task-scheduler/app/api/handlers/admin"
+	"github.com/hamidoujand/task-scheduler/business/domain/scheduler"
+)
+
+// fakeScheduler drains like a scheduler with one task executing, err fails Drain and Resume.
+type fakeScheduler struct {
+	draining bool
+	err      error
+}
+
+func (s *fakeScheduler) Drain() error {
+	if s.err != nil {
+		return s.err
+	}
+	s.draining = true
+	return nil
+}
+
+func (s *fakeScheduler) Resume() error {
+	if s.err != nil {
+		return s.err
+	}
+	s.draining = false
+	return nil
+}
+
+func (s *fakeScheduler) DrainState() string {
+	if s.draining {
+		return "draining"
+	}
+	return "ok"
+}
+
+func (s *fakeScheduler) Status() scheduler.Status {
+	return scheduler.Status{Intake: s.DrainState()}
+}
+
+func TestPauseResumeScheduler(t *testing.T) {
+	fake := fakeScheduler{}
+	h := admin.Handler{Scheduler: &fake}
+
+	intake := func(fn func(context.Context, http.ResponseWriter, *http.Request) error) string {
+		t.Helper()
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/v1/api/admin/scheduler/pause", nil)
+		if err := fn(context.Background(), w, r); err != nil {
+			t.Fatalf("expected the request to succeed: %s", err)
+		}
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("status= %d, got %d", http.StatusOK, w.Code)
+		}
+
+		var data struct {
+			Intake string `json:"intake"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&data); err != nil {
+			t.Fatalf("expected to decode the response: %s", err)
+		}
+		return data.Intake
+	}
+
+	if got := intake(h.PauseScheduler); got != "draining" || !fake.draining {
+		t.Errorf("intake= %s, got %s", "draining", got)
+	}
+
+	if got := intake(h.ResumeScheduler); got != "ok" || fake.draining {
+		t.Errorf("intake= %s, got %s", "ok", got)
+	}
+
+	//a scheduler that can not cancel its consumers is an internal error
+	fake.err = errors.New("broker is down")
+	r := httptest.NewRequest(http.MethodPost, "/v1/api/admin/scheduler/pause", nil)
+	err := h.PauseScheduler(context.Background(), httptest.NewRecorder(), r)
+
+	var appErr *errs.AppError
+	if !errors.As(err, &appErr) || appErr.Code != http.StatusInternalServerError {
+		t.Errorf("err= %d, got %v", http.StatusInternalServerError, err)
+	}
+}
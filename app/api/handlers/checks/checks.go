@@ -0,0 +1,95 @@
+// Package checks provides the health check handlers used by orchestrators and load balancers.
+package checks
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/database/postgres"
+	"github.com/hamidoujand/task-scheduler/foundation/breaker"
+	"github.com/hamidoujand/task-scheduler/foundation/web"
+)
+
+// dispatcher reports whether this instance is the one dispatching tasks, whether dispatch is paused
+// because of executor infrastructure failures and whether the instance is drained.
+type dispatcher interface {
+	IsActive() bool
+	IsPaused() bool
+	DrainState() string
+}
+
+// Handler represents set of health check handlers.
+type Handler struct {
+	Build      string
+	DB         *postgres.Client
+	Dispatcher dispatcher
+	Breakers   []*breaker.Breaker
+}
+
+// Readiness checks the dependencies of the api and reports whether this instance is dispatching tasks or is
+// in standby, a standby instance is still ready to serve api requests. Intake reports whether the instance was
+// drained and whether its tasks are still executing, dependencies the state of the breakers around them.
+func (h *Handler) Readiness(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	role := "standby"
+	if h.Dispatcher.IsActive() {
+		role = "leader"
+	}
+
+	executor := "ok"
+	if h.Dispatcher.IsPaused() {
+		executor = "paused"
+	}
+
+	status := "ok"
+	statusCode := http.StatusOK
+	if err := h.DB.StatusCheck(ctx); err != nil {
+		status = "db not ready"
+		statusCode = http.StatusInternalServerError
+	}
+
+	dependencies := make(map[string]string, len(h.Breakers))
+	for _, b := range h.Breakers {
+		dependencies[b.Name()] = b.State().String()
+	}
+
+	data := struct {
+		Status       string            `json:"status"`
+		Scheduler    string            `json:"scheduler"`
+		Executor     string            `json:"executor"`
+		Intake       string            `json:"intake"`
+		Dependencies map[string]string `json:"dependencies"`
+	}{
+		Status:       status,
+		Scheduler:    role,
+		Executor:     executor,
+		Intake:       h.Dispatcher.DrainState(),
+		Dependencies: dependencies,
+	}
+
+	return web.Respond(ctx, w, statusCode, data)
+}
+
+// Liveness reports that the process is up and running.
+func (h *Handler) Liveness(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unavailable"
+	}
+
+	data := struct {
+		Status string `json:"status"`
+		Build  string `json:"build"`
+		Host   string `json:"host"`
+	}{
+		Status: "up",
+		Build:  h.Build,
+		Host:   host,
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, data)
+}
@@ -0,0 +1,23 @@
+// Package dashboard serves the admin dashboard, a single page that reads the admin endpoints of the api.
+package dashboard
+
+import (
+	"context"
+	_ "embed"
+	"net/http"
+)
+
+//go:embed index.html
+var index []byte
+
+// Index responds with the page of the dashboard for every path under it, the page asks for the credentials of an
+// admin itself so it is served without authentication.
+func Index(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.WriteHeader(http.StatusOK)
+
+	_, err := w.Write(index)
+	return err
+}
@@ -10,154 +10,650 @@ import (
 
 	"github.com/hamidoujand/task-scheduler/app/api/auth"
 	"github.com/hamidoujand/task-scheduler/app/api/errs"
+	"github.com/hamidoujand/task-scheduler/app/api/handlers/admin"
+	"github.com/hamidoujand/task-scheduler/app/api/handlers/checks"
+	"github.com/hamidoujand/task-scheduler/app/api/handlers/dashboard"
+	"github.com/hamidoujand/task-scheduler/app/api/handlers/notifications"
+	"github.com/hamidoujand/task-scheduler/app/api/handlers/presets"
+	"github.com/hamidoujand/task-scheduler/app/api/handlers/quotas"
+	"github.com/hamidoujand/task-scheduler/app/api/handlers/secrets"
 	"github.com/hamidoujand/task-scheduler/app/api/handlers/tasks"
+	usageHandlers "github.com/hamidoujand/task-scheduler/app/api/handlers/usage"
 	"github.com/hamidoujand/task-scheduler/app/api/handlers/users"
+	"github.com/hamidoujand/task-scheduler/app/api/handlers/views"
 	"github.com/hamidoujand/task-scheduler/app/api/mid"
-	"github.com/hamidoujand/task-scheduler/business/broker/rabbitmq"
+	"github.com/hamidoujand/task-scheduler/business/broker"
 	"github.com/hamidoujand/task-scheduler/business/database/postgres"
+	"github.com/hamidoujand/task-scheduler/business/domain/blackout"
+	blackoutPostgresRepo "github.com/hamidoujand/task-scheduler/business/domain/blackout/store/postgres"
+	"github.com/hamidoujand/task-scheduler/business/domain/notification"
+	notificationPostgresRepo "github.com/hamidoujand/task-scheduler/business/domain/notification/store/postgres"
+	"github.com/hamidoujand/task-scheduler/business/domain/preset"
+	presetPostgresRepo "github.com/hamidoujand/task-scheduler/business/domain/preset/store/postgres"
+	"github.com/hamidoujand/task-scheduler/business/domain/quota"
+	quotaPostgresRepo "github.com/hamidoujand/task-scheduler/business/domain/quota/store/postgres"
+	quotaRedisRepo "github.com/hamidoujand/task-scheduler/business/domain/quota/store/redis"
 	"github.com/hamidoujand/task-scheduler/business/domain/scheduler"
+	schedulerDiskRepo "github.com/hamidoujand/task-scheduler/business/domain/scheduler/store/disk"
+	schedulerPostgresRepo "github.com/hamidoujand/task-scheduler/business/domain/scheduler/store/postgres"
 	redisRepo "github.com/hamidoujand/task-scheduler/business/domain/scheduler/store/redis"
+	"github.com/hamidoujand/task-scheduler/business/domain/secret"
+	secretPostgresRepo "github.com/hamidoujand/task-scheduler/business/domain/secret/store/postgres"
 	"github.com/hamidoujand/task-scheduler/business/domain/task"
 	taskPostgresRepo "github.com/hamidoujand/task-scheduler/business/domain/task/store/postgres"
+	taskRedisRepo "github.com/hamidoujand/task-scheduler/business/domain/task/store/redis"
+	"github.com/hamidoujand/task-scheduler/business/domain/usage"
+	usagePostgresRepo "github.com/hamidoujand/task-scheduler/business/domain/usage/store/postgres"
 	"github.com/hamidoujand/task-scheduler/business/domain/user"
 	userPostgresRepo "github.com/hamidoujand/task-scheduler/business/domain/user/store/postgres"
+	userRedisRepo "github.com/hamidoujand/task-scheduler/business/domain/user/store/redis"
+	"github.com/hamidoujand/task-scheduler/business/domain/view"
+	viewPostgresRepo "github.com/hamidoujand/task-scheduler/business/domain/view/store/postgres"
+	"github.com/hamidoujand/task-scheduler/business/domain/worker"
+	workerRedisRepo "github.com/hamidoujand/task-scheduler/business/domain/worker/store/redis"
+	"github.com/hamidoujand/task-scheduler/foundation/breaker"
+	"github.com/hamidoujand/task-scheduler/foundation/distlock"
+	"github.com/hamidoujand/task-scheduler/foundation/mailer"
+	"github.com/hamidoujand/task-scheduler/foundation/runtime"
 	"github.com/hamidoujand/task-scheduler/foundation/web"
+	"github.com/jackc/pgx/v5"
 	"github.com/redis/go-redis/v9"
 )
 
+// BlobStore represents the object storage large task results are offloaded to.
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Alerter represents the endpoint alerts are posted to.
+type Alerter interface {
+	Post(ctx context.Context, payload any) error
+}
+
 type Config struct {
-	Shutdown                    chan os.Signal
-	Logger                      *slog.Logger
-	Validator                   *errs.AppValidator
-	PostgresClient              *postgres.Client
-	ActiveKID                   string
-	TokenAge                    time.Duration
-	Keystore                    auth.Keystore
-	RClient                     *rabbitmq.Client
-	RedisClient                 *redis.Client
-	MaxRunningTasks             int
-	MaxFailedTasksRetry         int
-	MaxTimeForTaskUpdates       time.Duration
-	MaxTimeForSchedulerShutdown time.Duration
-	MaxTimeForTaskExecution     time.Duration
+	Build                   string
+	Shutdown                chan os.Signal
+	Logger                  *slog.Logger
+	Validator               *errs.AppValidator
+	PostgresClient          *postgres.Client
+	ActiveKID               string
+	TokenAge                time.Duration
+	Keystore                auth.Keystore
+	Broker                  broker.Broker
+	RedisClient             *redis.Client
+	Mailer                  *mailer.Mailer
+	LoginMaxFailures        int
+	LoginMaxFailuresPerIP   int
+	LoginLockDuration       time.Duration
+	MaxRunningTasks         int
+	MaxFailedTasksRetry     int
+	MaxRetriesPerTask       int
+	DedupWindow             time.Duration
+	MaxTaskWait             time.Duration
+	MaxTimeForTaskUpdates   time.Duration
+	StatusUpdateAttempts    int
+	StatusUpdateBackoff     time.Duration
+	ResultHandlers          int
+	StatusBatchInterval     time.Duration
+	StatusBatchSize         int
+	RetentionMonths         int
+	RetryTTL                time.Duration
+	LateAfter               time.Duration
+	SpillDir                string
+	MaxTimeForTaskExecution time.Duration
+	MaxTimeForImagePull     time.Duration
+	Standby                 bool
+	LeaseTTL                time.Duration
+	BreakerThreshold        int
+	BreakerProbeInterval    time.Duration
+	AffinityTimeout         time.Duration
+	AssignTimeout           time.Duration
+	MaxRedeliveries         int
+	//ImageLimits is how many tasks of an image may run at the same time, it requires redis.
+	ImageLimits map[string]int
+	//QueuedTimeout is how long a task may stay queued before it is published again.
+	QueuedTimeout time.Duration
+	//SchedulerMode is ModeDispatch when standalone workers execute the tasks.
+	SchedulerMode scheduler.Mode
+	//SecretKey is the 32 bytes key the secrets of users are encrypted with.
+	SecretKey []byte
+	//ResultBlobs is optional, with it results larger than ResultOffloadBytes are kept inside of it.
+	ResultBlobs        BlobStore
+	ResultOffloadBytes int
+	//MaxResultBytes is how much of the output of a task is kept.
+	MaxResultBytes int
+	//Networks decides which network modes tasks run with, InternalNetwork and EgressNetwork are the docker networks
+	//of the modes.
+	Networks        task.NetworkPolicy
+	InternalNetwork string
+	EgressNetwork   string
+	//Security is the hardening containers run with unless their preset has its own.
+	Security task.SecurityProfile
+	//Runner is the container runtime tasks are executed with, defaults to docker.
+	Runner runtime.Runner
+	//WarmPool is how many idle containers are kept per image, WarmMaxUses and WarmMaxIdle decide when one of them
+	//is replaced.
+	WarmPool    map[string]int
+	WarmMaxUses int
+	WarmMaxIdle time.Duration
+	//BacklogThreshold is the depth of the tasks queue reported as a backlog once it lasts for BacklogDuration.
+	BacklogThreshold    int
+	BacklogDuration     time.Duration
+	BacklogPollInterval time.Duration
+	//BacklogAlert is optional, a backlog and late tasks are posted to it.
+	BacklogAlert Alerter
+	//CacheTTL is how long tasks and users are cached inside of redis, zero disables the cache.
+	CacheTTL time.Duration
+	//AuthClaimsOnly validates tokens by their claims without looking the user up.
+	AuthClaimsOnly bool
+	//ProblemJSON sends every error as "application/problem+json", otherwise only clients accepting it get one.
+	ProblemJSON bool
+	//MaxBodyBytes is the largest body a request may have, zero removes the limit.
+	MaxBodyBytes int64
+	//BasePath and VirtualHost are optional, with them the routes are served under the path and only for the host.
+	BasePath    string
+	VirtualHost string
+	//NotifySlack lets users send the notifications of their tasks to slack webhooks, emails are sent with Mailer.
+	NotifySlack        bool
+	NotifySlackTimeout time.Duration
+	//Breakers are the breakers around postgres, redis and rabbitmq, readiness reports their state.
+	Breakers []*breaker.Breaker
+	//AccessLogger is optional and receives the access log instead of Logger, AccessSampleRate is the fraction of
+	//the requests answered with a 2xx status that are logged.
+	AccessLogger     *slog.Logger
+	AccessSampleRate float64
+	//EffectiveConfig is optional, it returns the settings the api runs with including the reloaded ones. Secrets
+	//must be masked.
+	EffectiveConfig func() (map[string]string, error)
 }
 
-func RegisterRoutes(conf Config) (*web.App, error) {
+// API represents the routes of the api along with the scheduler and the notifier that run next to them.
+type API struct {
+	*web.App
+	scheduler     *scheduler.Scheduler
+	notifications *notification.Service
+	users         *user.Service
+}
+
+// SetLockout changes the limits of login throttling while the api serves, a zero value keeps the current limit. It
+// reports false when throttling is disabled.
+func (a *API) SetLockout(maxFailures int, maxPerIP int, lockDuration time.Duration) bool {
+	l, ok := a.users.Lockout()
+	if !ok {
+		return false
+	}
+
+	if maxFailures > 0 {
+		l.MaxFailures = maxFailures
+	}
+
+	if maxPerIP > 0 {
+		l.MaxPerIP = maxPerIP
+	}
+
+	if lockDuration > 0 {
+		l.LockDuration = lockDuration
+	}
+
+	a.users.EnableLockout(l)
+	return true
+}
+
+// SetMaxRetries changes how many times the scheduler retries a failed task that does not set its own retries.
+func (a *API) SetMaxRetries(retries int) error {
+	return a.scheduler.SetMaxRetries(retries)
+}
+
+// Shutdown stops the notifier and drains the scheduler, it is called once the http server stopped serving requests
+// so none of them can enqueue a task after the consumers stopped.
+func (a *API) Shutdown(ctx context.Context) error {
+	if err := a.notifications.Stop(); err != nil {
+		return fmt.Errorf("stop notifier: %w", err)
+	}
+
+	if err := a.scheduler.Shutdown(ctx); err != nil {
+		return fmt.Errorf("shutdown scheduler: %w", err)
+	}
+	return nil
+}
+
+func RegisterRoutes(conf Config) (*API, error) {
 	//==============================================================================
 	//setup
 	const version = "v1"
+	if conf.AccessLogger == nil {
+		conf.AccessLogger = conf.Logger
+	}
+
 	app := web.NewApp(conf.Shutdown,
-		mid.Logger(conf.Logger),
-		mid.Errors(conf.Logger),
+		mid.Logger(conf.AccessLogger, mid.AccessLog{SuccessSampleRate: conf.AccessSampleRate}),
+		mid.Errors(conf.Logger, conf.ProblemJSON),
 		mid.Panics(),
+		mid.DBOp(),
+		mid.MaxBodySize(conf.MaxBodyBytes),
 	)
+	app.EnableBasePath(conf.BasePath)
+	app.EnableHost(conf.VirtualHost)
 
 	taskRepo := taskPostgresRepo.NewRepository(conf.PostgresClient)
-	taskService, err := task.NewService(taskRepo, conf.RClient)
+	taskService, err := task.NewService(taskRepo, conf.Broker)
 	if err != nil {
 		return nil, fmt.Errorf("new service: %w", err)
 	}
 
+	if conf.ResultBlobs != nil {
+		taskService.EnableResultOffload(conf.ResultBlobs, conf.ResultOffloadBytes)
+	}
+
 	userRepo := userPostgresRepo.NewRepository(conf.PostgresClient)
-	userService := user.NewService(userRepo)
+
+	//email verification and password reset need both redis for tokens and a mailer to deliver them
+	var userService *user.Service
+	if conf.RedisClient != nil && conf.Mailer != nil {
+		userService = user.NewService(userRepo, userRedisRepo.NewRepository(conf.RedisClient))
+	} else {
+		conf.Logger.Info("users", "status", "email verification disabled", "msg", "requires redis and smtp")
+		userService = user.NewService(userRepo, nil)
+	}
+
+	//reads by id go through redis, every instance that changes tasks or users drops them from it
+	if conf.RedisClient != nil && conf.CacheTTL > 0 {
+		taskService.EnableCache(taskRedisRepo.NewCache(conf.RedisClient, conf.CacheTTL))
+		userService.EnableCache(userRedisRepo.NewCache(conf.RedisClient, conf.CacheTTL))
+	}
+
+	//requests waiting for a task on any instance are woken up once it finishes on another one
+	if conf.RedisClient != nil {
+		if err := taskService.EnableStatusNotifier(context.Background(), taskRedisRepo.NewNotifier(conf.RedisClient)); err != nil {
+			return nil, fmt.Errorf("enable status notifier: %w", err)
+		}
+	}
+
+	//login throttling keeps its counters inside of redis
+	if conf.RedisClient != nil {
+		userService.EnableLockout(user.Lockout{
+			Store:        userRedisRepo.NewRepository(conf.RedisClient),
+			MaxFailures:  conf.LoginMaxFailures,
+			MaxPerIP:     conf.LoginMaxFailuresPerIP,
+			LockDuration: conf.LoginLockDuration,
+		})
+	} else {
+		conf.Logger.Info("users", "status", "login throttling disabled", "msg", "requires redis")
+	}
+
+	//quotas live in postgres and are cached in redis when it is enabled
+	var quotaService *quota.Service
+	quotaRepo := quotaPostgresRepo.NewRepository(conf.PostgresClient)
+	if conf.RedisClient != nil {
+		quotaService = quota.NewService(quotaRedisRepo.NewRepository(conf.RedisClient, quotaRepo))
+	} else {
+		quotaService = quota.NewService(quotaRepo)
+	}
+
+	secretService, err := secret.NewService(secretPostgresRepo.NewRepository(conf.PostgresClient), conf.SecretKey)
+	if err != nil {
+		return nil, fmt.Errorf("new secret service: %w", err)
+	}
+
+	blackoutService := blackout.NewService(blackoutPostgresRepo.NewRepository(conf.PostgresClient))
+
+	usageService := usage.NewService(usagePostgresRepo.NewRepository(conf.PostgresClient))
+
+	//finished tasks are sent through the rules of their owners
+	notificationService, err := notification.NewService(notificationPostgresRepo.NewRepository(conf.PostgresClient), conf.Broker, conf.Logger)
+	if err != nil {
+		return nil, fmt.Errorf("new notification service: %w", err)
+	}
+
+	if conf.Mailer != nil {
+		notificationService.EnableEmail(conf.Mailer)
+	}
+
+	if conf.NotifySlack {
+		notificationService.EnableSlack(conf.NotifySlackTimeout)
+	}
+
+	if err := notificationService.Start(); err != nil {
+		return nil, fmt.Errorf("start notifier: %w", err)
+	}
+
+	viewService := view.NewService(viewPostgresRepo.NewRepository(conf.PostgresClient))
+
+	presetService := preset.NewService(presetPostgresRepo.NewRepository(conf.PostgresClient))
 
 	taskHandler := tasks.Handler{
-		Validator:   conf.Validator,
-		TaskService: taskService,
-		UserService: userService,
+		Validator:     conf.Validator,
+		TaskService:   taskService,
+		UserService:   userService,
+		QuotaService:  quotaService,
+		UsageService:  usageService,
+		ViewService:   viewService,
+		PresetService: presetService,
+		MaxRetries:    conf.MaxRetriesPerTask,
+		DedupWindow:   conf.DedupWindow,
+		MaxWait:       conf.MaxTaskWait,
+		Networks:      conf.Networks,
+	}
+
+	//tasks with a dedup key are looked up and created inside of the same transaction
+	taskHandler.WithinTran = func(ctx context.Context, fn func(tsks *task.Service) error) error {
+		var tsks *task.Service
+		err := conf.PostgresClient.WithinTran(ctx, func(tx pgx.Tx) error {
+			tsks = taskService.WithTx(taskPostgresRepo.NewWithTx(tx))
+			return fn(tsks)
+		})
+		if err != nil {
+			return err
+		}
+
+		tsks.Committed(ctx)
+		return nil
 	}
 
 	//setup auth
-	auth := auth.New(conf.Keystore, userService)
+	authenticator := auth.New(conf.Keystore, userService)
+	if conf.AuthClaimsOnly {
+		authenticator.EnableClaimsOnly()
+	}
 
 	userHandler := users.Handler{
 		Validator:    conf.Validator,
 		UsersService: userService,
-		Auth:         auth,
+		Auth:         authenticator,
 		ActiveKID:    conf.ActiveKID,
 		TokenAge:     conf.TokenAge,
 	}
-	//redisRepo
-	redisR := redisRepo.NewRepository(conf.RedisClient)
 
+	//avoid a typed nil inside of the interface
+	if conf.Mailer != nil {
+		userHandler.Mailer = conf.Mailer
+	}
+
+	//a deleted user takes their tasks along inside of the same transaction
+	userHandler.TaskService = taskService
+	userHandler.WithinTran = func(ctx context.Context, fn func(usrs *user.Service, tsks *task.Service) error) error {
+		var (
+			usrs *user.Service
+			tsks *task.Service
+		)
+
+		err := conf.PostgresClient.WithinTran(ctx, func(tx pgx.Tx) error {
+			usrs = userService.WithTx(userPostgresRepo.NewWithTx(tx))
+			tsks = taskService.WithTx(taskPostgresRepo.NewWithTx(tx))
+			return fn(usrs, tsks)
+		})
+		if err != nil {
+			return err
+		}
+
+		//the caches are cleared once the changes are visible to other requests
+		usrs.Committed(ctx)
+		tsks.Committed(ctx)
+		return nil
+	}
 	//setup scheduler
-	scheduler, err := scheduler.New(scheduler.Config{
-		RabbitClient:            conf.RClient,
+	schedulerConf := scheduler.Config{
+		Build:                   conf.Build,
+		Broker:                  conf.Broker,
 		Logger:                  conf.Logger,
 		TaskService:             taskService,
-		RedisRepo:               redisR,
 		MaxRunningTask:          conf.MaxRunningTasks,
 		MaxRetries:              conf.MaxFailedTasksRetry,
 		MaxTimeForUpdateOps:     conf.MaxTimeForTaskUpdates,
+		UpdateAttempts:          conf.StatusUpdateAttempts,
+		UpdateBackoff:           conf.StatusUpdateBackoff,
+		HandlerWorkers:          conf.ResultHandlers,
+		StatusBatchInterval:     conf.StatusBatchInterval,
+		StatusBatchSize:         conf.StatusBatchSize,
+		Partitions:              taskRepo,
+		RetentionMonths:         conf.RetentionMonths,
+		RetryTTL:                conf.RetryTTL,
+		LateAfter:               conf.LateAfter,
 		MaxTimeForTaskExecution: conf.MaxTimeForTaskExecution,
-	})
+		MaxTimeForImagePull:     conf.MaxTimeForImagePull,
+		BreakerThreshold:        conf.BreakerThreshold,
+		BreakerProbeInterval:    conf.BreakerProbeInterval,
+		AffinityTimeout:         conf.AffinityTimeout,
+		Quotas:                  quotaService,
+		Usage:                   usageService,
+		Mode:                    conf.SchedulerMode,
+		AssignTimeout:           conf.AssignTimeout,
+		MaxRedeliveries:         conf.MaxRedeliveries,
+		ImageLimits:             conf.ImageLimits,
+		QueuedTimeout:           conf.QueuedTimeout,
+		Secrets:                 secretService,
+		MaxResultBytes:          conf.MaxResultBytes,
+		Blackouts:               blackoutService,
+		BacklogThreshold:        conf.BacklogThreshold,
+		BacklogDuration:         conf.BacklogDuration,
+		BacklogPollInterval:     conf.BacklogPollInterval,
+		BacklogAlert:            conf.BacklogAlert,
+		Notifier:                notificationService,
+		Presets:                 presetService,
+		Networks:                conf.Networks,
+		InternalNetwork:         conf.InternalNetwork,
+		EgressNetwork:           conf.EgressNetwork,
+		Security:                conf.Security,
+		Runner:                  conf.Runner,
+		WarmPool:                conf.WarmPool,
+		WarmMaxUses:             conf.WarmMaxUses,
+		WarmMaxIdle:             conf.WarmMaxIdle,
+	}
+
+	//retry and lease stores, redis is optional infrastructure
+	var workerService *worker.Service
+	if conf.RedisClient != nil {
+		//due and retried tasks are assigned to the least loaded standalone worker once workers register
+		workerService = worker.NewService(workerRedisRepo.NewRepository(conf.RedisClient))
+		schedulerConf.Workers = workerService
 
-	if conf.MaxTimeForSchedulerShutdown <= 0 {
-		conf.MaxTimeForSchedulerShutdown = time.Minute
+		schedulerRedisRepo := redisRepo.NewRepository(conf.RedisClient)
+		schedulerConf.RetryStore = schedulerRedisRepo
+		schedulerConf.LeaseStore = schedulerRedisRepo
+		schedulerConf.SlotStore = schedulerRedisRepo
+		schedulerConf.DelayStore = schedulerRedisRepo
+		schedulerConf.SpillStore = schedulerRedisRepo
+		schedulerConf.ClaimStore = schedulerRedisRepo
+		schedulerConf.MonitorLock = distlock.New(conf.RedisClient, "monitor", conf.LeaseTTL)
+	} else {
+		conf.Logger.Info("scheduler", "status", "redis disabled", "msg", "using postgres for retry counters")
+		schedulerPGRepo := schedulerPostgresRepo.NewRepository(conf.PostgresClient)
+		schedulerConf.RetryStore = schedulerPGRepo
+		schedulerConf.LeaseStore = schedulerPGRepo
+
+		//statuses are spilled next to the scheduler while postgres is down
+		spillRepo, err := schedulerDiskRepo.NewRepository(conf.SpillDir)
+		if err != nil {
+			return nil, fmt.Errorf("spill dir: %w", err)
+		}
+		schedulerConf.SpillStore = spillRepo
 	}
+	schedulerConf.LeaseTTL = conf.LeaseTTL
 
+	scheduler, err := scheduler.New(schedulerConf)
 	if err != nil {
 		return nil, fmt.Errorf("creating scheduler: %w", err)
 	}
 
-	//setup all consumers
-	if err := scheduler.ConsumeTasks(); err != nil {
-		return nil, fmt.Errorf("task consumer: %w", err)
+	//setup all consumers, in standby mode they only start once this instance holds the leader lease
+	if conf.Standby {
+		conf.Logger.Info("scheduler", "status", "standby", "msg", "dispatch disabled until leader lease is acquired")
+		if err := scheduler.Standby(); err != nil {
+			return nil, fmt.Errorf("standby: %w", err)
+		}
+	} else {
+		if err := scheduler.Activate(); err != nil {
+			return nil, fmt.Errorf("activate: %w", err)
+		}
+	}
+
+	//==============================================================================
+	//every route declares who may call it, the declarations are collected into the authorization matrix
+	var (
+		public        = auth.Rule{Public: true}
+		authenticated = auth.Rule{}
+		adminOnly     = auth.Rule{Roles: []user.Role{user.RoleAdmin}}
+	)
+
+	matrix := auth.Matrix{}
+	handle := func(method string, path string, handler web.Handler, rule auth.Rule, mids ...web.Middleware) {
+		matrix.Add(method, app.Path(version, path), rule)
+
+		if rule.Public {
+			app.HandleFunc(method, version, path, handler)
+			return
+		}
+
+		//route specific mids run between authentication and authorization
+		mids = append([]web.Middleware{mid.Authenticate(authenticator)}, mids...)
+		app.HandleFunc(method, version, path, handler, append(mids, mid.Authorize(rule))...)
 	}
 
-	if err := scheduler.OnTaskSuccess(); err != nil {
-		return nil, fmt.Errorf("on task success consumer: %w", err)
+	//admins act as the user named by the request on the task routes
+	impersonate := mid.Impersonate(userService, conf.Logger)
+
+	//==============================================================================
+	//checks
+	checkHandler := checks.Handler{
+		Build:      conf.Build,
+		DB:         conf.PostgresClient,
+		Dispatcher: scheduler,
+		Breakers:   conf.Breakers,
 	}
+	handle(http.MethodGet, "/readiness", checkHandler.Readiness, public)
+	handle(http.MethodGet, "/liveness", checkHandler.Liveness, public)
+
+	//==============================================================================
+	//tasks
+	handle(http.MethodPost, "/api/tasks/", taskHandler.CreateTask, authenticated, impersonate)
+	handle(http.MethodPost, "/api/tasks/batch", taskHandler.CreateTasks, authenticated, impersonate)
+	handle(http.MethodGet, "/api/tasks/", taskHandler.GetAllTasksForUser, authenticated, impersonate)
+	handle(http.MethodDelete, "/api/tasks/", taskHandler.DeleteTasks, authenticated, impersonate)
+	handle(http.MethodGet, "/api/tasks/upcoming", taskHandler.GetUpcomingTasks, authenticated, impersonate)
+	handle(http.MethodPost, "/api/tasks/status", taskHandler.GetStatuses, authenticated, impersonate)
+	handle(http.MethodGet, "/api/tasks/{id}", taskHandler.GetTaskById, taskHandler.OwnerOnly(), impersonate)
+	handle(http.MethodGet, "/api/tasks/{id}/wait", taskHandler.WaitForTask, taskHandler.OwnerOnly(), impersonate)
+	handle(http.MethodDelete, "/api/tasks/{id}", taskHandler.DeleteTaskById, taskHandler.OwnerOrAdmin(), impersonate)
+	handle(http.MethodGet, "/api/tasks/{id}/runs", taskHandler.GetRuns, taskHandler.OwnerOrAdmin(), impersonate)
+	handle(http.MethodGet, "/api/tasks/{id}/runs/{runId}", taskHandler.GetRunById, taskHandler.OwnerOrAdmin(), impersonate)
+	handle(http.MethodGet, "/api/tasks/{id}/events", taskHandler.GetEvents, taskHandler.OwnerOrAdmin(), impersonate)
+
+	//==============================================================================
+	//users
+	handle(http.MethodPost, "/api/users/", userHandler.CreateUser, adminOnly)
+	handle(http.MethodGet, "/api/users/", userHandler.ListUsers, adminOnly)
+	handle(http.MethodPost, "/api/users/login", userHandler.Login, public)
+	handle(http.MethodPost, "/api/users/signup", userHandler.Signup, public)
+	handle(http.MethodPost, "/api/users/verify", userHandler.Verify, public)
+	handle(http.MethodPost, "/api/users/verify/request", userHandler.RequestVerification, authenticated)
+	handle(http.MethodPost, "/api/users/password-reset", userHandler.ResetPassword, public)
+	handle(http.MethodPost, "/api/users/password-reset/request", userHandler.RequestPasswordReset, public)
+	handle(http.MethodPut, "/api/users/role/{id}", userHandler.UpdateRole, adminOnly)
+	handle(http.MethodPost, "/api/users/{id}/unlock", userHandler.Unlock, adminOnly)
+	handle(http.MethodGet, "/api/users/me", userHandler.GetMe, authenticated)
+	handle(http.MethodPut, "/api/users/me", userHandler.UpdateMe, authenticated)
+	handle(http.MethodGet, "/api/users/{id}", userHandler.GetUserById, users.SelfOrAdmin)
+	handle(http.MethodPut, "/api/users/{id}", userHandler.UpdateUser, users.SelfOrAdmin)
+	handle(http.MethodDelete, "/api/users/{id}", userHandler.DeleteUserById, users.SelfOrAdmin)
+	handle(http.MethodGet, "/api/users/{id}/tasks", taskHandler.GetTasksByUserId, users.SelfOrAdmin)
 
-	if err := scheduler.OnTaskRetry(); err != nil {
-		return nil, fmt.Errorf("on task retry consumer: %w", err)
+	//==============================================================================
+	//quotas
+	quotaHandler := quotas.Handler{
+		Validator:    conf.Validator,
+		QuotaService: quotaService,
+		UserService:  userService,
 	}
+	handle(http.MethodGet, "/api/users/{id}/quota", quotaHandler.GetQuota, users.SelfOrAdmin)
+	handle(http.MethodPut, "/api/users/{id}/quota", quotaHandler.UpdateQuota, adminOnly)
 
-	if err := scheduler.OnTaskFailure(); err != nil {
-		return nil, fmt.Errorf("on task failure: %w", err)
+	//==============================================================================
+	//usage
+	usageHandler := usageHandlers.Handler{
+		Validator:    conf.Validator,
+		UsageService: usageService,
+		UserService:  userService,
 	}
+	handle(http.MethodGet, "/api/usage", usageHandler.GetUsage, authenticated, impersonate)
+	handle(http.MethodGet, "/api/users/{id}/budget", usageHandler.GetBudget, users.SelfOrAdmin)
+	handle(http.MethodPut, "/api/users/{id}/budget", usageHandler.UpdateBudget, adminOnly)
 
-	if err := scheduler.MonitorScheduledTasks(); err != nil {
-		return nil, fmt.Errorf("monitor scheduled tasks: %w", err)
+	//==============================================================================
+	//secrets
+	secretHandler := secrets.Handler{
+		Validator:     conf.Validator,
+		SecretService: secretService,
 	}
+	handle(http.MethodGet, "/api/secrets/", secretHandler.GetSecrets, authenticated)
+	handle(http.MethodPut, "/api/secrets/{name}", secretHandler.SetSecret, authenticated)
+	handle(http.MethodDelete, "/api/secrets/{name}", secretHandler.DeleteSecret, authenticated)
 
-	//scheduler monitor
-	//long-lived goroutine
-	go func() {
-		//wait for server shutdown
-		<-conf.Shutdown
-		conf.Logger.Info("scheduler", "status", "received shutdown signal", "msg", "shutting down scheduler")
-		ctx, cancel := context.WithTimeout(context.Background(), conf.MaxTimeForSchedulerShutdown)
-		defer cancel()
+	//==============================================================================
+	//notifications
+	notificationHandler := notifications.Handler{
+		Validator:           conf.Validator,
+		NotificationService: notificationService,
+	}
+	handle(http.MethodGet, "/api/notifications/rules", notificationHandler.GetRules, authenticated)
+	handle(http.MethodPost, "/api/notifications/rules", notificationHandler.CreateRule, authenticated)
+	handle(http.MethodDelete, "/api/notifications/rules/{id}", notificationHandler.DeleteRule, authenticated)
 
-		if err := scheduler.Shutdown(ctx); err != nil {
-			conf.Logger.Error("scheduler", "status", "failed to gracfully shutdown", "msg", err)
-			return
-		}
-	}()
+	//==============================================================================
+	//views
+	viewHandler := views.Handler{
+		Validator:   conf.Validator,
+		ViewService: viewService,
+	}
+	handle(http.MethodGet, "/api/views", viewHandler.GetViews, authenticated)
+	handle(http.MethodPost, "/api/views", viewHandler.CreateView, authenticated)
+	handle(http.MethodDelete, "/api/views/{name}", viewHandler.DeleteView, authenticated)
 
 	//==============================================================================
-	//tasks
-	app.HandleFunc(http.MethodPost, version, "/api/tasks/", taskHandler.CreateTask, mid.Authenticate(auth))
-	app.HandleFunc(http.MethodGet, version, "/api/tasks/{id}", taskHandler.GetTaskById, mid.Authenticate(auth))
-	app.HandleFunc(http.MethodDelete, version, "/api/tasks/{id}", taskHandler.DeleteTaskById, mid.Authenticate(auth))
+	//presets
+	presetHandler := presets.Handler{
+		Validator:     conf.Validator,
+		PresetService: presetService,
+	}
+	handle(http.MethodGet, "/api/presets", presetHandler.GetPresets, authenticated)
+	handle(http.MethodGet, "/api/presets/{name}", presetHandler.GetPreset, authenticated)
+	handle(http.MethodPut, "/api/presets/{name}", presetHandler.SetPreset, adminOnly)
+	handle(http.MethodDelete, "/api/presets/{name}", presetHandler.DeletePreset, adminOnly)
 
 	//==============================================================================
-	//users
-	app.HandleFunc(http.MethodPost, version, "/api/users/", userHandler.CreateUser,
-		mid.Authenticate(auth),
-		mid.Authorized(auth, user.RoleAdmin),
-	)
+	//admin
+	adminHandler := admin.Handler{
+		Matrix:          &matrix,
+		Validator:       conf.Validator,
+		BlackoutService: blackoutService,
+		Scheduler:       scheduler,
+		TaskService:     taskService,
+		EffectiveConfig: conf.EffectiveConfig,
+	}
+	handle(http.MethodGet, "/api/admin/authz", adminHandler.Authorization, adminOnly)
+	handle(http.MethodPost, "/api/admin/users/{id}/disable", userHandler.DisableUser, adminOnly)
+	handle(http.MethodGet, "/api/admin/blackouts", adminHandler.GetBlackouts, adminOnly)
+	handle(http.MethodPost, "/api/admin/blackouts", adminHandler.CreateBlackout, adminOnly)
+	handle(http.MethodPut, "/api/admin/blackouts/pause", adminHandler.SetPause, adminOnly)
+	handle(http.MethodDelete, "/api/admin/blackouts/{id}", adminHandler.DeleteBlackout, adminOnly)
+	handle(http.MethodGet, "/api/admin/scheduler/status", adminHandler.SchedulerStatus, adminOnly)
+	handle(http.MethodPost, "/api/admin/scheduler/pause", adminHandler.PauseScheduler, adminOnly)
+	handle(http.MethodPost, "/api/admin/scheduler/resume", adminHandler.ResumeScheduler, adminOnly)
+	handle(http.MethodGet, "/api/admin/tasks/failed", adminHandler.RecentFailures, adminOnly)
+	if conf.EffectiveConfig != nil {
+		handle(http.MethodGet, "/api/admin/config", adminHandler.Config, adminOnly)
+	}
 
-	app.HandleFunc(http.MethodPost, version, "/api/users/login", userHandler.Login)
-	app.HandleFunc(http.MethodPost, version, "/api/users/signup", userHandler.Signup)
-	app.HandleFunc(http.MethodPut, version, "/api/users/role/{id}", userHandler.UpdateRole,
-		mid.Authenticate(auth),
-		mid.Authorized(auth, user.RoleAdmin))
+	//the dashboard is a static page next to the versioned api, it calls the admin routes with the token of an admin
+	matrix.Add(http.MethodGet, app.Path("", "/admin/"), public)
+	app.HandleFunc(http.MethodGet, "", "/admin/", dashboard.Index)
 
-	app.HandleFunc(http.MethodGet, version, "/api/users/{id}", userHandler.GetUserById)
-	app.HandleFunc(http.MethodPut, version, "/api/users/{id}", userHandler.UpdateUser, mid.Authenticate(auth))
-	app.HandleFunc(http.MethodDelete, version, "/api/users/{id}", userHandler.DeleteUserById, mid.Authenticate(auth))
+	//workers register themselves inside of redis
+	if workerService != nil {
+		adminHandler.WorkerService = workerService
+		handle(http.MethodGet, "/api/admin/workers", adminHandler.Workers, adminOnly)
+	}
 
-	return app, nil
+	return &API{App: app, scheduler: scheduler, notifications: notificationService, users: userService}, nil
 }
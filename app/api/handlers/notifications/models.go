@@ -0,0 +1,63 @@
+package notifications
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/notification"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+// Rule represents a notification rule that goes to client.
+type Rule struct {
+	Id        string    `json:"id"`
+	Statuses  []string  `json:"statuses"`
+	Image     string    `json:"image,omitempty"`
+	Channel   string    `json:"channel"`
+	Target    string    `json:"target"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func fromDomainRule(r notification.Rule) Rule {
+	statuses := make([]string, len(r.Statuses))
+	for i, status := range r.Statuses {
+		statuses[i] = status.String()
+	}
+
+	return Rule{
+		Id:        r.Id.String(),
+		Statuses:  statuses,
+		Image:     r.Image,
+		Channel:   string(r.Channel),
+		Target:    r.Target,
+		CreatedAt: r.CreatedAt,
+	}
+}
+
+// NewRule represents the data required for creating a rule sent by client.
+type NewRule struct {
+	Statuses []string `json:"statuses" validate:"required,min=1,dive,oneof=failed completed"`
+	Image    string   `json:"image" validate:"max=255"`
+	Channel  string   `json:"channel" validate:"required,oneof=email slack"`
+	Target   string   `json:"target" validate:"required,max=2048"`
+}
+
+func toDomainNewRule(userId uuid.UUID, nr NewRule) (notification.NewRule, error) {
+	statuses := make([]task.Status, len(nr.Statuses))
+	for i, name := range nr.Statuses {
+		status, err := task.ParseStatus(name)
+		if err != nil {
+			return notification.NewRule{}, fmt.Errorf("parse status: %w", err)
+		}
+		statuses[i] = status
+	}
+
+	return notification.NewRule{
+		UserId:   userId,
+		Statuses: statuses,
+		Image:    nr.Image,
+		Channel:  notification.Channel(nr.Channel),
+		Target:   nr.Target,
+	}, nil
+}
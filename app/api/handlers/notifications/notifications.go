@@ -0,0 +1,101 @@
+// Package notifications provides the handlers used for managing the notification rules of the authenticated user.
+package notifications
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/app/api/auth"
+	"github.com/hamidoujand/task-scheduler/app/api/errs"
+	"github.com/hamidoujand/task-scheduler/business/domain/notification"
+	"github.com/hamidoujand/task-scheduler/foundation/web"
+)
+
+// Handler represents set of notification rule handlers.
+type Handler struct {
+	Validator           *errs.AppValidator
+	NotificationService *notification.Service
+}
+
+// CreateRule creates a notification rule for the authenticated user.
+func (h *Handler) CreateRule(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	usr, err := auth.GetUser(ctx)
+	if err != nil {
+		return errs.NewAppCodeError(http.StatusUnauthorized, errs.CodeNotAuthenticated, "unauthorized")
+	}
+
+	var nr NewRule
+	if err := web.Decode(r, &nr); err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidJSON, "invalid json: %s", err)
+	}
+
+	fields, ok := h.Validator.Check(nr)
+	if !ok {
+		return errs.NewAppValidationError(http.StatusBadRequest, "invalid input", fields)
+	}
+
+	newRule, err := toDomainNewRule(usr.Id, nr)
+	if err != nil {
+		return errs.NewAppCodeError(http.StatusBadRequest, errs.CodeInvalidRule, err.Error())
+	}
+
+	rule, err := h.NotificationService.CreateRule(ctx, newRule)
+	if err != nil {
+		switch {
+		case errors.Is(err, notification.ErrChannelDisabled):
+			return errs.NewAppCodeErrorf(http.StatusServiceUnavailable, errs.CodeFeatureDisabled, "channel %q is not configured", nr.Channel)
+		case errors.Is(err, notification.ErrInvalidTarget), errors.Is(err, notification.ErrInvalidStatus):
+			return errs.NewAppCodeError(http.StatusBadRequest, errs.CodeInvalidRule, err.Error())
+		case errors.Is(err, notification.ErrTooManyRules):
+			return errs.NewAppCodeError(http.StatusConflict, errs.CodeLimitReached, err.Error())
+		}
+		return errs.NewAppInternalErr(err)
+	}
+
+	return web.Respond(ctx, w, http.StatusCreated, fromDomainRule(rule))
+}
+
+// GetRules returns the notification rules of the authenticated user.
+func (h *Handler) GetRules(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	usr, err := auth.GetUser(ctx)
+	if err != nil {
+		return errs.NewAppCodeError(http.StatusUnauthorized, errs.CodeNotAuthenticated, "unauthorized")
+	}
+
+	rules, err := h.NotificationService.Rules(ctx, usr.Id)
+	if err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+
+	resp := make([]Rule, len(rules))
+	for i, rule := range rules {
+		resp[i] = fromDomainRule(rule)
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, resp)
+}
+
+// DeleteRule deletes the notification rule in the "id" path value.
+func (h *Handler) DeleteRule(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	usr, err := auth.GetUser(ctx)
+	if err != nil {
+		return errs.NewAppCodeError(http.StatusUnauthorized, errs.CodeNotAuthenticated, "unauthorized")
+	}
+
+	id := r.PathValue("id")
+	ruleId, err := uuid.Parse(id)
+	if err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidId, "%q not a valid uuid", id)
+	}
+
+	if err := h.NotificationService.DeleteRule(ctx, usr.Id, ruleId); err != nil {
+		if errors.Is(err, notification.ErrRuleNotFound) {
+			return errs.NewAppCodeErrorf(http.StatusNotFound, errs.CodeRuleNotFound, "rule with id %q not found", id)
+		}
+		return errs.NewAppInternalErr(err)
+	}
+
+	return web.Respond(ctx, w, http.StatusNoContent, nil)
+}
@@ -0,0 +1,102 @@
+package presets
+
+import (
+	"strings"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/domain/preset"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+// Preset represents an execution preset that goes to client.
+type Preset struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Image       string            `json:"image"`
+	Environment map[string]string `json:"environment"`
+	CPUs        float64           `json:"cpus,omitempty"`
+	MemoryMB    int               `json:"memoryMb,omitempty"`
+	PidsLimit   int               `json:"pidsLimit,omitempty"`
+	Network     string            `json:"network,omitempty"`
+	Security    *SecurityProfile  `json:"security,omitempty"`
+	CreatedAt   time.Time         `json:"createdAt"`
+	UpdatedAt   time.Time         `json:"updatedAt"`
+}
+
+func fromDomainPreset(p preset.Preset) Preset {
+	env := make(map[string]string)
+	for _, v := range strings.Fields(p.Environment) {
+		key, val, _ := strings.Cut(v, "=")
+		env[key] = val
+	}
+
+	var security *SecurityProfile
+	if p.Security != nil {
+		sp := SecurityProfile(*p.Security)
+		security = &sp
+	}
+
+	return Preset{
+		Name:        p.Name,
+		Description: p.Description,
+		Image:       p.Image,
+		Environment: env,
+		CPUs:        p.Limits.CPUs,
+		MemoryMB:    p.Limits.MemoryMB,
+		PidsLimit:   p.Limits.Pids,
+		Network:     string(p.Network),
+		Security:    security,
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+	}
+}
+
+// SecurityProfile represents the hardening the tasks of a preset run with.
+type SecurityProfile struct {
+	ReadOnly        bool   `json:"readOnly"`
+	CapDropAll      bool   `json:"capDropAll"`
+	NoNewPrivileges bool   `json:"noNewPrivileges"`
+	User            string `json:"user,omitempty" validate:"max=64"`
+	Tmpfs           string `json:"tmpfs,omitempty" validate:"omitempty,startswith=/,max=256"`
+	TmpfsSizeMB     int    `json:"tmpfsSizeMb,omitempty" validate:"min=0"`
+}
+
+// SetPreset represents an execution preset sent by client, zero limits leave the resource unlimited, an empty
+// network and a missing security profile leave them to the defaults of the scheduler.
+type SetPreset struct {
+	Description string            `json:"description" validate:"max=256"`
+	Image       string            `json:"image" validate:"required"`
+	Environment map[string]string `json:"environment"`
+	CPUs        float64           `json:"cpus" validate:"min=0,max=64"`
+	MemoryMB    int               `json:"memoryMb" validate:"min=0"`
+	PidsLimit   int               `json:"pidsLimit" validate:"min=0"`
+	Network     string            `json:"network" validate:"omitempty,oneof=none internal egress"`
+	Security    *SecurityProfile  `json:"security"`
+}
+
+func toDomainNewPreset(sp SetPreset) preset.NewPreset {
+	var builder strings.Builder
+	for key, val := range sp.Environment {
+		builder.WriteString(key + "=" + val)
+		builder.WriteByte(' ')
+	}
+
+	var security *task.SecurityProfile
+	if sp.Security != nil {
+		profile := task.SecurityProfile(*sp.Security)
+		security = &profile
+	}
+
+	return preset.NewPreset{
+		Description: sp.Description,
+		Image:       sp.Image,
+		Environment: builder.String(),
+		Limits: preset.Limits{
+			CPUs:     sp.CPUs,
+			MemoryMB: sp.MemoryMB,
+			Pids:     sp.PidsLimit,
+		},
+		Network:  task.NetworkMode(sp.Network),
+		Security: security,
+	}
+}
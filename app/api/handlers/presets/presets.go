@@ -0,0 +1,86 @@
+// Package presets provides the handlers used for managing the execution presets tasks run in.
+package presets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/hamidoujand/task-scheduler/app/api/errs"
+	"github.com/hamidoujand/task-scheduler/business/domain/preset"
+	"github.com/hamidoujand/task-scheduler/foundation/web"
+)
+
+// Handler represents set of preset handlers.
+type Handler struct {
+	Validator     *errs.AppValidator
+	PresetService *preset.Service
+}
+
+// SetPreset creates or replaces the preset in the "name" path value.
+func (h *Handler) SetPreset(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var sp SetPreset
+	if err := web.Decode(r, &sp); err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidJSON, "invalid json: %s", err)
+	}
+
+	fields, ok := h.Validator.Check(sp)
+	if !ok {
+		return errs.NewAppValidationError(http.StatusBadRequest, "invalid input", fields)
+	}
+
+	p, err := h.PresetService.SetPreset(ctx, r.PathValue("name"), toDomainNewPreset(sp))
+	if err != nil {
+		switch {
+		case errors.Is(err, preset.ErrInvalidName), errors.Is(err, preset.ErrInvalidNetwork), errors.Is(err, preset.ErrInvalidLimits),
+			errors.Is(err, preset.ErrInvalidSecurity):
+			return errs.NewAppCodeError(http.StatusBadRequest, errs.CodeInvalidPreset, err.Error())
+		}
+		return errs.NewAppInternalErr(err)
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, fromDomainPreset(p))
+}
+
+// GetPresets returns all of the presets.
+func (h *Handler) GetPresets(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	presets, err := h.PresetService.GetPresets(ctx)
+	if err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+
+	resp := make([]Preset, len(presets))
+	for i, p := range presets {
+		resp[i] = fromDomainPreset(p)
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, resp)
+}
+
+// GetPreset returns the preset in the "name" path value.
+func (h *Handler) GetPreset(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	name := r.PathValue("name")
+
+	p, err := h.PresetService.GetPreset(ctx, name)
+	if err != nil {
+		if errors.Is(err, preset.ErrPresetNotFound) {
+			return errs.NewAppCodeErrorf(http.StatusNotFound, errs.CodePresetNotFound, "preset %q not found", name)
+		}
+		return errs.NewAppInternalErr(err)
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, fromDomainPreset(p))
+}
+
+// DeletePreset deletes the preset in the "name" path value.
+func (h *Handler) DeletePreset(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	name := r.PathValue("name")
+	if err := h.PresetService.DeletePreset(ctx, name); err != nil {
+		if errors.Is(err, preset.ErrPresetNotFound) {
+			return errs.NewAppCodeErrorf(http.StatusNotFound, errs.CodePresetNotFound, "preset %q not found", name)
+		}
+		return errs.NewAppInternalErr(err)
+	}
+
+	return web.Respond(ctx, w, http.StatusNoContent, nil)
+}
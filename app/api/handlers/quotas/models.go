@@ -0,0 +1,37 @@
+package quotas
+
+import (
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/domain/quota"
+)
+
+// Quota represents the limits of a user that goes to client, zero means unlimited.
+type Quota struct {
+	UserId     string    `json:"userId"`
+	MaxPending int       `json:"maxPending"`
+	MaxRunning int       `json:"maxRunning"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+func fromDomainQuota(q quota.Quota) Quota {
+	return Quota{
+		UserId:     q.UserId.String(),
+		MaxPending: q.MaxPending,
+		MaxRunning: q.MaxRunning,
+		UpdatedAt:  q.UpdatedAt,
+	}
+}
+
+// UpdateQuota represents the limits an admin can update, zero means unlimited.
+type UpdateQuota struct {
+	MaxPending *int `json:"maxPending" validate:"omitempty,min=0"`
+	MaxRunning *int `json:"maxRunning" validate:"omitempty,min=0"`
+}
+
+func (uq UpdateQuota) toServiceUpdateQuota() quota.UpdateQuota {
+	return quota.UpdateQuota{
+		MaxPending: uq.MaxPending,
+		MaxRunning: uq.MaxRunning,
+	}
+}
@@ -0,0 +1,82 @@
+// Package quotas provides the handlers used for managing the task quotas of users.
+package quotas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/app/api/errs"
+	"github.com/hamidoujand/task-scheduler/business/domain/quota"
+	"github.com/hamidoujand/task-scheduler/business/domain/user"
+	"github.com/hamidoujand/task-scheduler/foundation/web"
+)
+
+// Handler represents set of quota handlers.
+type Handler struct {
+	Validator    *errs.AppValidator
+	QuotaService *quota.Service
+	UserService  *user.Service
+}
+
+// GetQuota returns the quota of the user in the "id" path value.
+func (h *Handler) GetQuota(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	userId, err := h.pathUser(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	q, err := h.QuotaService.GetQuota(ctx, userId)
+	if err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, fromDomainQuota(q))
+}
+
+// UpdateQuota updates the quota of the user in the "id" path value.
+func (h *Handler) UpdateQuota(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	userId, err := h.pathUser(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	var uq UpdateQuota
+	if err := web.Decode(r, &uq); err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidJSON, "invalid json: %s", err)
+	}
+
+	fields, ok := h.Validator.Check(uq)
+	if !ok {
+		return errs.NewAppValidationError(http.StatusBadRequest, "invalid input", fields)
+	}
+
+	q, err := h.QuotaService.SetQuota(ctx, userId, uq.toServiceUpdateQuota())
+	if err != nil {
+		if errors.Is(err, quota.ErrInvalidQuota) {
+			return errs.NewAppCodeError(http.StatusBadRequest, errs.CodeInvalidQuota, err.Error())
+		}
+		return errs.NewAppInternalErr(err)
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, fromDomainQuota(q))
+}
+
+// pathUser parses the "id" path value and makes sure the user exists.
+func (h *Handler) pathUser(ctx context.Context, r *http.Request) (uuid.UUID, error) {
+	id := r.PathValue("id")
+
+	userId, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.UUID{}, errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidId, "%q not a valid uuid", id)
+	}
+
+	if _, err := h.UserService.GetUserById(ctx, userId); err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return uuid.UUID{}, errs.NewAppCodeErrorf(http.StatusNotFound, errs.CodeUserNotFound, "user with id %q not found", id)
+		}
+		return uuid.UUID{}, errs.NewAppInternalErr(err)
+	}
+	return userId, nil
+}
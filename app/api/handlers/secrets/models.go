@@ -0,0 +1,29 @@
+package secrets
+
+import (
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/domain/secret"
+)
+
+// Secret represents a secret that goes to client, its value is never returned.
+type Secret struct {
+	Name      string    `json:"name"`
+	Ref       string    `json:"ref"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func fromDomainSecret(s secret.Secret) Secret {
+	return Secret{
+		Name:      s.Name,
+		Ref:       secret.RefPrefix + s.Name,
+		CreatedAt: s.CreatedAt,
+		UpdatedAt: s.UpdatedAt,
+	}
+}
+
+// SetSecret represents the value of a secret sent by client.
+type SetSecret struct {
+	Value string `json:"value" validate:"required,max=4096"`
+}
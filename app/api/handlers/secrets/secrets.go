@@ -0,0 +1,85 @@
+// Package secrets provides the handlers used for managing the secrets of the authenticated user.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/hamidoujand/task-scheduler/app/api/auth"
+	"github.com/hamidoujand/task-scheduler/app/api/errs"
+	"github.com/hamidoujand/task-scheduler/business/domain/secret"
+	"github.com/hamidoujand/task-scheduler/foundation/web"
+)
+
+// Handler represents set of secret handlers.
+type Handler struct {
+	Validator     *errs.AppValidator
+	SecretService *secret.Service
+}
+
+// SetSecret creates or replaces the secret in the "name" path value.
+func (h *Handler) SetSecret(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	usr, err := auth.GetUser(ctx)
+	if err != nil {
+		return errs.NewAppCodeError(http.StatusUnauthorized, errs.CodeNotAuthenticated, "unauthorized")
+	}
+
+	var ss SetSecret
+	if err := web.Decode(r, &ss); err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidJSON, "invalid json: %s", err)
+	}
+
+	fields, ok := h.Validator.Check(ss)
+	if !ok {
+		return errs.NewAppValidationError(http.StatusBadRequest, "invalid input", fields)
+	}
+
+	sec, err := h.SecretService.SetSecret(ctx, usr.Id, r.PathValue("name"), ss.Value)
+	if err != nil {
+		if errors.Is(err, secret.ErrInvalidName) {
+			return errs.NewAppCodeError(http.StatusBadRequest, errs.CodeInvalidSecret, err.Error())
+		}
+		return errs.NewAppInternalErr(err)
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, fromDomainSecret(sec))
+}
+
+// GetSecrets returns the secrets of the authenticated user without their values.
+func (h *Handler) GetSecrets(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	usr, err := auth.GetUser(ctx)
+	if err != nil {
+		return errs.NewAppCodeError(http.StatusUnauthorized, errs.CodeNotAuthenticated, "unauthorized")
+	}
+
+	secrets, err := h.SecretService.ListSecrets(ctx, usr.Id)
+	if err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+
+	resp := make([]Secret, len(secrets))
+	for i, sec := range secrets {
+		resp[i] = fromDomainSecret(sec)
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, resp)
+}
+
+// DeleteSecret deletes the secret in the "name" path value.
+func (h *Handler) DeleteSecret(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	usr, err := auth.GetUser(ctx)
+	if err != nil {
+		return errs.NewAppCodeError(http.StatusUnauthorized, errs.CodeNotAuthenticated, "unauthorized")
+	}
+
+	name := r.PathValue("name")
+	if err := h.SecretService.DeleteSecret(ctx, usr.Id, name); err != nil {
+		if errors.Is(err, secret.ErrSecretNotFound) {
+			return errs.NewAppCodeErrorf(http.StatusNotFound, errs.CodeSecretNotFound, "secret %q not found", name)
+		}
+		return errs.NewAppInternalErr(err)
+	}
+
+	return web.Respond(ctx, w, http.StatusNoContent, nil)
+}
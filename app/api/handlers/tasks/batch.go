@@ -0,0 +1,90 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/hamidoujand/task-scheduler/app/api/auth"
+	"github.com/hamidoujand/task-scheduler/app/api/errs"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	"github.com/hamidoujand/task-scheduler/foundation/web"
+)
+
+// CreateTasks creates a batch of tasks for the authenticated user, either all of them are created or none. The fields
+// of an invalid task are reported under "tasks[i]".
+func (h *Handler) CreateTasks(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	usr, err := auth.GetUser(ctx)
+	if err != nil {
+		return errs.NewAppCodeError(http.StatusUnauthorized, errs.CodeNotAuthenticated, "unauthorized")
+	}
+
+	var batch NewTasks
+	if err := web.Decode(r, &batch); err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidJSON, "invalid json: %s", err)
+	}
+
+	if fields, ok := h.Validator.Check(batch); !ok {
+		return errs.NewAppValidationError(http.StatusBadRequest, "invalid input", fields)
+	}
+
+	domainTasks := make([]task.NewTask, len(batch.Tasks))
+	for i, newTask := range batch.Tasks {
+		if fields, ok := h.Validator.Check(newTask); !ok {
+			return errs.NewAppValidationError(http.StatusBadRequest, "invalid input", inBatch(i, fields))
+		}
+
+		//the keys are not looked up, a batch is created as a whole
+		if newTask.DedupKey != "" {
+			return errs.NewAppValidationError(http.StatusBadRequest, "invalid input", inBatch(i, map[string]string{
+				"dedupKey": "dedupKey is not supported in batches",
+			}))
+		}
+
+		domainTask, err := h.toDomainNewTask(ctx, usr.Id, newTask)
+		if err != nil {
+			var appErr *errs.AppError
+			if errors.As(err, &appErr) && len(appErr.Fields) > 0 {
+				appErr.Fields = inBatch(i, appErr.Fields)
+			}
+			return err
+		}
+		domainTasks[i] = domainTask
+	}
+
+	if err := h.checkPendingQuota(ctx, usr.Id, len(domainTasks)); err != nil {
+		return err
+	}
+
+	warning, err := h.checkBudget(ctx, usr.Id)
+	if err != nil {
+		return err
+	}
+
+	tsks, err := h.TaskService.CreateTasks(ctx, domainTasks)
+	if err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+
+	resp := make([]Task, len(tsks))
+	for i, tsk := range tsks {
+		h.recordCreated(ctx, tsk)
+		resp[i] = fromDomainTask(tsk)
+	}
+
+	if warning != "" {
+		w.Header().Set(budgetWarningHeader, warning)
+	}
+
+	return web.Respond(ctx, w, http.StatusCreated, resp)
+}
+
+// inBatch prefixes the invalid fields with the index of their task inside of the batch.
+func inBatch(i int, fields map[string]string) map[string]string {
+	prefixed := make(map[string]string, len(fields))
+	for field, msg := range fields {
+		prefixed[fmt.Sprintf("tasks[%d].%s", i, field)] = msg
+	}
+	return prefixed
+}
@@ -0,0 +1,40 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/app/api/errs"
+)
+
+// budgetWarningHeader carries the warning of a created task whose user used up most of their budget.
+const budgetWarningHeader = "X-Budget-Warning"
+
+// checkBudget rejects the task once the user used up their hard budget of the month, the returned warning is not
+// empty once they used up WarningRatio of it or all of a soft one.
+func (h *Handler) checkBudget(ctx context.Context, userId uuid.UUID) (string, error) {
+	if h.UsageService == nil {
+		return "", nil
+	}
+
+	status, err := h.UsageService.GetBudgetStatus(ctx, userId)
+	if err != nil {
+		return "", errs.NewAppInternalErr(err)
+	}
+
+	if status.Exceeded() && status.Hard {
+		return "", errs.NewAppCodeErrorf(http.StatusTooManyRequests, errs.CodeBudgetExceeded,
+			"budget exceeded: the execution time of %s for %s is used up", status.Limit, status.Month.Format("2006-01"))
+	}
+
+	if !status.Warning() {
+		return "", nil
+	}
+
+	return fmt.Sprintf("%d%% of the execution time budget of %s for %s is used up", int(math.Floor(status.Ratio()*100)),
+		status.Limit.Round(time.Second), status.Month.Format("2006-01")), nil
+}
@@ -0,0 +1,67 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/app/api/auth"
+	"github.com/hamidoujand/task-scheduler/app/api/errs"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	"github.com/hamidoujand/task-scheduler/foundation/web"
+)
+
+// DeleteTasks deletes the finished tasks of the authenticated user in bulk, "status" limits it to a comma separated
+// list of finished statuses and "before" to the tasks created before a date or an RFC3339 time.
+func (h *Handler) DeleteTasks(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	usr, err := auth.GetUser(ctx)
+	if err != nil {
+		return errs.NewAppCodeError(http.StatusUnauthorized, errs.CodeNotAuthenticated, "unauthorized")
+	}
+
+	filter, err := parseDeleteFilter(r)
+	if err != nil {
+		return errs.NewAppCodeError(http.StatusBadRequest, errs.CodeInvalidQuery, err.Error())
+	}
+
+	deleted, err := h.TaskService.DeleteFinishedTasks(ctx, usr.Id, filter)
+	if err != nil {
+		if errors.Is(err, task.ErrNotFinished) {
+			return errs.NewAppCodeError(http.StatusBadRequest, errs.CodeTaskNotFinished, err.Error())
+		}
+		return errs.NewAppInternalErr(err)
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, DeletedTasks{Deleted: deleted})
+}
+
+func parseDeleteFilter(r *http.Request) (task.DeleteFilter, error) {
+	var filter task.DeleteFilter
+	query := r.URL.Query()
+
+	if raw := query.Get("status"); raw != "" {
+		for _, s := range strings.Split(raw, ",") {
+			status, err := task.ParseStatus(strings.TrimSpace(s))
+			if err != nil {
+				return task.DeleteFilter{}, fmt.Errorf("unknown status: %q", s)
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+
+	if raw := query.Get("before"); raw != "" {
+		before, err := time.Parse(time.DateOnly, raw)
+		if err != nil {
+			before, err = time.Parse(time.RFC3339, raw)
+			if err != nil {
+				return task.DeleteFilter{}, fmt.Errorf("invalid before %q, expected a date or RFC3339", raw)
+			}
+		}
+		filter.Before = before
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,43 @@
+package tasks
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/app/api/errs"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	"github.com/hamidoujand/task-scheduler/foundation/web"
+)
+
+// GetEvents returns the timeline of the task, oldest first.
+func (h *Handler) GetEvents(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	taskId := r.PathValue("id")
+
+	taskUUID, err := uuid.Parse(taskId)
+	if err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidId, "%q not a valid uuid", taskId)
+	}
+
+	events, err := h.TaskService.GetEventsByTaskId(ctx, taskUUID)
+	if err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+
+	appEvents := make([]Event, len(events))
+	for i, event := range events {
+		appEvents[i] = fromDomainEvent(event)
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, appEvents)
+}
+
+// recordCreated starts the timeline of the new task, tasks due soon are queued right away. The task exists either
+// way so failures are ignored, the timeline is best effort.
+func (h *Handler) recordCreated(ctx context.Context, tsk task.Task) {
+	_, _ = h.TaskService.CreateEvent(ctx, task.NewEvent{TaskId: tsk.Id, Kind: task.EventCreated, Source: task.EventSourceAPI})
+
+	if tsk.Status == task.StatusQueued {
+		_, _ = h.TaskService.CreateEvent(ctx, task.NewEvent{TaskId: tsk.Id, Kind: task.EventQueued, Source: task.EventSourceAPI})
+	}
+}
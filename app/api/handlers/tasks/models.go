@@ -1,9 +1,12 @@
 package tasks
 
 import (
+	"encoding/base64"
+	"fmt"
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/hamidoujand/task-scheduler/business/domain/task"
 )
 
@@ -13,14 +16,47 @@ type Task struct {
 	UserId      string            `json:"user_id"`
 	Command     string            `json:"command"`
 	Args        []string          `json:"args"`
+	Steps       []Step            `json:"steps,omitempty"`
 	Image       string            `json:"image"`
+	ImageDigest string            `json:"imageDigest,omitempty"`
+	FloatingTag bool              `json:"floatingTag"`
+	Preset      string            `json:"preset,omitempty"`
+	NetworkMode string            `json:"networkMode,omitempty"`
+	PullPolicy  string            `json:"pullPolicy,omitempty"`
+	WorkDir     string            `json:"workDir,omitempty"`
+	RunAsUser   string            `json:"runAsUser,omitempty"`
 	Environment map[string]string `json:"environment"`
 	Status      string            `json:"status"`
 	Result      string            `json:"result,omitempty"`
 	ErrMessage  string            `json:"errorMsg,omitempty"`
+	ErrCategory string            `json:"errorCategory,omitempty"`
 	ScheduledAt time.Time         `json:"scheduledAt"`
 	CreatedAt   time.Time         `json:"createdAt"`
 	UpdatedAt   time.Time         `json:"updatedAt"`
+	Version     int               `json:"version"`
+	EnqueuedAt  *time.Time        `json:"enqueuedAt,omitempty"`
+	//StartedAt and FinishedAt belong to the last execution, QueueLatencyMs is how long it waited after
+	//ScheduledAt before it started.
+	StartedAt      *time.Time `json:"startedAt,omitempty"`
+	FinishedAt     *time.Time `json:"finishedAt,omitempty"`
+	QueueLatencyMs int64      `json:"queueLatencyMs,omitempty"`
+	DurationMs     int64      `json:"durationMs,omitempty"`
+	MaxRetries     *int       `json:"maxRetries,omitempty"`
+	RetryOn        []int      `json:"retryOn,omitempty"`
+	//ResultOffloaded is true when the result is kept in object storage, only GET /tasks/{id} returns it.
+	ResultOffloaded bool `json:"resultOffloaded,omitempty"`
+	//ResultSize is the size of the output in bytes, the result is truncated when it is larger than the limit.
+	ResultSize int `json:"resultSize,omitempty"`
+	//InputSize is the size of the input piped to the command in bytes, the input itself is not returned.
+	InputSize int    `json:"inputSize,omitempty"`
+	DedupKey  string `json:"dedupKey,omitempty"`
+}
+
+// Step represents one of the commands of a multi-step task with what it printed during the last execution.
+type Step struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+	Output  string   `json:"output,omitempty"`
 }
 
 func fromDomainTask(t task.Task) Task {
@@ -32,27 +68,289 @@ func fromDomainTask(t task.Task) Task {
 		envMap[parts[0]] = parts[1]
 	}
 
+	var steps []Step
+	for _, step := range t.Steps {
+		steps = append(steps, Step{Command: step.Command, Args: step.Args, Output: step.Output})
+	}
+
+	var enqueuedAt, startedAt, finishedAt *time.Time
+	if !t.EnqueuedAt.IsZero() {
+		local := t.EnqueuedAt.Local()
+		enqueuedAt = &local
+	}
+	if !t.StartedAt.IsZero() {
+		local := t.StartedAt.Local()
+		startedAt = &local
+	}
+	if !t.FinishedAt.IsZero() {
+		local := t.FinishedAt.Local()
+		finishedAt = &local
+	}
+
 	return Task{
 		Id:          t.Id.String(),
 		UserId:      t.UserId.String(),
 		Command:     t.Command,
 		Args:        t.Args,
+		Steps:       steps,
 		Image:       t.Image,
+		ImageDigest: t.ImageDigest,
+		FloatingTag: t.FloatingTag,
+		Preset:      t.Preset,
+		NetworkMode: string(t.NetworkMode),
+		PullPolicy:  string(t.PullPolicy),
+		WorkDir:     t.WorkDir,
+		RunAsUser:   t.RunAsUser,
 		Environment: envMap,
 		Status:      t.Status.String(),
 		Result:      t.Result,
 		ErrMessage:  t.ErrMessage,
+		ErrCategory: string(t.ErrCategory),
 		ScheduledAt: t.ScheduledAt,
 		CreatedAt:   t.CreatedAt.Local(),
 		UpdatedAt:   t.UpdatedAt.Local(),
+		Version:     t.Version,
+		EnqueuedAt:  enqueuedAt,
+
+		StartedAt:      startedAt,
+		FinishedAt:     finishedAt,
+		QueueLatencyMs: t.QueueLatency.Milliseconds(),
+		DurationMs:     t.Duration().Milliseconds(),
+		MaxRetries:     t.MaxRetries,
+		RetryOn:        t.RetryOn,
+
+		ResultOffloaded: t.ResultRef != "",
+		ResultSize:      t.ResultSize,
+		InputSize:       len(t.Input),
+		DedupKey:        t.DedupKey,
 	}
 }
 
+func toAppTasks(tasks []task.Task) []Task {
+	appTasks := make([]Task, len(tasks))
+	for i, t := range tasks {
+		appTasks[i] = fromDomainTask(t)
+	}
+	return appTasks
+}
+
+// TaskPage is a page of tasks returned by cursor pagination, NextCursor is passed as "after" to get the next page
+// and is empty on the last one.
+type TaskPage struct {
+	Tasks      []Task `json:"tasks"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// DeletedTasks represents how many tasks were deleted in bulk.
+type DeletedTasks struct {
+	Deleted int `json:"deleted"`
+}
+
+// Upcoming represents the pending tasks scheduled between From and To grouped into windows.
+type Upcoming struct {
+	From    time.Time        `json:"from"`
+	To      time.Time        `json:"to"`
+	Windows []UpcomingWindow `json:"windows"`
+}
+
+// UpcomingWindow represents the tasks scheduled in [Start, End).
+type UpcomingWindow struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Tasks []Task    `json:"tasks"`
+}
+
 // NewTask represents data required for task creation.
 type NewTask struct {
-	Command     string            `json:"command" validate:"required,ascii,commonCommands"`
+	Command     string            `json:"command" validate:"required_without=Steps,excluded_with=Steps,ascii,commonCommands"`
 	Args        []string          `json:"args" validate:"commonArgs"`
-	Image       string            `json:"image" validate:"required"`
+	Steps       []NewStep         `json:"steps" validate:"omitempty,max=20,dive"`
+	Image       string            `json:"image" validate:"required_without=Preset,excluded_with=Preset"`
+	Preset      string            `json:"preset" validate:"max=64"`
+	NetworkMode string            `json:"networkMode" validate:"omitempty,oneof=none internal egress"`
+	PullPolicy  string            `json:"pullPolicy" validate:"omitempty,oneof=always if-not-present"`
+	WorkDir     string            `json:"workDir" validate:"omitempty,max=256,workDir"`
+	RunAsUser   string            `json:"runAsUser" validate:"omitempty,runAsUser"`
+	FloatingTag bool              `json:"floatingTag"`
 	Environment map[string]string `json:"environment"`
 	ScheduledAt time.Time         `json:"scheduledAt" validate:"required,validScheduledAt"`
+	MaxRetries  *int              `json:"maxRetries" validate:"omitempty,min=0"`
+	RetryOn     []int             `json:"retryOn" validate:"omitempty,dive,min=1,max=255"`
+	//Input is piped to the command, binary input is sent base64 encoded with InputEncoding "base64".
+	Input         string `json:"input"`
+	InputEncoding string `json:"inputEncoding" validate:"omitempty,oneof=text base64"`
+	//DedupKey returns the task of an earlier request with the same key instead of creating a duplicate.
+	DedupKey string `json:"dedupKey" validate:"omitempty,max=200"`
+}
+
+// NewTasks represents a batch of tasks that are created together, either all of them or none.
+type NewTasks struct {
+	Tasks []NewTask `json:"tasks" validate:"required,min=1,max=100"`
+}
+
+// maxArgsBytes, maxEnvBytes and maxInputBytes bound the args, including the ones of the steps, the environment and
+// the decoded input of a task since they are stored inline with it.
+const (
+	maxArgsBytes  = 32 * 1024
+	maxEnvBytes   = 32 * 1024
+	maxInputBytes = 256 * 1024
+)
+
+// decodeInput returns the bytes piped to the command of the task.
+func (nt NewTask) decodeInput() ([]byte, error) {
+	if nt.InputEncoding != "base64" {
+		return []byte(nt.Input), nil
+	}
+
+	input, err := base64.StdEncoding.DecodeString(nt.Input)
+	if err != nil {
+		return nil, fmt.Errorf("input must be valid base64: %w", err)
+	}
+	return input, nil
+}
+
+// checkSizes returns the fields of the task that are larger than they are allowed to be.
+func (nt NewTask) checkSizes() map[string]string {
+	fields := make(map[string]string)
+
+	argsSize := 0
+	for _, arg := range nt.Args {
+		argsSize += len(arg)
+	}
+	for _, step := range nt.Steps {
+		for _, arg := range step.Args {
+			argsSize += len(arg)
+		}
+	}
+	if argsSize > maxArgsBytes {
+		fields["args"] = fmt.Sprintf("args must be at most %d bytes in total, got %d", maxArgsBytes, argsSize)
+	}
+
+	envSize := 0
+	for key, val := range nt.Environment {
+		envSize += len(key) + len(val) + 1
+	}
+	if envSize > maxEnvBytes {
+		fields["environment"] = fmt.Sprintf("environment must be at most %d bytes in total, got %d", maxEnvBytes, envSize)
+	}
+
+	if input, err := nt.decodeInput(); err == nil && len(input) > maxInputBytes {
+		fields["input"] = fmt.Sprintf("input must be at most %d bytes, got %d", maxInputBytes, len(input))
+	}
+	return fields
+}
+
+// NewStep represents one of the commands of a multi-step task.
+type NewStep struct {
+	Command string   `json:"command" validate:"required,ascii,commonCommands"`
+	Args    []string `json:"args" validate:"commonArgs"`
+}
+
+// Environment represents the fingerprint of the executor that ran a task.
+type Environment struct {
+	WorkerId      string `json:"workerId"`
+	WorkerBuild   string `json:"workerBuild"`
+	Runtime       string `json:"runtime,omitempty"`
+	DockerVersion string `json:"dockerVersion"`
+	OS            string `json:"os"`
+	Arch          string `json:"arch"`
+	ImageDigest   string `json:"imageDigest"`
+}
+
+// SecurityProfile represents the hardening the container of an execution attempt ran with.
+type SecurityProfile struct {
+	ReadOnly        bool   `json:"readOnly"`
+	CapDropAll      bool   `json:"capDropAll"`
+	NoNewPrivileges bool   `json:"noNewPrivileges"`
+	User            string `json:"user,omitempty"`
+	Tmpfs           string `json:"tmpfs,omitempty"`
+	TmpfsSizeMB     int    `json:"tmpfsSizeMb,omitempty"`
+}
+
+// Run represents an execution attempt of a task that goes to client.
+type Run struct {
+	Id          string          `json:"id"`
+	TaskId      string          `json:"taskId"`
+	Status      string          `json:"status"`
+	ErrMessage  string          `json:"errorMsg,omitempty"`
+	Environment Environment     `json:"environment"`
+	Security    SecurityProfile `json:"security"`
+	CreatedAt   time.Time       `json:"createdAt"`
+}
+
+func fromDomainRun(r task.Run) Run {
+	return Run{
+		Id:          r.Id.String(),
+		TaskId:      r.TaskId.String(),
+		Status:      r.Status.String(),
+		ErrMessage:  r.ErrMessage,
+		Environment: Environment(r.Environment),
+		Security:    SecurityProfile(r.Security),
+		CreatedAt:   r.CreatedAt.Local(),
+	}
+}
+
+// StatusQuery represents the ids of the tasks whose statuses are asked for at once.
+type StatusQuery struct {
+	Ids []string `json:"ids" validate:"required,min=1,max=500,dive,uuid"`
+}
+
+// TaskStatus represents the status of a task without the rest of it.
+type TaskStatus struct {
+	Id        string    `json:"id"`
+	Status    string    `json:"status"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// TaskStatuses represents the statuses of the asked tasks, NotFound are the ids of the tasks that do not exist or
+// belong to someone else.
+type TaskStatuses struct {
+	Tasks    []TaskStatus `json:"tasks"`
+	NotFound []string     `json:"notFound"`
+}
+
+func toAppStatuses(ids []uuid.UUID, statuses []task.StatusSummary) TaskStatuses {
+	found := make(map[uuid.UUID]bool, len(statuses))
+
+	result := TaskStatuses{
+		Tasks:    make([]TaskStatus, len(statuses)),
+		NotFound: []string{},
+	}
+	for i, st := range statuses {
+		found[st.Id] = true
+		result.Tasks[i] = TaskStatus{
+			Id:        st.Id.String(),
+			Status:    st.Status.String(),
+			UpdatedAt: st.UpdatedAt.Local(),
+		}
+	}
+
+	for _, id := range ids {
+		if !found[id] {
+			result.NotFound = append(result.NotFound, id.String())
+			found[id] = true
+		}
+	}
+	return result
+}
+
+// Event represents an entry of the timeline of a task that goes to client.
+type Event struct {
+	Id        string    `json:"id"`
+	TaskId    string    `json:"taskId"`
+	Kind      string    `json:"kind"`
+	Message   string    `json:"message,omitempty"`
+	Source    string    `json:"source"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func fromDomainEvent(e task.Event) Event {
+	return Event{
+		Id:        e.Id.String(),
+		TaskId:    e.TaskId.String(),
+		Kind:      string(e.Kind),
+		Message:   e.Message,
+		Source:    e.Source,
+		CreatedAt: e.CreatedAt.Local(),
+	}
 }
@@ -1,9 +1,7 @@
 package tasks
 
 import (
-	"fmt"
 	"net/http"
-	"strings"
 
 	"github.com/hamidoujand/task-scheduler/business/domain/task"
 )
@@ -20,37 +18,5 @@ func parseOrder(r *http.Request) (task.OrderBy, error) {
 		return deafultOrder, nil
 	}
 
-	parts := strings.Split(orderString, ",")
-
-	var order task.OrderBy
-
-	switch len(parts) {
-	case 1:
-		fieldString := parts[0]
-		field, err := task.ParseField(fieldString)
-		if err != nil {
-			return task.OrderBy{}, fmt.Errorf("unknown field: %q", fieldString)
-		}
-		order.Field = field
-		order.Direction = task.DirectionASC
-	case 2:
-		fieldString := parts[0]
-
-		field, err := task.ParseField(fieldString)
-		if err != nil {
-			return task.OrderBy{}, fmt.Errorf("unknown field: %q", fieldString)
-		}
-
-		dirString := parts[1]
-
-		dir, err := task.ParseDirection(dirString)
-		if err != nil {
-			return task.OrderBy{}, fmt.Errorf("unknown direction: %q", dirString)
-		}
-
-		order.Field = field
-		order.Direction = dir
-	}
-
-	return order, nil
+	return task.ParseOrderBy(orderString)
 }
@@ -0,0 +1,61 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/app/api/errs"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	"github.com/hamidoujand/task-scheduler/foundation/web"
+)
+
+// GetRuns returns all of the execution attempts of the task along with the environment they ran in.
+func (h *Handler) GetRuns(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	taskId := r.PathValue("id")
+
+	taskUUID, err := uuid.Parse(taskId)
+	if err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidId, "%q not a valid uuid", taskId)
+	}
+
+	runs, err := h.TaskService.GetRunsByTaskId(ctx, taskUUID)
+	if err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+
+	appRuns := make([]Run, len(runs))
+	for i, run := range runs {
+		appRuns[i] = fromDomainRun(run)
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, appRuns)
+}
+
+// GetRunById returns a single execution attempt of the task.
+func (h *Handler) GetRunById(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	taskId := r.PathValue("id")
+
+	taskUUID, err := uuid.Parse(taskId)
+	if err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidId, "%q not a valid uuid", taskId)
+	}
+
+	runId := r.PathValue("runId")
+
+	runUUID, err := uuid.Parse(runId)
+	if err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidId, "%q not a valid uuid", runId)
+	}
+
+	run, err := h.TaskService.GetRunById(ctx, taskUUID, runUUID)
+	if err != nil {
+		if errors.Is(err, task.ErrRunNotFound) {
+			return errs.NewAppCodeErrorf(http.StatusNotFound, errs.CodeRunNotFound, "run with id %q not found", runId)
+		}
+		return errs.NewAppInternalErr(err)
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, fromDomainRun(run))
+}
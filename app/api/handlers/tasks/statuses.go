@@ -0,0 +1,41 @@
+package tasks
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/app/api/auth"
+	"github.com/hamidoujand/task-scheduler/app/api/errs"
+	"github.com/hamidoujand/task-scheduler/foundation/web"
+)
+
+// GetStatuses returns the status of every task of the authenticated user named by the request, clients that track
+// many tasks poll it instead of getting them one by one.
+func (h *Handler) GetStatuses(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	usr, err := auth.GetUser(ctx)
+	if err != nil {
+		return errs.NewAppCodeError(http.StatusUnauthorized, errs.CodeNotAuthenticated, "unauthorized")
+	}
+
+	var query StatusQuery
+	if err := web.Decode(r, &query); err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidJSON, "invalid json: %s", err)
+	}
+
+	if fields, ok := h.Validator.Check(query); !ok {
+		return errs.NewAppValidationError(http.StatusBadRequest, "invalid input", fields)
+	}
+
+	ids := make([]uuid.UUID, len(query.Ids))
+	for i, id := range query.Ids {
+		ids[i] = uuid.MustParse(id)
+	}
+
+	statuses, err := h.TaskService.GetStatusesByIds(ctx, usr.Id, ids)
+	if err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, toAppStatuses(ids, statuses))
+}
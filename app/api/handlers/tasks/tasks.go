@@ -2,37 +2,58 @@ package tasks
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
-	"slices"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/hamidoujand/task-scheduler/app/api/auth"
 	"github.com/hamidoujand/task-scheduler/app/api/errs"
+	"github.com/hamidoujand/task-scheduler/business/domain/preset"
+	"github.com/hamidoujand/task-scheduler/business/domain/quota"
 	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	"github.com/hamidoujand/task-scheduler/business/domain/usage"
 	"github.com/hamidoujand/task-scheduler/business/domain/user"
+	"github.com/hamidoujand/task-scheduler/business/domain/view"
 	"github.com/hamidoujand/task-scheduler/foundation/web"
 )
 
 // Handler represents set of http handlers.
 type Handler struct {
-	Validator   *errs.AppValidator
-	TaskService *task.Service
-	UserService *user.Service
+	Validator    *errs.AppValidator
+	TaskService  *task.Service
+	UserService  *user.Service
+	QuotaService *quota.Service
+	ViewService  *view.Service
+	//UsageService is optional, without it the execution time budgets of users are not enforced.
+	UsageService *usage.Service
+	//PresetService is optional, without it tasks can not name a preset.
+	PresetService *preset.Service
+	//Networks are the network modes tasks may ask for.
+	Networks task.NetworkPolicy
+	//MaxRetries is the upper bound of the retries a task may ask for instead of the ones of the scheduler.
+	MaxRetries int
+	//DedupWindow is how long a completed task is returned instead of creating another one with its dedup key.
+	DedupWindow time.Duration
+	//MaxWait is the longest a request may wait for a task to finish, it must end before the write timeout.
+	MaxWait time.Duration
+	//WithinTran is optional, it runs fn with a service bound to a single transaction. Without it fn runs against
+	//TaskService.
+	WithinTran func(ctx context.Context, fn func(tsks *task.Service) error) error
 }
 
 // CreateTask creates a task for the authenticated user or returns possible errors.
 func (h *Handler) CreateTask(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	usr, err := auth.GetUser(ctx)
 	if err != nil {
-		return errs.NewAppError(http.StatusUnauthorized, "unauthorized")
+		return errs.NewAppCodeError(http.StatusUnauthorized, errs.CodeNotAuthenticated, "unauthorized")
 	}
 
 	var newTask NewTask
-	if err := json.NewDecoder(r.Body).Decode(&newTask); err != nil {
-		return errs.NewAppErrorf(http.StatusBadRequest, "invalid data: %s", err.Error())
+	if err := web.Decode(r, &newTask); err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidJSON, "invalid json: %s", err)
 	}
 
 	fields, ok := h.Validator.Check(newTask)
@@ -41,7 +62,87 @@ func (h *Handler) CreateTask(ctx context.Context, w http.ResponseWriter, r *http
 		return errs.NewAppValidationError(http.StatusBadRequest, "invalid input", fields)
 	}
 
+	domainTask, err := h.toDomainNewTask(ctx, usr.Id, newTask)
+	if err != nil {
+		return err
+	}
+
+	//producers that fire twice get the task of the first request back
+	if newTask.DedupKey != "" {
+		existing, err := h.TaskService.GetTaskByDedupKey(ctx, usr.Id, newTask.DedupKey, h.DedupWindow)
+		if err == nil {
+			return web.Respond(ctx, w, http.StatusOK, fromDomainTask(existing))
+		}
+		if !errors.Is(err, task.ErrTaskNotFound) {
+			return errs.NewAppInternalErr(err)
+		}
+	}
+
 	//valid data
+	if err := h.checkPendingQuota(ctx, usr.Id, 1); err != nil {
+		return err
+	}
+
+	warning, err := h.checkBudget(ctx, usr.Id)
+	if err != nil {
+		return err
+	}
+
+	task, created, err := h.createTask(ctx, domainTask)
+	if err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+
+	//another request with the dedup key created the task in the meantime
+	if !created {
+		return web.Respond(ctx, w, http.StatusOK, fromDomainTask(task))
+	}
+
+	h.recordCreated(ctx, task)
+
+	if warning != "" {
+		w.Header().Set(budgetWarningHeader, warning)
+	}
+
+	if err := web.Respond(ctx, w, http.StatusCreated, fromDomainTask(task)); err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+
+	return nil
+}
+
+// toDomainNewTask checks the parts of the task the validator does not know about and converts it into the task of
+// the user.
+func (h *Handler) toDomainNewTask(ctx context.Context, userId uuid.UUID, newTask NewTask) (task.NewTask, error) {
+	if newTask.MaxRetries != nil && *newTask.MaxRetries > h.MaxRetries {
+		return task.NewTask{}, errs.NewAppValidationError(http.StatusBadRequest, "invalid input", map[string]string{
+			"maxRetries": fmt.Sprintf("maxRetries must be at most %d", h.MaxRetries),
+		})
+	}
+
+	input, err := newTask.decodeInput()
+	if err != nil {
+		return task.NewTask{}, errs.NewAppValidationError(http.StatusBadRequest, "invalid input", map[string]string{
+			"input": err.Error(),
+		})
+	}
+
+	//too large to store inline with the task
+	if fields := newTask.checkSizes(); len(fields) > 0 {
+		return task.NewTask{}, errs.NewAppValidationError(http.StatusBadRequest, "invalid input", fields)
+	}
+
+	networkMode := task.NetworkMode(newTask.NetworkMode)
+	if networkMode != "" && !h.Networks.Allows(networkMode) {
+		return task.NewTask{}, errs.NewAppValidationError(http.StatusBadRequest, "invalid input", map[string]string{
+			"networkMode": fmt.Sprintf("network mode %q is not allowed", networkMode),
+		})
+	}
+
+	image, err := h.resolveImage(ctx, newTask)
+	if err != nil {
+		return task.NewTask{}, err
+	}
 
 	var builder strings.Builder
 	for key, val := range newTask.Environment {
@@ -49,25 +150,64 @@ func (h *Handler) CreateTask(ctx context.Context, w http.ResponseWriter, r *http
 		builder.WriteByte(' ')
 	}
 
+	var steps []task.Step
+	for _, step := range newTask.Steps {
+		steps = append(steps, task.Step{Command: step.Command, Args: step.Args})
+	}
+
 	domainTask := task.NewTask{
 		Command:     newTask.Command,
 		Args:        newTask.Args,
 		ScheduledAt: newTask.ScheduledAt,
-		UserId:      usr.Id,
-		Image:       newTask.Image,
+		UserId:      userId,
+		Image:       image,
+		FloatingTag: newTask.FloatingTag,
+		Preset:      newTask.Preset,
+		NetworkMode: networkMode,
+		PullPolicy:  task.PullPolicy(newTask.PullPolicy),
+		WorkDir:     newTask.WorkDir,
+		RunAsUser:   newTask.RunAsUser,
+		MaxRetries:  newTask.MaxRetries,
+		RetryOn:     newTask.RetryOn,
+		Steps:       steps,
+		Input:       input,
+		DedupKey:    newTask.DedupKey,
 		Environment: builder.String(),
 	}
 
-	task, err := h.TaskService.CreateTask(ctx, domainTask)
-	if err != nil {
-		return errs.NewAppInternalErr(err)
+	return domainTask, nil
+}
+
+// createTask creates the task, a task with a dedup key is only created when the key is not taken. The lookup and the
+// creation share a transaction so requests that race with the same key create a single task.
+func (h *Handler) createTask(ctx context.Context, nt task.NewTask) (task.Task, bool, error) {
+	if nt.DedupKey == "" {
+		tsk, err := h.TaskService.CreateTask(ctx, nt)
+		return tsk, err == nil, err
 	}
 
-	if err := web.Respond(ctx, w, http.StatusCreated, fromDomainTask(task)); err != nil {
-		return errs.NewAppInternalErr(err)
+	if h.WithinTran == nil {
+		return h.TaskService.CreateTaskOnce(ctx, nt, h.DedupWindow)
 	}
 
-	return nil
+	var (
+		tsk     task.Task
+		created bool
+	)
+	err := h.WithinTran(ctx, func(tsks *task.Service) error {
+		var err error
+		tsk, created, err = tsks.CreateTaskOnce(ctx, nt, h.DedupWindow)
+		return err
+	})
+	if err != nil {
+		return task.Task{}, false, err
+	}
+
+	//the message of a task that is due soon is relayed once the transaction committed
+	if created {
+		_, _ = h.TaskService.RelayOutbox(ctx)
+	}
+	return tsk, created, nil
 }
 
 // GetTaskById returns a task for the given id if the user is the creator of that task or returns possible errors.
@@ -77,29 +217,28 @@ func (h *Handler) GetTaskById(ctx context.Context, w http.ResponseWriter, r *htt
 
 	taskUUID, err := uuid.Parse(taskId)
 	if err != nil {
-		return errs.NewAppErrorf(http.StatusBadRequest, "%q not a valid uuid", taskId)
-	}
-
-	usr, err := auth.GetUser(ctx)
-	if err != nil {
-		return errs.NewAppError(http.StatusUnauthorized, "unauthorized")
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidId, "%q not a valid uuid", taskId)
 	}
 
 	t, err := h.TaskService.GetTaskById(ctx, taskUUID)
 
 	if err != nil {
 		if errors.Is(err, task.ErrTaskNotFound) {
-			return errs.NewAppErrorf(http.StatusNotFound, "task with id %q not found", taskId)
+			return errs.NewAppCodeErrorf(http.StatusNotFound, errs.CodeTaskNotFound, "task with id %q not found", taskId)
 		}
 		//internal
 		return errs.NewAppInternalErr(err)
 	}
 
-	if t.UserId != usr.Id {
-		return errs.NewAppErrorf(http.StatusUnauthorized, "unauthorized: task with id %s, does not belong to this user", taskId)
+	//offloaded results are only fetched for a single task
+	t, err = h.TaskService.LoadResult(ctx, t)
+	if err != nil {
+		return errs.NewAppInternalErr(err)
 	}
 
-	if err := web.Respond(ctx, w, http.StatusOK, fromDomainTask(t)); err != nil {
+	//every change of a task bumps its version
+	etag := web.ETag(t.Id, t.Version)
+	if err := web.RespondETag(ctx, w, r, http.StatusOK, etag, fromDomainTask(t)); err != nil {
 		return errs.NewAppInternalErr(err)
 	}
 
@@ -108,32 +247,23 @@ func (h *Handler) GetTaskById(ctx context.Context, w http.ResponseWriter, r *htt
 
 // DeleteTaskById deletes the task by id if creator or admin request it or returns possible errors.
 func (h *Handler) DeleteTaskById(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-	usr, err := auth.GetUser(ctx)
-	if err != nil {
-		return errs.NewAppError(http.StatusUnauthorized, "unauthorized")
-	}
-
 	taskId := r.PathValue("id")
 	taskUUID, err := uuid.Parse(taskId)
 
 	if err != nil {
-		return errs.NewAppErrorf(http.StatusBadRequest, "%q not a valid uuid", taskId)
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidId, "%q not a valid uuid", taskId)
 	}
 
 	t, err := h.TaskService.GetTaskById(ctx, taskUUID)
 
 	if err != nil {
 		if errors.Is(err, task.ErrTaskNotFound) {
-			return errs.NewAppErrorf(http.StatusNotFound, "task with id %q not found", taskId)
+			return errs.NewAppCodeErrorf(http.StatusNotFound, errs.CodeTaskNotFound, "task with id %q not found", taskId)
 		}
 		//internal
 		return errs.NewAppInternalErr(err)
 	}
 
-	if t.UserId != usr.Id && !isItAdmin(usr.Roles) {
-		return errs.NewAppError(http.StatusUnauthorized, "unauthorized: operation not permitted")
-	}
-
 	if err := h.TaskService.DeleteTask(ctx, t); err != nil {
 		return errs.NewAppInternalErr(err)
 	}
@@ -148,32 +278,179 @@ func (h *Handler) DeleteTaskById(ctx context.Context, w http.ResponseWriter, r *
 func (h *Handler) GetAllTasksForUser(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	usr, err := auth.GetUser(ctx)
 	if err != nil {
-		return errs.NewAppError(http.StatusUnauthorized, "unauthorized")
+		return errs.NewAppCodeError(http.StatusUnauthorized, errs.CodeNotAuthenticated, "unauthorized")
+	}
+
+	return h.respondTasksOf(ctx, w, r, usr.Id)
+}
+
+// GetTasksByUserId returns the tasks of the user in the "id" path value with the same pagination and ordering
+// as GetAllTasksForUser, used by admins to inspect the work of a specific user.
+func (h *Handler) GetTasksByUserId(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+
+	userId, err := uuid.Parse(id)
+	if err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidId, "%q not a valid uuid", id)
+	}
+
+	if _, err := h.UserService.GetUserById(ctx, userId); err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return errs.NewAppCodeErrorf(http.StatusNotFound, errs.CodeUserNotFound, "user with id %q not found", id)
+		}
+		return errs.NewAppInternalErr(err)
+	}
+
+	return h.respondTasksOf(ctx, w, r, userId)
+}
+
+func (h *Handler) respondTasksOf(ctx context.Context, w http.ResponseWriter, r *http.Request, userId uuid.UUID) error {
+	if err := h.applyView(ctx, r); err != nil {
+		return err
 	}
 
 	rows, page, err := parsePagination(r)
 	if err != nil {
-		return errs.NewAppError(http.StatusBadRequest, err.Error())
+		return errs.NewAppCodeError(http.StatusBadRequest, errs.CodeInvalidQuery, err.Error())
 	}
 
 	order, err := parseOrder(r)
 	if err != nil {
-		return errs.NewAppError(http.StatusBadRequest, err.Error())
+		return errs.NewAppCodeError(http.StatusBadRequest, errs.CodeInvalidQuery, err.Error())
 	}
 
-	userTasks, err := h.TaskService.GetTasksByUserId(ctx, usr.Id, rows, page, order)
+	if query := r.URL.Query().Get("q"); query != "" {
+		if r.URL.Query().Has("after") {
+			return errs.NewAppCodeError(http.StatusBadRequest, errs.CodeInvalidCursor, "search results can not be paginated with a cursor")
+		}
+
+		found, err := h.TaskService.SearchTasks(ctx, userId, query, rows, page)
+		if err != nil {
+			return errs.NewAppInternalErr(err)
+		}
+		return web.Respond(ctx, w, http.StatusOK, toAppTasks(found))
+	}
+
+	//large lists are walked with a cursor, "after" is empty for the first page
+	if r.URL.Query().Has("after") {
+		return h.respondTasksAfter(ctx, w, r, userId, rows, order)
+	}
+
+	userTasks, err := h.TaskService.GetTasksByUserId(ctx, userId, rows, page, order)
 	if err != nil {
 		return errs.NewAppInternalErr(err)
 	}
 
-	appTasks := make([]Task, len(userTasks))
-	for i, t := range userTasks {
-		appTasks[i] = fromDomainTask(t)
+	return web.Respond(ctx, w, http.StatusOK, toAppTasks(userTasks))
+}
+
+func (h *Handler) respondTasksAfter(ctx context.Context, w http.ResponseWriter, r *http.Request, userId uuid.UUID, rows int, order task.OrderBy) error {
+	if order.Field != task.FieldCreatedAt {
+		return errs.NewAppCodeError(http.StatusBadRequest, errs.CodeInvalidCursor, "cursor pagination only supports ordering by createdAt")
 	}
 
-	return web.Respond(ctx, w, http.StatusOK, appTasks)
+	var after *task.Cursor
+	if token := r.URL.Query().Get("after"); token != "" {
+		cursor, err := task.ParseCursor(token)
+		if err != nil {
+			return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidCursor, "%q is an invalid cursor", token)
+		}
+		after = &cursor
+	}
+
+	userTasks, next, err := h.TaskService.GetTasksByUserIdAfter(ctx, userId, rows, after, order.Direction)
+	if err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+
+	page := TaskPage{
+		Tasks:      toAppTasks(userTasks),
+		NextCursor: next,
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, page)
 }
 
-func isItAdmin(roles []user.Role) bool {
-	return slices.Contains(roles, user.RoleAdmin)
+// resolveImage returns the image the new task runs, a task that names a preset runs the image of the preset.
+func (h *Handler) resolveImage(ctx context.Context, nt NewTask) (string, error) {
+	if nt.Preset == "" {
+		return nt.Image, nil
+	}
+
+	if h.PresetService == nil {
+		return "", errs.NewAppCodeError(http.StatusServiceUnavailable, errs.CodeFeatureDisabled, "presets are disabled")
+	}
+
+	p, err := h.PresetService.GetPreset(ctx, nt.Preset)
+	if err != nil {
+		if errors.Is(err, preset.ErrPresetNotFound) {
+			return "", errs.NewAppValidationError(http.StatusBadRequest, "invalid input", map[string]string{
+				"preset": fmt.Sprintf("preset %q not found", nt.Preset),
+			})
+		}
+		return "", errs.NewAppInternalErr(err)
+	}
+	return p.Image, nil
+}
+
+// checkPendingQuota returns a 429 when adding more pending tasks takes the user over their quota.
+func (h *Handler) checkPendingQuota(ctx context.Context, userId uuid.UUID, adding int) error {
+	if h.QuotaService == nil {
+		return nil
+	}
+
+	q, err := h.QuotaService.GetQuota(ctx, userId)
+	if err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+
+	if q.MaxPending == 0 {
+		return nil
+	}
+
+	pending, err := h.TaskService.CountTasks(ctx, userId, task.StatusPending)
+	if err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+
+	if !q.AllowsPending(pending + adding - 1) {
+		return errs.NewAppCodeErrorf(http.StatusTooManyRequests, errs.CodeQuotaExceeded, "quota exceeded: at most %d pending tasks are allowed", q.MaxPending)
+	}
+	return nil
+}
+
+// OwnerOnly is the rule of routes that only the creator of the task in the "id" path value may call.
+func (h *Handler) OwnerOnly() auth.Rule {
+	return auth.Rule{
+		Owner:            h.TaskOwner,
+		OwnerDescription: "creator of the task",
+	}
+}
+
+// OwnerOrAdmin is the rule of routes that admins and the creator of the task in the "id" path value may call.
+func (h *Handler) OwnerOrAdmin() auth.Rule {
+	return auth.Rule{
+		Roles:            []user.Role{user.RoleAdmin},
+		Owner:            h.TaskOwner,
+		OwnerDescription: "creator of the task",
+	}
+}
+
+// TaskOwner resolves the creator of the task in the "id" path value.
+func (h *Handler) TaskOwner(ctx context.Context, r *http.Request) (uuid.UUID, error) {
+	taskId := r.PathValue("id")
+
+	taskUUID, err := uuid.Parse(taskId)
+	if err != nil {
+		return uuid.UUID{}, errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidId, "%q not a valid uuid", taskId)
+	}
+
+	t, err := h.TaskService.GetTaskById(ctx, taskUUID)
+	if err != nil {
+		if errors.Is(err, task.ErrTaskNotFound) {
+			return uuid.UUID{}, errs.NewAppCodeErrorf(http.StatusNotFound, errs.CodeTaskNotFound, "task with id %q not found", taskId)
+		}
+		return uuid.UUID{}, err
+	}
+	return t.UserId, nil
 }
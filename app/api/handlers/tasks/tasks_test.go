@@ -10,6 +10,8 @@ import (
 	"net/http/httptest"
 	"net/mail"
 	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -17,10 +19,17 @@ import (
 	"github.com/hamidoujand/task-scheduler/app/api/auth"
 	"github.com/hamidoujand/task-scheduler/app/api/errs"
 	"github.com/hamidoujand/task-scheduler/app/api/handlers/tasks"
+	"github.com/hamidoujand/task-scheduler/app/api/handlers/users"
+	"github.com/hamidoujand/task-scheduler/app/api/mid"
 	"github.com/hamidoujand/task-scheduler/business/brokertest"
+	"github.com/hamidoujand/task-scheduler/business/domain/preset"
+	presetMemRepo "github.com/hamidoujand/task-scheduler/business/domain/preset/store/memory"
 	"github.com/hamidoujand/task-scheduler/business/domain/task"
 	"github.com/hamidoujand/task-scheduler/business/domain/task/store/memory"
 	"github.com/hamidoujand/task-scheduler/business/domain/user"
+	userMemRepo "github.com/hamidoujand/task-scheduler/business/domain/user/store/memory"
+	"github.com/hamidoujand/task-scheduler/business/domain/view"
+	viewMemRepo "github.com/hamidoujand/task-scheduler/business/domain/view/store/memory"
 )
 
 func TestCreateTask(t *testing.T) {
@@ -29,7 +38,7 @@ func TestCreateTask(t *testing.T) {
 		Tasks: make(map[uuid.UUID]task.Task),
 	}
 
-	rClient := brokertest.NewTestClient(t, context.Background(), "test_create_task_app")
+	rClient := brokertest.NewMemoryClient(t)
 
 	taskService, err := task.NewService(&memRepo, rClient)
 	if err != nil {
@@ -54,17 +63,29 @@ func TestCreateTask(t *testing.T) {
 		UpdatedAt:    time.Now(),
 	}
 
+	presetService := preset.NewService(&presetMemRepo.Repository{})
+	if _, err := presetService.SetPreset(context.Background(), "python", preset.NewPreset{Image: "python:3.12-slim"}); err != nil {
+		t.Fatalf("expected to set the preset: %s", err)
+	}
+
 	h := tasks.Handler{
-		Validator:   v,
-		TaskService: taskService,
+		Validator:     v,
+		TaskService:   taskService,
+		PresetService: presetService,
+		MaxRetries:    5,
+		Networks:      task.NetworkPolicy{Allowed: []task.NetworkMode{task.NetworkInternal}},
 	}
 
+	retries := 3
+	tooManyRetries := 6
+
 	tests := map[string]struct {
 		input        tasks.NewTask
 		expectError  bool
 		status       int
 		fields       []string
 		unauthorized bool
+		image        string
 	}{
 		"success": {
 			input: tasks.NewTask{
@@ -91,6 +112,244 @@ func TestCreateTask(t *testing.T) {
 			fields:      []string{"command", "args", "scheduledAt", "image"},
 		},
 
+		"custom retries": {
+			input: tasks.NewTask{
+				Command:     "date",
+				Image:       "alpine:3.20",
+				ScheduledAt: time.Now().Add(time.Hour),
+				MaxRetries:  &retries,
+			},
+			expectError: false,
+			status:      http.StatusCreated,
+		},
+
+		"too many retries": {
+			input: tasks.NewTask{
+				Command:     "date",
+				Image:       "alpine:3.20",
+				ScheduledAt: time.Now().Add(time.Hour),
+				MaxRetries:  &tooManyRetries,
+			},
+			expectError: true,
+			status:      http.StatusBadRequest,
+			fields:      []string{"maxRetries"},
+		},
+
+		"args too large": {
+			input: tasks.NewTask{
+				Command:     "echo",
+				Args:        []string{strings.Repeat("a", 32*1024+1)},
+				Image:       "alpine:3.20",
+				ScheduledAt: time.Now().Add(time.Hour),
+			},
+			expectError: true,
+			status:      http.StatusBadRequest,
+			fields:      []string{"args"},
+		},
+
+		"environment too large": {
+			input: tasks.NewTask{
+				Command:     "date",
+				Image:       "alpine:3.20",
+				ScheduledAt: time.Now().Add(time.Hour),
+				Environment: map[string]string{"PAYLOAD": strings.Repeat("a", 32*1024)},
+			},
+			expectError: true,
+			status:      http.StatusBadRequest,
+			fields:      []string{"environment"},
+		},
+
+		"retry on exit codes": {
+			input: tasks.NewTask{
+				Command:     "date",
+				Image:       "alpine:3.20",
+				ScheduledAt: time.Now().Add(time.Hour),
+				RetryOn:     []int{1, 137},
+			},
+			expectError: false,
+			status:      http.StatusCreated,
+		},
+
+		"multi-step task": {
+			input: tasks.NewTask{
+				Image:       "alpine:3.20",
+				ScheduledAt: time.Now().Add(time.Hour),
+				Steps: []tasks.NewStep{
+					{Command: "date"},
+					{Command: "ls", Args: []string{"-l"}},
+				},
+			},
+			expectError: false,
+			status:      http.StatusCreated,
+		},
+
+		"command and steps": {
+			input: tasks.NewTask{
+				Command:     "date",
+				Image:       "alpine:3.20",
+				ScheduledAt: time.Now().Add(time.Hour),
+				Steps:       []tasks.NewStep{{Command: "ls"}},
+			},
+			expectError: true,
+			status:      http.StatusBadRequest,
+			fields:      []string{"command"},
+		},
+
+		"invalid exit code": {
+			input: tasks.NewTask{
+				Command:     "date",
+				Image:       "alpine:3.20",
+				ScheduledAt: time.Now().Add(time.Hour),
+				RetryOn:     []int{0},
+			},
+			expectError: true,
+			status:      http.StatusBadRequest,
+			fields:      []string{"retryOn[0]"},
+		},
+
+		"preset": {
+			input: tasks.NewTask{
+				Command:     "python",
+				Args:        []string{"--version"},
+				Preset:      "python",
+				ScheduledAt: time.Now().Add(time.Hour),
+			},
+			expectError: false,
+			status:      http.StatusCreated,
+			image:       "python:3.12-slim",
+		},
+
+		"unknown preset": {
+			input: tasks.NewTask{
+				Command:     "date",
+				Preset:      "ruby",
+				ScheduledAt: time.Now().Add(time.Hour),
+			},
+			expectError: true,
+			status:      http.StatusBadRequest,
+			fields:      []string{"preset"},
+		},
+
+		"image and preset": {
+			input: tasks.NewTask{
+				Command:     "date",
+				Image:       "alpine:3.20",
+				Preset:      "python",
+				ScheduledAt: time.Now().Add(time.Hour),
+			},
+			expectError: true,
+			status:      http.StatusBadRequest,
+			fields:      []string{"image"},
+		},
+
+		"allowed network mode": {
+			input: tasks.NewTask{
+				Command:     "date",
+				Image:       "alpine:3.20",
+				NetworkMode: "internal",
+				ScheduledAt: time.Now().Add(time.Hour),
+			},
+			expectError: false,
+			status:      http.StatusCreated,
+		},
+
+		"disallowed network mode": {
+			input: tasks.NewTask{
+				Command:     "date",
+				Image:       "alpine:3.20",
+				NetworkMode: "egress",
+				ScheduledAt: time.Now().Add(time.Hour),
+			},
+			expectError: true,
+			status:      http.StatusBadRequest,
+			fields:      []string{"networkMode"},
+		},
+
+		"base64 input": {
+			input: tasks.NewTask{
+				Command:       "base64",
+				Image:         "alpine:3.20",
+				Input:         "AAECAw==",
+				InputEncoding: "base64",
+				ScheduledAt:   time.Now().Add(time.Hour),
+			},
+			expectError: false,
+			status:      http.StatusCreated,
+		},
+
+		"invalid base64 input": {
+			input: tasks.NewTask{
+				Command:       "base64",
+				Image:         "alpine:3.20",
+				Input:         "not base64!",
+				InputEncoding: "base64",
+				ScheduledAt:   time.Now().Add(time.Hour),
+			},
+			expectError: true,
+			status:      http.StatusBadRequest,
+			fields:      []string{"input"},
+		},
+
+		"input too large": {
+			input: tasks.NewTask{
+				Command:     "cat",
+				Image:       "alpine:3.20",
+				Input:       strings.Repeat("a", 256*1024+1),
+				ScheduledAt: time.Now().Add(time.Hour),
+			},
+			expectError: true,
+			status:      http.StatusBadRequest,
+			fields:      []string{"input"},
+		},
+
+		"workdir and user": {
+			input: tasks.NewTask{
+				Command:     "date",
+				Image:       "alpine:3.20",
+				WorkDir:     "/srv/app",
+				RunAsUser:   "1000:1000",
+				ScheduledAt: time.Now().Add(time.Hour),
+			},
+			expectError: false,
+			status:      http.StatusCreated,
+		},
+
+		"relative workdir": {
+			input: tasks.NewTask{
+				Command:     "date",
+				Image:       "alpine:3.20",
+				WorkDir:     "srv/../app",
+				ScheduledAt: time.Now().Add(time.Hour),
+			},
+			expectError: true,
+			status:      http.StatusBadRequest,
+			fields:      []string{"workDir"},
+		},
+
+		"root user": {
+			input: tasks.NewTask{
+				Command:     "date",
+				Image:       "alpine:3.20",
+				RunAsUser:   "0:0",
+				ScheduledAt: time.Now().Add(time.Hour),
+			},
+			expectError: true,
+			status:      http.StatusBadRequest,
+			fields:      []string{"runAsUser"},
+		},
+
+		"unknown pull policy": {
+			input: tasks.NewTask{
+				Command:     "date",
+				Image:       "alpine:3.20",
+				PullPolicy:  "never",
+				ScheduledAt: time.Now().Add(time.Hour),
+			},
+			expectError: true,
+			status:      http.StatusBadRequest,
+			fields:      []string{"pullPolicy"},
+		},
+
 		"unauthorized user": {
 			input: tasks.NewTask{
 				Command:     "date",
@@ -148,46 +407,497 @@ func TestCreateTask(t *testing.T) {
 					t.Errorf("task.UserId=%s, got %s", taskCreator.Id, resp.UserId)
 				}
 
-				if resp.Image != test.input.Image {
-					t.Errorf("image= %s, got %s", test.input.Image, resp.Image)
-				}
+				//tasks that name a preset run its image
+				image := test.input.Image
+				if test.image != "" {
+					image = test.image
+				}
+
+				if resp.Image != image {
+					t.Errorf("image= %s, got %s", image, resp.Image)
+				}
+
+				if resp.Preset != test.input.Preset {
+					t.Errorf("preset= %s, got %s", test.input.Preset, resp.Preset)
+				}
+
+				if resp.NetworkMode != test.input.NetworkMode {
+					t.Errorf("networkMode= %s, got %s", test.input.NetworkMode, resp.NetworkMode)
+				}
+
+				for key, val := range test.input.Environment {
+					if resp.Environment[key] != val {
+						t.Errorf("expected env %q to have the same value as %q", key, val)
+					}
+				}
+
+				if resp.CreatedAt.IsZero() {
+					t.Error("expected the createdAt field to not be zero value")
+				}
+
+				if resp.UpdatedAt.IsZero() {
+					t.Error("expected the updatedAt field to not be zero value")
+				}
+
+				if test.input.MaxRetries != nil && (resp.MaxRetries == nil || *resp.MaxRetries != *test.input.MaxRetries) {
+					t.Errorf("maxRetries= %d, got %v", *test.input.MaxRetries, resp.MaxRetries)
+				}
+
+				if len(resp.Steps) != len(test.input.Steps) {
+					t.Errorf("steps= %d, got %d", len(test.input.Steps), len(resp.Steps))
+				}
+
+				if !slices.Equal(resp.RetryOn, test.input.RetryOn) {
+					t.Errorf("retryOn= %v, got %v", test.input.RetryOn, resp.RetryOn)
+				}
+
+				//the timeline of the task starts with its creation
+				events, err := taskService.GetEventsByTaskId(context.Background(), uuid.MustParse(resp.Id))
+				if err != nil {
+					t.Fatalf("expected to get the events of the task: %s", err)
+				}
+
+				if len(events) == 0 || events[0].Kind != task.EventCreated {
+					t.Errorf("first event= %s, got %+v", task.EventCreated, events)
+				}
+
+			} else {
+				//failure path
+				var appErr *errs.AppError
+				if !errors.As(err, &appErr) {
+					t.Fatalf("expected the error type to be *appError, got %T", err)
+				}
+
+				if appErr.Code != test.status {
+					t.Errorf("appError.Code=%d, got %d", test.status, appErr.Code)
+				}
+
+				if appErr.Fields != nil {
+					for name, value := range appErr.Fields {
+						if !slices.Contains(test.fields, name) {
+							t.Errorf("expected field %s to be invalid: %s", name, value)
+						}
+					}
+				}
+			}
+		})
+	}
+
+}
+
+func TestCreateTaskDedup(t *testing.T) {
+	t.Parallel()
+	memRepo := memory.Repository{
+		Tasks: make(map[uuid.UUID]task.Task),
+	}
+
+	taskService, err := task.NewService(&memRepo, brokertest.NewMemoryClient(t))
+	if err != nil {
+		t.Fatalf("expected to create a new server: %s", err)
+	}
+	v, err := errs.NewAppValidator()
+	if err != nil {
+		t.Fatalf("should be able to create a validator: %s", err)
+	}
+
+	h := tasks.Handler{
+		Validator:   v,
+		TaskService: taskService,
+		MaxRetries:  5,
+		DedupWindow: time.Minute,
+	}
+
+	usr := user.User{Id: uuid.New(), Roles: []user.Role{user.RoleUser}, Enabled: true}
+
+	create := func(key string) (int, tasks.Task) {
+		input := tasks.NewTask{
+			Command:     "date",
+			Image:       "alpine:3.20",
+			ScheduledAt: time.Now().Add(time.Hour),
+			DedupKey:    key,
+		}
+
+		var buff bytes.Buffer
+		if err := json.NewEncoder(&buff).Encode(input); err != nil {
+			t.Fatalf("expected to encode input: %s", err)
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/api/tasks/", &buff)
+		w := httptest.NewRecorder()
+
+		if err := h.CreateTask(auth.SetUser(req.Context(), usr), w, req); err != nil {
+			t.Fatalf("should be able to create a task: %s", err)
+		}
+
+		var resp tasks.Task
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("should be able to decode response body: %s", err)
+		}
+		return w.Code, resp
+	}
+
+	status, first := create("order-42")
+	if status != http.StatusCreated {
+		t.Fatalf("status= %d, got %d", http.StatusCreated, status)
+	}
+
+	//the pending task is returned for the same key
+	status, second := create("order-42")
+	if status != http.StatusOK || second.Id != first.Id {
+		t.Errorf("expected task %s with status %d, got %s with %d", first.Id, http.StatusOK, second.Id, status)
+	}
+
+	//other keys are not affected
+	if status, other := create("order-43"); status != http.StatusCreated || other.Id == first.Id {
+		t.Errorf("expected a new task with status %d, got %s with %d", http.StatusCreated, other.Id, status)
+	}
+
+	//completed tasks are returned until the window passes
+	id := uuid.MustParse(first.Id)
+	completed := memRepo.Tasks[id]
+	completed.Status = task.StatusCompleted
+	completed.FinishedAt = time.Now()
+	memRepo.Tasks[id] = completed
+
+	if status, got := create("order-42"); status != http.StatusOK || got.Id != first.Id {
+		t.Errorf("expected task %s with status %d, got %s with %d", first.Id, http.StatusOK, got.Id, status)
+	}
+
+	completed.FinishedAt = time.Now().Add(-time.Hour)
+	memRepo.Tasks[id] = completed
+
+	if status, got := create("order-42"); status != http.StatusCreated || got.Id == first.Id {
+		t.Errorf("expected a new task with status %d, got %s with %d", http.StatusCreated, got.Id, status)
+	}
+}
+
+func TestCreateTasks(t *testing.T) {
+	t.Parallel()
+	memRepo := memory.Repository{
+		Tasks: make(map[uuid.UUID]task.Task),
+	}
+
+	taskService, err := task.NewService(&memRepo, brokertest.NewMemoryClient(t))
+	if err != nil {
+		t.Fatalf("expected to create a new server: %s", err)
+	}
+	v, err := errs.NewAppValidator()
+	if err != nil {
+		t.Fatalf("should be able to create a validator: %s", err)
+	}
+
+	h := tasks.Handler{
+		Validator:   v,
+		TaskService: taskService,
+		MaxRetries:  5,
+	}
+
+	usr := user.User{Id: uuid.New(), Roles: []user.Role{user.RoleUser}, Enabled: true}
+
+	create := func(batch tasks.NewTasks) (*httptest.ResponseRecorder, error) {
+		var buff bytes.Buffer
+		if err := json.NewEncoder(&buff).Encode(batch); err != nil {
+			t.Fatalf("expected to encode input: %s", err)
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/api/tasks/batch", &buff)
+		w := httptest.NewRecorder()
+		return w, h.CreateTasks(auth.SetUser(req.Context(), usr), w, req)
+	}
+
+	valid := tasks.NewTask{Command: "date", Image: "alpine:3.20", ScheduledAt: time.Now().Add(time.Hour)}
+	tooManyRetries := 6
+
+	tests := map[string]struct {
+		batch tasks.NewTasks
+		field string
+	}{
+		"empty":            {batch: tasks.NewTasks{}, field: "tasks"},
+		"invalid task":     {batch: tasks.NewTasks{Tasks: []tasks.NewTask{valid, {Image: "alpine:3.20", ScheduledAt: valid.ScheduledAt}}}, field: "tasks[1].command"},
+		"too many retries": {batch: tasks.NewTasks{Tasks: []tasks.NewTask{{Command: "date", Image: "alpine:3.20", ScheduledAt: valid.ScheduledAt, MaxRetries: &tooManyRetries}}}, field: "tasks[0].maxRetries"},
+		"dedup key":        {batch: tasks.NewTasks{Tasks: []tasks.NewTask{valid, {Command: "date", Image: "alpine:3.20", ScheduledAt: valid.ScheduledAt, DedupKey: "order-42"}}}, field: "tasks[1].dedupKey"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := create(test.batch)
+
+			var appErr *errs.AppError
+			if !errors.As(err, &appErr) || appErr.Code != http.StatusBadRequest {
+				t.Fatalf("expected a bad request, got %v", err)
+			}
+
+			if _, ok := appErr.Fields[test.field]; !ok {
+				t.Errorf("expected field %q to be invalid, got %v", test.field, appErr.Fields)
+			}
+		})
+	}
+
+	//an invalid task keeps the valid ones of its batch from being created
+	if count, err := taskService.CountTasks(context.Background(), usr.Id, task.StatusPending); err != nil || count != 0 {
+		t.Fatalf("expected no tasks, got %d: %v", count, err)
+	}
+
+	w, err := create(tasks.NewTasks{Tasks: []tasks.NewTask{valid, {Command: "ls", Image: "alpine:3.20", ScheduledAt: valid.ScheduledAt}}})
+	if err != nil {
+		t.Fatalf("expected to create the tasks: %s", err)
+	}
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status= %d, got %d", http.StatusCreated, w.Code)
+	}
+
+	var created []tasks.Task
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("should be able to decode response body: %s", err)
+	}
+
+	if len(created) != 2 || created[0].Command != "date" || created[1].Command != "ls" {
+		t.Errorf("expected the tasks in the order of the batch, got %+v", created)
+	}
+}
+
+func TestCreateTaskDedupConcurrent(t *testing.T) {
+	t.Parallel()
+	memRepo := memory.Repository{
+		Tasks: make(map[uuid.UUID]task.Task),
+	}
+
+	taskService, err := task.NewService(&memRepo, brokertest.NewMemoryClient(t))
+	if err != nil {
+		t.Fatalf("expected to create a new server: %s", err)
+	}
+	v, err := errs.NewAppValidator()
+	if err != nil {
+		t.Fatalf("should be able to create a validator: %s", err)
+	}
+
+	//the transactions serialize on the dedup key like the advisory lock of postgres does
+	var tranMu sync.Mutex
+	h := tasks.Handler{
+		Validator:   v,
+		TaskService: taskService,
+		MaxRetries:  5,
+		DedupWindow: time.Minute,
+		WithinTran: func(ctx context.Context, fn func(tsks *task.Service) error) error {
+			tranMu.Lock()
+			defer tranMu.Unlock()
+			return fn(taskService)
+		},
+	}
+
+	usr := user.User{Id: uuid.New(), Roles: []user.Role{user.RoleUser}, Enabled: true}
+
+	var wg sync.WaitGroup
+	codes := make(chan int, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			input := tasks.NewTask{
+				Command:     "date",
+				Image:       "alpine:3.20",
+				ScheduledAt: time.Now().Add(time.Hour),
+				DedupKey:    "order-42",
+			}
+
+			var buff bytes.Buffer
+			if err := json.NewEncoder(&buff).Encode(input); err != nil {
+				t.Errorf("expected to encode input: %s", err)
+				return
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/api/tasks/", &buff)
+			w := httptest.NewRecorder()
+
+			if err := h.CreateTask(auth.SetUser(req.Context(), usr), w, req); err != nil {
+				t.Errorf("should be able to create a task: %s", err)
+				return
+			}
+			codes <- w.Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	var created int
+	for code := range codes {
+		if code == http.StatusCreated {
+			created++
+		}
+	}
+
+	if created != 1 {
+		t.Errorf("created= %d, got %d", 1, created)
+	}
+
+	count, err := taskService.CountTasks(context.Background(), usr.Id, task.StatusPending)
+	if err != nil {
+		t.Fatalf("expected to count the tasks: %s", err)
+	}
+
+	if count != 1 {
+		t.Errorf("tasks= %d, got %d", 1, count)
+	}
+}
+
+func TestWaitForTask(t *testing.T) {
+	t.Parallel()
+
+	owner := user.User{Id: uuid.New(), Roles: []user.Role{user.RoleUser}, Enabled: true}
+	now := time.Now()
+	queued := task.Task{Id: uuid.New(), UserId: owner.Id, Command: "date", Status: task.StatusQueued, CreatedAt: now, UpdatedAt: now}
+
+	memRepo := memory.Repository{
+		Tasks: map[uuid.UUID]task.Task{queued.Id: queued},
+	}
+
+	taskService, err := task.NewService(&memRepo, brokertest.NewMemoryClient(t))
+	if err != nil {
+		t.Fatalf("expected to create a new server: %s", err)
+	}
+
+	h := tasks.Handler{
+		TaskService: taskService,
+		MaxWait:     time.Minute,
+	}
+
+	wait := func(timeout string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/api/tasks/"+queued.Id.String()+"/wait?timeout="+timeout, nil)
+		req.SetPathValue("id", queued.Id.String())
+		w := httptest.NewRecorder()
+		return w, h.WaitForTask(auth.SetUser(req.Context(), owner), w, req)
+	}
+
+	//a zero timeout reads the task once
+	w, err := wait("0s")
+	if err != nil {
+		t.Fatalf("expected to read the task: %s", err)
+	}
+
+	var resp tasks.Task
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("should be able to decode response body: %s", err)
+	}
+
+	if w.Code != http.StatusOK || resp.Status != task.StatusQueued.String() {
+		t.Errorf("expected the queued task with status %d, got %s with %d", http.StatusOK, resp.Status, w.Code)
+	}
+
+	_, err = wait("-1s")
+	var appErr *errs.AppError
+	if !errors.As(err, &appErr) || appErr.Code != http.StatusBadRequest {
+		t.Errorf("expected a bad request for a negative timeout, got %v", err)
+	}
+}
+
+func TestGetStatuses(t *testing.T) {
+	t.Parallel()
+
+	owner := user.User{Id: uuid.New(), Roles: []user.Role{user.RoleUser}, Enabled: true}
+	now := time.Now()
+
+	queued := task.Task{Id: uuid.New(), UserId: owner.Id, Status: task.StatusQueued, UpdatedAt: now}
+	completed := task.Task{Id: uuid.New(), UserId: owner.Id, Status: task.StatusCompleted, UpdatedAt: now}
+	others := task.Task{Id: uuid.New(), UserId: uuid.New(), Status: task.StatusPending, UpdatedAt: now}
+
+	memRepo := memory.Repository{
+		Tasks: map[uuid.UUID]task.Task{
+			queued.Id:    queued,
+			completed.Id: completed,
+			others.Id:    others,
+		},
+	}
+
+	taskService, err := task.NewService(&memRepo, brokertest.NewMemoryClient(t))
+	if err != nil {
+		t.Fatalf("expected to create a new server: %s", err)
+	}
+	v, err := errs.NewAppValidator()
+	if err != nil {
+		t.Fatalf("should be able to create a validator: %s", err)
+	}
+
+	h := tasks.Handler{
+		Validator:   v,
+		TaskService: taskService,
+	}
+
+	missing := uuid.NewString()
+
+	tests := map[string]struct {
+		ids         []string
+		expectError bool
+		statuses    map[string]string
+		notFound    []string
+	}{
+		"success": {
+			ids: []string{queued.Id.String(), completed.Id.String()},
+			statuses: map[string]string{
+				queued.Id.String():    task.StatusQueued.String(),
+				completed.Id.String(): task.StatusCompleted.String(),
+			},
+			notFound: []string{},
+		},
+		"tasks of others are not found": {
+			ids:      []string{queued.Id.String(), others.Id.String(), missing},
+			statuses: map[string]string{queued.Id.String(): task.StatusQueued.String()},
+			notFound: []string{others.Id.String(), missing},
+		},
+		"invalid id": {
+			ids:         []string{"not-a-uuid"},
+			expectError: true,
+		},
+		"no ids": {
+			ids:         []string{},
+			expectError: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buff bytes.Buffer
+			if err := json.NewEncoder(&buff).Encode(tasks.StatusQuery{Ids: test.ids}); err != nil {
+				t.Fatalf("expected to encode input: %s", err)
+			}
+
+			r := httptest.NewRequest(http.MethodPost, "/v1/api/tasks/status", &buff)
+			w := httptest.NewRecorder()
 
-				for key, val := range test.input.Environment {
-					if resp.Environment[key] != val {
-						t.Errorf("expected env %q to have the same value as %q", key, val)
-					}
+			err := h.GetStatuses(auth.SetUser(r.Context(), owner), w, r)
+			if test.expectError {
+				var appErr *errs.AppError
+				if !errors.As(err, &appErr) || appErr.Code != http.StatusBadRequest {
+					t.Fatalf("expected a bad request, got %v", err)
 				}
+				return
+			}
 
-				if resp.CreatedAt.IsZero() {
-					t.Error("expected the createdAt field to not be zero value")
-				}
+			if err != nil {
+				t.Fatalf("expected to get the statuses: %s", err)
+			}
 
-				if resp.UpdatedAt.IsZero() {
-					t.Error("expected the updatedAt field to not be zero value")
-				}
+			var resp tasks.TaskStatuses
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("should be able to decode response body: %s", err)
+			}
 
-			} else {
-				//failure path
-				var appErr *errs.AppError
-				if !errors.As(err, &appErr) {
-					t.Fatalf("expected the error type to be *appError, got %T", err)
-				}
+			if len(resp.Tasks) != len(test.statuses) {
+				t.Fatalf("len(tasks)= %d, got %d", len(test.statuses), len(resp.Tasks))
+			}
 
-				if appErr.Code != test.status {
-					t.Errorf("appError.Code=%d, got %d", test.status, appErr.Code)
+			for _, st := range resp.Tasks {
+				if test.statuses[st.Id] != st.Status {
+					t.Errorf("status of %s= %s, got %s", st.Id, test.statuses[st.Id], st.Status)
 				}
+			}
 
-				if appErr.Fields != nil {
-					for name, value := range appErr.Fields {
-						if !slices.Contains(test.fields, name) {
-							t.Errorf("expected field %s to be invalid: %s", name, value)
-						}
-					}
-				}
+			if !slices.Equal(resp.NotFound, test.notFound) {
+				t.Errorf("notFound= %v, got %v", test.notFound, resp.NotFound)
 			}
 		})
 	}
-
 }
 
 func TestGetTaskById(t *testing.T) {
@@ -241,7 +951,7 @@ func TestGetTaskById(t *testing.T) {
 		},
 	}
 
-	rClient := brokertest.NewTestClient(t, context.Background(), "test_create_get_task_by_id_app")
+	rClient := brokertest.NewMemoryClient(t)
 
 	taskService, err := task.NewService(&memRepo, rClient)
 	if err != nil {
@@ -307,7 +1017,7 @@ func TestGetTaskById(t *testing.T) {
 
 			}
 
-			err := h.GetTaskById(ctx, w, r)
+			err := mid.Authorize(h.OwnerOnly())(h.GetTaskById)(ctx, w, r)
 			if !test.expectError {
 
 				if err != nil {
@@ -443,7 +1153,7 @@ func TestDeleteTaskById(t *testing.T) {
 		},
 	}
 
-	rClient := brokertest.NewTestClient(t, context.Background(), "test_delete_task_by_id_app")
+	rClient := brokertest.NewMemoryClient(t)
 	taskService, err := task.NewService(&memRepo, rClient)
 	if err != nil {
 		t.Fatalf("expected to create new service: %s", err)
@@ -506,7 +1216,7 @@ func TestDeleteTaskById(t *testing.T) {
 				ctx = auth.SetUser(r.Context(), taskCreator)
 			}
 
-			err := h.DeleteTaskById(ctx, w, r)
+			err := mid.Authorize(h.OwnerOrAdmin())(h.DeleteTaskById)(ctx, w, r)
 			if !test.expectError {
 				if err != nil {
 					t.Fatalf("expected to delete task with id %s: %s", test.input, err)
@@ -532,6 +1242,105 @@ func TestDeleteTaskById(t *testing.T) {
 
 // pagination and order by have solid tests against real database so we do not need to test against
 // memory repository just the parsing part of "order by" and "pagination"
+func TestDeleteTasks(t *testing.T) {
+	owner := user.User{Id: uuid.New(), Roles: []user.Role{user.RoleUser}, Enabled: true}
+	jan := time.Date(2024, time.January, 10, 0, 0, 0, 0, time.UTC)
+	dec := time.Date(2023, time.December, 10, 0, 0, 0, 0, time.UTC)
+
+	newRepo := func() *memory.Repository {
+		repo := memory.Repository{Tasks: make(map[uuid.UUID]task.Task)}
+		for _, tsk := range []task.Task{
+			{Id: uuid.New(), UserId: owner.Id, Status: task.StatusCompleted, CreatedAt: dec},
+			{Id: uuid.New(), UserId: owner.Id, Status: task.StatusCompleted, CreatedAt: jan},
+			{Id: uuid.New(), UserId: owner.Id, Status: task.StatusFailed, CreatedAt: dec},
+			{Id: uuid.New(), UserId: owner.Id, Status: task.StatusPending, CreatedAt: dec},
+			{Id: uuid.New(), UserId: uuid.New(), Status: task.StatusCompleted, CreatedAt: dec},
+		} {
+			repo.Tasks[tsk.Id] = tsk
+		}
+		return &repo
+	}
+
+	tests := map[string]struct {
+		query      string
+		statusCode int
+		deleted    int
+	}{
+		"every finished task": {
+			query:      "",
+			statusCode: http.StatusOK,
+			deleted:    3,
+		},
+		"completed before": {
+			query:      "?status=completed&before=2024-01-01",
+			statusCode: http.StatusOK,
+			deleted:    1,
+		},
+		"several statuses": {
+			query:      "?status=completed,failed&before=2024-01-01T00:00:00Z",
+			statusCode: http.StatusOK,
+			deleted:    2,
+		},
+		"unfinished status": {
+			query:      "?status=pending",
+			statusCode: http.StatusBadRequest,
+		},
+		"unknown status": {
+			query:      "?status=done",
+			statusCode: http.StatusBadRequest,
+		},
+		"invalid before": {
+			query:      "?before=yesterday",
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+			taskService, err := task.NewService(repo, brokertest.NewMemoryClient(t))
+			if err != nil {
+				t.Fatalf("expected to create the task service: %s", err)
+			}
+
+			h := tasks.Handler{TaskService: taskService}
+
+			r := httptest.NewRequest(http.MethodDelete, "/v1/api/tasks/"+test.query, nil)
+			w := httptest.NewRecorder()
+
+			err = h.DeleteTasks(auth.SetUser(r.Context(), owner), w, r)
+			if test.statusCode != http.StatusOK {
+				var appErr *errs.AppError
+				if !errors.As(err, &appErr) {
+					t.Fatalf("expected the error to be *appError: %T", err)
+				}
+
+				if appErr.Code != test.statusCode {
+					t.Errorf("appErr.Code= %d, got %d", test.statusCode, appErr.Code)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected to delete the tasks: %s", err)
+			}
+
+			var resp tasks.DeletedTasks
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("expected to decode the response body: %s", err)
+			}
+
+			if resp.Deleted != test.deleted {
+				t.Errorf("deleted= %d, got %d", test.deleted, resp.Deleted)
+			}
+
+			if len(repo.Tasks) != 5-test.deleted {
+				t.Errorf("tasks= %d, got %d", 5-test.deleted, len(repo.Tasks))
+			}
+		})
+	}
+}
+
 func TestGetAllTasks(t *testing.T) {
 	t.Parallel()
 
@@ -597,7 +1406,7 @@ func TestGetAllTasks(t *testing.T) {
 			task3Id: tsk3,
 		},
 	}
-	rClient := brokertest.NewTestClient(t, context.Background(), "test_get_all_tasks_app")
+	rClient := brokertest.NewMemoryClient(t)
 	taskService, err := task.NewService(&memRepo, rClient)
 	if err != nil {
 		t.Fatalf("expected to create new service: %s", err)
@@ -710,3 +1519,350 @@ func TestGetAllTasks(t *testing.T) {
 	}
 
 }
+
+func TestGetTasksByUserId(t *testing.T) {
+	t.Parallel()
+
+	taskCreator := user.User{
+		Id:   uuid.New(),
+		Name: "John Doe",
+		Email: mail.Address{
+			Name:    "john",
+			Address: "john@gmail.com",
+		},
+		Roles:        []user.Role{user.RoleUser},
+		PasswordHash: []byte("[hashed_pass]"),
+		Enabled:      true,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	admin := user.User{
+		Id:    uuid.New(),
+		Name:  "admin",
+		Roles: []user.Role{user.RoleAdmin},
+	}
+
+	stranger := user.User{
+		Id:    uuid.New(),
+		Name:  "jane",
+		Roles: []user.Role{user.RoleUser},
+	}
+
+	taskId := uuid.New()
+	memRepo := memory.Repository{
+		Tasks: map[uuid.UUID]task.Task{
+			taskId: {
+				Id:          taskId,
+				UserId:      taskCreator.Id,
+				Command:     "date",
+				Status:      task.StatusCompleted,
+				ScheduledAt: time.Now().Add(-time.Hour),
+				CreatedAt:   time.Now().Add(-time.Hour * 2),
+				UpdatedAt:   time.Now().Add(-time.Hour * 2),
+			},
+		},
+	}
+	rClient := brokertest.NewMemoryClient(t)
+	taskService, err := task.NewService(&memRepo, rClient)
+	if err != nil {
+		t.Fatalf("expected to create new service: %s", err)
+	}
+
+	userRepo := userMemRepo.Repository{
+		Users: map[uuid.UUID]user.User{
+			taskCreator.Id: taskCreator,
+		},
+	}
+
+	h := tasks.Handler{
+		TaskService: taskService,
+		UserService: user.NewService(&userRepo, nil),
+	}
+
+	tests := map[string]struct {
+		caller      user.User
+		userId      string
+		expectError bool
+		status      int
+	}{
+		"admin": {
+			caller: admin,
+			userId: taskCreator.Id.String(),
+			status: http.StatusOK,
+		},
+		"the user itself": {
+			caller: taskCreator,
+			userId: taskCreator.Id.String(),
+			status: http.StatusOK,
+		},
+		"another user": {
+			caller:      stranger,
+			userId:      taskCreator.Id.String(),
+			expectError: true,
+			status:      http.StatusUnauthorized,
+		},
+		"user not found": {
+			caller:      admin,
+			userId:      uuid.NewString(),
+			expectError: true,
+			status:      http.StatusNotFound,
+		},
+		"invalid id": {
+			caller:      admin,
+			userId:      "abc",
+			expectError: true,
+			status:      http.StatusBadRequest,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/api/users/"+test.userId+"/tasks", nil)
+			r.SetPathValue("id", test.userId)
+			w := httptest.NewRecorder()
+			ctx := auth.SetUser(r.Context(), test.caller)
+
+			err := mid.Authorize(users.SelfOrAdmin)(h.GetTasksByUserId)(ctx, w, r)
+			if !test.expectError {
+				if err != nil {
+					t.Fatalf("expected to get the user's tasks: %s", err)
+				}
+
+				var resp []tasks.Task
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatalf("should be able to decode response body: %s", err)
+				}
+
+				if len(resp) != 1 {
+					t.Errorf("expected the results to be 1 task got %d", len(resp))
+				}
+				return
+			}
+
+			var appErr *errs.AppError
+			if !errors.As(err, &appErr) {
+				t.Fatalf("expected the error type to be *appError, got %T", err)
+			}
+
+			if appErr.Code != test.status {
+				t.Errorf("appError.Code=%d, got %d", test.status, appErr.Code)
+			}
+		})
+	}
+}
+
+func TestGetUpcomingTasks(t *testing.T) {
+	t.Parallel()
+
+	usr := user.User{
+		Id:    uuid.New(),
+		Name:  "John Doe",
+		Roles: []user.Role{user.RoleUser},
+	}
+
+	from := time.Date(2026, time.October, 16, 10, 0, 0, 0, time.UTC)
+
+	scheduled := map[uuid.UUID]time.Time{
+		uuid.New(): from.Add(time.Minute * 10),
+		uuid.New(): from.Add(time.Minute * 50),
+		uuid.New(): from.Add(time.Hour*3 + time.Minute),
+		//outside of the range
+		uuid.New(): from.Add(time.Hour * 25),
+	}
+
+	memRepo := memory.Repository{
+		Tasks: make(map[uuid.UUID]task.Task),
+	}
+	for id, at := range scheduled {
+		memRepo.Tasks[id] = task.Task{
+			Id:          id,
+			UserId:      usr.Id,
+			Command:     "date",
+			Status:      task.StatusPending,
+			ScheduledAt: at,
+		}
+	}
+
+	//not pending anymore
+	done := uuid.New()
+	memRepo.Tasks[done] = task.Task{Id: done, UserId: usr.Id, Status: task.StatusCompleted, ScheduledAt: from.Add(time.Minute)}
+
+	rClient := brokertest.NewMemoryClient(t)
+	taskService, err := task.NewService(&memRepo, rClient)
+	if err != nil {
+		t.Fatalf("expected to create new service: %s", err)
+	}
+
+	h := tasks.Handler{
+		TaskService: taskService,
+	}
+
+	tests := map[string]struct {
+		query   string
+		status  int
+		windows []int
+	}{
+		"hourly windows": {
+			query:   "from=2026-10-16T10:00:00Z&to=2026-10-17T10:00:00Z",
+			status:  http.StatusOK,
+			windows: []int{2, 1},
+		},
+		"single window": {
+			query:   "from=2026-10-16T10:00:00Z&to=2026-10-17T10:00:00Z&window=24h",
+			status:  http.StatusOK,
+			windows: []int{3},
+		},
+		"to before from": {
+			query:  "from=2026-10-16T10:00:00Z&to=2026-10-16T09:00:00Z",
+			status: http.StatusBadRequest,
+		},
+		"range too large": {
+			query:  "from=2026-10-16T10:00:00Z&to=2027-10-16T10:00:00Z",
+			status: http.StatusBadRequest,
+		},
+		"invalid window": {
+			query:  "from=2026-10-16T10:00:00Z&window=1s",
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/api/tasks/upcoming?"+test.query, nil)
+			w := httptest.NewRecorder()
+			ctx := auth.SetUser(r.Context(), usr)
+
+			err := h.GetUpcomingTasks(ctx, w, r)
+			if test.status != http.StatusOK {
+				var appErr *errs.AppError
+				if !errors.As(err, &appErr) {
+					t.Fatalf("expected the error type to be *appError, got %T", err)
+				}
+
+				if appErr.Code != test.status {
+					t.Errorf("appError.Code=%d, got %d", test.status, appErr.Code)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected to get upcoming tasks: %s", err)
+			}
+
+			var resp tasks.Upcoming
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("should be able to decode response body: %s", err)
+			}
+
+			var got []int
+			for _, window := range resp.Windows {
+				got = append(got, len(window.Tasks))
+			}
+
+			if !slices.Equal(got, test.windows) {
+				t.Errorf("windows= %v, got %v", test.windows, got)
+			}
+		})
+	}
+}
+
+func TestGetTasksByView(t *testing.T) {
+	t.Parallel()
+
+	usr := user.User{
+		Id:      uuid.New(),
+		Name:    "John Doe",
+		Roles:   []user.Role{user.RoleUser},
+		Enabled: true,
+	}
+
+	memRepo := memory.Repository{Tasks: make(map[uuid.UUID]task.Task)}
+	for i, command := range []string{"date", "ls"} {
+		tsk := task.Task{
+			Id:        uuid.New(),
+			UserId:    usr.Id,
+			Command:   command,
+			Status:    task.StatusCompleted,
+			CreatedAt: time.Now().Add(time.Duration(i) * time.Minute),
+		}
+		memRepo.Tasks[tsk.Id] = tsk
+	}
+
+	taskService, err := task.NewService(&memRepo, brokertest.NewMemoryClient(t))
+	if err != nil {
+		t.Fatalf("expected to create new service: %s", err)
+	}
+
+	viewService := view.NewService(&viewMemRepo.Repository{})
+	_, err = viewService.CreateView(context.Background(), view.NewView{
+		UserId: usr.Id,
+		Name:   "latest",
+		Order:  task.OrderBy{Field: task.FieldCreatedAt, Direction: task.DirectionDESC},
+		Rows:   1,
+	})
+	if err != nil {
+		t.Fatalf("expected to create the view: %s", err)
+	}
+
+	h := tasks.Handler{
+		TaskService: taskService,
+		ViewService: viewService,
+	}
+
+	tests := map[string]struct {
+		query    string
+		status   int
+		commands []string
+	}{
+		"view": {
+			query:    "view=latest&after=",
+			status:   http.StatusOK,
+			commands: []string{"ls"},
+		},
+		"request overrides view": {
+			query:    "view=latest&after=&rows=2&orderby=createdAt,asc",
+			status:   http.StatusOK,
+			commands: []string{"date", "ls"},
+		},
+		"unknown view": {
+			query:  "view=missing",
+			status: http.StatusNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/api/tasks?"+test.query, nil)
+			w := httptest.NewRecorder()
+			ctx := auth.SetUser(r.Context(), usr)
+
+			err := h.GetAllTasksForUser(ctx, w, r)
+			if test.status != http.StatusOK {
+				var appErr *errs.AppError
+				if !errors.As(err, &appErr) || appErr.Code != test.status {
+					t.Fatalf("expected an app error with status %d, got %v", test.status, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected to list the tasks of the view: %s", err)
+			}
+
+			var resp tasks.TaskPage
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("should be able to decode response body: %s", err)
+			}
+
+			commands := make([]string, len(resp.Tasks))
+			for i, tsk := range resp.Tasks {
+				commands[i] = tsk.Command
+			}
+
+			if !slices.Equal(commands, test.commands) {
+				t.Errorf("commands= %v, got %v", test.commands, commands)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/app/api/auth"
+	"github.com/hamidoujand/task-scheduler/app/api/errs"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	"github.com/hamidoujand/task-scheduler/foundation/web"
+)
+
+const (
+	defaultUpcomingRange  = time.Hour * 24
+	maxUpcomingRange      = time.Hour * 24 * 31
+	defaultUpcomingWindow = time.Hour
+	minUpcomingWindow     = time.Minute
+)
+
+// GetUpcomingTasks returns the pending tasks of the authenticated user scheduled between "from" and "to", grouped
+// into windows of "window" starting at "from".
+func (h *Handler) GetUpcomingTasks(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	usr, err := auth.GetUser(ctx)
+	if err != nil {
+		return errs.NewAppCodeError(http.StatusUnauthorized, errs.CodeNotAuthenticated, "unauthorized")
+	}
+
+	from, to, window, err := parseUpcomingRange(r, time.Now())
+	if err != nil {
+		return errs.NewAppCodeError(http.StatusBadRequest, errs.CodeInvalidQuery, err.Error())
+	}
+
+	tasks, err := h.TaskService.GetUpcomingTasks(ctx, usr.Id, from, to)
+	if err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, groupUpcoming(tasks, from, to, window))
+}
+
+func parseUpcomingRange(r *http.Request, now time.Time) (from time.Time, to time.Time, window time.Duration, err error) {
+	query := r.URL.Query()
+
+	from = now
+	if raw := query.Get("from"); raw != "" {
+		from, err = time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return time.Time{}, time.Time{}, 0, fmt.Errorf("invalid from %q, expected RFC3339", raw)
+		}
+	}
+
+	to = from.Add(defaultUpcomingRange)
+	if raw := query.Get("to"); raw != "" {
+		to, err = time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return time.Time{}, time.Time{}, 0, fmt.Errorf("invalid to %q, expected RFC3339", raw)
+		}
+	}
+
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, 0, fmt.Errorf("to must be after from")
+	}
+
+	if to.Sub(from) > maxUpcomingRange {
+		return time.Time{}, time.Time{}, 0, fmt.Errorf("range must be at most %s", maxUpcomingRange)
+	}
+
+	window = defaultUpcomingWindow
+	if raw := query.Get("window"); raw != "" {
+		window, err = time.ParseDuration(raw)
+		if err != nil || window < minUpcomingWindow {
+			return time.Time{}, time.Time{}, 0, fmt.Errorf("invalid window %q, expected a duration of at least %s", raw, minUpcomingWindow)
+		}
+	}
+
+	return from, to, window, nil
+}
+
+// groupUpcoming puts the tasks, ordered by their scheduled time, into windows starting at from, windows without
+// tasks are left out.
+func groupUpcoming(tasks []task.Task, from time.Time, to time.Time, window time.Duration) Upcoming {
+	upcoming := Upcoming{
+		From:    from,
+		To:      to,
+		Windows: []UpcomingWindow{},
+	}
+
+	for _, t := range tasks {
+		idx := t.ScheduledAt.Sub(from) / window
+		start := from.Add(idx * window)
+
+		last := len(upcoming.Windows) - 1
+		if last < 0 || !upcoming.Windows[last].Start.Equal(start) {
+			end := start.Add(window)
+			if end.After(to) {
+				end = to
+			}
+
+			upcoming.Windows = append(upcoming.Windows, UpcomingWindow{Start: start, End: end})
+			last++
+		}
+		upcoming.Windows[last].Tasks = append(upcoming.Windows[last].Tasks, fromDomainTask(t))
+	}
+
+	return upcoming
+}
@@ -0,0 +1,55 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/hamidoujand/task-scheduler/app/api/auth"
+	"github.com/hamidoujand/task-scheduler/app/api/errs"
+	"github.com/hamidoujand/task-scheduler/business/domain/view"
+)
+
+// applyView fills the list parameters of the request from the view of the authenticated user in the "view" query
+// parameter, the parameters of the request take precedence over the ones of the view.
+func (h *Handler) applyView(ctx context.Context, r *http.Request) error {
+	query := r.URL.Query()
+
+	name := query.Get("view")
+	if name == "" {
+		return nil
+	}
+
+	usr, err := auth.GetUser(ctx)
+	if err != nil {
+		return errs.NewAppCodeError(http.StatusUnauthorized, errs.CodeNotAuthenticated, "unauthorized")
+	}
+
+	if h.ViewService == nil {
+		return errs.NewAppCodeErrorf(http.StatusNotFound, errs.CodeViewNotFound, "view %q not found", name)
+	}
+
+	v, err := h.ViewService.GetView(ctx, usr.Id, name)
+	if err != nil {
+		if errors.Is(err, view.ErrViewNotFound) {
+			return errs.NewAppCodeErrorf(http.StatusNotFound, errs.CodeViewNotFound, "view %q not found", name)
+		}
+		return errs.NewAppInternalErr(err)
+	}
+
+	if !query.Has("q") && v.Search != "" {
+		query.Set("q", v.Search)
+	}
+
+	if !query.Has("orderby") {
+		query.Set("orderby", v.Order.String())
+	}
+
+	if !query.Has("rows") && v.Rows > 0 {
+		query.Set("rows", strconv.Itoa(v.Rows))
+	}
+
+	r.URL.RawQuery = query.Encode()
+	return nil
+}
@@ -0,0 +1,49 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/app/api/errs"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	"github.com/hamidoujand/task-scheduler/foundation/web"
+)
+
+// WaitForTask blocks until the task finished or "timeout" elapsed and returns it, a task that is not finished yet
+// is returned as is once the timeout elapsed and a timeout of 0s returns it right away. The timeout is cut down to MaxWait, which is also the default since the
+// write timeout of the server cuts off any longer wait.
+func (h *Handler) WaitForTask(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	taskId := r.PathValue("id")
+
+	taskUUID, err := uuid.Parse(taskId)
+	if err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidId, "%q not a valid uuid", taskId)
+	}
+
+	timeout := h.MaxWait
+	if raw := r.URL.Query().Get("timeout"); raw != "" {
+		timeout, err = time.ParseDuration(raw)
+		if err != nil || timeout < 0 {
+			return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidQuery, "invalid timeout %q, expected a duration like 30s or 0s to not wait", raw)
+		}
+	}
+	timeout = min(timeout, h.MaxWait)
+
+	t, err := h.TaskService.WaitForTask(ctx, taskUUID, timeout)
+	if err != nil {
+		if errors.Is(err, task.ErrTaskNotFound) {
+			return errs.NewAppCodeErrorf(http.StatusNotFound, errs.CodeTaskNotFound, "task with id %q not found", taskId)
+		}
+		return errs.NewAppInternalErr(err)
+	}
+
+	t, err = h.TaskService.LoadResult(ctx, t)
+	if err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, fromDomainTask(t))
+}
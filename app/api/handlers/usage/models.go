@@ -0,0 +1,91 @@
+package usage
+
+import (
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/domain/usage"
+)
+
+// monthLayout is how months are written in the query and the responses.
+const monthLayout = "2006-01"
+
+// Usage represents the usage of the months between From and To that goes to client, months without executions are
+// left out. Budget is only set for the usage of a single user.
+type Usage struct {
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	Months []Monthly `json:"months"`
+	Budget *Budget   `json:"budget,omitempty"`
+}
+
+// Monthly represents the usage of a user during a month, cpu seconds and the memory peak are zero for executions the
+// runtime could not measure.
+type Monthly struct {
+	UserId          string  `json:"userId"`
+	Month           string  `json:"month"`
+	Executions      int     `json:"executions"`
+	CPUSeconds      float64 `json:"cpuSeconds"`
+	MemoryPeakBytes int64   `json:"memoryPeakBytes"`
+	DurationSeconds float64 `json:"durationSeconds"`
+}
+
+func toAppUsage(from time.Time, to time.Time, monthly []usage.Monthly) Usage {
+	u := Usage{
+		From:   from.Format(monthLayout),
+		To:     to.Format(monthLayout),
+		Months: make([]Monthly, len(monthly)),
+	}
+
+	for i, m := range monthly {
+		u.Months[i] = Monthly{
+			UserId:          m.UserId.String(),
+			Month:           m.Month.Format(monthLayout),
+			Executions:      m.Executions,
+			CPUSeconds:      m.CPU.Seconds(),
+			MemoryPeakBytes: m.MemoryPeak,
+			DurationSeconds: m.Duration.Seconds(),
+		}
+	}
+	return u
+}
+
+// Budget represents the budget of a user that goes to client along with how much of it is used up during Month, a
+// zero limit means unlimited.
+type Budget struct {
+	UserId          string    `json:"userId"`
+	LimitSeconds    int64     `json:"limitSeconds"`
+	Hard            bool      `json:"hard"`
+	Month           string    `json:"month"`
+	ConsumedSeconds float64   `json:"consumedSeconds"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+func fromDomainBudgetStatus(bs usage.BudgetStatus) Budget {
+	return Budget{
+		UserId:          bs.UserId.String(),
+		LimitSeconds:    int64(bs.Limit.Seconds()),
+		Hard:            bs.Hard,
+		Month:           bs.Month.Format(monthLayout),
+		ConsumedSeconds: bs.Consumed.Seconds(),
+		UpdatedAt:       bs.UpdatedAt,
+	}
+}
+
+// UpdateBudget represents the parts of a budget an admin can update, a zero limit means unlimited.
+type UpdateBudget struct {
+	LimitSeconds *int64 `json:"limitSeconds" validate:"omitempty,min=0"`
+	Hard         *bool  `json:"hard"`
+}
+
+func (ub UpdateBudget) toServiceUpdateBudget() usage.UpdateBudget {
+	var limit *time.Duration
+	if ub.LimitSeconds != nil {
+		l := time.Duration(*ub.LimitSeconds) * time.Second
+		limit = &l
+	}
+
+	return usage.UpdateBudget{
+		Limit: limit,
+		Hard:  ub.Hard,
+	}
+}
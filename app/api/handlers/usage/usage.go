@@ -0,0 +1,188 @@
+// Package usage provides the handlers used for reading the resources consumed by the executions of tasks.
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/app/api/auth"
+	"github.com/hamidoujand/task-scheduler/app/api/errs"
+	"github.com/hamidoujand/task-scheduler/business/domain/usage"
+	"github.com/hamidoujand/task-scheduler/business/domain/user"
+	"github.com/hamidoujand/task-scheduler/foundation/web"
+)
+
+const (
+	defaultUsageMonths = 12
+	maxUsageMonths     = 36
+)
+
+// Handler represents set of usage handlers.
+type Handler struct {
+	Validator    *errs.AppValidator
+	UsageService *usage.Service
+	UserService  *user.Service
+}
+
+// GetUsage returns the usage of the authenticated user per month between the months "from" and "to" ("2006-01"),
+// the last 12 months by default, along with their budget. Admins get the usage of every user without a budget, or of
+// the one in "userId".
+func (h *Handler) GetUsage(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	usr, err := auth.GetUser(ctx)
+	if err != nil {
+		return errs.NewAppCodeError(http.StatusUnauthorized, errs.CodeNotAuthenticated, "unauthorized")
+	}
+
+	from, to, err := parseMonths(r, time.Now())
+	if err != nil {
+		return errs.NewAppCodeError(http.StatusBadRequest, errs.CodeInvalidQuery, err.Error())
+	}
+
+	userId, err := h.usageOwner(r, usr)
+	if err != nil {
+		return err
+	}
+
+	monthly, err := h.UsageService.GetMonthly(ctx, userId, from, to)
+	if err != nil {
+		if errors.Is(err, usage.ErrInvalidRange) {
+			return errs.NewAppCodeError(http.StatusBadRequest, errs.CodeInvalidQuery, err.Error())
+		}
+		return errs.NewAppInternalErr(err)
+	}
+
+	u := toAppUsage(from, to, monthly)
+	if userId != uuid.Nil {
+		status, err := h.UsageService.GetBudgetStatus(ctx, userId)
+		if err != nil {
+			return errs.NewAppInternalErr(err)
+		}
+		budget := fromDomainBudgetStatus(status)
+		u.Budget = &budget
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, u)
+}
+
+// GetBudget returns the budget of the user in the "id" path value along with how much of it is used up.
+func (h *Handler) GetBudget(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	userId, err := h.pathUser(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	status, err := h.UsageService.GetBudgetStatus(ctx, userId)
+	if err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, fromDomainBudgetStatus(status))
+}
+
+// UpdateBudget updates the budget of the user in the "id" path value.
+func (h *Handler) UpdateBudget(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	userId, err := h.pathUser(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	var ub UpdateBudget
+	if err := web.Decode(r, &ub); err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidJSON, "invalid json: %s", err)
+	}
+
+	fields, ok := h.Validator.Check(ub)
+	if !ok {
+		return errs.NewAppValidationError(http.StatusBadRequest, "invalid input", fields)
+	}
+
+	if _, err := h.UsageService.SetBudget(ctx, userId, ub.toServiceUpdateBudget()); err != nil {
+		if errors.Is(err, usage.ErrInvalidBudget) {
+			return errs.NewAppCodeError(http.StatusBadRequest, errs.CodeInvalidBudget, err.Error())
+		}
+		return errs.NewAppInternalErr(err)
+	}
+
+	status, err := h.UsageService.GetBudgetStatus(ctx, userId)
+	if err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, fromDomainBudgetStatus(status))
+}
+
+// pathUser parses the "id" path value and makes sure the user exists.
+func (h *Handler) pathUser(ctx context.Context, r *http.Request) (uuid.UUID, error) {
+	id := r.PathValue("id")
+
+	userId, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.UUID{}, errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidId, "%q not a valid uuid", id)
+	}
+
+	if _, err := h.UserService.GetUserById(ctx, userId); err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return uuid.UUID{}, errs.NewAppCodeErrorf(http.StatusNotFound, errs.CodeUserNotFound, "user with id %q not found", id)
+		}
+		return uuid.UUID{}, errs.NewAppInternalErr(err)
+	}
+	return userId, nil
+}
+
+// usageOwner returns whose usage is asked for, uuid.Nil stands for every user.
+func (h *Handler) usageOwner(r *http.Request, usr user.User) (uuid.UUID, error) {
+	raw := r.URL.Query().Get("userId")
+	admin := slices.Contains(usr.Roles, user.RoleAdmin)
+
+	if raw == "" {
+		if admin {
+			return uuid.Nil, nil
+		}
+		return usr.Id, nil
+	}
+
+	userId, err := uuid.Parse(raw)
+	if err != nil {
+		return uuid.Nil, errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidId, "%q not a valid uuid", raw)
+	}
+
+	if userId != usr.Id && !admin {
+		return uuid.Nil, errs.NewAppCodeError(http.StatusForbidden, errs.CodeNotPermitted, "usage of other users can only be read by an admin")
+	}
+	return userId, nil
+}
+
+func parseMonths(r *http.Request, now time.Time) (from time.Time, to time.Time, err error) {
+	query := r.URL.Query()
+
+	to = usage.MonthOf(now)
+	if raw := query.Get("to"); raw != "" {
+		to, err = time.Parse(monthLayout, raw)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid to %q, expected YYYY-MM", raw)
+		}
+	}
+
+	from = to.AddDate(0, -(defaultUsageMonths - 1), 0)
+	if raw := query.Get("from"); raw != "" {
+		from, err = time.Parse(monthLayout, raw)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid from %q, expected YYYY-MM", raw)
+		}
+	}
+
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("to must not be before from")
+	}
+
+	if from.AddDate(0, maxUsageMonths, 0).Before(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("range must be at most %d months", maxUsageMonths)
+	}
+
+	return from, to, nil
+}
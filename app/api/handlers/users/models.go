@@ -17,6 +17,7 @@ type User struct {
 	PasswordHash []byte    `json:"-"`
 	Token        string    `json:"token,omitempty"`
 	Enabled      bool      `json:"enabled"`
+	Verified     bool      `json:"verified"`
 	CreatedAt    time.Time `json:"createdAt"`
 	UpdatedAt    time.Time `json:"updatedAt"`
 }
@@ -29,6 +30,7 @@ func toAppUser(usr user.User) User {
 		Roles:        user.EncodeRoles(usr.Roles),
 		PasswordHash: usr.PasswordHash,
 		Enabled:      usr.Enabled,
+		Verified:     usr.Verified,
 		CreatedAt:    usr.CreatedAt,
 		UpdatedAt:    usr.UpdatedAt,
 	}
@@ -42,11 +44,18 @@ func toAppUserWithToken(usr user.User, token string) User {
 		PasswordHash: usr.PasswordHash,
 		Token:        token,
 		Enabled:      usr.Enabled,
+		Verified:     usr.Verified,
 		CreatedAt:    usr.CreatedAt,
 		UpdatedAt:    usr.UpdatedAt,
 	}
 }
 
+// DisabledUser represents the user that was disabled along with how many of their tasks were canceled.
+type DisabledUser struct {
+	User          User `json:"user"`
+	CanceledTasks int  `json:"canceledTasks"`
+}
+
 // NewUser represents all of the required data to create a new user.
 type NewUser struct {
 	Name            string   `json:"name" validate:"required"`
@@ -79,7 +88,7 @@ type UpdateUser struct {
 	Email           *string `json:"email" validate:"omitempty,email"`
 	Enabled         *bool   `json:"enabled"`
 	Password        *string `json:"password" validate:"omitempty,min=8"`
-	PasswordConfirm *string `json:"passowordConfirm" validate:"omitempty,eqfield=Password"`
+	PasswordConfirm *string `json:"passwordConfirm" validate:"omitempty,eqfield=Password"`
 }
 
 func (u UpdateUser) toServiceUpdateUser() (user.UpdateUser, error) {
@@ -100,6 +109,13 @@ func (u UpdateUser) toServiceUpdateUser() (user.UpdateUser, error) {
 	}, nil
 }
 
+// UpdateMe represents the data users update their own profile with, they are not allowed to change whether they are
+// enabled or their roles.
+type UpdateMe struct {
+	UpdateUser
+	Roles []string `json:"roles"`
+}
+
 // UpdateRole represents required data for updating roles
 type UpdateRole struct {
 	//TODO search validator package for better enum like validation.
@@ -132,3 +148,20 @@ type Login struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,min=8"`
 }
+
+// VerifyEmail represents required data for verifying an email.
+type VerifyEmail struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// RequestPasswordReset represents required data for requesting a password reset.
+type RequestPasswordReset struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPassword represents required data for resetting a password.
+type ResetPassword struct {
+	Token           string `json:"token" validate:"required"`
+	Password        string `json:"password" validate:"required,min=8"`
+	PasswordConfirm string `json:"passwordConfirm" validate:"required,eqfield=Password"`
+}
@@ -0,0 +1,88 @@
+package users
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hamidoujand/task-scheduler/business/domain/user"
+)
+
+func parsePagination(r *http.Request) (rows int, page int, err error) {
+	rows, page = 10, 1
+
+	if rowsString := r.URL.Query().Get("rows"); rowsString != "" {
+		rows, err = strconv.Atoi(rowsString)
+		if err != nil || rows <= 0 {
+			return 0, 0, errors.New("invalid rows parameter")
+		}
+	}
+
+	if pageString := r.URL.Query().Get("page"); pageString != "" {
+		page, err = strconv.Atoi(pageString)
+		if err != nil || page <= 0 {
+			return 0, 0, errors.New("invalid page parameter")
+		}
+	}
+	return rows, page, nil
+}
+
+// parseOrder parses "orderby" in the form of "field" or "field,direction", users are ordered by the time they were
+// created by default.
+func parseOrder(r *http.Request) (user.OrderBy, error) {
+	order := user.OrderBy{
+		Field:     user.FieldCreatedAt,
+		Direction: user.DirectionASC,
+	}
+
+	orderString := r.URL.Query().Get("orderby")
+	if orderString == "" {
+		return order, nil
+	}
+
+	parts := strings.Split(orderString, ",")
+	if len(parts) > 2 {
+		return user.OrderBy{}, fmt.Errorf("invalid orderby: %q", orderString)
+	}
+
+	field, err := user.ParseField(parts[0])
+	if err != nil {
+		return user.OrderBy{}, fmt.Errorf("unknown field: %q", parts[0])
+	}
+	order.Field = field
+
+	if len(parts) == 2 {
+		dir, err := user.ParseDirection(parts[1])
+		if err != nil {
+			return user.OrderBy{}, fmt.Errorf("unknown direction: %q", parts[1])
+		}
+		order.Direction = dir
+	}
+	return order, nil
+}
+
+func parseFilter(r *http.Request) (user.Filter, error) {
+	var filter user.Filter
+	query := r.URL.Query()
+
+	if roleString := query.Get("role"); roleString != "" {
+		role, err := user.ParseRole(roleString)
+		if err != nil {
+			return user.Filter{}, fmt.Errorf("unknown role: %q", roleString)
+		}
+		filter.Role = &role
+	}
+
+	if enabledString := query.Get("enabled"); enabledString != "" {
+		enabled, err := strconv.ParseBool(enabledString)
+		if err != nil {
+			return user.Filter{}, fmt.Errorf("invalid enabled parameter: %q", enabledString)
+		}
+		filter.Enabled = &enabled
+	}
+
+	filter.Email = strings.TrimSpace(query.Get("email"))
+	return filter, nil
+}
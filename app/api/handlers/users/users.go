@@ -2,21 +2,31 @@ package users
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
+	"net"
 	"net/http"
 	"net/mail"
 	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
 	"github.com/hamidoujand/task-scheduler/app/api/auth"
 	"github.com/hamidoujand/task-scheduler/app/api/errs"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
 	"github.com/hamidoujand/task-scheduler/business/domain/user"
 	"github.com/hamidoujand/task-scheduler/foundation/web"
 )
 
+// mailer represents the behaviour required for sending verification and password reset emails.
+type mailer interface {
+	Send(ctx context.Context, to mail.Address, subject string, body string) error
+}
+
 // Handler represents set of APIs used
 type Handler struct {
 	Validator    *errs.AppValidator
@@ -24,14 +34,20 @@ type Handler struct {
 	Auth         *auth.Auth
 	ActiveKID    string
 	TokenAge     time.Duration
+	Mailer       mailer
+	//TaskService is optional, with it the tasks of a deleted user are deleted along with the user.
+	TaskService *task.Service
+	//WithinTran is optional, it runs fn with services bound to a single transaction. Without it fn runs against the
+	//services of the handler.
+	WithinTran func(ctx context.Context, fn func(usrs *user.Service, tsks *task.Service) error) error
 }
 
 // CreateUser creates a user inside the system, returns errors on duplicated emails and invalid inputs.
 func (h *Handler) CreateUser(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	var nu NewUser
 
-	if err := json.NewDecoder(r.Body).Decode(&nu); err != nil {
-		return errs.NewAppErrorf(http.StatusBadRequest, "invalid json data: %s", err.Error())
+	if err := web.Decode(r, &nu); err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidJSON, "invalid json data: %s", err.Error())
 	}
 
 	//validate
@@ -43,14 +59,14 @@ func (h *Handler) CreateUser(ctx context.Context, w http.ResponseWriter, r *http
 	//TODO add a custom validator for "Roles" field
 	usrService, err := nu.toServiceNewUser()
 	if err != nil {
-		return errs.NewAppError(http.StatusBadRequest, err.Error())
+		return errs.NewAppCodeError(http.StatusBadRequest, errs.CodeInvalidRole, err.Error())
 	}
 
 	usr, err := h.UsersService.CreateUser(ctx, usrService)
 
 	if err != nil {
 		if errors.Is(err, user.ErrUniqueEmail) {
-			return errs.NewAppError(http.StatusConflict, err.Error())
+			return errs.NewAppCodeError(http.StatusConflict, errs.CodeEmailInUse, err.Error())
 		}
 		return errs.NewAppInternalErr(err)
 	}
@@ -64,72 +80,110 @@ func (h *Handler) GetUserById(ctx context.Context, w http.ResponseWriter, r *htt
 
 	userId, err := uuid.Parse(id)
 	if err != nil {
-		return errs.NewAppErrorf(http.StatusBadRequest, "%q, invalid uuid", id)
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidId, "%q, invalid uuid", id)
 	}
 
 	usr, err := h.UsersService.GetUserById(ctx, userId)
 	if err != nil {
 		if errors.Is(err, user.ErrUserNotFound) {
-			return errs.NewAppErrorf(http.StatusNotFound, "%q, no user with this id", id)
+			return errs.NewAppCodeErrorf(http.StatusNotFound, errs.CodeUserNotFound, "%q, no user with this id", id)
 		}
 		return errs.NewAppInternalErr(err)
 	}
 
-	return web.Respond(ctx, w, http.StatusOK, toAppUser(usr))
+	return web.RespondETag(ctx, w, r, http.StatusOK, userETag(usr), toAppUser(usr))
 }
 
-// DeleteUserById is going to delete user when the the role is admin or user itself and returns possible errors.
-func (h *Handler) DeleteUserById(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-	id := r.PathValue("id")
-	userId, err := uuid.Parse(id)
+// ListUsers returns a page of the users, supports the "page", "rows" and "orderby" query parameters along with the
+// "role", "enabled" and "email" filters.
+func (h *Handler) ListUsers(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	rows, page, err := parsePagination(r)
 	if err != nil {
-		return errs.NewAppErrorf(http.StatusBadRequest, "%q not a valid uuid", id)
+		return errs.NewAppCodeError(http.StatusBadRequest, errs.CodeInvalidQuery, err.Error())
 	}
 
-	usr, err := auth.GetUser(ctx)
+	order, err := parseOrder(r)
+	if err != nil {
+		return errs.NewAppCodeError(http.StatusBadRequest, errs.CodeInvalidQuery, err.Error())
+	}
+
+	filter, err := parseFilter(r)
+	if err != nil {
+		return errs.NewAppCodeError(http.StatusBadRequest, errs.CodeInvalidQuery, err.Error())
+	}
+
+	usrs, err := h.UsersService.QueryUsers(ctx, filter, order, rows, page)
 	if err != nil {
-		return errs.NewAppError(http.StatusUnauthorized, "unauthorized")
+		return errs.NewAppInternalErr(err)
 	}
 
-	if userId != usr.Id && !isItAdmin(usr.Roles) {
-		return errs.NewAppError(http.StatusUnauthorized, "unauthorized")
+	appUsers := make([]User, len(usrs))
+	for i, usr := range usrs {
+		appUsers[i] = toAppUser(usr)
+	}
+	return web.Respond(ctx, w, http.StatusOK, appUsers)
+}
+
+// DeleteUserById is going to delete user when the the role is admin or user itself and returns possible errors.
+func (h *Handler) DeleteUserById(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	userId, err := uuid.Parse(id)
+	if err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidId, "%q not a valid uuid", id)
 	}
 
 	fetched, err := h.UsersService.GetUserById(ctx, userId)
 	if err != nil {
 		if errors.Is(err, user.ErrUserNotFound) {
-			return errs.NewAppErrorf(http.StatusNotFound, "user with id %s not found", userId)
+			return errs.NewAppCodeErrorf(http.StatusNotFound, errs.CodeUserNotFound, "user with id %s not found", userId)
 		}
 		return errs.NewAppInternalErr(err)
 	}
 
-	if err := h.UsersService.DeleteUser(ctx, fetched); err != nil {
+	err = h.withinTran(ctx, func(usrs *user.Service, tsks *task.Service) error {
+		if tsks != nil {
+			if err := tsks.DeleteTasksByUserId(ctx, fetched.Id); err != nil {
+				return fmt.Errorf("delete tasks: %w", err)
+			}
+		}
+		return usrs.DeleteUser(ctx, fetched)
+	})
+	if err != nil {
 		return errs.NewAppInternalErr(err)
 	}
 
 	return web.Respond(ctx, w, http.StatusNoContent, nil)
 }
 
-// UpdateUser updates a user and returns the possible errors.
+func (h *Handler) withinTran(ctx context.Context, fn func(usrs *user.Service, tsks *task.Service) error) error {
+	if h.WithinTran == nil {
+		return fn(h.UsersService, h.TaskService)
+	}
+	return h.WithinTran(ctx, fn)
+}
+
+// UpdateUser updates a user and returns the possible errors, only admins can enable or disable users.
 func (h *Handler) UpdateUser(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	usr, err := auth.GetUser(ctx)
+	if err != nil {
+		return errs.NewAppCodeError(http.StatusUnauthorized, errs.CodeNotAuthenticated, "unauthorized")
+	}
+
 	id := r.PathValue("id")
 
 	userId, err := uuid.Parse(id)
 	if err != nil {
-		return errs.NewAppErrorf(http.StatusBadRequest, "%q is invalid uuid", userId)
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidId, "%q is invalid uuid", userId)
 	}
 
-	usr, err := auth.GetUser(ctx)
-	if err != nil {
-		return errs.NewAppError(http.StatusUnauthorized, "unauthorized")
+	var uu UpdateUser
+	if err := web.Decode(r, &uu); err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidJSON, "invalid json: %s", err.Error())
 	}
 
-	if usr.Id != userId && !isItAdmin(usr.Roles) {
-		return errs.NewAppError(http.StatusUnauthorized, "unauthorized")
-	}
-	var uu UpdateUser
-	if err := json.NewDecoder(r.Body).Decode(&uu); err != nil {
-		return errs.NewAppErrorf(http.StatusBadRequest, "invalid json: %s", err.Error())
+	//same as UpdateMe, users reach their own account through this route as well
+	if uu.Enabled != nil && !slices.Contains(usr.Roles, user.RoleAdmin) {
+		return errs.NewAppCodeError(http.StatusForbidden, errs.CodeNotPermitted, "enabled can only be changed by an admin")
 	}
 
 	fields, ok := h.Validator.Check(uu)
@@ -141,45 +195,104 @@ func (h *Handler) UpdateUser(ctx context.Context, w http.ResponseWriter, r *http
 	fetched, err := h.UsersService.GetUserById(ctx, userId)
 	if err != nil {
 		if errors.Is(err, user.ErrUserNotFound) {
-			return errs.NewAppErrorf(http.StatusNotFound, "user with id %q not found", userId)
+			return errs.NewAppCodeErrorf(http.StatusNotFound, errs.CodeUserNotFound, "user with id %q not found", userId)
 		}
 		return errs.NewAppInternalErr(err)
 	}
 
 	suu, err := uu.toServiceUpdateUser()
 	if err != nil {
-		return errs.NewAppError(http.StatusBadRequest, err.Error())
+		return errs.NewAppCodeError(http.StatusBadRequest, errs.CodeInvalidEmail, err.Error())
 	}
 	//update it
 	updated, err := h.UsersService.UpdateUser(ctx, suu, fetched)
 	if err != nil {
 		return errs.NewAppInternalErr(err)
 	}
+	h.reverify(ctx, fetched, updated)
 
 	return web.Respond(ctx, w, http.StatusOK, toAppUser(updated))
 }
 
-func (h *Handler) UpdateRole(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-	id := r.PathValue("id")
+// GetMe returns the authenticated user.
+func (h *Handler) GetMe(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	usr, err := h.me(ctx)
+	if err != nil {
+		return err
+	}
+	return web.RespondETag(ctx, w, r, http.StatusOK, userETag(usr), toAppUser(usr))
+}
 
-	userId, err := uuid.Parse(id)
+// userETag tags the user by its last update.
+func userETag(usr user.User) string {
+	return web.ETag(usr.Id, usr.UpdatedAt.UnixNano())
+}
+
+// UpdateMe updates the profile of the authenticated user, they can not enable or disable themselves or change their
+// roles.
+func (h *Handler) UpdateMe(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var um UpdateMe
+	if err := web.Decode(r, &um); err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidJSON, "invalid json: %s", err.Error())
+	}
+
+	if um.Enabled != nil || um.Roles != nil {
+		return errs.NewAppCodeError(http.StatusForbidden, errs.CodeNotPermitted, "enabled and roles can only be changed by an admin")
+	}
+
+	fields, ok := h.Validator.Check(um.UpdateUser)
+	if !ok {
+		return errs.NewAppValidationError(http.StatusBadRequest, "invalid input", fields)
+	}
+
+	suu, err := um.toServiceUpdateUser()
 	if err != nil {
-		return errs.NewAppErrorf(http.StatusBadRequest, "%q not a valid uuid", id)
+		return errs.NewAppCodeError(http.StatusBadRequest, errs.CodeInvalidEmail, err.Error())
 	}
 
-	usr, err := auth.GetUser(ctx)
+	usr, err := h.me(ctx)
+	if err != nil {
+		return err
+	}
+
+	updated, err := h.UsersService.UpdateUser(ctx, suu, usr)
+	if err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+	h.reverify(ctx, usr, updated)
+
+	return web.Respond(ctx, w, http.StatusOK, toAppUser(updated))
+}
+
+// me loads the authenticated user from the store, the user in the context only carries their id and roles when
+// tokens are validated by their claims.
+func (h *Handler) me(ctx context.Context) (user.User, error) {
+	authUsr, err := auth.GetUser(ctx)
+	if err != nil {
+		return user.User{}, errs.NewAppCodeError(http.StatusUnauthorized, errs.CodeNotAuthenticated, "unauthorized")
+	}
+
+	usr, err := h.UsersService.GetUserById(ctx, authUsr.Id)
 	if err != nil {
-		return errs.NewAppError(http.StatusUnauthorized, "unauthorized")
+		if errors.Is(err, user.ErrUserNotFound) {
+			return user.User{}, errs.NewAppCodeError(http.StatusUnauthorized, errs.CodeInvalidToken, "unauthorized")
+		}
+		return user.User{}, errs.NewAppInternalErr(err)
 	}
+	return usr, nil
+}
 
-	//admin only
-	if !isItAdmin(usr.Roles) {
-		return errs.NewAppError(http.StatusUnauthorized, "unauthorized")
+func (h *Handler) UpdateRole(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+
+	userId, err := uuid.Parse(id)
+	if err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidId, "%q not a valid uuid", id)
 	}
 
 	var ur UpdateRole
-	if err := json.NewDecoder(r.Body).Decode(&ur); err != nil {
-		return errs.NewAppErrorf(http.StatusBadRequest, "invalid json: %s", err)
+	if err := web.Decode(r, &ur); err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidJSON, "invalid json: %s", err)
 	}
 
 	fields, ok := h.Validator.Check(ur)
@@ -189,7 +302,7 @@ func (h *Handler) UpdateRole(ctx context.Context, w http.ResponseWriter, r *http
 
 	parsedRoles, err := user.ParseRoles(ur.Roles)
 	if err != nil {
-		return errs.NewAppErrorf(http.StatusBadRequest, "parsing roles: %s", err)
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidRole, "parsing roles: %s", err)
 	}
 
 	uu := user.UpdateUser{
@@ -200,7 +313,7 @@ func (h *Handler) UpdateRole(ctx context.Context, w http.ResponseWriter, r *http
 	fetched, err := h.UsersService.GetUserById(ctx, userId)
 	if err != nil {
 		if errors.Is(err, user.ErrUserNotFound) {
-			return errs.NewAppErrorf(http.StatusNotFound, "user with id %q not found", id)
+			return errs.NewAppCodeErrorf(http.StatusNotFound, errs.CodeUserNotFound, "user with id %q not found", id)
 		}
 		return errs.NewAppInternalErr(err)
 	}
@@ -216,8 +329,8 @@ func (h *Handler) UpdateRole(ctx context.Context, w http.ResponseWriter, r *http
 // Signup is going to signup a user and generate a token.
 func (h *Handler) Signup(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	var signup SignUp
-	if err := json.NewDecoder(r.Body).Decode(&signup); err != nil {
-		return errs.NewAppErrorf(http.StatusBadRequest, "invalid json data: %s", err)
+	if err := web.Decode(r, &signup); err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidJSON, "invalid json data: %s", err)
 	}
 
 	fields, ok := h.Validator.Check(signup)
@@ -228,7 +341,7 @@ func (h *Handler) Signup(ctx context.Context, w http.ResponseWriter, r *http.Req
 	newUser, err := h.UsersService.CreateUser(ctx, signup.toServiceNewUser())
 	if err != nil {
 		if errors.Is(err, user.ErrUniqueEmail) {
-			return errs.NewAppErrorf(http.StatusConflict, "%q already in use", signup.Email)
+			return errs.NewAppCodeErrorf(http.StatusConflict, errs.CodeEmailInUse, "%q already in use", signup.Email)
 		}
 		return errs.NewAppInternalErr(err)
 	}
@@ -243,18 +356,23 @@ func (h *Handler) Signup(ctx context.Context, w http.ResponseWriter, r *http.Req
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
-	tkn, err := h.Auth.GenerateToken(h.ActiveKID, c)
+	tkn, err := h.Auth.GenerateToken(ctx, h.ActiveKID, c)
 	if err != nil {
 		return errs.NewAppInternalErr(err)
 	}
 
+	//not fatal, the user can ask for another email using "/verify/request"
+	if !newUser.Verified {
+		_ = h.sendVerification(ctx, newUser)
+	}
+
 	return web.Respond(ctx, w, http.StatusCreated, toAppUserWithToken(newUser, tkn))
 }
 
 func (h *Handler) Login(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	var login Login
-	if err := json.NewDecoder(r.Body).Decode(&login); err != nil {
-		return errs.NewAppErrorf(http.StatusBadRequest, "invalid json data: %s", err)
+	if err := web.Decode(r, &login); err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidJSON, "invalid json data: %s", err)
 	}
 
 	fields, ok := h.Validator.Check(login)
@@ -264,17 +382,25 @@ func (h *Handler) Login(ctx context.Context, w http.ResponseWriter, r *http.Requ
 
 	parsedMail, err := mail.ParseAddress(login.Email)
 	if err != nil {
-		return errs.NewAppErrorf(http.StatusBadRequest, "parsing email %q", login.Email)
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidEmail, "parsing email %q", login.Email)
 	}
 
 	//authenticate
-	usr, err := h.UsersService.Login(ctx, *parsedMail, login.Password)
+	usr, err := h.UsersService.Login(ctx, *parsedMail, login.Password, clientIP(r))
 	if err != nil {
+		var lockedErr *user.LockedError
+		if errors.As(err, &lockedErr) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+			if errors.Is(err, user.ErrTooManyAttempts) {
+				return errs.NewAppCodeError(http.StatusTooManyRequests, errs.CodeLoginThrottled, "too many failed login attempts")
+			}
+			return errs.NewAppCodeError(http.StatusLocked, errs.CodeAccountLocked, "account is temporarily locked")
+		}
 		if errors.Is(err, user.ErrUserNotFound) {
-			return errs.NewAppError(http.StatusBadRequest, "invalid credentials")
+			return errs.NewAppCodeError(http.StatusBadRequest, errs.CodeLoginFailed, "invalid credentials")
 		}
 		if errors.Is(err, user.ErrLoginFailed) {
-			return errs.NewAppError(http.StatusBadRequest, "invalid credentials")
+			return errs.NewAppCodeError(http.StatusBadRequest, errs.CodeLoginFailed, "invalid credentials")
 		}
 
 		return errs.NewAppInternalErr(err)
@@ -290,7 +416,7 @@ func (h *Handler) Login(ctx context.Context, w http.ResponseWriter, r *http.Requ
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
-	tkn, err := h.Auth.GenerateToken(h.ActiveKID, c)
+	tkn, err := h.Auth.GenerateToken(ctx, h.ActiveKID, c)
 	if err != nil {
 		return errs.NewAppInternalErr(err)
 	}
@@ -298,6 +424,236 @@ func (h *Handler) Login(ctx context.Context, w http.ResponseWriter, r *http.Requ
 	return web.Respond(ctx, w, http.StatusOK, toAppUserWithToken(usr, tkn))
 }
 
-func isItAdmin(roles []user.Role) bool {
-	return slices.Contains(roles, user.RoleAdmin)
+// Unlock removes the lock of a user that is locked out after too many failed logins.
+func (h *Handler) Unlock(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+
+	userId, err := uuid.Parse(id)
+	if err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidId, "%q not a valid uuid", id)
+	}
+
+	usr, err := h.UsersService.GetUserById(ctx, userId)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return errs.NewAppCodeErrorf(http.StatusNotFound, errs.CodeUserNotFound, "user with id %q not found", id)
+		}
+		return errs.NewAppInternalErr(err)
+	}
+
+	if err := h.UsersService.Unlock(ctx, usr); err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+
+	return web.Respond(ctx, w, http.StatusNoContent, nil)
+}
+
+// DisableUser disables the user in the "id" path value, revokes their tokens and cancels their pending and queued
+// tasks inside of a single transaction.
+func (h *Handler) DisableUser(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+
+	userId, err := uuid.Parse(id)
+	if err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidId, "%q not a valid uuid", id)
+	}
+
+	fetched, err := h.UsersService.GetUserById(ctx, userId)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return errs.NewAppCodeErrorf(http.StatusNotFound, errs.CodeUserNotFound, "user with id %q not found", id)
+		}
+		return errs.NewAppInternalErr(err)
+	}
+
+	var (
+		disabled user.User
+		canceled int
+	)
+
+	err = h.withinTran(ctx, func(usrs *user.Service, tsks *task.Service) error {
+		var err error
+		disabled, err = usrs.DisableUser(ctx, fetched)
+		if err != nil {
+			return fmt.Errorf("disable user: %w", err)
+		}
+
+		if tsks != nil {
+			canceled, err = tsks.CancelTasksByUserId(ctx, fetched.Id)
+			if err != nil {
+				return fmt.Errorf("cancel tasks: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, DisabledUser{User: toAppUser(disabled), CanceledTasks: canceled})
+}
+
+// clientIP returns the host of the remote address of the request.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// SelfOrAdmin is the rule of routes that act on the user in the "id" path value, admins and the user itself are allowed.
+var SelfOrAdmin = auth.Rule{
+	Roles:            []user.Role{user.RoleAdmin},
+	Owner:            PathOwner,
+	OwnerDescription: "the user itself",
+}
+
+// PathOwner resolves the owner of user routes from the "id" path value.
+func PathOwner(ctx context.Context, r *http.Request) (uuid.UUID, error) {
+	id := r.PathValue("id")
+
+	userId, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.UUID{}, errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidId, "%q not a valid uuid", id)
+	}
+	return userId, nil
+}
+
+// RequestVerification sends a new verification email to the authenticated user.
+func (h *Handler) RequestVerification(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	usr, err := h.me(ctx)
+	if err != nil {
+		return err
+	}
+
+	if usr.Verified {
+		return errs.NewAppCodeError(http.StatusConflict, errs.CodeEmailVerified, "email is already verified")
+	}
+
+	if err := h.sendVerification(ctx, usr); err != nil {
+		if errors.Is(err, user.ErrTokensDisabled) {
+			return errs.NewAppCodeError(http.StatusServiceUnavailable, errs.CodeFeatureDisabled, "email verification is disabled")
+		}
+		return errs.NewAppInternalErr(err)
+	}
+
+	return web.Respond(ctx, w, http.StatusNoContent, nil)
+}
+
+// Verify verifies the email of the user that owns the token.
+func (h *Handler) Verify(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var ve VerifyEmail
+	if err := web.Decode(r, &ve); err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidJSON, "invalid json data: %s", err)
+	}
+
+	fields, ok := h.Validator.Check(ve)
+	if !ok {
+		return errs.NewAppValidationError(http.StatusBadRequest, "invalid input", fields)
+	}
+
+	usr, err := h.UsersService.Verify(ctx, ve.Token)
+	if err != nil {
+		if errors.Is(err, user.ErrInvalidToken) {
+			return errs.NewAppCodeError(http.StatusBadRequest, errs.CodeInvalidOneTimeToken, err.Error())
+		}
+		if errors.Is(err, user.ErrTokensDisabled) {
+			return errs.NewAppCodeError(http.StatusServiceUnavailable, errs.CodeFeatureDisabled, "email verification is disabled")
+		}
+		return errs.NewAppInternalErr(err)
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, toAppUser(usr))
+}
+
+// RequestPasswordReset emails a password reset token, it responds the same way whether the email exists or not
+// so it can not be used for discovering accounts.
+func (h *Handler) RequestPasswordReset(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var rpr RequestPasswordReset
+	if err := web.Decode(r, &rpr); err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidJSON, "invalid json data: %s", err)
+	}
+
+	fields, ok := h.Validator.Check(rpr)
+	if !ok {
+		return errs.NewAppValidationError(http.StatusBadRequest, "invalid input", fields)
+	}
+
+	if h.Mailer == nil {
+		return errs.NewAppCodeError(http.StatusServiceUnavailable, errs.CodeFeatureDisabled, "password reset is disabled")
+	}
+
+	parsedMail, err := mail.ParseAddress(rpr.Email)
+	if err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidEmail, "parsing email %q", rpr.Email)
+	}
+
+	usr, token, err := h.UsersService.RequestPasswordReset(ctx, *parsedMail)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return web.Respond(ctx, w, http.StatusNoContent, nil)
+		}
+		if errors.Is(err, user.ErrTokensDisabled) {
+			return errs.NewAppCodeError(http.StatusServiceUnavailable, errs.CodeFeatureDisabled, "password reset is disabled")
+		}
+		return errs.NewAppInternalErr(err)
+	}
+
+	body := fmt.Sprintf("Use the following token to reset your password, it expires in %s:\n\n%s\n\nIf you did not ask for a password reset you can ignore this email.", user.PasswordResetTokenTTL, token)
+	if err := h.Mailer.Send(ctx, usr.Email, "Reset your password", body); err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+
+	return web.Respond(ctx, w, http.StatusNoContent, nil)
+}
+
+// ResetPassword sets a new password for the user that owns the token.
+func (h *Handler) ResetPassword(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var rp ResetPassword
+	if err := web.Decode(r, &rp); err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidJSON, "invalid json data: %s", err)
+	}
+
+	fields, ok := h.Validator.Check(rp)
+	if !ok {
+		return errs.NewAppValidationError(http.StatusBadRequest, "invalid input", fields)
+	}
+
+	if _, err := h.UsersService.ResetPassword(ctx, rp.Token, rp.Password); err != nil {
+		if errors.Is(err, user.ErrInvalidToken) {
+			return errs.NewAppCodeError(http.StatusBadRequest, errs.CodeInvalidOneTimeToken, err.Error())
+		}
+		if errors.Is(err, user.ErrTokensDisabled) {
+			return errs.NewAppCodeError(http.StatusServiceUnavailable, errs.CodeFeatureDisabled, "password reset is disabled")
+		}
+		return errs.NewAppInternalErr(err)
+	}
+
+	return web.Respond(ctx, w, http.StatusNoContent, nil)
+}
+
+// reverify sends a verification email to the new address of a user whose email changed.
+func (h *Handler) reverify(ctx context.Context, before user.User, after user.User) {
+	if !after.Verified && !strings.EqualFold(before.Email.Address, after.Email.Address) {
+		//not fatal, the user can ask for another email using "/verify/request"
+		_ = h.sendVerification(ctx, after)
+	}
+}
+
+func (h *Handler) sendVerification(ctx context.Context, usr user.User) error {
+	if h.Mailer == nil {
+		return user.ErrTokensDisabled
+	}
+
+	token, err := h.UsersService.RequestVerification(ctx, usr)
+	if err != nil {
+		return fmt.Errorf("request verification: %w", err)
+	}
+
+	body := fmt.Sprintf("Use the following token to verify your email address, it expires in %s:\n\n%s", user.VerificationTokenTTL, token)
+	if err := h.Mailer.Send(ctx, usr.Email, "Verify your email address", body); err != nil {
+		return fmt.Errorf("send: %w", err)
+	}
+	return nil
 }
@@ -17,6 +17,10 @@ import (
 	"github.com/hamidoujand/task-scheduler/app/api/auth"
 	"github.com/hamidoujand/task-scheduler/app/api/errs"
 	"github.com/hamidoujand/task-scheduler/app/api/handlers/users"
+	"github.com/hamidoujand/task-scheduler/app/api/mid"
+	"github.com/hamidoujand/task-scheduler/business/brokertest"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	taskmemory "github.com/hamidoujand/task-scheduler/business/domain/task/store/memory"
 	"github.com/hamidoujand/task-scheduler/business/domain/user"
 	"github.com/hamidoujand/task-scheduler/business/domain/user/store/memory"
 	"golang.org/x/crypto/bcrypt"
@@ -94,7 +98,7 @@ func TestCreateUser(t *testing.T) {
 		},
 	}
 
-	userService := user.NewService(&userRepo)
+	userService := user.NewService(&userRepo, nil)
 
 	h := users.Handler{
 		Validator:    v,
@@ -228,7 +232,7 @@ func TestGetUserById(t *testing.T) {
 		},
 	}
 
-	userService := user.NewService(&userRepo)
+	userService := user.NewService(&userRepo, nil)
 
 	h := users.Handler{
 		Validator:    v,
@@ -282,6 +286,124 @@ func TestGetUserById(t *testing.T) {
 
 }
 
+func TestListUsers(t *testing.T) {
+	now := time.Now()
+	newUser := func(name string, email string, role user.Role, enabled bool, created time.Duration) user.User {
+		return user.User{
+			Id:        uuid.New(),
+			Name:      name,
+			Email:     mail.Address{Name: name, Address: email},
+			Roles:     []user.Role{role},
+			Enabled:   enabled,
+			CreatedAt: now.Add(created),
+			UpdatedAt: now.Add(created),
+		}
+	}
+
+	alice := newUser("alice", "alice@example.com", user.RoleAdmin, true, 0)
+	bob := newUser("bob", "bob@gmail.com", user.RoleUser, true, time.Minute)
+	carol := newUser("carol", "carol@gmail.com", user.RoleUser, false, time.Minute*2)
+
+	userRepo := memory.Repository{
+		Users: map[uuid.UUID]user.User{
+			alice.Id: alice,
+			bob.Id:   bob,
+			carol.Id: carol,
+		},
+	}
+
+	h := users.Handler{
+		UsersService: user.NewService(&userRepo, nil),
+	}
+
+	tests := map[string]struct {
+		query      string
+		statusCode int
+		expected   []string
+	}{
+		"default order": {
+			query:      "",
+			statusCode: http.StatusOK,
+			expected:   []string{"alice", "bob", "carol"},
+		},
+		"order by name desc": {
+			query:      "?orderby=name,desc",
+			statusCode: http.StatusOK,
+			expected:   []string{"carol", "bob", "alice"},
+		},
+		"pagination": {
+			query:      "?rows=2&page=2",
+			statusCode: http.StatusOK,
+			expected:   []string{"carol"},
+		},
+		"filter by role": {
+			query:      "?role=user",
+			statusCode: http.StatusOK,
+			expected:   []string{"bob", "carol"},
+		},
+		"filter by enabled": {
+			query:      "?role=user&enabled=false",
+			statusCode: http.StatusOK,
+			expected:   []string{"carol"},
+		},
+		"filter by email": {
+			query:      "?email=GMAIL",
+			statusCode: http.StatusOK,
+			expected:   []string{"bob", "carol"},
+		},
+		"invalid role": {
+			query:      "?role=root",
+			statusCode: http.StatusBadRequest,
+		},
+		"invalid enabled": {
+			query:      "?enabled=maybe",
+			statusCode: http.StatusBadRequest,
+		},
+		"invalid field": {
+			query:      "?orderby=password",
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/api/users/"+test.query, nil)
+			w := httptest.NewRecorder()
+
+			err := h.ListUsers(context.Background(), w, r)
+			if test.statusCode != http.StatusOK {
+				var appError *errs.AppError
+				if !errors.As(err, &appError) {
+					t.Fatalf("expected the error to be *appError: %T", err)
+				}
+
+				if appError.Code != test.statusCode {
+					t.Errorf("appError.Code= %d, got %d", test.statusCode, appError.Code)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected to list users: %s", err)
+			}
+
+			var resp []users.User
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("expected to decode the users from response body: %s", err)
+			}
+
+			names := make([]string, len(resp))
+			for i, usr := range resp {
+				names[i] = usr.Name
+			}
+
+			if !slices.Equal(names, test.expected) {
+				t.Errorf("names= %v, got %v", test.expected, names)
+			}
+		})
+	}
+}
+
 func TestDeleteUserById(t *testing.T) {
 	hashed, err := bcrypt.GenerateFromPassword([]byte("test1234"), bcrypt.MinCost)
 	if err != nil {
@@ -365,7 +487,7 @@ func TestDeleteUserById(t *testing.T) {
 		},
 	}
 
-	userService := user.NewService(&userRepo)
+	userService := user.NewService(&userRepo, nil)
 
 	h := users.Handler{
 		Validator:    v,
@@ -432,7 +554,7 @@ func TestDeleteUserById(t *testing.T) {
 				ctx = context.Background()
 			}
 
-			err := h.DeleteUserById(ctx, w, req)
+			err := mid.Authorize(users.SelfOrAdmin)(h.DeleteUserById)(ctx, w, req)
 			if !test.expectError {
 				//success path
 				if err != nil {
@@ -457,6 +579,325 @@ func TestDeleteUserById(t *testing.T) {
 	}
 }
 
+func TestMe(t *testing.T) {
+	v, err := errs.NewAppValidator()
+	if err != nil {
+		t.Fatalf("expected to create the app validator: %s", err)
+	}
+
+	usr := user.User{
+		Id:        uuid.New(),
+		Name:      "Jane Doe",
+		Email:     mail.Address{Name: "Jane Doe", Address: "jane@gmail.com"},
+		Roles:     []user.Role{user.RoleUser},
+		Enabled:   true,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	userRepo := memory.Repository{
+		Users: map[uuid.UUID]user.User{
+			usr.Id: usr,
+		},
+	}
+
+	h := users.Handler{
+		Validator:    v,
+		UsersService: user.NewService(&userRepo, nil),
+	}
+
+	//only the id of the authenticated user is used
+	ctx := auth.SetUser(context.Background(), user.User{Id: usr.Id, Roles: usr.Roles})
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/api/users/me", nil)
+	w := httptest.NewRecorder()
+	if err := h.GetMe(ctx, w, r); err != nil {
+		t.Fatalf("expected to get the authenticated user: %s", err)
+	}
+
+	var resp users.User
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("expected to decode the user from response body: %s", err)
+	}
+
+	if resp.Email != usr.Email.Address {
+		t.Errorf("resp.Email= %s, got %s", usr.Email.Address, resp.Email)
+	}
+
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected the user to be tagged")
+	}
+
+	tests := map[string]struct {
+		body       string
+		ctx        context.Context
+		statusCode int
+	}{
+		"update name": {
+			body:       `{"name":"Jane Smith"}`,
+			ctx:        ctx,
+			statusCode: http.StatusOK,
+		},
+		"enable itself": {
+			body:       `{"enabled":false}`,
+			ctx:        ctx,
+			statusCode: http.StatusForbidden,
+		},
+		"change roles": {
+			body:       `{"roles":["admin"]}`,
+			ctx:        ctx,
+			statusCode: http.StatusForbidden,
+		},
+		"invalid input": {
+			body:       `{"name":"j"}`,
+			ctx:        ctx,
+			statusCode: http.StatusBadRequest,
+		},
+		"unauthenticated": {
+			body:       `{"name":"Jane Smith"}`,
+			ctx:        context.Background(),
+			statusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/v1/api/users/me", bytes.NewBufferString(test.body))
+			w := httptest.NewRecorder()
+
+			err := h.UpdateMe(test.ctx, w, r)
+			if test.statusCode == http.StatusOK {
+				if err != nil {
+					t.Fatalf("expected to update the authenticated user: %s", err)
+				}
+
+				var resp users.User
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatalf("expected to decode the user from response body: %s", err)
+				}
+
+				if resp.Name != "Jane Smith" {
+					t.Errorf("resp.Name= %s, got %s", "Jane Smith", resp.Name)
+				}
+
+				if !resp.Enabled || !slices.Equal(resp.Roles, []string{"user"}) {
+					t.Errorf("expected enabled and roles to be unchanged, got %t %v", resp.Enabled, resp.Roles)
+				}
+				return
+			}
+
+			var appErr *errs.AppError
+			if !errors.As(err, &appErr) {
+				t.Fatalf("expected the error type to be *appError, got %T", err)
+			}
+
+			if appErr.Code != test.statusCode {
+				t.Errorf("appErr.Code=%d, got %d", test.statusCode, appErr.Code)
+			}
+		})
+	}
+
+	//the name was updated so the old tag no longer matches
+	r = httptest.NewRequest(http.MethodGet, "/v1/api/users/me", nil)
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	if err := h.GetMe(ctx, w, r); err != nil {
+		t.Fatalf("expected to get the authenticated user: %s", err)
+	}
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status= %d, got %d", http.StatusOK, w.Code)
+	}
+
+	etag = w.Header().Get("ETag")
+	r = httptest.NewRequest(http.MethodGet, "/v1/api/users/me", nil)
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	if err := h.GetMe(ctx, w, r); err != nil {
+		t.Fatalf("expected to get the authenticated user: %s", err)
+	}
+
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("status= %d, got %d with %d bytes", http.StatusNotModified, w.Code, w.Body.Len())
+	}
+}
+
+// sentMail records the emails sent through it.
+type sentMail struct {
+	to []mail.Address
+}
+
+func (m *sentMail) Send(ctx context.Context, to mail.Address, subject string, body string) error {
+	m.to = append(m.to, to)
+	return nil
+}
+
+func TestChangeEmail(t *testing.T) {
+	v, err := errs.NewAppValidator()
+	if err != nil {
+		t.Fatalf("expected to create the app validator: %s", err)
+	}
+
+	admin := user.User{Id: uuid.New(), Roles: []user.Role{user.RoleAdmin}}
+
+	tests := map[string]struct {
+		email    string
+		admin    bool
+		verified bool
+	}{
+		"user changes its own email":   {email: "jane.smith@gmail.com", verified: false},
+		"admin changes the email":      {email: "jane.smith@gmail.com", admin: true, verified: false},
+		"same email in another casing": {email: "Jane@Gmail.com", verified: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			usr := user.User{
+				Id:        uuid.New(),
+				Name:      "Jane Doe",
+				Email:     mail.Address{Name: "Jane Doe", Address: "jane@gmail.com"},
+				Roles:     []user.Role{user.RoleUser},
+				Enabled:   true,
+				Verified:  true,
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+			}
+
+			userRepo := memory.Repository{
+				Users: map[uuid.UUID]user.User{
+					usr.Id: usr,
+				},
+			}
+
+			var sent sentMail
+			h := users.Handler{
+				Validator:    v,
+				UsersService: user.NewService(&userRepo, &userRepo),
+				Mailer:       &sent,
+			}
+
+			body := `{"email":"` + test.email + `"}`
+			w := httptest.NewRecorder()
+
+			if test.admin {
+				r := httptest.NewRequest(http.MethodPut, "/v1/api/users/"+usr.Id.String(), bytes.NewBufferString(body))
+				r.SetPathValue("id", usr.Id.String())
+				err = h.UpdateUser(auth.SetUser(context.Background(), admin), w, r)
+			} else {
+				r := httptest.NewRequest(http.MethodPut, "/v1/api/users/me", bytes.NewBufferString(body))
+				err = h.UpdateMe(auth.SetUser(context.Background(), user.User{Id: usr.Id, Roles: usr.Roles}), w, r)
+			}
+			if err != nil {
+				t.Fatalf("expected to update the email: %s", err)
+			}
+
+			var resp users.User
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("expected to decode the user from response body: %s", err)
+			}
+
+			if resp.Verified != test.verified {
+				t.Errorf("resp.Verified= %t, got %t", test.verified, resp.Verified)
+			}
+
+			//the new address gets the verification email, an unchanged one gets nothing
+			if test.verified {
+				if len(sent.to) != 0 {
+					t.Errorf("expected no email to be sent, got %v", sent.to)
+				}
+				return
+			}
+
+			if len(sent.to) != 1 || sent.to[0].Address != test.email {
+				t.Errorf("expected a verification email to %s, got %v", test.email, sent.to)
+			}
+		})
+	}
+}
+
+func TestDisableUser(t *testing.T) {
+	usr := user.User{
+		Id:        uuid.New(),
+		Name:      "Jane Doe",
+		Email:     mail.Address{Name: "Jane Doe", Address: "jane@gmail.com"},
+		Roles:     []user.Role{user.RoleUser},
+		Enabled:   true,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	userRepo := memory.Repository{
+		Users: map[uuid.UUID]user.User{
+			usr.Id: usr,
+		},
+	}
+
+	statuses := []task.Status{task.StatusPending, task.StatusQueued, task.StatusCompleted}
+	taskRepo := taskmemory.Repository{Tasks: make(map[uuid.UUID]task.Task)}
+	for _, status := range statuses {
+		tsk := task.Task{Id: uuid.New(), UserId: usr.Id, Status: status}
+		taskRepo.Tasks[tsk.Id] = tsk
+	}
+
+	//tasks of other users are left alone
+	other := task.Task{Id: uuid.New(), UserId: uuid.New(), Status: task.StatusPending}
+	taskRepo.Tasks[other.Id] = other
+
+	taskService, err := task.NewService(&taskRepo, brokertest.NewMemoryClient(t))
+	if err != nil {
+		t.Fatalf("expected to create the task service: %s", err)
+	}
+
+	h := users.Handler{
+		UsersService: user.NewService(&userRepo, nil),
+		TaskService:  taskService,
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/api/admin/users/"+uuid.NewString()+"/disable", nil)
+	r.SetPathValue("id", uuid.NewString())
+	err = h.DisableUser(context.Background(), httptest.NewRecorder(), r)
+
+	var appErr *errs.AppError
+	if !errors.As(err, &appErr) || appErr.Code != http.StatusNotFound {
+		t.Fatalf("expected a not found error for a missing user, got %v", err)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/v1/api/admin/users/"+usr.Id.String()+"/disable", nil)
+	r.SetPathValue("id", usr.Id.String())
+	w := httptest.NewRecorder()
+
+	if err := h.DisableUser(context.Background(), w, r); err != nil {
+		t.Fatalf("expected to disable the user: %s", err)
+	}
+
+	var resp users.DisabledUser
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("expected to decode the response body: %s", err)
+	}
+
+	if resp.User.Enabled {
+		t.Error("expected the user to be disabled")
+	}
+
+	if resp.CanceledTasks != 2 {
+		t.Errorf("canceledTasks= %d, got %d", 2, resp.CanceledTasks)
+	}
+
+	if userRepo.Users[usr.Id].TokensValidAfter.IsZero() {
+		t.Error("expected the tokens of the user to be revoked")
+	}
+
+	for _, tsk := range taskRepo.Tasks {
+		switch {
+		case tsk.Id == other.Id && tsk.Status != task.StatusPending:
+			t.Errorf("status= %s, got %s for the task of another user", task.StatusPending, tsk.Status)
+		case tsk.UserId == usr.Id && tsk.Status == task.StatusPending, tsk.UserId == usr.Id && tsk.Status == task.StatusQueued:
+			t.Errorf("expected task %s to be canceled, got %s", tsk.Id, tsk.Status)
+		}
+	}
+}
+
 func TestUpdateUser(t *testing.T) {
 	//Setup
 
@@ -493,7 +934,7 @@ func TestUpdateUser(t *testing.T) {
 		},
 	}
 
-	userService := user.NewService(&userRepo)
+	userService := user.NewService(&userRepo, nil)
 
 	h := users.Handler{
 		Validator:    v,
@@ -520,7 +961,6 @@ func TestUpdateUser(t *testing.T) {
 			input: input{
 				Name:            stringPointer("John Doe"),
 				Email:           stringPointer("john@gmail.com"),
-				Enabled:         boolPointer(false),
 				Password:        stringPointer("test54321"),
 				PasswordConfirm: stringPointer("test54321"),
 			},
@@ -530,6 +970,16 @@ func TestUpdateUser(t *testing.T) {
 			statusCode:  http.StatusOK,
 		},
 
+		"user can not enable or disable its own account": {
+			input: input{
+				Enabled: boolPointer(true),
+			},
+			userId:      userId,
+			usr:         usr,
+			expectError: true,
+			statusCode:  http.StatusForbidden,
+		},
+
 		"admin can update anyone": {
 			input: input{
 				Name:            stringPointer("John Doe"),
@@ -610,7 +1060,7 @@ func TestUpdateUser(t *testing.T) {
 
 			ctx := auth.SetUser(r.Context(), test.usr)
 
-			err := h.UpdateUser(ctx, w, r)
+			err := mid.Authorize(users.SelfOrAdmin)(h.UpdateUser)(ctx, w, r)
 			if !test.expectError {
 				if err != nil {
 					t.Errorf("expected the user to be updated: %s", err)
@@ -727,7 +1177,7 @@ func TestUpdateRoles(t *testing.T) {
 		},
 	}
 
-	userService := user.NewService(&userRepo)
+	userService := user.NewService(&userRepo, nil)
 
 	h := users.Handler{
 		Validator:    v,
@@ -791,7 +1241,7 @@ func TestUpdateRoles(t *testing.T) {
 
 			ctx := auth.SetUser(context.Background(), test.updater)
 
-			err := h.UpdateRole(ctx, w, r)
+			err := mid.Authorize(auth.Rule{Roles: []user.Role{user.RoleAdmin}})(h.UpdateRole)(ctx, w, r)
 
 			if !test.expectError {
 				if err != nil {
@@ -868,7 +1318,7 @@ func TestSignup(t *testing.T) {
 		},
 	}
 	ks := auth.NewMockKeyStore(t)
-	userService := user.NewService(&userRepo)
+	userService := user.NewService(&userRepo, nil)
 	a := auth.New(ks, userService)
 
 	h := users.Handler{
@@ -1017,7 +1467,7 @@ func TestLogin(t *testing.T) {
 		},
 	}
 	ks := auth.NewMockKeyStore(t)
-	userService := user.NewService(&userRepo)
+	userService := user.NewService(&userRepo, nil)
 	a := auth.New(ks, userService)
 
 	h := users.Handler{
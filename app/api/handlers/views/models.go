@@ -0,0 +1,60 @@
+package views
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	"github.com/hamidoujand/task-scheduler/business/domain/view"
+)
+
+// View represents a saved view that goes to client, the fields are named after the query parameters of the task list.
+type View struct {
+	Name      string    `json:"name"`
+	Search    string    `json:"q,omitempty"`
+	OrderBy   string    `json:"orderby"`
+	Rows      int       `json:"rows,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func fromDomainView(v view.View) View {
+	return View{
+		Name:      v.Name,
+		Search:    v.Search,
+		OrderBy:   v.Order.String(),
+		Rows:      v.Rows,
+		CreatedAt: v.CreatedAt,
+	}
+}
+
+// NewView represents the data required for creating a view sent by client, OrderBy defaults to "createdAt,ASC".
+type NewView struct {
+	Name    string `json:"name" validate:"required,max=64"`
+	Search  string `json:"q" validate:"max=255"`
+	OrderBy string `json:"orderby" validate:"max=64"`
+	Rows    int    `json:"rows" validate:"min=0,max=100"`
+}
+
+func toDomainNewView(userId uuid.UUID, nv NewView) (view.NewView, error) {
+	order := task.OrderBy{
+		Field:     task.FieldCreatedAt,
+		Direction: task.DirectionASC,
+	}
+
+	if nv.OrderBy != "" {
+		o, err := task.ParseOrderBy(nv.OrderBy)
+		if err != nil {
+			return view.NewView{}, fmt.Errorf("orderby: %w", err)
+		}
+		order = o
+	}
+
+	return view.NewView{
+		UserId: userId,
+		Name:   nv.Name,
+		Search: nv.Search,
+		Order:  order,
+		Rows:   nv.Rows,
+	}, nil
+}
@@ -0,0 +1,95 @@
+// Package views provides the handlers used for managing the saved views of the authenticated user.
+package views
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/hamidoujand/task-scheduler/app/api/auth"
+	"github.com/hamidoujand/task-scheduler/app/api/errs"
+	"github.com/hamidoujand/task-scheduler/business/domain/view"
+	"github.com/hamidoujand/task-scheduler/foundation/web"
+)
+
+// Handler represents set of view handlers.
+type Handler struct {
+	Validator   *errs.AppValidator
+	ViewService *view.Service
+}
+
+// CreateView saves a view for the authenticated user.
+func (h *Handler) CreateView(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	usr, err := auth.GetUser(ctx)
+	if err != nil {
+		return errs.NewAppCodeError(http.StatusUnauthorized, errs.CodeNotAuthenticated, "unauthorized")
+	}
+
+	var nv NewView
+	if err := web.Decode(r, &nv); err != nil {
+		return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidJSON, "invalid json: %s", err)
+	}
+
+	fields, ok := h.Validator.Check(nv)
+	if !ok {
+		return errs.NewAppValidationError(http.StatusBadRequest, "invalid input", fields)
+	}
+
+	newView, err := toDomainNewView(usr.Id, nv)
+	if err != nil {
+		return errs.NewAppCodeError(http.StatusBadRequest, errs.CodeInvalidView, err.Error())
+	}
+
+	v, err := h.ViewService.CreateView(ctx, newView)
+	if err != nil {
+		switch {
+		case errors.Is(err, view.ErrInvalidName):
+			return errs.NewAppCodeError(http.StatusBadRequest, errs.CodeInvalidView, err.Error())
+		case errors.Is(err, view.ErrViewExists):
+			return errs.NewAppCodeError(http.StatusConflict, errs.CodeViewExists, err.Error())
+		case errors.Is(err, view.ErrTooManyViews):
+			return errs.NewAppCodeError(http.StatusConflict, errs.CodeLimitReached, err.Error())
+		}
+		return errs.NewAppInternalErr(err)
+	}
+
+	return web.Respond(ctx, w, http.StatusCreated, fromDomainView(v))
+}
+
+// GetViews returns the views of the authenticated user.
+func (h *Handler) GetViews(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	usr, err := auth.GetUser(ctx)
+	if err != nil {
+		return errs.NewAppCodeError(http.StatusUnauthorized, errs.CodeNotAuthenticated, "unauthorized")
+	}
+
+	views, err := h.ViewService.Views(ctx, usr.Id)
+	if err != nil {
+		return errs.NewAppInternalErr(err)
+	}
+
+	resp := make([]View, len(views))
+	for i, v := range views {
+		resp[i] = fromDomainView(v)
+	}
+
+	return web.Respond(ctx, w, http.StatusOK, resp)
+}
+
+// DeleteView deletes the view in the "name" path value.
+func (h *Handler) DeleteView(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	usr, err := auth.GetUser(ctx)
+	if err != nil {
+		return errs.NewAppCodeError(http.StatusUnauthorized, errs.CodeNotAuthenticated, "unauthorized")
+	}
+
+	name := r.PathValue("name")
+	if err := h.ViewService.DeleteView(ctx, usr.Id, name); err != nil {
+		if errors.Is(err, view.ErrViewNotFound) {
+			return errs.NewAppCodeErrorf(http.StatusNotFound, errs.CodeViewNotFound, "view %q not found", name)
+		}
+		return errs.NewAppInternalErr(err)
+	}
+
+	return web.Respond(ctx, w, http.StatusNoContent, nil)
+}
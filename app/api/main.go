@@ -6,19 +6,40 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/mail"
 	"os"
 	"os/signal"
 	"runtime"
+	"strconv"
 	"syscall"
 	"time"
 
 	"github.com/ardanlabs/conf/v3"
+	"github.com/hamidoujand/task-scheduler/app/api/auth"
+	"github.com/hamidoujand/task-scheduler/app/api/debug"
 	"github.com/hamidoujand/task-scheduler/app/api/errs"
 	"github.com/hamidoujand/task-scheduler/app/api/handlers"
+	"github.com/hamidoujand/task-scheduler/business/broker"
+	"github.com/hamidoujand/task-scheduler/business/broker/memory"
 	"github.com/hamidoujand/task-scheduler/business/broker/rabbitmq"
 	"github.com/hamidoujand/task-scheduler/business/database/postgres"
+	"github.com/hamidoujand/task-scheduler/business/domain/scheduler"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	"github.com/hamidoujand/task-scheduler/business/metrics"
+	"github.com/hamidoujand/task-scheduler/foundation/blob"
+	"github.com/hamidoujand/task-scheduler/foundation/blob/s3"
+	"github.com/hamidoujand/task-scheduler/foundation/breaker"
 	"github.com/hamidoujand/task-scheduler/foundation/keystore"
+	"github.com/hamidoujand/task-scheduler/foundation/keystore/awskms"
+	"github.com/hamidoujand/task-scheduler/foundation/keystore/vault"
+	"github.com/hamidoujand/task-scheduler/foundation/listener"
 	"github.com/hamidoujand/task-scheduler/foundation/logger"
+	"github.com/hamidoujand/task-scheduler/foundation/mailer"
+	"github.com/hamidoujand/task-scheduler/foundation/reload"
+	containerRuntime "github.com/hamidoujand/task-scheduler/foundation/runtime"
+	"github.com/hamidoujand/task-scheduler/foundation/shutdown"
+	"github.com/hamidoujand/task-scheduler/foundation/tlsconf"
+	"github.com/hamidoujand/task-scheduler/foundation/webhook"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -38,10 +59,28 @@ func run() error {
 	configs := struct {
 		API struct {
 			Host            string        `conf:"default:0.0.0.0:8000"`
+			DebugHost       string        `conf:"default:0.0.0.0:4000"`
 			ReadTimeout     time.Duration `conf:"default:5s"`
-			WriteTimeout    time.Duration `conf:"default:10s"`
-			ShutdownTimeout time.Duration `conf:"default:20s"`
+			WriteTimeout    time.Duration `conf:"default:10s,help:requests are cut off after it and task waits end a second before"`
+			ShutdownTimeout time.Duration `conf:"default:1m,help:deadline for draining requests then the scheduler and closing connections"`
 			Environment     string        `conf:"default:development"`
+			ProblemJSON     bool          `conf:"default:false,help:send every error as application/problem+json"`
+			MaxBodyBytes    int64         `conf:"default:1048576,help:largest body of a request and 0 removes the limit"`
+			BasePath        string        `conf:"help:prefix the routes are served under like /scheduler"`
+			VirtualHost     string        `conf:"help:host the routes are served for like api.example.com and any host when empty"`
+			ReusePort       bool          `conf:"default:false,help:bind Host with SO_REUSEPORT so a new process serves before the old one drains"`
+			SocketName      string        `conf:"help:FileDescriptorName of the systemd socket of the api and the first socket when empty"`
+		}
+
+		Log struct {
+			Level            string  `conf:"default:info,help:debug|info|warn|error and reloaded on SIGHUP"`
+			AccessFile       string  `conf:"help:file the access log is appended to instead of stdout"`
+			AccessSampleRate float64 `conf:"default:1,help:fraction of the 2xx requests written into the access log, others are always written"`
+		}
+
+		Reload struct {
+			File     string        `conf:"help:KEY=VALUE file of the settings that are reloaded when it changes or on SIGHUP"`
+			Interval time.Duration `conf:"default:5s,help:how often the reload file is checked for changes"`
 		}
 
 		DB struct {
@@ -49,39 +88,175 @@ func run() error {
 			Password        string        `conf:"default:password,mask"`
 			Host            string        `conf:"default:localhost:5432"`
 			Name            string        `conf:"default:postgres"`
-			MaxIdleConns    int           `conf:"default:10"`
 			MaxOpenConns    int           `conf:"default:10"`
 			MaxIdleConnTime time.Duration `conf:"default:5m"`
 			MaxConnLifeTime time.Duration `conf:"default:10m"`
 			DisableTLS      bool          `conf:"default:true"`
+			SlowQuery       time.Duration `conf:"default:500ms,help:queries taking longer are logged and 0 disables it"`
+		}
+
+		Breaker struct {
+			Threshold int           `conf:"default:5,help:failures of postgres, redis or rabbitmq in a row that make their calls fail fast"`
+			Cooldown  time.Duration `conf:"default:10s,help:how long calls fail fast before one of them probes the dependency"`
 		}
 
 		Auth struct {
-			KeysFolder string        `conf:"default:zarf/keys/"`
-			ActiveKid  string        `conf:"default:a41bace0-da3c-4119-85ad-bbd293bf31ee"`
-			Issuer     string        `conf:"default:task scheduler"`
-			TokenAge   time.Duration `conf:"default:24h"`
+			KeysFolder  string        `conf:"default:zarf/keys/"`
+			ActiveKid   string        `conf:"default:a41bace0-da3c-4119-85ad-bbd293bf31ee"`
+			TrustedKids []string      `conf:"help:keys of vault or awskms tokens are still accepted from besides the active one like old-key"`
+			Issuer      string        `conf:"default:task scheduler"`
+			TokenAge    time.Duration `conf:"default:24h"`
+			Backend     string        `conf:"default:file,help:keystore backend: file|vault|awskms"`
+			ClaimsOnly  bool          `conf:"default:false,help:trust the roles of valid tokens without looking the user up"`
+		}
+
+		Secrets struct {
+			KeysFolder string `conf:"default:zarf/keys/"`
+			KeyID      string `conf:"default:a41bace0-da3c-4119-85ad-bbd293bf31ee,help:key the task secrets are encrypted with"`
+		}
+
+		Login struct {
+			MaxFailures      int           `conf:"default:5"`
+			MaxFailuresPerIP int           `conf:"default:20"`
+			LockDuration     time.Duration `conf:"default:15m"`
+		}
+
+		Vault struct {
+			Address   string `conf:"default:http://localhost:8200"`
+			Token     string `conf:"mask"`
+			MountPath string `conf:"default:transit"`
+		}
+
+		KMS struct {
+			Region          string
+			AccessKeyID     string
+			SecretAccessKey string `conf:"mask"`
+			SessionToken    string `conf:"mask"`
+			Endpoint        string
+		}
+
+		Results struct {
+			Backend      string `conf:"default:none,help:blob storage of large results: none|dir|s3"`
+			OffloadBytes int    `conf:"default:1048576,help:results larger than this are kept in the blob storage"`
+			Dir          string `conf:"default:zarf/results/"`
+		}
+
+		S3 struct {
+			Bucket          string
+			Region          string
+			AccessKeyID     string
+			SecretAccessKey string `conf:"mask"`
+			SessionToken    string `conf:"mask"`
+			Endpoint        string
 		}
 
 		Redis struct {
+			Enabled  bool          `conf:"default:true"`
 			Host     string        `conf:"default:localhost:6379"`
-			Password string        `conf:"default:'',"`
+			Password string        `conf:"mask"`
 			DBIdx    int           `conf:"default:0"`
 			Timeout  time.Duration `conf:"default:5s"`
+			CacheTTL time.Duration `conf:"default:30s,help:how long tasks and users are cached and 0 disables it"`
+		}
+
+		SMTP struct {
+			Host     string
+			Port     int `conf:"default:587"`
+			Username string
+			Password string `conf:"mask"`
+			From     string `conf:"default:Task Scheduler <no-reply@localhost>"`
+		}
+
+		Broker struct {
+			Kind string `conf:"default:rabbitmq,help:rabbitmq|memory and memory keeps the queues inside of a single instance"`
 		}
 
 		Rabbitmq struct {
 			Host                 string        `conf:"default:localhost:5672"`
 			User                 string        `conf:"default:guest"`
-			Password             string        `conf:"default:guest"`
+			Password             string        `conf:"default:guest,mask"`
+			VHost                string        `conf:"help:vhost of the queues and the default vhost when empty"`
+			QueuePrefix          string        `conf:"help:put in front of every queue name like staging. so environments can share a vhost"`
+			Exchange             string        `conf:"help:direct exchange messages are published through instead of the default one"`
 			MaxTimeForConnection time.Duration `conf:"default:1m"`
 		}
 
 		Scheduler struct {
-			MaxFailedTasksRetries       int           `conf:"default:1"`
-			MaxTimeForTaskUpdates       time.Duration `conf:"default:1m"` //slow machine maybe
-			MaxTimeForGraceFullShutdown time.Duration `conf:"default:1m"`
-			MaxTimeForTaskExecution     time.Duration `conf:"default:1m"`
+			MaxFailedTasksRetries   int            `conf:"default:1"`
+			MaxRetriesPerTask       int            `conf:"default:10,help:upper bound of the retries a task may ask for"`
+			DedupWindow             time.Duration  `conf:"default:10m,help:how long a completed task is returned for a new task with its dedupKey"`
+			MaxTimeForTaskUpdates   time.Duration  `conf:"default:1m"` //slow machine maybe
+			UpdateAttempts          int            `conf:"default:3,help:how many times the status of a finished task is written before it is spilled"`
+			UpdateBackoff           time.Duration  `conf:"default:1s,help:wait after the first failed write of a status and it doubles after every attempt"`
+			ResultHandlers          int            `conf:"default:10,help:messages of each of the success, failed and retry queues handled at the same time"`
+			UpdateBatchInterval     time.Duration  `conf:"default:10ms,help:how long a status waits to be written along with others and 0 writes each on its own"`
+			UpdateBatchSize         int            `conf:"default:100,help:statuses written at most in a single update"`
+			RetentionMonths         int            `conf:"default:0,help:months of tasks kept besides the current one and 0 keeps all"`
+			RetryTTL                time.Duration  `conf:"default:0s,help:how long a retry counter is kept after its last retry and 0 derives it from the max retries"`
+			LateAfter               time.Duration  `conf:"default:0s,help:how long after its scheduled time a task that did not start is reported as late and 0 disables it"`
+			SpillDir                string         `conf:"default:zarf/spill/,help:where statuses are kept during a database outage when redis is disabled"`
+			MaxTimeForTaskExecution time.Duration  `conf:"default:1m"`
+			MaxTimeForImagePull     time.Duration  `conf:"default:5m,help:bounds pulling the image of a task before it runs"`
+			Standby                 bool           `conf:"default:false"`
+			LeaseTTL                time.Duration  `conf:"default:15s"`
+			BreakerThreshold        int            `conf:"default:5"`
+			BreakerProbeInterval    time.Duration  `conf:"default:30s"`
+			AffinityTimeout         time.Duration  `conf:"default:30s"`
+			AssignTimeout           time.Duration  `conf:"default:30s"`
+			MaxRedeliveries         int            `conf:"default:5"`
+			QueuedTimeout           time.Duration  `conf:"default:15m"`
+			ImageLimits             map[string]int `conf:"help:running tasks per image across instances like postgres-backup:2;ffmpeg:1"`
+			Mode                    string         `conf:"default:all,help:all|dispatch, dispatch leaves execution to workers"`
+			MaxResultBytes          int            `conf:"default:16777216,help:bytes of output kept per task"`
+			NetworkDefault          string         `conf:"default:none,help:network mode of tasks that do not ask for one none|internal|egress"`
+			NetworkAllowed          []string       `conf:"default:none,help:network modes tasks may ask for like none;internal"`
+			InternalNetwork         string         `conf:"default:tasks-internal,help:docker network of the internal mode"`
+			EgressNetwork           string         `conf:"default:bridge,help:docker network of the egress mode"`
+			Runtime                 string         `conf:"default:docker,help:runtime tasks run with docker|podman|containerd|exec, exec runs them on the host"`
+			WarmPool                []string       `conf:"help:idle containers kept per image like alpine:3.20=2;python:3.12-slim=1"`
+			WarmMaxUses             int            `conf:"default:1,help:tasks a warm container runs before it is replaced"`
+			WarmMaxIdle             time.Duration  `conf:"default:10m,help:how long a warm container stays idle before it is replaced"`
+		}
+
+		Exec struct {
+			Allowed    []string `conf:"help:commands the exec runtime may run like date;curl"`
+			WorkDir    string   `conf:"help:where the working directory of every run is created, the temp dir when empty"`
+			Chroot     string   `conf:"help:root directory commands run inside of, requires root and user"`
+			User       string   `conf:"help:unprivileged uid:gid commands run as like 65534:65534, the user of the scheduler when empty"`
+			CPUSeconds int      `conf:"default:0"`
+			MemoryMB   int      `conf:"default:512"`
+			OpenFiles  int      `conf:"default:256"`
+			Processes  int      `conf:"default:0,help:processes of the user commands run as"`
+		}
+
+		Sandbox struct {
+			ReadOnly        bool   `conf:"default:false,help:mounts the root filesystem of containers as read-only"`
+			CapDropAll      bool   `conf:"default:true,help:drops every linux capability of containers"`
+			NoNewPrivileges bool   `conf:"default:true"`
+			User            string `conf:"help:uid[:gid] commands run as like 65534:65534, the user of the image when empty"`
+			Tmpfs           string `conf:"default:/tmp,help:path of the writable scratch directory and none when empty"`
+			TmpfsSizeMB     int    `conf:"default:64"`
+		}
+
+		TLS struct {
+			CertFile         string
+			KeyFile          string
+			AutocertDomains  []string `conf:"help:domains certificates are requested for from lets encrypt like api.example.com;example.com"`
+			AutocertEmail    string
+			AutocertCacheDir string `conf:"default:zarf/certs/"`
+			RedirectHost     string `conf:"help:plain http listener like 0.0.0.0:80 that redirects to https and answers acme challenges"`
+		}
+
+		Notify struct {
+			Slack        bool          `conf:"default:true,help:lets users send the notifications of their tasks to slack webhooks"`
+			SlackTimeout time.Duration `conf:"default:10s,help:requests are cut off after it and task waits end a second before"`
+		}
+
+		Backlog struct {
+			Threshold    int           `conf:"default:0,help:depth of queue_tasks that is reported as a backlog and 0 disables it"`
+			Duration     time.Duration `conf:"default:5m,help:how long the depth must stay above the threshold"`
+			PollInterval time.Duration `conf:"default:30s"`
+			AlertWebhook string        `conf:"help:url a backlog and its recovery and late tasks are posted to"`
 		}
 	}{}
 
@@ -104,7 +279,25 @@ func run() error {
 		{Key: "app", Value: slog.StringValue("task-scheduler")},
 	}
 
-	logger := logger.NewCustomLogger(slog.LevelInfo, isProd, attrs...)
+	//the level changes when the settings are reloaded
+	var level slog.LevelVar
+	if err := level.UnmarshalText([]byte(configs.Log.Level)); err != nil {
+		return fmt.Errorf("parse log level: %w", err)
+	}
+
+	//the access log shares the level of the application logs
+	var accessLogger *slog.Logger
+	if configs.Log.AccessFile != "" {
+		f, err := os.OpenFile(configs.Log.AccessFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+		if err != nil {
+			return fmt.Errorf("open access log: %w", err)
+		}
+		defer f.Close()
+
+		accessLogger = logger.New(f, &level, isProd, attrs...)
+	}
+
+	logger := logger.NewCustomLogger(&level, isProd, attrs...)
 
 	//==========================================================================
 	//validator
@@ -113,6 +306,22 @@ func run() error {
 		return fmt.Errorf("creating app validator: %w", err)
 	}
 	logger.Info("application validator", "status", "successfully initialized")
+	//==========================================================================
+	//calls to postgres, redis and rabbitmq fail fast after repeated failures instead of piling up
+	var breakers []*breaker.Breaker
+	newBreaker := func(name string) *breaker.Breaker {
+		b := breaker.New(name, breaker.Config{
+			Threshold: configs.Breaker.Threshold,
+			Cooldown:  configs.Breaker.Cooldown,
+			OnStateChange: func(name string, from breaker.State, to breaker.State) {
+				logger.Warn("breaker", "status", to.String(), "dependency", name, "from", from.String())
+				metrics.SetDependencyBreaker(name, to)
+			},
+		})
+		breakers = append(breakers, b)
+		return b
+	}
+
 	//==========================================================================
 	//database setup
 	logger.Info("database setup", "status", "connecting", "host", configs.DB.Host)
@@ -122,10 +331,12 @@ func run() error {
 		Host:        configs.DB.Host,
 		Name:        configs.DB.Name,
 		DisableTLS:  configs.DB.DisableTLS,
-		MaxIdleConn: configs.DB.MaxIdleConns,
 		MaxOpenConn: configs.DB.MaxOpenConns,
 		MaxIdleTime: configs.DB.MaxIdleConnTime,
 		MaxLifeTime: configs.DB.MaxConnLifeTime,
+		SlowQuery:   configs.DB.SlowQuery,
+		Logger:      logger,
+		Breaker:     newBreaker("postgres"),
 	})
 	if err != nil {
 		return fmt.Errorf("connecting to db: %w", err)
@@ -146,50 +357,215 @@ func run() error {
 	}
 	logger.Info("database", "status", "ready to use")
 
+	//connection pool stats are read from the debug server
+	metrics.PublishDBPool(func() any {
+		return client.Stats()
+	})
+
 	//==========================================================================
 	//keystore
 	logger.Info("keystore", "status", "initializing keystore support")
 
-	ks, err := keystore.LoadFromFS(os.DirFS(configs.Auth.KeysFolder))
+	var ks auth.Keystore
+	switch configs.Auth.Backend {
+	case "file":
+		fileKS, err := keystore.LoadFromFS(os.DirFS(configs.Auth.KeysFolder))
+		if err != nil {
+			return fmt.Errorf("loadFromFS: %w", err)
+		}
+		ks = fileKS
+
+	case "vault":
+		vaultKS, err := vault.New(vault.Config{
+			Address:   configs.Vault.Address,
+			Token:     configs.Vault.Token,
+			MountPath: configs.Vault.MountPath,
+			Keys:      append([]string{configs.Auth.ActiveKid}, configs.Auth.TrustedKids...),
+		})
+		if err != nil {
+			return fmt.Errorf("vault keystore: %w", err)
+		}
+		ks = vaultKS
+
+	case "awskms":
+		kmsKS, err := awskms.New(awskms.Config{
+			Region:          configs.KMS.Region,
+			AccessKeyID:     configs.KMS.AccessKeyID,
+			SecretAccessKey: configs.KMS.SecretAccessKey,
+			SessionToken:    configs.KMS.SessionToken,
+			Endpoint:        configs.KMS.Endpoint,
+			Keys:            append([]string{configs.Auth.ActiveKid}, configs.Auth.TrustedKids...),
+		})
+		if err != nil {
+			return fmt.Errorf("kms keystore: %w", err)
+		}
+		ks = kmsKS
+
+	default:
+		return fmt.Errorf("unknown keystore backend %q", configs.Auth.Backend)
+	}
+
+	//fail fast when the active key is not reachable
+	if _, err := ks.PublicKey(configs.Auth.ActiveKid); err != nil {
+		return fmt.Errorf("active key %q: %w", configs.Auth.ActiveKid, err)
+	}
+	logger.Info("keystore", "status", "initialized", "backend", configs.Auth.Backend)
+
+	//secrets are encrypted with a key of the file keystore whatever signs the tokens
+	secretsKS, err := keystore.LoadFromFS(os.DirFS(configs.Secrets.KeysFolder))
 	if err != nil {
-		return fmt.Errorf("loadFromFS: %w", err)
+		return fmt.Errorf("load secrets keystore: %w", err)
+	}
+
+	secretKey, err := secretsKS.EncryptionKey(configs.Secrets.KeyID)
+	if err != nil {
+		return fmt.Errorf("secrets key: %w", err)
 	}
 
 	//==========================================================================
-	//redis
-	logger.Info("redis", "status", "initializing keystore support")
-	redisClient := redis.NewClient(&redis.Options{
-		Addr:     configs.Redis.Host,
-		Password: configs.Redis.Password,
-		DB:       configs.Redis.DBIdx,
-	})
+	//blob storage, optional: without it results are stored inline inside of postgres.
+	var resultBlobs handlers.BlobStore
+	switch configs.Results.Backend {
+	case "none":
+		logger.Info("results", "status", "offloading disabled")
+
+	case "dir":
+		dir, err := blob.NewDir(configs.Results.Dir)
+		if err != nil {
+			return fmt.Errorf("results dir: %w", err)
+		}
+		resultBlobs = dir
+
+	case "s3":
+		s3Store, err := s3.New(s3.Config{
+			Bucket:          configs.S3.Bucket,
+			Region:          configs.S3.Region,
+			AccessKeyID:     configs.S3.AccessKeyID,
+			SecretAccessKey: configs.S3.SecretAccessKey,
+			SessionToken:    configs.S3.SessionToken,
+			Endpoint:        configs.S3.Endpoint,
+		})
+		if err != nil {
+			return fmt.Errorf("results s3: %w", err)
+		}
+		resultBlobs = s3Store
 
-	logger.Info("redis", "status", "pinging redis engine")
-	ctx, cancel = context.WithTimeout(context.Background(), time.Second*configs.Redis.Timeout)
-	defer cancel()
+	default:
+		return fmt.Errorf("unknown results backend %q", configs.Results.Backend)
+	}
 
-	err = redisClient.Ping(ctx).Err()
-	if err != nil {
-		return fmt.Errorf("redis ping: %w", err)
+	if resultBlobs != nil {
+		logger.Info("results", "status", "offloading enabled", "backend", configs.Results.Backend, "offloadBytes", configs.Results.OffloadBytes)
 	}
-	logger.Info("redis", "status", "successfully connected")
 
 	//==========================================================================
-	// rabbitmq setup
-	logger.Info("rabbitmq", "status", "setting up the connection")
-	ctx, cancel = context.WithTimeout(context.Background(), configs.Rabbitmq.MaxTimeForConnection)
-	defer cancel()
+	//redis, optional: small deployments can keep all of the scheduler state inside postgres.
+	var redisClient *redis.Client
+	if configs.Redis.Enabled {
+		logger.Info("redis", "status", "initializing redis support")
+		redisClient = redis.NewClient(&redis.Options{
+			Addr:     configs.Redis.Host,
+			Password: configs.Redis.Password,
+			DB:       configs.Redis.DBIdx,
+		})
+		redisClient.AddHook(breaker.NewRedisHook(newBreaker("redis")))
+
+		logger.Info("redis", "status", "pinging redis engine")
+		ctx, cancel = context.WithTimeout(context.Background(), configs.Redis.Timeout)
+		defer cancel()
 
-	rabbitMQC, err := rabbitmq.NewClient(ctx, rabbitmq.Configs{
-		Host:     configs.Rabbitmq.Host,
-		User:     configs.Rabbitmq.User,
-		Password: configs.Rabbitmq.Password,
-	})
-	if err != nil {
-		return fmt.Errorf("new rabbitmq client: %w", err)
+		err = redisClient.Ping(ctx).Err()
+		if err != nil {
+			return fmt.Errorf("redis ping: %w", err)
+		}
+		logger.Info("redis", "status", "successfully connected")
+	} else {
+		logger.Info("redis", "status", "disabled")
 	}
 
-	logger.Info("rabbitmq", "status", "connection successfully made to the server")
+	//==========================================================================
+	//mailer, optional: without it email verification and password reset are disabled.
+	var smtpMailer *mailer.Mailer
+	if configs.SMTP.Host != "" {
+		from, err := mail.ParseAddress(configs.SMTP.From)
+		if err != nil {
+			return fmt.Errorf("parse smtp from address: %w", err)
+		}
+
+		smtpMailer, err = mailer.New(mailer.Config{
+			Host:     configs.SMTP.Host,
+			Port:     configs.SMTP.Port,
+			Username: configs.SMTP.Username,
+			Password: configs.SMTP.Password,
+			From:     *from,
+		})
+		if err != nil {
+			return fmt.Errorf("new mailer: %w", err)
+		}
+		logger.Info("mailer", "status", "initialized", "host", configs.SMTP.Host)
+	} else {
+		logger.Info("mailer", "status", "disabled")
+	}
+
+	//==========================================================================
+	// broker setup
+	var (
+		msgBroker   broker.Broker
+		closeBroker func() error
+	)
+	switch configs.Broker.Kind {
+	case "rabbitmq":
+		logger.Info("rabbitmq", "status", "setting up the connection")
+		ctx, cancel = context.WithTimeout(context.Background(), configs.Rabbitmq.MaxTimeForConnection)
+		defer cancel()
+
+		rabbitMQC, err := rabbitmq.NewClient(ctx, rabbitmq.Configs{
+			Host:        configs.Rabbitmq.Host,
+			User:        configs.Rabbitmq.User,
+			Password:    configs.Rabbitmq.Password,
+			VHost:       configs.Rabbitmq.VHost,
+			QueuePrefix: configs.Rabbitmq.QueuePrefix,
+			Exchange:    configs.Rabbitmq.Exchange,
+			Breaker:     newBreaker("rabbitmq"),
+		})
+		if err != nil {
+			return fmt.Errorf("new rabbitmq client: %w", err)
+		}
+
+		logger.Info("rabbitmq", "status", "connection successfully made to the server")
+		msgBroker = rabbitMQC
+		closeBroker = rabbitMQC.Close
+
+	case "memory":
+		//queued messages are lost on restart, their tasks are claimed again once the queued timeout passes
+		logger.Warn("broker", "status", "in-memory broker", "msg", "queues live inside of this instance only")
+		memBroker := memory.New()
+		msgBroker = memBroker
+		closeBroker = memBroker.Close
+
+	default:
+		return fmt.Errorf("unknown broker kind %q", configs.Broker.Kind)
+	}
+
+	//==========================================================================
+	//backlog alerts, optional: without a webhook a backlog and late tasks are only logged.
+	var backlogAlert handlers.Alerter
+	if configs.Backlog.AlertWebhook != "" {
+		hook, err := webhook.New(configs.Backlog.AlertWebhook, 0)
+		if err != nil {
+			return fmt.Errorf("backlog webhook: %w", err)
+		}
+		backlogAlert = hook
+	}
+
+	//==========================================================================
+	//debug server
+	go func() {
+		logger.Info("debug", "status", "debug server started", "host", configs.API.DebugHost)
+		if err := http.ListenAndServe(configs.API.DebugHost, debug.Mux()); err != nil {
+			logger.Error("debug", "status", "debug server closed", "host", configs.API.DebugHost, "msg", err)
+		}
+	}()
 
 	//==========================================================================
 	//server
@@ -201,54 +577,289 @@ func run() error {
 
 	maxRunningTasks := runtime.GOMAXPROCS(0)
 
+	schedulerMode, err := scheduler.ParseMode(configs.Scheduler.Mode)
+	if err != nil {
+		return fmt.Errorf("parse scheduler mode: %w", err)
+	}
+
+	//standalone workers can not reach the queues of an in-memory broker
+	if configs.Broker.Kind == "memory" && schedulerMode != scheduler.ModeAll {
+		return fmt.Errorf("the memory broker requires scheduler mode all, got %s", schedulerMode)
+	}
+
+	networks, err := task.ParseNetworkPolicy(configs.Scheduler.NetworkDefault, configs.Scheduler.NetworkAllowed)
+	if err != nil {
+		return fmt.Errorf("parse network policy: %w", err)
+	}
+
+	var runner containerRuntime.Runner
+	switch configs.Scheduler.Runtime {
+	case "exec":
+		runner, err = containerRuntime.NewExec(containerRuntime.ExecConfig{
+			Allowed: configs.Exec.Allowed,
+			WorkDir: configs.Exec.WorkDir,
+			Chroot:  configs.Exec.Chroot,
+			User:    configs.Exec.User,
+			Limits: containerRuntime.ExecLimits{
+				CPUSeconds: configs.Exec.CPUSeconds,
+				MemoryMB:   configs.Exec.MemoryMB,
+				OpenFiles:  configs.Exec.OpenFiles,
+				Processes:  configs.Exec.Processes,
+			},
+		})
+	default:
+		runner, err = containerRuntime.New(configs.Scheduler.Runtime)
+	}
+	if err != nil {
+		return fmt.Errorf("container runtime: %w", err)
+	}
+
+	warmPool, err := scheduler.ParseWarmPool(configs.Scheduler.WarmPool)
+	if err != nil {
+		return fmt.Errorf("parse warm pool: %w", err)
+	}
+
+	security := task.SecurityProfile{
+		ReadOnly:        configs.Sandbox.ReadOnly,
+		CapDropAll:      configs.Sandbox.CapDropAll,
+		NoNewPrivileges: configs.Sandbox.NoNewPrivileges,
+		User:            configs.Sandbox.User,
+		Tmpfs:           configs.Sandbox.Tmpfs,
+		TmpfsSizeMB:     configs.Sandbox.TmpfsSizeMB,
+	}
+
+	//the socket is taken before the scheduler starts, so the processes of tasks never inherit it
+	ln, err := listener.Listen(configs.API.Host, listener.Config{
+		Name:      configs.API.SocketName,
+		ReusePort: configs.API.ReusePort,
+	})
+	if err != nil {
+		return fmt.Errorf("api listener: %w", err)
+	}
+
+	//the watcher is started once the services its settings are applied to exist
+	var watcher *reload.Watcher
+
 	app, err := handlers.RegisterRoutes(handlers.Config{
-		Shutdown:                    shutdownCh,
-		Logger:                      logger,
-		Validator:                   appValidator,
-		PostgresClient:              client,
-		ActiveKID:                   configs.Auth.ActiveKid,
-		TokenAge:                    configs.Auth.TokenAge,
-		Keystore:                    ks,
-		RClient:                     rabbitMQC,
-		RedisClient:                 redisClient,
-		MaxRunningTasks:             maxRunningTasks,
-		MaxFailedTasksRetry:         configs.Scheduler.MaxFailedTasksRetries,
-		MaxTimeForTaskUpdates:       configs.Scheduler.MaxTimeForTaskUpdates,
-		MaxTimeForSchedulerShutdown: configs.Scheduler.MaxTimeForGraceFullShutdown,
-		MaxTimeForTaskExecution:     configs.Scheduler.MaxTimeForTaskExecution,
+		Build:                   build,
+		Shutdown:                shutdownCh,
+		Logger:                  logger,
+		Validator:               appValidator,
+		PostgresClient:          client,
+		ActiveKID:               configs.Auth.ActiveKid,
+		TokenAge:                configs.Auth.TokenAge,
+		Keystore:                ks,
+		SecretKey:               secretKey,
+		Broker:                  msgBroker,
+		RedisClient:             redisClient,
+		Mailer:                  smtpMailer,
+		ResultBlobs:             resultBlobs,
+		ResultOffloadBytes:      configs.Results.OffloadBytes,
+		LoginMaxFailures:        configs.Login.MaxFailures,
+		LoginMaxFailuresPerIP:   configs.Login.MaxFailuresPerIP,
+		LoginLockDuration:       configs.Login.LockDuration,
+		MaxRunningTasks:         maxRunningTasks,
+		MaxFailedTasksRetry:     configs.Scheduler.MaxFailedTasksRetries,
+		MaxRetriesPerTask:       configs.Scheduler.MaxRetriesPerTask,
+		DedupWindow:             configs.Scheduler.DedupWindow,
+		MaxTaskWait:             configs.API.WriteTimeout - time.Second, //answered before the write timeout cuts them off
+		MaxTimeForTaskUpdates:   configs.Scheduler.MaxTimeForTaskUpdates,
+		StatusUpdateAttempts:    configs.Scheduler.UpdateAttempts,
+		StatusUpdateBackoff:     configs.Scheduler.UpdateBackoff,
+		ResultHandlers:          configs.Scheduler.ResultHandlers,
+		StatusBatchInterval:     configs.Scheduler.UpdateBatchInterval,
+		StatusBatchSize:         configs.Scheduler.UpdateBatchSize,
+		RetentionMonths:         configs.Scheduler.RetentionMonths,
+		RetryTTL:                configs.Scheduler.RetryTTL,
+		LateAfter:               configs.Scheduler.LateAfter,
+		SpillDir:                configs.Scheduler.SpillDir,
+		MaxTimeForTaskExecution: configs.Scheduler.MaxTimeForTaskExecution,
+		MaxTimeForImagePull:     configs.Scheduler.MaxTimeForImagePull,
+		Standby:                 configs.Scheduler.Standby,
+		LeaseTTL:                configs.Scheduler.LeaseTTL,
+		BreakerThreshold:        configs.Scheduler.BreakerThreshold,
+		BreakerProbeInterval:    configs.Scheduler.BreakerProbeInterval,
+		AffinityTimeout:         configs.Scheduler.AffinityTimeout,
+		AssignTimeout:           configs.Scheduler.AssignTimeout,
+		MaxRedeliveries:         configs.Scheduler.MaxRedeliveries,
+		ImageLimits:             configs.Scheduler.ImageLimits,
+		QueuedTimeout:           configs.Scheduler.QueuedTimeout,
+		SchedulerMode:           schedulerMode,
+		MaxResultBytes:          configs.Scheduler.MaxResultBytes,
+		Networks:                networks,
+		InternalNetwork:         configs.Scheduler.InternalNetwork,
+		EgressNetwork:           configs.Scheduler.EgressNetwork,
+		Security:                security,
+		Runner:                  runner,
+		WarmPool:                warmPool,
+		WarmMaxUses:             configs.Scheduler.WarmMaxUses,
+		WarmMaxIdle:             configs.Scheduler.WarmMaxIdle,
+		BacklogThreshold:        configs.Backlog.Threshold,
+		BacklogDuration:         configs.Backlog.Duration,
+		BacklogPollInterval:     configs.Backlog.PollInterval,
+		BacklogAlert:            backlogAlert,
+		CacheTTL:                configs.Redis.CacheTTL,
+		AuthClaimsOnly:          configs.Auth.ClaimsOnly,
+		ProblemJSON:             configs.API.ProblemJSON,
+		MaxBodyBytes:            configs.API.MaxBodyBytes,
+		BasePath:                configs.API.BasePath,
+		VirtualHost:             configs.API.VirtualHost,
+		NotifySlack:             configs.Notify.Slack,
+		NotifySlackTimeout:      configs.Notify.SlackTimeout,
+		Breakers:                breakers,
+		AccessLogger:            accessLogger,
+		AccessSampleRate:        configs.Log.AccessSampleRate,
+		EffectiveConfig: func() (map[string]string, error) {
+			return watcher.Effective(prefix, &configs)
+		},
 	})
 
 	if err != nil {
 		return fmt.Errorf("register routes: %w", err)
 	}
 
+	//==========================================================================
+	//reload
+
+	watcher = reload.New(reload.Config{
+		File:     configs.Reload.File,
+		Interval: configs.Reload.Interval,
+		Logger:   logger,
+		Settings: []reload.Setting{
+			{Key: prefix + "_LOG_LEVEL", Apply: func(value string) error {
+				return level.UnmarshalText([]byte(value))
+			}},
+			{Key: prefix + "_LOGIN_MAXFAILURES", Apply: func(value string) error {
+				n, err := parsePositive(value)
+				if err != nil {
+					return err
+				}
+				return setLockout(app.SetLockout(n, 0, 0))
+			}},
+			{Key: prefix + "_LOGIN_MAXFAILURESPERIP", Apply: func(value string) error {
+				n, err := parsePositive(value)
+				if err != nil {
+					return err
+				}
+				return setLockout(app.SetLockout(0, n, 0))
+			}},
+			{Key: prefix + "_LOGIN_LOCKDURATION", Apply: func(value string) error {
+				d, err := time.ParseDuration(value)
+				if err != nil {
+					return err
+				}
+				if d <= 0 {
+					return fmt.Errorf("must be positive: %s", value)
+				}
+				return setLockout(app.SetLockout(0, 0, d))
+			}},
+			{Key: prefix + "_SCHEDULER_MAXFAILEDTASKSRETRIES", Apply: func(value string) error {
+				n, err := strconv.Atoi(value)
+				if err != nil {
+					return err
+				}
+				return app.SetMaxRetries(n)
+			}},
+		},
+	})
+	watcher.Start()
+	defer watcher.Stop()
+
 	srv := http.Server{
 		Addr:        configs.API.Host,
 		Handler:     http.TimeoutHandler(app, configs.API.WriteTimeout, "timed out"),
 		ReadTimeout: configs.API.ReadTimeout,
 		ErrorLog:    slog.NewLogLogger(logger.Handler(), slog.LevelError),
 	}
+
+	tlsConf, challenges, err := tlsconf.New(tlsconf.Config{
+		CertFile:         configs.TLS.CertFile,
+		KeyFile:          configs.TLS.KeyFile,
+		AutocertDomains:  configs.TLS.AutocertDomains,
+		AutocertEmail:    configs.TLS.AutocertEmail,
+		AutocertCacheDir: configs.TLS.AutocertCacheDir,
+	})
+	if err != nil {
+		return fmt.Errorf("tls: %w", err)
+	}
+	srv.TLSConfig = tlsConf
+
 	logger.Info("mux", "status", "registering routes to the mux")
 	//server start
 	go func() {
-		logger.Info("server", "status", "started", "host", configs.API.Host, "environment", configs.API.Environment)
-		serverErrors <- srv.ListenAndServe()
+		logger.Info("server", "status", "started", "host", configs.API.Host, "environment", configs.API.Environment, "tls", tlsConf != nil)
+		if tlsConf != nil {
+			//certificates come from the tls config, http/2 is negotiated through it
+			serverErrors <- srv.ServeTLS(ln, "", "")
+			return
+		}
+		serverErrors <- srv.Serve(ln)
 	}()
 
+	var redirectSrv *http.Server
+	if configs.TLS.RedirectHost != "" && tlsConf != nil {
+		redirectLn, err := listener.Listen(configs.TLS.RedirectHost, listener.Config{
+			Name:      "redirect",
+			ReusePort: configs.API.ReusePort,
+		})
+		if err != nil {
+			return fmt.Errorf("redirect listener: %w", err)
+		}
+
+		redirectSrv = &http.Server{
+			Addr:        configs.TLS.RedirectHost,
+			Handler:     challenges(tlsconf.RedirectHTTPS(configs.API.Host)),
+			ReadTimeout: configs.API.ReadTimeout,
+			ErrorLog:    slog.NewLogLogger(logger.Handler(), slog.LevelError),
+		}
+
+		go func() {
+			logger.Info("redirect", "status", "started", "host", configs.TLS.RedirectHost)
+			serverErrors <- redirectSrv.Serve(redirectLn)
+		}()
+	}
+
 	//block
 	select {
 	case serverErr := <-serverErrors:
 		return fmt.Errorf("server error: %w", serverErr)
 	case signal := <-shutdownCh:
-		//graceful shutdown
+		//requests are drained before the scheduler so none of them enqueues a task once the consumers stopped, the
+		//connections are closed last since both of them use them.
 		logger.Info("shutdown", "status", "started", "signal", signal)
 
 		ctx, cancel := context.WithTimeout(context.Background(), configs.API.ShutdownTimeout)
 		defer cancel()
 
-		if err := srv.Shutdown(ctx); err != nil {
-			//force shutdown
-			_ = srv.Close()
+		err := shutdown.Run(ctx, logger,
+			shutdown.Phase{Name: "http", Stop: func(ctx context.Context) error {
+				if redirectSrv != nil {
+					_ = redirectSrv.Shutdown(ctx)
+				}
+
+				if err := srv.Shutdown(ctx); err != nil {
+					//force shutdown
+					_ = srv.Close()
+					return err
+				}
+				return nil
+			}},
+			shutdown.Phase{Name: "scheduler", Stop: app.Shutdown},
+			shutdown.Phase{Name: "broker", Stop: func(ctx context.Context) error {
+				return closeBroker()
+			}},
+			shutdown.Phase{Name: "redis", Stop: func(ctx context.Context) error {
+				if redisClient == nil {
+					return nil
+				}
+				return redisClient.Close()
+			}},
+			shutdown.Phase{Name: "database", Stop: func(ctx context.Context) error {
+				client.Close()
+				return nil
+			}},
+		)
+		if err != nil {
 			return fmt.Errorf("graceful shutdown failed: %w", err)
 		}
 
@@ -256,3 +867,24 @@ func run() error {
 	}
 	return nil
 }
+
+// setLockout fails when login throttling is disabled, since the limits would not apply to anything.
+func setLockout(ok bool) error {
+	if !ok {
+		return errors.New("login throttling is disabled, it requires redis")
+	}
+	return nil
+}
+
+// parsePositive parses a setting that has to be larger than zero.
+func parsePositive(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+
+	if n <= 0 {
+		return 0, fmt.Errorf("must be positive: %s", value)
+	}
+	return n, nil
+}
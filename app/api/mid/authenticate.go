@@ -15,18 +15,19 @@ func Authenticate(a *auth.Auth) web.Middleware {
 		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 			authHeader := r.Header.Get("authorization")
 			if authHeader == "" {
-				return errs.NewAppError(http.StatusUnauthorized, "missing authorization header")
+				return errs.NewAppCodeError(http.StatusUnauthorized, errs.CodeMissingToken, "missing authorization header")
 			}
 			ctx, cancel := context.WithTimeout(ctx, time.Second*5)
 			defer cancel()
 
 			user, err := a.ValidateToken(ctx, authHeader)
 			if err != nil {
-				return errs.NewAppError(http.StatusUnauthorized, err.Error())
+				return errs.NewAppCodeError(http.StatusUnauthorized, errs.CodeInvalidToken, err.Error())
 			}
 
 			//add claims into ctx
 			ctx = auth.SetUser(ctx, user)
+			setAccessUser(ctx, user.Id)
 			//call the next handler
 			return h(ctx, w, r)
 		}
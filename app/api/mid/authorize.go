@@ -2,7 +2,9 @@ package mid
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"slices"
 
 	"github.com/hamidoujand/task-scheduler/app/api/auth"
 	"github.com/hamidoujand/task-scheduler/app/api/errs"
@@ -16,16 +18,61 @@ func Authorized(a *auth.Auth, roles ...user.Role) web.Middleware {
 			//get the user
 			usr, err := auth.GetUser(ctx)
 			if err != nil {
-				return errs.NewAppError(http.StatusUnauthorized, err.Error())
+				return errs.NewAppCodeError(http.StatusUnauthorized, errs.CodeNotAuthenticated, err.Error())
 			}
 
 			//check the user roles
 			err = a.Authorized(usr, roles)
 			if err != nil {
-				return errs.NewAppError(http.StatusUnauthorized, "unauthorized")
+				return errs.NewAppCodeError(http.StatusUnauthorized, errs.CodeNotPermitted, "unauthorized")
 			}
 
 			return h(ctx, w, r)
 		}
 	}
 }
+
+// Authorize evaluates the declared rule of the route against the authenticated user, it must run after Authenticate.
+func Authorize(rule auth.Rule) web.Middleware {
+	return func(h web.Handler) web.Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if rule.Public {
+				return h(ctx, w, r)
+			}
+
+			usr, err := auth.GetUser(ctx)
+			if err != nil {
+				return errs.NewAppCodeError(http.StatusUnauthorized, errs.CodeNotAuthenticated, "unauthorized")
+			}
+
+			//any authenticated user
+			if len(rule.Roles) == 0 && rule.Owner == nil {
+				return h(ctx, w, r)
+			}
+
+			for _, role := range usr.Roles {
+				if slices.Contains(rule.Roles, role) {
+					return h(ctx, w, r)
+				}
+			}
+
+			if rule.Owner != nil {
+				owner, err := rule.Owner(ctx, r)
+				if err != nil {
+					//resolvers report bad ids and missing resources themselves
+					var appErr *errs.AppError
+					if errors.As(err, &appErr) {
+						return err
+					}
+					return errs.NewAppInternalErr(err)
+				}
+
+				if owner == usr.Id {
+					return h(ctx, w, r)
+				}
+			}
+
+			return errs.NewAppCodeError(http.StatusUnauthorized, errs.CodeNotPermitted, "unauthorized: operation not permitted")
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/hamidoujand/task-scheduler/foundation/web"
+)
+
+// MaxBodySize limits the bodies of the requests to limit bytes, reading past it fails web.Decode.
+func MaxBodySize(limit int64) web.Middleware {
+	m := func(h web.Handler) web.Handler {
+		handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if limit > 0 && r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, limit)
+			}
+			return h(ctx, w, r)
+		}
+		return handler
+	}
+	return m
+}
@@ -0,0 +1,22 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/hamidoujand/task-scheduler/business/database/postgres"
+	"github.com/hamidoujand/task-scheduler/foundation/web"
+)
+
+// DBOp is a middleware, it names the database queries of the request after its route so slow queries can be traced
+// back to their handler.
+func DBOp() web.Middleware {
+	m := func(h web.Handler) web.Handler {
+		handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			ctx = postgres.WithOp(ctx, r.Pattern)
+			return h(ctx, w, r)
+		}
+		return handler
+	}
+	return m
+}
@@ -6,13 +6,15 @@ import (
 	"log/slog"
 	"net/http"
 	"path/filepath"
+	"strings"
 
 	"github.com/hamidoujand/task-scheduler/app/api/errs"
 	"github.com/hamidoujand/task-scheduler/foundation/web"
 )
 
-// Errors is a middleware used to do the error handling of the routes.
-func Errors(logger *slog.Logger) web.Middleware {
+// Errors is a middleware used to do the error handling of the routes, errors are sent as problem details of RFC 7807
+// when problems is true or the client accepts "application/problem+json".
+func Errors(logger *slog.Logger, problems bool) web.Middleware {
 	m := func(h web.Handler) web.Handler {
 		handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 			err := h(ctx, w, r)
@@ -31,7 +33,12 @@ func Errors(logger *slog.Logger) web.Middleware {
 						appErr.Message = http.StatusText(http.StatusInternalServerError)
 					}
 					// send response to client
-					if err := web.Respond(ctx, w, appErr.Code, appErr); err != nil {
+					if problems || acceptsProblem(r) {
+						err = web.RespondAs(ctx, w, appErr.Code, errs.ProblemContentType, appErr.Problem(r.URL.Path))
+					} else {
+						err = web.Respond(ctx, w, appErr.Code, appErr)
+					}
+					if err != nil {
 						return errs.NewAppInternalErr(err)
 					}
 					return nil //stop err propagation
@@ -48,3 +55,7 @@ func Errors(logger *slog.Logger) web.Middleware {
 	}
 	return m
 }
+
+func acceptsProblem(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), errs.ProblemContentType)
+}
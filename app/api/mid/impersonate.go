@@ -0,0 +1,73 @@
+package mid
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/app/api/auth"
+	"github.com/hamidoujand/task-scheduler/app/api/errs"
+	"github.com/hamidoujand/task-scheduler/business/domain/user"
+	"github.com/hamidoujand/task-scheduler/foundation/web"
+)
+
+// ImpersonateHeader names the user an admin acts as, the "as_user" query parameter is used when it is not set.
+const ImpersonateHeader = "X-Impersonate-User"
+
+// Impersonate lets admins act as the user named by ImpersonateHeader or "as_user", the rest of the chain sees that
+// user as the authenticated one. Every impersonated request is written into the audit log. It must run after
+// Authenticate and before Authorize.
+func Impersonate(users *user.Service, logger *slog.Logger) web.Middleware {
+	return func(h web.Handler) web.Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			id := r.Header.Get(ImpersonateHeader)
+			if id == "" {
+				id = r.URL.Query().Get("as_user")
+			}
+
+			if id == "" {
+				return h(ctx, w, r)
+			}
+
+			admin, err := auth.GetUser(ctx)
+			if err != nil {
+				return errs.NewAppCodeError(http.StatusUnauthorized, errs.CodeNotAuthenticated, "unauthorized")
+			}
+
+			if !slices.Contains(admin.Roles, user.RoleAdmin) {
+				return errs.NewAppCodeError(http.StatusUnauthorized, errs.CodeNotPermitted, "unauthorized: only admins can impersonate users")
+			}
+
+			userId, err := uuid.Parse(id)
+			if err != nil {
+				return errs.NewAppCodeErrorf(http.StatusBadRequest, errs.CodeInvalidId, "%q not a valid uuid", id)
+			}
+
+			usr, err := users.GetUserById(ctx, userId)
+			if err != nil {
+				if errors.Is(err, user.ErrUserNotFound) {
+					return errs.NewAppCodeErrorf(http.StatusNotFound, errs.CodeUserNotFound, "user with id %q not found", id)
+				}
+				return errs.NewAppInternalErr(err)
+			}
+
+			ctx = auth.SetUser(ctx, usr)
+			ctx = auth.SetImpersonator(ctx, admin)
+
+			err = h(ctx, w, r)
+
+			args := []any{"id", web.GetRequestId(ctx), "admin", admin.Id, "user", usr.Id, "method", r.Method, "path", r.URL.Path}
+			if err != nil {
+				args = append(args, "msg", err)
+			} else {
+				args = append(args, "statusCode", web.GetStatusCode(ctx))
+			}
+			logger.Info("audit: impersonation", args...)
+
+			return err
+		}
+	}
+}
@@ -4,14 +4,42 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"math/rand/v2"
 	"net/http"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/hamidoujand/task-scheduler/foundation/web"
 )
 
-// Logger is a middleware, used to log a bare min info around the lifecycle of request.
-func Logger(logger *slog.Logger) web.Middleware {
+type accessKey int
+
+const accessEntryKey accessKey = 1
+
+// AccessLog represents which requests are written into the access log.
+type AccessLog struct {
+	//SuccessSampleRate is the fraction of the requests answered with a 2xx status that are logged, from 0 to 1.
+	//Every other request is always logged.
+	SuccessSampleRate float64
+}
+
+// accessEntry holds what the middlewares after Logger learn about the request, like the authenticated user.
+type accessEntry struct {
+	userId uuid.UUID
+}
+
+// setAccessUser records the authenticated user of the request for the access log.
+func setAccessUser(ctx context.Context, userId uuid.UUID) {
+	entry, ok := ctx.Value(accessEntryKey).(*accessEntry)
+	if !ok {
+		return
+	}
+	entry.userId = userId
+}
+
+// Logger is a middleware that writes a line into the access log for every request once it is answered, successful
+// requests are sampled by conf.
+func Logger(logger *slog.Logger, conf AccessLog) web.Middleware {
 	m := func(h web.Handler) web.Handler {
 		handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 			path := r.URL.Path
@@ -22,16 +50,33 @@ func Logger(logger *slog.Logger) web.Middleware {
 
 			reqId := web.GetRequestId(ctx)
 
-			logger.Info("request started", "id", reqId, "method", r.Method, "path", path, "remoteAddr", r.RemoteAddr)
-			err := h(ctx, w, r)
+			logger.Debug("request started", "id", reqId, "method", r.Method, "path", path, "remoteAddr", r.RemoteAddr)
 
-			startedAt := web.GetStartedAt(ctx)
-			statusCode := web.GetStatusCode(ctx)
+			var entry accessEntry
+			ctx = context.WithValue(ctx, accessEntryKey, &entry)
 
-			logger.Info("request completed", "id", reqId, "method", r.Method, "path", path, "remoteAddr", r.RemoteAddr,
-				"took", time.Since(startedAt),
+			rw := responseWriter{ResponseWriter: w}
+			err := h(ctx, &rw, r)
+
+			statusCode := rw.statusCode
+			if statusCode == 0 {
+				statusCode = web.GetStatusCode(ctx)
+			}
+
+			if !logged(statusCode, conf.SuccessSampleRate) {
+				return err
+			}
+
+			args := []any{"id", reqId, "method", r.Method, "path", path, "remoteAddr", r.RemoteAddr,
 				"statusCode", statusCode,
-			)
+				"bytes", rw.bytes,
+				"took", time.Since(web.GetStartedAt(ctx)),
+			}
+			if entry.userId != uuid.Nil {
+				args = append(args, "userId", entry.userId)
+			}
+
+			logger.Info("request completed", args...)
 
 			return err
 		}
@@ -39,3 +84,47 @@ func Logger(logger *slog.Logger) web.Middleware {
 	}
 	return m
 }
+
+// logged decides whether a request answered with statusCode is written into the access log.
+func logged(statusCode int, successSampleRate float64) bool {
+	if statusCode < 200 || statusCode > 299 {
+		return true
+	}
+
+	switch {
+	case successSampleRate >= 1:
+		return true
+	case successSampleRate <= 0:
+		return false
+	}
+	return rand.Float64() < successSampleRate
+}
+
+// responseWriter records the status and the size of the response.
+type responseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	bytes      int
+}
+
+func (rw *responseWriter) WriteHeader(statusCode int) {
+	if rw.statusCode == 0 {
+		rw.statusCode = statusCode
+	}
+	rw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if rw.statusCode == 0 {
+		rw.statusCode = http.StatusOK
+	}
+
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the writer of the server.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
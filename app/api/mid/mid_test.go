@@ -5,11 +5,13 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"net/mail"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -18,6 +20,7 @@ import (
 	"github.com/hamidoujand/task-scheduler/app/api/auth"
 	"github.com/hamidoujand/task-scheduler/app/api/errs"
 	"github.com/hamidoujand/task-scheduler/app/api/mid"
+	"github.com/hamidoujand/task-scheduler/business/database/postgres"
 	"github.com/hamidoujand/task-scheduler/business/domain/user"
 	"github.com/hamidoujand/task-scheduler/business/domain/user/store/memory"
 	"github.com/hamidoujand/task-scheduler/foundation/web"
@@ -26,9 +29,10 @@ import (
 
 func TestErrorsMiddleware(t *testing.T) {
 	type Response struct {
-		Code    int               `json:"code"`
-		Message string            `json:"message"`
-		Fields  map[string]string `json:"fields,omitempty"`
+		Code      int               `json:"code"`
+		ErrorCode string            `json:"errorCode"`
+		Message   string            `json:"message"`
+		Fields    map[string]string `json:"fields,omitempty"`
 	}
 	type Data struct {
 		input    web.Handler
@@ -51,8 +55,9 @@ func TestErrorsMiddleware(t *testing.T) {
 			},
 			hasErr: true,
 			response: Response{
-				Code:    http.StatusInternalServerError,
-				Message: http.StatusText(http.StatusInternalServerError),
+				Code:      http.StatusInternalServerError,
+				ErrorCode: "INTERNAL_ERROR",
+				Message:   http.StatusText(http.StatusInternalServerError),
 			},
 		},
 
@@ -63,8 +68,22 @@ func TestErrorsMiddleware(t *testing.T) {
 
 			hasErr: true,
 			response: Response{
-				Code:    http.StatusBadRequest,
-				Message: "task with id 1 Not Found",
+				Code:      http.StatusBadRequest,
+				ErrorCode: "BAD_REQUEST",
+				Message:   "task with id 1 Not Found",
+			},
+		},
+
+		"handler with a specific code": {
+			input: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return errs.NewAppCodeErrorf(http.StatusNotFound, errs.CodeTaskNotFound, "task with id %d not found", 1)
+			},
+
+			hasErr: true,
+			response: Response{
+				Code:      http.StatusNotFound,
+				ErrorCode: "TASK_NOT_FOUND",
+				Message:   "task with id 1 not found",
 			},
 		},
 
@@ -80,8 +99,9 @@ func TestErrorsMiddleware(t *testing.T) {
 			},
 			hasErr: true,
 			response: Response{
-				Code:    http.StatusBadRequest,
-				Message: "invalid data",
+				Code:      http.StatusBadRequest,
+				ErrorCode: "VALIDATION_FAILED",
+				Message:   "invalid data",
 				Fields: map[string]string{
 					"name": "name is a required field",
 					"age":  "age is a required field",
@@ -94,7 +114,7 @@ func TestErrorsMiddleware(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			var output bytes.Buffer
 			logger := slog.New(slog.NewTextHandler(&output, &slog.HandlerOptions{Level: slog.LevelInfo}))
-			middle := mid.Errors(logger)
+			middle := mid.Errors(logger, false)
 			req := httptest.NewRequest(http.MethodGet, "/", nil)
 			w := httptest.NewRecorder()
 			wrapped := middle(test.input)
@@ -116,6 +136,10 @@ func TestErrorsMiddleware(t *testing.T) {
 					t.Errorf("expected response message to be %q but got %q", test.response.Message, resp.Message)
 				}
 
+				if resp.ErrorCode != test.response.ErrorCode {
+					t.Errorf("expected response error code to be %q but got %q", test.response.ErrorCode, resp.ErrorCode)
+				}
+
 				if !reflect.DeepEqual(resp.Fields, test.response.Fields) {
 					t.Logf("expected\n%+v\ngot\n%+v\n", test.response.Fields, resp.Fields)
 					t.Errorf("expected the fields to be the same as well")
@@ -127,6 +151,110 @@ func TestErrorsMiddleware(t *testing.T) {
 
 }
 
+func TestErrorsMiddlewareProblem(t *testing.T) {
+	failed := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return errs.NewAppValidationError(http.StatusBadRequest, "invalid data", map[string]string{
+			"name": "name is a required field",
+			"age":  "age is a required field",
+		})
+	}
+
+	tests := map[string]struct {
+		problems bool
+		accept   string
+	}{
+		"enabled by config":      {problems: true},
+		"negotiated by a client": {accept: "application/problem+json, application/json"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			req := httptest.NewRequest(http.MethodPost, "/api/tasks/", nil)
+			req.Header.Set("Accept", test.accept)
+			w := httptest.NewRecorder()
+
+			_ = mid.Errors(logger, test.problems)(failed)(context.Background(), w, req)
+
+			if ct := w.Header().Get("Content-Type"); ct != errs.ProblemContentType {
+				t.Errorf("content type= %s, got %s", errs.ProblemContentType, ct)
+			}
+
+			var p errs.Problem
+			if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+				t.Fatalf("should be able to decode problem: %s", err)
+			}
+
+			expected := errs.Problem{
+				Type:      "urn:task-scheduler:validation-failed",
+				Title:     http.StatusText(http.StatusBadRequest),
+				Status:    http.StatusBadRequest,
+				Detail:    "invalid data",
+				Instance:  "/api/tasks/",
+				ErrorCode: errs.CodeValidationFailed,
+				InvalidParams: []errs.InvalidParam{
+					{Name: "age", Reason: "age is a required field"},
+					{Name: "name", Reason: "name is a required field"},
+				},
+			}
+			if !reflect.DeepEqual(p, expected) {
+				t.Errorf("expected\n%+v\ngot\n%+v", expected, p)
+			}
+		})
+	}
+
+	//clients that do not ask for it keep the default body
+	w := httptest.NewRecorder()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	_ = mid.Errors(logger, false)(failed)(context.Background(), w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("content type= %s, got %s", "application/json", ct)
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	type body struct {
+		Name string `json:"name"`
+	}
+
+	tests := map[string]struct {
+		body string
+		err  string
+	}{
+		"valid body":    {body: `{"name":"john"}`},
+		"unknown field": {body: `{"name":"john","age":30}`, err: `unknown field "age"`},
+		"wrong type":    {body: `{"name":30}`, err: `field "name" must be of type string`},
+		"two values":    {body: `{"name":"john"}{}`, err: "body must only contain a single json value"},
+		"empty body":    {body: "", err: "body must not be empty"},
+		"too large":     {body: `{"name":"` + strings.Repeat("a", 64) + `"}`, err: "body must not be larger than 32 bytes"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var decodeErr error
+			handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				var b body
+				decodeErr = web.Decode(r, &b)
+				return nil
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(test.body))
+			_ = mid.MaxBodySize(32)(handler)(context.Background(), httptest.NewRecorder(), req)
+
+			if test.err == "" {
+				if decodeErr != nil {
+					t.Fatalf("expected the body to be decoded: %s", decodeErr)
+				}
+				return
+			}
+
+			if decodeErr == nil || decodeErr.Error() != test.err {
+				t.Errorf("err= %s, got %v", test.err, decodeErr)
+			}
+		})
+	}
+}
+
 //==============================================================================
 
 const (
@@ -163,7 +291,7 @@ func TestAuthenticate(t *testing.T) {
 		},
 	}
 
-	userService := user.NewService(&userRepo)
+	userService := user.NewService(&userRepo, nil)
 	a := auth.New(ks, userService)
 
 	tests := []struct {
@@ -218,7 +346,7 @@ func TestAuthenticate(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tkn, err := a.GenerateToken(kid, tt.claims)
+			tkn, err := a.GenerateToken(context.Background(), kid, tt.claims)
 			if err != nil {
 				t.Fatalf("expected to generate a token: %s", err)
 			}
@@ -305,7 +433,7 @@ func TestAuthorized(t *testing.T) {
 			userRepo := memory.Repository{
 				Users: map[uuid.UUID]user.User{},
 			}
-			userService := user.NewService(&userRepo)
+			userService := user.NewService(&userRepo, nil)
 			a := auth.New(ks, userService)
 
 			r := httptest.NewRequest(http.MethodGet, "/secret", nil)
@@ -345,3 +473,292 @@ func TestAuthorized(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthorize(t *testing.T) {
+	ownerId := uuid.New()
+	owner := func(ctx context.Context, r *http.Request) (uuid.UUID, error) {
+		return ownerId, nil
+	}
+
+	tests := map[string]struct {
+		user        user.User
+		rule        auth.Rule
+		expectError bool
+		errorCode   int
+		code        errs.ErrorCode
+	}{
+		"any authenticated user": {
+			user:        user.User{Id: uuid.New(), Roles: []user.Role{user.RoleUser}},
+			rule:        auth.Rule{},
+			expectError: false,
+		},
+
+		"role allowed": {
+			user:        user.User{Id: uuid.New(), Roles: []user.Role{user.RoleAdmin}},
+			rule:        auth.Rule{Roles: []user.Role{user.RoleAdmin}, Owner: owner},
+			expectError: false,
+		},
+
+		"owner allowed": {
+			user:        user.User{Id: ownerId, Roles: []user.Role{user.RoleUser}},
+			rule:        auth.Rule{Roles: []user.Role{user.RoleAdmin}, Owner: owner},
+			expectError: false,
+		},
+
+		"not the owner": {
+			user:        user.User{Id: uuid.New(), Roles: []user.Role{user.RoleUser}},
+			rule:        auth.Rule{Roles: []user.Role{user.RoleAdmin}, Owner: owner},
+			expectError: true,
+			errorCode:   http.StatusUnauthorized,
+			code:        errs.CodeNotPermitted,
+		},
+
+		"owner resolver error": {
+			user: user.User{Id: uuid.New(), Roles: []user.Role{user.RoleUser}},
+			rule: auth.Rule{Owner: func(ctx context.Context, r *http.Request) (uuid.UUID, error) {
+				return uuid.UUID{}, errs.NewAppCodeError(http.StatusNotFound, errs.CodeTaskNotFound, "not found")
+			}},
+			expectError: true,
+			errorCode:   http.StatusNotFound,
+			code:        errs.CodeTaskNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/secret", nil)
+			w := httptest.NewRecorder()
+
+			called := false
+			h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				called = true
+				return nil
+			}
+
+			ctx := auth.SetUser(context.Background(), test.user)
+			err := mid.Authorize(test.rule)(h)(ctx, w, r)
+			if !test.expectError {
+				if err != nil {
+					t.Fatalf("expected the user to be authorized: %s", err)
+				}
+				if !called {
+					t.Error("expected the handler to be called")
+				}
+				return
+			}
+
+			if called {
+				t.Error("expected the handler to not be called")
+			}
+
+			var appErr *errs.AppError
+			if !errors.As(err, &appErr) {
+				t.Fatalf("expected the type of error to be *errs.AppErr but got %T", err)
+			}
+
+			if appErr.Code != test.errorCode {
+				t.Errorf("appErr.Code= %d, got %d", test.errorCode, appErr.Code)
+			}
+
+			if appErr.ErrorCode != test.code {
+				t.Errorf("appErr.ErrorCode= %s, got %s", test.code, appErr.ErrorCode)
+			}
+		})
+	}
+
+	//a request that did not go through Authenticate has no user
+	err := mid.Authorize(auth.Rule{})(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return nil
+	})(context.Background(), httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/secret", nil))
+
+	var appErr *errs.AppError
+	if !errors.As(err, &appErr) || appErr.ErrorCode != errs.CodeNotAuthenticated {
+		t.Errorf("err= %s, got %v", errs.CodeNotAuthenticated, err)
+	}
+}
+
+func TestDBOp(t *testing.T) {
+	var op string
+	h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		op = postgres.Op(ctx)
+		return nil
+	}
+
+	app := web.NewApp(nil, mid.DBOp())
+	app.HandleFunc(http.MethodGet, "v1", "/tasks/{id}", h)
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/tasks/"+uuid.NewString(), nil)
+	app.ServeHTTP(httptest.NewRecorder(), r)
+
+	if op != "GET /v1/tasks/{id}" {
+		t.Errorf("op= %s, got %s", "GET /v1/tasks/{id}", op)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	tests := map[string]struct {
+		statusCode int
+		sampleRate float64
+		logged     bool
+	}{
+		"success":                 {statusCode: http.StatusOK, sampleRate: 1, logged: true},
+		"success sampled out":     {statusCode: http.StatusOK, sampleRate: 0, logged: false},
+		"client error always":     {statusCode: http.StatusNotFound, sampleRate: 0, logged: true},
+		"server error always":     {statusCode: http.StatusInternalServerError, sampleRate: 0, logged: true},
+		"redirect is not sampled": {statusCode: http.StatusFound, sampleRate: 0, logged: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+			h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return web.Respond(ctx, w, test.statusCode, map[string]string{"status": "ok"})
+			}
+
+			app := web.NewApp(nil, mid.Logger(logger, mid.AccessLog{SuccessSampleRate: test.sampleRate}))
+			app.HandleFunc(http.MethodGet, "v1", "/tasks", h)
+
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/tasks?page=2", nil))
+
+			if !test.logged {
+				if buf.Len() != 0 {
+					t.Errorf("expected the request to be sampled out, got %s", buf.String())
+				}
+				return
+			}
+
+			var entry struct {
+				Msg        string `json:"msg"`
+				Id         string `json:"id"`
+				Method     string `json:"method"`
+				Path       string `json:"path"`
+				StatusCode int    `json:"statusCode"`
+				Bytes      int    `json:"bytes"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("expected a single access log entry: %s: %s", err, buf.String())
+			}
+
+			if entry.StatusCode != test.statusCode {
+				t.Errorf("statusCode= %d, got %d", test.statusCode, entry.StatusCode)
+			}
+
+			if entry.Bytes != rec.Body.Len() {
+				t.Errorf("bytes= %d, got %d", rec.Body.Len(), entry.Bytes)
+			}
+
+			if entry.Path != "/v1/tasks?page=2" {
+				t.Errorf("path= %s, got %s", "/v1/tasks?page=2", entry.Path)
+			}
+
+			if entry.Id == "" || entry.Id == uuid.Nil.String() {
+				t.Errorf("expected the request id, got %q", entry.Id)
+			}
+		})
+	}
+}
+
+func TestImpersonate(t *testing.T) {
+	admin := user.User{Id: uuid.New(), Roles: []user.Role{user.RoleAdmin}, Enabled: true}
+	target := user.User{Id: uuid.New(), Roles: []user.Role{user.RoleUser}, Enabled: true}
+	other := user.User{Id: uuid.New(), Roles: []user.Role{user.RoleUser}, Enabled: true}
+
+	repo := memory.Repository{Users: map[uuid.UUID]user.User{
+		admin.Id:  admin,
+		target.Id: target,
+		other.Id:  other,
+	}}
+
+	var audit bytes.Buffer
+	impersonate := mid.Impersonate(user.NewService(&repo, nil), slog.New(slog.NewJSONHandler(&audit, nil)))
+
+	tests := map[string]struct {
+		caller     user.User
+		header     string
+		query      string
+		statusCode int
+		expected   uuid.UUID
+	}{
+		"no impersonation": {
+			caller:   other,
+			expected: other.Id,
+		},
+		"admin with header": {
+			caller:   admin,
+			header:   target.Id.String(),
+			expected: target.Id,
+		},
+		"admin with query": {
+			caller:   admin,
+			query:    "?as_user=" + target.Id.String(),
+			expected: target.Id,
+		},
+		"user impersonating": {
+			caller:     other,
+			header:     target.Id.String(),
+			statusCode: http.StatusUnauthorized,
+		},
+		"invalid id": {
+			caller:     admin,
+			query:      "?as_user=abc",
+			statusCode: http.StatusBadRequest,
+		},
+		"missing user": {
+			caller:     admin,
+			header:     uuid.NewString(),
+			statusCode: http.StatusNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var acting uuid.UUID
+			h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				usr, err := auth.GetUser(ctx)
+				if err != nil {
+					return err
+				}
+				acting = usr.Id
+
+				if impersonator, ok := auth.GetImpersonator(ctx); ok && impersonator.Id != test.caller.Id {
+					t.Errorf("impersonator= %s, got %s", test.caller.Id, impersonator.Id)
+				}
+				return nil
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "/v1/api/tasks/"+test.query, nil)
+			if test.header != "" {
+				r.Header.Set(mid.ImpersonateHeader, test.header)
+			}
+
+			err := impersonate(h)(auth.SetUser(context.Background(), test.caller), httptest.NewRecorder(), r)
+			if test.statusCode != 0 {
+				var appErr *errs.AppError
+				if !errors.As(err, &appErr) {
+					t.Fatalf("expected the error to be *appError: %T", err)
+				}
+
+				if appErr.Code != test.statusCode {
+					t.Errorf("appErr.Code= %d, got %d", test.statusCode, appErr.Code)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected the handler to be called: %s", err)
+			}
+
+			if acting != test.expected {
+				t.Errorf("user= %s, got %s", test.expected, acting)
+			}
+		})
+	}
+
+	//only the two impersonated requests are audited
+	if n := bytes.Count(audit.Bytes(), []byte(admin.Id.String())); n != 2 {
+		t.Errorf("audit entries= %d, got %d", 2, n)
+	}
+}
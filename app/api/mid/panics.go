@@ -16,7 +16,7 @@ func Panics() web.Middleware {
 				if r := recover(); r != nil {
 					//need to capture and return error to "error handler mid"
 					stackTrace := debug.Stack()
-					err = errs.NewAppErrorf(http.StatusInternalServerError, "PANIC[%v] STACK[%s]", r, string(stackTrace))
+					err = errs.NewAppCodeErrorf(http.StatusInternalServerError, errs.CodeInternal, "PANIC[%v] STACK[%s]", r, string(stackTrace))
 				}
 			}()
 
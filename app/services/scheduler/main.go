@@ -0,0 +1,496 @@
+// Worker process that only executes tasks, it consumes "queue_tasks" and runs the containers while the API
+// dispatches due tasks and handles their results, so execution capacity scales independently of the API.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/ardanlabs/conf/v3"
+	"github.com/hamidoujand/task-scheduler/app/api/debug"
+	"github.com/hamidoujand/task-scheduler/business/broker/rabbitmq"
+	"github.com/hamidoujand/task-scheduler/business/database/postgres"
+	"github.com/hamidoujand/task-scheduler/business/domain/blackout"
+	blackoutPostgresRepo "github.com/hamidoujand/task-scheduler/business/domain/blackout/store/postgres"
+	"github.com/hamidoujand/task-scheduler/business/domain/preset"
+	presetPostgresRepo "github.com/hamidoujand/task-scheduler/business/domain/preset/store/postgres"
+	"github.com/hamidoujand/task-scheduler/business/domain/quota"
+	quotaPostgresRepo "github.com/hamidoujand/task-scheduler/business/domain/quota/store/postgres"
+	quotaRedisRepo "github.com/hamidoujand/task-scheduler/business/domain/quota/store/redis"
+	"github.com/hamidoujand/task-scheduler/business/domain/scheduler"
+	redisRepo "github.com/hamidoujand/task-scheduler/business/domain/scheduler/store/redis"
+	"github.com/hamidoujand/task-scheduler/business/domain/secret"
+	secretPostgresRepo "github.com/hamidoujand/task-scheduler/business/domain/secret/store/postgres"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	taskPostgresRepo "github.com/hamidoujand/task-scheduler/business/domain/task/store/postgres"
+	taskRedisRepo "github.com/hamidoujand/task-scheduler/business/domain/task/store/redis"
+	"github.com/hamidoujand/task-scheduler/business/domain/usage"
+	usagePostgresRepo "github.com/hamidoujand/task-scheduler/business/domain/usage/store/postgres"
+	"github.com/hamidoujand/task-scheduler/business/domain/worker"
+	workerRedisRepo "github.com/hamidoujand/task-scheduler/business/domain/worker/store/redis"
+	"github.com/hamidoujand/task-scheduler/business/metrics"
+	"github.com/hamidoujand/task-scheduler/foundation/breaker"
+	"github.com/hamidoujand/task-scheduler/foundation/keystore"
+	"github.com/hamidoujand/task-scheduler/foundation/logger"
+	"github.com/hamidoujand/task-scheduler/foundation/reload"
+	containerRuntime "github.com/hamidoujand/task-scheduler/foundation/runtime"
+	"github.com/hamidoujand/task-scheduler/foundation/shutdown"
+	"github.com/redis/go-redis/v9"
+)
+
+// will be changed from build tags
+var build = "0.0.1"
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "err: %s", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	//==========================================================================
+	//setup configurations
+	configs := struct {
+		Worker struct {
+			DebugHost         string        `conf:"default:0.0.0.0:4001"`
+			Environment       string        `conf:"default:development"`
+			MaxRunningTasks   int           `conf:"help:defaults to GOMAXPROCS"`
+			HeartbeatInterval time.Duration `conf:"default:5s"`
+			ShutdownTimeout   time.Duration `conf:"default:1m"`
+		}
+
+		DB struct {
+			User            string        `conf:"default:postgres"`
+			Password        string        `conf:"default:password,mask"`
+			Host            string        `conf:"default:localhost:5432"`
+			Name            string        `conf:"default:postgres"`
+			MaxOpenConns    int           `conf:"default:10"`
+			MaxIdleConnTime time.Duration `conf:"default:5m"`
+			MaxConnLifeTime time.Duration `conf:"default:10m"`
+			DisableTLS      bool          `conf:"default:true"`
+			SlowQuery       time.Duration `conf:"default:500ms,help:queries taking longer are logged and 0 disables it"`
+		}
+
+		Breaker struct {
+			Threshold int           `conf:"default:5,help:failures of postgres, redis or rabbitmq in a row that make their calls fail fast"`
+			Cooldown  time.Duration `conf:"default:10s,help:how long calls fail fast before one of them probes the dependency"`
+		}
+
+		Redis struct {
+			Host     string        `conf:"default:localhost:6379"`
+			Password string        `conf:"mask"`
+			DBIdx    int           `conf:"default:0"`
+			Timeout  time.Duration `conf:"default:5s"`
+			CacheTTL time.Duration `conf:"default:30s,help:must match the api since updates drop cached tasks and 0 disables it"`
+		}
+
+		Rabbitmq struct {
+			Host                 string        `conf:"default:localhost:5672"`
+			User                 string        `conf:"default:guest"`
+			Password             string        `conf:"default:guest,mask"`
+			VHost                string        `conf:"help:vhost of the queues and the default vhost when empty"`
+			QueuePrefix          string        `conf:"help:put in front of every queue name like staging. so environments can share a vhost"`
+			Exchange             string        `conf:"help:direct exchange messages are published through instead of the default one"`
+			MaxTimeForConnection time.Duration `conf:"default:1m"`
+		}
+
+		Log struct {
+			Level string `conf:"default:info,help:debug|info|warn|error and reloaded on SIGHUP"`
+		}
+
+		Reload struct {
+			File     string        `conf:"help:KEY=VALUE file of the settings that are reloaded when it changes or on SIGHUP"`
+			Interval time.Duration `conf:"default:5s,help:how often the reload file is checked for changes"`
+		}
+
+		Secrets struct {
+			KeysFolder string `conf:"default:zarf/keys/"`
+			KeyID      string `conf:"default:a41bace0-da3c-4119-85ad-bbd293bf31ee,help:key the task secrets are encrypted with"`
+		}
+
+		Scheduler struct {
+			MaxTimeForTaskUpdates   time.Duration  `conf:"default:1m"`
+			MaxTimeForTaskExecution time.Duration  `conf:"default:1m"`
+			MaxTimeForImagePull     time.Duration  `conf:"default:5m,help:bounds pulling the image of a task before it runs"`
+			BreakerThreshold        int            `conf:"default:5"`
+			BreakerProbeInterval    time.Duration  `conf:"default:30s"`
+			AffinityTimeout         time.Duration  `conf:"default:30s"`
+			AssignTimeout           time.Duration  `conf:"default:30s"`
+			MaxRedeliveries         int            `conf:"default:5"`
+			ImageLimits             map[string]int `conf:"help:running tasks per image across instances like postgres-backup:2;ffmpeg:1"`
+			MaxResultBytes          int            `conf:"default:16777216,help:bytes of output kept per task"`
+			NetworkDefault          string         `conf:"default:none,help:network mode of tasks that do not ask for one none|internal|egress"`
+			NetworkAllowed          []string       `conf:"default:none,help:network modes tasks may ask for like none;internal"`
+			InternalNetwork         string         `conf:"default:tasks-internal,help:docker network of the internal mode"`
+			EgressNetwork           string         `conf:"default:bridge,help:docker network of the egress mode"`
+			Runtime                 string         `conf:"default:docker,help:runtime tasks run with docker|podman|containerd|exec, exec runs them on the host"`
+			WarmPool                []string       `conf:"help:idle containers kept per image like alpine:3.20=2;python:3.12-slim=1"`
+			WarmMaxUses             int            `conf:"default:1,help:tasks a warm container runs before it is replaced"`
+			WarmMaxIdle             time.Duration  `conf:"default:10m,help:how long a warm container stays idle before it is replaced"`
+		}
+
+		Exec struct {
+			Allowed    []string `conf:"help:commands the exec runtime may run like date;curl"`
+			WorkDir    string   `conf:"help:where the working directory of every run is created, the temp dir when empty"`
+			Chroot     string   `conf:"help:root directory commands run inside of, requires root and user"`
+			User       string   `conf:"help:unprivileged uid:gid commands run as like 65534:65534, the user of the scheduler when empty"`
+			CPUSeconds int      `conf:"default:0"`
+			MemoryMB   int      `conf:"default:512"`
+			OpenFiles  int      `conf:"default:256"`
+			Processes  int      `conf:"default:0,help:processes of the user commands run as"`
+		}
+
+		Sandbox struct {
+			ReadOnly        bool   `conf:"default:false,help:mounts the root filesystem of containers as read-only"`
+			CapDropAll      bool   `conf:"default:true,help:drops every linux capability of containers"`
+			NoNewPrivileges bool   `conf:"default:true"`
+			User            string `conf:"help:uid[:gid] commands run as like 65534:65534, the user of the image when empty"`
+			Tmpfs           string `conf:"default:/tmp,help:path of the writable scratch directory and none when empty"`
+			TmpfsSizeMB     int    `conf:"default:64"`
+		}
+	}{}
+
+	prefix := "WORKER"
+	if help, err := conf.Parse(prefix, &configs); err != nil {
+		if errors.Is(err, conf.ErrHelpWanted) {
+			fmt.Println(help)
+			return nil
+		}
+		return fmt.Errorf("parsing config: %w", err)
+	}
+
+	//==========================================================================
+	//setup logger
+	isProd := configs.Worker.Environment == "production"
+
+	attrs := []slog.Attr{
+		{Key: "build", Value: slog.StringValue(build)},
+		{Key: "app", Value: slog.StringValue("task-scheduler-worker")},
+	}
+
+	//the level changes when the settings are reloaded
+	var level slog.LevelVar
+	if err := level.UnmarshalText([]byte(configs.Log.Level)); err != nil {
+		return fmt.Errorf("parse log level: %w", err)
+	}
+
+	logger := logger.NewCustomLogger(&level, isProd, attrs...)
+
+	//the worker has no retries of its own, the dispatchers retry the tasks
+	watcher := reload.New(reload.Config{
+		File:     configs.Reload.File,
+		Interval: configs.Reload.Interval,
+		Logger:   logger,
+		Settings: []reload.Setting{
+			{Key: prefix + "_LOG_LEVEL", Apply: func(value string) error {
+				return level.UnmarshalText([]byte(value))
+			}},
+		},
+	})
+	watcher.Start()
+	defer watcher.Stop()
+
+	//==========================================================================
+	//calls to postgres, redis and rabbitmq fail fast after repeated failures instead of piling up
+	newBreaker := func(name string) *breaker.Breaker {
+		return breaker.New(name, breaker.Config{
+			Threshold: configs.Breaker.Threshold,
+			Cooldown:  configs.Breaker.Cooldown,
+			OnStateChange: func(name string, from breaker.State, to breaker.State) {
+				logger.Warn("breaker", "status", to.String(), "dependency", name, "from", from.String())
+				metrics.SetDependencyBreaker(name, to)
+			},
+		})
+	}
+
+	//==========================================================================
+	//database setup, migrations are owned by the api
+	logger.Info("database setup", "status", "connecting", "host", configs.DB.Host)
+	client, err := postgres.NewClient(postgres.Config{
+		User:        configs.DB.User,
+		Password:    configs.DB.Password,
+		Host:        configs.DB.Host,
+		Name:        configs.DB.Name,
+		DisableTLS:  configs.DB.DisableTLS,
+		MaxOpenConn: configs.DB.MaxOpenConns,
+		MaxIdleTime: configs.DB.MaxIdleConnTime,
+		MaxLifeTime: configs.DB.MaxConnLifeTime,
+		SlowQuery:   configs.DB.SlowQuery,
+		Logger:      logger,
+		Breaker:     newBreaker("postgres"),
+	})
+	if err != nil {
+		return fmt.Errorf("connecting to db: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	if err := client.StatusCheck(ctx); err != nil {
+		return fmt.Errorf("status check: %w", err)
+	}
+	logger.Info("database", "status", "ready to use")
+
+	//connection pool stats are read from the debug server
+	metrics.PublishDBPool(func() any {
+		return client.Stats()
+	})
+
+	//==========================================================================
+	//redis, required: workers register themselves and keep retry counters inside of it
+	logger.Info("redis", "status", "initializing redis support")
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     configs.Redis.Host,
+		Password: configs.Redis.Password,
+		DB:       configs.Redis.DBIdx,
+	})
+	redisClient.AddHook(breaker.NewRedisHook(newBreaker("redis")))
+
+	ctx, cancel = context.WithTimeout(context.Background(), configs.Redis.Timeout)
+	defer cancel()
+
+	if err := redisClient.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("redis ping: %w", err)
+	}
+	logger.Info("redis", "status", "successfully connected")
+
+	//==========================================================================
+	// rabbitmq setup
+	logger.Info("rabbitmq", "status", "setting up the connection")
+	ctx, cancel = context.WithTimeout(context.Background(), configs.Rabbitmq.MaxTimeForConnection)
+	defer cancel()
+
+	rabbitMQC, err := rabbitmq.NewClient(ctx, rabbitmq.Configs{
+		Host:        configs.Rabbitmq.Host,
+		User:        configs.Rabbitmq.User,
+		Password:    configs.Rabbitmq.Password,
+		VHost:       configs.Rabbitmq.VHost,
+		QueuePrefix: configs.Rabbitmq.QueuePrefix,
+		Exchange:    configs.Rabbitmq.Exchange,
+		Breaker:     newBreaker("rabbitmq"),
+	})
+	if err != nil {
+		return fmt.Errorf("new rabbitmq client: %w", err)
+	}
+	logger.Info("rabbitmq", "status", "connection successfully made to the server")
+
+	//==========================================================================
+	//debug server
+	go func() {
+		logger.Info("debug", "status", "debug server started", "host", configs.Worker.DebugHost)
+		if err := http.ListenAndServe(configs.Worker.DebugHost, debug.Mux()); err != nil {
+			logger.Error("debug", "status", "debug server closed", "host", configs.Worker.DebugHost, "msg", err)
+		}
+	}()
+
+	//==========================================================================
+	//scheduler
+	taskService, err := task.NewService(taskPostgresRepo.NewRepository(client), rabbitMQC)
+	if err != nil {
+		return fmt.Errorf("new task service: %w", err)
+	}
+
+	//tasks cached by the api are dropped whenever a worker updates them
+	if configs.Redis.CacheTTL > 0 {
+		taskService.EnableCache(taskRedisRepo.NewCache(redisClient, configs.Redis.CacheTTL))
+	}
+
+	workerService := worker.NewService(workerRedisRepo.NewRepository(redisClient))
+
+	quotaService := quota.NewService(quotaRedisRepo.NewRepository(redisClient, quotaPostgresRepo.NewRepository(client)))
+
+	usageService := usage.NewService(usagePostgresRepo.NewRepository(client))
+
+	//the secrets referenced by tasks are decrypted right before they run
+	secretsKS, err := keystore.LoadFromFS(os.DirFS(configs.Secrets.KeysFolder))
+	if err != nil {
+		return fmt.Errorf("load secrets keystore: %w", err)
+	}
+
+	secretKey, err := secretsKS.EncryptionKey(configs.Secrets.KeyID)
+	if err != nil {
+		return fmt.Errorf("secrets key: %w", err)
+	}
+
+	secretService, err := secret.NewService(secretPostgresRepo.NewRepository(client), secretKey)
+	if err != nil {
+		return fmt.Errorf("new secret service: %w", err)
+	}
+
+	maxRunningTasks := configs.Worker.MaxRunningTasks
+	if maxRunningTasks <= 0 {
+		maxRunningTasks = runtime.GOMAXPROCS(0)
+	}
+
+	schedulerRedisRepo := redisRepo.NewRepository(redisClient)
+
+	networks, err := task.ParseNetworkPolicy(configs.Scheduler.NetworkDefault, configs.Scheduler.NetworkAllowed)
+	if err != nil {
+		return fmt.Errorf("parse network policy: %w", err)
+	}
+
+	var runner containerRuntime.Runner
+	switch configs.Scheduler.Runtime {
+	case "exec":
+		runner, err = containerRuntime.NewExec(containerRuntime.ExecConfig{
+			Allowed: configs.Exec.Allowed,
+			WorkDir: configs.Exec.WorkDir,
+			Chroot:  configs.Exec.Chroot,
+			User:    configs.Exec.User,
+			Limits: containerRuntime.ExecLimits{
+				CPUSeconds: configs.Exec.CPUSeconds,
+				MemoryMB:   configs.Exec.MemoryMB,
+				OpenFiles:  configs.Exec.OpenFiles,
+				Processes:  configs.Exec.Processes,
+			},
+		})
+	default:
+		runner, err = containerRuntime.New(configs.Scheduler.Runtime)
+	}
+	if err != nil {
+		return fmt.Errorf("container runtime: %w", err)
+	}
+
+	warmPool, err := scheduler.ParseWarmPool(configs.Scheduler.WarmPool)
+	if err != nil {
+		return fmt.Errorf("parse warm pool: %w", err)
+	}
+
+	security := task.SecurityProfile{
+		ReadOnly:        configs.Sandbox.ReadOnly,
+		CapDropAll:      configs.Sandbox.CapDropAll,
+		NoNewPrivileges: configs.Sandbox.NoNewPrivileges,
+		User:            configs.Sandbox.User,
+		Tmpfs:           configs.Sandbox.Tmpfs,
+		TmpfsSizeMB:     configs.Sandbox.TmpfsSizeMB,
+	}
+
+	sch, err := scheduler.New(scheduler.Config{
+		Build:                   build,
+		Broker:                  rabbitMQC,
+		Logger:                  logger,
+		TaskService:             taskService,
+		RetryStore:              schedulerRedisRepo,
+		MaxRunningTask:          maxRunningTasks,
+		MaxTimeForUpdateOps:     configs.Scheduler.MaxTimeForTaskUpdates,
+		MaxTimeForTaskExecution: configs.Scheduler.MaxTimeForTaskExecution,
+		MaxTimeForImagePull:     configs.Scheduler.MaxTimeForImagePull,
+		BreakerThreshold:        configs.Scheduler.BreakerThreshold,
+		BreakerProbeInterval:    configs.Scheduler.BreakerProbeInterval,
+		AffinityTimeout:         configs.Scheduler.AffinityTimeout,
+		Quotas:                  quotaService,
+		Usage:                   usageService,
+		Mode:                    scheduler.ModeExecute,
+		Workers:                 workerService,
+		AssignTimeout:           configs.Scheduler.AssignTimeout,
+		MaxRedeliveries:         configs.Scheduler.MaxRedeliveries,
+		ImageLimits:             configs.Scheduler.ImageLimits,
+		SlotStore:               schedulerRedisRepo,
+		DelayStore:              schedulerRedisRepo,
+		ClaimStore:              schedulerRedisRepo,
+		Secrets:                 secretService,
+		MaxResultBytes:          configs.Scheduler.MaxResultBytes,
+		Blackouts:               blackout.NewService(blackoutPostgresRepo.NewRepository(client)),
+		Presets:                 preset.NewService(presetPostgresRepo.NewRepository(client)),
+		Networks:                networks,
+		InternalNetwork:         configs.Scheduler.InternalNetwork,
+		EgressNetwork:           configs.Scheduler.EgressNetwork,
+		Security:                security,
+		Runner:                  runner,
+		WarmPool:                warmPool,
+		WarmMaxUses:             configs.Scheduler.WarmMaxUses,
+		WarmMaxIdle:             configs.Scheduler.WarmMaxIdle,
+	})
+	if err != nil {
+		return fmt.Errorf("creating scheduler: %w", err)
+	}
+
+	if err := sch.Activate(); err != nil {
+		return fmt.Errorf("activate: %w", err)
+	}
+	logger.Info("scheduler", "status", "consuming tasks", "maxRunningTasks", maxRunningTasks)
+
+	//==========================================================================
+	//registry
+	hostname, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("hostname: %w", err)
+	}
+
+	wrk := worker.Worker{
+		Id:         sch.Id(),
+		Hostname:   hostname,
+		Build:      build,
+		MaxRunning: maxRunningTasks,
+	}
+
+	//dispatchers skip the worker while its executor is down
+	report := func() worker.Report {
+		status := worker.StatusActive
+		if sch.IsPaused() {
+			status = worker.StatusPaused
+		}
+		return worker.Report{Running: sch.Running(), Status: status}
+	}
+
+	keepAliveCtx, stopKeepAlive := context.WithCancel(context.Background())
+	keepAliveErrors := make(chan error, 1)
+	go func() {
+		logger.Info("worker", "status", "registered", "id", wrk.Id)
+		keepAliveErrors <- workerService.KeepAlive(keepAliveCtx, logger, wrk, configs.Worker.HeartbeatInterval, report)
+	}()
+
+	//==========================================================================
+	//block
+	shutdownCh := make(chan os.Signal, 1)
+	signal.Notify(shutdownCh, syscall.SIGTERM, syscall.SIGINT)
+
+	select {
+	case err := <-keepAliveErrors:
+		stopKeepAlive()
+		return fmt.Errorf("keep alive: %w", err)
+
+	case sig := <-shutdownCh:
+		//the worker stops taking tasks and leaves the registry before the running ones are drained, the connections
+		//are closed last since the scheduler uses them.
+		logger.Info("shutdown", "status", "started", "signal", sig)
+
+		ctx, cancel := context.WithTimeout(context.Background(), configs.Worker.ShutdownTimeout)
+		defer cancel()
+
+		err := shutdown.Run(ctx, logger,
+			shutdown.Phase{Name: "consumers", Stop: func(ctx context.Context) error {
+				return sch.Deactivate()
+			}},
+			shutdown.Phase{Name: "registry", Stop: func(ctx context.Context) error {
+				stopKeepAlive()
+				return <-keepAliveErrors
+			}},
+			shutdown.Phase{Name: "scheduler", Stop: sch.Shutdown},
+			shutdown.Phase{Name: "broker", Stop: func(ctx context.Context) error {
+				return rabbitMQC.Close()
+			}},
+			shutdown.Phase{Name: "redis", Stop: func(ctx context.Context) error {
+				return redisClient.Close()
+			}},
+			shutdown.Phase{Name: "database", Stop: func(ctx context.Context) error {
+				client.Close()
+				return nil
+			}},
+		)
+		if err != nil {
+			return fmt.Errorf("graceful shutdown failed: %w", err)
+		}
+		logger.Info("shutdown", "status", "completed")
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package commands_test
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/app/tooling/admin/commands"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	"github.com/hamidoujand/task-scheduler/business/domain/user"
+	"github.com/hamidoujand/task-scheduler/business/domain/user/store/memory"
+	"github.com/hamidoujand/task-scheduler/foundation/keystore"
+)
+
+func TestGenKey(t *testing.T) {
+	t.Parallel()
+
+	folder := filepath.Join(t.TempDir(), "keys")
+	kid := uuid.NewString()
+
+	created, err := commands.GenKey(folder, kid)
+	if err != nil {
+		t.Fatalf("expected to generate a key: %s", err)
+	}
+
+	if !created {
+		t.Fatal("expected the key to be created on first run")
+	}
+
+	before, err := os.ReadFile(filepath.Join(folder, kid+".pem"))
+	if err != nil {
+		t.Fatalf("expected to read the generated key: %s", err)
+	}
+
+	//second run must not touch the key
+	created, err = commands.GenKey(folder, kid)
+	if err != nil {
+		t.Fatalf("expected second run to succeed: %s", err)
+	}
+
+	if created {
+		t.Error("expected the key to not be created on second run")
+	}
+
+	after, err := os.ReadFile(filepath.Join(folder, kid+".pem"))
+	if err != nil {
+		t.Fatalf("expected to read the generated key: %s", err)
+	}
+
+	if string(before) != string(after) {
+		t.Error("expected the key to be left untouched")
+	}
+
+	//keystore must be able to load it
+	ks, err := keystore.LoadFromFS(os.DirFS(folder))
+	if err != nil {
+		t.Fatalf("expected keystore to load generated key: %s", err)
+	}
+
+	if _, err := ks.PublicKey(kid); err != nil {
+		t.Errorf("expected to find public key for %s: %s", kid, err)
+	}
+}
+
+func TestSeedAdmin(t *testing.T) {
+	t.Parallel()
+
+	repo := memory.Repository{
+		Users: make(map[uuid.UUID]user.User),
+	}
+	service := user.NewService(&repo, &repo)
+
+	usr, result, err := commands.SeedAdmin(context.Background(), service, "admin", "admin@gmail.com", "test1234")
+	if err != nil {
+		t.Fatalf("expected to seed the admin: %s", err)
+	}
+
+	if result != commands.SeedCreated {
+		t.Errorf("result= %s, got %s", commands.SeedCreated, result)
+	}
+
+	if !slices.Contains(usr.Roles, user.RoleAdmin) {
+		t.Errorf("expected the seeded user to be admin, got %v", usr.Roles)
+	}
+
+	//idempotent
+	again, result, err := commands.SeedAdmin(context.Background(), service, "admin", "admin@gmail.com", "test1234")
+	if err != nil {
+		t.Fatalf("expected second seed to succeed: %s", err)
+	}
+
+	if result != commands.SeedUnchanged {
+		t.Errorf("result= %s, got %s", commands.SeedUnchanged, result)
+	}
+
+	if again.Id != usr.Id {
+		t.Errorf("id= %s, got %s", usr.Id, again.Id)
+	}
+
+	//existing non-admin user gets promoted
+	existing, err := service.CreateUser(context.Background(), user.NewUser{
+		Name:     "john",
+		Email:    mail.Address{Name: "john", Address: "john@gmail.com"},
+		Roles:    []user.Role{user.RoleUser},
+		Password: "test1234",
+	})
+	if err != nil {
+		t.Fatalf("expected to create user: %s", err)
+	}
+
+	promoted, result, err := commands.SeedAdmin(context.Background(), service, "john", "john@gmail.com", "other1234")
+	if err != nil {
+		t.Fatalf("expected to promote existing user: %s", err)
+	}
+
+	if result != commands.SeedPromoted {
+		t.Errorf("result= %s, got %s", commands.SeedPromoted, result)
+	}
+
+	if promoted.Id != existing.Id {
+		t.Errorf("id= %s, got %s", existing.Id, promoted.Id)
+	}
+
+	if !slices.Contains(promoted.Roles, user.RoleAdmin) || !slices.Contains(promoted.Roles, user.RoleUser) {
+		t.Errorf("expected both roles to be present, got %v", promoted.Roles)
+	}
+
+	//invalid input
+	if _, _, err := commands.SeedAdmin(context.Background(), service, "admin", "", "test1234"); err == nil {
+		t.Error("expected an error when email is missing")
+	}
+}
+
+type fakeRuns map[uuid.UUID]int
+
+func (f fakeRuns) CountFailedRuns(ctx context.Context, status task.Status) (map[uuid.UUID]int, error) {
+	return f, nil
+}
+
+type fakeRetries map[string]int
+
+func (f fakeRetries) Get(ctx context.Context, taskId string) (int, error) {
+	retries, ok := f[taskId]
+	if !ok {
+		return 0, errors.New("not found")
+	}
+	return retries, nil
+}
+
+func (f fakeRetries) Update(ctx context.Context, taskId string, retries int) error {
+	f[taskId] = retries
+	return nil
+}
+
+type fakeCache int
+
+func (f fakeCache) Purge(ctx context.Context) (int, error) {
+	return int(f), nil
+}
+
+func TestRebuildRedis(t *testing.T) {
+	t.Parallel()
+
+	lost := uuid.New()
+	behind := uuid.New()
+	ahead := uuid.New()
+
+	runs := fakeRuns{lost: 2, behind: 3, ahead: 1}
+	retries := fakeRetries{behind.String(): 1, ahead.String(): 2}
+
+	report, err := commands.RebuildRedis(context.Background(), runs, retries, fakeCache(4))
+	if err != nil {
+		t.Fatalf("expected to rebuild redis: %s", err)
+	}
+
+	want := commands.RebuildReport{RetryCounters: 2, RetryCountersKept: 1, CachesPurged: 4}
+	if report != want {
+		t.Errorf("report= %+v, got %+v", want, report)
+	}
+
+	tests := map[string]struct {
+		taskId  uuid.UUID
+		retries int
+	}{
+		"lost counter is restored":    {taskId: lost, retries: 2},
+		"counter behind is raised":    {taskId: behind, retries: 3},
+		"counter ahead is left as is": {taskId: ahead, retries: 2},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := retries[test.taskId.String()]; got != test.retries {
+				t.Errorf("retries= %d, got %d", test.retries, got)
+			}
+		})
+	}
+}
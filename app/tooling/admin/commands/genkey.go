@@ -0,0 +1,70 @@
+// Package commands contains the functionality for the set of admin commands.
+package commands
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// GenKey creates a private key in PKCS8 format for the given key id inside of the folder, if there is already
+// a key with that id it is left untouched so the command can run on every deploy.
+func GenKey(folder string, kid string) (bool, error) {
+	if kid == "" {
+		return false, errors.New("key id is required")
+	}
+
+	path := filepath.Join(folder, kid+".pem")
+
+	_, err := os.Stat(path)
+	if err == nil {
+		//already there
+		return false, nil
+	}
+
+	if !errors.Is(err, fs.ErrNotExist) {
+		return false, fmt.Errorf("stat: %s: %w", path, err)
+	}
+
+	if err := os.MkdirAll(folder, 0700); err != nil {
+		return false, fmt.Errorf("mkdir: %s: %w", folder, err)
+	}
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return false, fmt.Errorf("generating key: %w", err)
+	}
+
+	//auth package only accepts "PKCS8"
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return false, fmt.Errorf("marshal pkcs8: %w", err)
+	}
+
+	//O_EXCL so two deploys racing each other never overwrite a key that is already used for signing
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("create: %s: %w", path, err)
+	}
+	defer file.Close()
+
+	block := pem.Block{
+		Type:  "PRIVATE KEY",
+		Bytes: pkcs8,
+	}
+
+	if err := pem.Encode(file, &block); err != nil {
+		return false, fmt.Errorf("encoding private key: %w", err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+// runCounter represents the storage of task runs, the failed runs of a pending task are the retries it used.
+type runCounter interface {
+	CountFailedRuns(ctx context.Context, status task.Status) (map[uuid.UUID]int, error)
+}
+
+// retryStore represents the redis storage of retry counters.
+type retryStore interface {
+	Get(ctx context.Context, taskId string) (int, error)
+	Update(ctx context.Context, taskId string, retries int) error
+}
+
+// cache represents a redis cache that is filled again from postgres on demand.
+type cache interface {
+	Purge(ctx context.Context) (int, error)
+}
+
+// RebuildReport represents what rebuilding the redis state did.
+type RebuildReport struct {
+	//RetryCounters is the number of retry counters restored from the failed runs of pending tasks.
+	RetryCounters int
+	//RetryCountersKept is the number of retry counters that were already at least as high as postgres says.
+	RetryCountersKept int
+	//CachesPurged is the number of cached entries dropped so they are loaded again from postgres.
+	CachesPurged int
+}
+
+// RebuildRedis reconstructs the redis state that is derived from postgres after redis lost its data. The retry
+// counter of every queued task is restored from its failed runs, counters that are already higher are kept so
+// running it against a healthy redis never gives a task extra retries. Caches are purged and fill up again from
+// postgres. Worker records and the leader lease are not rebuilt, they are written again by the next heartbeat
+// and lease renewal.
+func RebuildRedis(ctx context.Context, runs runCounter, retries retryStore, caches ...cache) (RebuildReport, error) {
+	var report RebuildReport
+
+	//tasks stay queued until their last attempt finished
+	counts, err := runs.CountFailedRuns(ctx, task.StatusQueued)
+	if err != nil {
+		return report, fmt.Errorf("count failed runs: %w", err)
+	}
+
+	for taskId, failed := range counts {
+		//a missing counter is what we are here to fix
+		current, err := retries.Get(ctx, taskId.String())
+		if err == nil && current >= failed {
+			report.RetryCountersKept++
+			continue
+		}
+
+		if err := retries.Update(ctx, taskId.String(), failed); err != nil {
+			return report, fmt.Errorf("update retries of task %s: %w", taskId, err)
+		}
+		report.RetryCounters++
+	}
+
+	for _, c := range caches {
+		purged, err := c.Purge(ctx)
+		report.CachesPurged += purged
+		if err != nil {
+			return report, fmt.Errorf("purge cache: %w", err)
+		}
+	}
+
+	return report, nil
+}
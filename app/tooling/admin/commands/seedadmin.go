@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"slices"
+
+	"github.com/hamidoujand/task-scheduler/business/domain/user"
+)
+
+// SeedResult represents what seeding did to the admin user.
+type SeedResult int
+
+const (
+	SeedCreated SeedResult = iota
+	SeedPromoted
+	SeedUnchanged
+)
+
+var seedResultNames = [...]string{"created", "promoted", "unchanged"}
+
+// String implements the stringer interface.
+func (sr SeedResult) String() string {
+	if sr < SeedCreated || sr > SeedUnchanged {
+		return "UNKNOWN"
+	}
+	return seedResultNames[sr]
+}
+
+// SeedAdmin makes sure there is an enabled admin with the given email, it creates the user when missing and
+// promotes an existing user to admin otherwise, so it is safe to run multiple times.
+func SeedAdmin(ctx context.Context, userService *user.Service, name string, email string, password string) (user.User, SeedResult, error) {
+	if name == "" || email == "" || password == "" {
+		return user.User{}, SeedUnchanged, errors.New("name, email and password are required")
+	}
+
+	if len(password) < 8 {
+		return user.User{}, SeedUnchanged, errors.New("password must be at least 8 characters")
+	}
+
+	parsed, err := mail.ParseAddress(email)
+	if err != nil {
+		return user.User{}, SeedUnchanged, fmt.Errorf("parse email: %w", err)
+	}
+	parsed.Name = name
+
+	usr, err := userService.GetByEmail(ctx, *parsed)
+	if err == nil {
+		return promote(ctx, userService, usr)
+	}
+
+	if !errors.Is(err, user.ErrUserNotFound) {
+		return user.User{}, SeedUnchanged, fmt.Errorf("get by email: %w", err)
+	}
+
+	nu := user.NewUser{
+		Name:     name,
+		Email:    *parsed,
+		Roles:    []user.Role{user.RoleAdmin},
+		Password: password,
+		Verified: true,
+	}
+
+	usr, err = userService.CreateUser(ctx, nu)
+	if err != nil {
+		//another instance won the race, treat it as an existing user
+		if errors.Is(err, user.ErrUniqueEmail) {
+			usr, err := userService.GetByEmail(ctx, *parsed)
+			if err != nil {
+				return user.User{}, SeedUnchanged, fmt.Errorf("get by email: %w", err)
+			}
+			return promote(ctx, userService, usr)
+		}
+		return user.User{}, SeedUnchanged, fmt.Errorf("create user: %w", err)
+	}
+
+	return usr, SeedCreated, nil
+}
+
+func promote(ctx context.Context, userService *user.Service, usr user.User) (user.User, SeedResult, error) {
+	if slices.Contains(usr.Roles, user.RoleAdmin) && usr.Enabled && usr.Verified {
+		return usr, SeedUnchanged, nil
+	}
+
+	//we never touch the password of an existing user
+	enabled := true
+	uu := user.UpdateUser{
+		Enabled:  &enabled,
+		Verified: &enabled,
+	}
+
+	if !slices.Contains(usr.Roles, user.RoleAdmin) {
+		uu.Roles = append(slices.Clone(usr.Roles), user.RoleAdmin)
+	}
+
+	updated, err := userService.UpdateUser(ctx, uu, usr)
+	if err != nil {
+		return user.User{}, SeedUnchanged, fmt.Errorf("update user: %w", err)
+	}
+
+	return updated, SeedPromoted, nil
+}
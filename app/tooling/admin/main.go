@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/ardanlabs/conf/v3"
+	"github.com/hamidoujand/task-scheduler/app/tooling/admin/commands"
+	"github.com/hamidoujand/task-scheduler/business/database/postgres"
+	quotaPostgresRepo "github.com/hamidoujand/task-scheduler/business/domain/quota/store/postgres"
+	quotaRedisRepo "github.com/hamidoujand/task-scheduler/business/domain/quota/store/redis"
+	schedulerRedisRepo "github.com/hamidoujand/task-scheduler/business/domain/scheduler/store/redis"
+	taskPostgresRepo "github.com/hamidoujand/task-scheduler/business/domain/task/store/postgres"
+	"github.com/hamidoujand/task-scheduler/business/domain/user"
+	userPostgresRepo "github.com/hamidoujand/task-scheduler/business/domain/user/store/postgres"
+	"github.com/hamidoujand/task-scheduler/foundation/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+// will be changed from build tags
+var build = "0.0.1"
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "err: %s", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	//==========================================================================
+	//setup configurations, uses the same prefix as the api so deployments can share env vars.
+	configs := struct {
+		conf.Version
+		Args conf.Args
+
+		DB struct {
+			User       string        `conf:"default:postgres"`
+			Password   string        `conf:"default:password,mask"`
+			Host       string        `conf:"default:localhost:5432"`
+			Name       string        `conf:"default:postgres"`
+			DisableTLS bool          `conf:"default:true"`
+			Timeout    time.Duration `conf:"default:1m"`
+		}
+
+		Auth struct {
+			KeysFolder string `conf:"default:zarf/keys/"`
+			ActiveKid  string `conf:"default:a41bace0-da3c-4119-85ad-bbd293bf31ee"`
+		}
+
+		Redis struct {
+			Host     string `conf:"default:localhost:6379"`
+			Password string `conf:"default:'',"`
+			DBIdx    int    `conf:"default:0"`
+		}
+
+		Admin struct {
+			Name     string `conf:"default:admin"`
+			Email    string
+			Password string `conf:"mask"`
+		}
+	}{
+		Version: conf.Version{
+			Build: build,
+			Desc:  "admin tooling for task scheduler, commands: genkey, seed-admin, rebuild-redis",
+		},
+	}
+
+	prefix := "TASKS"
+	if help, err := conf.Parse(prefix, &configs); err != nil {
+		if errors.Is(err, conf.ErrHelpWanted) {
+			fmt.Println(help)
+			return nil
+		}
+		return fmt.Errorf("parsing config: %w", err)
+	}
+
+	attrs := []slog.Attr{
+		{Key: "build", Value: slog.StringValue(build)},
+		{Key: "app", Value: slog.StringValue("task-scheduler-admin")},
+	}
+	logger := logger.NewCustomLogger(slog.LevelInfo, false, attrs...)
+
+	switch cmd := configs.Args.Num(0); cmd {
+	case "genkey":
+		created, err := commands.GenKey(configs.Auth.KeysFolder, configs.Auth.ActiveKid)
+		if err != nil {
+			return fmt.Errorf("genkey: %w", err)
+		}
+		logger.Info("genkey", "status", "completed", "kid", configs.Auth.ActiveKid, "created", created)
+		return nil
+
+	case "seed-admin":
+		created, err := commands.GenKey(configs.Auth.KeysFolder, configs.Auth.ActiveKid)
+		if err != nil {
+			return fmt.Errorf("genkey: %w", err)
+		}
+		logger.Info("genkey", "status", "completed", "kid", configs.Auth.ActiveKid, "created", created)
+
+		client, err := postgres.NewClient(postgres.Config{
+			User:       configs.DB.User,
+			Password:   configs.DB.Password,
+			Host:       configs.DB.Host,
+			Name:       configs.DB.Name,
+			DisableTLS: configs.DB.DisableTLS,
+		})
+		if err != nil {
+			return fmt.Errorf("connecting to db: %w", err)
+		}
+		defer client.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), configs.DB.Timeout)
+		defer cancel()
+
+		if err := client.StatusCheck(ctx); err != nil {
+			return fmt.Errorf("status check: %w", err)
+		}
+
+		//deploys may run this before the api ever started
+		if err := client.Migrate(); err != nil {
+			return fmt.Errorf("running migrations: %w", err)
+		}
+
+		userService := user.NewService(userPostgresRepo.NewRepository(client), nil)
+
+		usr, result, err := commands.SeedAdmin(ctx, userService, configs.Admin.Name, configs.Admin.Email, configs.Admin.Password)
+		if err != nil {
+			return fmt.Errorf("seed admin: %w", err)
+		}
+		logger.Info("seed-admin", "status", "completed", "id", usr.Id, "email", usr.Email.Address, "result", result)
+		return nil
+
+	case "rebuild-redis":
+		client, err := postgres.NewClient(postgres.Config{
+			User:       configs.DB.User,
+			Password:   configs.DB.Password,
+			Host:       configs.DB.Host,
+			Name:       configs.DB.Name,
+			DisableTLS: configs.DB.DisableTLS,
+		})
+		if err != nil {
+			return fmt.Errorf("connecting to db: %w", err)
+		}
+		defer client.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), configs.DB.Timeout)
+		defer cancel()
+
+		if err := client.StatusCheck(ctx); err != nil {
+			return fmt.Errorf("status check: %w", err)
+		}
+
+		redisClient := redis.NewClient(&redis.Options{
+			Addr:     configs.Redis.Host,
+			Password: configs.Redis.Password,
+			DB:       configs.Redis.DBIdx,
+		})
+		defer redisClient.Close()
+
+		if err := redisClient.Ping(ctx).Err(); err != nil {
+			return fmt.Errorf("redis ping: %w", err)
+		}
+
+		quotaCache := quotaRedisRepo.NewRepository(redisClient, quotaPostgresRepo.NewRepository(client))
+
+		report, err := commands.RebuildRedis(ctx,
+			taskPostgresRepo.NewRepository(client),
+			schedulerRedisRepo.NewRepository(redisClient),
+			quotaCache,
+		)
+		if err != nil {
+			return fmt.Errorf("rebuild redis: %w", err)
+		}
+		logger.Info("rebuild-redis", "status", "completed",
+			"retryCounters", report.RetryCounters,
+			"retryCountersKept", report.RetryCountersKept,
+			"cachesPurged", report.CachesPurged,
+			"msg", "workers and the leader lease are restored by their next heartbeat and renewal",
+		)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown command %q, available commands: genkey, seed-admin, rebuild-redis", cmd)
+	}
+}
@@ -0,0 +1,134 @@
+// Package load creates tasks against a running instance of the api and measures how long they take to be created
+// and to finish, the reports of runs against instances with different MaxRunningTasks are compared for capacity
+// planning.
+package load
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client represents the calls of the api the load generator makes.
+type Client struct {
+	host  string
+	token string
+	http  *http.Client
+}
+
+// NewClient creates a client for the api at host, like "http://localhost:8000".
+func NewClient(host string, token string) *Client {
+	return &Client{
+		host:  strings.TrimSuffix(host, "/"),
+		token: token,
+		http:  &http.Client{Timeout: time.Minute},
+	}
+}
+
+// NewTask represents the task the load generator creates.
+type NewTask struct {
+	Command     string    `json:"command"`
+	Args        []string  `json:"args,omitempty"`
+	Image       string    `json:"image"`
+	ScheduledAt time.Time `json:"scheduledAt"`
+	MaxRetries  *int      `json:"maxRetries,omitempty"`
+}
+
+// Task represents the fields of a created task the load generator reads.
+type Task struct {
+	Id        string    `json:"id"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// TaskStatus represents the status of a task returned by the bulk status endpoint.
+type TaskStatus struct {
+	Id        string    `json:"id"`
+	Status    string    `json:"status"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Login exchanges the credentials of a user for a token the next calls are made with.
+func (c *Client) Login(ctx context.Context, email string, password string) error {
+	login := struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}{
+		Email:    email,
+		Password: password,
+	}
+
+	var usr struct {
+		Token string `json:"token"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/users/login", login, &usr); err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+
+	c.token = usr.Token
+	return nil
+}
+
+// CreateTask creates the task.
+func (c *Client) CreateTask(ctx context.Context, nt NewTask) (Task, error) {
+	var tsk Task
+	if err := c.do(ctx, http.MethodPost, "/api/tasks/", nt, &tsk); err != nil {
+		return Task{}, fmt.Errorf("create task: %w", err)
+	}
+	return tsk, nil
+}
+
+// Statuses returns the statuses of up to maxStatusIds tasks.
+func (c *Client) Statuses(ctx context.Context, ids []string) ([]TaskStatus, error) {
+	req := struct {
+		Ids []string `json:"ids"`
+	}{
+		Ids: ids,
+	}
+
+	var statuses struct {
+		Tasks []TaskStatus `json:"tasks"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/tasks/status", req, &statuses); err != nil {
+		return nil, fmt.Errorf("task statuses: %w", err)
+	}
+	return statuses.Tasks, nil
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, body any, dest any) error {
+	bs, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.host+path, bytes.NewReader(bs))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s: status %d: %s", method, path, resp.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(dest); err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package load
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxStatusIds is how many tasks the bulk status endpoint returns at once.
+const maxStatusIds = 500
+
+// Config represents the configuration of a load run.
+type Config struct {
+	//Tasks is how many tasks are created.
+	Tasks int
+	//Concurrency is how many create requests are in flight at the same time, defaults to 10.
+	Concurrency int
+	//Task is the task that is created Tasks times, its ScheduledAt is set by the run.
+	Task NewTask
+	//Delay is how far in the future the tasks are scheduled, the api rejects tasks scheduled in the past so it
+	//covers the skew between the clocks of the load generator and the api. Defaults to 1s.
+	Delay time.Duration
+	//PollInterval is how often the statuses of the unfinished tasks are read, defaults to 1s.
+	PollInterval time.Duration
+	//Timeout is how long the run waits for the created tasks to finish, the ones that did not are reported as
+	//unfinished. Defaults to 10m.
+	Timeout time.Duration
+	//MaxRunningTasks is the MaxRunningTasks the instance runs with, it only labels the report.
+	MaxRunningTasks int
+}
+
+// Run creates the tasks and waits for them to finish.
+func Run(ctx context.Context, client *Client, conf Config) (Report, error) {
+	if conf.Tasks <= 0 {
+		return Report{}, errors.New("tasks must be greater than 0")
+	}
+
+	if conf.Concurrency <= 0 {
+		conf.Concurrency = 10
+	}
+
+	if conf.Delay <= 0 {
+		conf.Delay = time.Second
+	}
+
+	if conf.PollInterval <= 0 {
+		conf.PollInterval = time.Second
+	}
+
+	if conf.Timeout <= 0 {
+		conf.Timeout = time.Minute * 10
+	}
+
+	report := Report{
+		MaxRunningTasks: conf.MaxRunningTasks,
+		StartedAt:       time.Now(),
+		Tasks:           conf.Tasks,
+	}
+
+	created, latencies, err := create(ctx, client, conf, &report)
+	if err != nil {
+		return Report{}, err
+	}
+	report.Created = len(created)
+	report.CreateLatency = latencyOf(latencies)
+	report.CreateRate = rate(len(created), time.Since(report.StartedAt))
+
+	waitCtx, cancel := context.WithTimeout(ctx, conf.Timeout)
+	defer cancel()
+
+	finished, err := wait(waitCtx, client, conf.PollInterval, created)
+	if err != nil {
+		return Report{}, err
+	}
+
+	//the server side times are used so the poll interval does not add up to the measured times
+	var (
+		first    time.Time
+		last     time.Time
+		endToEnd []time.Duration
+	)
+	for id, createdAt := range created {
+		if first.IsZero() || createdAt.Before(first) {
+			first = createdAt
+		}
+
+		st, ok := finished[id]
+		if !ok {
+			report.Unfinished++
+			continue
+		}
+
+		switch st.Status {
+		case "completed":
+			report.Completed++
+		case "failed":
+			report.Failed++
+		default:
+			report.Canceled++
+		}
+
+		endToEnd = append(endToEnd, st.UpdatedAt.Sub(createdAt))
+		if st.UpdatedAt.After(last) {
+			last = st.UpdatedAt
+		}
+	}
+
+	report.EndToEnd = latencyOf(endToEnd)
+	if len(endToEnd) > 0 {
+		report.Duration = last.Sub(first)
+		report.Throughput = rate(len(endToEnd), report.Duration)
+	}
+
+	return report, nil
+}
+
+// create creates the tasks and returns when each of them was created by the api along with the latency of the
+// requests that succeeded.
+func create(ctx context.Context, client *Client, conf Config, report *Report) (map[string]time.Time, []time.Duration, error) {
+	jobs := make(chan struct{})
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		created   = make(map[string]time.Time, conf.Tasks)
+		latencies = make([]time.Duration, 0, conf.Tasks)
+	)
+
+	for range min(conf.Concurrency, conf.Tasks) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range jobs {
+				nt := conf.Task
+				nt.ScheduledAt = time.Now().Add(conf.Delay)
+
+				start := time.Now()
+				tsk, err := client.CreateTask(ctx, nt)
+				latency := time.Since(start)
+
+				mu.Lock()
+				if err != nil {
+					report.CreateErrors++
+					if report.FirstError == "" {
+						report.FirstError = err.Error()
+					}
+				} else {
+					created[tsk.Id] = tsk.CreatedAt
+					latencies = append(latencies, latency)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for range conf.Tasks {
+		select {
+		case jobs <- struct{}{}:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if len(created) == 0 {
+		return nil, nil, fmt.Errorf("none of the %d tasks was created: %s", conf.Tasks, report.FirstError)
+	}
+	return created, latencies, nil
+}
+
+// wait polls the statuses of the created tasks until all of them are finished or the context is done, it returns the
+// final status of the finished ones.
+func wait(ctx context.Context, client *Client, interval time.Duration, created map[string]time.Time) (map[string]TaskStatus, error) {
+	pending := make([]string, 0, len(created))
+	for id := range created {
+		pending = append(pending, id)
+	}
+
+	finished := make(map[string]TaskStatus, len(created))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for len(pending) > 0 {
+		select {
+		case <-ctx.Done():
+			return finished, nil
+		case <-ticker.C:
+		}
+
+		for chunk := range slices.Chunk(pending, maxStatusIds) {
+			statuses, err := client.Statuses(ctx, chunk)
+			if err != nil {
+				if ctx.Err() != nil {
+					return finished, nil
+				}
+				return nil, err
+			}
+
+			for _, st := range statuses {
+				switch st.Status {
+				case "completed", "failed", "canceled":
+					finished[st.Id] = st
+				}
+			}
+		}
+
+		pending = slices.DeleteFunc(pending, func(id string) bool {
+			_, ok := finished[id]
+			return ok
+		})
+	}
+
+	return finished, nil
+}
+
+func rate(n int, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(n) / d.Seconds()
+}
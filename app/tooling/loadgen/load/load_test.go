@@ -0,0 +1,287 @@
+package load_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/app/tooling/loadgen/load"
+)
+
+// fakeAPI finishes every task on the second time its status is read, the tasks with the "false" command fail.
+type fakeAPI struct {
+	mu       sync.Mutex
+	commands map[string]string
+	polls    map[string]int
+	tokens   []string
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/api/users/login":
+		json.NewEncoder(w).Encode(map[string]string{"token": "token"})
+
+	case "/api/tasks/":
+		f.tokens = append(f.tokens, r.Header.Get("Authorization"))
+
+		var nt load.NewTask
+		json.NewDecoder(r.Body).Decode(&nt)
+
+		if nt.ScheduledAt.Before(time.Now()) {
+			http.Error(w, `{"error":"scheduledAt in the past"}`, http.StatusBadRequest)
+			return
+		}
+
+		id := uuid.NewString()
+		f.commands[id] = nt.Command
+		json.NewEncoder(w).Encode(load.Task{Id: id, Status: "pending", CreatedAt: time.Now()})
+
+	case "/api/tasks/status":
+		var req struct {
+			Ids []string `json:"ids"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		statuses := make([]load.TaskStatus, 0, len(req.Ids))
+		for _, id := range req.Ids {
+			f.polls[id]++
+
+			status := "running"
+			if f.polls[id] > 1 {
+				status = "completed"
+				if f.commands[id] == "false" {
+					status = "failed"
+				}
+			}
+			statuses = append(statuses, load.TaskStatus{Id: id, Status: status, UpdatedAt: time.Now()})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"tasks": statuses})
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	api := &fakeAPI{commands: make(map[string]string), polls: make(map[string]int)}
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	client := load.NewClient(srv.URL, "")
+	if err := client.Login(context.Background(), "user@example.com", "password"); err != nil {
+		t.Fatalf("expected to login: %s", err)
+	}
+
+	tests := map[string]struct {
+		command       string
+		wantCompleted int
+		wantFailed    int
+	}{
+		"completed": {command: "date", wantCompleted: 25},
+		"failed":    {command: "false", wantFailed: 25},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			report, err := load.Run(context.Background(), client, load.Config{
+				Tasks:           25,
+				Concurrency:     4,
+				Task:            load.NewTask{Command: test.command, Image: "alpine:3.20"},
+				PollInterval:    time.Millisecond * 10,
+				MaxRunningTasks: 8,
+			})
+			if err != nil {
+				t.Fatalf("expected the run to succeed: %s", err)
+			}
+
+			if report.Created != 25 {
+				t.Errorf("created= %d, got %d", 25, report.Created)
+			}
+
+			if report.Completed != test.wantCompleted {
+				t.Errorf("completed= %d, got %d", test.wantCompleted, report.Completed)
+			}
+
+			if report.Failed != test.wantFailed {
+				t.Errorf("failed= %d, got %d", test.wantFailed, report.Failed)
+			}
+
+			if report.Unfinished != 0 {
+				t.Errorf("unfinished= %d, got %d", 0, report.Unfinished)
+			}
+
+			if report.MaxRunningTasks != 8 {
+				t.Errorf("maxRunningTasks= %d, got %d", 8, report.MaxRunningTasks)
+			}
+
+			if report.EndToEnd.P50 <= 0 || report.EndToEnd.P50 > report.EndToEnd.Max {
+				t.Errorf("expected 0 < p50 <= max, got p50 %s, max %s", report.EndToEnd.P50, report.EndToEnd.Max)
+			}
+
+			if report.Throughput <= 0 {
+				t.Errorf("expected a positive throughput, got %f", report.Throughput)
+			}
+		})
+	}
+
+	for _, token := range api.tokens {
+		if token != "Bearer token" {
+			t.Fatalf("authorization= %q, got %q", "Bearer token", token)
+		}
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	t.Parallel()
+
+	//tasks are created but never finish
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/tasks/status" {
+			json.NewEncoder(w).Encode(map[string]any{"tasks": []load.TaskStatus{}})
+			return
+		}
+		json.NewEncoder(w).Encode(load.Task{Id: uuid.NewString(), CreatedAt: time.Now()})
+	}))
+	defer srv.Close()
+
+	report, err := load.Run(context.Background(), load.NewClient(srv.URL, "token"), load.Config{
+		Tasks:        3,
+		PollInterval: time.Millisecond * 10,
+		Timeout:      time.Millisecond * 50,
+	})
+	if err != nil {
+		t.Fatalf("expected the run to succeed: %s", err)
+	}
+
+	if report.Unfinished != 3 {
+		t.Errorf("unfinished= %d, got %d", 3, report.Unfinished)
+	}
+
+	if report.Throughput != 0 {
+		t.Errorf("throughput= %f, got %f", 0.0, report.Throughput)
+	}
+}
+
+func TestRunCreateErrors(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"quota exceeded"}`, http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	_, err := load.Run(context.Background(), load.NewClient(srv.URL, "token"), load.Config{Tasks: 3})
+	if err == nil {
+		t.Fatal("expected the run to fail when no task was created")
+	}
+
+	if !strings.Contains(err.Error(), "quota exceeded") {
+		t.Errorf("expected the error of the api to be reported, got %s", err)
+	}
+}
+
+func TestReports(t *testing.T) {
+	t.Parallel()
+
+	folder := t.TempDir()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i, maxRunning := range []int{16, 4} {
+		r := load.Report{
+			MaxRunningTasks: maxRunning,
+			StartedAt:       start.Add(time.Duration(i) * time.Minute),
+			Tasks:           100,
+			Completed:       100,
+			EndToEnd:        load.Latency{P50: time.Second, P90: time.Second * 2, P99: time.Second * 3},
+			Throughput:      float64(maxRunning),
+		}
+		if _, err := load.SaveReport(folder, r); err != nil {
+			t.Fatalf("expected to save the report: %s", err)
+		}
+	}
+
+	reports, err := load.LoadReports(folder)
+	if err != nil {
+		t.Fatalf("expected to load the reports: %s", err)
+	}
+
+	if len(reports) != 2 {
+		t.Fatalf("len(reports)= %d, got %d", 2, len(reports))
+	}
+
+	var buf bytes.Buffer
+	if err := load.WriteTable(&buf, reports); err != nil {
+		t.Fatalf("expected to write the table: %s", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("len(lines)= %d, got %d:\n%s", 3, len(lines), buf.String())
+	}
+
+	//ordered by max running tasks
+	if !strings.HasPrefix(lines[1], "4 ") || !strings.HasPrefix(lines[2], "16 ") {
+		t.Errorf("expected the rows to be ordered by max running tasks:\n%s", buf.String())
+	}
+}
+
+// The benchmarks run against the instance at LOADGEN_API_HOST with the token of LOADGEN_API_TOKEN, for example:
+//
+//	LOADGEN_API_HOST=http://localhost:8000 LOADGEN_API_TOKEN=... go test -run=^$ -bench=. ./app/tooling/loadgen/load
+func benchClient(b *testing.B) *load.Client {
+	host := os.Getenv("LOADGEN_API_HOST")
+	if host == "" {
+		b.Skip("LOADGEN_API_HOST is not set")
+	}
+	return load.NewClient(host, os.Getenv("LOADGEN_API_TOKEN"))
+}
+
+// BenchmarkCreateTask measures how long creating a single task takes.
+func BenchmarkCreateTask(b *testing.B) {
+	client := benchClient(b)
+
+	b.ResetTimer()
+	for range b.N {
+		nt := load.NewTask{Command: "date", Image: "alpine:3.20", ScheduledAt: time.Now().Add(time.Second)}
+		if _, err := client.CreateTask(context.Background(), nt); err != nil {
+			b.Fatalf("expected to create the task: %s", err)
+		}
+	}
+}
+
+// BenchmarkRun creates b.N tasks at once and reports how long they took to finish.
+func BenchmarkRun(b *testing.B) {
+	client := benchClient(b)
+
+	for _, concurrency := range []int{1, 10, 50} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			report, err := load.Run(context.Background(), client, load.Config{
+				Tasks:       b.N,
+				Concurrency: concurrency,
+				Task:        load.NewTask{Command: "date", Image: "alpine:3.20"},
+			})
+			if err != nil {
+				b.Fatalf("expected the run to succeed: %s", err)
+			}
+
+			b.ReportMetric(float64(report.CreateLatency.P50.Microseconds()), "create-p50-µs")
+			b.ReportMetric(float64(report.EndToEnd.P50.Milliseconds()), "e2e-p50-ms")
+			b.ReportMetric(float64(report.EndToEnd.P99.Milliseconds()), "e2e-p99-ms")
+			b.ReportMetric(report.Throughput, "tasks/s")
+		})
+	}
+}
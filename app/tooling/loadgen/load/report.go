@@ -0,0 +1,143 @@
+package load
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Report represents the result of a load run.
+type Report struct {
+	MaxRunningTasks int       `json:"maxRunningTasks"`
+	StartedAt       time.Time `json:"startedAt"`
+	Tasks           int       `json:"tasks"`
+	Created         int       `json:"created"`
+	CreateErrors    int       `json:"createErrors"`
+	FirstError      string    `json:"firstError,omitempty"`
+	Completed       int       `json:"completed"`
+	Failed          int       `json:"failed"`
+	Canceled        int       `json:"canceled"`
+	//Unfinished are the created tasks that did not finish before the run timed out.
+	Unfinished int `json:"unfinished"`
+	//CreateLatency is how long the create requests took.
+	CreateLatency Latency `json:"createLatency"`
+	//CreateRate is how many tasks were created per second.
+	CreateRate float64 `json:"createRate"`
+	//EndToEnd is the time between a task was created and it finished, as reported by the api.
+	EndToEnd Latency `json:"endToEnd"`
+	//Duration is the time between the first task was created and the last one finished.
+	Duration time.Duration `json:"duration"`
+	//Throughput is how many tasks finished per second over Duration.
+	Throughput float64 `json:"throughput"`
+}
+
+// Latency represents the distribution of a set of durations.
+type Latency struct {
+	P50 time.Duration `json:"p50"`
+	P90 time.Duration `json:"p90"`
+	P99 time.Duration `json:"p99"`
+	Max time.Duration `json:"max"`
+}
+
+func latencyOf(ds []time.Duration) Latency {
+	if len(ds) == 0 {
+		return Latency{}
+	}
+
+	sorted := slices.Clone(ds)
+	slices.Sort(sorted)
+
+	at := func(p float64) time.Duration {
+		idx := int(float64(len(sorted))*p+0.5) - 1
+		return sorted[max(0, min(idx, len(sorted)-1))]
+	}
+
+	return Latency{
+		P50: at(0.50),
+		P90: at(0.90),
+		P99: at(0.99),
+		Max: sorted[len(sorted)-1],
+	}
+}
+
+// SaveReport writes the report as json into the folder and returns the path of the file.
+func SaveReport(folder string, r Report) (string, error) {
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		return "", fmt.Errorf("mkdir: %s: %w", folder, err)
+	}
+
+	bs, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("marshal: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-max%d.json", r.StartedAt.UTC().Format("20060102T150405"), r.MaxRunningTasks)
+	path := filepath.Join(folder, name)
+
+	if err := os.WriteFile(path, bs, 0644); err != nil {
+		return "", fmt.Errorf("write: %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// LoadReports reads the reports saved inside of the folder.
+func LoadReports(folder string) ([]Report, error) {
+	paths, err := filepath.Glob(filepath.Join(folder, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("glob: %w", err)
+	}
+
+	reports := make([]Report, 0, len(paths))
+	for _, path := range paths {
+		bs, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read: %s: %w", path, err)
+		}
+
+		var r Report
+		if err := json.Unmarshal(bs, &r); err != nil {
+			return nil, fmt.Errorf("unmarshal: %s: %w", path, err)
+		}
+		reports = append(reports, r)
+	}
+	return reports, nil
+}
+
+// WriteTable writes the reports as a table ordered by MaxRunningTasks, so the throughput gained by running more tasks
+// at the same time can be read off of it.
+func WriteTable(w io.Writer, reports []Report) error {
+	sorted := slices.Clone(reports)
+	slices.SortFunc(sorted, func(a Report, b Report) int {
+		return cmp.Or(cmp.Compare(a.MaxRunningTasks, b.MaxRunningTasks), a.StartedAt.Compare(b.StartedAt))
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	header := []string{"MAX RUNNING", "TASKS", "CREATE ERRORS", "COMPLETED", "FAILED", "UNFINISHED",
+		"CREATE P50", "CREATE P99", "CREATED/S", "E2E P50", "E2E P90", "E2E P99", "FINISHED/S"}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+
+	for _, r := range sorted {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%d\t%d\t%s\t%s\t%.1f\t%s\t%s\t%s\t%.2f\n",
+			r.MaxRunningTasks, r.Tasks, r.CreateErrors, r.Completed, r.Failed, r.Unfinished,
+			round(r.CreateLatency.P50), round(r.CreateLatency.P99), r.CreateRate,
+			round(r.EndToEnd.P50), round(r.EndToEnd.P90), round(r.EndToEnd.P99), r.Throughput,
+		)
+	}
+
+	return tw.Flush()
+}
+
+func round(d time.Duration) time.Duration {
+	if d < time.Second {
+		return d.Round(time.Microsecond * 100)
+	}
+	return d.Round(time.Millisecond * 10)
+}
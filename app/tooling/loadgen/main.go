@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ardanlabs/conf/v3"
+	"github.com/hamidoujand/task-scheduler/app/tooling/loadgen/load"
+	"github.com/hamidoujand/task-scheduler/foundation/logger"
+)
+
+// will be changed from build tags
+var build = "0.0.1"
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "err: %s", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	//==========================================================================
+	//setup configurations
+	configs := struct {
+		conf.Version
+		Args conf.Args
+
+		API struct {
+			Host string `conf:"default:http://localhost:8000"`
+			//Token is used as it is, without it the load generator logs in with Email and Password.
+			Token    string `conf:"mask"`
+			Email    string
+			Password string `conf:"mask"`
+		}
+
+		Load struct {
+			Tasks        int           `conf:"default:1000"`
+			Concurrency  int           `conf:"default:20"`
+			Image        string        `conf:"default:alpine:3.20"`
+			Command      string        `conf:"default:date"`
+			Delay        time.Duration `conf:"default:1s"`
+			PollInterval time.Duration `conf:"default:1s"`
+			Timeout      time.Duration `conf:"default:10m"`
+			//MaxRunningTasks is how many tasks the instance runs at the same time, TASKS_WORKER_MAXRUNNINGTASKS of the
+			//workers or GOMAXPROCS of the api. It labels the report.
+			MaxRunningTasks int
+		}
+
+		Report struct {
+			Folder string `conf:"default:zarf/loadgen/"`
+		}
+	}{
+		Version: conf.Version{
+			Build: build,
+			Desc:  "load generator for task scheduler, commands: run, report",
+		},
+	}
+
+	prefix := "LOADGEN"
+	if help, err := conf.Parse(prefix, &configs); err != nil {
+		if errors.Is(err, conf.ErrHelpWanted) {
+			fmt.Println(help)
+			return nil
+		}
+		return fmt.Errorf("parsing config: %w", err)
+	}
+
+	attrs := []slog.Attr{
+		{Key: "build", Value: slog.StringValue(build)},
+		{Key: "app", Value: slog.StringValue("task-scheduler-loadgen")},
+	}
+	logger := logger.NewCustomLogger(slog.LevelInfo, false, attrs...)
+
+	switch cmd := configs.Args.Num(0); cmd {
+	case "run":
+		//reports of different instances can only be compared when they are labeled
+		if configs.Load.MaxRunningTasks <= 0 {
+			return errors.New("LOADGEN_LOAD_MAXRUNNINGTASKS is required, set it to how many tasks the instance runs at the same time")
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		client := load.NewClient(configs.API.Host, configs.API.Token)
+		if configs.API.Token == "" {
+			if err := client.Login(ctx, configs.API.Email, configs.API.Password); err != nil {
+				return err
+			}
+		}
+
+		logger.Info("run", "status", "started", "tasks", configs.Load.Tasks, "maxRunningTasks", configs.Load.MaxRunningTasks)
+
+		report, err := load.Run(ctx, client, load.Config{
+			Tasks:       configs.Load.Tasks,
+			Concurrency: configs.Load.Concurrency,
+			Task: load.NewTask{
+				Command: configs.Load.Command,
+				Image:   configs.Load.Image,
+			},
+			Delay:           configs.Load.Delay,
+			PollInterval:    configs.Load.PollInterval,
+			Timeout:         configs.Load.Timeout,
+			MaxRunningTasks: configs.Load.MaxRunningTasks,
+		})
+		if err != nil {
+			return fmt.Errorf("run: %w", err)
+		}
+
+		path, err := load.SaveReport(configs.Report.Folder, report)
+		if err != nil {
+			return fmt.Errorf("save report: %w", err)
+		}
+		logger.Info("run", "status", "completed", "report", path, "createErrors", report.CreateErrors, "firstError", report.FirstError)
+
+		return load.WriteTable(os.Stdout, []load.Report{report})
+
+	case "report":
+		reports, err := load.LoadReports(configs.Report.Folder)
+		if err != nil {
+			return fmt.Errorf("load reports: %w", err)
+		}
+
+		if len(reports) == 0 {
+			return fmt.Errorf("no reports inside of %s, create them with the run command", configs.Report.Folder)
+		}
+		return load.WriteTable(os.Stdout, reports)
+
+	default:
+		return fmt.Errorf("unknown command %q, available commands: run, report", cmd)
+	}
+}
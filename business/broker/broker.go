@@ -0,0 +1,65 @@
+// Package broker defines the message broker the task service and the scheduler are built on, so the broker a
+// deployment runs can be swapped without touching either of them.
+package broker
+
+import (
+	"context"
+	"time"
+)
+
+// Headers represents the headers of a message, integers are carried as int64.
+type Headers map[string]any
+
+// QueueOptions represents the optional behavior of a queue.
+type QueueOptions struct {
+	//MessageTTL is how long a message may wait inside of the queue before it expires, zero keeps it forever.
+	MessageTTL time.Duration
+	//DeadLetterQueue receives the expired messages, they are dropped when it is empty.
+	DeadLetterQueue string
+	//Expires is how long the queue may stay without consumers before it is deleted, zero keeps it forever.
+	Expires time.Duration
+}
+
+// QueueStats represents the number of ready messages inside of a queue and the number of its consumers.
+type QueueStats struct {
+	Messages  int
+	Consumers int
+}
+
+// Acknowledger settles a delivered message.
+type Acknowledger interface {
+	//Ack removes the message from the broker once it was processed.
+	Ack() error
+	//Nack gives the message back to the broker, with requeue it is delivered again.
+	Nack(requeue bool) error
+}
+
+// Delivery represents a message delivered to a consumer, it must be settled with Ack or Nack.
+type Delivery struct {
+	Acknowledger
+	Queue   string
+	Body    []byte
+	Headers Headers
+}
+
+// Broker represents the message broker, every publish waits for the broker to confirm it.
+type Broker interface {
+	DeclareQueue(name string) error
+	DeclareQueueWithOptions(name string, opts QueueOptions) error
+	PublishWithConfirm(ctx context.Context, queue string, msg []byte) error
+	PublishWithConfirmAndHeaders(ctx context.Context, queue string, msg []byte, headers Headers) error
+	//Consume delivers the messages of the queue one at a time, an empty tag lets the broker generate one.
+	Consume(queue string, consumerTag string) (<-chan Delivery, error)
+	//ConsumeWithPrefetch is Consume with up to prefetch messages delivered before the first of them is settled.
+	ConsumeWithPrefetch(queue string, consumerTag string, prefetch int) (<-chan Delivery, error)
+	//Cancel stops the consumer with the tag, its delivery channel is closed afterwards.
+	Cancel(consumerTag string) error
+	QueueStats(name string) (QueueStats, error)
+	//DeclareTopic creates the topic exchange when it does not exist.
+	DeclareTopic(exchange string) error
+	//BindQueue routes the messages of the exchange whose routing key matches pattern into the queue, "*" matches a
+	//single word of the key and "#" any number of them.
+	BindQueue(queue string, exchange string, pattern string) error
+	//PublishTopicWithConfirm publishes the message into the exchange, a message that matches no binding is dropped.
+	PublishTopicWithConfirm(ctx context.Context, exchange string, routingKey string, msg []byte) error
+}
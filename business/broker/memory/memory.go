@@ -0,0 +1,442 @@
+// Package memory provides a broker that keeps its queues inside of the process, used by tests and by a single
+// instance that runs without rabbitmq. Messages are lost when the process exits.
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/broker"
+)
+
+var (
+	ErrQueueNotFound    = errors.New("queue not declared")
+	ErrExchangeNotFound = errors.New("exchange not declared")
+	ErrClosed           = errors.New("broker closed")
+	ErrSettled          = errors.New("delivery already settled")
+)
+
+// expireInterval is how often the messages whose ttl passed are dead lettered.
+const expireInterval = time.Second
+
+type message struct {
+	body        []byte
+	headers     broker.Headers
+	publishedAt time.Time
+}
+
+type queue struct {
+	opts      broker.QueueOptions
+	messages  []message
+	consumers int
+	//published is closed and replaced whenever a message becomes ready, consumers wait on it.
+	published chan struct{}
+}
+
+// binding routes the messages of a topic exchange whose routing key matches pattern into queue.
+type binding struct {
+	queue   string
+	pattern string
+}
+
+// Broker represents the queues of the process.
+type Broker struct {
+	mu        sync.Mutex
+	queues    map[string]*queue
+	exchanges map[string][]binding
+	consumers map[string]chan struct{}
+	tags      int
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// New creates a broker, Close stops its consumers.
+func New() *Broker {
+	b := Broker{
+		queues:    make(map[string]*queue),
+		exchanges: make(map[string][]binding),
+		consumers: make(map[string]chan struct{}),
+		closed:    make(chan struct{}),
+	}
+
+	go b.expireLoop()
+
+	return &b
+}
+
+// Close stops all of the consumers, their delivery channels are closed.
+func (b *Broker) Close() error {
+	b.closeOnce.Do(func() {
+		close(b.closed)
+	})
+	return nil
+}
+
+// DeclareQueue creates the queue when it does not exist.
+func (b *Broker) DeclareQueue(name string) error {
+	return b.DeclareQueueWithOptions(name, broker.QueueOptions{})
+}
+
+// DeclareQueueWithOptions creates the queue when it does not exist, the ttl of its messages and their dead letter
+// queue are applied while Expires is ignored since queues of the process go away with it.
+func (b *Broker) DeclareQueueWithOptions(name string, opts broker.QueueOptions) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.queues[name]; ok {
+		return nil
+	}
+
+	b.queues[name] = &queue{
+		opts:      opts,
+		published: make(chan struct{}),
+	}
+	return nil
+}
+
+// PublishWithConfirm enqueues the message, it is confirmed as soon as it is inside of the queue.
+func (b *Broker) PublishWithConfirm(ctx context.Context, queue string, msg []byte) error {
+	return b.PublishWithConfirmAndHeaders(ctx, queue, msg, nil)
+}
+
+// PublishWithConfirmAndHeaders is PublishWithConfirm for messages that carry headers.
+func (b *Broker) PublishWithConfirmAndHeaders(ctx context.Context, queue string, msg []byte, headers broker.Headers) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q, ok := b.queues[queue]
+	if !ok {
+		return fmt.Errorf("publish into %s: %w", queue, ErrQueueNotFound)
+	}
+
+	q.push(message{
+		body:        append([]byte(nil), msg...),
+		headers:     maps.Clone(headers),
+		publishedAt: time.Now(),
+	})
+	return nil
+}
+
+// Consume delivers the messages of the queue one at a time, the next one is delivered once the previous one is
+// settled. An empty tag generates one.
+func (b *Broker) Consume(queue string, consumerTag string) (<-chan broker.Delivery, error) {
+	return b.ConsumeWithPrefetch(queue, consumerTag, 1)
+}
+
+// ConsumeWithPrefetch is Consume with up to prefetch messages delivered before the first of them is settled.
+func (b *Broker) ConsumeWithPrefetch(queue string, consumerTag string, prefetch int) (<-chan broker.Delivery, error) {
+	if prefetch <= 0 {
+		return nil, fmt.Errorf("prefetch must be greater than 0: %d", prefetch)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q, ok := b.queues[queue]
+	if !ok {
+		return nil, fmt.Errorf("consume %s: %w", queue, ErrQueueNotFound)
+	}
+
+	if consumerTag == "" {
+		b.tags++
+		consumerTag = fmt.Sprintf("ctag-%d", b.tags)
+	}
+
+	if _, ok := b.consumers[consumerTag]; ok {
+		return nil, fmt.Errorf("consumer %s already exists", consumerTag)
+	}
+
+	stop := make(chan struct{})
+	b.consumers[consumerTag] = stop
+	q.consumers++
+
+	deliveries := make(chan broker.Delivery)
+	go b.consume(queue, q, stop, prefetch, deliveries)
+
+	return deliveries, nil
+}
+
+// Cancel stops the consumer with the tag, a delivery it did not settle yet can still be settled.
+func (b *Broker) Cancel(consumerTag string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stop, ok := b.consumers[consumerTag]
+	if !ok {
+		return nil
+	}
+
+	close(stop)
+	delete(b.consumers, consumerTag)
+	return nil
+}
+
+// QueueStats returns the number of ready messages of the queue and the number of its consumers.
+func (b *Broker) QueueStats(name string) (broker.QueueStats, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q, ok := b.queues[name]
+	if !ok {
+		return broker.QueueStats{}, fmt.Errorf("queue stats of %s: %w", name, ErrQueueNotFound)
+	}
+
+	return broker.QueueStats{
+		Messages:  len(q.messages),
+		Consumers: q.consumers,
+	}, nil
+}
+
+// DeclareTopic creates the topic exchange when it does not exist.
+func (b *Broker) DeclareTopic(exchange string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.exchanges[exchange]; !ok {
+		b.exchanges[exchange] = nil
+	}
+	return nil
+}
+
+// BindQueue routes the messages of the exchange whose routing key matches pattern into the queue.
+func (b *Broker) BindQueue(queue string, exchange string, pattern string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	bindings, ok := b.exchanges[exchange]
+	if !ok {
+		return fmt.Errorf("bind %s: %w", exchange, ErrExchangeNotFound)
+	}
+
+	if _, ok := b.queues[queue]; !ok {
+		return fmt.Errorf("bind %s: %w", queue, ErrQueueNotFound)
+	}
+
+	bd := binding{queue: queue, pattern: pattern}
+	if !slices.Contains(bindings, bd) {
+		b.exchanges[exchange] = append(bindings, bd)
+	}
+	return nil
+}
+
+// PublishTopicWithConfirm enqueues the message into every queue with a matching binding, a queue gets it once even
+// when several of its bindings match.
+func (b *Broker) PublishTopicWithConfirm(ctx context.Context, exchange string, routingKey string, msg []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	bindings, ok := b.exchanges[exchange]
+	if !ok {
+		return fmt.Errorf("publish into %s: %w", exchange, ErrExchangeNotFound)
+	}
+
+	routed := make(map[string]bool)
+	for _, bd := range bindings {
+		if routed[bd.queue] || !matchTopic(strings.Split(bd.pattern, "."), strings.Split(routingKey, ".")) {
+			continue
+		}
+		routed[bd.queue] = true
+
+		b.queues[bd.queue].push(message{
+			body:        append([]byte(nil), msg...),
+			publishedAt: time.Now(),
+		})
+	}
+	return nil
+}
+
+// matchTopic reports whether the words of a routing key match the words of a pattern, "*" matches a single word and
+// "#" any number of them.
+func matchTopic(pattern []string, key []string) bool {
+	if len(pattern) == 0 {
+		return len(key) == 0
+	}
+
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(key); i++ {
+			if matchTopic(pattern[1:], key[i:]) {
+				return true
+			}
+		}
+		return false
+
+	case "*":
+		return len(key) > 0 && matchTopic(pattern[1:], key[1:])
+
+	default:
+		return len(key) > 0 && pattern[0] == key[0] && matchTopic(pattern[1:], key[1:])
+	}
+}
+
+func (b *Broker) consume(name string, q *queue, stop chan struct{}, prefetch int, deliveries chan broker.Delivery) {
+	//every unsettled delivery holds a slot
+	unsettled := make(chan struct{}, prefetch)
+
+	defer func() {
+		b.mu.Lock()
+		q.consumers--
+		b.mu.Unlock()
+		close(deliveries)
+	}()
+
+	for {
+		select {
+		case unsettled <- struct{}{}:
+		case <-stop:
+			return
+		case <-b.closed:
+			return
+		}
+
+		msg, ok := b.next(q, stop)
+		if !ok {
+			return
+		}
+
+		d := broker.Delivery{
+			Acknowledger: &acknowledger{broker: b, queue: q, msg: msg, unsettled: unsettled},
+			Queue:        name,
+			Body:         msg.body,
+			Headers:      maps.Clone(msg.headers),
+		}
+
+		select {
+		case deliveries <- d:
+		case <-stop:
+			b.requeue(q, msg)
+			return
+		case <-b.closed:
+			b.requeue(q, msg)
+			return
+		}
+	}
+}
+
+// next blocks until the queue has a message or the consumer stops.
+func (b *Broker) next(q *queue, stop chan struct{}) (message, bool) {
+	for {
+		b.mu.Lock()
+		if len(q.messages) > 0 {
+			msg := q.messages[0]
+			q.messages = q.messages[1:]
+			b.mu.Unlock()
+			return msg, true
+		}
+		published := q.published
+		b.mu.Unlock()
+
+		select {
+		case <-published:
+		case <-stop:
+			return message{}, false
+		case <-b.closed:
+			return message{}, false
+		}
+	}
+}
+
+// requeue puts the message back to the head of its queue.
+func (b *Broker) requeue(q *queue, msg message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q.messages = append([]message{msg}, q.messages...)
+	q.notify()
+}
+
+// expireLoop dead letters the messages whose ttl passed.
+func (b *Broker) expireLoop() {
+	ticker := time.NewTicker(expireInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.closed:
+			return
+		case <-ticker.C:
+		}
+
+		b.expire(time.Now())
+	}
+}
+
+func (b *Broker) expire(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, q := range b.queues {
+		if q.opts.MessageTTL <= 0 {
+			continue
+		}
+
+		var kept []message
+		for _, msg := range q.messages {
+			if now.Sub(msg.publishedAt) < q.opts.MessageTTL {
+				kept = append(kept, msg)
+				continue
+			}
+
+			//expired messages without a dead letter queue are dropped
+			if dlq, ok := b.queues[q.opts.DeadLetterQueue]; ok {
+				msg.publishedAt = now
+				dlq.push(msg)
+			}
+		}
+		q.messages = kept
+	}
+}
+
+func (q *queue) push(msg message) {
+	q.messages = append(q.messages, msg)
+	q.notify()
+}
+
+func (q *queue) notify() {
+	close(q.published)
+	q.published = make(chan struct{})
+}
+
+// acknowledger settles a delivery of the memory broker.
+type acknowledger struct {
+	broker *Broker
+	queue  *queue
+	msg    message
+	once   sync.Once
+	//unsettled frees the slot of the delivery once it is settled.
+	unsettled chan struct{}
+}
+
+func (a *acknowledger) Ack() error {
+	return a.settle(false)
+}
+
+func (a *acknowledger) Nack(requeue bool) error {
+	return a.settle(requeue)
+}
+
+func (a *acknowledger) settle(requeue bool) error {
+	err := ErrSettled
+	a.once.Do(func() {
+		err = nil
+		if requeue {
+			a.broker.requeue(a.queue, a.msg)
+		}
+		<-a.unsettled
+	})
+	return err
+}
@@ -0,0 +1,262 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/broker"
+	"github.com/hamidoujand/task-scheduler/business/broker/memory"
+)
+
+const queueTest = "queue_test"
+
+func TestBroker(t *testing.T) {
+	t.Parallel()
+
+	b := memory.New()
+	t.Cleanup(func() {
+		b.Close()
+	})
+
+	if err := b.DeclareQueue(queueTest); err != nil {
+		t.Fatalf("expected to declare queue %s: %s", queueTest, err)
+	}
+
+	ctx := context.Background()
+	headers := broker.Headers{"x-redeliveries": int64(2)}
+	if err := b.PublishWithConfirmAndHeaders(ctx, queueTest, []byte("first"), headers); err != nil {
+		t.Fatalf("expected to publish into %s: %s", queueTest, err)
+	}
+
+	if err := b.PublishWithConfirm(ctx, queueTest, []byte("second")); err != nil {
+		t.Fatalf("expected to publish into %s: %s", queueTest, err)
+	}
+
+	if err := b.PublishWithConfirm(ctx, "missing_queue", []byte("lost")); !errors.Is(err, memory.ErrQueueNotFound) {
+		t.Errorf("err= %v, got %v", memory.ErrQueueNotFound, err)
+	}
+
+	msgs, err := b.Consume(queueTest, "consumer")
+	if err != nil {
+		t.Fatalf("expected to consume %s: %s", queueTest, err)
+	}
+
+	d := receive(t, msgs)
+	if string(d.Body) != "first" {
+		t.Errorf("body= %s, got %s", "first", d.Body)
+	}
+
+	if d.Headers["x-redeliveries"] != int64(2) {
+		t.Errorf("redeliveries= %d, got %v", 2, d.Headers["x-redeliveries"])
+	}
+
+	if d.Queue != queueTest {
+		t.Errorf("queue= %s, got %s", queueTest, d.Queue)
+	}
+
+	//the same message is delivered again after a requeue
+	if err := d.Nack(true); err != nil {
+		t.Fatalf("expected to nack: %s", err)
+	}
+
+	if err := d.Ack(); !errors.Is(err, memory.ErrSettled) {
+		t.Errorf("err= %v, got %v", memory.ErrSettled, err)
+	}
+
+	d = receive(t, msgs)
+	if string(d.Body) != "first" {
+		t.Errorf("body= %s, got %s", "first", d.Body)
+	}
+
+	if err := d.Ack(); err != nil {
+		t.Fatalf("expected to ack: %s", err)
+	}
+
+	stats, err := b.QueueStats(queueTest)
+	if err != nil {
+		t.Fatalf("expected to get the stats of %s: %s", queueTest, err)
+	}
+
+	if stats.Consumers != 1 {
+		t.Errorf("consumers= %d, got %d", 1, stats.Consumers)
+	}
+
+	d = receive(t, msgs)
+	if string(d.Body) != "second" {
+		t.Errorf("body= %s, got %s", "second", d.Body)
+	}
+
+	//the delivery channel is closed once the consumer is canceled
+	if err := b.Cancel("consumer"); err != nil {
+		t.Fatalf("expected to cancel the consumer: %s", err)
+	}
+
+	select {
+	case _, ok := <-msgs:
+		if ok {
+			t.Error("expected no delivery after cancel")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the delivery channel to be closed")
+	}
+
+	if err := d.Ack(); err != nil {
+		t.Errorf("expected to ack after cancel: %s", err)
+	}
+}
+
+func TestDeadLetter(t *testing.T) {
+	t.Parallel()
+
+	b := memory.New()
+	t.Cleanup(func() {
+		b.Close()
+	})
+
+	if err := b.DeclareQueue("queue_dlq"); err != nil {
+		t.Fatalf("expected to declare the dead letter queue: %s", err)
+	}
+
+	opts := broker.QueueOptions{
+		MessageTTL:      time.Millisecond * 10,
+		DeadLetterQueue: "queue_dlq",
+	}
+	if err := b.DeclareQueueWithOptions(queueTest, opts); err != nil {
+		t.Fatalf("expected to declare queue %s: %s", queueTest, err)
+	}
+
+	if err := b.PublishWithConfirm(context.Background(), queueTest, []byte("expires")); err != nil {
+		t.Fatalf("expected to publish into %s: %s", queueTest, err)
+	}
+
+	msgs, err := b.Consume("queue_dlq", "")
+	if err != nil {
+		t.Fatalf("expected to consume the dead letter queue: %s", err)
+	}
+
+	d := receive(t, msgs)
+	if string(d.Body) != "expires" {
+		t.Errorf("body= %s, got %s", "expires", d.Body)
+	}
+}
+
+func TestPrefetch(t *testing.T) {
+	t.Parallel()
+
+	b := memory.New()
+	t.Cleanup(func() {
+		b.Close()
+	})
+
+	if err := b.DeclareQueue(queueTest); err != nil {
+		t.Fatalf("expected to declare queue %s: %s", queueTest, err)
+	}
+
+	for _, body := range []string{"first", "second", "third"} {
+		if err := b.PublishWithConfirm(context.Background(), queueTest, []byte(body)); err != nil {
+			t.Fatalf("expected to publish into %s: %s", queueTest, err)
+		}
+	}
+
+	msgs, err := b.ConsumeWithPrefetch(queueTest, "", 2)
+	if err != nil {
+		t.Fatalf("expected to consume %s: %s", queueTest, err)
+	}
+
+	first := receive(t, msgs)
+	second := receive(t, msgs)
+	if string(first.Body) != "first" || string(second.Body) != "second" {
+		t.Errorf("bodies= %s %s, got %s %s", "first", "second", first.Body, second.Body)
+	}
+
+	//both slots are taken until one of the deliveries is settled
+	select {
+	case d := <-msgs:
+		t.Fatalf("expected no delivery before a settle, got %s", d.Body)
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	if err := second.Ack(); err != nil {
+		t.Fatalf("expected to ack: %s", err)
+	}
+
+	third := receive(t, msgs)
+	if string(third.Body) != "third" {
+		t.Errorf("body= %s, got %s", "third", third.Body)
+	}
+
+	if _, err := b.ConsumeWithPrefetch(queueTest, "", 0); err == nil {
+		t.Error("expected a prefetch of 0 to fail")
+	}
+}
+
+func receive(t *testing.T, msgs <-chan broker.Delivery) broker.Delivery {
+	t.Helper()
+
+	select {
+	case d, ok := <-msgs:
+		if !ok {
+			t.Fatal("expected a delivery, the channel is closed")
+		}
+		return d
+	case <-time.After(time.Second * 5):
+		t.Fatal("expected a delivery")
+	}
+	return broker.Delivery{}
+}
+
+func TestTopic(t *testing.T) {
+	t.Parallel()
+
+	b := memory.New()
+	t.Cleanup(func() {
+		b.Close()
+	})
+
+	if err := b.DeclareTopic("tasks"); err != nil {
+		t.Fatalf("expected to declare the topic: %s", err)
+	}
+
+	bindings := map[string]string{
+		"queue_failed_only": "task.failed",
+		"queue_all":         "task.*",
+		"queue_nested":      "#.failed",
+	}
+	for queue, pattern := range bindings {
+		if err := b.DeclareQueue(queue); err != nil {
+			t.Fatalf("expected to declare queue %s: %s", queue, err)
+		}
+
+		if err := b.BindQueue(queue, "tasks", pattern); err != nil {
+			t.Fatalf("expected to bind queue %s: %s", queue, err)
+		}
+	}
+
+	for _, key := range []string{"task.created", "task.failed"} {
+		if err := b.PublishTopicWithConfirm(context.Background(), "tasks", key, []byte(key)); err != nil {
+			t.Fatalf("expected to publish %s: %s", key, err)
+		}
+	}
+
+	expected := map[string]int{
+		"queue_failed_only": 1,
+		"queue_all":         2,
+		"queue_nested":      1,
+	}
+	for queue, messages := range expected {
+		stats, err := b.QueueStats(queue)
+		if err != nil {
+			t.Fatalf("expected to get the stats of %s: %s", queue, err)
+		}
+
+		if stats.Messages != messages {
+			t.Errorf("messages of %s= %d, got %d", queue, messages, stats.Messages)
+		}
+	}
+
+	if err := b.BindQueue("queue_all", "missing", "#"); !errors.Is(err, memory.ErrExchangeNotFound) {
+		t.Errorf("expected error to be %v, got %v", memory.ErrExchangeNotFound, err)
+	}
+}
@@ -0,0 +1,80 @@
+// Package messages defines the envelope every task travels inside of through the queues, so instances running
+// different builds during a rolling upgrade can still read the messages of each other.
+package messages
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// SchemaVersion is the version of the messages this build writes. It is only bumped when a payload changes in a way
+// older builds can not read, adding a field does not need a new version since unknown fields are ignored.
+const SchemaVersion = 1
+
+// Type represents what the payload of a message is.
+type Type string
+
+// TypeTask is the type of the messages whose payload is a task.
+const TypeTask Type = "task"
+
+var (
+	ErrUnsupportedVersion = errors.New("unsupported schema version")
+	ErrUnexpectedType     = errors.New("unexpected message type")
+)
+
+// Envelope represents a message of the queues.
+type Envelope struct {
+	SchemaVersion int             `json:"schema_version"`
+	Type          Type            `json:"type"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+// Marshal wraps the payload inside of an envelope of the current version.
+func Marshal(typ Type, payload any) ([]byte, error) {
+	bs, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshal payload: %w", err)
+	}
+
+	env := Envelope{
+		SchemaVersion: SchemaVersion,
+		Type:          typ,
+		Payload:       bs,
+	}
+
+	bs, err = json.Marshal(env)
+	if err != nil {
+		return nil, fmt.Errorf("marshal envelope: %w", err)
+	}
+	return bs, nil
+}
+
+// Unmarshal decodes the payload of a message of typ into payload.
+// Messages written before the envelope existed carry the payload on its own and are read as version 0, messages of
+// a newer version than SchemaVersion fail with ErrUnsupportedVersion so they can be left to an upgraded instance.
+func Unmarshal(bs []byte, typ Type, payload any) error {
+	var env Envelope
+	if err := json.Unmarshal(bs, &env); err != nil {
+		return fmt.Errorf("unmarshal envelope: %w", err)
+	}
+
+	switch {
+	case env.SchemaVersion == 0:
+		//a bare payload has neither of the fields of the envelope
+		env.Type = typ
+		env.Payload = bs
+
+	case env.SchemaVersion > SchemaVersion:
+		return fmt.Errorf("version %d, this build reads up to %d: %w", env.SchemaVersion, SchemaVersion, ErrUnsupportedVersion)
+	}
+
+	if env.Type != typ {
+		return fmt.Errorf("expected %q, got %q: %w", typ, env.Type, ErrUnexpectedType)
+	}
+
+	if err := json.Unmarshal(env.Payload, payload); err != nil {
+		return fmt.Errorf("unmarshal payload: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package messages_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hamidoujand/task-scheduler/business/broker/messages"
+)
+
+type payload struct {
+	Id   string
+	Name string
+}
+
+func TestUnmarshal(t *testing.T) {
+	current, err := messages.Marshal(messages.TypeTask, payload{Id: "1", Name: "ls"})
+	if err != nil {
+		t.Fatalf("expected to marshal the payload: %s", err)
+	}
+
+	tests := map[string]struct {
+		body    string
+		typ     messages.Type
+		want    payload
+		wantErr error
+	}{
+		"current version": {
+			body: string(current),
+			typ:  messages.TypeTask,
+			want: payload{Id: "1", Name: "ls"},
+		},
+		"bare payload of older builds": {
+			body: `{"Id":"2","Name":"echo"}`,
+			typ:  messages.TypeTask,
+			want: payload{Id: "2", Name: "echo"},
+		},
+		"unknown fields are ignored": {
+			body: `{"schema_version":1,"type":"task","payload":{"Id":"3","Priority":5}}`,
+			typ:  messages.TypeTask,
+			want: payload{Id: "3"},
+		},
+		"newer version": {
+			body:    `{"schema_version":2,"type":"task","payload":{"Id":"4"}}`,
+			typ:     messages.TypeTask,
+			wantErr: messages.ErrUnsupportedVersion,
+		},
+		"other type": {
+			body:    string(current),
+			typ:     messages.Type("event"),
+			wantErr: messages.ErrUnexpectedType,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got payload
+			err := messages.Unmarshal([]byte(tt.body), tt.typ, &got)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected error to be %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected to unmarshal the message: %s", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("payload= %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
@@ -2,18 +2,28 @@ package rabbitmq
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/hamidoujand/task-scheduler/business/broker"
+	"github.com/hamidoujand/task-scheduler/foundation/breaker"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 // RabbitMQClient represents a set of APIs we need to access when working againt
 // rabbitmq client.
 type Client struct {
-	conn    *amqp.Connection
-	channel *amqp.Channel
+	conn     *amqp.Connection
+	channel  *amqp.Channel
+	breaker  *breaker.Breaker
+	prefix   string
+	exchange string
+	//consumeMu keeps the qos of a consumer from being changed by another one before it started.
+	consumeMu sync.Mutex
 }
 
 // Configs represents all required configs for creating a rabbitmq client.
@@ -21,6 +31,17 @@ type Configs struct {
 	Host     string
 	User     string
 	Password string
+	//VHost is optional, the client connects to the default vhost without it.
+	VHost string
+	//QueuePrefix is optional, it is put in front of the name of every queue the client touches so environments
+	//that share a vhost, like "staging." and "prod.", do not consume the messages of each other.
+	QueuePrefix string
+	//Exchange is optional, messages are published through this direct exchange instead of the default one. It is
+	//declared along with a binding of every declared queue.
+	Exchange string
+	//Breaker is optional, with it publishes and queue stats fail fast while the broker is down. Consumers are not
+	//guarded, their deliveries stop on their own.
+	Breaker *breaker.Breaker
 }
 
 // NewClient creates a connection rabbitmq server and creates a client return it or possible error.
@@ -38,6 +59,10 @@ func NewClient(ctx context.Context, conf Configs) (*Client, error) {
 		User:   url.UserPassword(conf.User, conf.Password),
 	}
 
+	if conf.VHost != "" {
+		url.Path = "/" + conf.VHost
+	}
+
 	var conn *amqp.Connection
 
 	//we need a retry functionality
@@ -66,12 +91,31 @@ func NewClient(ctx context.Context, conf Configs) (*Client, error) {
 		return nil, fmt.Errorf("open channel: %w", err)
 	}
 
+	//the broker confirms every publish, only PublishWithConfirm waits for them
+	if err := ch.Confirm(false); err != nil {
+		return nil, fmt.Errorf("confirm mode: %w", err)
+	}
+
+	if conf.Exchange != "" {
+		if err := ch.ExchangeDeclare(conf.Exchange, amqp.ExchangeDirect, true, false, false, false, nil); err != nil {
+			return nil, fmt.Errorf("declare exchange: %w", err)
+		}
+	}
+
 	return &Client{
-		conn:    conn,
-		channel: ch,
+		conn:     conn,
+		channel:  ch,
+		breaker:  conf.Breaker,
+		prefix:   conf.QueuePrefix,
+		exchange: conf.Exchange,
 	}, nil
 }
 
+// name returns the name of the queue or the exchange inside of the broker.
+func (rc *Client) name(name string) string {
+	return rc.prefix + name
+}
+
 // Close will close the connection and the channel or returns possible errors
 func (rc *Client) Close() error {
 	err := rc.channel.Close()
@@ -88,28 +132,109 @@ func (rc *Client) Close() error {
 
 // DeclareQueue is going to create a queue to push messages into it.
 func (rc *Client) DeclareQueue(name string) error {
+	return rc.DeclareQueueWithArgs(name, nil)
+}
+
+// DeclareQueueWithArgs creates a queue with optional arguments like message ttl and dead lettering.
+func (rc *Client) DeclareQueueWithArgs(name string, args amqp.Table) error {
+	name = rc.name(name)
 	_, err := rc.channel.QueueDeclare(
 		name,
 		true,
 		false,
 		false,
 		false,
-		nil,
+		args,
 	)
 	if err != nil {
 		return fmt.Errorf("declareQueue: %w", err)
 	}
+
+	//the default exchange routes by the name of the queue on its own
+	if rc.exchange != "" {
+		if err := rc.channel.QueueBind(name, name, rc.exchange, false, nil); err != nil {
+			return fmt.Errorf("bind queue: %w", err)
+		}
+	}
 	return nil
 }
 
+// QueueStats returns the stats of the queue using a passive declare. The broker closes the channel of a passive
+// declare when the queue does not exist, so it uses a channel of its own.
+func (rc *Client) QueueStats(name string) (broker.QueueStats, error) {
+	var stats broker.QueueStats
+	err := rc.guard(func() error {
+		var err error
+		stats, err = rc.queueStats(name)
+		return err
+	})
+	return stats, err
+}
+
+func (rc *Client) queueStats(name string) (broker.QueueStats, error) {
+	ch, err := rc.conn.Channel()
+	if err != nil {
+		return broker.QueueStats{}, fmt.Errorf("open channel: %w", err)
+	}
+	defer ch.Close()
+
+	q, err := ch.QueueDeclarePassive(
+		rc.name(name),
+		true,
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		return broker.QueueStats{}, fmt.Errorf("declare passive: %w", err)
+	}
+
+	return broker.QueueStats{
+		Messages:  q.Messages,
+		Consumers: q.Consumers,
+	}, nil
+}
+
+// DeclareQueueWithOptions creates a queue whose expired messages are dead lettered into another queue.
+func (rc *Client) DeclareQueueWithOptions(name string, opts broker.QueueOptions) error {
+	args := amqp.Table{}
+	if opts.MessageTTL > 0 {
+		args["x-message-ttl"] = opts.MessageTTL.Milliseconds()
+	}
+
+	if opts.DeadLetterQueue != "" {
+		args["x-dead-letter-exchange"] = rc.exchange
+		args["x-dead-letter-routing-key"] = rc.name(opts.DeadLetterQueue)
+	}
+
+	if opts.Expires > 0 {
+		args["x-expires"] = opts.Expires.Milliseconds()
+	}
+
+	return rc.DeclareQueueWithArgs(name, args)
+}
+
 // Publish enqueues the message into the queue or returns possible errors.
 func (rc *Client) Publish(queue string, msg []byte) error {
+	return rc.PublishWithHeaders(queue, msg, nil)
+}
+
+// PublishWithHeaders enqueues the message into the queue along with headers that consumers can use as hints.
+func (rc *Client) PublishWithHeaders(queue string, msg []byte, headers amqp.Table) error {
+	return rc.guard(func() error {
+		return rc.publish(queue, msg, headers)
+	})
+}
+
+func (rc *Client) publish(queue string, msg []byte, headers amqp.Table) error {
 	if err := rc.channel.Publish(
-		"",
-		queue,
+		rc.exchange,
+		rc.name(queue),
 		false,
 		false,
 		amqp.Publishing{
+			Headers:      headers,
 			ContentType:  "application/json",
 			DeliveryMode: amqp.Persistent,
 			Body:         msg,
@@ -121,17 +246,156 @@ func (rc *Client) Publish(queue string, msg []byte) error {
 	return nil
 }
 
+// ErrNacked is returned when the broker refuses to take responsibility for a published message.
+var ErrNacked = errors.New("message nacked by the broker")
+
+// confirmBackoff is the base delay between attempts of a confirmed publish.
+const confirmBackoff = time.Millisecond * 100
+
+// PublishWithConfirm enqueues the message and waits for the broker to confirm it is stored, failed attempts are
+// retried until ctx is done. Without a deadline on ctx the publish gives up after 5 seconds.
+func (rc *Client) PublishWithConfirm(ctx context.Context, queue string, msg []byte) error {
+	return rc.PublishWithConfirmAndHeaders(ctx, queue, msg, nil)
+}
+
+// PublishWithConfirmAndHeaders is PublishWithConfirm for messages that carry headers.
+func (rc *Client) PublishWithConfirmAndHeaders(ctx context.Context, queue string, msg []byte, headers broker.Headers) error {
+	return rc.guard(func() error {
+		return rc.publishWithConfirm(ctx, rc.exchange, rc.name(queue), msg, headers)
+	})
+}
+
+// PublishTopicWithConfirm is PublishWithConfirm for a topic exchange, a message that matches no binding is dropped
+// by the broker.
+func (rc *Client) PublishTopicWithConfirm(ctx context.Context, exchange string, routingKey string, msg []byte) error {
+	return rc.guard(func() error {
+		return rc.publishWithConfirm(ctx, rc.name(exchange), routingKey, msg, nil)
+	})
+}
+
+func (rc *Client) publishWithConfirm(ctx context.Context, exchange string, key string, msg []byte, headers broker.Headers) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Second*5)
+		defer cancel()
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := rc.publishConfirmed(ctx, exchange, key, msg, amqp.Table(headers))
+		if err == nil {
+			return nil
+		}
+
+		//retrying on a closed channel never succeeds
+		if errors.Is(err, amqp.ErrClosed) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("attempt %d: %w", attempt, err)
+		case <-time.After(time.Duration(attempt) * confirmBackoff):
+		}
+	}
+}
+
+func (rc *Client) publishConfirmed(ctx context.Context, exchange string, key string, msg []byte, headers amqp.Table) error {
+	confirm, err := rc.channel.PublishWithDeferredConfirmWithContext(
+		ctx,
+		exchange,
+		key,
+		false,
+		false,
+		amqp.Publishing{
+			Headers:      headers,
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			Body:         msg,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+
+	acked, err := confirm.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("wait for confirm: %w", err)
+	}
+
+	if !acked {
+		return ErrNacked
+	}
+	return nil
+}
+
+// DeclareTopic creates a durable topic exchange, it carries the queue prefix like queues do.
+func (rc *Client) DeclareTopic(exchange string) error {
+	if err := rc.channel.ExchangeDeclare(rc.name(exchange), amqp.ExchangeTopic, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("declare topic: %w", err)
+	}
+	return nil
+}
+
+// BindQueue routes the messages of the topic exchange whose routing key matches pattern into the queue.
+func (rc *Client) BindQueue(queue string, exchange string, pattern string) error {
+	if err := rc.channel.QueueBind(rc.name(queue), pattern, rc.name(exchange), false, nil); err != nil {
+		return fmt.Errorf("bind queue: %w", err)
+	}
+	return nil
+}
+
 // Consumer returns <-chan amqp.Delivery to consume messages from or possible error.
 func (rc *Client) Consumer(queue string) (<-chan amqp.Delivery, error) {
+	return rc.consume(queue, "", 1)
+}
+
+// Consume returns the deliveries of the queue registered under the given consumer tag, so it can be canceled later
+// using Cancel, an empty tag lets the server generate one.
+func (rc *Client) Consume(queue string, consumerTag string) (<-chan broker.Delivery, error) {
+	return rc.ConsumeWithPrefetch(queue, consumerTag, 1)
+}
+
+// ConsumeWithPrefetch is Consume with up to prefetch messages delivered before the first of them is acknowledged.
+func (rc *Client) ConsumeWithPrefetch(queue string, consumerTag string, prefetch int) (<-chan broker.Delivery, error) {
+	if prefetch <= 0 {
+		return nil, fmt.Errorf("prefetch must be greater than 0: %d", prefetch)
+	}
+
+	msgs, err := rc.consume(queue, consumerTag, prefetch)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make(chan broker.Delivery)
+	go func() {
+		defer close(deliveries)
+		for msg := range msgs {
+			deliveries <- broker.Delivery{
+				Acknowledger: acknowledger{msg: msg},
+				Queue:        strings.TrimPrefix(msg.RoutingKey, rc.prefix),
+				Body:         msg.Body,
+				Headers:      broker.Headers(msg.Headers),
+			}
+		}
+	}()
+
+	return deliveries, nil
+}
+
+func (rc *Client) consume(queue string, consumerTag string, prefetch int) (<-chan amqp.Delivery, error) {
+	//the qos of the channel applies to the consumers started after it, another consumer must not change it in between
+	rc.consumeMu.Lock()
+	defer rc.consumeMu.Unlock()
+
 	//limit the number of messages that the broker will deliver to consumers
 	//before requiring an acknowledgment
-	if err := rc.channel.Qos(1, 0, false); err != nil {
+	if err := rc.channel.Qos(prefetch, 0, false); err != nil {
 		return nil, fmt.Errorf("qos: %w", err)
 	}
 
 	msgs, err := rc.channel.Consume(
-		queue,
-		"",
+		rc.name(queue),
+		consumerTag,
 		false,
 		false,
 		false,
@@ -145,3 +409,33 @@ func (rc *Client) Consumer(queue string) (<-chan amqp.Delivery, error) {
 
 	return msgs, nil
 }
+
+// Cancel stops the deliveries of the consumer with the given tag, the delivery channel of that consumer will be
+// closed after all of the buffered messages are delivered.
+func (rc *Client) Cancel(consumerTag string) error {
+	if err := rc.channel.Cancel(consumerTag, false); err != nil {
+		return fmt.Errorf("cancel: %w", err)
+	}
+	return nil
+}
+
+// guard runs fn through the breaker when there is one.
+func (rc *Client) guard(fn func() error) error {
+	if rc.breaker == nil {
+		return fn()
+	}
+	return rc.breaker.Do(fn)
+}
+
+// acknowledger settles a delivery of rabbitmq.
+type acknowledger struct {
+	msg amqp.Delivery
+}
+
+func (a acknowledger) Ack() error {
+	return a.msg.Ack(false)
+}
+
+func (a acknowledger) Nack(requeue bool) error {
+	return a.msg.Nack(false, requeue)
+}
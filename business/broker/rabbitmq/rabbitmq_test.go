@@ -7,32 +7,13 @@ import (
 	"time"
 
 	"github.com/hamidoujand/task-scheduler/business/broker/rabbitmq"
-	"github.com/hamidoujand/task-scheduler/foundation/docker"
+	"github.com/hamidoujand/task-scheduler/business/testinfra"
 )
 
 const queueTest = "queue_test"
 
 func TestRabbitMQClient(t *testing.T) {
-	image := "rabbitmq:latest"
-
-	c, err := docker.StartContainer(image, "test_rabbitmqClient", "5672", nil, nil)
-	if err != nil {
-		t.Fatalf("expected to start rabbitmq container: %s", err)
-	}
-
-	//slow machine
-	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*2)
-	defer cancel()
-
-	//check conn
-	client, err := rabbitmq.NewClient(ctx, rabbitmq.Configs{
-		Host:     c.HostPort,
-		User:     "guest",
-		Password: "guest",
-	})
-	if err != nil {
-		t.Fatalf("expected to create a rabbitmq client: %s", err)
-	}
+	client := testinfra.NewRabbitMQ(t)
 
 	if err := client.DeclareQueue(queueTest); err != nil {
 		t.Fatalf("expected to declare queue %s: %s", queueTest, err)
@@ -72,14 +53,81 @@ func TestRabbitMQClient(t *testing.T) {
 		t.Errorf("message= %s, got %s", want, got)
 	}
 
-	t.Cleanup(func() {
-		if err := client.Close(); err != nil {
-			t.Errorf("expected to gracefully close rabbitmq: %s", err)
-		}
+	if err := delivery.Ack(false); err != nil {
+		t.Fatalf("expected to ack the delivery: %s", err)
+	}
+
+	//confirmed publish
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
 
-		//close container
-		if err := c.Stop(); err != nil {
-			t.Errorf("expected to stop the container %s: %s", c.Id, err)
-		}
+	if err := client.PublishWithConfirm(ctx, queueTest, bs); err != nil {
+		t.Fatalf("expected the broker to confirm the publish: %s", err)
+	}
+
+	delivery = <-msgs
+	if string(delivery.Body) != string(bs) {
+		t.Errorf("body= %s, got %s", bs, delivery.Body)
+	}
+
+	//stats
+	stats, err := client.QueueStats(queueTest)
+	if err != nil {
+		t.Fatalf("expected to get the stats of %s: %s", queueTest, err)
+	}
+
+	if stats.Consumers != 1 {
+		t.Errorf("consumers= %d, got %d", 1, stats.Consumers)
+	}
+
+	if _, err := client.QueueStats("missing_queue"); err == nil {
+		t.Error("expected an error for a missing queue")
+	}
+
+	//a failed passive declare must not close the channel of the client
+	if err := client.Publish(queueTest, bs); err != nil {
+		t.Fatalf("expected to publish after a failed passive declare: %s", err)
+	}
+}
+
+func TestQueuePrefixAndExchange(t *testing.T) {
+	client := testinfra.NewRabbitMQWithConfigs(t, rabbitmq.Configs{
+		QueuePrefix: "staging.",
+		Exchange:    "scheduler",
 	})
+
+	if err := client.DeclareQueue(queueTest); err != nil {
+		t.Fatalf("expected to declare queue %s: %s", queueTest, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	if err := client.PublishWithConfirm(ctx, queueTest, []byte(`{}`)); err != nil {
+		t.Fatalf("expected the broker to confirm the publish: %s", err)
+	}
+
+	msgs, err := client.Consume(queueTest, "")
+	if err != nil {
+		t.Fatalf("expected to get delivery channel: %s", err)
+	}
+
+	//the prefix never leaves the client
+	delivery := <-msgs
+	if delivery.Queue != queueTest {
+		t.Errorf("queue= %s, got %s", queueTest, delivery.Queue)
+	}
+
+	if err := delivery.Ack(); err != nil {
+		t.Fatalf("expected to ack the delivery: %s", err)
+	}
+
+	stats, err := client.QueueStats(queueTest)
+	if err != nil {
+		t.Fatalf("expected to get the stats of %s: %s", queueTest, err)
+	}
+
+	if stats.Consumers != 1 {
+		t.Errorf("consumers= %d, got %d", 1, stats.Consumers)
+	}
 }
@@ -1,49 +1,19 @@
-// Package brokertest provides stup and clean up for testing rabbitmq.
+// Package brokertest provides in-memory brokers for tests, testinfra.NewRabbitMQ provides a rabbitmq one.
 package brokertest
 
 import (
-	"context"
 	"testing"
-	"time"
 
-	"github.com/hamidoujand/task-scheduler/business/broker/rabbitmq"
-	"github.com/hamidoujand/task-scheduler/foundation/docker"
+	"github.com/hamidoujand/task-scheduler/business/broker/memory"
 )
 
-func NewTestClient(t *testing.T, ctx context.Context, containerName string) *rabbitmq.Client {
-	image := "rabbitmq:3.13.6"
-
-	c, err := docker.StartContainer(image, containerName, "5672", nil, nil)
-	if err != nil {
-		t.Fatalf("expected to start rabbitmq container: %s", err)
-	}
-
-	//slow machine
-	if _, ok := ctx.Deadline(); !ok {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(context.Background(), time.Minute*2)
-		defer cancel()
-	}
-
-	//check conn
-	client, err := rabbitmq.NewClient(ctx, rabbitmq.Configs{
-		Host:     c.HostPort,
-		User:     "guest",
-		Password: "guest",
-	})
-	if err != nil {
-		t.Fatalf("expected to create a rabbitmq client: %s", err)
-	}
-
+// NewMemoryClient returns an in-memory broker for tests that do not need rabbitmq itself, it is closed on cleanup.
+func NewMemoryClient(t *testing.T) *memory.Broker {
+	b := memory.New()
 	t.Cleanup(func() {
-		if err := client.Close(); err != nil {
-			t.Errorf("expected to gracefully close rabbitmq: %s", err)
-		}
-
-		//close container
-		if err := c.Stop(); err != nil {
-			t.Errorf("expected to stop the container %s: %s", c.Id, err)
+		if err := b.Close(); err != nil {
+			t.Errorf("expected to close the memory broker: %s", err)
 		}
 	})
-	return client
+	return b
 }
@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/hamidoujand/task-scheduler/foundation/breaker"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Pool represents the connection pool of the client, with a breaker its queries fail fast while the database is
+// down instead of waiting for a connection. Statements inside of a transaction are not guarded, beginning it is.
+type Pool struct {
+	*pgxpool.Pool
+	breaker *breaker.Breaker
+}
+
+// Exec runs the statement through the breaker.
+func (p *Pool) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	if p.breaker == nil {
+		return p.Pool.Exec(ctx, sql, args...)
+	}
+
+	if err := p.breaker.Allow(); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+
+	tag, err := p.Pool.Exec(ctx, sql, args...)
+	p.done(err)
+	return tag, err
+}
+
+// Query runs the query through the breaker.
+func (p *Pool) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if p.breaker == nil {
+		return p.Pool.Query(ctx, sql, args...)
+	}
+
+	if err := p.breaker.Allow(); err != nil {
+		return nil, err
+	}
+
+	rows, err := p.Pool.Query(ctx, sql, args...)
+	p.done(err)
+	return rows, err
+}
+
+// QueryRow runs the query through the breaker, its result is reported once the row is scanned.
+func (p *Pool) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	if p.breaker == nil {
+		return p.Pool.QueryRow(ctx, sql, args...)
+	}
+
+	if err := p.breaker.Allow(); err != nil {
+		return errRow{err: err}
+	}
+
+	return breakerRow{row: p.Pool.QueryRow(ctx, sql, args...), breaker: p.breaker}
+}
+
+// Begin starts a transaction through the breaker.
+func (p *Pool) Begin(ctx context.Context) (pgx.Tx, error) {
+	if p.breaker == nil {
+		return p.Pool.Begin(ctx)
+	}
+
+	if err := p.breaker.Allow(); err != nil {
+		return nil, err
+	}
+
+	tx, err := p.Pool.Begin(ctx)
+	p.done(err)
+	return tx, err
+}
+
+// IsOutage reports whether err points to an outage of the database instead of an error of a statement, like a
+// missing row or a violated constraint.
+func IsOutage(err error) bool {
+	if err == nil || errors.Is(err, pgx.ErrNoRows) || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return true
+	}
+
+	//connection exceptions, insufficient resources and the server shutting down
+	for _, class := range []string{"08", "53", "57P"} {
+		if strings.HasPrefix(pgErr.Code, class) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Pool) done(err error) {
+	done(p.breaker, err)
+}
+
+// done reports the result of a statement, only outages count as failures.
+func done(b *breaker.Breaker, err error) {
+	if !IsOutage(err) {
+		err = nil
+	}
+	b.Done(err)
+}
+
+type breakerRow struct {
+	row     pgx.Row
+	breaker *breaker.Breaker
+}
+
+func (r breakerRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	done(r.breaker, err)
+	return err
+}
+
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(dest ...any) error {
+	return r.err
+}
@@ -2,17 +2,18 @@ package postgres
 
 import (
 	"context"
-	"database/sql"
 	"embed"
 	"fmt"
+	"log/slog"
 	"net/url"
 	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
-
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/hamidoujand/task-scheduler/foundation/breaker"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
 )
 
 var (
@@ -27,19 +28,25 @@ type Config struct {
 	Host        string
 	Name        string
 	Schema      string
-	MaxIdleConn int
 	MaxOpenConn int
 	MaxIdleTime time.Duration
 	MaxLifeTime time.Duration
 	DisableTLS  bool
+	//SlowQuery is optional, queries taking longer than it are logged into Logger.
+	SlowQuery time.Duration
+	Logger    *slog.Logger
+	//Breaker is optional, with it queries fail fast while the database is down. Only errors IsOutage reports count
+	//as its failures.
+	Breaker *breaker.Breaker
 }
 
-// Client represents a postgres client and has some extended functionalities over database/sql.
+// Client represents a postgres client on top of a pgx connection pool, statements are prepared and cached per
+// connection by pgx.
 type Client struct {
-	DB *sql.DB
+	Pool *Pool
 }
 
-// NewClient initialize a client instance and returns it.
+// NewClient initialize a client instance and returns it, connections are established lazily.
 func NewClient(conf Config) (*Client, error) {
 	//default
 	sslMode := "required"
@@ -67,31 +74,50 @@ func NewClient(conf Config) (*Client, error) {
 		RawQuery: q.Encode(),
 	}
 
-	db, err := sql.Open("pgx", uri.String())
-
+	poolConf, err := pgxpool.ParseConfig(uri.String())
 	if err != nil {
-		return nil, fmt.Errorf("opening connection: %w", err)
+		return nil, fmt.Errorf("parsing config: %w", err)
 	}
 
 	//overwrite defaults
 
-	//default: unlimited
-	db.SetMaxOpenConns(conf.MaxOpenConn)
+	//default: greater of 4 or the number of cpus
+	if conf.MaxOpenConn > 0 {
+		poolConf.MaxConns = int32(conf.MaxOpenConn)
+	}
 
-	//default: 2
-	db.SetMaxIdleConns(conf.MaxIdleConn)
+	//default: 30 minutes
+	if conf.MaxIdleTime > 0 {
+		poolConf.MaxConnIdleTime = conf.MaxIdleTime
+	}
 
-	//default: unlimited
-	db.SetConnMaxIdleTime(conf.MaxIdleTime)
+	//default: 1 hour
+	if conf.MaxLifeTime > 0 {
+		poolConf.MaxConnLifetime = conf.MaxLifeTime
+	}
 
-	//default: unlimited
-	db.SetConnMaxLifetime(conf.MaxLifeTime)
+	if conf.SlowQuery > 0 && conf.Logger != nil {
+		poolConf.ConnConfig.Tracer = &slowQueryTracer{
+			threshold: conf.SlowQuery,
+			logger:    conf.Logger,
+		}
+	}
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConf)
+	if err != nil {
+		return nil, fmt.Errorf("opening connection: %w", err)
+	}
 
 	return &Client{
-		DB: db,
+		Pool: &Pool{Pool: pool, breaker: conf.Breaker},
 	}, nil
 }
 
+// Close closes all of the connections of the pool.
+func (c *Client) Close() {
+	c.Pool.Close()
+}
+
 // StatusCheck checks the status of the db to
 func (c *Client) StatusCheck(ctx context.Context) error {
 	//check ctx to make sure its a deadline ctx
@@ -104,7 +130,7 @@ func (c *Client) StatusCheck(ctx context.Context) error {
 
 	//with retry
 	for try := 1; ; try++ {
-		pingErr := c.DB.PingContext(ctx)
+		pingErr := c.Pool.Ping(ctx)
 
 		if pingErr == nil {
 			break
@@ -128,8 +154,8 @@ func (c *Client) StatusCheck(ctx context.Context) error {
 	var result bool
 	const q = "SELECT TRUE"
 
-	if err := c.DB.QueryRowContext(ctx, q).Scan(&result); err != nil {
-		return fmt.Errorf("queryRowContext: %w", err)
+	if err := c.Pool.QueryRow(ctx, q).Scan(&result); err != nil {
+		return fmt.Errorf("query row: %w", err)
 	}
 
 	return nil
@@ -137,7 +163,11 @@ func (c *Client) StatusCheck(ctx context.Context) error {
 
 // Migrate is going to do schema migration against client.
 func (c *Client) Migrate() error {
-	driver, err := postgres.WithInstance(c.DB, &postgres.Config{})
+	//migrate speaks database/sql, it borrows connections of the pool
+	db := stdlib.OpenDBFromPool(c.Pool.Pool)
+	defer db.Close()
+
+	driver, err := postgres.WithInstance(db, &postgres.Config{})
 
 	if err != nil {
 		return fmt.Errorf("selecting driver: %w", err)
@@ -0,0 +1 @@
+DROP TABLE task_retries;
\ No newline at end of file
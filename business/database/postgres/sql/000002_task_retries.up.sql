@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS task_retries(
+    task_id UUID PRIMARY KEY,
+    retries INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1 @@
+DROP TABLE leases;
\ No newline at end of file
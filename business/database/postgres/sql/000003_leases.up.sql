@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS leases(
+    name TEXT PRIMARY KEY,
+    owner TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,3 @@
+-- users that existed before verification are trusted.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS verified BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE users ALTER COLUMN verified SET DEFAULT FALSE;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS task_runs (
+    id UUID PRIMARY KEY,
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    status TEXT NOT NULL,
+    error_msg TEXT,
+    environment JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS task_runs_task_id_idx ON task_runs (task_id, created_at);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS user_quotas(
+    user_id UUID PRIMARY KEY,
+    max_pending INT NOT NULL,
+    max_running INT NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
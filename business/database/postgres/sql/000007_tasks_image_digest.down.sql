@@ -0,0 +1,2 @@
+ALTER TABLE tasks DROP COLUMN IF EXISTS floating_tag;
+ALTER TABLE tasks DROP COLUMN IF EXISTS image_digest;
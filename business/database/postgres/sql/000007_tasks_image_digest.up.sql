@@ -0,0 +1,2 @@
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS image_digest TEXT;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS floating_tag BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS task_outbox (
+    id UUID PRIMARY KEY,
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    queue TEXT NOT NULL,
+    payload BYTEA NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS task_outbox_created_at_idx ON task_outbox (created_at);
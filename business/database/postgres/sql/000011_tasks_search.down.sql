@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS tasks_search_idx;
+DROP TRIGGER IF EXISTS tasks_search_trigger ON tasks;
+DROP FUNCTION IF EXISTS tasks_search_update();
+ALTER TABLE tasks DROP COLUMN IF EXISTS search;
@@ -0,0 +1,21 @@
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS search TSVECTOR;
+
+CREATE OR REPLACE FUNCTION tasks_search_update() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search := to_tsvector('simple',
+        coalesce(NEW.command, '') || ' ' ||
+        coalesce(array_to_string(NEW.args, ' '), '') || ' ' ||
+        coalesce(NEW.result, '') || ' ' ||
+        coalesce(NEW.error_msg, ''));
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tasks_search_trigger
+    BEFORE INSERT OR UPDATE OF command, args, result, error_msg ON tasks
+    FOR EACH ROW EXECUTE FUNCTION tasks_search_update();
+
+-- fill the column for the existing tasks
+UPDATE tasks SET command = command;
+
+CREATE INDEX IF NOT EXISTS tasks_search_idx ON tasks USING GIN (search);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS tasks_user_id_scheduled_at_idx;
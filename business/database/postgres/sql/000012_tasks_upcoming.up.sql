@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS tasks_user_id_scheduled_at_idx ON tasks (user_id, scheduled_at) WHERE status = 'pending';
@@ -0,0 +1,3 @@
+ALTER TABLE tasks DROP COLUMN IF EXISTS queue_latency_ms;
+ALTER TABLE tasks DROP COLUMN IF EXISTS finished_at;
+ALTER TABLE tasks DROP COLUMN IF EXISTS started_at;
@@ -0,0 +1,3 @@
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS started_at TIMESTAMP;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS finished_at TIMESTAMP;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS queue_latency_ms BIGINT;
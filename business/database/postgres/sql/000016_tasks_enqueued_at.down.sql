@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS tasks_queued_enqueued_at_idx;
+ALTER TABLE tasks DROP COLUMN IF EXISTS enqueued_at;
@@ -0,0 +1,2 @@
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS enqueued_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS tasks_queued_enqueued_at_idx ON tasks (enqueued_at) WHERE status = 'queued';
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS secrets(
+    user_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    value BYTEA NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, name)
+);
@@ -0,0 +1,2 @@
+DROP TABLE execution_pause;
+DROP TABLE blackout_windows;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS blackout_windows(
+    id UUID PRIMARY KEY,
+    days INT[],
+    start_minute INT NOT NULL,
+    end_minute INT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS execution_pause(
+    id INT PRIMARY KEY CHECK (id = 1),
+    paused BOOLEAN NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS tasks_failed_updated_at_idx;
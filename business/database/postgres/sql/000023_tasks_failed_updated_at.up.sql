@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS tasks_failed_updated_at_idx ON tasks (updated_at DESC) WHERE status = 'failed';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS notification_rules_user_id_idx;
+DROP TABLE IF EXISTS notification_rules;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS notification_rules(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    statuses TEXT[] NOT NULL,
+    image TEXT NOT NULL DEFAULT '',
+    channel TEXT NOT NULL,
+    target TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS notification_rules_user_id_idx ON notification_rules (user_id);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS task_views(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    search TEXT NOT NULL DEFAULT '',
+    order_by TEXT NOT NULL,
+    rows INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL,
+    UNIQUE (user_id, name)
+);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS presets(
+    name TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    image TEXT NOT NULL,
+    environment TEXT NOT NULL DEFAULT '',
+    cpus DOUBLE PRECISION NOT NULL DEFAULT 0,
+    memory_mb INT NOT NULL DEFAULT 0,
+    pids_limit INT NOT NULL DEFAULT 0,
+    network TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS preset TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS network_mode TEXT NOT NULL DEFAULT '';
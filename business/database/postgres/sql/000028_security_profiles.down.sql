@@ -0,0 +1,2 @@
+ALTER TABLE presets DROP COLUMN IF EXISTS security;
+ALTER TABLE task_runs DROP COLUMN IF EXISTS security;
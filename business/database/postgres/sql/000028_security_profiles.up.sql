@@ -0,0 +1,2 @@
+ALTER TABLE task_runs ADD COLUMN IF NOT EXISTS security JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE presets ADD COLUMN IF NOT EXISTS security JSONB;
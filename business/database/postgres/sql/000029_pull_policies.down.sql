@@ -0,0 +1,2 @@
+ALTER TABLE tasks DROP COLUMN IF EXISTS error_category;
+ALTER TABLE tasks DROP COLUMN IF EXISTS pull_policy;
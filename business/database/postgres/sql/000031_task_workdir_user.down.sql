@@ -0,0 +1,2 @@
+ALTER TABLE tasks DROP COLUMN IF EXISTS run_as_user;
+ALTER TABLE tasks DROP COLUMN IF EXISTS work_dir;
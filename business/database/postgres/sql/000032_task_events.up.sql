@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS task_events (
+    id UUID PRIMARY KEY,
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    message TEXT,
+    source TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS task_events_task_id_idx ON task_events (task_id, created_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS tasks_user_id_dedup_key_idx;
+ALTER TABLE tasks DROP COLUMN IF EXISTS dedup_key;
@@ -0,0 +1,2 @@
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS dedup_key TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS tasks_user_id_dedup_key_idx ON tasks (user_id, dedup_key, created_at) WHERE dedup_key <> '';
@@ -0,0 +1,31 @@
+ALTER TABLE tasks RENAME TO tasks_partitioned;
+ALTER TABLE tasks_partitioned RENAME CONSTRAINT tasks_pkey TO tasks_partitioned_pkey;
+DROP TRIGGER IF EXISTS tasks_search_trigger ON tasks_partitioned;
+DROP TRIGGER IF EXISTS tasks_delete_trigger ON tasks_partitioned;
+DROP FUNCTION IF EXISTS tasks_delete_children();
+
+CREATE TABLE tasks (LIKE tasks_partitioned INCLUDING DEFAULTS INCLUDING CONSTRAINTS);
+ALTER TABLE tasks ADD PRIMARY KEY (id);
+
+INSERT INTO tasks SELECT * FROM tasks_partitioned;
+DROP TABLE tasks_partitioned;
+
+CREATE TRIGGER tasks_search_trigger
+    BEFORE INSERT OR UPDATE OF command, args, result, error_msg ON tasks
+    FOR EACH ROW EXECUTE FUNCTION tasks_search_update();
+
+CREATE INDEX IF NOT EXISTS tasks_user_id_created_at_idx ON tasks (user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS tasks_search_idx ON tasks USING GIN (search);
+CREATE INDEX IF NOT EXISTS tasks_user_id_scheduled_at_idx ON tasks (user_id, scheduled_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS tasks_queued_enqueued_at_idx ON tasks (enqueued_at) WHERE status = 'queued';
+CREATE INDEX IF NOT EXISTS tasks_failed_updated_at_idx ON tasks (updated_at DESC) WHERE status = 'failed';
+CREATE INDEX IF NOT EXISTS tasks_user_id_dedup_key_idx ON tasks (user_id, dedup_key, created_at) WHERE dedup_key <> '';
+
+-- the dropped partitions left runs, events and outbox messages of tasks that do not exist anymore behind
+DELETE FROM task_runs WHERE task_id NOT IN (SELECT id FROM tasks);
+DELETE FROM task_events WHERE task_id NOT IN (SELECT id FROM tasks);
+DELETE FROM task_outbox WHERE task_id NOT IN (SELECT id FROM tasks);
+
+ALTER TABLE task_runs ADD CONSTRAINT task_runs_task_id_fkey FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE;
+ALTER TABLE task_outbox ADD CONSTRAINT task_outbox_task_id_fkey FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE;
+ALTER TABLE task_events ADD CONSTRAINT task_events_task_id_fkey FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE;
@@ -0,0 +1,54 @@
+-- tasks are partitioned by the month of created_at, so the primary key includes it and other tables can not reference
+-- it anymore. The runs, events and outbox messages of a deleted task are deleted by a trigger instead.
+ALTER TABLE task_runs DROP CONSTRAINT IF EXISTS task_runs_task_id_fkey;
+ALTER TABLE task_outbox DROP CONSTRAINT IF EXISTS task_outbox_task_id_fkey;
+ALTER TABLE task_events DROP CONSTRAINT IF EXISTS task_events_task_id_fkey;
+
+ALTER TABLE tasks RENAME TO tasks_unpartitioned;
+ALTER TABLE tasks_unpartitioned RENAME CONSTRAINT tasks_pkey TO tasks_unpartitioned_pkey;
+DROP TRIGGER IF EXISTS tasks_search_trigger ON tasks_unpartitioned;
+
+CREATE TABLE tasks (LIKE tasks_unpartitioned INCLUDING DEFAULTS INCLUDING CONSTRAINTS) PARTITION BY RANGE (created_at);
+ALTER TABLE tasks ADD PRIMARY KEY (id, created_at);
+
+-- keeps the tasks of months without a partition, the janitor creates the partitions ahead of time
+CREATE TABLE IF NOT EXISTS tasks_default PARTITION OF tasks DEFAULT;
+
+DO $$
+DECLARE
+    part_start TIMESTAMP := date_trunc('month', coalesce((SELECT min(created_at) FROM tasks_unpartitioned), now()::TIMESTAMP));
+BEGIN
+    WHILE part_start <= date_trunc('month', now()::TIMESTAMP) + INTERVAL '2 months' LOOP
+        EXECUTE format('CREATE TABLE IF NOT EXISTS %I PARTITION OF tasks FOR VALUES FROM (%L) TO (%L)',
+            'tasks_' || to_char(part_start, 'YYYYMM'), part_start, part_start + INTERVAL '1 month');
+        part_start := part_start + INTERVAL '1 month';
+    END LOOP;
+END
+$$;
+
+INSERT INTO tasks SELECT * FROM tasks_unpartitioned;
+DROP TABLE tasks_unpartitioned;
+
+CREATE TRIGGER tasks_search_trigger
+    BEFORE INSERT OR UPDATE OF command, args, result, error_msg ON tasks
+    FOR EACH ROW EXECUTE FUNCTION tasks_search_update();
+
+CREATE OR REPLACE FUNCTION tasks_delete_children() RETURNS TRIGGER AS $$
+BEGIN
+    DELETE FROM task_runs WHERE task_id = OLD.id;
+    DELETE FROM task_events WHERE task_id = OLD.id;
+    DELETE FROM task_outbox WHERE task_id = OLD.id;
+    RETURN OLD;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tasks_delete_trigger
+    AFTER DELETE ON tasks
+    FOR EACH ROW EXECUTE FUNCTION tasks_delete_children();
+
+CREATE INDEX IF NOT EXISTS tasks_user_id_created_at_idx ON tasks (user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS tasks_search_idx ON tasks USING GIN (search);
+CREATE INDEX IF NOT EXISTS tasks_user_id_scheduled_at_idx ON tasks (user_id, scheduled_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS tasks_queued_enqueued_at_idx ON tasks (enqueued_at) WHERE status = 'queued';
+CREATE INDEX IF NOT EXISTS tasks_failed_updated_at_idx ON tasks (updated_at DESC) WHERE status = 'failed';
+CREATE INDEX IF NOT EXISTS tasks_user_id_dedup_key_idx ON tasks (user_id, dedup_key, created_at) WHERE dedup_key <> '';
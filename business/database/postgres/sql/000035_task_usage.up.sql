@@ -0,0 +1,13 @@
+-- usage outlives its task, it is what the executions are billed by
+CREATE TABLE IF NOT EXISTS task_usage (
+    run_id UUID PRIMARY KEY,
+    task_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    cpu_ms BIGINT NOT NULL,
+    memory_peak_bytes BIGINT NOT NULL,
+    duration_ms BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS task_usage_user_id_created_at_idx ON task_usage (user_id, created_at);
+CREATE INDEX IF NOT EXISTS task_usage_created_at_idx ON task_usage (created_at);
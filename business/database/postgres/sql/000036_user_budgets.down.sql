@@ -0,0 +1,2 @@
+DROP TABLE usage_consumption;
+DROP TABLE user_budgets;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS user_budgets(
+    user_id UUID PRIMARY KEY,
+    limit_ms BIGINT NOT NULL,
+    hard BOOLEAN NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- the execution time of every user per month, it is increased along with every usage record
+CREATE TABLE IF NOT EXISTS usage_consumption(
+    user_id UUID NOT NULL,
+    month TIMESTAMP NOT NULL,
+    duration_ms BIGINT NOT NULL,
+    PRIMARY KEY (user_id, month)
+);
+
+INSERT INTO usage_consumption (user_id, month, duration_ms)
+SELECT user_id, date_trunc('month', created_at), sum(duration_ms) FROM task_usage GROUP BY 1, 2;
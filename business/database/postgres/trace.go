@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type ctxKey int
+
+const (
+	opKey ctxKey = iota + 1
+	queryStartKey
+)
+
+// WithOp names the queries that run with the returned context after op, slow queries are logged with it so they can
+// be traced back to their caller.
+func WithOp(ctx context.Context, op string) context.Context {
+	return context.WithValue(ctx, opKey, op)
+}
+
+// Op returns the name set by WithOp.
+func Op(ctx context.Context) string {
+	op, _ := ctx.Value(opKey).(string)
+	return op
+}
+
+// PoolStats represents the state of the connection pool.
+type PoolStats struct {
+	InUse int32 `json:"inUse"`
+	Idle  int32 `json:"idle"`
+	Total int32 `json:"total"`
+	Max   int32 `json:"max"`
+	//WaitCount is how many acquires had to wait for a connection and WaitMs how long they waited in total.
+	WaitCount int64 `json:"waitCount"`
+	WaitMs    int64 `json:"waitMs"`
+}
+
+// Stats returns the state of the connection pool.
+func (c *Client) Stats() PoolStats {
+	st := c.Pool.Stat()
+	return PoolStats{
+		InUse:     st.AcquiredConns(),
+		Idle:      st.IdleConns(),
+		Total:     st.TotalConns(),
+		Max:       st.MaxConns(),
+		WaitCount: st.EmptyAcquireCount(),
+		WaitMs:    st.AcquireDuration().Milliseconds(),
+	}
+}
+
+// slowQueryTracer logs the queries that take longer than threshold.
+type slowQueryTracer struct {
+	threshold time.Duration
+	logger    *slog.Logger
+}
+
+type queryStart struct {
+	sql       string
+	startedAt time.Time
+}
+
+func (t *slowQueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryStartKey, queryStart{sql: data.SQL, startedAt: time.Now()})
+}
+
+func (t *slowQueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(queryStartKey).(queryStart)
+	if !ok {
+		return
+	}
+
+	took := time.Since(start.startedAt)
+	if took < t.threshold {
+		return
+	}
+
+	//queries are indented inside of the repositories, keep them on a single line
+	query := strings.Join(strings.Fields(start.sql), " ")
+
+	args := []any{"op", Op(ctx), "took", took, "query", query}
+	if data.Err != nil {
+		args = append(args, "msg", data.Err)
+	}
+	t.logger.Warn("slow query", args...)
+}
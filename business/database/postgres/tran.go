@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// DB is satisfied by both *Pool and pgx.Tx, repositories run their queries against it so they behave the
+// same inside and outside of a transaction.
+type DB interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// WithinTran runs fn inside of a transaction, the transaction is committed when fn returns nil and rolled back
+// otherwise.
+func (c *Client) WithinTran(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	return withinTran(ctx, c.Pool, fn)
+}
+
+// InTran runs fn inside of db when it already is a transaction and inside of a new one otherwise, repositories use
+// it for statements that must be atomic so they join the transaction they were created with.
+func InTran(ctx context.Context, db DB, fn func(tx pgx.Tx) error) error {
+	switch db := db.(type) {
+	case pgx.Tx:
+		return fn(db)
+	case *Pool:
+		return withinTran(ctx, db, fn)
+	default:
+		return fmt.Errorf("transactions are not supported by %T", db)
+	}
+}
+
+func withinTran(ctx context.Context, pool *Pool, fn func(tx pgx.Tx) error) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(ctx); rbErr != nil && !errors.Is(rbErr, pgx.ErrTxClosed) {
+			return fmt.Errorf("rollback: %w: %w", rbErr, err)
+		}
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// NoRows maps pgx.ErrNoRows into sql.ErrNoRows, the error every store reports a missing record with, so the domains
+// and their in-memory stores do not depend on pgx.
+func NoRows(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return sql.ErrNoRows
+	}
+	return err
+}
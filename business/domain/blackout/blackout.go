@@ -0,0 +1,234 @@
+// Package blackout provides the maintenance windows and the global pause during which no task is executed.
+package blackout
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidWindow  = errors.New("window start and end must be different times of the day")
+	ErrWindowNotFound = errors.New("blackout window not found")
+)
+
+// cacheTTL is how long the windows and the pause are served from memory before they are read again, other instances
+// notice a change after it.
+const cacheTTL = time.Second * 5
+
+// pauseRecheck is how long executions are deferred at a time while execution is paused since a pause has no end.
+const pauseRecheck = time.Second * 30
+
+// store represents the storage of windows and of the pause, GetPause and DeleteWindow return sql.ErrNoRows when
+// there is no record.
+type store interface {
+	CreateWindow(ctx context.Context, w Window) error
+	GetWindows(ctx context.Context) ([]Window, error)
+	DeleteWindow(ctx context.Context, id uuid.UUID) error
+	GetPause(ctx context.Context) (Pause, error)
+	SetPause(ctx context.Context, p Pause) error
+}
+
+// Window represents a recurring period in UTC during which no task starts. Start and End are offsets from midnight
+// and an End before Start crosses midnight. Days are the days the window starts on, every day when empty.
+type Window struct {
+	Id        uuid.UUID
+	Days      []time.Weekday
+	Start     time.Duration
+	End       time.Duration
+	Reason    string
+	CreatedAt time.Time
+}
+
+// NewWindow represents the data required for creating a window.
+type NewWindow struct {
+	Days   []time.Weekday
+	Start  time.Duration
+	End    time.Duration
+	Reason string
+}
+
+// Pause represents the global pause of all executions.
+type Pause struct {
+	Paused    bool
+	Reason    string
+	UpdatedAt time.Time
+}
+
+// ActiveUntil reports whether t falls into an occurrence of the window and when that occurrence ends.
+func (w Window) ActiveUntil(t time.Time) (time.Time, bool) {
+	t = t.UTC()
+	length := w.End - w.Start
+	if length <= 0 {
+		length += time.Hour * 24
+	}
+
+	//the occurrence that started today or the one that started yesterday and crosses midnight
+	today := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	for _, day := range [...]time.Time{today, today.AddDate(0, 0, -1)} {
+		if len(w.Days) > 0 && !slices.Contains(w.Days, day.Weekday()) {
+			continue
+		}
+
+		from := day.Add(w.Start)
+		to := from.Add(length)
+		if !t.Before(from) && t.Before(to) {
+			return to, true
+		}
+	}
+	return time.Time{}, false
+}
+
+type snapshot struct {
+	windows []Window
+	pause   Pause
+	readAt  time.Time
+}
+
+// Service represents set of APIs for managing blackouts.
+type Service struct {
+	store store
+
+	mu     sync.Mutex
+	cached *snapshot
+}
+
+// NewService creates a blackout service.
+func NewService(store store) *Service {
+	return &Service{
+		store: store,
+	}
+}
+
+// CreateWindow creates a window, executions that fall into it are deferred from the next read of the windows on.
+func (s *Service) CreateWindow(ctx context.Context, nw NewWindow) (Window, error) {
+	day := time.Hour * 24
+	if nw.Start < 0 || nw.Start >= day || nw.End < 0 || nw.End >= day || nw.Start == nw.End {
+		return Window{}, ErrInvalidWindow
+	}
+
+	w := Window{
+		Id:        uuid.New(),
+		Days:      nw.Days,
+		Start:     nw.Start,
+		End:       nw.End,
+		Reason:    nw.Reason,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.store.CreateWindow(ctx, w); err != nil {
+		return Window{}, fmt.Errorf("create window: %w", err)
+	}
+
+	s.invalidate()
+	return w, nil
+}
+
+// GetWindows returns all of the windows.
+func (s *Service) GetWindows(ctx context.Context) ([]Window, error) {
+	windows, err := s.store.GetWindows(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get windows: %w", err)
+	}
+	return windows, nil
+}
+
+// DeleteWindow deletes the window, returns ErrWindowNotFound when there is no window with the id.
+func (s *Service) DeleteWindow(ctx context.Context, id uuid.UUID) error {
+	if err := s.store.DeleteWindow(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrWindowNotFound
+		}
+		return fmt.Errorf("delete window: %w", err)
+	}
+
+	s.invalidate()
+	return nil
+}
+
+// GetPause returns the global pause, execution is not paused when it was never set.
+func (s *Service) GetPause(ctx context.Context) (Pause, error) {
+	p, err := s.store.GetPause(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Pause{}, nil
+		}
+		return Pause{}, fmt.Errorf("get pause: %w", err)
+	}
+	return p, nil
+}
+
+// SetPause pauses or resumes the execution of all tasks across all instances.
+func (s *Service) SetPause(ctx context.Context, paused bool, reason string) (Pause, error) {
+	p := Pause{
+		Paused:    paused,
+		Reason:    reason,
+		UpdatedAt: time.Now(),
+	}
+
+	if err := s.store.SetPause(ctx, p); err != nil {
+		return Pause{}, fmt.Errorf("set pause: %w", err)
+	}
+
+	s.invalidate()
+	return p, nil
+}
+
+// Until reports whether executions are blacked out at now and until when, while execution is paused it is a short
+// while after now since a pause has no end.
+func (s *Service) Until(ctx context.Context, now time.Time) (time.Time, bool, error) {
+	snap, err := s.snapshot(ctx)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	if snap.pause.Paused {
+		return now.Add(pauseRecheck), true, nil
+	}
+
+	var until time.Time
+	for _, w := range snap.windows {
+		if end, ok := w.ActiveUntil(now); ok && end.After(until) {
+			until = end
+		}
+	}
+	return until, !until.IsZero(), nil
+}
+
+func (s *Service) snapshot(ctx context.Context) (*snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached != nil && time.Since(s.cached.readAt) < cacheTTL {
+		return s.cached, nil
+	}
+
+	windows, err := s.GetWindows(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pause, err := s.GetPause(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cached = &snapshot{
+		windows: windows,
+		pause:   pause,
+		readAt:  time.Now(),
+	}
+	return s.cached, nil
+}
+
+func (s *Service) invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cached = nil
+}
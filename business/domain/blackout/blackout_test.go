@@ -0,0 +1,132 @@
+package blackout_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/domain/blackout"
+	"github.com/hamidoujand/task-scheduler/business/domain/blackout/store/memory"
+)
+
+func TestActiveUntil(t *testing.T) {
+	t.Parallel()
+
+	sundays := blackout.Window{
+		Days:  []time.Weekday{time.Sunday},
+		Start: time.Hour * 2,
+		End:   time.Hour * 3,
+	}
+
+	nightly := blackout.Window{
+		Start: time.Hour * 23,
+		End:   time.Hour * 1,
+	}
+
+	//2024-06-02 is a sunday
+	tests := map[string]struct {
+		window blackout.Window
+		at     time.Time
+		active bool
+		until  time.Time
+	}{
+		"inside of the window": {
+			window: sundays,
+			at:     time.Date(2024, 6, 2, 2, 30, 0, 0, time.UTC),
+			active: true,
+			until:  time.Date(2024, 6, 2, 3, 0, 0, 0, time.UTC),
+		},
+		"at the end of the window": {
+			window: sundays,
+			at:     time.Date(2024, 6, 2, 3, 0, 0, 0, time.UTC),
+		},
+		"another day": {
+			window: sundays,
+			at:     time.Date(2024, 6, 3, 2, 30, 0, 0, time.UTC),
+		},
+		"before midnight": {
+			window: nightly,
+			at:     time.Date(2024, 6, 2, 23, 30, 0, 0, time.UTC),
+			active: true,
+			until:  time.Date(2024, 6, 3, 1, 0, 0, 0, time.UTC),
+		},
+		"after midnight": {
+			window: nightly,
+			at:     time.Date(2024, 6, 3, 0, 30, 0, 0, time.UTC),
+			active: true,
+			until:  time.Date(2024, 6, 3, 1, 0, 0, 0, time.UTC),
+		},
+		"other time zone": {
+			window: sundays,
+			at:     time.Date(2024, 6, 2, 4, 30, 0, 0, time.FixedZone("CEST", 2*60*60)),
+			active: true,
+			until:  time.Date(2024, 6, 2, 3, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			until, active := test.window.ActiveUntil(test.at)
+			if active != test.active {
+				t.Fatalf("active= %t, got %t", test.active, active)
+			}
+
+			if !until.Equal(test.until) {
+				t.Errorf("until= %s, got %s", test.until, until)
+			}
+		})
+	}
+}
+
+func TestUntil(t *testing.T) {
+	t.Parallel()
+
+	repo := memory.Repository{}
+	service := blackout.NewService(&repo)
+	ctx := context.Background()
+
+	//the window ends at midnight, which is the start of the next day
+	now := time.Date(2024, 6, 3, 23, 30, 0, 0, time.UTC)
+
+	w, err := service.CreateWindow(ctx, blackout.NewWindow{
+		Start:  time.Hour * 23,
+		End:    0,
+		Reason: "maintenance",
+	})
+	if err != nil {
+		t.Fatalf("expected to create the window: %s", err)
+	}
+
+	until, ok, err := service.Until(ctx, now)
+	if err != nil {
+		t.Fatalf("expected to check the blackouts: %s", err)
+	}
+	if midnight := time.Date(2024, 6, 4, 0, 0, 0, 0, time.UTC); !ok || !until.Equal(midnight) {
+		t.Errorf("expected to be blacked out until %s, got %t until %s", midnight, ok, until)
+	}
+
+	if err := service.DeleteWindow(ctx, w.Id); err != nil {
+		t.Fatalf("expected to delete the window: %s", err)
+	}
+
+	if _, ok, _ := service.Until(ctx, now); ok {
+		t.Error("expected no blackout after the window was deleted")
+	}
+
+	if _, err := service.SetPause(ctx, true, "incident"); err != nil {
+		t.Fatalf("expected to pause: %s", err)
+	}
+
+	if _, ok, _ := service.Until(ctx, now); !ok {
+		t.Error("expected a blackout while paused")
+	}
+
+	if err := service.DeleteWindow(ctx, w.Id); !errors.Is(err, blackout.ErrWindowNotFound) {
+		t.Errorf("err= %v, got %v", blackout.ErrWindowNotFound, err)
+	}
+
+	if _, err := service.CreateWindow(ctx, blackout.NewWindow{Start: time.Hour, End: time.Hour}); !errors.Is(err, blackout.ErrInvalidWindow) {
+		t.Errorf("err= %v, got %v", blackout.ErrInvalidWindow, err)
+	}
+}
@@ -0,0 +1,71 @@
+// Package memory provides an in memory repository used for testing.
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/blackout"
+)
+
+// Repository represents an in-memory storage for testing.
+type Repository struct {
+	Windows []blackout.Window
+	Pause   *blackout.Pause
+	mu      sync.Mutex
+}
+
+// CreateWindow stores the window.
+func (r *Repository) CreateWindow(ctx context.Context, w blackout.Window) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Windows = append(r.Windows, w)
+	return nil
+}
+
+// GetWindows returns all of the windows.
+func (r *Repository) GetWindows(ctx context.Context) ([]blackout.Window, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	windows := make([]blackout.Window, len(r.Windows))
+	copy(windows, r.Windows)
+	return windows, nil
+}
+
+// DeleteWindow deletes the window or returns sql.ErrNoRows.
+func (r *Repository) DeleteWindow(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, w := range r.Windows {
+		if w.Id == id {
+			r.Windows = append(r.Windows[:i], r.Windows[i+1:]...)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+// GetPause returns the pause or sql.ErrNoRows when it was never set.
+func (r *Repository) GetPause(ctx context.Context) (blackout.Pause, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Pause == nil {
+		return blackout.Pause{}, sql.ErrNoRows
+	}
+	return *r.Pause, nil
+}
+
+// SetPause replaces the pause.
+func (r *Repository) SetPause(ctx context.Context, p blackout.Pause) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Pause = &p
+	return nil
+}
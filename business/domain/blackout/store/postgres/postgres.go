@@ -0,0 +1,140 @@
+// Package postgres provides the blackout storage on top of postgres.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/database/postgres"
+	"github.com/hamidoujand/task-scheduler/business/domain/blackout"
+)
+
+// Repository represents all of the APIs used for CRUD against postgres.
+type Repository struct {
+	client *postgres.Client
+}
+
+// NewRepository creates a new postgres repository.
+func NewRepository(client *postgres.Client) *Repository {
+	return &Repository{
+		client: client,
+	}
+}
+
+// CreateWindow stores the window, its start and end are stored with minute precision.
+func (r *Repository) CreateWindow(ctx context.Context, w blackout.Window) error {
+	const q = `
+	INSERT INTO blackout_windows
+		(id,days,start_minute,end_minute,reason,created_at)
+	VALUES
+		($1,$2,$3,$4,$5,$6)
+	`
+
+	var days []int
+	for _, d := range w.Days {
+		days = append(days, int(d))
+	}
+
+	if _, err := r.client.Pool.Exec(ctx, q,
+		w.Id,
+		days,
+		int(w.Start/time.Minute),
+		int(w.End/time.Minute),
+		w.Reason,
+		w.CreatedAt.UTC(),
+	); err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+	return nil
+}
+
+// GetWindows returns all of the windows in the order they were created.
+func (r *Repository) GetWindows(ctx context.Context) ([]blackout.Window, error) {
+	const q = `
+	SELECT
+		id,days,start_minute,end_minute,reason,created_at
+	FROM blackout_windows
+	ORDER BY created_at
+	`
+
+	rows, err := r.client.Pool.Query(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	var windows []blackout.Window
+	for rows.Next() {
+		var w blackout.Window
+		var days []int
+		var start, end int
+
+		if err := rows.Scan(&w.Id, &days, &start, &end, &w.Reason, &w.CreatedAt); err != nil {
+			return nil, fmt.Errorf("rows scan: %w", err)
+		}
+
+		for _, d := range days {
+			w.Days = append(w.Days, time.Weekday(d))
+		}
+
+		w.Start = time.Duration(start) * time.Minute
+		w.End = time.Duration(end) * time.Minute
+		w.CreatedAt = w.CreatedAt.In(time.Local)
+		windows = append(windows, w)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+	return windows, nil
+}
+
+// DeleteWindow deletes the window, returns sql.ErrNoRows when there is no record.
+func (r *Repository) DeleteWindow(ctx context.Context, id uuid.UUID) error {
+	const q = `DELETE FROM blackout_windows WHERE id = $1`
+
+	tag, err := r.client.Pool.Exec(ctx, q, id)
+	if err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetPause returns the pause, returns sql.ErrNoRows when it was never set.
+func (r *Repository) GetPause(ctx context.Context) (blackout.Pause, error) {
+	const q = `SELECT paused,reason,updated_at FROM execution_pause WHERE id = 1`
+
+	var p blackout.Pause
+	if err := r.client.Pool.QueryRow(ctx, q).Scan(&p.Paused, &p.Reason, &p.UpdatedAt); err != nil {
+		return blackout.Pause{}, fmt.Errorf("row scan: %w", postgres.NoRows(err))
+	}
+
+	p.UpdatedAt = p.UpdatedAt.In(time.Local)
+	return p, nil
+}
+
+// SetPause creates or replaces the pause.
+func (r *Repository) SetPause(ctx context.Context, p blackout.Pause) error {
+	const q = `
+	INSERT INTO execution_pause
+		(id,paused,reason,updated_at)
+	VALUES
+		(1,$1,$2,$3)
+	ON CONFLICT (id) DO UPDATE SET
+		paused = EXCLUDED.paused,
+		reason = EXCLUDED.reason,
+		updated_at = EXCLUDED.updated_at
+	`
+
+	if _, err := r.client.Pool.Exec(ctx, q, p.Paused, p.Reason, p.UpdatedAt.UTC()); err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/foundation/webhook"
+)
+
+// emailSender sends the notifications as plain text emails.
+type emailSender struct {
+	mailer mailer
+}
+
+func (s emailSender) Send(ctx context.Context, target string, e Event) error {
+	to, err := mail.ParseAddress(target)
+	if err != nil {
+		return fmt.Errorf("parse address: %w", err)
+	}
+
+	if err := s.mailer.Send(ctx, *to, subject(e), text(e)); err != nil {
+		return fmt.Errorf("send: %w", err)
+	}
+	return nil
+}
+
+// slackSender posts the notifications to slack incoming webhooks.
+type slackSender struct {
+	timeout time.Duration
+}
+
+func (s slackSender) Send(ctx context.Context, target string, e Event) error {
+	hook, err := webhook.New(target, s.timeout)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+
+	payload := struct {
+		Text string `json:"text"`
+	}{
+		Text: subject(e) + "\n" + text(e),
+	}
+
+	if err := hook.Post(ctx, payload); err != nil {
+		return fmt.Errorf("post: %w", err)
+	}
+	return nil
+}
+
+func subject(e Event) string {
+	return fmt.Sprintf("Task %s %s", e.TaskId, e.Status)
+}
+
+func text(e Event) string {
+	msg := fmt.Sprintf("Task: %s\nImage: %s\nCommand: %s\nStatus: %s\nFinished at: %s\n",
+		e.TaskId, e.Image, e.Command, e.Status, e.FinishedAt.UTC().Format(time.RFC3339))
+
+	if e.ErrMessage != "" {
+		msg += "Error: " + e.ErrMessage + "\n"
+	}
+	return msg
+}
@@ -0,0 +1,351 @@
+// Package notification provides the rules users are notified by once their tasks finish, every finished task that
+// matches a rule of its owner is sent to the channel of the rule.
+package notification
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/broker"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+// EventsQueue receives an event for every task that finished.
+const EventsQueue = "queue_task_events"
+
+// maxRules is how many rules a user may have.
+const maxRules = 20
+
+// sendAttempts is how many times a notification is sent before it is given up on, the attempts are backoff apart
+// and the backoff doubles after each of them.
+const (
+	sendAttempts = 3
+	sendBackoff  = time.Second
+)
+
+var (
+	ErrRuleNotFound    = errors.New("notification rule not found")
+	ErrTooManyRules    = fmt.Errorf("a user can have at most %d notification rules", maxRules)
+	ErrChannelDisabled = errors.New("notification channel is not configured")
+	ErrInvalidTarget   = errors.New("target must be an email address for email and an https url for slack")
+	ErrInvalidStatus   = errors.New("rules can only match failed or completed tasks")
+)
+
+// Channel represents where a notification is sent to.
+type Channel string
+
+const (
+	ChannelEmail Channel = "email"
+	ChannelSlack Channel = "slack"
+)
+
+// store represents the storage of rules, Delete returns sql.ErrNoRows when the user has no rule with the id.
+type store interface {
+	Create(ctx context.Context, r Rule) error
+	GetByUserId(ctx context.Context, userId uuid.UUID) ([]Rule, error)
+	Delete(ctx context.Context, userId uuid.UUID, id uuid.UUID) error
+}
+
+// sender delivers a notification through a channel.
+type sender interface {
+	Send(ctx context.Context, target string, e Event) error
+}
+
+// mailer represents the smtp client emails are sent through.
+type mailer interface {
+	Send(ctx context.Context, to mail.Address, subject string, body string) error
+}
+
+// Rule represents a rule of a user, the finished tasks of the user with one of Statuses are sent to Target through
+// Channel. Image limits the rule to the tasks of an image, its tag is ignored.
+type Rule struct {
+	Id        uuid.UUID
+	UserId    uuid.UUID
+	Statuses  []task.Status
+	Image     string
+	Channel   Channel
+	Target    string
+	CreatedAt time.Time
+}
+
+// NewRule represents the data required for creating a rule.
+type NewRule struct {
+	UserId   uuid.UUID
+	Statuses []task.Status
+	Image    string
+	Channel  Channel
+	Target   string
+}
+
+// Event represents a task that finished.
+type Event struct {
+	TaskId     uuid.UUID   `json:"taskId"`
+	UserId     uuid.UUID   `json:"userId"`
+	Image      string      `json:"image"`
+	Command    string      `json:"command"`
+	Status     task.Status `json:"status"`
+	ErrMessage string      `json:"errMessage,omitempty"`
+	FinishedAt time.Time   `json:"finishedAt"`
+}
+
+// Matches reports whether the event is one the rule notifies about.
+func (r Rule) Matches(e Event) bool {
+	if r.UserId != e.UserId || !slices.Contains(r.Statuses, e.Status) {
+		return false
+	}
+	return r.Image == "" || imageName(r.Image) == imageName(e.Image)
+}
+
+// Service represents set of APIs for managing rules and sending notifications.
+type Service struct {
+	store       store
+	broker      broker.Broker
+	logger      *slog.Logger
+	senders     map[Channel]sender
+	consumerTag string
+}
+
+// NewService creates a notification service, channels are enabled with EnableEmail and EnableSlack.
+func NewService(store store, b broker.Broker, logger *slog.Logger) (*Service, error) {
+	if err := b.DeclareQueue(EventsQueue); err != nil {
+		return nil, fmt.Errorf("declare queue: %w", err)
+	}
+
+	return &Service{
+		store:       store,
+		broker:      b,
+		logger:      logger,
+		senders:     make(map[Channel]sender),
+		consumerTag: "notifier-" + uuid.NewString(),
+	}, nil
+}
+
+// EnableEmail sends the notifications of email rules through m.
+func (s *Service) EnableEmail(m mailer) {
+	s.senders[ChannelEmail] = emailSender{mailer: m}
+}
+
+// EnableSlack posts the notifications of slack rules to their incoming webhooks, posts time out after timeout.
+func (s *Service) EnableSlack(timeout time.Duration) {
+	s.senders[ChannelSlack] = slackSender{timeout: timeout}
+}
+
+// CreateRule creates a rule for the user.
+func (s *Service) CreateRule(ctx context.Context, nr NewRule) (Rule, error) {
+	if _, ok := s.senders[nr.Channel]; !ok {
+		return Rule{}, ErrChannelDisabled
+	}
+
+	if err := validTarget(nr.Channel, nr.Target); err != nil {
+		return Rule{}, err
+	}
+
+	if len(nr.Statuses) == 0 {
+		return Rule{}, ErrInvalidStatus
+	}
+
+	//only the scheduler reports finished tasks
+	for _, status := range nr.Statuses {
+		if status != task.StatusFailed && status != task.StatusCompleted {
+			return Rule{}, ErrInvalidStatus
+		}
+	}
+
+	rules, err := s.store.GetByUserId(ctx, nr.UserId)
+	if err != nil {
+		return Rule{}, fmt.Errorf("get by user id: %w", err)
+	}
+
+	if len(rules) >= maxRules {
+		return Rule{}, ErrTooManyRules
+	}
+
+	rule := Rule{
+		Id:        uuid.New(),
+		UserId:    nr.UserId,
+		Statuses:  nr.Statuses,
+		Image:     nr.Image,
+		Channel:   nr.Channel,
+		Target:    nr.Target,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.store.Create(ctx, rule); err != nil {
+		return Rule{}, fmt.Errorf("create: %w", err)
+	}
+	return rule, nil
+}
+
+// Rules returns the rules of the user in the order they were created.
+func (s *Service) Rules(ctx context.Context, userId uuid.UUID) ([]Rule, error) {
+	rules, err := s.store.GetByUserId(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("get by user id: %w", err)
+	}
+	return rules, nil
+}
+
+// DeleteRule deletes the rule of the user, returns ErrRuleNotFound when the user has no rule with the id.
+func (s *Service) DeleteRule(ctx context.Context, userId uuid.UUID, id uuid.UUID) error {
+	if err := s.store.Delete(ctx, userId, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRuleNotFound
+		}
+		return fmt.Errorf("delete: %w", err)
+	}
+	return nil
+}
+
+// TaskFinished publishes the event of the task, the rules are evaluated by the consumer of the events so the caller
+// does not wait for the notifications.
+func (s *Service) TaskFinished(ctx context.Context, tsk task.Task) error {
+	e := Event{
+		TaskId:     tsk.Id,
+		UserId:     tsk.UserId,
+		Image:      tsk.Image,
+		Command:    tsk.Command,
+		Status:     tsk.Status,
+		ErrMessage: tsk.ErrMessage,
+		FinishedAt: tsk.FinishedAt,
+	}
+
+	if e.FinishedAt.IsZero() {
+		e.FinishedAt = time.Now()
+	}
+
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	if err := s.broker.PublishWithConfirm(ctx, EventsQueue, body); err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+	return nil
+}
+
+// Notify sends the event through every rule of its owner that matches it, a failed send is retried before it is
+// reported.
+func (s *Service) Notify(ctx context.Context, e Event) error {
+	rules, err := s.store.GetByUserId(ctx, e.UserId)
+	if err != nil {
+		return fmt.Errorf("get by user id: %w", err)
+	}
+
+	var errs []error
+	for _, rule := range rules {
+		if !rule.Matches(e) {
+			continue
+		}
+
+		sender, ok := s.senders[rule.Channel]
+		if !ok {
+			//the channel was disabled after the rule was created
+			continue
+		}
+
+		if err := s.send(ctx, sender, rule.Target, e); err != nil {
+			errs = append(errs, fmt.Errorf("rule %s: %w", rule.Id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Service) send(ctx context.Context, sender sender, target string, e Event) error {
+	backoff := sendBackoff
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = sender.Send(ctx, target, e); err == nil || attempt == sendAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(backoff):
+			backoff *= 2
+		}
+	}
+}
+
+// Start consumes the events and sends the notifications of the rules they match until Stop is called.
+func (s *Service) Start() error {
+	msgs, err := s.broker.Consume(EventsQueue, s.consumerTag)
+	if err != nil {
+		return fmt.Errorf("consume: %w", err)
+	}
+
+	go func() {
+		for msg := range msgs {
+			s.handleEvent(msg)
+		}
+	}()
+	return nil
+}
+
+// Stop stops consuming the events.
+func (s *Service) Stop() error {
+	if err := s.broker.Cancel(s.consumerTag); err != nil {
+		return fmt.Errorf("cancel: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) handleEvent(msg broker.Delivery) {
+	var e Event
+	if err := json.Unmarshal(msg.Body, &e); err != nil {
+		s.logger.Error("notifier", "status", "dropping invalid event", "msg", err)
+		_ = msg.Nack(false)
+		return
+	}
+
+	//every send is retried already, a notification that still fails is dropped instead of sending the others again
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err := s.Notify(ctx, e); err != nil {
+		s.logger.Error("notifier", "status", fmt.Sprintf("failed to notify about task %s", e.TaskId), "msg", err)
+	}
+
+	if err := msg.Ack(); err != nil {
+		s.logger.Error("notifier", "status", "failed to ack event", "msg", err)
+	}
+}
+
+func validTarget(channel Channel, target string) error {
+	switch channel {
+	case ChannelEmail:
+		if _, err := mail.ParseAddress(target); err != nil {
+			return ErrInvalidTarget
+		}
+	case ChannelSlack:
+		u, err := url.Parse(target)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return ErrInvalidTarget
+		}
+	}
+	return nil
+}
+
+// imageName strips the tag and the digest off of the image.
+func imageName(image string) string {
+	if i := strings.Index(image, "@"); i != -1 {
+		image = image[:i]
+	}
+
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
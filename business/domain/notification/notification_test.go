@@ -0,0 +1,160 @@
+package notification_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/mail"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/brokertest"
+	"github.com/hamidoujand/task-scheduler/business/domain/notification"
+	"github.com/hamidoujand/task-scheduler/business/domain/notification/store/memory"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+type sentMail struct {
+	to      string
+	subject string
+}
+
+type mockMailer struct {
+	mu   sync.Mutex
+	sent []sentMail
+}
+
+func (m *mockMailer) Send(ctx context.Context, to mail.Address, subject string, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, sentMail{to: to.Address, subject: subject})
+	return nil
+}
+
+func (m *mockMailer) Sent() []sentMail {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]sentMail(nil), m.sent...)
+}
+
+func TestRules(t *testing.T) {
+	t.Parallel()
+
+	repo := memory.Repository{}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	service, err := notification.NewService(&repo, brokertest.NewMemoryClient(t), logger)
+	if err != nil {
+		t.Fatalf("expected to create the service: %s", err)
+	}
+
+	ctx := context.Background()
+	userId := uuid.New()
+	nr := notification.NewRule{
+		UserId:   userId,
+		Statuses: []task.Status{task.StatusFailed},
+		Image:    "postgres",
+		Channel:  notification.ChannelEmail,
+		Target:   "ops@example.com",
+	}
+
+	//channels are only usable once they are enabled
+	if _, err := service.CreateRule(ctx, nr); !errors.Is(err, notification.ErrChannelDisabled) {
+		t.Errorf("err= %v, got %v", notification.ErrChannelDisabled, err)
+	}
+
+	mailer := mockMailer{}
+	service.EnableEmail(&mailer)
+	service.EnableSlack(time.Second)
+
+	invalid := map[string]struct {
+		rule notification.NewRule
+		err  error
+	}{
+		"email target": {
+			rule: notification.NewRule{UserId: userId, Statuses: nr.Statuses, Channel: notification.ChannelEmail, Target: "ops"},
+			err:  notification.ErrInvalidTarget,
+		},
+		"slack target": {
+			rule: notification.NewRule{UserId: userId, Statuses: nr.Statuses, Channel: notification.ChannelSlack, Target: "http://hooks.slack.com/x"},
+			err:  notification.ErrInvalidTarget,
+		},
+		"pending status": {
+			rule: notification.NewRule{UserId: userId, Statuses: []task.Status{task.StatusPending}, Channel: notification.ChannelEmail, Target: "ops@example.com"},
+			err:  notification.ErrInvalidStatus,
+		},
+	}
+
+	for name, test := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := service.CreateRule(ctx, test.rule); !errors.Is(err, test.err) {
+				t.Errorf("err= %v, got %v", test.err, err)
+			}
+		})
+	}
+
+	rule, err := service.CreateRule(ctx, nr)
+	if err != nil {
+		t.Fatalf("expected to create the rule: %s", err)
+	}
+
+	events := map[string]struct {
+		event   notification.Event
+		matches bool
+	}{
+		"failed with another tag": {
+			event:   notification.Event{UserId: userId, Image: "postgres:16", Status: task.StatusFailed},
+			matches: true,
+		},
+		"completed": {
+			event: notification.Event{UserId: userId, Image: "postgres", Status: task.StatusCompleted},
+		},
+		"another image": {
+			event: notification.Event{UserId: userId, Image: "alpine", Status: task.StatusFailed},
+		},
+		"another user": {
+			event: notification.Event{UserId: uuid.New(), Image: "postgres", Status: task.StatusFailed},
+		},
+	}
+
+	for name, test := range events {
+		t.Run(name, func(t *testing.T) {
+			if rule.Matches(test.event) != test.matches {
+				t.Errorf("matches= %t, got %t", test.matches, !test.matches)
+			}
+		})
+	}
+
+	//finished tasks go through the events queue to the notifier
+	if err := service.Start(); err != nil {
+		t.Fatalf("expected to start the notifier: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = service.Stop()
+	})
+
+	tsk := task.Task{Id: uuid.New(), UserId: userId, Image: "postgres:16", Status: task.StatusFailed, ErrMessage: "exit 1"}
+	if err := service.TaskFinished(ctx, tsk); err != nil {
+		t.Fatalf("expected to publish the event: %s", err)
+	}
+
+	deadline := time.Now().Add(time.Second * 5)
+	for len(mailer.Sent()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	sent := mailer.Sent()
+	if len(sent) != 1 || sent[0].to != nr.Target {
+		t.Fatalf("expected a single email to %s, got %+v", nr.Target, sent)
+	}
+
+	if err := service.DeleteRule(ctx, uuid.New(), rule.Id); !errors.Is(err, notification.ErrRuleNotFound) {
+		t.Errorf("err= %v, got %v", notification.ErrRuleNotFound, err)
+	}
+
+	if err := service.DeleteRule(ctx, userId, rule.Id); err != nil {
+		t.Errorf("expected to delete the rule: %s", err)
+	}
+}
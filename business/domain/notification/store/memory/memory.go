@@ -0,0 +1,54 @@
+// Package memory provides an in memory repository used for testing.
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/notification"
+)
+
+// Repository represents an in-memory storage for testing.
+type Repository struct {
+	Rules []notification.Rule
+	mu    sync.Mutex
+}
+
+// Create stores the rule.
+func (r *Repository) Create(ctx context.Context, rule notification.Rule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Rules = append(r.Rules, rule)
+	return nil
+}
+
+// GetByUserId returns the rules of the user in the order they were created.
+func (r *Repository) GetByUserId(ctx context.Context, userId uuid.UUID) ([]notification.Rule, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var rules []notification.Rule
+	for _, rule := range r.Rules {
+		if rule.UserId == userId {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// Delete deletes the rule of the user or returns sql.ErrNoRows.
+func (r *Repository) Delete(ctx context.Context, userId uuid.UUID, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, rule := range r.Rules {
+		if rule.Id == id && rule.UserId == userId {
+			r.Rules = append(r.Rules[:i], r.Rules[i+1:]...)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
@@ -0,0 +1,114 @@
+// Package postgres provides the notification rule storage on top of postgres.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/database/postgres"
+	"github.com/hamidoujand/task-scheduler/business/domain/notification"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+// Repository represents all of the APIs used for CRUD against postgres.
+type Repository struct {
+	client *postgres.Client
+}
+
+// NewRepository creates a new postgres repository.
+func NewRepository(client *postgres.Client) *Repository {
+	return &Repository{
+		client: client,
+	}
+}
+
+// Create stores the rule.
+func (r *Repository) Create(ctx context.Context, rule notification.Rule) error {
+	const q = `
+	INSERT INTO notification_rules
+		(id,user_id,statuses,image,channel,target,created_at)
+	VALUES
+		($1,$2,$3,$4,$5,$6,$7)
+	`
+
+	statuses := make([]string, len(rule.Statuses))
+	for i, status := range rule.Statuses {
+		statuses[i] = status.String()
+	}
+
+	if _, err := r.client.Pool.Exec(ctx, q,
+		rule.Id,
+		rule.UserId,
+		statuses,
+		rule.Image,
+		string(rule.Channel),
+		rule.Target,
+		rule.CreatedAt.UTC(),
+	); err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+	return nil
+}
+
+// GetByUserId returns the rules of the user in the order they were created.
+func (r *Repository) GetByUserId(ctx context.Context, userId uuid.UUID) ([]notification.Rule, error) {
+	const q = `
+	SELECT
+		id,user_id,statuses,image,channel,target,created_at
+	FROM notification_rules
+	WHERE user_id = $1
+	ORDER BY created_at
+	`
+
+	rows, err := r.client.Pool.Query(ctx, q, userId)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []notification.Rule
+	for rows.Next() {
+		var rule notification.Rule
+		var statuses []string
+		var channel string
+
+		if err := rows.Scan(&rule.Id, &rule.UserId, &statuses, &rule.Image, &channel, &rule.Target, &rule.CreatedAt); err != nil {
+			return nil, fmt.Errorf("rows scan: %w", err)
+		}
+
+		for _, name := range statuses {
+			status, err := task.ParseStatus(name)
+			if err != nil {
+				return nil, fmt.Errorf("parse status: %w", err)
+			}
+			rule.Statuses = append(rule.Statuses, status)
+		}
+
+		rule.Channel = notification.Channel(channel)
+		rule.CreatedAt = rule.CreatedAt.In(time.Local)
+		rules = append(rules, rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+	return rules, nil
+}
+
+// Delete deletes the rule of the user, returns sql.ErrNoRows when there is no record.
+func (r *Repository) Delete(ctx context.Context, userId uuid.UUID, id uuid.UUID) error {
+	const q = `DELETE FROM notification_rules WHERE id = $1 AND user_id = $2`
+
+	tag, err := r.client.Pool.Exec(ctx, q, id, userId)
+	if err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+// Package preset provides the execution presets curated by administrators, a preset bundles an image with a base
+// environment, resource limits and a network so users run their tasks inside of a vetted runtime by naming it.
+package preset
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+var (
+	ErrPresetNotFound  = errors.New("preset not found")
+	ErrInvalidName     = errors.New("preset name must be 1 to 64 letters, digits, '-' or '_'")
+	ErrInvalidNetwork  = fmt.Errorf("preset network must be one of %v", task.NetworkModes)
+	ErrInvalidLimits   = errors.New("preset limits must be greater than or equal to 0")
+	ErrInvalidSecurity = errors.New("preset security profile is invalid")
+)
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// store represents the storage of presets, Get and Delete return sql.ErrNoRows when there is no preset with the
+// name.
+type store interface {
+	Upsert(ctx context.Context, p Preset) error
+	Get(ctx context.Context, name string) (Preset, error)
+	List(ctx context.Context) ([]Preset, error)
+	Delete(ctx context.Context, name string) error
+}
+
+// Preset represents a named runtime tasks are executed in. Environment is formatted like the environment of a task
+// "KEY=value KEY2=value2" and is overridden by it, Network is the network mode of the tasks that do not ask for one
+// and leaves it to the default of the scheduler when empty. Security replaces the security profile of the scheduler
+// when set.
+type Preset struct {
+	Name        string
+	Description string
+	Image       string
+	Environment string
+	Limits      Limits
+	Network     task.NetworkMode
+	Security    *task.SecurityProfile
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Limits represents the resources a task of a preset may use, a zero value leaves the resource unlimited.
+type Limits struct {
+	CPUs     float64
+	MemoryMB int
+	Pids     int
+}
+
+// NewPreset represents the data required for creating or replacing a preset.
+type NewPreset struct {
+	Description string
+	Image       string
+	Environment string
+	Limits      Limits
+	Network     task.NetworkMode
+	Security    *task.SecurityProfile
+}
+
+// Service represents set of APIs for managing presets.
+type Service struct {
+	store store
+}
+
+// NewService creates a preset service.
+func NewService(store store) *Service {
+	return &Service{
+		store: store,
+	}
+}
+
+// SetPreset creates the preset or replaces it when there is already a preset with the name, tasks that reference it
+// run with the new one from their next execution on.
+func (s *Service) SetPreset(ctx context.Context, name string, np NewPreset) (Preset, error) {
+	if !validName.MatchString(name) {
+		return Preset{}, ErrInvalidName
+	}
+
+	if np.Network != "" {
+		if _, err := task.ParseNetworkMode(string(np.Network)); err != nil {
+			return Preset{}, ErrInvalidNetwork
+		}
+	}
+
+	if np.Limits.CPUs < 0 || np.Limits.MemoryMB < 0 || np.Limits.Pids < 0 {
+		return Preset{}, ErrInvalidLimits
+	}
+
+	if np.Security != nil {
+		if err := np.Security.Validate(); err != nil {
+			return Preset{}, fmt.Errorf("%w: %w", ErrInvalidSecurity, err)
+		}
+	}
+
+	now := time.Now()
+	p := Preset{
+		Name:        name,
+		Description: np.Description,
+		Image:       np.Image,
+		Environment: np.Environment,
+		Limits:      np.Limits,
+		Network:     np.Network,
+		Security:    np.Security,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	existing, err := s.store.Get(ctx, name)
+	switch {
+	case err == nil:
+		p.CreatedAt = existing.CreatedAt
+	case !errors.Is(err, sql.ErrNoRows):
+		return Preset{}, fmt.Errorf("get: %w", err)
+	}
+
+	if err := s.store.Upsert(ctx, p); err != nil {
+		return Preset{}, fmt.Errorf("upsert: %w", err)
+	}
+	return p, nil
+}
+
+// GetPreset returns the preset with the name, returns ErrPresetNotFound when there is none.
+func (s *Service) GetPreset(ctx context.Context, name string) (Preset, error) {
+	p, err := s.store.Get(ctx, name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Preset{}, ErrPresetNotFound
+		}
+		return Preset{}, fmt.Errorf("get: %w", err)
+	}
+	return p, nil
+}
+
+// GetPresets returns all of the presets ordered by name.
+func (s *Service) GetPresets(ctx context.Context) ([]Preset, error) {
+	presets, err := s.store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list: %w", err)
+	}
+	return presets, nil
+}
+
+// DeletePreset deletes the preset, tasks that still reference it fail on their next execution. Returns
+// ErrPresetNotFound when there is no preset with the name.
+func (s *Service) DeletePreset(ctx context.Context, name string) error {
+	if err := s.store.Delete(ctx, name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrPresetNotFound
+		}
+		return fmt.Errorf("delete: %w", err)
+	}
+	return nil
+}
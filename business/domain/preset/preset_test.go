@@ -0,0 +1,107 @@
+package preset_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/domain/preset"
+	"github.com/hamidoujand/task-scheduler/business/domain/preset/store/memory"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+func TestPreset(t *testing.T) {
+	t.Parallel()
+
+	service := preset.NewService(&memory.Repository{})
+	ctx := context.Background()
+
+	np := preset.NewPreset{
+		Image:       "python:3.12-slim",
+		Environment: "PYTHONUNBUFFERED=1",
+		Limits:      preset.Limits{CPUs: 0.5, MemoryMB: 256},
+		Network:     task.NetworkNone,
+	}
+
+	created, err := service.SetPreset(ctx, "python", np)
+	if err != nil {
+		t.Fatalf("expected to set the preset: %s", err)
+	}
+
+	//replacing a preset keeps when it was created
+	time.Sleep(time.Millisecond)
+	np.Image = "python:3.13-slim"
+	replaced, err := service.SetPreset(ctx, "python", np)
+	if err != nil {
+		t.Fatalf("expected to replace the preset: %s", err)
+	}
+
+	if !replaced.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("createdAt= %s, got %s", created.CreatedAt, replaced.CreatedAt)
+	}
+
+	got, err := service.GetPreset(ctx, "python")
+	if err != nil {
+		t.Fatalf("expected to get the preset: %s", err)
+	}
+
+	if got.Image != "python:3.13-slim" {
+		t.Errorf("image= %s, got %s", "python:3.13-slim", got.Image)
+	}
+
+	if got.Limits != np.Limits {
+		t.Errorf("limits= %+v, got %+v", np.Limits, got.Limits)
+	}
+
+	if got.Security != nil {
+		t.Errorf("expected the preset to follow the security profile of the scheduler, got %+v", got.Security)
+	}
+
+	invalid := map[string]struct {
+		name   string
+		preset preset.NewPreset
+		err    error
+	}{
+		"name": {
+			name:   "python 3",
+			preset: preset.NewPreset{Image: "python:3.12"},
+			err:    preset.ErrInvalidName,
+		},
+		"network": {
+			name:   "host",
+			preset: preset.NewPreset{Image: "alpine", Network: "host"},
+			err:    preset.ErrInvalidNetwork,
+		},
+		"limits": {
+			name:   "negative",
+			preset: preset.NewPreset{Image: "alpine", Limits: preset.Limits{MemoryMB: -1}},
+			err:    preset.ErrInvalidLimits,
+		},
+		"security": {
+			name:   "scratch",
+			preset: preset.NewPreset{Image: "alpine", Security: &task.SecurityProfile{Tmpfs: "tmp"}},
+			err:    preset.ErrInvalidSecurity,
+		},
+	}
+
+	for name, test := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := service.SetPreset(ctx, test.name, test.preset); !errors.Is(err, test.err) {
+				t.Errorf("err= %v, got %v", test.err, err)
+			}
+		})
+	}
+
+	if err := service.DeletePreset(ctx, "python"); err != nil {
+		t.Fatalf("expected to delete the preset: %s", err)
+	}
+
+	if _, err := service.GetPreset(ctx, "python"); !errors.Is(err, preset.ErrPresetNotFound) {
+		t.Errorf("err= %v, got %v", preset.ErrPresetNotFound, err)
+	}
+
+	if err := service.DeletePreset(ctx, "python"); !errors.Is(err, preset.ErrPresetNotFound) {
+		t.Errorf("err= %v, got %v", preset.ErrPresetNotFound, err)
+	}
+}
@@ -0,0 +1,70 @@
+// Package memory provides an in memory repository used for testing.
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/hamidoujand/task-scheduler/business/domain/preset"
+)
+
+// Repository represents an in-memory storage for testing.
+type Repository struct {
+	presets map[string]preset.Preset
+	mu      sync.Mutex
+}
+
+// Upsert creates or replaces the preset.
+func (r *Repository) Upsert(ctx context.Context, p preset.Preset) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.presets == nil {
+		r.presets = make(map[string]preset.Preset)
+	}
+	r.presets[p.Name] = p
+	return nil
+}
+
+// Get returns the preset or sql.ErrNoRows.
+func (r *Repository) Get(ctx context.Context, name string) (preset.Preset, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, ok := r.presets[name]
+	if !ok {
+		return preset.Preset{}, sql.ErrNoRows
+	}
+	return p, nil
+}
+
+// List returns the presets ordered by name.
+func (r *Repository) List(ctx context.Context) ([]preset.Preset, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var presets []preset.Preset
+	for _, p := range r.presets {
+		presets = append(presets, p)
+	}
+
+	slices.SortFunc(presets, func(a, b preset.Preset) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return presets, nil
+}
+
+// Delete deletes the preset or returns sql.ErrNoRows.
+func (r *Repository) Delete(ctx context.Context, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.presets[name]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(r.presets, name)
+	return nil
+}
@@ -0,0 +1,182 @@
+// Package postgres provides the preset storage on top of postgres.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/database/postgres"
+	"github.com/hamidoujand/task-scheduler/business/domain/preset"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+// Repository represents all of the APIs used for CRUD against postgres.
+type Repository struct {
+	client *postgres.Client
+}
+
+// NewRepository creates a new postgres repository.
+func NewRepository(client *postgres.Client) *Repository {
+	return &Repository{
+		client: client,
+	}
+}
+
+// securityProfile represents the security profile of a preset stored as json.
+type securityProfile struct {
+	ReadOnly        bool   `json:"readOnly,omitempty"`
+	CapDropAll      bool   `json:"capDropAll,omitempty"`
+	NoNewPrivileges bool   `json:"noNewPrivileges,omitempty"`
+	User            string `json:"user,omitempty"`
+	Tmpfs           string `json:"tmpfs,omitempty"`
+	TmpfsSizeMB     int    `json:"tmpfsSizeMb,omitempty"`
+}
+
+// Upsert creates or replaces the preset.
+func (r *Repository) Upsert(ctx context.Context, p preset.Preset) error {
+	const q = `
+	INSERT INTO presets
+		(name,description,image,environment,cpus,memory_mb,pids_limit,network,security,created_at,updated_at)
+	VALUES
+		($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+	ON CONFLICT (name) DO UPDATE SET
+		description = EXCLUDED.description,
+		image = EXCLUDED.image,
+		environment = EXCLUDED.environment,
+		cpus = EXCLUDED.cpus,
+		memory_mb = EXCLUDED.memory_mb,
+		pids_limit = EXCLUDED.pids_limit,
+		network = EXCLUDED.network,
+		security = EXCLUDED.security,
+		updated_at = EXCLUDED.updated_at
+	`
+
+	//a preset without a profile keeps a NULL so it follows the scheduler
+	var security []byte
+	if p.Security != nil {
+		data, err := json.Marshal(securityProfile(*p.Security))
+		if err != nil {
+			return fmt.Errorf("marshal security: %w", err)
+		}
+		security = data
+	}
+
+	if _, err := r.client.Pool.Exec(ctx, q,
+		p.Name,
+		p.Description,
+		p.Image,
+		p.Environment,
+		p.Limits.CPUs,
+		p.Limits.MemoryMB,
+		p.Limits.Pids,
+		string(p.Network),
+		security,
+		p.CreatedAt.UTC(),
+		p.UpdatedAt.UTC(),
+	); err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+	return nil
+}
+
+// Get returns the preset, returns sql.ErrNoRows when there is no record.
+func (r *Repository) Get(ctx context.Context, name string) (preset.Preset, error) {
+	const q = `
+	SELECT
+		name,description,image,environment,cpus,memory_mb,pids_limit,network,security,created_at,updated_at
+	FROM presets
+	WHERE name = $1
+	`
+
+	p, err := scanPreset(r.client.Pool.QueryRow(ctx, q, name))
+	if err != nil {
+		return preset.Preset{}, fmt.Errorf("row scan: %w", postgres.NoRows(err))
+	}
+	return p, nil
+}
+
+// List returns all of the presets ordered by name.
+func (r *Repository) List(ctx context.Context) ([]preset.Preset, error) {
+	const q = `
+	SELECT
+		name,description,image,environment,cpus,memory_mb,pids_limit,network,security,created_at,updated_at
+	FROM presets
+	ORDER BY name
+	`
+
+	rows, err := r.client.Pool.Query(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	var presets []preset.Preset
+	for rows.Next() {
+		p, err := scanPreset(rows)
+		if err != nil {
+			return nil, fmt.Errorf("rows scan: %w", err)
+		}
+		presets = append(presets, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+	return presets, nil
+}
+
+// Delete deletes the preset, returns sql.ErrNoRows when there is no record.
+func (r *Repository) Delete(ctx context.Context, name string) error {
+	const q = `DELETE FROM presets WHERE name = $1`
+
+	tag, err := r.client.Pool.Exec(ctx, q, name)
+	if err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPreset(row rowScanner) (preset.Preset, error) {
+	var p preset.Preset
+	var network string
+	var security []byte
+	if err := row.Scan(
+		&p.Name,
+		&p.Description,
+		&p.Image,
+		&p.Environment,
+		&p.Limits.CPUs,
+		&p.Limits.MemoryMB,
+		&p.Limits.Pids,
+		&network,
+		&security,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	); err != nil {
+		return preset.Preset{}, err
+	}
+
+	p.Network = task.NetworkMode(network)
+	if security != nil {
+		var sp securityProfile
+		if err := json.Unmarshal(security, &sp); err != nil {
+			return preset.Preset{}, fmt.Errorf("unmarshal security: %w", err)
+		}
+		profile := task.SecurityProfile(sp)
+		p.Security = &profile
+	}
+	p.CreatedAt = p.CreatedAt.In(time.Local)
+	p.UpdatedAt = p.UpdatedAt.In(time.Local)
+	return p, nil
+}
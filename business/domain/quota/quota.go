@@ -0,0 +1,96 @@
+// Package quota provides the per-user limits on pending and running tasks.
+package quota
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidQuota = errors.New("quota limits must be greater or equal to 0")
+)
+
+// store represents the storage of quotas, Get returns sql.ErrNoRows when the user has no quota.
+type store interface {
+	Get(ctx context.Context, userId uuid.UUID) (Quota, error)
+	Upsert(ctx context.Context, q Quota) error
+}
+
+// Quota represents the limits of a user, a zero limit means unlimited.
+type Quota struct {
+	UserId     uuid.UUID
+	MaxPending int
+	MaxRunning int
+	UpdatedAt  time.Time
+}
+
+// AllowsPending reports whether the user can have one more pending task when they already have pending tasks.
+func (q Quota) AllowsPending(pending int) bool {
+	return q.MaxPending == 0 || pending < q.MaxPending
+}
+
+// AllowsRunning reports whether the user can run one more task when they already have running tasks.
+func (q Quota) AllowsRunning(running int) bool {
+	return q.MaxRunning == 0 || running < q.MaxRunning
+}
+
+// UpdateQuota represents the limits that can be updated.
+type UpdateQuota struct {
+	MaxPending *int
+	MaxRunning *int
+}
+
+// Service represents set of APIs for managing quotas.
+type Service struct {
+	store store
+}
+
+// NewService creates a quota service.
+func NewService(store store) *Service {
+	return &Service{
+		store: store,
+	}
+}
+
+// GetQuota returns the quota of the user, users without a quota are unlimited.
+func (s *Service) GetQuota(ctx context.Context, userId uuid.UUID) (Quota, error) {
+	q, err := s.store.Get(ctx, userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Quota{UserId: userId}, nil
+		}
+		return Quota{}, fmt.Errorf("get: %w", err)
+	}
+	return q, nil
+}
+
+// SetQuota updates the limits of the user.
+func (s *Service) SetQuota(ctx context.Context, userId uuid.UUID, uq UpdateQuota) (Quota, error) {
+	q, err := s.GetQuota(ctx, userId)
+	if err != nil {
+		return Quota{}, err
+	}
+
+	if uq.MaxPending != nil {
+		q.MaxPending = *uq.MaxPending
+	}
+
+	if uq.MaxRunning != nil {
+		q.MaxRunning = *uq.MaxRunning
+	}
+
+	if q.MaxPending < 0 || q.MaxRunning < 0 {
+		return Quota{}, ErrInvalidQuota
+	}
+
+	q.UpdatedAt = time.Now()
+	if err := s.store.Upsert(ctx, q); err != nil {
+		return Quota{}, fmt.Errorf("upsert: %w", err)
+	}
+	return q, nil
+}
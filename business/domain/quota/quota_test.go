@@ -0,0 +1,58 @@
+package quota_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/quota"
+	"github.com/hamidoujand/task-scheduler/business/domain/quota/store/memory"
+)
+
+func TestQuota(t *testing.T) {
+	t.Parallel()
+
+	repo := memory.Repository{}
+	service := quota.NewService(&repo)
+	userId := uuid.New()
+
+	//users without a quota are unlimited
+	q, err := service.GetQuota(context.Background(), userId)
+	if err != nil {
+		t.Fatalf("expected to get the default quota: %s", err)
+	}
+
+	if !q.AllowsPending(1000) || !q.AllowsRunning(1000) {
+		t.Errorf("expected the default quota to be unlimited, got %+v", q)
+	}
+
+	maxPending := 2
+	if _, err := service.SetQuota(context.Background(), userId, quota.UpdateQuota{MaxPending: &maxPending}); err != nil {
+		t.Fatalf("expected to set the quota: %s", err)
+	}
+
+	maxRunning := 1
+	q, err = service.SetQuota(context.Background(), userId, quota.UpdateQuota{MaxRunning: &maxRunning})
+	if err != nil {
+		t.Fatalf("expected to set the quota: %s", err)
+	}
+
+	if q.MaxPending != maxPending || q.MaxRunning != maxRunning {
+		t.Errorf("quota= %d/%d, got %d/%d", maxPending, maxRunning, q.MaxPending, q.MaxRunning)
+	}
+
+	if !q.AllowsPending(1) || q.AllowsPending(2) {
+		t.Errorf("expected only 2 pending tasks to be allowed")
+	}
+
+	if !q.AllowsRunning(0) || q.AllowsRunning(1) {
+		t.Errorf("expected only 1 running task to be allowed")
+	}
+
+	invalid := -1
+	_, err = service.SetQuota(context.Background(), userId, quota.UpdateQuota{MaxRunning: &invalid})
+	if !errors.Is(err, quota.ErrInvalidQuota) {
+		t.Errorf("err= %v, got %v", quota.ErrInvalidQuota, err)
+	}
+}
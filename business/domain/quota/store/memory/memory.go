@@ -0,0 +1,41 @@
+// Package memory provides an in memory repository used for testing.
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/quota"
+)
+
+// Repository represents an in-memory storage for testing.
+type Repository struct {
+	Quotas map[uuid.UUID]quota.Quota
+	mu     sync.Mutex
+}
+
+// Get returns the quota of the user or sql.ErrNoRows.
+func (r *Repository) Get(ctx context.Context, userId uuid.UUID) (quota.Quota, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	q, ok := r.Quotas[userId]
+	if !ok {
+		return quota.Quota{}, sql.ErrNoRows
+	}
+	return q, nil
+}
+
+// Upsert creates or replaces the quota of the user.
+func (r *Repository) Upsert(ctx context.Context, q quota.Quota) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Quotas == nil {
+		r.Quotas = make(map[uuid.UUID]quota.Quota)
+	}
+	r.Quotas[q.UserId] = q
+	return nil
+}
@@ -0,0 +1,66 @@
+// Package postgres provides the quota storage on top of postgres.
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/database/postgres"
+	"github.com/hamidoujand/task-scheduler/business/domain/quota"
+)
+
+// Repository represents all of the APIs used for CRUD against postgres.
+type Repository struct {
+	client *postgres.Client
+}
+
+// NewRepository creates a new postgres repository.
+func NewRepository(client *postgres.Client) *Repository {
+	return &Repository{
+		client: client,
+	}
+}
+
+// Get returns the quota of the user, returns sql.ErrNoRows when there is no record.
+func (r *Repository) Get(ctx context.Context, userId uuid.UUID) (quota.Quota, error) {
+	const q = `
+	SELECT
+		user_id,max_pending,max_running,updated_at
+	FROM user_quotas
+	WHERE user_id = $1
+	`
+
+	var qt quota.Quota
+	if err := r.client.Pool.QueryRow(ctx, q, userId).Scan(
+		&qt.UserId,
+		&qt.MaxPending,
+		&qt.MaxRunning,
+		&qt.UpdatedAt,
+	); err != nil {
+		return quota.Quota{}, fmt.Errorf("row scan: %w", postgres.NoRows(err))
+	}
+
+	qt.UpdatedAt = qt.UpdatedAt.In(time.Local)
+	return qt, nil
+}
+
+// Upsert creates or replaces the quota of the user.
+func (r *Repository) Upsert(ctx context.Context, qt quota.Quota) error {
+	const q = `
+	INSERT INTO user_quotas
+		(user_id,max_pending,max_running,updated_at)
+	VALUES
+		($1,$2,$3,$4)
+	ON CONFLICT (user_id) DO UPDATE SET
+		max_pending = EXCLUDED.max_pending,
+		max_running = EXCLUDED.max_running,
+		updated_at = EXCLUDED.updated_at
+	`
+
+	if _, err := r.client.Pool.Exec(ctx, q, qt.UserId, qt.MaxPending, qt.MaxRunning, qt.UpdatedAt.UTC()); err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+// Package redis provides a redis cache in front of another quota storage.
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/quota"
+	"github.com/redis/go-redis/v9"
+)
+
+const entity = "quotas"
+
+// cacheTTL bounds how long a quota changed behind the cache, like a manual update in the database, can be stale.
+const cacheTTL = time.Minute * 5
+
+// store represents the storage that is the source of truth for quotas.
+type store interface {
+	Get(ctx context.Context, userId uuid.UUID) (quota.Quota, error)
+	Upsert(ctx context.Context, q quota.Quota) error
+}
+
+// Repository represents all of the APIs used for caching quotas inside of redis.
+type Repository struct {
+	client *redis.Client
+	next   store
+}
+
+// NewRepository creates a new redis repository that caches the quotas of next.
+func NewRepository(c *redis.Client, next store) *Repository {
+	return &Repository{
+		client: c,
+		next:   next,
+	}
+}
+
+// Get returns the cached quota of the user or loads it from the underlying store, errors of the underlying store
+// are returned as is.
+func (r *Repository) Get(ctx context.Context, userId uuid.UUID) (quota.Quota, error) {
+	key := entity + ":" + userId.String()
+
+	val, err := r.client.Get(ctx, key).Bytes()
+	if err == nil {
+		var q quota.Quota
+		if err := json.Unmarshal(val, &q); err != nil {
+			return quota.Quota{}, fmt.Errorf("unmarshal: %w", err)
+		}
+		return q, nil
+	}
+
+	if !errors.Is(err, redis.Nil) {
+		return quota.Quota{}, fmt.Errorf("get: %w", err)
+	}
+
+	q, err := r.next.Get(ctx, userId)
+	if err != nil {
+		return quota.Quota{}, err
+	}
+
+	bs, err := json.Marshal(q)
+	if err != nil {
+		return quota.Quota{}, fmt.Errorf("marshal: %w", err)
+	}
+
+	if err := r.client.Set(ctx, key, bs, cacheTTL).Err(); err != nil {
+		return quota.Quota{}, fmt.Errorf("set: %w", err)
+	}
+	return q, nil
+}
+
+// Upsert writes the quota into the underlying store and drops the cached one.
+func (r *Repository) Upsert(ctx context.Context, q quota.Quota) error {
+	if err := r.next.Upsert(ctx, q); err != nil {
+		return err
+	}
+
+	if err := r.client.Del(ctx, entity+":"+q.UserId.String()).Err(); err != nil {
+		return fmt.Errorf("del: %w", err)
+	}
+	return nil
+}
+
+// Purge drops every cached quota so they are loaded again from the underlying store, returns the number of
+// dropped quotas.
+func (r *Repository) Purge(ctx context.Context) (int, error) {
+	var purged int
+
+	iter := r.client.Scan(ctx, 0, entity+":*", 100).Iterator()
+	for iter.Next(ctx) {
+		if err := r.client.Del(ctx, iter.Val()).Err(); err != nil {
+			return purged, fmt.Errorf("del: %w", err)
+		}
+		purged++
+	}
+
+	if err := iter.Err(); err != nil {
+		return purged, fmt.Errorf("scan: %w", err)
+	}
+	return purged, nil
+}
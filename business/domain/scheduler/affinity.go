@@ -0,0 +1,87 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/broker"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+const (
+	//headerPreferredWorker is the id of the worker that ran the previous attempt of the task.
+	headerPreferredWorker = "x-preferred-worker"
+	//headerAffinityUntil is the unix milli time after which any worker may claim the task.
+	headerAffinityUntil = "x-affinity-until"
+)
+
+// affinityRecheck is how long a worker waits before sending back a task that prefers another worker, so a
+// lonely task does not spin between the queue and the workers.
+const affinityRecheck = time.Millisecond * 100
+
+// claims reports whether this worker may execute the task of the message, retried tasks prefer the worker
+// that ran their previous attempt since it may have the image cached, until their affinity expires.
+func (s *Scheduler) claims(msg broker.Delivery) bool {
+	//the dispatcher already took the preference into account
+	if assigned, ok := msg.Headers[headerAssignedWorker].(string); ok && assigned == s.id {
+		return true
+	}
+
+	worker, ok := msg.Headers[headerPreferredWorker].(string)
+	if !ok || worker == s.id {
+		return true
+	}
+
+	until, ok := msg.Headers[headerAffinityUntil].(int64)
+	if !ok {
+		return true
+	}
+
+	return !s.clock.Now().Before(time.UnixMilli(until))
+}
+
+// deferTask sends the task of the message to the back of the tasks queue for its preferred worker.
+func (s *Scheduler) deferTask(msg broker.Delivery) error {
+	<-s.clock.After(affinityRecheck)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+	defer cancel()
+
+	if err := s.broker.PublishWithConfirmAndHeaders(ctx, queueTasks, msg.Body, msg.Headers); err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+
+	if err := msg.Ack(); err != nil {
+		return fmt.Errorf("ack: %w", err)
+	}
+	return nil
+}
+
+// affinityHeaders returns the routing hints of a retry that prefers the worker of the previous attempt.
+func (s *Scheduler) affinityHeaders(msg broker.Delivery) broker.Headers {
+	worker, ok := msg.Headers[headerPreferredWorker].(string)
+	if !ok || s.affinityTimeout <= 0 {
+		return nil
+	}
+
+	return broker.Headers{
+		headerPreferredWorker: worker,
+		headerAffinityUntil:   s.clock.Now().Add(s.affinityTimeout).UnixMilli(),
+	}
+}
+
+func (s *Scheduler) publishTaskWithHeaders(tsk task.Task, queue string, headers broker.Headers) error {
+	bs, err := s.marshalTask(tsk)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+	defer cancel()
+
+	if err := s.broker.PublishWithConfirmAndHeaders(ctx, queue, bs, headers); err != nil {
+		return fmt.Errorf("publish task to queue %s: %w", queue, err)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/broker"
+	"github.com/hamidoujand/task-scheduler/business/broker/messages"
+	"github.com/hamidoujand/task-scheduler/business/domain/scheduler"
+	retryMemoryRepo "github.com/hamidoujand/task-scheduler/business/domain/scheduler/store/memory"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	taskMemoryRepo "github.com/hamidoujand/task-scheduler/business/domain/task/store/memory"
+	"github.com/hamidoujand/task-scheduler/foundation/clock"
+	"github.com/hamidoujand/task-scheduler/foundation/runtime"
+)
+
+// flakyRunner fails the first "flaky" command and runs every other command like benchRunner.
+type flakyRunner struct {
+	benchRunner
+	failed *atomic.Bool
+}
+
+func (r flakyRunner) RunCommand(ctx context.Context, image string, command string, runArgs []string, cmdArgs []string, stdin []byte) (string, error) {
+	if command == "flaky" && r.failed.CompareAndSwap(false, true) {
+		return "", errors.New("flaked")
+	}
+	return r.benchRunner.RunCommand(ctx, image, command, runArgs, cmdArgs, stdin)
+}
+
+func (r flakyRunner) RunSteps(ctx context.Context, image string, runArgs []string, steps []runtime.Step) ([]string, error) {
+	return r.benchRunner.RunSteps(ctx, image, runArgs, steps)
+}
+
+func TestAffinity(t *testing.T) {
+	b := newSettleBroker()
+	defer b.Close()
+
+	//the headers of every task sent into the tasks queue
+	var (
+		mu      sync.Mutex
+		routing []broker.Headers
+	)
+	b.failPublish = func(queue string, headers broker.Headers) bool {
+		if queue == "queue_tasks" {
+			mu.Lock()
+			routing = append(routing, headers)
+			mu.Unlock()
+		}
+		return false
+	}
+
+	repo := taskMemoryRepo.Repository{Tasks: make(map[uuid.UUID]task.Task)}
+	taskService, err := task.NewService(&repo, b)
+	if err != nil {
+		t.Fatalf("expected to create task service: %s", err)
+	}
+
+	fake := clock.NewFake(time.Now())
+
+	s, err := scheduler.New(scheduler.Config{
+		Broker:                  b,
+		Logger:                  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Clock:                   fake,
+		TaskService:             taskService,
+		RetryStore:              &retryMemoryRepo.Repository{},
+		MaxRunningTask:          1,
+		MaxRetries:              1,
+		MaxTimeForTaskExecution: time.Minute,
+		AffinityTimeout:         time.Second * 30,
+		Runner:                  flakyRunner{benchRunner: benchRunner{duration: time.Millisecond}, failed: &atomic.Bool{}},
+		OutboxInterval:          time.Millisecond * 10,
+	})
+	if err != nil {
+		t.Fatalf("expected to create a scheduler: %s", err)
+	}
+
+	if err := s.Activate(); err != nil {
+		t.Fatalf("expected to activate the scheduler: %s", err)
+	}
+	defer s.Shutdown(context.Background())
+
+	instance := s.Status().Instance
+
+	//publish puts a retried task into the tasks queue that prefers the worker until its affinity expires
+	publish := func(worker string) task.Task {
+		t.Helper()
+
+		now := fake.Now()
+		tsk := task.Task{
+			Id:          uuid.New(),
+			UserId:      uuid.New(),
+			Command:     "date",
+			Image:       "alpine:3.20",
+			Status:      task.StatusQueued,
+			ScheduledAt: now,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		if err := repo.Create(context.Background(), tsk); err != nil {
+			t.Fatalf("expected to create the task: %s", err)
+		}
+
+		bs, err := messages.Marshal(messages.TypeTask, tsk)
+		if err != nil {
+			t.Fatalf("expected to marshal the task: %s", err)
+		}
+
+		headers := broker.Headers{
+			"x-preferred-worker": worker,
+			"x-affinity-until":   now.Add(time.Second * 30).UnixMilli(),
+		}
+		if err := b.PublishWithConfirmAndHeaders(context.Background(), "queue_tasks", bs, headers); err != nil {
+			t.Fatalf("expected to publish the task: %s", err)
+		}
+		return tsk
+	}
+
+	status := func(tsk task.Task) task.Status {
+		t.Helper()
+
+		tsk, err := taskService.GetTaskById(context.Background(), tsk.Id)
+		if err != nil {
+			t.Fatalf("expected to get the task: %s", err)
+		}
+		return tsk.Status
+	}
+
+	//the worker of the previous attempt runs the task right away
+	own := publish(instance)
+	eventually(t, "the task preferring this worker to complete", func() bool {
+		return status(own) == task.StatusCompleted
+	})
+
+	//a task preferring another worker goes back to the queue until its affinity expires
+	acked, _ := b.settled("queue_tasks")
+	other := publish("another-worker")
+	for range 3 {
+		eventually(t, "the task preferring another worker to be sent back", func() bool {
+			fake.Advance(time.Millisecond * 100)
+			acks, _ := b.settled("queue_tasks")
+			return acks > acked
+		})
+		acked, _ = b.settled("queue_tasks")
+	}
+
+	if st := status(other); st != task.StatusQueued {
+		t.Fatalf("status= %s, got %s", task.StatusQueued, st)
+	}
+
+	//any worker may run it once its affinity expired
+	fake.Advance(time.Second * 30)
+	eventually(t, "the task to complete once its affinity expired", func() bool {
+		fake.Advance(time.Millisecond * 100)
+		return status(other) == task.StatusCompleted
+	})
+
+	//the retry of a failed attempt prefers the worker that ran it until the affinity timeout
+	retried, err := taskService.CreateTask(context.Background(), task.NewTask{
+		UserId:      uuid.New(),
+		Command:     "flaky",
+		Image:       "alpine:3.20",
+		ScheduledAt: fake.Now(),
+	})
+	if err != nil {
+		t.Fatalf("expected to create the task: %s", err)
+	}
+
+	eventually(t, "the retried task to complete", func() bool {
+		return status(retried) == task.StatusCompleted
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	last := routing[len(routing)-1]
+	if worker, _ := last["x-preferred-worker"].(string); worker != instance {
+		t.Errorf("preferred worker= %s, got %s", instance, worker)
+	}
+
+	if until, _ := last["x-affinity-until"].(int64); until != fake.Now().Add(time.Second*30).UnixMilli() {
+		t.Errorf("affinity until= %d, got %d", fake.Now().Add(time.Second*30).UnixMilli(), until)
+	}
+}
@@ -0,0 +1,124 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/metrics"
+)
+
+// alerter represents the endpoint alerts are posted to.
+type alerter interface {
+	Post(ctx context.Context, payload any) error
+}
+
+// backlog watches the depth of the tasks queue, a backlog is reported once the depth stays above threshold for
+// duration.
+type backlog struct {
+	threshold int
+	duration  time.Duration
+	interval  time.Duration
+	alerter   alerter
+}
+
+// BacklogAlert represents the payload posted to the alert webhook, Event is "backlog" once the tasks queue stays
+// above the threshold and "backlog_recovered" once it drops below it again.
+type BacklogAlert struct {
+	Event     string    `json:"event"`
+	Queue     string    `json:"queue"`
+	Messages  int       `json:"messages"`
+	Threshold int       `json:"threshold"`
+	Since     time.Time `json:"since"`
+	Instance  string    `json:"instance"`
+}
+
+// MonitorBacklog polls the depth of the tasks queue until dispatch is disabled, only the instance that runs the
+// scheduled tasks monitor reports a backlog.
+func (s *Scheduler) MonitorBacklog() error {
+	if s.backlog.threshold <= 0 {
+		return nil
+	}
+
+	s.mu.RLock()
+	stop := s.monitorStop
+	s.mu.RUnlock()
+
+	go func() {
+		ticker := s.clock.NewTicker(s.backlog.interval)
+		defer ticker.Stop()
+
+		//since is when the depth went above the threshold, zero while it is below
+		var since time.Time
+		var alerted bool
+
+		for {
+			select {
+			case <-s.shutdown:
+				return
+			case <-stop:
+				return
+			case <-ticker.C():
+			}
+
+			stats, err := s.broker.QueueStats(queueTasks)
+			if err != nil {
+				s.logger.Error("monitorBacklog", "status", "failed to read queue stats", "msg", err)
+				continue
+			}
+			metrics.SetQueueDepth(stats.Messages)
+
+			if !s.holdsMonitorLock() {
+				continue
+			}
+
+			now := s.clock.Now()
+			if stats.Messages <= s.backlog.threshold {
+				if alerted {
+					s.logger.Info("monitorBacklog", "status", "backlog recovered", "messages", stats.Messages)
+					s.alertBacklog("backlog_recovered", stats.Messages, since)
+				}
+				since, alerted = time.Time{}, false
+				continue
+			}
+
+			if since.IsZero() {
+				since = now
+			}
+
+			if alerted || now.Sub(since) < s.backlog.duration {
+				continue
+			}
+
+			s.logger.Warn("monitorBacklog", "status", fmt.Sprintf("%s has more than %d messages since %s", queueTasks, s.backlog.threshold, since.Format(time.RFC3339)),
+				"messages", stats.Messages)
+			metrics.AddBacklogAlert()
+			s.alertBacklog("backlog", stats.Messages, since)
+			alerted = true
+		}
+	}()
+
+	return nil
+}
+
+func (s *Scheduler) alertBacklog(event string, messages int, since time.Time) {
+	if s.backlog.alerter == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+	defer cancel()
+
+	alert := BacklogAlert{
+		Event:     event,
+		Queue:     queueTasks,
+		Messages:  messages,
+		Threshold: s.backlog.threshold,
+		Since:     since,
+		Instance:  s.id,
+	}
+
+	if err := s.backlog.alerter.Post(ctx, alert); err != nil {
+		s.logger.Error("monitorBacklog", "status", "failed to post alert", "msg", err)
+	}
+}
@@ -0,0 +1,87 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	"github.com/hamidoujand/task-scheduler/foundation/clock"
+)
+
+// statusWrite represents a status waiting inside of a batch, its error is sent to done once the batch is written.
+type statusWrite struct {
+	update task.BatchUpdate
+	done   chan error
+}
+
+// statusBatch coalesces the status writes of the result handlers into multi-row updates. The first write of a batch
+// waits for interval or until the batch holds size writes and then writes all of them, there is no goroutine of its
+// own so a batch that is still open during shutdown is written by the handler that waits for it.
+type statusBatch struct {
+	interval time.Duration
+	size     int
+	clock    clock.Clock
+	write    func(updates []task.BatchUpdate) []error
+
+	mu      sync.Mutex
+	pending []statusWrite
+	//full is closed once pending holds size writes.
+	full chan struct{}
+}
+
+// add puts the update into the open batch and returns the error of its write.
+func (b *statusBatch) add(update task.BatchUpdate) error {
+	w := statusWrite{
+		update: update,
+		done:   make(chan error, 1),
+	}
+
+	b.mu.Lock()
+	b.pending = append(b.pending, w)
+	first := len(b.pending) == 1
+	if first {
+		b.full = make(chan struct{})
+	}
+	full := b.full
+	if len(b.pending) == b.size {
+		close(full)
+	}
+	b.mu.Unlock()
+
+	//the first write of a batch writes all of it
+	if first {
+		select {
+		case <-b.clock.After(b.interval):
+		case <-full:
+		}
+		b.flush()
+	}
+
+	return <-w.done
+}
+
+func (b *statusBatch) flush() {
+	b.mu.Lock()
+	writes := b.pending
+	b.pending = nil
+	b.mu.Unlock()
+
+	updates := make([]task.BatchUpdate, len(writes))
+	for i, w := range writes {
+		updates[i] = w.update
+	}
+
+	errs := b.write(updates)
+	for i, w := range writes {
+		w.done <- errs[i]
+	}
+}
+
+// writeStatuses writes the batch through the task service.
+func (s *Scheduler) writeStatuses(updates []task.BatchUpdate) []error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+	defer cancel()
+
+	return s.taskService.UpdateTasks(ctx, updates)
+}
@@ -0,0 +1,88 @@
+package scheduler_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/broker/memory"
+	"github.com/hamidoujand/task-scheduler/business/domain/scheduler"
+	retryMemoryRepo "github.com/hamidoujand/task-scheduler/business/domain/scheduler/store/memory"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	taskMemoryRepo "github.com/hamidoujand/task-scheduler/business/domain/task/store/memory"
+)
+
+func TestStatusBatch(t *testing.T) {
+	broker := memory.New()
+	defer broker.Close()
+
+	taskService, err := task.NewService(&taskMemoryRepo.Repository{Tasks: make(map[uuid.UUID]task.Task)}, broker)
+	if err != nil {
+		t.Fatalf("expected to create task service: %s", err)
+	}
+
+	s, err := scheduler.New(scheduler.Config{
+		Broker:                  broker,
+		Logger:                  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		TaskService:             taskService,
+		RetryStore:              &retryMemoryRepo.Repository{},
+		MaxRunningTask:          5,
+		MaxTimeForTaskExecution: time.Minute,
+		Runner:                  benchRunner{duration: time.Millisecond},
+		OutboxInterval:          time.Millisecond * 10,
+		StatusBatchInterval:     time.Millisecond * 20,
+		StatusBatchSize:         3,
+	})
+	if err != nil {
+		t.Fatalf("expected to create a scheduler: %s", err)
+	}
+
+	if err := s.Activate(); err != nil {
+		t.Fatalf("expected to activate the scheduler: %s", err)
+	}
+	defer s.Shutdown(context.Background())
+
+	ids := make([]uuid.UUID, 10)
+	for i := range ids {
+		tsk, err := taskService.CreateTask(context.Background(), task.NewTask{
+			UserId:      uuid.New(),
+			Command:     "date",
+			Image:       "alpine:3.20",
+			ScheduledAt: time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("expected to create the task: %s", err)
+		}
+		ids[i] = tsk.Id
+	}
+
+	//every status is written, whether its batch filled up or waited for the interval
+	for _, id := range ids {
+		deadline := time.Now().Add(time.Second * 10)
+		for {
+			tsk, err := taskService.GetTaskById(context.Background(), id)
+			if err != nil {
+				t.Fatalf("expected to get the task: %s", err)
+			}
+
+			if finished(tsk) {
+				if tsk.Status != task.StatusCompleted {
+					t.Errorf("status= %s, got %s: %s", task.StatusCompleted, tsk.Status, tsk.ErrMessage)
+				}
+
+				if tsk.Result != "done" {
+					t.Errorf("result= %s, got %s", "done", tsk.Result)
+				}
+				break
+			}
+
+			if time.Now().After(deadline) {
+				t.Fatalf("expected task %s to finish, got %s", id, tsk.Status)
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package scheduler_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/broker/memory"
+	"github.com/hamidoujand/task-scheduler/business/domain/scheduler"
+	retryMemoryRepo "github.com/hamidoujand/task-scheduler/business/domain/scheduler/store/memory"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	taskMemoryRepo "github.com/hamidoujand/task-scheduler/business/domain/task/store/memory"
+	"github.com/hamidoujand/task-scheduler/foundation/runtime"
+)
+
+// benchRunner runs every command by sleeping for the duration of a container, so the benchmark measures the
+// scheduler instead of docker.
+type benchRunner struct {
+	duration time.Duration
+}
+
+func (r benchRunner) Name() string { return "bench" }
+
+func (r benchRunner) RunCommand(ctx context.Context, image string, command string, runArgs []string, cmdArgs []string, stdin []byte) (string, error) {
+	select {
+	case <-time.After(r.duration):
+		return "done", nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func (r benchRunner) RunSteps(ctx context.Context, image string, runArgs []string, steps []runtime.Step) ([]string, error) {
+	out, err := r.RunCommand(ctx, image, "", runArgs, nil, nil)
+	return []string{out}, err
+}
+
+func (r benchRunner) ExitCode(err error) (int, bool)                    { return 0, false }
+func (r benchRunner) Ping(ctx context.Context) error                    { return nil }
+func (r benchRunner) EnsureNetwork(context.Context, string, bool) error { return nil }
+
+func (r benchRunner) Version(ctx context.Context) (runtime.Version, error) {
+	return runtime.Version{}, nil
+}
+
+func (r benchRunner) ImageDigest(ctx context.Context, image string) (string, error) {
+	return "sha256:bench", nil
+}
+
+func (r benchRunner) ResolveDigest(ctx context.Context, image string) (string, error) {
+	return "sha256:bench", nil
+}
+
+func (r benchRunner) PullImage(ctx context.Context, image string, progress func(line string)) error {
+	return nil
+}
+
+// BenchmarkThroughput measures how many tasks that run for 10ms each a single scheduler finishes per second
+// depending on MaxRunningTask, with the broker and the database kept in memory.
+func BenchmarkThroughput(b *testing.B) {
+	for _, maxRunning := range []int{1, 4, 16, 64} {
+		b.Run(fmt.Sprintf("maxRunningTask=%d", maxRunning), func(b *testing.B) {
+			broker := memory.New()
+			defer broker.Close()
+
+			taskService, err := task.NewService(&taskMemoryRepo.Repository{Tasks: make(map[uuid.UUID]task.Task)}, broker)
+			if err != nil {
+				b.Fatalf("expected to create task service: %s", err)
+			}
+
+			s, err := scheduler.New(scheduler.Config{
+				Broker:                  broker,
+				Logger:                  slog.New(slog.NewTextHandler(io.Discard, nil)),
+				TaskService:             taskService,
+				RetryStore:              &retryMemoryRepo.Repository{},
+				MaxRunningTask:          maxRunning,
+				MaxTimeForTaskExecution: time.Minute,
+				Runner:                  benchRunner{duration: time.Millisecond * 10},
+				OutboxInterval:          time.Millisecond * 10,
+			})
+			if err != nil {
+				b.Fatalf("expected to create a scheduler: %s", err)
+			}
+
+			if err := s.Activate(); err != nil {
+				b.Fatalf("expected to activate the scheduler: %s", err)
+			}
+			defer s.Shutdown(context.Background())
+
+			b.ResetTimer()
+			start := time.Now()
+
+			ids := make([]uuid.UUID, b.N)
+			for i := range b.N {
+				tsk, err := taskService.CreateTask(context.Background(), task.NewTask{
+					UserId:      uuid.New(),
+					Command:     "date",
+					Image:       "alpine:3.20",
+					ScheduledAt: time.Now(),
+				})
+				if err != nil {
+					b.Fatalf("expected to create the task: %s", err)
+				}
+				ids[i] = tsk.Id
+			}
+
+			for _, id := range ids {
+				for {
+					tsk, err := taskService.GetTaskById(context.Background(), id)
+					if err != nil {
+						b.Fatalf("expected to get the task %s: %s", id, err)
+					}
+
+					if finished(tsk) {
+						if tsk.Status != task.StatusCompleted {
+							b.Fatalf("status= %s, got %s: %s", task.StatusCompleted, tsk.Status, tsk.ErrMessage)
+						}
+						break
+					}
+					time.Sleep(time.Millisecond)
+				}
+			}
+
+			b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "tasks/s")
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+// blackouts represents the maintenance windows and the global pause during which no task starts.
+type blackouts interface {
+	Until(ctx context.Context, now time.Time) (time.Time, bool, error)
+}
+
+// deferBlackout defers the task to the end of the blackout it falls into, returns true when the task was handed to
+// the delay store or back to the tasks queue and the executer is done with it. Without a delay store the task waits
+// inside of its executer.
+func (s *Scheduler) deferBlackout(tsk task.Task) bool {
+	if s.blackouts == nil {
+		return false
+	}
+
+	for {
+		until, ok := s.blackoutUntil()
+		if !ok {
+			return false
+		}
+
+		s.logger.Info("blackout", "status", fmt.Sprintf("deferring task %s until %s", tsk.Id, until.Format(time.RFC3339)))
+
+		if s.delays != nil {
+			err := s.delayUntil(tsk, until)
+			if err == nil {
+				return true
+			}
+			s.logger.Error("blackout", "status", fmt.Sprintf("failed to delay task %s", tsk.Id), "msg", err)
+		}
+
+		select {
+		case <-s.shutdown:
+			//another instance runs it once the blackout is over
+			if err := s.publishTask(tsk, queueTasks); err != nil {
+				s.logger.Error("blackout", "status", fmt.Sprintf("failed to requeue task %s", tsk.Id), "msg", err)
+			}
+			return true
+
+		case <-s.clock.After(until.Sub(s.clock.Now())):
+		}
+	}
+}
+
+// blackoutUntil reports whether executions are blacked out right now, failures of the store do not block execution.
+func (s *Scheduler) blackoutUntil() (time.Time, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+	defer cancel()
+
+	until, ok, err := s.blackouts.Until(ctx, s.clock.Now())
+	if err != nil {
+		s.logger.Error("blackout", "status", "failed to check blackouts", "msg", err)
+		return time.Time{}, false
+	}
+	return until, ok
+}
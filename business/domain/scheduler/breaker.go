@@ -0,0 +1,108 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/metrics"
+)
+
+// breaker pauses dispatch after consecutive infrastructure failures of the executor, so an outage of the docker
+// daemon or the registry does not burn the retries of every task.
+type breaker struct {
+	mu            sync.Mutex
+	threshold     int
+	probeInterval time.Duration
+	probe         func(ctx context.Context) error
+	failures      int
+	//resume is not nil while the breaker is open and is closed once dispatch resumes.
+	resume chan struct{}
+}
+
+// IsPaused reports whether dispatch is paused because of executor infrastructure failures.
+func (s *Scheduler) IsPaused() bool {
+	s.breaker.mu.Lock()
+	defer s.breaker.mu.Unlock()
+	return s.breaker.resume != nil
+}
+
+// waitForDispatch blocks while the breaker is open.
+func (s *Scheduler) waitForDispatch() error {
+	s.breaker.mu.Lock()
+	resume := s.breaker.resume
+	s.breaker.mu.Unlock()
+
+	if resume == nil {
+		return nil
+	}
+
+	select {
+	case <-s.shutdown:
+		return errors.New("received shutdown signal")
+	case <-resume:
+		return nil
+	}
+}
+
+// recordExecution counts consecutive infrastructure failures and trips the breaker once they reach the threshold,
+// any other result proves the executor works and resets the count.
+func (s *Scheduler) recordExecution(infraFailure bool) {
+	s.breaker.mu.Lock()
+	defer s.breaker.mu.Unlock()
+
+	if !infraFailure {
+		s.breaker.failures = 0
+		return
+	}
+
+	s.breaker.failures++
+	if s.breaker.failures < s.breaker.threshold || s.breaker.resume != nil {
+		return
+	}
+
+	s.breaker.resume = make(chan struct{})
+	s.logger.Error("breaker", "status", "tripped", "msg", "executor infrastructure is failing, dispatch paused",
+		"failures", s.breaker.failures)
+	metrics.AddBreakerTrip()
+	metrics.SetBreakerOpen(true)
+
+	s.wg.Add(1)
+	go s.probeExecutor()
+}
+
+// probeExecutor probes the executor periodically and resumes dispatch as soon as it is reachable again.
+func (s *Scheduler) probeExecutor() {
+	defer s.wg.Done()
+
+	ticker := s.clock.NewTicker(s.breaker.probeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.shutdown:
+			return
+		case <-ticker.C():
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), s.breaker.probeInterval)
+		err := s.breaker.probe(ctx)
+		cancel()
+
+		if err != nil {
+			s.logger.Warn("breaker", "status", "probe failed", "msg", err)
+			continue
+		}
+
+		s.breaker.mu.Lock()
+		close(s.breaker.resume)
+		s.breaker.resume = nil
+		s.breaker.failures = 0
+		s.breaker.mu.Unlock()
+
+		s.logger.Info("breaker", "status", "closed", "msg", "executor is reachable again, dispatch resumed")
+		metrics.SetBreakerOpen(false)
+		return
+	}
+}
@@ -0,0 +1,79 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	"github.com/hamidoujand/task-scheduler/business/metrics"
+)
+
+// claimRecheck is how long a task whose execution is claimed by another executer waits before it is sent back to
+// the tasks queue.
+const claimRecheck = time.Second * 10
+
+// claimStore represents the storage of the execution claims, a task is executed by the run that holds its claim.
+type claimStore interface {
+	//ClaimExecution returns false when another run holds the claim of the task.
+	ClaimExecution(ctx context.Context, taskId string, runId string, ttl time.Duration) (bool, error)
+	//ReleaseExecution only releases the claim while the run still holds it.
+	ReleaseExecution(ctx context.Context, taskId string, runId string) error
+}
+
+// claimExecution claims the task for the run until deadline passes and the image could have been pulled, so a
+// message that is delivered twice does not execute the task twice at the same time. The claim of an executer that
+// died expires on its own and failures of the store do not block execution.
+func (s *Scheduler) claimExecution(tsk task.Task, runId uuid.UUID, deadline time.Time) bool {
+	if s.executionClaims == nil {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+	defer cancel()
+
+	ttl := deadline.Sub(s.clock.Now()) + s.maxTimeForImagePull + slotMargin
+	claimed, err := s.executionClaims.ClaimExecution(ctx, tsk.Id.String(), runId.String(), ttl)
+	if err != nil {
+		s.logger.Error("claim", "status", fmt.Sprintf("failed to claim execution of task %s", tsk.Id), "msg", err)
+		return true
+	}
+	return claimed
+}
+
+// releaseExecution releases the claim of the run, it is called once the execution finished and before its outcome
+// is published so the retry of the task can claim it right away.
+func (s *Scheduler) releaseExecution(tsk task.Task, runId uuid.UUID) {
+	if s.executionClaims == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+	defer cancel()
+
+	if err := s.executionClaims.ReleaseExecution(ctx, tsk.Id.String(), runId.String()); err != nil {
+		s.logger.Error("claim", "status", fmt.Sprintf("failed to release execution of task %s", tsk.Id), "msg", err)
+	}
+}
+
+// deferClaimed sends a task that is executing elsewhere back to the tasks queue, by the time it comes back the
+// other execution may have finished or died. The task is dropped once the other execution finished it, otherwise the
+// duplicate would run again as soon as the claim is released.
+func (s *Scheduler) deferClaimed(tsk task.Task) {
+	s.logger.Info("claim", "status", fmt.Sprintf("deferring task %s, it is already executing", tsk.Id))
+	metrics.AddClaimConflict()
+
+	select {
+	case <-s.shutdown:
+	case <-s.clock.After(claimRecheck):
+	}
+
+	if s.finished(tsk) {
+		return
+	}
+
+	if err := s.publishTask(tsk, queueTasks); err != nil {
+		s.logger.Error("claim", "status", fmt.Sprintf("failed to requeue claimed task %s", tsk.Id), "msg", err)
+	}
+}
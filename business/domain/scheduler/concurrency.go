@@ -0,0 +1,85 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+// slotMargin is added to the execution timeout to get how long a slot is held at most, slots of executers that
+// died are freed after it.
+const slotMargin = time.Minute
+
+// limitRecheck is how long a task whose image is at its concurrency limit waits before it is sent back to the
+// tasks queue.
+const limitRecheck = time.Second
+
+// slotStore represents the distributed semaphores that limit how many tasks of an image run across all instances.
+type slotStore interface {
+	AcquireSlot(ctx context.Context, name string, holder string, limit int, ttl time.Duration) (bool, error)
+	ReleaseSlot(ctx context.Context, name string, holder string) error
+}
+
+// imageName returns the image without its tag or digest, "postgres-backup:1.2" and "postgres-backup@sha256:..."
+// both share the limit of "postgres-backup".
+func imageName(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+
+	//a colon before the last slash belongs to the registry port
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
+
+// acquireImageSlot reserves one of the slots of the image of the task for the executer, returns false when the
+// image already runs as many tasks as its limit allows across all instances. Images without a limit always get a
+// slot and failures of the store do not block execution.
+func (s *Scheduler) acquireImageSlot(tsk task.Task, executerId string) bool {
+	limit, ok := s.imageLimits[imageName(tsk.Image)]
+	if !ok {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+	defer cancel()
+
+	acquired, err := s.slots.AcquireSlot(ctx, imageName(tsk.Image), executerId, limit, s.maxTimeForTaskExecution+s.maxTimeForImagePull+slotMargin)
+	if err != nil {
+		s.logger.Error("concurrency", "status", fmt.Sprintf("failed to acquire slot of image %s", tsk.Image), "msg", err)
+		return true
+	}
+	return acquired
+}
+
+func (s *Scheduler) releaseImageSlot(tsk task.Task, executerId string) {
+	if _, ok := s.imageLimits[imageName(tsk.Image)]; !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+	defer cancel()
+
+	if err := s.slots.ReleaseSlot(ctx, imageName(tsk.Image), executerId); err != nil {
+		s.logger.Error("concurrency", "status", fmt.Sprintf("failed to release slot of image %s", tsk.Image), "msg", err)
+	}
+}
+
+// deferOverLimit sends the task back to the tasks queue so it runs once one of the tasks of its image finishes.
+func (s *Scheduler) deferOverLimit(tsk task.Task) {
+	s.logger.Info("concurrency", "status", fmt.Sprintf("deferring task %s, image %s is at its concurrency limit", tsk.Id, tsk.Image))
+
+	select {
+	case <-s.shutdown:
+	case <-s.clock.After(limitRecheck):
+	}
+
+	if err := s.publishTask(tsk, queueTasks); err != nil {
+		s.logger.Error("concurrency", "status", fmt.Sprintf("failed to requeue task %s", tsk.Id), "msg", err)
+	}
+}
@@ -0,0 +1,149 @@
+package scheduler_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/broker/memory"
+	"github.com/hamidoujand/task-scheduler/business/domain/scheduler"
+	retryMemoryRepo "github.com/hamidoujand/task-scheduler/business/domain/scheduler/store/memory"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	taskMemoryRepo "github.com/hamidoujand/task-scheduler/business/domain/task/store/memory"
+	"github.com/hamidoujand/task-scheduler/foundation/clock"
+)
+
+// newDeadlineScheduler creates an active scheduler whose fake clock lags behind the wall clock by lag, executions
+// run until their context is done.
+func newDeadlineScheduler(t *testing.T, lag time.Duration) (*scheduler.Scheduler, *task.Service, *clock.Fake) {
+	t.Helper()
+
+	broker := memory.New()
+	t.Cleanup(func() { broker.Close() })
+
+	taskService, err := task.NewService(&taskMemoryRepo.Repository{Tasks: make(map[uuid.UUID]task.Task)}, broker)
+	if err != nil {
+		t.Fatalf("expected to create task service: %s", err)
+	}
+
+	fake := clock.NewFake(time.Now().Add(-lag))
+
+	s, err := scheduler.New(scheduler.Config{
+		Broker:                  broker,
+		Logger:                  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Clock:                   fake,
+		TaskService:             taskService,
+		RetryStore:              &retryMemoryRepo.Repository{},
+		MaxRunningTask:          2,
+		MaxTimeForTaskExecution: time.Second * 30,
+		Runner:                  benchRunner{duration: time.Hour},
+		OutboxInterval:          time.Millisecond * 10,
+	})
+	if err != nil {
+		t.Fatalf("expected to create a scheduler: %s", err)
+	}
+
+	if err := s.Activate(); err != nil {
+		t.Fatalf("expected to activate the scheduler: %s", err)
+	}
+	t.Cleanup(func() { s.Shutdown(context.Background()) })
+
+	return s, taskService, fake
+}
+
+// executerOf waits until the scheduler executes or waits to execute the task.
+func executerOf(t *testing.T, s *scheduler.Scheduler, taskId uuid.UUID) scheduler.Executer {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second * 5)
+	for time.Now().Before(deadline) {
+		for _, ex := range s.Status().Executers {
+			if ex.TaskId == taskId {
+				return ex
+			}
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	t.Fatalf("expected task %s to reach an executer", taskId)
+	return scheduler.Executer{}
+}
+
+func TestExecutionDeadline(t *testing.T) {
+	s, taskService, fake := newDeadlineScheduler(t, 0)
+
+	//a task that reaches its executer early gets all of its execution time once it is due
+	early, err := taskService.CreateTask(context.Background(), task.NewTask{
+		UserId:      uuid.New(),
+		Command:     "date",
+		Image:       "alpine:3.20",
+		ScheduledAt: fake.Now().Add(time.Second * 20),
+	})
+	if err != nil {
+		t.Fatalf("expected to create the task: %s", err)
+	}
+
+	ex := executerOf(t, s, early.Id)
+	if expected := early.ScheduledAt.Add(time.Second * 30); !ex.Deadline.Equal(expected) {
+		t.Errorf("deadline= %s, got %s", expected, ex.Deadline)
+	}
+
+	//a task picked up late counts from now instead of from when it was due
+	late, err := taskService.CreateTask(context.Background(), task.NewTask{
+		UserId:      uuid.New(),
+		Command:     "date",
+		Image:       "alpine:3.20",
+		ScheduledAt: fake.Now().Add(-time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("expected to create the task: %s", err)
+	}
+
+	ex = executerOf(t, s, late.Id)
+	if expected := fake.Now().Add(time.Second * 30); !ex.Deadline.Equal(expected) {
+		t.Errorf("deadline= %s, got %s", expected, ex.Deadline)
+	}
+
+	//lets the early task run so shutdown does not wait for it
+	fake.Advance(time.Second * 20)
+}
+
+func TestExecutionTimeout(t *testing.T) {
+	//the fake clock lags so the deadline of 30s passes on the wall clock right away
+	s, taskService, fake := newDeadlineScheduler(t, time.Second*30-time.Millisecond*200)
+
+	tsk, err := taskService.CreateTask(context.Background(), task.NewTask{
+		UserId:      uuid.New(),
+		Command:     "sleep",
+		Image:       "alpine:3.20",
+		ScheduledAt: fake.Now(),
+	})
+	if err != nil {
+		t.Fatalf("expected to create the task: %s", err)
+	}
+
+	ex := executerOf(t, s, tsk.Id)
+
+	var runs []task.Run
+	deadline := time.Now().Add(time.Second * 5)
+	for time.Now().Before(deadline) && len(runs) == 0 {
+		runs, err = taskService.GetRunsByTaskId(context.Background(), tsk.Id)
+		if err != nil {
+			t.Fatalf("expected to get the runs: %s", err)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	if len(runs) == 0 {
+		t.Fatalf("expected task %s to time out", tsk.Id)
+	}
+
+	//the effective deadline is reported instead of the configured execution time
+	msg := runs[0].ErrMessage
+	if expected := "timed out at its deadline " + ex.Deadline.UTC().Format(time.RFC3339); !strings.Contains(msg, expected) {
+		t.Errorf("expected the error %q to contain %q", msg, expected)
+	}
+}
@@ -0,0 +1,114 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/broker"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+// delayPoll is how often the tasks that became due are moved from the delay store into the tasks queue.
+const delayPoll = time.Second
+
+// delayBatch is the max number of due tasks moved in one poll.
+const delayBatch = 100
+
+// delayStore represents the storage that holds the tasks that are not due yet ordered by when they run.
+type delayStore interface {
+	AddDelayed(ctx context.Context, body []byte, runAt time.Time) error
+	PopDue(ctx context.Context, now time.Time, limit int) ([][]byte, error)
+}
+
+// delayTask parks a task that is not due yet inside of the delay store so it does not hold an executer while it
+// waits, returns false when the task must wait inside of its executer instead.
+func (s *Scheduler) delayTask(tsk task.Task) bool {
+	if s.delays == nil || !tsk.ScheduledAt.After(s.clock.Now()) {
+		return false
+	}
+
+	if err := s.delayUntil(tsk, tsk.ScheduledAt); err != nil {
+		s.logger.Error("delayTask", "status", fmt.Sprintf("failed to delay task %s", tsk.Id), "msg", err)
+		return false
+	}
+	return true
+}
+
+// delayUntil parks the task inside of the delay store until runAt.
+func (s *Scheduler) delayUntil(tsk task.Task, runAt time.Time) error {
+	bs, err := s.marshalTask(tsk)
+	if err != nil {
+		return fmt.Errorf("marshal task: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+	defer cancel()
+
+	if err := s.delays.AddDelayed(ctx, bs, runAt); err != nil {
+		return fmt.Errorf("add delayed: %w", err)
+	}
+	return nil
+}
+
+// DispatchDelayedTasks moves the delayed tasks into the tasks queue once they are due, every instance that executes
+// tasks runs it and a task is only popped by one of them.
+func (s *Scheduler) DispatchDelayedTasks() error {
+	if s.delays == nil {
+		return nil
+	}
+
+	//nil when the dispatcher is not started by Activate, receiving from it blocks forever.
+	s.mu.RLock()
+	stop := s.monitorStop
+	s.mu.RUnlock()
+
+	go func() {
+		ticker := s.clock.NewTicker(delayPoll)
+		defer ticker.Stop()
+
+		for range ticker.C() {
+			select {
+			case <-s.shutdown:
+				s.logger.Info("dispatchDelayedTasks", "status", "received shutdown signal", "msg", "shutting down")
+				return
+
+			case <-stop:
+				s.logger.Info("dispatchDelayedTasks", "status", "dispatch disabled", "msg", "stopping delayed tasks dispatcher")
+				return
+
+			default:
+				s.dispatchDue()
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (s *Scheduler) dispatchDue() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+	defer cancel()
+
+	bodies, err := s.delays.PopDue(ctx, s.clock.Now(), delayBatch)
+	if err != nil {
+		s.logger.Error("dispatchDelayedTasks", "status", "failed to pop due tasks", "msg", err)
+		return
+	}
+
+	for _, bs := range bodies {
+		tsk, err := s.parseTask(bs)
+		if err != nil {
+			s.logger.Error("dispatchDelayedTasks", "status", "dropping malformed delayed task", "msg", err)
+			continue
+		}
+
+		if err := s.dispatchTask(tsk, broker.Headers{}); err != nil {
+			//put it back so the next poll tries again
+			s.logger.Error("dispatchDelayedTasks", "status", fmt.Sprintf("failed to dispatch task %s", tsk.Id), "msg", err)
+			if err := s.delays.AddDelayed(ctx, bs, tsk.ScheduledAt); err != nil {
+				s.logger.Error("dispatchDelayedTasks", "status", fmt.Sprintf("lost delayed task %s", tsk.Id), "msg", err)
+			}
+		}
+	}
+}
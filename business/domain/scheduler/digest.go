@@ -0,0 +1,41 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+// pinImage returns the image reference the task must be executed with, on first dispatch the tag is resolved
+// to a digest and persisted so every later attempt runs the exact same image. tasks that opted into a floating
+// tag always run the tag, and a failed resolution falls back to the tag instead of failing the task.
+func (s *Scheduler) pinImage(tsk *task.Task) string {
+	if tsk.FloatingTag {
+		return tsk.Image
+	}
+
+	if tsk.ImageDigest != "" {
+		return tsk.ImageDigest
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps+maxTimeForFingerprint)
+	defer cancel()
+
+	digest, err := s.runner.ResolveDigest(ctx, tsk.Image)
+	if err != nil {
+		s.logger.Warn("pinImage", "status", fmt.Sprintf("failed to resolve digest of image %s, running the tag", tsk.Image), "msg", err)
+		return tsk.Image
+	}
+
+	tsk.ImageDigest = digest
+
+	updated, err := s.taskService.UpdateTask(ctx, *tsk, task.UpdateTask{ImageDigest: &digest})
+	if err != nil {
+		s.logger.Error("pinImage", "status", fmt.Sprintf("failed to persist digest of task %s", tsk.Id), "msg", err)
+		return digest
+	}
+
+	*tsk = updated
+	return digest
+}
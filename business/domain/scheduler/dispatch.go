@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/broker"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	"github.com/hamidoujand/task-scheduler/business/domain/worker"
+)
+
+// headerAssignedWorker is the id of the worker the task was assigned to, the load of that worker is released once
+// the task is handled no matter which worker ends up running it.
+const headerAssignedWorker = "x-assigned-worker"
+
+// workerQueueExpiry is how long the queue of a worker that stopped consuming is kept, it must outlive the
+// assignment timeout so the tasks left inside of it are dead lettered before it is deleted.
+const workerQueueExpiry = time.Hour
+
+// registry represents the workers that tasks can be assigned to.
+type registry interface {
+	Assign(ctx context.Context, preferred string) (worker.Worker, error)
+	AddLoad(ctx context.Context, id string, delta int) error
+}
+
+// workerQueue returns the name of the queue that holds the tasks assigned to the worker.
+func workerQueue(id string) string {
+	return queueTasks + "." + id
+}
+
+// declareWorkerQueue declares the queue of the tasks assigned to this worker, tasks that wait there longer than
+// the assignment timeout, for example because the worker died, are dead lettered into the shared tasks queue.
+func (s *Scheduler) declareWorkerQueue() error {
+	opts := broker.QueueOptions{
+		MessageTTL:      s.assignTimeout,
+		DeadLetterQueue: queueTasks,
+		Expires:         workerQueueExpiry,
+	}
+
+	if err := s.broker.DeclareQueueWithOptions(workerQueue(s.id), opts); err != nil {
+		return fmt.Errorf("declare worker queue: %w", err)
+	}
+	return nil
+}
+
+// dispatchTask sends the task to the queue of the least loaded worker, or the worker its headers prefer, and falls
+// back to the shared tasks queue when there is no registry or no worker is active.
+func (s *Scheduler) dispatchTask(tsk task.Task, headers broker.Headers) error {
+	if s.workers == nil {
+		return s.publishTaskWithHeaders(tsk, queueTasks, headers)
+	}
+
+	preferred, _ := headers[headerPreferredWorker].(string)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+	defer cancel()
+
+	wrk, err := s.workers.Assign(ctx, preferred)
+	if err != nil {
+		if !errors.Is(err, worker.ErrNoWorkers) {
+			s.logger.Error("dispatch", "status", fmt.Sprintf("failed to assign task %s to a worker", tsk.Id), "msg", err)
+		}
+		return s.publishTaskWithHeaders(tsk, queueTasks, headers)
+	}
+
+	assigned := broker.Headers{headerAssignedWorker: wrk.Id}
+	for key, val := range headers {
+		assigned[key] = val
+	}
+
+	if err := s.publishTaskWithHeaders(tsk, workerQueue(wrk.Id), assigned); err != nil {
+		//the task never reached the worker
+		s.releaseAssignment(wrk.Id)
+		return err
+	}
+	return nil
+}
+
+// releaseAssignment removes a handled task from the load of the worker it was assigned to.
+func (s *Scheduler) releaseAssignment(workerId string) {
+	if s.workers == nil || workerId == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+	defer cancel()
+
+	if err := s.workers.AddLoad(ctx, workerId, -1); err != nil {
+		s.logger.Error("dispatch", "status", fmt.Sprintf("failed to release load of worker %s", workerId), "msg", err)
+	}
+}
@@ -0,0 +1,70 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Drain stops this instance from taking new tasks off of the task queues, tasks that are already executing are left
+// to finish. Other instances keep consuming the queues, so it is meant for draining an instance before a deploy.
+func (s *Scheduler) Drain() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.draining {
+		return nil
+	}
+	s.draining = true
+
+	s.logger.Info("drain", "status", "draining", "msg", "stopped taking new tasks", "instance", s.id)
+
+	//consumers only exist while this instance is active
+	if !s.active || !s.mode.executes() {
+		return nil
+	}
+	return s.cancelTaskConsumers()
+}
+
+// Resume makes this instance take new tasks off of the task queues again after Drain.
+func (s *Scheduler) Resume() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.draining {
+		return nil
+	}
+	s.draining = false
+
+	s.logger.Info("drain", "status", "resumed", "msg", "taking new tasks again", "instance", s.id)
+
+	if !s.active || !s.mode.executes() {
+		return nil
+	}
+	return s.consumeTaskQueues()
+}
+
+// DrainState reports "ok" while this instance takes new tasks, "draining" while it is drained and tasks are still
+// executing and "drained" once all of them finished.
+func (s *Scheduler) DrainState() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	switch {
+	case !s.draining:
+		return "ok"
+	case len(s.executers) > 0:
+		return "draining"
+	default:
+		return "drained"
+	}
+}
+
+func (s *Scheduler) cancelTaskConsumers() error {
+	var errs []error
+	for _, queue := range s.taskQueues() {
+		if err := s.broker.Cancel(s.consumerTag(queue)); err != nil {
+			errs = append(errs, fmt.Errorf("cancel consumer of %s: %w", queue, err))
+		}
+	}
+	return errors.Join(errs...)
+}
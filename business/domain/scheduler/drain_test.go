@@ -0,0 +1,146 @@
+package scheduler_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/broker/memory"
+	"github.com/hamidoujand/task-scheduler/business/domain/scheduler"
+	retryMemoryRepo "github.com/hamidoujand/task-scheduler/business/domain/scheduler/store/memory"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	taskMemoryRepo "github.com/hamidoujand/task-scheduler/business/domain/task/store/memory"
+	"github.com/hamidoujand/task-scheduler/foundation/runtime"
+)
+
+// gateRunner holds the "block" command until release is closed and runs every other command like benchRunner.
+type gateRunner struct {
+	benchRunner
+	release chan struct{}
+}
+
+func (r gateRunner) RunCommand(ctx context.Context, image string, command string, runArgs []string, cmdArgs []string, stdin []byte) (string, error) {
+	if command == "block" {
+		select {
+		case <-r.release:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	return r.benchRunner.RunCommand(ctx, image, command, runArgs, cmdArgs, stdin)
+}
+
+func (r gateRunner) RunSteps(ctx context.Context, image string, runArgs []string, steps []runtime.Step) ([]string, error) {
+	return r.benchRunner.RunSteps(ctx, image, runArgs, steps)
+}
+
+func TestDrain(t *testing.T) {
+	broker := memory.New()
+	defer broker.Close()
+
+	taskService, err := task.NewService(&taskMemoryRepo.Repository{Tasks: make(map[uuid.UUID]task.Task)}, broker)
+	if err != nil {
+		t.Fatalf("expected to create task service: %s", err)
+	}
+
+	release := make(chan struct{})
+
+	s, err := scheduler.New(scheduler.Config{
+		Broker:                  broker,
+		Logger:                  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		TaskService:             taskService,
+		RetryStore:              &retryMemoryRepo.Repository{},
+		MaxRunningTask:          2,
+		MaxTimeForTaskExecution: time.Minute,
+		Runner:                  gateRunner{benchRunner: benchRunner{duration: time.Millisecond}, release: release},
+		OutboxInterval:          time.Millisecond * 10,
+	})
+	if err != nil {
+		t.Fatalf("expected to create a scheduler: %s", err)
+	}
+
+	if err := s.Activate(); err != nil {
+		t.Fatalf("expected to activate the scheduler: %s", err)
+	}
+	defer s.Shutdown(context.Background())
+
+	create := func(command string) task.Task {
+		t.Helper()
+
+		tsk, err := taskService.CreateTask(context.Background(), task.NewTask{
+			UserId:      uuid.New(),
+			Command:     command,
+			Image:       "alpine:3.20",
+			ScheduledAt: time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("expected to create the task: %s", err)
+		}
+		return tsk
+	}
+
+	status := func(tsk task.Task) task.Status {
+		t.Helper()
+
+		tsk, err := taskService.GetTaskById(context.Background(), tsk.Id)
+		if err != nil {
+			t.Fatalf("expected to get the task: %s", err)
+		}
+		return tsk.Status
+	}
+
+	inFlight := create("block")
+	eventually(t, "the blocked task to reach an executer", func() bool {
+		return len(s.Status().Executers) == 1
+	})
+
+	if err := s.Drain(); err != nil {
+		t.Fatalf("expected to drain the scheduler: %s", err)
+	}
+
+	if state := s.DrainState(); state != "draining" {
+		t.Errorf("state= %s, got %s", "draining", state)
+	}
+
+	//new tasks stay inside of the queue while drained
+	waiting := create("date")
+	time.Sleep(time.Millisecond * 200)
+
+	if st := status(waiting); st != task.StatusQueued {
+		t.Errorf("status= %s, got %s", task.StatusQueued, st)
+	}
+
+	stats, err := broker.QueueStats("queue_tasks")
+	if err != nil {
+		t.Fatalf("expected to get the stats of queue_tasks: %s", err)
+	}
+
+	if stats.Messages != 1 || stats.Consumers != 0 {
+		t.Errorf("messages/consumers= %d/%d, got %d/%d", 1, 0, stats.Messages, stats.Consumers)
+	}
+
+	//the task that was already executing finishes
+	close(release)
+	eventually(t, "the in-flight task to complete", func() bool {
+		return status(inFlight) == task.StatusCompleted
+	})
+
+	eventually(t, "the scheduler to be drained", func() bool {
+		return s.DrainState() == "drained"
+	})
+
+	if err := s.Resume(); err != nil {
+		t.Fatalf("expected to resume the scheduler: %s", err)
+	}
+
+	eventually(t, "the waiting task to complete after resume", func() bool {
+		return status(waiting) == task.StatusCompleted
+	})
+
+	if state := s.DrainState(); state != "ok" {
+		t.Errorf("state= %s, got %s", "ok", state)
+	}
+}
@@ -0,0 +1,82 @@
+package scheduler_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	taskMemoryRepo "github.com/hamidoujand/task-scheduler/business/domain/task/store/memory"
+	"github.com/hamidoujand/task-scheduler/foundation/runtime"
+)
+
+// countingRunner counts the commands it runs.
+type countingRunner struct {
+	benchRunner
+	runs *atomic.Int64
+}
+
+func (r countingRunner) RunCommand(ctx context.Context, image string, command string, runArgs []string, cmdArgs []string, stdin []byte) (string, error) {
+	r.runs.Add(1)
+	return r.benchRunner.RunCommand(ctx, image, command, runArgs, cmdArgs, stdin)
+}
+
+func (r countingRunner) RunSteps(ctx context.Context, image string, runArgs []string, steps []runtime.Step) ([]string, error) {
+	out, err := r.RunCommand(ctx, image, "", runArgs, nil, nil)
+	return []string{out}, err
+}
+
+func TestDuplicateDelivery(t *testing.T) {
+	b := newSettleBroker()
+	defer b.Close()
+
+	taskService, err := task.NewService(&taskMemoryRepo.Repository{Tasks: make(map[uuid.UUID]task.Task)}, b)
+	if err != nil {
+		t.Fatalf("expected to create task service: %s", err)
+	}
+
+	var runs atomic.Int64
+	newSettleScheduler(t, b, taskService, countingRunner{benchRunner: benchRunner{duration: time.Millisecond}, runs: &runs})
+
+	tsk, err := taskService.CreateTask(context.Background(), task.NewTask{
+		UserId:      uuid.New(),
+		Command:     "date",
+		Image:       "alpine:3.20",
+		ScheduledAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("expected to create the task: %s", err)
+	}
+
+	eventually(t, "the task to complete", func() bool {
+		stored, err := taskService.GetTaskById(context.Background(), tsk.Id)
+		return err == nil && stored.Status == task.StatusCompleted
+	})
+
+	//the broker delivers the same message once more after the task finished
+	published := b.messages("queue_tasks")
+	if len(published) != 1 {
+		t.Fatalf("published= %d, got %d", 1, len(published))
+	}
+	if err := b.PublishWithConfirm(context.Background(), "queue_tasks", published[0]); err != nil {
+		t.Fatalf("expected to publish the duplicate: %s", err)
+	}
+
+	eventually(t, "the duplicate to be acked", func() bool {
+		acks, _ := b.settled("queue_tasks")
+		return acks == 2
+	})
+
+	//a duplicate that was submitted anyway finishes within a few milliseconds
+	time.Sleep(time.Millisecond * 100)
+
+	if n := runs.Load(); n != 1 {
+		t.Errorf("runs= %d, got %d", 1, n)
+	}
+
+	if acks, _ := b.settled("queue_success"); acks != 1 {
+		t.Errorf("success acks= %d, got %d", 1, acks)
+	}
+}
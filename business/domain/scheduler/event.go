@@ -0,0 +1,26 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+// recordEvent adds the event to the timeline of the task with this instance as its source, recording is best effort
+// and never fails the task.
+func (s *Scheduler) recordEvent(tsk task.Task, kind task.EventKind, message string) {
+	ne := task.NewEvent{
+		TaskId:  tsk.Id,
+		Kind:    kind,
+		Message: message,
+		Source:  s.id,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+	defer cancel()
+
+	if _, err := s.taskService.CreateEvent(ctx, ne); err != nil {
+		s.logger.Error("recordEvent", "status", fmt.Sprintf("failed to record %s event of task %s", kind, tsk.Id), "msg", err)
+	}
+}
@@ -0,0 +1,28 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+// finished reports whether the task was canceled after it was published or already finished through another
+// delivery of its message, such tasks are dropped instead of executed. Failures of the store do not block execution.
+func (s *Scheduler) finished(tsk task.Task) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+	defer cancel()
+
+	stored, err := s.taskService.GetTaskById(ctx, tsk.Id)
+	if err != nil {
+		s.logger.Error("consumeTasks", "status", fmt.Sprintf("failed to check whether task %s is finished", tsk.Id), "msg", err)
+		return false
+	}
+
+	if !stored.Status.Finished() {
+		return false
+	}
+
+	s.logger.Info("consumeTasks", "status", fmt.Sprintf("dropping %s task %s", stored.Status, tsk.Id))
+	return true
+}
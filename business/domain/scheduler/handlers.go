@@ -0,0 +1,86 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hamidoujand/task-scheduler/business/broker"
+	"github.com/hamidoujand/task-scheduler/business/metrics"
+)
+
+// handlerPool handles the deliveries of a queue with a fixed number of workers, a spike of results waits inside of
+// the broker instead of turning into a goroutine per message that all hit the database at the same time.
+type handlerPool struct {
+	queue  string
+	handle func(msg broker.Delivery)
+	msgs   chan broker.Delivery
+}
+
+// consumeWithHandlers starts a consumer of the queue whose deliveries are handled by handlerWorkers workers, the
+// broker delivers as many messages as there are workers before the first of them is settled. The pool is closed
+// once the consumer is canceled and s.handlers tracks its workers until they handled the deliveries they got.
+func (s *Scheduler) consumeWithHandlers(queue string, handle func(msg broker.Delivery)) (*handlerPool, <-chan broker.Delivery, error) {
+	msgs, err := s.broker.ConsumeWithPrefetch(queue, s.consumerTag(queue), s.handlerWorkers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p := handlerPool{
+		queue:  queue,
+		handle: handle,
+		msgs:   make(chan broker.Delivery, s.handlerWorkers),
+	}
+
+	s.handlers.Add(s.handlerWorkers)
+	for range s.handlerWorkers {
+		go func() {
+			defer s.handlers.Done()
+			for msg := range p.msgs {
+				metrics.SetHandlerQueue(p.queue, len(p.msgs))
+				p.handle(msg)
+			}
+		}()
+	}
+
+	return &p, msgs, nil
+}
+
+// submit hands the delivery to the next free worker.
+func (p *handlerPool) submit(msg broker.Delivery) {
+	p.msgs <- msg
+	metrics.SetHandlerQueue(p.queue, len(p.msgs))
+}
+
+// close lets the workers stop once they handled the deliveries that were submitted.
+func (p *handlerPool) close() {
+	close(p.msgs)
+}
+
+// drainHandlers cancels the consumers of the result queues and waits for their workers to handle what they were
+// delivered, so the results of the tasks that finished during the shutdown are saved.
+func (s *Scheduler) drainHandlers(ctx context.Context) error {
+	s.mu.RLock()
+	active := s.active
+	s.mu.RUnlock()
+
+	if active && s.mode.dispatches() {
+		for _, queue := range []string{queueSuccess, queueFailed, queueRetry} {
+			if err := s.broker.Cancel(s.consumerTag(queue)); err != nil {
+				s.logger.Error("shutdown", "status", fmt.Sprintf("failed to cancel consumer of %s", queue), "msg", err)
+			}
+		}
+	}
+
+	ch := make(chan struct{})
+	go func() {
+		s.handlers.Wait()
+		close(ch)
+	}()
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
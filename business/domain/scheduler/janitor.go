@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// janitorInterval is how often the partitions of the tasks are maintained.
+const janitorInterval = time.Hour
+
+// partitionsAhead is how many monthly partitions exist at least, the current month included.
+const partitionsAhead = 3
+
+// partitioner represents the storage of the tasks that is partitioned by month.
+type partitioner interface {
+	CreatePartitions(ctx context.Context, from time.Time, months int) ([]string, error)
+	//DropPartitions drops the partitions, and everything of their tasks, that end before the month of before.
+	DropPartitions(ctx context.Context, before time.Time) ([]string, error)
+}
+
+// MaintainPartitions creates the partitions of the upcoming months and drops the ones outside of the retention until
+// dispatch is disabled, only the instance that runs the scheduled tasks monitor maintains them.
+func (s *Scheduler) MaintainPartitions() error {
+	if s.partitions == nil {
+		return nil
+	}
+
+	s.mu.RLock()
+	stop := s.monitorStop
+	s.mu.RUnlock()
+
+	go func() {
+		ticker := s.clock.NewTicker(janitorInterval)
+		defer ticker.Stop()
+
+		for {
+			if s.holdsMonitorLock() {
+				s.maintainPartitions()
+			}
+
+			select {
+			case <-s.shutdown:
+				return
+			case <-stop:
+				return
+			case <-ticker.C():
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (s *Scheduler) maintainPartitions() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+	defer cancel()
+
+	now := s.clock.Now()
+
+	created, err := s.partitions.CreatePartitions(ctx, now, partitionsAhead)
+	if len(created) > 0 {
+		s.logger.Info("maintainPartitions", "status", fmt.Sprintf("created partitions %s", strings.Join(created, ", ")))
+	}
+	if err != nil {
+		s.logger.Error("maintainPartitions", "status", "failed to create partitions", "msg", err)
+	}
+
+	if s.retentionMonths <= 0 {
+		return
+	}
+
+	dropped, err := s.partitions.DropPartitions(ctx, now.AddDate(0, -s.retentionMonths, 0))
+	if len(dropped) > 0 {
+		s.logger.Info("maintainPartitions", "status", fmt.Sprintf("dropped partitions %s", strings.Join(dropped, ", ")))
+	}
+	if err != nil {
+		s.logger.Error("maintainPartitions", "status", "failed to drop partitions", "msg", err)
+	}
+}
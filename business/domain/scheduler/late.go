@@ -0,0 +1,106 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	"github.com/hamidoujand/task-scheduler/business/metrics"
+)
+
+// lateInterval is how often the tasks are checked for being late.
+const lateInterval = time.Minute
+
+// maxLateTasks is how many late tasks are reported at most on every check, the rest are reported by the next ones.
+const maxLateTasks = 100
+
+// LateAlert represents the payload posted to the alert webhook once tasks did not start within LateAfter of their
+// scheduled time, Oldest is the scheduled time of the oldest one.
+type LateAlert struct {
+	Event    string      `json:"event"`
+	Tasks    []uuid.UUID `json:"tasks"`
+	Oldest   time.Time   `json:"oldest"`
+	Instance string      `json:"instance"`
+}
+
+// MonitorLateTasks reports the tasks that did not start within lateAfter of their scheduled time until dispatch is
+// disabled, every task is reported once with an EventLate. Only the instance that runs the scheduled tasks monitor
+// reports them.
+func (s *Scheduler) MonitorLateTasks() error {
+	if s.lateAfter <= 0 {
+		return nil
+	}
+
+	s.mu.RLock()
+	stop := s.monitorStop
+	s.mu.RUnlock()
+
+	go func() {
+		ticker := s.clock.NewTicker(lateInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.shutdown:
+				return
+			case <-stop:
+				return
+			case <-ticker.C():
+			}
+
+			if s.holdsMonitorLock() {
+				s.reportLateTasks()
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (s *Scheduler) reportLateTasks() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+	defer cancel()
+
+	tsks, err := s.taskService.GetLateTasks(ctx, s.lateAfter, maxLateTasks)
+	if err != nil {
+		s.logger.Error("monitorLateTasks", "status", "failed to get late tasks", "msg", err)
+		return
+	}
+
+	if len(tsks) == 0 {
+		return
+	}
+
+	ids := make([]uuid.UUID, len(tsks))
+	for i, tsk := range tsks {
+		ids[i] = tsk.Id
+		s.recordEvent(tsk, task.EventLate, fmt.Sprintf("not started %s after its scheduled time", s.lateAfter))
+	}
+
+	s.logger.Warn("monitorLateTasks", "status", fmt.Sprintf("%d tasks did not start %s after their scheduled time", len(tsks), s.lateAfter),
+		"oldest", tsks[0].ScheduledAt.Format(time.RFC3339))
+	metrics.AddLateTasks(len(tsks))
+	s.alertLateTasks(ids, tsks[0].ScheduledAt)
+}
+
+func (s *Scheduler) alertLateTasks(ids []uuid.UUID, oldest time.Time) {
+	if s.backlog.alerter == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+	defer cancel()
+
+	alert := LateAlert{
+		Event:    "late",
+		Tasks:    ids,
+		Oldest:   oldest,
+		Instance: s.id,
+	}
+
+	if err := s.backlog.alerter.Post(ctx, alert); err != nil {
+		s.logger.Error("monitorLateTasks", "status", "failed to post alert", "msg", err)
+	}
+}
@@ -0,0 +1,61 @@
+package scheduler
+
+import "fmt"
+
+// Mode selects which part of the task pipeline a scheduler runs.
+type Mode int
+
+const (
+	//ModeAll dispatches due tasks, executes them and handles their results inside of the same process.
+	ModeAll Mode = iota
+	//ModeDispatch dispatches due tasks and handles their results, execution is left to workers.
+	ModeDispatch
+	//ModeExecute only consumes "queue_tasks" and executes the tasks, used by standalone workers.
+	ModeExecute
+)
+
+// ParseMode parses the mode from its name.
+func ParseMode(name string) (Mode, error) {
+	switch name {
+	case "all", "":
+		return ModeAll, nil
+	case "dispatch":
+		return ModeDispatch, nil
+	case "execute":
+		return ModeExecute, nil
+	default:
+		return 0, fmt.Errorf("unknown scheduler mode %q", name)
+	}
+}
+
+// String returns the name of the mode.
+func (m Mode) String() string {
+	switch m {
+	case ModeDispatch:
+		return "dispatch"
+	case ModeExecute:
+		return "execute"
+	default:
+		return "all"
+	}
+}
+
+func (m Mode) executes() bool {
+	return m != ModeDispatch
+}
+
+func (m Mode) dispatches() bool {
+	return m != ModeExecute
+}
+
+// Id returns the id of this scheduler, workers register themselves with it.
+func (s *Scheduler) Id() string {
+	return s.id
+}
+
+// Running returns the number of tasks this scheduler is executing or waiting to execute.
+func (s *Scheduler) Running() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.executers)
+}
@@ -0,0 +1,69 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+)
+
+// monitorLock represents the lock that lets only one instance run the scheduled tasks monitor at a time.
+type monitorLock interface {
+	Acquire(ctx context.Context) (bool, error)
+	Release(ctx context.Context) error
+	Held() bool
+	TTL() time.Duration
+}
+
+// holdsMonitorLock reports whether this instance may run the scheduled tasks monitor, instances without a lock
+// always do.
+func (s *Scheduler) holdsMonitorLock() bool {
+	return s.monitorLock == nil || s.monitorLock.Held()
+}
+
+// keepMonitorLock acquires the monitor lock, or renews it once it holds it, every third of its ttl until stop is
+// closed or the scheduler shuts down and releases it afterwards. Another instance takes over once the lock expires when this one dies.
+func (s *Scheduler) keepMonitorLock(stop <-chan struct{}) {
+	if s.monitorLock == nil {
+		return
+	}
+
+	interval := s.monitorLock.TTL() / 3
+	ticker := s.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	var held bool
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		if _, err := s.monitorLock.Acquire(ctx); err != nil {
+			s.logger.Error("monitorLock", "status", "failed to acquire monitor lock", "msg", err)
+		}
+		cancel()
+
+		switch now := s.monitorLock.Held(); {
+		case now && !held:
+			s.logger.Info("monitorLock", "status", "monitor lock acquired, monitoring scheduled tasks", "instance", s.id)
+			held = true
+		case !now && held:
+			s.logger.Warn("monitorLock", "status", "monitor lock lost, another instance monitors scheduled tasks", "instance", s.id)
+			held = false
+		}
+
+		select {
+		case <-s.shutdown:
+			s.releaseMonitorLock()
+			return
+		case <-stop:
+			s.releaseMonitorLock()
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+func (s *Scheduler) releaseMonitorLock() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+	defer cancel()
+
+	if err := s.monitorLock.Release(ctx); err != nil {
+		s.logger.Error("monitorLock", "status", "failed to release monitor lock", "msg", err)
+	}
+}
@@ -0,0 +1,55 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+// maxTimeForNetwork bounds the runtime calls used for creating the internal network.
+const maxTimeForNetwork = time.Second * 10
+
+// networkArgs returns the docker arguments that attach the container to the network of the mode, modes the policy
+// does not allow are rejected even when they were allowed once the task was created.
+func (s *Scheduler) networkArgs(mode task.NetworkMode) ([]string, error) {
+	mode = s.networks.Mode(mode)
+	if !s.networks.Allows(mode) {
+		return nil, fmt.Errorf("network mode %q is not allowed", mode)
+	}
+
+	switch mode {
+	case task.NetworkInternal:
+		if err := s.ensureInternalNetwork(); err != nil {
+			return nil, err
+		}
+		return []string{"--network", s.internalNetwork}, nil
+
+	case task.NetworkEgress:
+		return []string{"--network", s.egressNetwork}, nil
+
+	default:
+		return []string{"--network", "none"}, nil
+	}
+}
+
+// ensureInternalNetwork creates the internal network once, a failed attempt is tried again by the next task.
+func (s *Scheduler) ensureInternalNetwork() error {
+	s.networkMu.Lock()
+	defer s.networkMu.Unlock()
+
+	if s.internalReady {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), maxTimeForNetwork)
+	defer cancel()
+
+	if err := s.runner.EnsureNetwork(ctx, s.internalNetwork, true); err != nil {
+		return fmt.Errorf("ensure internal network: %w", err)
+	}
+
+	s.internalReady = true
+	return nil
+}
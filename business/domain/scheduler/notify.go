@@ -0,0 +1,25 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+// notifier represents the consumer of the tasks that finished.
+type notifier interface {
+	TaskFinished(ctx context.Context, tsk task.Task) error
+}
+
+// notify hands the task that finished to the notifier once its status is saved, a task that is not handed over is
+// only logged since it already finished.
+func (s *Scheduler) notify(ctx context.Context, tsk task.Task) {
+	if s.notifier == nil {
+		return
+	}
+
+	if err := s.notifier.TaskFinished(ctx, tsk); err != nil {
+		s.logger.Error("notify", "status", fmt.Sprintf("failed to hand over finished task %s", tsk.Id), "msg", err)
+	}
+}
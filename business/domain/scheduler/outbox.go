@@ -0,0 +1,47 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+)
+
+// RelayOutbox publishes the tasks left inside of the outbox by task creation every outbox interval, for example
+// because the broker was down or the api crashed right after the task was stored.
+func (s *Scheduler) RelayOutbox() error {
+	//nil when the relay is not started by Activate, receiving from it blocks forever.
+	s.mu.RLock()
+	stop := s.monitorStop
+	s.mu.RUnlock()
+
+	go func() {
+		ticker := s.clock.NewTicker(s.outboxInterval)
+		defer ticker.Stop()
+
+		for range ticker.C() {
+			select {
+			case <-s.shutdown:
+				s.logger.Info("relayOutbox", "status", "received shutdown signal", "msg", "shutting down")
+				return
+
+			case <-stop:
+				s.logger.Info("relayOutbox", "status", "dispatch disabled", "msg", "stopping relay")
+				return
+
+			default:
+				ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+				published, err := s.taskService.RelayOutbox(ctx)
+				cancel()
+
+				if err != nil {
+					s.logger.Error("relayOutbox", "status", "failed to relay outbox", "msg", err)
+				}
+
+				if published > 0 {
+					s.logger.Info("relayOutbox", "status", fmt.Sprintf("relayed %d tasks from outbox", published))
+				}
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,393 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/metrics"
+	"github.com/hamidoujand/task-scheduler/foundation/runtime"
+)
+
+// warmInterval is how often the warm pool is refilled and its stale containers are recycled.
+const warmInterval = time.Second * 5
+
+// maxTimeForWarmup bounds pulling an image of the warm pool and starting one of its containers.
+const maxTimeForWarmup = time.Minute * 5
+
+// commandRunner represents what executes the command or the steps of a task.
+type commandRunner interface {
+	RunCommand(ctx context.Context, image string, command string, runArgs []string, cmdArgs []string, stdin []byte) (string, error)
+	RunSteps(ctx context.Context, image string, runArgs []string, steps []runtime.Step) ([]string, error)
+}
+
+// warmPool keeps idle containers running per image, tasks exec into one of them instead of starting their own.
+type warmPool struct {
+	warmer  runtime.Warmer
+	maxUses int
+	maxIdle time.Duration
+	//refill wakes the pool up once a container was taken out of it.
+	refill chan struct{}
+
+	mu sync.Mutex
+	//args are the run arguments of the containers, nil until they could be built.
+	args   []string
+	images []*warmImage
+	closed bool
+	//gen tells the loops of the pool apart, a loop that stopped only closes the pool when no other one started since.
+	gen int
+}
+
+// warmImage represents the idle containers of an image.
+type warmImage struct {
+	image string
+	//digest is what the containers are started from, empty until the image was pulled.
+	digest string
+	size   int
+	idle   []warmContainer
+}
+
+// warmContainer represents a container of the pool.
+type warmContainer struct {
+	id        string
+	uses      int
+	idleSince time.Time
+}
+
+func newWarmPool(warmer runtime.Warmer, sizes map[string]int, maxUses int, maxIdle time.Duration) *warmPool {
+	p := warmPool{
+		warmer:  warmer,
+		maxUses: maxUses,
+		maxIdle: maxIdle,
+		refill:  make(chan struct{}, 1),
+		closed:  true,
+	}
+
+	for image, size := range sizes {
+		p.images = append(p.images, &warmImage{image: image, size: size})
+	}
+	return &p
+}
+
+// ParseWarmPool parses the sizes of the warm pool out of "image=count" entries like "alpine:3.20=2".
+func ParseWarmPool(entries []string) (map[string]int, error) {
+	sizes := make(map[string]int, len(entries))
+	for _, entry := range entries {
+		image, count, ok := strings.Cut(entry, "=")
+		if !ok || image == "" {
+			return nil, fmt.Errorf("warm pool entry %q must be image=count", entry)
+		}
+
+		size, err := strconv.Atoi(count)
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("warm pool entry %q must have a count greater than 0", entry)
+		}
+		sizes[image] = size
+	}
+	return sizes, nil
+}
+
+// WarmContainers keeps the warm pool filled until dispatch is disabled, its idle containers are removed once it is.
+func (s *Scheduler) WarmContainers() error {
+	if s.warm == nil {
+		return nil
+	}
+
+	s.mu.RLock()
+	stop := s.monitorStop
+	s.mu.RUnlock()
+
+	s.warm.mu.Lock()
+	s.warm.closed = false
+	s.warm.gen++
+	gen := s.warm.gen
+	s.warm.mu.Unlock()
+
+	go func() {
+		ticker := s.clock.NewTicker(warmInterval)
+		defer ticker.Stop()
+
+		for {
+			s.fillWarmPool()
+
+			select {
+			case <-s.shutdown:
+				s.closeWarmPool(gen)
+				return
+			case <-stop:
+				s.closeWarmPool(gen)
+				return
+			case <-ticker.C():
+			case <-s.warm.refill:
+			}
+		}
+	}()
+
+	return nil
+}
+
+// fillWarmPool recycles the containers that stayed idle for too long and starts new ones until every image has as
+// many idle containers as it should. Images are pulled before their first container.
+func (s *Scheduler) fillWarmPool() {
+	if !s.warmArgs() {
+		return
+	}
+
+	for _, img := range s.warm.images {
+		for _, c := range s.warm.expired(img, s.clock.Now()) {
+			s.removeWarm(img, c.id)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), maxTimeForWarmup)
+		if !s.pullWarm(ctx, img) {
+			cancel()
+			continue
+		}
+
+		for s.warm.missing(img) > 0 {
+			s.warm.mu.Lock()
+			digest, args := img.digest, s.warm.args
+			s.warm.mu.Unlock()
+
+			id, err := s.warm.warmer.StartIdle(ctx, digest, args)
+			if err != nil {
+				s.logger.Error("warmPool", "status", fmt.Sprintf("failed to start a warm container of %s", img.image), "msg", err)
+				break
+			}
+
+			//the pool was closed in between
+			if !s.warm.put(img, warmContainer{id: id, idleSince: s.clock.Now()}) {
+				s.removeWarm(img, id)
+				break
+			}
+		}
+		cancel()
+
+		metrics.SetWarmIdle(img.image, s.warm.idleCount(img))
+	}
+}
+
+// warmArgs builds the run arguments of the warm containers once, they are the ones of a task without a preset that
+// runs with the default network mode and the security profile of the scheduler.
+func (s *Scheduler) warmArgs() bool {
+	s.warm.mu.Lock()
+	ready := s.warm.args != nil
+	s.warm.mu.Unlock()
+
+	if ready {
+		return true
+	}
+
+	args, err := s.networkArgs("")
+	if err != nil {
+		s.logger.Error("warmPool", "status", "failed to build the arguments of warm containers", "msg", err)
+		return false
+	}
+	args = append(args, securityArgs(s.security)...)
+
+	s.warm.mu.Lock()
+	s.warm.args = args
+	s.warm.mu.Unlock()
+	return true
+}
+
+// pullWarm pulls the image once and reports whether it is available.
+func (s *Scheduler) pullWarm(ctx context.Context, img *warmImage) bool {
+	s.warm.mu.Lock()
+	pulled := img.digest != ""
+	s.warm.mu.Unlock()
+
+	if pulled {
+		return true
+	}
+
+	s.logger.Info("warmPool", "status", fmt.Sprintf("pre-pulling image %s", img.image))
+	digest, err := s.runner.ResolveDigest(ctx, img.image)
+	if err != nil {
+		s.logger.Error("warmPool", "status", fmt.Sprintf("failed to pull image %s", img.image), "msg", err)
+		return false
+	}
+
+	s.warm.mu.Lock()
+	img.digest = digest
+	s.warm.mu.Unlock()
+	return true
+}
+
+// closeWarmPool removes every idle container, containers that are running a task are removed once it finishes.
+func (s *Scheduler) closeWarmPool(gen int) {
+	s.warm.mu.Lock()
+	if s.warm.gen != gen {
+		s.warm.mu.Unlock()
+		return
+	}
+	s.warm.closed = true
+	idle := make(map[*warmImage][]warmContainer)
+	for _, img := range s.warm.images {
+		idle[img] = img.idle
+		img.idle = nil
+	}
+	s.warm.mu.Unlock()
+
+	for img, containers := range idle {
+		for _, c := range containers {
+			if err := s.warm.warmer.Remove(c.id); err != nil {
+				s.logger.Error("warmPool", "status", fmt.Sprintf("failed to remove warm container %s", c.id), "msg", err)
+			}
+		}
+		metrics.SetWarmIdle(img.image, 0)
+	}
+}
+
+func (s *Scheduler) removeWarm(img *warmImage, id string) {
+	metrics.AddWarmRecycle(img.image)
+	if err := s.warm.warmer.Remove(id); err != nil {
+		s.logger.Error("warmPool", "status", fmt.Sprintf("failed to remove warm container %s", id), "msg", err)
+	}
+}
+
+// leaseWarm takes an idle container of the image out of the pool when the task runs with the same arguments as its
+// containers, only the environment may differ since it is passed to every command.
+func (s *Scheduler) leaseWarm(image string, runArgs []string) (*warmLease, bool) {
+	if s.warm == nil {
+		return nil, false
+	}
+
+	s.warm.mu.Lock()
+	defer s.warm.mu.Unlock()
+
+	idx := slices.IndexFunc(s.warm.images, func(img *warmImage) bool {
+		return img.image == image || (img.digest != "" && img.digest == image)
+	})
+	if idx < 0 {
+		return nil, false
+	}
+	img := s.warm.images[idx]
+
+	args, env := splitEnv(runArgs)
+	if s.warm.closed || len(img.idle) == 0 || s.warm.args == nil || !slices.Equal(args, s.warm.args) {
+		metrics.AddWarmMiss(img.image)
+		return nil, false
+	}
+
+	c := img.idle[len(img.idle)-1]
+	img.idle = img.idle[:len(img.idle)-1]
+	metrics.AddWarmHit(img.image)
+	metrics.SetWarmIdle(img.image, len(img.idle))
+
+	select {
+	case s.warm.refill <- struct{}{}:
+	default:
+	}
+
+	return &warmLease{warmer: s.warm.warmer, img: img, container: c, env: env}, true
+}
+
+// releaseWarm puts the container back into the pool, it is replaced instead after a failure, since a killed command
+// may still be running inside of it, and once it ran as many tasks as it may.
+func (s *Scheduler) releaseWarm(lease *warmLease, runErr error) {
+	c := lease.container
+	c.uses++
+	c.idleSince = s.clock.Now()
+
+	if runErr == nil && c.uses < s.warm.maxUses && s.warm.put(lease.img, c) {
+		return
+	}
+
+	s.removeWarm(lease.img, c.id)
+	select {
+	case s.warm.refill <- struct{}{}:
+	default:
+	}
+}
+
+// expired takes the containers of the image that stayed idle for longer than maxIdle out of the pool.
+func (p *warmPool) expired(img *warmImage, now time.Time) []warmContainer {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var expired []warmContainer
+	idle := img.idle[:0]
+	for _, c := range img.idle {
+		if now.Sub(c.idleSince) > p.maxIdle {
+			expired = append(expired, c)
+			continue
+		}
+		idle = append(idle, c)
+	}
+	img.idle = idle
+	return expired
+}
+
+// missing returns how many containers the image lacks, none once the pool is closed.
+func (p *warmPool) missing(img *warmImage) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return 0
+	}
+	return img.size - len(img.idle)
+}
+
+// put adds the idle container to the pool, it reports false when the pool is closed or the image has enough.
+func (p *warmPool) put(img *warmImage, c warmContainer) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed || len(img.idle) >= img.size {
+		return false
+	}
+	img.idle = append(img.idle, c)
+	return true
+}
+
+func (p *warmPool) idleCount(img *warmImage) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(img.idle)
+}
+
+// warmLease represents a warm container that runs a single task, it executes the command or the steps of the task
+// inside of the container with the environment of the task.
+type warmLease struct {
+	warmer    runtime.Warmer
+	img       *warmImage
+	container warmContainer
+	env       []string
+}
+
+// RunCommand executes the command inside of the warm container.
+func (l *warmLease) RunCommand(ctx context.Context, image string, command string, runArgs []string, cmdArgs []string, stdin []byte) (string, error) {
+	return l.warmer.Exec(ctx, l.container.id, l.env, command, cmdArgs, stdin)
+}
+
+// RunSteps executes the steps one after another inside of the warm container and stops at the first one that fails.
+func (l *warmLease) RunSteps(ctx context.Context, image string, runArgs []string, steps []runtime.Step) ([]string, error) {
+	outputs := make([]string, 0, len(steps))
+	for i, step := range steps {
+		output, err := l.warmer.Exec(ctx, l.container.id, l.env, step.Command, step.Args, step.Stdin)
+		if err != nil {
+			return outputs, fmt.Errorf("step %d: %w", i+1, err)
+		}
+		outputs = append(outputs, output)
+	}
+	return outputs, nil
+}
+
+// splitEnv splits the run arguments into the environment ("-e KEY=value") and everything else.
+func splitEnv(runArgs []string) (args []string, env []string) {
+	for i := 0; i < len(runArgs); i++ {
+		if runArgs[i] == "-e" && i+1 < len(runArgs) {
+			env = append(env, runArgs[i+1])
+			i++
+			continue
+		}
+		args = append(args, runArgs[i])
+	}
+	return args, env
+}
@@ -0,0 +1,96 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hamidoujand/task-scheduler/business/domain/preset"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+// presetResolver represents the execution presets tasks reference by name.
+type presetResolver interface {
+	GetPreset(ctx context.Context, name string) (preset.Preset, error)
+}
+
+// dockerArgs returns the docker arguments the task is executed with along with the security profile they apply. The
+// task runs on the network it asked for, otherwise on the one of its preset, and the arguments of its preset come
+// before the environment of the task so the environment of the task overrides the base environment of the preset.
+// The user the task asked for replaces the one of the profile.
+func (s *Scheduler) dockerArgs(tsk task.Task) ([]string, task.SecurityProfile, error) {
+	p, err := s.preset(tsk)
+	if err != nil {
+		return nil, task.SecurityProfile{}, err
+	}
+
+	mode := tsk.NetworkMode
+	if mode == "" {
+		mode = p.Network
+	}
+
+	args, err := s.networkArgs(mode)
+	if err != nil {
+		return nil, task.SecurityProfile{}, err
+	}
+
+	profile := s.securityProfile(p)
+	if tsk.RunAsUser != "" {
+		profile.User = tsk.RunAsUser
+	}
+	args = append(args, securityArgs(profile)...)
+	args = append(args, presetArgs(p)...)
+
+	if tsk.WorkDir != "" {
+		args = append(args, "--workdir", tsk.WorkDir)
+	}
+
+	envArgs, err := s.envArgs(tsk)
+	if err != nil {
+		return nil, task.SecurityProfile{}, err
+	}
+	return append(args, envArgs...), profile, nil
+}
+
+// preset returns the preset of the task, the zero preset when it has none. The preset is read right before
+// execution so a change of it applies to the tasks that already reference it.
+func (s *Scheduler) preset(tsk task.Task) (preset.Preset, error) {
+	if tsk.Preset == "" {
+		return preset.Preset{}, nil
+	}
+
+	if s.presets == nil {
+		return preset.Preset{}, fmt.Errorf("task uses preset %q but presets are not enabled", tsk.Preset)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+	defer cancel()
+
+	p, err := s.presets.GetPreset(ctx, tsk.Preset)
+	if err != nil {
+		return preset.Preset{}, fmt.Errorf("get preset %s: %w", tsk.Preset, err)
+	}
+	return p, nil
+}
+
+// presetArgs returns the docker arguments that apply the limits and the base environment of the preset.
+func presetArgs(p preset.Preset) []string {
+	var args []string
+	if p.Limits.CPUs > 0 {
+		args = append(args, "--cpus", strconv.FormatFloat(p.Limits.CPUs, 'f', -1, 64))
+	}
+
+	if p.Limits.MemoryMB > 0 {
+		args = append(args, "--memory", strconv.Itoa(p.Limits.MemoryMB)+"m")
+	}
+
+	if p.Limits.Pids > 0 {
+		args = append(args, "--pids-limit", strconv.Itoa(p.Limits.Pids))
+	}
+
+	for _, v := range strings.Fields(p.Environment) {
+		args = append(args, "-e", v)
+	}
+	return args
+}
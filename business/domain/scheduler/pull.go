@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+// errImagePull is wrapped by the failures of pulling the image of a task.
+var errImagePull = errors.New("image pull failed")
+
+// pullProgressInterval is how often the progress of a pull is logged at most.
+const pullProgressInterval = time.Second * 5
+
+// pullImage makes sure the image the task runs with is available before it runs, it is pulled on every execution
+// with task.PullAlways and only when it is missing otherwise. The pull is bound by maxTimeForImagePull instead of the
+// execution deadline and returns how long it took.
+func (s *Scheduler) pullImage(ctx context.Context, tsk task.Task) (time.Duration, error) {
+	image := tsk.Image
+	if tsk.ImageDigest != "" && !tsk.FloatingTag {
+		image = tsk.ImageDigest
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.maxTimeForImagePull)
+	defer cancel()
+
+	if tsk.PullPolicy != task.PullAlways {
+		if _, err := s.runner.ImageDigest(ctx, image); err == nil {
+			return 0, nil
+		}
+	}
+
+	started := s.clock.Now()
+	s.logger.Info("pullImage", "status", fmt.Sprintf("pulling image %s for task %s", image, tsk.Id))
+
+	var logged time.Time
+	progress := func(line string) {
+		if now := s.clock.Now(); now.Sub(logged) >= pullProgressInterval {
+			logged = now
+			s.logger.Info("pullImage", "status", fmt.Sprintf("pulling image %s", image), "progress", line)
+		}
+	}
+
+	if err := s.runner.PullImage(ctx, image, progress); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return s.clock.Now().Sub(started), fmt.Errorf("%w: %s: timed out after %s: %w", errImagePull, image, s.maxTimeForImagePull, err)
+		}
+		return s.clock.Now().Sub(started), fmt.Errorf("%w: %s: %w", errImagePull, image, err)
+	}
+
+	took := s.clock.Now().Sub(started)
+	s.logger.Info("pullImage", "status", fmt.Sprintf("pulled image %s", image), "took", took.String())
+	return took, nil
+}
+
+// extendDeadline pushes the deadline of the executer back by how long its image took to pull and returns it.
+func (s *Scheduler) extendDeadline(executerId string, deadline time.Time, pulled time.Duration) time.Time {
+	if pulled <= 0 {
+		return deadline
+	}
+	deadline = deadline.Add(pulled)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ex, ok := s.executers[executerId]; ok {
+		ex.deadline = deadline
+		s.executers[executerId] = ex
+	}
+	return deadline
+}
@@ -0,0 +1,69 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/quota"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+// quotaRecheck is how long a task that is over the running quota of its user waits before it is sent back to
+// the tasks queue.
+const quotaRecheck = time.Second
+
+// quotaService represents the behaviour required for reading the limits of users.
+type quotaService interface {
+	GetQuota(ctx context.Context, userId uuid.UUID) (quota.Quota, error)
+}
+
+// acquireUserSlot reserves a running slot for the user of the task, returns false when the user already runs as
+// many tasks as their quota allows on this instance. Quota lookups that fail do not block execution.
+func (s *Scheduler) acquireUserSlot(tsk task.Task) bool {
+	var q quota.Quota
+	if s.quotas != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+		defer cancel()
+
+		var err error
+		q, err = s.quotas.GetQuota(ctx, tsk.UserId)
+		if err != nil {
+			s.logger.Error("quota", "status", fmt.Sprintf("failed to get quota of user %s", tsk.UserId), "msg", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !q.AllowsRunning(s.running[tsk.UserId]) {
+		return false
+	}
+	s.running[tsk.UserId]++
+	return true
+}
+
+func (s *Scheduler) releaseUserSlot(userId uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.running[userId]--
+	if s.running[userId] <= 0 {
+		delete(s.running, userId)
+	}
+}
+
+// deferOverQuota sends the task back to the tasks queue so it runs once one of the tasks of its user finishes.
+func (s *Scheduler) deferOverQuota(tsk task.Task) {
+	s.logger.Info("quota", "status", fmt.Sprintf("deferring task %s, user %s is at its running quota", tsk.Id, tsk.UserId))
+
+	select {
+	case <-s.shutdown:
+	case <-s.clock.After(quotaRecheck):
+	}
+
+	if err := s.publishTask(tsk, queueTasks); err != nil {
+		s.logger.Error("quota", "status", fmt.Sprintf("failed to requeue task %s", tsk.Id), "msg", err)
+	}
+}
@@ -0,0 +1,35 @@
+package scheduler
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/hamidoujand/task-scheduler/business/broker"
+	"github.com/hamidoujand/task-scheduler/business/metrics"
+)
+
+// recovered keeps a panic of a goroutine of the scheduler from crashing the process, it has to be deferred directly
+// since recover only stops a panic when it is called by the deferred function. The panic is logged with its stack
+// and handed to onPanic, which is optional and cleans up after the work that was interrupted.
+func (s *Scheduler) recovered(where string, onPanic func(err error)) {
+	rec := recover()
+	if rec == nil {
+		return
+	}
+
+	err := fmt.Errorf("panic: %v", rec)
+	s.logger.Error(where, "status", "recovered from panic", "msg", err, "stack", string(debug.Stack()))
+	metrics.AddPanic(where)
+
+	if onPanic != nil {
+		onPanic(err)
+	}
+}
+
+// redeliverOnPanic hands the message of a consumer that panicked to another consumer until it ran out of
+// redeliveries and is parked, so a message that always panics does not circle forever.
+func (s *Scheduler) redeliverOnPanic(msg broker.Delivery, consumer string) func(err error) {
+	return func(err error) {
+		s.redeliver(msg, consumer, msg.Queue, err)
+	}
+}
@@ -0,0 +1,101 @@
+package scheduler_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/broker/memory"
+	"github.com/hamidoujand/task-scheduler/business/domain/scheduler"
+	retryMemoryRepo "github.com/hamidoujand/task-scheduler/business/domain/scheduler/store/memory"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	taskMemoryRepo "github.com/hamidoujand/task-scheduler/business/domain/task/store/memory"
+)
+
+// panicRunner panics while it runs the "panic" command and runs every other command like benchRunner.
+type panicRunner struct {
+	benchRunner
+}
+
+func (r panicRunner) RunCommand(ctx context.Context, image string, command string, runArgs []string, cmdArgs []string, stdin []byte) (string, error) {
+	if command == "panic" {
+		panic("runner is broken")
+	}
+	return r.benchRunner.RunCommand(ctx, image, command, runArgs, cmdArgs, stdin)
+}
+
+func TestExecuterPanic(t *testing.T) {
+	broker := memory.New()
+	defer broker.Close()
+
+	taskService, err := task.NewService(&taskMemoryRepo.Repository{Tasks: make(map[uuid.UUID]task.Task)}, broker)
+	if err != nil {
+		t.Fatalf("expected to create task service: %s", err)
+	}
+
+	s, err := scheduler.New(scheduler.Config{
+		Broker:                  broker,
+		Logger:                  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		TaskService:             taskService,
+		RetryStore:              &retryMemoryRepo.Repository{},
+		MaxRunningTask:          1,
+		MaxTimeForTaskExecution: time.Minute,
+		Runner:                  panicRunner{benchRunner{duration: time.Millisecond}},
+		OutboxInterval:          time.Millisecond * 10,
+	})
+	if err != nil {
+		t.Fatalf("expected to create a scheduler: %s", err)
+	}
+
+	if err := s.Activate(); err != nil {
+		t.Fatalf("expected to activate the scheduler: %s", err)
+	}
+	defer s.Shutdown(context.Background())
+
+	wait := func(command string) task.Task {
+		t.Helper()
+
+		tsk, err := taskService.CreateTask(context.Background(), task.NewTask{
+			UserId:      uuid.New(),
+			Command:     command,
+			Image:       "alpine:3.20",
+			ScheduledAt: time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("expected to create the task: %s", err)
+		}
+
+		deadline := time.Now().Add(time.Second * 10)
+		for time.Now().Before(deadline) {
+			tsk, err = taskService.GetTaskById(context.Background(), tsk.Id)
+			if err != nil {
+				t.Fatalf("expected to get the task: %s", err)
+			}
+
+			if finished(tsk) {
+				return tsk
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+		t.Fatalf("expected task %s to finish, got %s", tsk.Id, tsk.Status)
+		return tsk
+	}
+
+	panicked := wait("panic")
+	if panicked.Status != task.StatusFailed {
+		t.Errorf("status= %s, got %s", task.StatusFailed, panicked.Status)
+	}
+
+	if panicked.ErrCategory != task.ErrorInternal {
+		t.Errorf("category= %s, got %s", task.ErrorInternal, panicked.ErrCategory)
+	}
+
+	//the executer released its slot, the next task still runs
+	completed := wait("date")
+	if completed.Status != task.StatusCompleted {
+		t.Errorf("status= %s, got %s: %s", task.StatusCompleted, completed.Status, completed.ErrMessage)
+	}
+}
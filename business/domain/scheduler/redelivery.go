@@ -0,0 +1,77 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hamidoujand/task-scheduler/business/broker"
+	"github.com/hamidoujand/task-scheduler/business/broker/messages"
+)
+
+// queueDead holds the messages that could not be processed, either because they are malformed or because they
+// kept failing for more than the redelivery cap, so they can be inspected instead of being lost.
+const queueDead = "queue_dead"
+
+// headerRedeliveries is the number of times a message was put back into its queue after processing failed.
+const headerRedeliveries = "x-redeliveries"
+
+// ack acknowledges a message that was processed, the message is gone from the broker after this point.
+func (s *Scheduler) ack(msg broker.Delivery, consumer string) {
+	if err := msg.Ack(); err != nil {
+		s.logger.Error(consumer, "status", "failed to ack()", "msg", err)
+	}
+}
+
+// redeliver puts a message whose processing failed back into its queue, once it failed more than the redelivery
+// cap it is parked in the dead queue instead. The copy is published before the original is acked so a crash in
+// between leads to a duplicate instead of a lost message.
+func (s *Scheduler) redeliver(msg broker.Delivery, consumer string, queue string, cause error) {
+	redeliveries, _ := msg.Headers[headerRedeliveries].(int64)
+
+	target := queue
+	if redeliveries >= int64(s.maxRedeliveries) {
+		s.logger.Error(consumer, "status", fmt.Sprintf("giving up after %d redeliveries, parking message in %s", redeliveries, queueDead), "msg", cause)
+		target = queueDead
+	} else {
+		s.logger.Warn(consumer, "status", fmt.Sprintf("processing failed, redelivering message %d/%d", redeliveries+1, s.maxRedeliveries), "msg", cause)
+	}
+
+	s.forward(msg, consumer, target, redeliveries+1)
+}
+
+// bury parks a message that can never be processed, like a malformed one, in the dead queue. A message of a newer
+// schema is redelivered instead, an upgraded instance can process it during a rolling upgrade.
+func (s *Scheduler) bury(msg broker.Delivery, consumer string, cause error) {
+	if errors.Is(cause, messages.ErrUnsupportedVersion) {
+		s.redeliver(msg, consumer, msg.Queue, cause)
+		return
+	}
+
+	s.logger.Error(consumer, "status", fmt.Sprintf("parking malformed message in %s", queueDead), "msg", cause)
+
+	redeliveries, _ := msg.Headers[headerRedeliveries].(int64)
+	s.forward(msg, consumer, queueDead, redeliveries)
+}
+
+func (s *Scheduler) forward(msg broker.Delivery, consumer string, queue string, redeliveries int64) {
+	headers := broker.Headers{}
+	for key, val := range msg.Headers {
+		headers[key] = val
+	}
+	headers[headerRedeliveries] = redeliveries
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+	defer cancel()
+
+	if err := s.broker.PublishWithConfirmAndHeaders(ctx, queue, msg.Body, headers); err != nil {
+		//let the broker hand the original to another consumer
+		s.logger.Error(consumer, "status", fmt.Sprintf("failed to publish message into %s, requeueing", queue), "msg", err)
+		if err := msg.Nack(true); err != nil {
+			s.logger.Error(consumer, "status", "failed to nack()", "msg", err)
+		}
+		return
+	}
+
+	s.ack(msg, consumer)
+}
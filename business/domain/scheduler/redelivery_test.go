@@ -0,0 +1,265 @@
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/broker"
+	"github.com/hamidoujand/task-scheduler/business/broker/memory"
+	"github.com/hamidoujand/task-scheduler/business/domain/scheduler"
+	retryMemoryRepo "github.com/hamidoujand/task-scheduler/business/domain/scheduler/store/memory"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	taskMemoryRepo "github.com/hamidoujand/task-scheduler/business/domain/task/store/memory"
+	"github.com/hamidoujand/task-scheduler/foundation/runtime"
+)
+
+// settleBroker records how the deliveries of every queue are settled and what was published into them,
+// failPublish fails the publishes it returns true for.
+type settleBroker struct {
+	*memory.Broker
+	failPublish func(queue string, headers broker.Headers) bool
+
+	mu        sync.Mutex
+	acks      map[string]int
+	nacks     map[string]int
+	published map[string][][]byte
+}
+
+func newSettleBroker() *settleBroker {
+	return &settleBroker{
+		Broker:    memory.New(),
+		acks:      make(map[string]int),
+		nacks:     make(map[string]int),
+		published: make(map[string][][]byte),
+	}
+}
+
+func (b *settleBroker) PublishWithConfirm(ctx context.Context, queue string, msg []byte) error {
+	return b.PublishWithConfirmAndHeaders(ctx, queue, msg, nil)
+}
+
+func (b *settleBroker) PublishWithConfirmAndHeaders(ctx context.Context, queue string, msg []byte, headers broker.Headers) error {
+	if b.failPublish != nil && b.failPublish(queue, headers) {
+		return errors.New("broker is down")
+	}
+
+	b.mu.Lock()
+	b.published[queue] = append(b.published[queue], msg)
+	b.mu.Unlock()
+	return b.Broker.PublishWithConfirmAndHeaders(ctx, queue, msg, headers)
+}
+
+// messages returns the bodies that were published into the queue.
+func (b *settleBroker) messages(queue string) [][]byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.published[queue]
+}
+
+func (b *settleBroker) Consume(queue string, consumerTag string) (<-chan broker.Delivery, error) {
+	return b.ConsumeWithPrefetch(queue, consumerTag, 1)
+}
+
+func (b *settleBroker) ConsumeWithPrefetch(queue string, consumerTag string, prefetch int) (<-chan broker.Delivery, error) {
+	deliveries, err := b.Broker.ConsumeWithPrefetch(queue, consumerTag, prefetch)
+	if err != nil {
+		return nil, err
+	}
+
+	recorded := make(chan broker.Delivery)
+	go func() {
+		defer close(recorded)
+		for d := range deliveries {
+			d.Acknowledger = &settleRecorder{Acknowledger: d.Acknowledger, broker: b, queue: queue}
+			recorded <- d
+		}
+	}()
+	return recorded, nil
+}
+
+// settled returns how many deliveries of the queue were acked and how many were nacked with requeue.
+func (b *settleBroker) settled(queue string) (acks int, nacks int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.acks[queue], b.nacks[queue]
+}
+
+type settleRecorder struct {
+	broker.Acknowledger
+	broker *settleBroker
+	queue  string
+}
+
+func (r *settleRecorder) Ack() error {
+	r.broker.mu.Lock()
+	r.broker.acks[r.queue]++
+	r.broker.mu.Unlock()
+	return r.Acknowledger.Ack()
+}
+
+func (r *settleRecorder) Nack(requeue bool) error {
+	if requeue {
+		r.broker.mu.Lock()
+		r.broker.nacks[r.queue]++
+		r.broker.mu.Unlock()
+	}
+	return r.Acknowledger.Nack(requeue)
+}
+
+// downStore fails every status update like a database that is down.
+type downStore struct {
+	*taskMemoryRepo.Repository
+}
+
+func (s downStore) Update(ctx context.Context, tsk task.Task) error {
+	return errors.New("database is down")
+}
+
+func (s downStore) UpdateMany(ctx context.Context, tsks []task.Task) ([]uuid.UUID, error) {
+	return nil, errors.New("database is down")
+}
+
+func newSettleScheduler(t *testing.T, b *settleBroker, taskService *task.Service, runner runtime.Runner) *scheduler.Scheduler {
+	t.Helper()
+
+	s, err := scheduler.New(scheduler.Config{
+		Broker:                  b,
+		Logger:                  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		TaskService:             taskService,
+		RetryStore:              &retryMemoryRepo.Repository{},
+		MaxRunningTask:          1,
+		MaxTimeForTaskExecution: time.Minute,
+		MaxRedeliveries:         2,
+		UpdateAttempts:          1,
+		Runner:                  runner,
+		OutboxInterval:          time.Millisecond * 10,
+	})
+	if err != nil {
+		t.Fatalf("expected to create a scheduler: %s", err)
+	}
+
+	if err := s.Activate(); err != nil {
+		t.Fatalf("expected to activate the scheduler: %s", err)
+	}
+	t.Cleanup(func() { s.Shutdown(context.Background()) })
+
+	return s
+}
+
+// eventually fails the test when cond does not hold within a few seconds.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second * 5)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	t.Fatalf("expected %s", what)
+}
+
+func TestAckOnSuccess(t *testing.T) {
+	b := newSettleBroker()
+	defer b.Close()
+
+	taskService, err := task.NewService(&taskMemoryRepo.Repository{Tasks: make(map[uuid.UUID]task.Task)}, b)
+	if err != nil {
+		t.Fatalf("expected to create task service: %s", err)
+	}
+	newSettleScheduler(t, b, taskService, benchRunner{duration: time.Millisecond})
+
+	tsk, err := taskService.CreateTask(context.Background(), task.NewTask{
+		UserId:      uuid.New(),
+		Command:     "date",
+		Image:       "alpine:3.20",
+		ScheduledAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("expected to create the task: %s", err)
+	}
+
+	eventually(t, "the success message to be acked", func() bool {
+		acks, _ := b.settled("queue_success")
+		return acks == 1
+	})
+
+	tsk, err = taskService.GetTaskById(context.Background(), tsk.Id)
+	if err != nil {
+		t.Fatalf("expected to get the task: %s", err)
+	}
+
+	if tsk.Status != task.StatusCompleted {
+		t.Errorf("status= %s, got %s", task.StatusCompleted, tsk.Status)
+	}
+
+	//each message is acked once it was processed and never given back
+	for _, queue := range []string{"queue_tasks", "queue_success"} {
+		if acks, nacks := b.settled(queue); acks != 1 || nacks != 0 {
+			t.Errorf("%s: acks/nacks= %d/%d, got %d/%d", queue, 1, 0, acks, nacks)
+		}
+	}
+}
+
+func TestRedeliveryCap(t *testing.T) {
+	b := newSettleBroker()
+	defer b.Close()
+
+	//the first redelivery can not be published, its original goes back into the queue instead of being lost
+	var failOnce sync.Once
+	b.failPublish = func(queue string, headers broker.Headers) bool {
+		failed := false
+		if redeliveries, _ := headers["x-redeliveries"].(int64); queue == "queue_success" && redeliveries == 1 {
+			failOnce.Do(func() { failed = true })
+		}
+		return failed
+	}
+
+	repo := downStore{&taskMemoryRepo.Repository{Tasks: make(map[uuid.UUID]task.Task)}}
+	taskService, err := task.NewService(repo, b)
+	if err != nil {
+		t.Fatalf("expected to create task service: %s", err)
+	}
+	newSettleScheduler(t, b, taskService, benchRunner{duration: time.Millisecond})
+
+	if _, err := taskService.CreateTask(context.Background(), task.NewTask{
+		UserId:      uuid.New(),
+		Command:     "date",
+		Image:       "alpine:3.20",
+		ScheduledAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("expected to create the task: %s", err)
+	}
+
+	eventually(t, "the success message to be parked in queue_dead", func() bool {
+		stats, err := b.QueueStats("queue_dead")
+		return err == nil && stats.Messages == 1
+	})
+
+	//delivered with 0, 0 again after the nack, 1 and 2 redeliveries, the last one is parked
+	if acks, nacks := b.settled("queue_success"); acks != 3 || nacks != 1 {
+		t.Errorf("acks/nacks= %d/%d, got %d/%d", 3, 1, acks, nacks)
+	}
+
+	dead, err := b.Broker.Consume("queue_dead", "")
+	if err != nil {
+		t.Fatalf("expected to consume queue_dead: %s", err)
+	}
+
+	select {
+	case d := <-dead:
+		if redeliveries, _ := d.Headers["x-redeliveries"].(int64); redeliveries != 3 {
+			t.Errorf("redeliveries= %d, got %d", 3, redeliveries)
+		}
+		d.Ack()
+	case <-time.After(time.Second):
+		t.Fatal("expected to receive the parked message")
+	}
+}
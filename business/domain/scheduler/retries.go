@@ -0,0 +1,78 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	"github.com/hamidoujand/task-scheduler/business/metrics"
+)
+
+// retrySweepInterval is how often the retry counters are swept.
+const retrySweepInterval = time.Minute * 10
+
+// retryWindow returns how long the retry counter of a task with maxRetries is kept after its last retry, by then
+// every remaining retry would have pulled its image and run out of time.
+func (s *Scheduler) retryWindow(maxRetries int) time.Duration {
+	if s.retryTTL > 0 {
+		return s.retryTTL
+	}
+	return time.Duration(maxRetries+1) * (s.maxTimeForImagePull + s.maxTimeForTaskExecution)
+}
+
+// clearRetries deletes the retry counter of the task once it finished, a counter that could not be deleted expires
+// on its own.
+func (s *Scheduler) clearRetries(ctx context.Context, consumer string, tsk task.Task) {
+	if err := s.retryStore.ClearRetries(ctx, tsk.Id.String()); err != nil {
+		s.logger.Warn(consumer, "status", fmt.Sprintf("failed to clear retries of task %s", tsk.Id), "msg", err)
+		return
+	}
+	metrics.AddRetryCounterCleared()
+}
+
+// SweepRetries sweeps the retry counters of the tasks that were never cleared until dispatch is disabled, only the
+// instance that runs the scheduled tasks monitor sweeps them.
+func (s *Scheduler) SweepRetries() error {
+	s.mu.RLock()
+	stop := s.monitorStop
+	s.mu.RUnlock()
+
+	go func() {
+		ticker := s.clock.NewTicker(retrySweepInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.shutdown:
+				return
+			case <-stop:
+				return
+			case <-ticker.C():
+			}
+
+			if s.holdsMonitorLock() {
+				s.sweepRetries()
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (s *Scheduler) sweepRetries() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+	defer cancel()
+
+	counters, swept, err := s.retryStore.SweepRetries(ctx, s.retryWindow(s.MaxRetries()))
+	if err != nil {
+		s.logger.Error("sweepRetries", "status", "failed to sweep retry counters", "msg", err)
+		return
+	}
+
+	metrics.SetRetryCounters(counters)
+	metrics.AddRetryCountersSwept(swept)
+	if swept > 0 {
+		s.logger.Info("sweepRetries", "status", fmt.Sprintf("swept %d of %d retry counters", swept, counters))
+	}
+}
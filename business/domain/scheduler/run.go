@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+// maxTimeForFingerprint bounds the runtime calls used for fingerprinting the executor.
+const maxTimeForFingerprint = time.Second * 5
+
+// recordRun stores the execution attempt of the task under the id it was claimed with along with the fingerprint of
+// the environment and the security profile it ran with, recording is best effort and never fails the task.
+func (s *Scheduler) recordRun(tsk task.Task, runId uuid.UUID, image string, security task.SecurityProfile, runErr error) {
+	nr := task.NewRun{
+		Id:          runId,
+		TaskId:      tsk.Id,
+		Status:      task.StatusCompleted,
+		Environment: s.fingerprint(image),
+		Security:    security,
+	}
+
+	if runErr != nil {
+		nr.Status = task.StatusFailed
+		nr.ErrMessage = runErr.Error()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+	defer cancel()
+
+	if _, err := s.taskService.CreateRun(ctx, nr); err != nil {
+		s.logger.Error("recordRun", "status", fmt.Sprintf("failed to record run of task %s", tsk.Id), "msg", err)
+	}
+}
+
+// fingerprint collects the environment of this executor, values that can not be read are left empty.
+func (s *Scheduler) fingerprint(image string) task.Environment {
+	ctx, cancel := context.WithTimeout(context.Background(), maxTimeForFingerprint)
+	defer cancel()
+
+	env := task.Environment{
+		WorkerId:    s.id,
+		WorkerBuild: s.build,
+		Runtime:     s.runner.Name(),
+	}
+
+	version, err := s.runner.Version(ctx)
+	if err != nil {
+		s.logger.Warn("fingerprint", "status", fmt.Sprintf("failed to read %s version", s.runner.Name()), "msg", err)
+	} else {
+		env.DockerVersion = version.Version
+		env.OS = version.OS
+		env.Arch = version.Arch
+	}
+
+	digest, err := s.runner.ImageDigest(ctx, image)
+	if err != nil {
+		s.logger.Warn("fingerprint", "status", fmt.Sprintf("failed to read digest of image %s", image), "msg", err)
+	} else {
+		env.ImageDigest = digest
+	}
+
+	return env
+}
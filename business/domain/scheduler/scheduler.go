@@ -3,21 +3,18 @@ package scheduler
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/hamidoujand/task-scheduler/business/broker/rabbitmq"
-	redisRepo "github.com/hamidoujand/task-scheduler/business/domain/scheduler/store/redis"
+	"github.com/hamidoujand/task-scheduler/business/broker"
+	"github.com/hamidoujand/task-scheduler/business/broker/messages"
 	"github.com/hamidoujand/task-scheduler/business/domain/task"
-	"github.com/hamidoujand/task-scheduler/foundation/docker"
-	"github.com/rabbitmq/amqp091-go"
-	"github.com/redis/go-redis/v9"
+	"github.com/hamidoujand/task-scheduler/foundation/clock"
+	"github.com/hamidoujand/task-scheduler/foundation/runtime"
 )
 
 const (
@@ -27,40 +24,218 @@ const (
 	queueRetry   = "queue_retry"
 )
 
+// RetryStore represents the storage that keeps track of retries of each task, redis and postgres implement it for
+// production and store/memory for tests.
+type RetryStore interface {
+	//Increment expires the counter ttl after its last increment.
+	Increment(ctx context.Context, taskId string, max int, ttl time.Duration) (int, bool, error)
+	ClearRetries(ctx context.Context, taskId string) error
+	//SweepRetries makes sure counters that were not incremented for ttl go away, it returns the number of counters
+	//along with the number of swept ones.
+	SweepRetries(ctx context.Context, ttl time.Duration) (int, int, error)
+}
+
 // Scheduler represents set of APIs used for scheduling tasks using worker.
 type Scheduler struct {
-	rClient                 *rabbitmq.Client
-	redisRepo               *redisRepo.Repository
+	id                      string
+	build                   string
+	broker                  broker.Broker
+	retryStore              RetryStore
+	leaseStore              leaseStore
+	leaseTTL                time.Duration
 	logger                  *slog.Logger
+	clock                   clock.Clock
 	taskService             *task.Service
 	maxRetries              int
 	maxTimeForUpdateOps     time.Duration
 	maxTimeForTaskExecution time.Duration
+	maxTimeForImagePull     time.Duration
 	wg                      sync.WaitGroup
+	handlers                sync.WaitGroup
+	handlerWorkers          int
+	statuses                *statusBatch
+	partitions              partitioner
+	retryTTL                time.Duration
+	retentionMonths         int
+	executionClaims         claimStore
+	lateAfter               time.Duration
+	usage                   usageRecorder
 	mu                      sync.RWMutex
 	sem                     chan struct{}
 	shutdown                chan struct{}
-	executers               map[string]context.CancelFunc
+	executers               map[string]executer
+	active                  bool
+	monitorStop             chan struct{}
+	breaker                 breaker
+	affinityTimeout         time.Duration
+	quotas                  quotaService
+	running                 map[uuid.UUID]int
+	mode                    Mode
+	maxRedeliveries         int
+	workers                 registry
+	assignTimeout           time.Duration
+	outboxInterval          time.Duration
+	slots                   slotStore
+	imageLimits             map[string]int
+	delays                  delayStore
+	queuedTimeout           time.Duration
+	monitorLock             monitorLock
+	secrets                 secretResolver
+	maxResultBytes          int
+	blackouts               blackouts
+	draining                bool
+	retries                 retryCounts
+	backlog                 backlog
+	notifier                notifier
+	presets                 presetResolver
+	networks                task.NetworkPolicy
+	internalNetwork         string
+	egressNetwork           string
+	security                task.SecurityProfile
+	runner                  runtime.Runner
+	warm                    *warmPool
+	spills                  spillStore
+	updateAttempts          int
+	updateBackoff           time.Duration
+	networkMu               sync.Mutex
+	internalReady           bool
 }
 
 // Config represents all of required configuration to create a scheduler.
 type Config struct {
-	RabbitClient            *rabbitmq.Client
+	Build                   string
+	Broker                  broker.Broker
 	Logger                  *slog.Logger
+	Clock                   clock.Clock
 	TaskService             *task.Service
-	RedisRepo               *redisRepo.Repository
+	RetryStore              RetryStore
+	LeaseStore              leaseStore
+	LeaseTTL                time.Duration
 	MaxRunningTask          int
 	MaxRetries              int
 	MaxTimeForUpdateOps     time.Duration
 	MaxTimeForTaskExecution time.Duration
+	//MaxTimeForImagePull bounds pulling the image of a task before it runs, the pull does not count against
+	//MaxTimeForTaskExecution. Defaults to 5 minutes.
+	MaxTimeForImagePull time.Duration
+	//BreakerThreshold is the number of consecutive executor infrastructure failures that pause dispatch.
+	BreakerThreshold int
+	//BreakerProbeInterval is how often the executor is probed while dispatch is paused.
+	BreakerProbeInterval time.Duration
+	//Runner is the container runtime tasks are executed with, defaults to docker.
+	Runner runtime.Runner
+	//WarmPool is how many idle containers are kept running per image, tasks of the image without a preset that
+	//run with the default network mode exec into one instead of starting their own. It requires a runner that
+	//implements runtime.Warmer.
+	WarmPool map[string]int
+	//WarmMaxUses is how many tasks a warm container runs before it is replaced, defaults to 1.
+	WarmMaxUses int
+	//WarmMaxIdle is how long a warm container may stay idle before it is replaced, defaults to 10 minutes.
+	WarmMaxIdle time.Duration
+	//Probe checks whether the executor is reachable, defaults to pinging the runtime.
+	Probe func(ctx context.Context) error
+	//AffinityTimeout is how long a retried task waits for the worker of its previous attempt before any
+	//worker may claim it, a negative value disables affinity.
+	AffinityTimeout time.Duration
+	//Quotas is optional, without it users can run any number of tasks at the same time.
+	Quotas quotaService
+	//Mode selects which part of the pipeline this scheduler runs, defaults to all of it.
+	Mode Mode
+	//MaxRedeliveries is how many times a message whose processing failed is put back into its queue before it is
+	//parked in "queue_dead", defaults to 5.
+	MaxRedeliveries int
+	//Workers is optional, with it due and retried tasks are assigned to the least loaded worker instead of
+	//the shared tasks queue.
+	Workers registry
+	//AssignTimeout is how long a task waits inside of the queue of its worker before it goes back to the
+	//shared tasks queue.
+	AssignTimeout time.Duration
+	//OutboxInterval is how often the tasks left inside of the outbox are published, defaults to 1s.
+	OutboxInterval time.Duration
+	//ImageLimits is how many tasks of an image, named without its tag, may run at the same time across all
+	//instances. Images without a limit are only bound by MaxRunningTask.
+	ImageLimits map[string]int
+	//SlotStore keeps the running tasks of the limited images, it is required when ImageLimits is set.
+	SlotStore slotStore
+	//DelayStore is optional, with it tasks that are not due yet wait inside of it instead of holding an executer
+	//until they are due.
+	DelayStore delayStore
+	//QueuedTimeout is how long a task may stay queued before it is considered lost and claimed again, it must be
+	//longer than a task waits in the queue plus all of its attempts. Defaults to 15m.
+	QueuedTimeout time.Duration
+	//MonitorLock is optional, with it only the instance that holds the lock monitors the scheduled tasks so
+	//replicas do not publish the same due tasks.
+	MonitorLock monitorLock
+	//Secrets is optional, without it "secretRef:NAME" values are passed to the tasks as they are.
+	Secrets secretResolver
+	//MaxResultBytes is how much of the output of a task is kept, the rest is cut off with a marker. Zero keeps all
+	//of it.
+	MaxResultBytes int
+	//Blackouts is optional, with it tasks that become due during a maintenance window or while execution is paused
+	//are deferred until it is over.
+	Blackouts blackouts
+	//BacklogThreshold is the depth of the tasks queue above which a backlog is reported once it lasts for
+	//BacklogDuration, zero disables the backlog monitor.
+	BacklogThreshold int
+	BacklogDuration  time.Duration
+	//BacklogPollInterval is how often the depth of the tasks queue is read, defaults to 30s.
+	BacklogPollInterval time.Duration
+	//BacklogAlert is optional, with it a backlog and its recovery are posted to it.
+	BacklogAlert alerter
+	//Notifier is optional, every task that completed or failed for good is handed to it once its status is saved.
+	Notifier notifier
+	//Presets is optional, without it tasks that reference a preset fail when they are executed.
+	Presets presetResolver
+	//Networks decides which network modes tasks run with, the zero value runs every task without a network.
+	Networks task.NetworkPolicy
+	//InternalNetwork is the docker network of the internal mode, it is created with "--internal" when it is
+	//missing. Defaults to "tasks-internal".
+	InternalNetwork string
+	//EgressNetwork is the docker network of the egress mode, defaults to "bridge".
+	EgressNetwork string
+	//Security is the hardening containers run with unless their preset has its own, the zero value applies none.
+	Security task.SecurityProfile
+	//UpdateAttempts is how many times the status of a finished task is written before it is spilled or
+	//redelivered, defaults to 3.
+	UpdateAttempts int
+	//UpdateBackoff is the wait after the first failed write of a status, it doubles after every attempt. Defaults
+	//to 1s.
+	UpdateBackoff time.Duration
+	//SpillStore is optional, with it the statuses that could not be written are kept inside of it and replayed
+	//once the database is back instead of being redelivered until they are parked in "queue_dead".
+	SpillStore spillStore
+	//HandlerWorkers is how many messages of each of the success, failed and retry queues are handled at the same
+	//time, defaults to 10.
+	HandlerWorkers int
+	//StatusBatchInterval is how long the status of a finished task waits for others to be written along with it in
+	//a single update, zero writes every status on its own.
+	StatusBatchInterval time.Duration
+	//StatusBatchSize is how many statuses are written at most in a single update, defaults to 100.
+	StatusBatchSize int
+	//Partitions is optional, with it the monthly partitions of the tasks are created ahead of time and the ones
+	//older than RetentionMonths are dropped.
+	Partitions partitioner
+	//RetentionMonths is how many months of tasks are kept besides the current one, zero keeps all of them.
+	RetentionMonths int
+	//RetryTTL is how long the retry counter of a task is kept after its last retry, defaults to the time the
+	//remaining retries of the task take at most.
+	RetryTTL time.Duration
+	//ClaimStore is optional, with it a task that is delivered more than once is never executed by two executers at
+	//the same time.
+	ClaimStore claimStore
+	//LateAfter is how long after its scheduled time a task that did not start is reported as late, late tasks are
+	//posted to BacklogAlert as well. Zero disables the late tasks monitor.
+	LateAfter time.Duration
+	//Usage is optional, with it the resources consumed by every execution are recorded.
+	Usage usageRecorder
 }
 
 // New creates a scheduler.
 func New(conf Config) (*Scheduler, error) {
 	//register queues
-	queues := [...]string{queueSuccess, queueFailed, queueRetry, queueTasks}
+	queues := [...]string{queueSuccess, queueFailed, queueRetry, queueTasks, queueDead}
 	for _, name := range queues {
-		if err := conf.RabbitClient.DeclareQueue(name); err != nil {
+		if err := conf.Broker.DeclareQueue(name); err != nil {
 			return nil, fmt.Errorf("declare queue: %w", err)
 		}
 	}
@@ -82,18 +257,217 @@ func New(conf Config) (*Scheduler, error) {
 		return nil, fmt.Errorf("max time for task execution must be greater than 0")
 	}
 
-	return &Scheduler{
-		rClient:                 conf.RabbitClient,
+	if conf.MaxTimeForImagePull <= 0 {
+		conf.MaxTimeForImagePull = time.Minute * 5
+	}
+
+	if conf.RetryStore == nil {
+		return nil, errors.New("retry store is required")
+	}
+
+	//tests drive the scheduler with a fake clock
+	if conf.Clock == nil {
+		conf.Clock = clock.New()
+	}
+
+	//also bounds confirmed publishes
+	if conf.MaxTimeForUpdateOps <= 0 {
+		conf.MaxTimeForUpdateOps = time.Minute
+	}
+
+	//only used in standby mode
+	if conf.LeaseTTL <= 0 {
+		conf.LeaseTTL = time.Second * 15
+	}
+
+	if conf.BreakerThreshold <= 0 {
+		conf.BreakerThreshold = 5
+	}
+
+	if conf.BreakerProbeInterval <= 0 {
+		conf.BreakerProbeInterval = time.Second * 30
+	}
+
+	if conf.Runner == nil {
+		conf.Runner = runtime.NewDocker()
+	}
+
+	if conf.Probe == nil {
+		conf.Probe = conf.Runner.Ping
+	}
+
+	if conf.WarmMaxUses <= 0 {
+		conf.WarmMaxUses = 1
+	}
+
+	if conf.WarmMaxIdle <= 0 {
+		conf.WarmMaxIdle = time.Minute * 10
+	}
+
+	var warm *warmPool
+	if len(conf.WarmPool) > 0 {
+		warmer, ok := conf.Runner.(runtime.Warmer)
+		if !ok {
+			return nil, fmt.Errorf("warm pool: runtime %s can not keep containers warm", conf.Runner.Name())
+		}
+		warm = newWarmPool(warmer, conf.WarmPool, conf.WarmMaxUses, conf.WarmMaxIdle)
+	}
+
+	if conf.AffinityTimeout == 0 {
+		conf.AffinityTimeout = time.Second * 30
+	}
+
+	if conf.MaxRedeliveries <= 0 {
+		conf.MaxRedeliveries = 5
+	}
+
+	if conf.AssignTimeout <= 0 {
+		conf.AssignTimeout = time.Second * 30
+	}
+
+	if conf.OutboxInterval <= 0 {
+		conf.OutboxInterval = time.Second
+	}
+
+	if conf.QueuedTimeout <= 0 {
+		conf.QueuedTimeout = time.Minute * 15
+	}
+
+	if conf.BacklogPollInterval <= 0 {
+		conf.BacklogPollInterval = time.Second * 30
+	}
+
+	if conf.UpdateAttempts <= 0 {
+		conf.UpdateAttempts = 3
+	}
+
+	if conf.UpdateBackoff <= 0 {
+		conf.UpdateBackoff = time.Second
+	}
+
+	if conf.HandlerWorkers <= 0 {
+		conf.HandlerWorkers = 10
+	}
+
+	if conf.StatusBatchSize <= 0 {
+		conf.StatusBatchSize = 100
+	}
+
+	if conf.InternalNetwork == "" {
+		conf.InternalNetwork = "tasks-internal"
+	}
+
+	if conf.EgressNetwork == "" {
+		conf.EgressNetwork = "bridge"
+	}
+
+	for _, mode := range append([]task.NetworkMode{conf.Networks.Mode("")}, conf.Networks.Allowed...) {
+		if _, err := task.ParseNetworkMode(string(mode)); err != nil {
+			return nil, fmt.Errorf("network policy: %w", err)
+		}
+	}
+
+	if err := conf.Security.Validate(); err != nil {
+		return nil, fmt.Errorf("security profile: %w", err)
+	}
+
+	if conf.MonitorLock != nil && conf.MonitorLock.TTL() <= 0 {
+		return nil, fmt.Errorf("monitor lock ttl must be greater than 0: %s", conf.MonitorLock.TTL())
+	}
+
+	if len(conf.ImageLimits) > 0 && conf.SlotStore == nil {
+		return nil, errors.New("slot store is required for image limits")
+	}
+
+	imageLimits := make(map[string]int, len(conf.ImageLimits))
+	for image, limit := range conf.ImageLimits {
+		if limit <= 0 {
+			return nil, fmt.Errorf("limit of image %s must be greater than 0: %d", image, limit)
+		}
+		imageLimits[imageName(image)] = limit
+	}
+
+	s := Scheduler{
+		id:                      uuid.NewString(),
+		build:                   conf.Build,
+		broker:                  conf.Broker,
 		logger:                  conf.Logger,
+		clock:                   conf.Clock,
 		taskService:             conf.TaskService,
-		redisRepo:               conf.RedisRepo,
+		retryStore:              conf.RetryStore,
+		leaseStore:              conf.LeaseStore,
+		leaseTTL:                conf.LeaseTTL,
 		maxRetries:              conf.MaxRetries,
 		sem:                     sem,
 		shutdown:                make(chan struct{}),
-		executers:               make(map[string]context.CancelFunc),
+		executers:               make(map[string]executer),
 		maxTimeForUpdateOps:     conf.MaxTimeForUpdateOps,
 		maxTimeForTaskExecution: conf.MaxTimeForTaskExecution,
-	}, nil
+		maxTimeForImagePull:     conf.MaxTimeForImagePull,
+		breaker: breaker{
+			threshold:     conf.BreakerThreshold,
+			probeInterval: conf.BreakerProbeInterval,
+			probe:         conf.Probe,
+		},
+		affinityTimeout: conf.AffinityTimeout,
+		quotas:          conf.Quotas,
+		running:         make(map[uuid.UUID]int),
+		mode:            conf.Mode,
+		maxRedeliveries: conf.MaxRedeliveries,
+		workers:         conf.Workers,
+		assignTimeout:   conf.AssignTimeout,
+		outboxInterval:  conf.OutboxInterval,
+		slots:           conf.SlotStore,
+		imageLimits:     imageLimits,
+		delays:          conf.DelayStore,
+		queuedTimeout:   conf.QueuedTimeout,
+		monitorLock:     conf.MonitorLock,
+		secrets:         conf.Secrets,
+		maxResultBytes:  conf.MaxResultBytes,
+		blackouts:       conf.Blackouts,
+		backlog: backlog{
+			threshold: conf.BacklogThreshold,
+			duration:  conf.BacklogDuration,
+			interval:  conf.BacklogPollInterval,
+			alerter:   conf.BacklogAlert,
+		},
+		notifier:        conf.Notifier,
+		presets:         conf.Presets,
+		networks:        conf.Networks,
+		internalNetwork: conf.InternalNetwork,
+		egressNetwork:   conf.EgressNetwork,
+		security:        conf.Security,
+		runner:          conf.Runner,
+		warm:            warm,
+		spills:          conf.SpillStore,
+		updateAttempts:  conf.UpdateAttempts,
+		updateBackoff:   conf.UpdateBackoff,
+		handlerWorkers:  conf.HandlerWorkers,
+		partitions:      conf.Partitions,
+		retentionMonths: conf.RetentionMonths,
+		retryTTL:        conf.RetryTTL,
+		executionClaims: conf.ClaimStore,
+		lateAfter:       conf.LateAfter,
+		usage:           conf.Usage,
+	}
+
+	if conf.StatusBatchInterval > 0 {
+		s.statuses = &statusBatch{
+			interval: conf.StatusBatchInterval,
+			size:     conf.StatusBatchSize,
+			clock:    conf.Clock,
+			write:    s.writeStatuses,
+		}
+	}
+
+	//standalone workers also consume the tasks assigned to them
+	if s.mode == ModeExecute {
+		if err := s.declareWorkerQueue(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &s, nil
 }
 
 // Shutdown is going to provide a graceful shutdown to all currently running executers.
@@ -106,8 +480,8 @@ func (s *Scheduler) Shutdown(ctx context.Context) error {
 		s.mu.RLock()
 		defer s.mu.RUnlock()
 
-		for _, cancel := range s.executers {
-			cancel()
+		for _, ex := range s.executers {
+			ex.cancel()
 		}
 	}()
 
@@ -121,52 +495,113 @@ func (s *Scheduler) Shutdown(ctx context.Context) error {
 	//waiting ...
 	select {
 	case <-ch:
-		//all graceful shutdown
-		return nil
+		//the results of the executers are saved before the connections go away
+		return s.drainHandlers(ctx)
 	case <-ctx.Done():
 		//forced shutdown
 		return ctx.Err()
 	}
 }
 
-// ConsumeTasks will listen to the "tasks" queue for new tasks.
+// ConsumeTasks will listen to the "tasks" queue for new tasks, standalone workers also listen to the queue of the
+// tasks assigned to them. A drained scheduler starts listening once it is resumed.
 func (s *Scheduler) ConsumeTasks() error {
-	msgs, err := s.rClient.Consumer(queueTasks)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.draining {
+		return nil
+	}
+	return s.consumeTaskQueues()
+}
+
+func (s *Scheduler) consumeTaskQueues() error {
+	for _, queue := range s.taskQueues() {
+		msgs, err := s.broker.Consume(queue, s.consumerTag(queue))
+		if err != nil {
+			return fmt.Errorf("create consumer of %s: %w", queue, err)
+		}
+
+		go s.consumeTasks(msgs)
+	}
+
+	return nil
+}
+
+func (s *Scheduler) taskQueues() []string {
+	if s.mode == ModeExecute {
+		return []string{queueTasks, workerQueue(s.id)}
+	}
+	return []string{queueTasks}
+}
+
+func (s *Scheduler) consumeTasks(msgs <-chan broker.Delivery) {
+	for msg := range msgs {
+		select {
+		case <-s.shutdown:
+			//do not proccess messages any more.
+			return
+		default:
+			s.consumeTask(msg)
+		}
+	}
+}
+
+func (s *Scheduler) consumeTask(msg broker.Delivery) {
+	defer s.recovered("consumeTasks", s.redeliverOnPanic(msg, "consumeTasks"))
+
+	//retried tasks wait for the worker of their previous attempt for a while
+	if !s.claims(msg) {
+		if err := s.deferTask(msg); err != nil {
+			s.logger.Error("consumeTasks", "status", "failed to defer task to its preferred worker", "msg", err)
+		}
+		return
+	}
+
+	assigned, _ := msg.Headers[headerAssignedWorker].(string)
+
+	tsk, err := s.parseTask(msg.Body)
 	if err != nil {
-		return fmt.Errorf("create consumer: %w", err)
+		s.bury(msg, "consumeTasks", err)
+		s.releaseAssignment(assigned)
+		return
 	}
 
-	//consumer
-	go func() {
-		for msg := range msgs {
-			select {
-			case <-s.shutdown:
-				//do not proccess messages any more.
-				return
-			default:
-				if err := msg.Ack(false); err != nil {
-					s.logger.Error("consumeTasks", "status", "failed to ack()", "msg", err)
-					continue
-				}
+	if s.finished(tsk) {
+		s.ack(msg, "consumeTasks")
+		s.releaseAssignment(assigned)
+		return
+	}
 
-				tsk, err := s.parseTask(msg.Body)
-				if err != nil {
-					s.logger.Error("consumeTasks", "status", "failed to parse task from message body", "msg", err)
-					continue
-				}
+	//tasks that are not due yet wait inside of the delay store instead of an executer
+	if s.delayTask(tsk) {
+		s.ack(msg, "consumeTasks")
+		s.releaseAssignment(assigned)
+		return
+	}
 
-				if err := s.submitTask(tsk); err != nil {
-					s.logger.Error("consumeTasks", "status", "failed to submit task to executer", "msg", err)
-					continue
-				}
-			}
+	//the task is not at fault, hand it to another instance
+	if err := s.submitTask(tsk, assigned); err != nil {
+		s.logger.Error("consumeTasks", "status", "failed to submit task to executer, requeueing", "msg", err)
+		//the requeued message keeps its assignment, whoever handles it releases the load
+		if err := msg.Nack(true); err != nil {
+			s.logger.Error("consumeTasks", "status", "failed to nack()", "msg", err)
 		}
-	}()
+		return
+	}
 
-	return nil
+	//the executer owns the task from now on
+	s.ack(msg, "consumeTasks")
 }
 
-func (s *Scheduler) submitTask(tsk task.Task) error {
+// submitTask executes the task once a slot is free, assigned is the worker whose load the task is released from
+// after it is handled.
+func (s *Scheduler) submitTask(tsk task.Task, assigned string) error {
+	//dispatch is paused while the executor infrastructure is down
+	if err := s.waitForDispatch(); err != nil {
+		return err
+	}
+
 	//wait for a semaphore
 	select {
 	case <-s.shutdown:
@@ -182,17 +617,22 @@ func (s *Scheduler) submitTask(tsk task.Task) error {
 		s.maxTimeForTaskExecution = time.Second * 30
 	}
 
-	//set a deadline
-	deadline := time.Now().Add(s.maxTimeForTaskExecution)
+	//the time spent waiting for the task to be due does not count against it
+	deadline := s.executionDeadline(tsk)
 
-	//create a new ctx that only scheduler uses to control executers
-	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	//create a new ctx that only scheduler uses to control executers, the deadline applies once the image is pulled
+	ctx, cancel := context.WithCancel(context.Background())
 
 	//register executer
 	func() {
 		s.mu.Lock()
 		defer s.mu.Unlock()
-		s.executers[executerId] = cancel
+		s.executers[executerId] = executer{
+			cancel:    cancel,
+			taskId:    tsk.Id,
+			startedAt: s.clock.Now(),
+			deadline:  deadline,
+		}
 	}()
 
 	//create the executer goroutine
@@ -208,34 +648,131 @@ func (s *Scheduler) submitTask(tsk task.Task) error {
 			s.wg.Done()
 			//release semaphore
 			s.sem <- struct{}{}
+			s.releaseAssignment(assigned)
 		}()
 
-		//actual task running logic
-		timeTillExecution := time.Until(tsk.ScheduledAt)
+		//a panic fails the task instead of the process, retrying it would panic again
+		defer s.recovered("executer", func(err error) {
+			tsk.ErrMessage = err.Error()
+			tsk.ErrCategory = task.ErrorInternal
+			tsk.Status = task.StatusFailed
+			if err := s.publishTask(tsk, queueFailed); err != nil {
+				s.logger.Error("executer", "status", fmt.Sprintf("failed to publish task %s to failed queue", tsk.Id), "msg", err)
+			}
+		})
+
+		//only tasks that could not be delayed wait here
+		timeTillExecution := tsk.ScheduledAt.Sub(s.clock.Now())
 		if timeTillExecution > 0 {
 			//sleep
-			time.Sleep(timeTillExecution)
+			<-s.clock.After(timeTillExecution)
+		}
+
+		//no task starts during maintenance
+		if s.deferBlackout(tsk) {
+			return
+		}
+
+		//a message that is delivered again while its task still runs elsewhere waits for that execution
+		runId := uuid.New()
+		if !s.claimExecution(tsk, runId, deadline) {
+			s.deferClaimed(tsk)
+			return
 		}
+		defer s.releaseExecution(tsk, runId)
 
-		var builder strings.Builder
-		for _, env := range strings.Split(tsk.Environment, " ") {
-			builder.WriteString("-e ")
-			builder.WriteString(env)
-			builder.WriteByte(' ')
+		//users can not run more tasks at the same time than their quota allows
+		if !s.acquireUserSlot(tsk) {
+			s.deferOverQuota(tsk)
+			return
+		}
+		defer s.releaseUserSlot(tsk.UserId)
+
+		//heavyweight images can not take over every executer of the cluster
+		if !s.acquireImageSlot(tsk, executerId) {
+			s.deferOverLimit(tsk)
+			return
 		}
+		defer s.releaseImageSlot(tsk, executerId)
 
-		dockerArgs := []string{builder.String()}
+		dockerArgs, security, err := s.dockerArgs(tsk)
+		if err != nil {
+			//a missing secret or preset and a disallowed network do not show up by retrying
+			s.logger.Error("executer", "status", fmt.Sprintf("failed to prepare task %s", tsk.Id), "msg", err)
+			tsk.ErrMessage = err.Error()
+			tsk.ErrCategory = task.ErrorSetup
+			tsk.Status = task.StatusFailed
+			if err := s.publishTask(tsk, queueFailed); err != nil {
+				s.logger.Error("submitTask", "status", fmt.Sprintf("failed to publish task %s to failed queue", tsk.Id), "msg", err)
+			}
+			return
+		}
 
 		s.logger.Info("executer", "status", fmt.Sprintf("executing task with id %s", tsk.Id))
+		s.recordEvent(tsk, task.EventStarted, "")
+
+		//the time spent pulling does not count against the execution deadline
+		pulled, err := s.pullImage(ctx, tsk)
+		execCtx, execCancel := context.WithDeadline(ctx, s.extendDeadline(executerId, deadline, pulled))
+		defer execCancel()
+
+		var output string
+		image := tsk.Image
+		executed := err == nil
+		usageCtx, consumed := runtime.WithUsage(execCtx)
+		tsk.StartedAt = s.clock.Now()
+		if executed {
+			image = s.pinImage(&tsk)
+			output, err = s.execute(usageCtx, &tsk, image, dockerArgs)
+		}
+		tsk.FinishedAt = s.clock.Now()
+		tsk.QueueLatency = max(tsk.StartedAt.Sub(tsk.ScheduledAt), 0)
+		err, category := s.interrupted(execCtx, tsk.StartedAt, err)
+		s.recordRun(tsk, runId, image, security, err)
+		if executed {
+			s.recordUsage(tsk, runId, consumed)
+		}
 
-		output, err := docker.RunCommand(ctx, tsk.Image, tsk.Command, dockerArgs, tsk.Args)
+		//the retry of the task must be able to claim it as soon as its outcome is published
+		s.releaseExecution(tsk, runId)
+
+		infraFailure := errors.Is(err, runtime.ErrInfrastructure)
+		s.recordExecution(infraFailure)
+
+		//the task is not at fault during an outage, send it back without using one of its retries
+		if infraFailure && s.IsPaused() {
+			s.logger.Warn("executer", "status", fmt.Sprintf("requeueing task %s until dispatch resumes", tsk.Id), "msg", err)
+			if err := s.publishTask(tsk, queueTasks); err != nil {
+				s.logger.Error("submitTask", "status", fmt.Sprintf("failed to requeue task %s", tsk.Id), "msg", err)
+			}
+			return
+		}
 
 		if err != nil {
 			//failed
-			tsk.ErrMessage = err.Error()
+			tsk.ErrMessage = task.Truncate(err.Error(), s.maxResultBytes)
+			tsk.ErrCategory = category
 			tsk.Status = task.StatusFailed
-			// publish task for retry queue
-			if err := s.publishTask(tsk, queueRetry); err != nil {
+
+			//an image the registry does not have does not show up by retrying, unlike one behind an outage or a pull that
+			//was interrupted
+			pullFailed := errors.Is(err, errImagePull) && category == task.ErrorExecution
+			if pullFailed {
+				tsk.ErrCategory = task.ErrorImagePull
+			}
+
+			//exit codes the task does not retry on fail it right away
+			if (pullFailed && !infraFailure) || !tsk.Retryable(s.runner.ExitCode(err)) {
+				s.logger.Info("executer", "status", fmt.Sprintf("task %s failed without a retry", tsk.Id), "category", tsk.ErrCategory)
+				if err := s.publishTask(tsk, queueFailed); err != nil {
+					s.logger.Error("submitTask", "status", fmt.Sprintf("failed to publish task %s to failed queue", tsk.Id), "msg", err)
+				}
+				return
+			}
+
+			// publish task for retry queue, the retry prefers this worker
+			headers := broker.Headers{headerPreferredWorker: s.id}
+			if err := s.publishTaskWithHeaders(tsk, queueRetry, headers); err != nil {
 				//logging is our error handler right now
 				s.logger.Error("submitTask", "status", fmt.Sprintf("failed to publish task %s to retry queue", tsk.Id), "msg", err)
 				return
@@ -243,7 +780,7 @@ func (s *Scheduler) submitTask(tsk task.Task) error {
 
 		} else {
 			//success
-			tsk.Result = output
+			tsk.SetResult(output, s.maxResultBytes)
 			tsk.Status = task.StatusCompleted
 			//publish task for success queue
 			if err := s.publishTask(tsk, queueSuccess); err != nil {
@@ -257,17 +794,18 @@ func (s *Scheduler) submitTask(tsk task.Task) error {
 
 // OnTaskSuccess handles the saving task into task service.
 func (s *Scheduler) OnTaskSuccess() error {
-	msgs, err := s.rClient.Consumer(queueSuccess)
+	pool, msgs, err := s.consumeWithHandlers(queueSuccess, s.handleSuccessMessage)
 	if err != nil {
 		return fmt.Errorf("creating consumer: %w", err)
 	}
 
 	//consumer
 	go func() {
+		defer pool.close()
 		for msg := range msgs {
 			//handle message.
 			//we need to ignore shutdown in here in case an executer manages to be in middle of a publish.
-			go s.handleSuccessMessage(msg)
+			pool.submit(msg)
 		}
 	}()
 
@@ -276,17 +814,18 @@ func (s *Scheduler) OnTaskSuccess() error {
 
 // OnTaskFailure handles the failed tasks by updating them into task service.
 func (s *Scheduler) OnTaskFailure() error {
-	msgs, err := s.rClient.Consumer(queueFailed)
+	pool, msgs, err := s.consumeWithHandlers(queueFailed, s.handleFailedMessage)
 	if err != nil {
 		return fmt.Errorf("create on failure consumer: %w", err)
 	}
 
 	//consumer
 	go func() {
+		defer pool.close()
 		for msg := range msgs {
 			//we need this to ignore shutdown in case an executer manages to be in middle of a publish
 			//so we able to update that task.
-			go s.handleFailedMessage(msg)
+			pool.submit(msg)
 		}
 	}()
 
@@ -295,13 +834,14 @@ func (s *Scheduler) OnTaskFailure() error {
 
 // OnTaskRetry handles the retry of failed tasks or sending them for total failure.
 func (s *Scheduler) OnTaskRetry() error {
-	msgs, err := s.rClient.Consumer(queueRetry)
+	pool, msgs, err := s.consumeWithHandlers(queueRetry, s.handleRetryMessage)
 	if err != nil {
 		return fmt.Errorf("creating on retry consumer: %w", err)
 	}
 
 	//consumer
 	go func() {
+		defer pool.close()
 		for msg := range msgs {
 			select {
 			case <-s.shutdown:
@@ -318,7 +858,7 @@ func (s *Scheduler) OnTaskRetry() error {
 
 				return
 			default:
-				go s.handleRetryMessage(msg)
+				pool.submit(msg)
 			}
 		}
 	}()
@@ -326,9 +866,36 @@ func (s *Scheduler) OnTaskRetry() error {
 	return nil
 }
 
+// MaxRetries returns how many times a failed task is retried when the task does not set its own retries.
+func (s *Scheduler) MaxRetries() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.maxRetries
+}
+
+// SetMaxRetries changes how many times a failed task is retried, it applies to the retries handled from now on.
+func (s *Scheduler) SetMaxRetries(retries int) error {
+	if retries < 0 {
+		return fmt.Errorf("max retries must not be negative: %d", retries)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxRetries = retries
+	return nil
+}
+
 // MonitorScheduledTasks fetches all of the tasks that have less than or equal
 // to one minute to their scheduledAt deadline every one minute.
 func (s *Scheduler) MonitorScheduledTasks() error {
+	//nil when the monitor is not started by Activate, receiving from it blocks forever.
+	s.mu.RLock()
+	stop := s.monitorStop
+	s.mu.RUnlock()
+
+	//only the instance that holds the lock monitors, the others take over once it dies
+	go s.keepMonitorLock(stop)
+
 	//monitor
 	go func() {
 		//this is a long-lived ctx used inside of the loop for any db operation, and
@@ -336,28 +903,21 @@ func (s *Scheduler) MonitorScheduledTasks() error {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
-		ticker := time.NewTicker(time.Minute)
+		ticker := s.clock.NewTicker(time.Minute)
 		defer ticker.Stop()
 
-		for range ticker.C {
+		for range ticker.C() {
 			select {
 			case <-s.shutdown:
 				s.logger.Info("monitorScheduledTasks", "status", "received shutdown signal", "msg", "shutting down")
 				return
 
-			default:
-				dueTasks, err := s.taskService.GetAllDueTasks(ctx)
-				if err != nil {
-					s.logger.Error("monitorScheduledTasks", "status", "failed to fetch due tasks", "msg", err)
-					return
-				}
+			case <-stop:
+				s.logger.Info("monitorScheduledTasks", "status", "dispatch disabled", "msg", "stopping monitor")
+				return
 
-				for _, tsk := range dueTasks {
-					if err := s.publishTask(tsk, queueTasks); err != nil {
-						s.logger.Error("monitorScheduledTasks", "status", "failed to publish task into tasks queue", "msg", err)
-						continue
-					}
-				}
+			default:
+				s.monitorTick(ctx)
 			}
 		}
 
@@ -366,115 +926,225 @@ func (s *Scheduler) MonitorScheduledTasks() error {
 	return nil
 }
 
-func (s *Scheduler) handleRetryMessage(msg amqp091.Delivery) {
-	if err := msg.Ack(false); err != nil {
-		//handle error with logging them
-		s.logger.Error("handleRetryMessage", "status", "failed to ack()", "msg", err)
+// monitorTick publishes the tasks that are due when this instance holds the monitor lock.
+func (s *Scheduler) monitorTick(ctx context.Context) {
+	//the monitor keeps running after a panic, the next tick tries again
+	defer s.recovered("monitorScheduledTasks", nil)
+
+	if !s.holdsMonitorLock() {
 		return
 	}
 
+	//tasks whose message got lost are claimed again
+	requeued, err := s.taskService.RequeueStuckTasks(ctx, s.queuedTimeout)
+	if err != nil {
+		s.logger.Error("monitorScheduledTasks", "status", "failed to requeue stuck tasks", "msg", err)
+	}
+	if requeued > 0 {
+		s.logger.Warn("monitorScheduledTasks", "status", fmt.Sprintf("requeued %d tasks stuck in queued state", requeued))
+	}
+
+	//claimed tasks are not published again by the next tick
+	dueTasks, err := s.taskService.ClaimDueTasks(ctx)
+	if err != nil {
+		s.logger.Error("monitorScheduledTasks", "status", "failed to fetch due tasks", "msg", err)
+		return
+	}
+
+	for _, tsk := range dueTasks {
+		if err := s.dispatchTask(tsk, nil); err != nil {
+			s.logger.Error("monitorScheduledTasks", "status", "failed to publish task into tasks queue", "msg", err)
+			continue
+		}
+		s.recordEvent(tsk, task.EventQueued, "")
+	}
+}
+
+func (s *Scheduler) handleRetryMessage(msg broker.Delivery) {
+	defer s.recovered("handleRetryMessage", s.redeliverOnPanic(msg, "handleRetryMessage"))
+
 	tsk, err := s.parseTask(msg.Body)
 	if err != nil {
-		s.logger.Error("handleRetryTask", "status", "failed to parse task from body", "msg", err)
+		s.bury(msg, "handleRetryMessage", err)
 		return
 	}
 
-	//default ctx for redis
+	//default ctx for retry store
 	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
 	defer cancel()
 
-	retries, err := s.redisRepo.Get(ctx, tsk.Id.String())
+	//check and increment happen atomically inside of the store
+	//the task may override the retries of the scheduler
+	maxRetries := s.MaxRetries()
+	if tsk.MaxRetries != nil {
+		maxRetries = *tsk.MaxRetries
+	}
+
+	retries, ok, err := s.retryStore.Increment(ctx, tsk.Id.String(), maxRetries, s.retryWindow(maxRetries))
 	if err != nil {
-		//not found
-		if errors.Is(err, redis.Nil) {
-			retries = 0
-		} else {
-			//something went wrong
-			s.logger.Error("handleRetryMessage", "status", "failed to fetch retries", "msg", err)
-			return
-		}
+		s.redeliver(msg, "handleRetryMessage", msg.Queue, fmt.Errorf("update retries: %w", err))
+		return
 	}
 
-	retries++
-	if retries > s.maxRetries {
+	if !ok {
 		//publish into failed queue
 		if err := s.publishTask(tsk, queueFailed); err != nil {
-			s.logger.Error("handleRetryMessage", "status", "failed to publish into queue_failed", "msg", err)
+			s.redeliver(msg, "handleRetryMessage", msg.Queue, fmt.Errorf("publish into queue_failed: %w", err))
+			return
 		}
+		s.retries.exhausted.Add(1)
+		s.ack(msg, "handleRetryMessage")
 		return
 	}
 
-	//update redis
-	if err := s.redisRepo.Update(ctx, tsk.Id.String(), retries); err != nil {
-		s.logger.Error("handleRetryMessage", "status", "failed to update retries", "msg", err)
-		return
-	}
-
-	s.logger.Info("handleRetryMessage", "status", fmt.Sprintf("%d/%d: retrying to execute task %s", retries, s.maxRetries, tsk.Id))
-	if err := s.publishTask(tsk, queueTasks); err != nil {
-		s.logger.Error("handleRetryMessage", "status", "failed to send task for a retry", "msg", err)
+	s.logger.Info("handleRetryMessage", "status", fmt.Sprintf("%d/%d: retrying to execute task %s", retries, maxRetries, tsk.Id))
+	if err := s.dispatchTask(tsk, s.affinityHeaders(msg)); err != nil {
+		//the redelivery uses up one more retry, which is better than losing the task
+		s.redeliver(msg, "handleRetryMessage", msg.Queue, fmt.Errorf("send task for a retry: %w", err))
 		return
 	}
-
+	s.retries.retried.Add(1)
+	s.ack(msg, "handleRetryMessage")
+	s.recordEvent(tsk, task.EventRetried, fmt.Sprintf("retry %d/%d after: %s", retries, maxRetries, tsk.ErrMessage))
 }
 
-func (s *Scheduler) handleFailedMessage(msg amqp091.Delivery) {
-	if err := msg.Ack(false); err != nil {
-		//handle error with logging them
-		s.logger.Error("handleFailedMessage", "status", "failed to ack()", "msg", err)
-		return
-	}
+func (s *Scheduler) handleFailedMessage(msg broker.Delivery) {
+	defer s.recovered("handleFailedMessage", s.redeliverOnPanic(msg, "handleFailedMessage"))
+
 	// parse the task from body
 	tsk, err := s.parseTask(msg.Body)
 	if err != nil {
-		s.logger.Error("handleFailedMessage", "status", "failed to parse task from body", "msg", err)
+		s.bury(msg, "handleFailedMessage", err)
 		return
 	}
 
-	ut := task.UpdateTask{
-		Status:     &tsk.Status,
-		ErrMessage: &tsk.ErrMessage,
+	if err := s.saveStatus(tsk); err != nil {
+		//the status is kept aside during an outage instead of running out of redeliveries
+		if s.spill("handleFailedMessage", tsk, msg.Body, err) {
+			s.ack(msg, "handleFailedMessage")
+			return
+		}
+		s.redeliver(msg, "handleFailedMessage", msg.Queue, err)
+		return
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
 	defer cancel()
 
-	if _, err := s.taskService.UpdateTask(ctx, tsk, ut); err != nil {
-		s.logger.Error("handleFailedMessage", "status", "failed to update task inside of task service", "msg", err)
-		return
-	}
+	s.notify(ctx, tsk)
+	s.clearRetries(ctx, "handleFailedMessage", tsk)
+	s.ack(msg, "handleFailedMessage")
+	s.recordEvent(tsk, task.EventFailed, tsk.ErrMessage)
 	s.logger.Info("handleFailedMessage", "status", fmt.Sprintf("task with id %s failed", tsk.Id))
 }
 
-func (s *Scheduler) handleSuccessMessage(msg amqp091.Delivery) {
-	if err := msg.Ack(false); err != nil {
-		//handle error with logging them
-		s.logger.Error("handleSuccessMessage", "status", "failed to ack()", "msg", err)
-		return
-	}
+func (s *Scheduler) handleSuccessMessage(msg broker.Delivery) {
+	defer s.recovered("handleSuccessMessage", s.redeliverOnPanic(msg, "handleSuccessMessage"))
 
 	//parse the task from body
 	tsk, err := s.parseTask(msg.Body)
 	if err != nil {
-		s.logger.Error("handleSuccessMessage", "status", "failed to parse task from body", "msg", err)
+		s.bury(msg, "handleSuccessMessage", err)
 		return
 	}
 
-	ut := task.UpdateTask{
-		Status: &tsk.Status,
-		Result: &tsk.Result,
+	if err := s.saveStatus(tsk); err != nil {
+		if s.spill("handleSuccessMessage", tsk, msg.Body, err) {
+			s.ack(msg, "handleSuccessMessage")
+			return
+		}
+		s.redeliver(msg, "handleSuccessMessage", msg.Queue, err)
+		return
 	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
 	defer cancel()
 
-	if _, err := s.taskService.UpdateTask(ctx, tsk, ut); err != nil {
-		s.logger.Error("handleSuccessMessage", "status", "failed to update task inside of task service", "msg", err)
-		return
-	}
+	s.notify(ctx, tsk)
+	s.clearRetries(ctx, "handleSuccessMessage", tsk)
+	s.ack(msg, "handleSuccessMessage")
+	s.recordEvent(tsk, task.EventCompleted, "")
 	//log message
 	s.logger.Info("handleSuccessMessage", "status", fmt.Sprintf("task with id %s completed", tsk.Id))
 }
 
+// executionDeadline returns when the execution of the task times out, it counts from ScheduledAt so a task that waits
+// inside of its executer until it is due still gets all of its execution time. Tasks that are picked up late count
+// from now.
+func (s *Scheduler) executionDeadline(tsk task.Task) time.Time {
+	start := s.clock.Now()
+	if tsk.ScheduledAt.After(start) {
+		start = tsk.ScheduledAt
+	}
+	return start.Add(s.maxTimeForTaskExecution)
+}
+
+// interrupted tells an execution that was stopped by its deadline or by the scheduler apart from a failed command and
+// returns the category of the failure.
+func (s *Scheduler) interrupted(execCtx context.Context, started time.Time, err error) (error, task.ErrorCategory) {
+	if err == nil {
+		return nil, ""
+	}
+
+	switch {
+	case errors.Is(execCtx.Err(), context.DeadlineExceeded):
+		//the deadline counts from when the task was due and is pushed back by the pull, so it is reported as is
+		deadline, _ := execCtx.Deadline()
+		return fmt.Errorf("timed out at its deadline %s, %s after it started: %w", deadline.UTC().Format(time.RFC3339),
+			deadline.Sub(started).Round(time.Millisecond), err), task.ErrorTimeout
+	case errors.Is(execCtx.Err(), context.Canceled):
+		return fmt.Errorf("canceled: %w", err), task.ErrorCanceled
+	}
+	return err, task.ErrorExecution
+}
+
+// execute runs the command of the task, or its steps one after another inside of the same container, and returns the
+// output of the command or of the last step. The input of the task is piped to the command, or to every step. The
+// output of every step is kept on the task.
+func (s *Scheduler) execute(ctx context.Context, tsk *task.Task, image string, dockerArgs []string) (output string, err error) {
+	//a warm container may run an image older than the one that was just pulled
+	var runner commandRunner = s.runner
+	if tsk.PullPolicy != task.PullAlways {
+		if lease, ok := s.leaseWarm(image, dockerArgs); ok {
+			runner = lease
+			defer func() { s.releaseWarm(lease, err) }()
+		}
+	}
+
+	if len(tsk.Steps) == 0 {
+		return runner.RunCommand(ctx, image, tsk.Command, dockerArgs, tsk.Args, tsk.Input)
+	}
+
+	steps := make([]runtime.Step, len(tsk.Steps))
+	for i, step := range tsk.Steps {
+		steps[i] = runtime.Step{Command: step.Command, Args: step.Args, Stdin: tsk.Input}
+		//outputs of a previous attempt do not belong to this one
+		tsk.Steps[i].Output = ""
+	}
+
+	outputs, err := runner.RunSteps(ctx, image, dockerArgs, steps)
+	for i, output := range outputs {
+		tsk.Steps[i].Output = task.Truncate(output, s.maxResultBytes)
+	}
+
+	if err != nil || len(outputs) == 0 {
+		return "", err
+	}
+	return outputs[len(outputs)-1], nil
+}
+
+// timingsOf returns the timings of the last execution carried by the task, nil when it never started.
+func timingsOf(tsk task.Task) *task.Timings {
+	if tsk.StartedAt.IsZero() {
+		return nil
+	}
+	return &task.Timings{
+		StartedAt:    tsk.StartedAt,
+		FinishedAt:   tsk.FinishedAt,
+		QueueLatency: tsk.QueueLatency,
+	}
+}
+
 func (s *Scheduler) removeExecuter(exId string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -482,22 +1152,38 @@ func (s *Scheduler) removeExecuter(exId string) {
 }
 
 func (s *Scheduler) publishTask(tsk task.Task, queue string) error {
-	bs, err := json.Marshal(tsk)
+	return s.publishTaskWithHeaders(tsk, queue, nil)
+}
+
+func (s *Scheduler) marshalTask(tsk task.Task) ([]byte, error) {
+	bs, err := messages.Marshal(messages.TypeTask, tsk)
 	if err != nil {
-		return fmt.Errorf("marshal: %w", err)
-	}
-	if err := s.rClient.Publish(queue, bs); err != nil {
-		return fmt.Errorf("publish task to queue %s: %w", queue, err)
+		return nil, fmt.Errorf("marshal: %w", err)
 	}
+	return bs, nil
+}
 
-	return nil
+func (s *Scheduler) consumerTag(queue string) string {
+	return queue + "-" + s.id
 }
 
+// errInvalidTask is returned for a task message that decodes but can not be handled.
+var errInvalidTask = errors.New("invalid task message")
+
+// parseTask reads the task out of a message of any schema version this build knows, including the bare tasks of
+// builds without the envelope.
 func (s *Scheduler) parseTask(bs []byte) (task.Task, error) {
 	var tsk task.Task
-	if err := json.Unmarshal(bs, &tsk); err != nil {
-		//handle error
-		return task.Task{}, fmt.Errorf("unmarshal task: %s", err)
+	if err := messages.Unmarshal(bs, messages.TypeTask, &tsk); err != nil {
+		return task.Task{}, fmt.Errorf("unmarshal task: %w", err)
+	}
+
+	if tsk.Id == uuid.Nil {
+		return task.Task{}, fmt.Errorf("%w: missing id", errInvalidTask)
+	}
+
+	if tsk.Image == "" {
+		return task.Task{}, fmt.Errorf("%w: task %s has no image", errInvalidTask, tsk.Id)
 	}
 	return tsk, nil
 }
@@ -5,21 +5,17 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"math/rand/v2"
-	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/hamidoujand/task-scheduler/business/broker/rabbitmq"
-	"github.com/hamidoujand/task-scheduler/business/brokertest"
-	"github.com/hamidoujand/task-scheduler/business/dbtest"
 	"github.com/hamidoujand/task-scheduler/business/domain/scheduler"
 	redisRepo "github.com/hamidoujand/task-scheduler/business/domain/scheduler/store/redis"
 	"github.com/hamidoujand/task-scheduler/business/domain/task"
 	taskRepo "github.com/hamidoujand/task-scheduler/business/domain/task/store/postgres"
-	"github.com/hamidoujand/task-scheduler/business/redistest"
+	"github.com/hamidoujand/task-scheduler/business/testinfra"
 	"github.com/hamidoujand/task-scheduler/foundation/logger"
 	"github.com/redis/go-redis/v9"
 )
@@ -32,14 +28,14 @@ func TestOnSuccess(t *testing.T) {
 	}
 
 	t.Parallel()
-	setups := setupTest(t, "test_on_success_tasks")
+	setups := setupTest(t)
 
 	scheduler, err := scheduler.New(scheduler.Config{
 		MaxRunningTask:          4,
-		RabbitClient:            setups.rabbitC,
+		Broker:                  setups.rabbitC,
 		Logger:                  setups.logger,
 		TaskService:             setups.taskService,
-		RedisRepo:               setups.redisR,
+		RetryStore:              setups.redisR,
 		MaxRetries:              maxRetries,
 		MaxTimeForUpdateOps:     time.Minute,
 		MaxTimeForTaskExecution: time.Minute,
@@ -110,7 +106,7 @@ func TestOnSuccess(t *testing.T) {
 				errChan <- fmt.Errorf("get task by id %q: %w", ids[1], err)
 				return
 			}
-			if (fetched1.Status != task.StatusPending) && (fetched2.Status != task.StatusPending) {
+			if finished(fetched1) && finished(fetched2) {
 
 				t.Logf("\ncommand: %s\nResult: %s\n", fetched1.Command, fetched1.Result)
 				t.Logf("\ncommand: %s\nResult: %s\n", fetched2.Command, fetched2.Result)
@@ -147,14 +143,14 @@ func TestOnFailure(t *testing.T) {
 	}
 
 	t.Parallel()
-	setups := setupTest(t, "test_on_failure_tasks")
+	setups := setupTest(t)
 
 	scheduler, err := scheduler.New(scheduler.Config{
 		MaxRunningTask:          4,
-		RabbitClient:            setups.rabbitC,
+		Broker:                  setups.rabbitC,
 		Logger:                  setups.logger,
 		TaskService:             setups.taskService,
-		RedisRepo:               setups.redisR,
+		RetryStore:              setups.redisR,
 		MaxRetries:              1,
 		MaxTimeForUpdateOps:     time.Minute,
 		MaxTimeForTaskExecution: time.Minute,
@@ -279,7 +275,7 @@ func TestOnFailure(t *testing.T) {
 				return
 			}
 
-			if (fetched1.Status != task.StatusPending) && (fetched2.Status != task.StatusPending) {
+			if finished(fetched1) && finished(fetched2) {
 				if fetched1.Status != task.StatusFailed {
 					taskErrs <- fmt.Errorf("status1=%s, got %s", task.StatusFailed, fetched1.Status)
 					return
@@ -325,14 +321,14 @@ func TestMonitorScheduledTasks(t *testing.T) {
 		t.Skip("skipping long running test due to its needs to poll db for scheduled tasks each minute.")
 	}
 	t.Parallel()
-	setups := setupTest(t, "test_monitor_scheduled_tasks")
+	setups := setupTest(t)
 
 	scheduler, err := scheduler.New(scheduler.Config{
 		MaxRunningTask:          4,
-		RabbitClient:            setups.rabbitC,
+		Broker:                  setups.rabbitC,
 		Logger:                  setups.logger,
 		TaskService:             setups.taskService,
-		RedisRepo:               setups.redisR,
+		RetryStore:              setups.redisR,
 		MaxRetries:              maxRetries,
 		MaxTimeForUpdateOps:     time.Minute,
 		MaxTimeForTaskExecution: time.Minute,
@@ -408,7 +404,7 @@ func TestMonitorScheduledTasks(t *testing.T) {
 				errChan <- fmt.Errorf("get task by id %q: %w", ids[1], err)
 				return
 			}
-			if (fetched1.Status != task.StatusPending) && (fetched2.Status != task.StatusPending) {
+			if finished(fetched1) && finished(fetched2) {
 
 				t.Logf("\ncommand: %s\nResult: %s\n", fetched1.Command, fetched1.Result)
 				t.Logf("\ncommand: %s\nResult: %s\n", fetched2.Command, fetched2.Result)
@@ -447,19 +443,10 @@ type setup struct {
 	redisR      *redisRepo.Repository
 }
 
-func setupTest(t *testing.T, container string) setup {
-	alpha := "abcdefghijklmnopqrstuvwxyz"
-	var builder strings.Builder
-	for range 8 {
-		idx := rand.IntN(len(alpha))
-		builder.WriteByte(alpha[idx])
-	}
-
-	salt := builder.String()
-
-	rabbitC := brokertest.NewTestClient(t, context.Background(), container+"_rebbitmq_"+salt)
-	redisC := redistest.NewRedisClient(t, context.Background(), container+"_redis_"+salt)
-	postgresC := dbtest.NewDatabaseClient(t, container+"_postgres_"+salt)
+func setupTest(t *testing.T) setup {
+	rabbitC := testinfra.NewRabbitMQ(t)
+	redisC := testinfra.NewRedis(t)
+	postgresC := testinfra.NewDatabase(t)
 	logger := logger.NewCustomLogger(slog.LevelInfo, false, slog.String("Env", "Test"))
 	store := taskRepo.NewRepository(postgresC)
 	redisRepo := redisRepo.NewRepository(redisC)
@@ -475,3 +462,54 @@ func setupTest(t *testing.T, container string) setup {
 		redisR:      redisRepo,
 	}
 }
+
+// finished reports whether the task reached a final status.
+func finished(tsk task.Task) bool {
+	return tsk.Status == task.StatusCompleted || tsk.Status == task.StatusFailed
+}
+
+func TestParseWarmPool(t *testing.T) {
+	tests := map[string]struct {
+		entries  []string
+		expected map[string]int
+		valid    bool
+	}{
+		"tagged images": {
+			entries:  []string{"alpine:3.20=2", "python:3.12-slim=1"},
+			expected: map[string]int{"alpine:3.20": 2, "python:3.12-slim": 1},
+			valid:    true,
+		},
+		"no entries": {
+			expected: map[string]int{},
+			valid:    true,
+		},
+		"missing count": {
+			entries: []string{"alpine:3.20"},
+		},
+		"zero count": {
+			entries: []string{"alpine=0"},
+		},
+		"missing image": {
+			entries: []string{"=2"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			sizes, err := scheduler.ParseWarmPool(test.entries)
+			if test.valid != (err == nil) {
+				t.Fatalf("valid= %t, got %s", test.valid, err)
+			}
+
+			if len(sizes) != len(test.expected) {
+				t.Fatalf("sizes= %v, got %v", test.expected, sizes)
+			}
+
+			for image, size := range test.expected {
+				if sizes[image] != size {
+					t.Errorf("size of %s= %d, got %d", image, size, sizes[image])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+// secretResolver represents the secrets of users that are referenced inside of the environment of tasks.
+type secretResolver interface {
+	ResolveEnv(ctx context.Context, userId uuid.UUID, env string) ([]string, error)
+}
+
+// envArgs returns the docker arguments that set the environment of the task, the referenced secrets are resolved
+// right before execution so their values never end up on the task or inside of the queues.
+func (s *Scheduler) envArgs(tsk task.Task) ([]string, error) {
+	vars := strings.Fields(tsk.Environment)
+
+	if s.secrets != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+		defer cancel()
+
+		resolved, err := s.secrets.ResolveEnv(ctx, tsk.UserId, tsk.Environment)
+		if err != nil {
+			return nil, fmt.Errorf("resolve secrets: %w", err)
+		}
+		vars = resolved
+	}
+
+	args := make([]string, 0, len(vars)*2)
+	for _, v := range vars {
+		args = append(args, "-e", v)
+	}
+	return args, nil
+}
@@ -0,0 +1,46 @@
+package scheduler
+
+import (
+	"strconv"
+
+	"github.com/hamidoujand/task-scheduler/business/domain/preset"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+// securityProfile returns the profile the container of a task with the preset runs with, the one of the preset
+// replaces the one of the scheduler.
+func (s *Scheduler) securityProfile(p preset.Preset) task.SecurityProfile {
+	if p.Security != nil {
+		return *p.Security
+	}
+	return s.security
+}
+
+// securityArgs returns the docker arguments that apply the profile.
+func securityArgs(profile task.SecurityProfile) []string {
+	var args []string
+	if profile.ReadOnly {
+		args = append(args, "--read-only")
+	}
+
+	if profile.CapDropAll {
+		args = append(args, "--cap-drop", "ALL")
+	}
+
+	if profile.NoNewPrivileges {
+		args = append(args, "--security-opt", "no-new-privileges")
+	}
+
+	if profile.User != "" {
+		args = append(args, "--user", profile.User)
+	}
+
+	if profile.Tmpfs != "" {
+		opts := "rw,nosuid,nodev"
+		if profile.TmpfsSizeMB > 0 {
+			opts += ",size=" + strconv.Itoa(profile.TmpfsSizeMB) + "m"
+		}
+		args = append(args, "--tmpfs", profile.Tmpfs+":"+opts)
+	}
+	return args
+}
@@ -0,0 +1,173 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+// spillInterval is how often the spilled statuses are written again.
+const spillInterval = time.Second * 10
+
+// spillBatch is the max number of spilled statuses replayed in one go.
+const spillBatch = 100
+
+// spillStore represents the storage that keeps the statuses of finished tasks while the database is down.
+type spillStore interface {
+	Spill(ctx context.Context, body []byte) error
+	//PopSpilled removes and returns up to limit bodies, the oldest first.
+	PopSpilled(ctx context.Context, limit int) ([][]byte, error)
+}
+
+// saveStatus writes the status of the finished task, a failed write is attempted again after a backoff that doubles
+// every time so a short outage of the database does not fail the update.
+func (s *Scheduler) saveStatus(tsk task.Task) error {
+	backoff := s.updateBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := s.updateStatus(tsk)
+		if err == nil || errors.Is(err, task.ErrTaskNotFound) || attempt >= s.updateAttempts {
+			return err
+		}
+
+		select {
+		case <-s.shutdown:
+			return err
+		case <-s.clock.After(backoff):
+			backoff *= 2
+		}
+	}
+}
+
+// updateStatus writes the status of the finished task along with what belongs to it.
+func (s *Scheduler) updateStatus(tsk task.Task) error {
+	ut := task.UpdateTask{
+		Status:  &tsk.Status,
+		Timings: timingsOf(tsk),
+		Steps:   tsk.Steps,
+	}
+
+	if tsk.Status == task.StatusCompleted {
+		ut.Result = &tsk.Result
+		ut.ResultSize = &tsk.ResultSize
+	} else {
+		ut.ErrMessage = &tsk.ErrMessage
+		ut.ErrCategory = &tsk.ErrCategory
+	}
+
+	//coalesced with the statuses of other tasks into a single update
+	if s.statuses != nil {
+		if err := s.statuses.add(task.BatchUpdate{Task: tsk, Update: ut}); err != nil {
+			return fmt.Errorf("update task inside of task service: %w", err)
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+	defer cancel()
+
+	if _, err := s.taskService.UpdateTask(ctx, tsk, ut); err != nil {
+		return fmt.Errorf("update task inside of task service: %w", err)
+	}
+	return nil
+}
+
+// spill keeps the status of the task that could not be saved for a replay, it reports false when there is no spill
+// store, the task is gone or spilling failed as well.
+func (s *Scheduler) spill(consumer string, tsk task.Task, body []byte, cause error) bool {
+	if s.spills == nil || errors.Is(cause, task.ErrTaskNotFound) {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+	defer cancel()
+
+	if err := s.spills.Spill(ctx, body); err != nil {
+		s.logger.Error(consumer, "status", fmt.Sprintf("failed to spill status of task %s", tsk.Id), "msg", err)
+		return false
+	}
+
+	s.logger.Warn(consumer, "status", fmt.Sprintf("spilled status of task %s until the database is back", tsk.Id), "msg", cause)
+	return true
+}
+
+// ReplaySpilledStatuses writes the spilled statuses again until dispatch is disabled, the ones that still can not be
+// written are spilled again for the next round.
+func (s *Scheduler) ReplaySpilledStatuses() error {
+	if s.spills == nil {
+		return nil
+	}
+
+	//nil when the replay is not started by Activate, receiving from it blocks forever.
+	s.mu.RLock()
+	stop := s.monitorStop
+	s.mu.RUnlock()
+
+	go func() {
+		ticker := s.clock.NewTicker(spillInterval)
+		defer ticker.Stop()
+
+		for range ticker.C() {
+			select {
+			case <-s.shutdown:
+				s.logger.Info("replaySpilledStatuses", "status", "received shutdown signal", "msg", "shutting down")
+				return
+
+			case <-stop:
+				s.logger.Info("replaySpilledStatuses", "status", "dispatch disabled", "msg", "stopping replay of spilled statuses")
+				return
+
+			default:
+				s.replaySpilled()
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (s *Scheduler) replaySpilled() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+	defer cancel()
+
+	bodies, err := s.spills.PopSpilled(ctx, spillBatch)
+	if err != nil {
+		s.logger.Error("replaySpilledStatuses", "status", "failed to pop spilled statuses", "msg", err)
+		return
+	}
+
+	for i, bs := range bodies {
+		tsk, err := s.parseTask(bs)
+		if err != nil {
+			s.logger.Error("replaySpilledStatuses", "status", "dropping malformed spilled status", "msg", err)
+			continue
+		}
+
+		if err := s.updateStatus(tsk); err != nil {
+			if errors.Is(err, task.ErrTaskNotFound) {
+				s.logger.Warn("replaySpilledStatuses", "status", fmt.Sprintf("dropping status of deleted task %s", tsk.Id))
+				continue
+			}
+
+			//the database is still down, the rest waits for the next round
+			s.logger.Warn("replaySpilledStatuses", "status", fmt.Sprintf("%d statuses are still spilled", len(bodies)-i), "msg", err)
+			for _, rest := range bodies[i:] {
+				if err := s.spills.Spill(ctx, rest); err != nil {
+					s.logger.Error("replaySpilledStatuses", "status", "lost spilled status", "msg", err)
+				}
+			}
+			return
+		}
+
+		s.notify(ctx, tsk)
+		kind := task.EventCompleted
+		if tsk.Status == task.StatusFailed {
+			kind = task.EventFailed
+		}
+		s.recordEvent(tsk, kind, tsk.ErrMessage)
+		s.logger.Info("replaySpilledStatuses", "status", fmt.Sprintf("saved spilled status of task %s", tsk.Id))
+	}
+}
@@ -0,0 +1,201 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/metrics"
+)
+
+// leaderLease is the name of the lease that only the dispatching scheduler holds.
+const leaderLease = "scheduler-leader"
+
+// leaseStore represents the storage that keeps the leader lease between scheduler instances.
+type leaseStore interface {
+	AcquireLease(ctx context.Context, name string, owner string, ttl time.Duration) (bool, error)
+	ReleaseLease(ctx context.Context, name string, owner string) error
+}
+
+// IsActive reports whether this scheduler is currently dispatching tasks.
+func (s *Scheduler) IsActive() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.active
+}
+
+// Activate enables dispatching by starting all of the consumers and the scheduled tasks monitor.
+func (s *Scheduler) Activate() error {
+	s.mu.Lock()
+	if s.active {
+		s.mu.Unlock()
+		return nil
+	}
+	s.active = true
+	s.monitorStop = make(chan struct{})
+	s.mu.Unlock()
+
+	type starter struct {
+		name  string
+		start func() error
+	}
+
+	var starters []starter
+	if s.mode.executes() {
+		starters = append(starters,
+			starter{name: "task consumer", start: s.ConsumeTasks},
+			starter{name: "delayed tasks dispatcher", start: s.DispatchDelayedTasks},
+			starter{name: "warm pool", start: s.WarmContainers},
+		)
+	}
+
+	if s.mode.dispatches() {
+		starters = append(starters,
+			starter{name: "on task success consumer", start: s.OnTaskSuccess},
+			starter{name: "on task retry consumer", start: s.OnTaskRetry},
+			starter{name: "on task failure consumer", start: s.OnTaskFailure},
+			starter{name: "monitor scheduled tasks", start: s.MonitorScheduledTasks},
+			starter{name: "outbox relay", start: s.RelayOutbox},
+			starter{name: "backlog monitor", start: s.MonitorBacklog},
+			starter{name: "spilled statuses replay", start: s.ReplaySpilledStatuses},
+			starter{name: "retention janitor", start: s.MaintainPartitions},
+			starter{name: "retry counters sweeper", start: s.SweepRetries},
+			starter{name: "late tasks monitor", start: s.MonitorLateTasks},
+		)
+	}
+
+	for _, starter := range starters {
+		if err := starter.start(); err != nil {
+			//do not leave half of the consumers running
+			if dErr := s.Deactivate(); dErr != nil {
+				s.logger.Error("activate", "status", "failed to deactivate", "msg", dErr)
+			}
+			return fmt.Errorf("%s: %w", starter.name, err)
+		}
+	}
+
+	if s.mode.dispatches() {
+		metrics.SetLeader(true)
+	}
+	return nil
+}
+
+// Deactivate disables dispatching by canceling all of the consumers and stopping the scheduled tasks monitor,
+// tasks that are already executing are left to finish.
+func (s *Scheduler) Deactivate() error {
+	s.mu.Lock()
+	if !s.active {
+		s.mu.Unlock()
+		return nil
+	}
+	s.active = false
+	close(s.monitorStop)
+	s.monitorStop = nil
+	draining := s.draining
+	s.mu.Unlock()
+
+	//a drained scheduler already canceled the consumers of the task queues
+	var queues []string
+	if s.mode.executes() && !draining {
+		queues = append(queues, s.taskQueues()...)
+	}
+
+	if s.mode.dispatches() {
+		metrics.SetLeader(false)
+		queues = append(queues, queueSuccess, queueFailed, queueRetry)
+	}
+
+	var errs []error
+	for _, queue := range queues {
+		if err := s.broker.Cancel(s.consumerTag(queue)); err != nil {
+			errs = append(errs, fmt.Errorf("cancel consumer of %s: %w", queue, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Standby starts this scheduler as a warm standby, dispatching stays disabled until this instance
+// acquires the leader lease. The lease is renewed every third of its ttl and dispatching is disabled
+// again as soon as the lease is lost, so at most one instance dispatches at a time. The lease is
+// released on shutdown so a standby can take over without waiting for it to expire.
+func (s *Scheduler) Standby() error {
+	if s.leaseStore == nil {
+		return errors.New("lease store is required for standby mode")
+	}
+
+	//workers do not dispatch, any number of them execute at the same time
+	if !s.mode.dispatches() {
+		return fmt.Errorf("standby mode is not supported in %s mode", s.mode)
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := s.clock.NewTicker(s.leaseTTL / 3)
+		defer ticker.Stop()
+
+		//last time this instance confirmed it holds the lease
+		var renewedAt time.Time
+
+		for {
+			renewedAt = s.checkLease(renewedAt)
+
+			select {
+			case <-s.shutdown:
+				s.logger.Info("standby", "status", "received shutdown signal", "msg", "releasing leader lease")
+				s.releaseLease()
+				return
+			case <-ticker.C():
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (s *Scheduler) checkLease(renewedAt time.Time) time.Time {
+	ctx, cancel := context.WithTimeout(context.Background(), s.leaseTTL/3)
+	defer cancel()
+
+	held, err := s.leaseStore.AcquireLease(ctx, leaderLease, s.id, s.leaseTTL)
+	if err != nil {
+		s.logger.Error("standby", "status", "failed to acquire leader lease", "msg", err)
+		//keep dispatching while the lease we already hold is not expired yet
+		held = s.IsActive() && s.clock.Now().Sub(renewedAt) < s.leaseTTL
+	} else if held {
+		renewedAt = s.clock.Now()
+	}
+
+	active := s.IsActive()
+	switch {
+	case held && !active:
+		s.logger.Info("standby", "status", "failover", "msg", "leader lease acquired, enabling dispatch", "instance", s.id)
+		if err := s.Activate(); err != nil {
+			s.logger.Error("standby", "status", "failed to enable dispatch", "msg", err)
+			s.releaseLease()
+			return time.Time{}
+		}
+		metrics.AddPromotion()
+
+	case !held && active:
+		s.logger.Warn("standby", "status", "demoted", "msg", "leader lease lost, disabling dispatch", "instance", s.id)
+		if err := s.Deactivate(); err != nil {
+			s.logger.Error("standby", "status", "failed to disable dispatch", "msg", err)
+		}
+		metrics.AddDemotion()
+	}
+
+	return renewedAt
+}
+
+func (s *Scheduler) releaseLease() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+	defer cancel()
+
+	if err := s.leaseStore.ReleaseLease(ctx, leaderLease, s.id); err != nil {
+		s.logger.Error("standby", "status", "failed to release leader lease", "msg", err)
+	}
+}
@@ -0,0 +1,114 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// executer represents a task this scheduler is executing or waiting to execute.
+type executer struct {
+	cancel    context.CancelFunc
+	taskId    uuid.UUID
+	startedAt time.Time
+	deadline  time.Time
+}
+
+// retryCounts counts the retries handled by this scheduler since it started.
+type retryCounts struct {
+	retried   atomic.Int64
+	exhausted atomic.Int64
+}
+
+// Executer represents a task this scheduler is executing or waiting to execute, Remaining is how long it has left
+// until its execution deadline.
+type Executer struct {
+	TaskId    uuid.UUID
+	StartedAt time.Time
+	Deadline  time.Time
+	Remaining time.Duration
+}
+
+// Queue represents a queue of the broker, Err is set when its stats could not be read.
+type Queue struct {
+	Name      string
+	Messages  int
+	Consumers int
+	Err       error
+}
+
+// Status represents the runtime state of this scheduler.
+type Status struct {
+	Instance  string
+	Mode      Mode
+	Active    bool
+	Intake    string
+	Executers []Executer
+	//SlotsUsed is how many of the MaxRunningTask slots are taken.
+	SlotsUsed     int
+	SlotsCapacity int
+	Queues        []Queue
+	//Retried is how many tasks were sent for a retry and RetriesExhausted how many failed after their last retry,
+	//both since this scheduler started.
+	Retried          int64
+	RetriesExhausted int64
+}
+
+// Status returns the runtime state of this scheduler, queue depths are read from the broker with a passive declare.
+func (s *Scheduler) Status() Status {
+	now := s.clock.Now()
+
+	st := Status{
+		Instance:         s.id,
+		Mode:             s.mode,
+		Active:           s.IsActive(),
+		Intake:           s.DrainState(),
+		SlotsUsed:        cap(s.sem) - len(s.sem),
+		SlotsCapacity:    cap(s.sem),
+		Retried:          s.retries.retried.Load(),
+		RetriesExhausted: s.retries.exhausted.Load(),
+	}
+
+	func() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+
+		st.Executers = make([]Executer, 0, len(s.executers))
+		for _, ex := range s.executers {
+			st.Executers = append(st.Executers, Executer{
+				TaskId:    ex.taskId,
+				StartedAt: ex.startedAt,
+				Deadline:  ex.deadline,
+				Remaining: max(ex.deadline.Sub(now), 0),
+			})
+		}
+	}()
+
+	//oldest first
+	slices.SortFunc(st.Executers, func(a, b Executer) int {
+		return a.StartedAt.Compare(b.StartedAt)
+	})
+
+	queues := []string{queueTasks, queueRetry, queueSuccess, queueFailed, queueDead}
+	if s.mode == ModeExecute {
+		queues = append(queues, workerQueue(s.id))
+	}
+
+	for _, name := range queues {
+		q := Queue{Name: name}
+		stats, err := s.broker.QueueStats(name)
+		if err != nil {
+			q.Err = fmt.Errorf("queue stats: %w", err)
+		} else {
+			q.Messages = stats.Messages
+			q.Consumers = stats.Consumers
+		}
+		st.Queues = append(st.Queues, q)
+	}
+
+	return st
+}
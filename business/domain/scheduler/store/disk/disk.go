@@ -0,0 +1,107 @@
+// Package disk provides a spill buffer for scheduler on the local disk.
+package disk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Repository represents a spill buffer that keeps every body as a file inside of a directory, so the bodies survive
+// a restart of the scheduler.
+type Repository struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewRepository creates the directory when it is missing and returns a repository on top of it.
+func NewRepository(dir string) (*Repository, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("mkdir: %w", err)
+	}
+	return &Repository{dir: dir}, nil
+}
+
+// Spill writes the body into a file of its own, file names sort by when they were spilled.
+func (r *Repository) Spill(ctx context.Context, body []byte) error {
+	name := fmt.Sprintf("%020d-%s.json", time.Now().UnixNano(), uuid.NewString())
+
+	//a body is only visible once it is fully written
+	tmp, err := os.CreateTemp(r.dir, ".spill-*")
+	if err != nil {
+		return fmt.Errorf("create temp: %w", err)
+	}
+
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("write: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("close: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(r.dir, name)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("rename: %w", err)
+	}
+	return nil
+}
+
+// PopSpilled removes and returns up to limit bodies, the oldest first.
+func (r *Repository) PopSpilled(ctx context.Context, limit int) ([][]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return nil, fmt.Errorf("read dir: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, entry.Name())
+		}
+	}
+	slices.Sort(names)
+
+	bodies := make([][]byte, 0, min(limit, len(names)))
+	for _, name := range names[:min(limit, len(names))] {
+		path := filepath.Join(r.dir, name)
+
+		body, err := os.ReadFile(path)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return r.popped(bodies, fmt.Errorf("read %s: %w", name, err))
+		}
+
+		if err := os.Remove(path); err != nil {
+			return r.popped(bodies, fmt.Errorf("remove %s: %w", name, err))
+		}
+		bodies = append(bodies, body)
+	}
+	return bodies, nil
+}
+
+// popped returns the bodies that were already removed, they would be lost otherwise. The file that failed stays for
+// the next pop.
+func (r *Repository) popped(bodies [][]byte, err error) ([][]byte, error) {
+	if len(bodies) > 0 {
+		return bodies, nil
+	}
+	return nil, err
+}
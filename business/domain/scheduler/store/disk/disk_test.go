@@ -0,0 +1,55 @@
+package disk_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/hamidoujand/task-scheduler/business/domain/scheduler/store/disk"
+)
+
+func TestSpilled(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := disk.NewRepository(dir)
+	if err != nil {
+		t.Fatalf("expected to create the repository: %s", err)
+	}
+
+	ctx := context.Background()
+
+	for _, body := range []string{"first", "second", "third"} {
+		if err := repo.Spill(ctx, []byte(body)); err != nil {
+			t.Fatalf("expected to spill body: %s", err)
+		}
+	}
+
+	bodies, err := repo.PopSpilled(ctx, 2)
+	if err != nil {
+		t.Fatalf("expected to pop spilled bodies: %s", err)
+	}
+	if len(bodies) != 2 || string(bodies[0]) != "first" || string(bodies[1]) != "second" {
+		t.Fatalf("bodies= [first second], got %q", bodies)
+	}
+
+	//the bodies survive a restart
+	repo, err = disk.NewRepository(dir)
+	if err != nil {
+		t.Fatalf("expected to create the repository: %s", err)
+	}
+
+	bodies, err = repo.PopSpilled(ctx, 2)
+	if err != nil {
+		t.Fatalf("expected to pop spilled bodies: %s", err)
+	}
+	if len(bodies) != 1 || string(bodies[0]) != "third" {
+		t.Fatalf("bodies= [third], got %q", bodies)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("expected to read the dir: %s", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected popped bodies to be removed, got %d files", len(entries))
+	}
+}
@@ -0,0 +1,91 @@
+// Package memory provides an in memory repository of the retry bookkeeping of scheduler used for testing.
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// counter represents the retries of a task and when they expire.
+type counter struct {
+	retries int
+	expires time.Time
+}
+
+// Repository represents an in-memory storage for testing.
+type Repository struct {
+	counters map[string]counter
+	mu       sync.Mutex
+}
+
+// Get returns the number of retries for this given task id, returns sql.ErrNoRows when there is no counter.
+func (r *Repository) Get(ctx context.Context, taskId string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.lookup(taskId)
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	return c.retries, nil
+}
+
+// Increment increments the retries of the given task id as long as they are below max and returns the new value,
+// false means retries are exhausted. The counter expires ttl after its last increment.
+func (r *Repository) Increment(ctx context.Context, taskId string, max int, ttl time.Duration) (int, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, _ := r.lookup(taskId)
+	if c.retries >= max {
+		return max, false, nil
+	}
+
+	if r.counters == nil {
+		r.counters = make(map[string]counter)
+	}
+
+	c.retries++
+	c.expires = time.Now().Add(ttl)
+	r.counters[taskId] = c
+	return c.retries, true, nil
+}
+
+// ClearRetries deletes the retry counter of the given task id, a missing counter is not an error.
+func (r *Repository) ClearRetries(ctx context.Context, taskId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.counters, taskId)
+	return nil
+}
+
+// SweepRetries deletes the expired counters, counters always expire so ttl is not used.
+func (r *Repository) SweepRetries(ctx context.Context, ttl time.Duration) (int, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var swept int
+	for id := range r.counters {
+		if _, ok := r.lookup(id); !ok {
+			swept++
+		}
+	}
+	return len(r.counters), swept, nil
+}
+
+// lookup returns the counter of the task id, expired counters are deleted.
+func (r *Repository) lookup(taskId string) (counter, bool) {
+	c, ok := r.counters[taskId]
+	if !ok {
+		return counter{}, false
+	}
+
+	if time.Now().After(c.expires) {
+		delete(r.counters, taskId)
+		return counter{}, false
+	}
+	return c, true
+}
@@ -0,0 +1,11 @@
+package postgres_test
+
+import (
+	"testing"
+
+	"github.com/hamidoujand/task-scheduler/business/testinfra"
+)
+
+func TestMain(m *testing.M) {
+	testinfra.Main(m)
+}
@@ -0,0 +1,212 @@
+// Package postgres provides the retry bookkeeping of scheduler on top of postgres for deployments without redis.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/database/postgres"
+	"github.com/jackc/pgx/v5"
+)
+
+// Repository represents all of the APIs used for CRUD against postgres.
+type Repository struct {
+	client *postgres.Client
+}
+
+// NewRepository creates a new postgres repository.
+func NewRepository(client *postgres.Client) *Repository {
+	return &Repository{
+		client: client,
+	}
+}
+
+// Create inserts a new record with zero retries.
+func (r *Repository) Create(ctx context.Context, taskId string) error {
+	id, err := uuid.Parse(taskId)
+	if err != nil {
+		return fmt.Errorf("parse task id: %w", err)
+	}
+
+	const q = `
+	INSERT INTO task_retries
+		(task_id,retries,updated_at)
+	VALUES
+		($1,0,$2)
+	ON CONFLICT (task_id) DO NOTHING	
+	`
+	if _, err := r.client.Pool.Exec(ctx, q, id, time.Now().UTC()); err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+	return nil
+}
+
+// Get returns the number of retries for this given task id, returns sql.ErrNoRows when there is no record.
+func (r *Repository) Get(ctx context.Context, taskId string) (int, error) {
+	id, err := uuid.Parse(taskId)
+	if err != nil {
+		return 0, fmt.Errorf("parse task id: %w", err)
+	}
+
+	const q = `
+	SELECT 
+		retries
+	FROM task_retries
+	WHERE task_id = $1	
+	`
+	var retries int
+	if err := r.client.Pool.QueryRow(ctx, q, id).Scan(&retries); err != nil {
+		return 0, fmt.Errorf("scan: %w", postgres.NoRows(err))
+	}
+	return retries, nil
+}
+
+// Update updates the retries of the given task id.
+func (r *Repository) Update(ctx context.Context, taskId string, retries int) error {
+	id, err := uuid.Parse(taskId)
+	if err != nil {
+		return fmt.Errorf("parse task id: %w", err)
+	}
+
+	const q = `
+	INSERT INTO task_retries
+		(task_id,retries,updated_at)
+	VALUES
+		($1,$2,$3)
+	ON CONFLICT (task_id) DO UPDATE SET
+		retries = EXCLUDED.retries,
+		updated_at = EXCLUDED.updated_at	
+	`
+	if _, err := r.client.Pool.Exec(ctx, q, id, retries, time.Now().UTC()); err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+	return nil
+}
+
+// Increment atomically increments the retries of the given task id as long as they are below max and returns the
+// new value, false means retries are exhausted. Rows do not expire by themselves, SweepRetries deletes the ones that
+// were not incremented for ttl.
+func (r *Repository) Increment(ctx context.Context, taskId string, max int, ttl time.Duration) (int, bool, error) {
+	if max <= 0 {
+		return 0, false, nil
+	}
+
+	id, err := uuid.Parse(taskId)
+	if err != nil {
+		return 0, false, fmt.Errorf("parse task id: %w", err)
+	}
+
+	//a single statement, so concurrent consumers can not both pass the check.
+	const q = `
+	INSERT INTO task_retries
+		(task_id,retries,updated_at)
+	VALUES
+		($1,1,$3)
+	ON CONFLICT (task_id) DO UPDATE SET
+		retries = task_retries.retries + 1,
+		updated_at = EXCLUDED.updated_at
+	WHERE task_retries.retries < $2
+	RETURNING retries	
+	`
+	var retries int
+	err = r.client.Pool.QueryRow(ctx, q, id, max, time.Now().UTC()).Scan(&retries)
+	if err != nil {
+		//the where clause filtered the update out
+		if errors.Is(err, pgx.ErrNoRows) {
+			return max, false, nil
+		}
+		return 0, false, fmt.Errorf("scan: %w", err)
+	}
+
+	return retries, true, nil
+}
+
+// Delete deletes a record with the given taskId, returns sql.ErrNoRows when there is no record.
+func (r *Repository) Delete(ctx context.Context, taskId string) error {
+	id, err := uuid.Parse(taskId)
+	if err != nil {
+		return fmt.Errorf("parse task id: %w", err)
+	}
+
+	const q = `
+	DELETE FROM task_retries WHERE task_id = $1
+	`
+	tag, err := r.client.Pool.Exec(ctx, q, id)
+	if err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ClearRetries deletes the retry counter of the given task id, a missing counter is not an error.
+func (r *Repository) ClearRetries(ctx context.Context, taskId string) error {
+	if err := r.Delete(ctx, taskId); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	return nil
+}
+
+// SweepRetries deletes the retry counters that were not incremented for ttl and returns the number of counters that
+// are left along with the number of deleted ones.
+func (r *Repository) SweepRetries(ctx context.Context, ttl time.Duration) (int, int, error) {
+	const q = `
+	DELETE FROM task_retries WHERE updated_at < $1
+	`
+	tag, err := r.client.Pool.Exec(ctx, q, time.Now().UTC().Add(-ttl))
+	if err != nil {
+		return 0, 0, fmt.Errorf("exec: %w", err)
+	}
+
+	var counters int
+	if err := r.client.Pool.QueryRow(ctx, `SELECT count(*) FROM task_retries`).Scan(&counters); err != nil {
+		return 0, 0, fmt.Errorf("count: %w", err)
+	}
+
+	return counters, int(tag.RowsAffected()), nil
+}
+
+// AcquireLease acquires or renews the lease with the given name for the owner, returns false when someone else
+// holds the lease. Expiry is calculated using the database clock so instances do not need synced clocks.
+func (r *Repository) AcquireLease(ctx context.Context, name string, owner string, ttl time.Duration) (bool, error) {
+	const q = `
+	INSERT INTO leases
+		(name,owner,expires_at)
+	VALUES
+		($1,$2,now() + $3 * interval '1 millisecond')
+	ON CONFLICT (name) DO UPDATE SET
+		owner = EXCLUDED.owner,
+		expires_at = EXCLUDED.expires_at
+	WHERE leases.owner = EXCLUDED.owner OR leases.expires_at < now()
+	RETURNING owner	
+	`
+	var holder string
+	err := r.client.Pool.QueryRow(ctx, q, name, owner, ttl.Milliseconds()).Scan(&holder)
+	if err != nil {
+		//lease is held by someone else
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("scan: %w", err)
+	}
+
+	return holder == owner, nil
+}
+
+// ReleaseLease releases the lease with the given name if the owner still holds it.
+func (r *Repository) ReleaseLease(ctx context.Context, name string, owner string) error {
+	const q = `
+	DELETE FROM leases WHERE name = $1 AND owner = $2
+	`
+	if _, err := r.client.Pool.Exec(ctx, q, name, owner); err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	schedulerRepo "github.com/hamidoujand/task-scheduler/business/domain/scheduler/store/postgres"
+	"github.com/hamidoujand/task-scheduler/business/testinfra"
+)
+
+func TestIncrement(t *testing.T) {
+	t.Parallel()
+	client := testinfra.NewDatabase(t)
+	repo := schedulerRepo.NewRepository(client)
+
+	taskId := uuid.NewString()
+	max := 2
+
+	for want := 1; want <= max; want++ {
+		retries, ok, err := repo.Increment(context.Background(), taskId, max, time.Hour)
+		if err != nil {
+			t.Fatalf("expected to increment retries: %s", err)
+		}
+
+		if !ok {
+			t.Fatalf("expected retry %d to be allowed", want)
+		}
+
+		if retries != want {
+			t.Errorf("retries= %d, got %d", want, retries)
+		}
+	}
+
+	//exhausted
+	retries, ok, err := repo.Increment(context.Background(), taskId, max, time.Hour)
+	if err != nil {
+		t.Fatalf("expected to increment retries: %s", err)
+	}
+
+	if ok {
+		t.Error("expected retries to be exhausted")
+	}
+
+	if retries != max {
+		t.Errorf("retries= %d, got %d", max, retries)
+	}
+
+	stored, err := repo.Get(context.Background(), taskId)
+	if err != nil {
+		t.Fatalf("expected to fetch retries: %s", err)
+	}
+
+	if stored != max {
+		t.Errorf("stored retries= %d, got %d", max, stored)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	t.Parallel()
+	client := testinfra.NewDatabase(t)
+	repo := schedulerRepo.NewRepository(client)
+
+	taskId := uuid.NewString()
+	if err := repo.Create(context.Background(), taskId); err != nil {
+		t.Fatalf("expected to insert a new record: %s", err)
+	}
+
+	if err := repo.Delete(context.Background(), taskId); err != nil {
+		t.Fatalf("expected to delete the record: %s", err)
+	}
+
+	_, err := repo.Get(context.Background(), taskId)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("error= %v, got %v", sql.ErrNoRows, err)
+	}
+}
+
+func TestLease(t *testing.T) {
+	t.Parallel()
+	client := testinfra.NewDatabase(t)
+	repo := schedulerRepo.NewRepository(client)
+
+	leader := uuid.NewString()
+	standby := uuid.NewString()
+	ttl := time.Second * 2
+
+	held, err := repo.AcquireLease(context.Background(), "leader", leader, ttl)
+	if err != nil {
+		t.Fatalf("expected to acquire lease: %s", err)
+	}
+
+	if !held {
+		t.Fatal("expected leader to hold the free lease")
+	}
+
+	//standby must not take it while it is alive
+	held, err = repo.AcquireLease(context.Background(), "leader", standby, ttl)
+	if err != nil {
+		t.Fatalf("expected to try the lease: %s", err)
+	}
+
+	if held {
+		t.Fatal("expected standby to not hold a lease that is owned by leader")
+	}
+
+	//renew
+	held, err = repo.AcquireLease(context.Background(), "leader", leader, ttl)
+	if err != nil {
+		t.Fatalf("expected to renew lease: %s", err)
+	}
+
+	if !held {
+		t.Fatal("expected leader to renew its own lease")
+	}
+
+	//lapsed lease gets taken over
+	time.Sleep(ttl + time.Second)
+
+	held, err = repo.AcquireLease(context.Background(), "leader", standby, ttl)
+	if err != nil {
+		t.Fatalf("expected to acquire lapsed lease: %s", err)
+	}
+
+	if !held {
+		t.Fatal("expected standby to take over the lapsed lease")
+	}
+
+	//old leader can not release the new owner's lease
+	if err := repo.ReleaseLease(context.Background(), "leader", leader); err != nil {
+		t.Fatalf("expected release to succeed: %s", err)
+	}
+
+	held, err = repo.AcquireLease(context.Background(), "leader", leader, ttl)
+	if err != nil {
+		t.Fatalf("expected to try the lease: %s", err)
+	}
+
+	if held {
+		t.Error("expected old leader to not hold the lease after takeover")
+	}
+}
@@ -3,8 +3,10 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 )
@@ -60,6 +62,81 @@ func (r *Repository) Update(ctx context.Context, taskId string, retries int) err
 	return nil
 }
 
+// incrementScript increments the retries only while they are below the max, returns -1 when retries are exhausted.
+// Every increment pushes the expiry of the counter ARGV[2] milliseconds into the future.
+var incrementScript = redis.NewScript(`
+local retries = tonumber(redis.call("HGET", KEYS[1], "retries") or "0")
+if retries >= tonumber(ARGV[1]) then
+	return -1
+end
+retries = redis.call("HINCRBY", KEYS[1], "retries", 1)
+redis.call("PEXPIRE", KEYS[1], ARGV[2])
+return retries
+`)
+
+// Increment atomically increments the retries of the given task id as long as they are below max and returns the
+// new value, false means retries are exhausted. The counter expires ttl after its last increment.
+func (r *Repository) Increment(ctx context.Context, taskId string, max int, ttl time.Duration) (int, bool, error) {
+	key := entity + ":" + taskId
+
+	retries, err := incrementScript.Run(ctx, r.client, []string{key}, max, ttl.Milliseconds()).Int()
+	if err != nil {
+		return 0, false, fmt.Errorf("run increment script: %w", err)
+	}
+
+	if retries < 0 {
+		return max, false, nil
+	}
+
+	return retries, true, nil
+}
+
+// ClearRetries deletes the retry counter of the given task id, a missing counter is not an error.
+func (r *Repository) ClearRetries(ctx context.Context, taskId string) error {
+	key := entity + ":" + taskId
+
+	if err := r.client.Del(ctx, key).Err(); err != nil {
+		return fmt.Errorf("del: %w", err)
+	}
+	return nil
+}
+
+// sweepBatch is the number of keys scanned at once by SweepRetries.
+const sweepBatch = 100
+
+// SweepRetries sets an expiry of ttl on the retry counters that have none, like the ones written before counters
+// expired, and returns the number of counters along with the number of them that got an expiry.
+func (r *Repository) SweepRetries(ctx context.Context, ttl time.Duration) (int, int, error) {
+	var counters, swept int
+
+	//the cached tasks share the prefix but are strings
+	iter := r.client.ScanType(ctx, 0, entity+":*", sweepBatch, "hash").Iterator()
+	for iter.Next(ctx) {
+		counters++
+
+		//-1 is a key without an expiry, the ones that expired in between are -2
+		remaining, err := r.client.PTTL(ctx, iter.Val()).Result()
+		if err != nil {
+			return counters, swept, fmt.Errorf("pttl: %w", err)
+		}
+
+		if remaining != -1 {
+			continue
+		}
+
+		if err := r.client.PExpire(ctx, iter.Val(), ttl).Err(); err != nil {
+			return counters, swept, fmt.Errorf("pexpire: %w", err)
+		}
+		swept++
+	}
+
+	if err := iter.Err(); err != nil {
+		return counters, swept, fmt.Errorf("scan: %w", err)
+	}
+
+	return counters, swept, nil
+}
+
 // Delete delete a record with the given taskId.
 func (r *Repository) Delete(ctx context.Context, taskId string) error {
 	key := entity + ":" + taskId
@@ -75,3 +152,163 @@ func (r *Repository) Delete(ctx context.Context, taskId string) error {
 
 	return nil
 }
+
+// acquireLeaseScript sets the lease when it is free or extends it when the caller already owns it.
+var acquireLeaseScript = redis.NewScript(`
+local owner = redis.call("GET", KEYS[1])
+if not owner then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+	return 1
+end
+if owner == ARGV[1] then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// releaseLeaseScript only deletes the lease when the caller owns it.
+var releaseLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// AcquireLease acquires or renews the lease with the given name for the owner, returns false when someone else
+// holds the lease.
+func (r *Repository) AcquireLease(ctx context.Context, name string, owner string, ttl time.Duration) (bool, error) {
+	key := "leases:" + name
+
+	acquired, err := acquireLeaseScript.Run(ctx, r.client, []string{key}, owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("run acquire lease script: %w", err)
+	}
+	return acquired == 1, nil
+}
+
+// ReleaseLease releases the lease with the given name if the owner still holds it.
+func (r *Repository) ReleaseLease(ctx context.Context, name string, owner string) error {
+	key := "leases:" + name
+
+	if err := releaseLeaseScript.Run(ctx, r.client, []string{key}, owner).Err(); err != nil {
+		return fmt.Errorf("run release lease script: %w", err)
+	}
+	return nil
+}
+
+// acquireSlotScript drops the holders whose slot expired and adds the holder while there are less than the limit,
+// the score of a holder is when its slot expires.
+var acquireSlotScript = redis.NewScript(`
+local now = tonumber(ARGV[2])
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now)
+if redis.call("ZSCORE", KEYS[1], ARGV[1]) then
+	return 1
+end
+if redis.call("ZCARD", KEYS[1]) >= tonumber(ARGV[3]) then
+	return 0
+end
+redis.call("ZADD", KEYS[1], now + tonumber(ARGV[4]), ARGV[1])
+redis.call("PEXPIRE", KEYS[1], ARGV[4])
+return 1
+`)
+
+// AcquireSlot takes one of the limit slots of the semaphore with the given name for the holder, returns false when
+// all of them are taken. A slot is freed by ReleaseSlot or after ttl in case its holder died.
+func (r *Repository) AcquireSlot(ctx context.Context, name string, holder string, limit int, ttl time.Duration) (bool, error) {
+	key := "slots:" + name
+
+	acquired, err := acquireSlotScript.Run(ctx, r.client, []string{key}, holder, time.Now().UnixMilli(), limit, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("run acquire slot script: %w", err)
+	}
+	return acquired == 1, nil
+}
+
+// ReleaseSlot frees the slot of the holder.
+func (r *Repository) ReleaseSlot(ctx context.Context, name string, holder string) error {
+	key := "slots:" + name
+
+	if err := r.client.ZRem(ctx, key, holder).Err(); err != nil {
+		return fmt.Errorf("zrem: %w", err)
+	}
+	return nil
+}
+
+// ClaimExecution claims the execution of the task for the run, returns false when another run holds the claim. The
+// claim expires after ttl in case its run died.
+func (r *Repository) ClaimExecution(ctx context.Context, taskId string, runId string, ttl time.Duration) (bool, error) {
+	key := "task:claim:" + taskId
+
+	claimed, err := r.client.SetNX(ctx, key, runId, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("setnx: %w", err)
+	}
+	return claimed, nil
+}
+
+// ReleaseExecution releases the claim of the task if the run still holds it.
+func (r *Repository) ReleaseExecution(ctx context.Context, taskId string, runId string) error {
+	key := "task:claim:" + taskId
+
+	if err := releaseLeaseScript.Run(ctx, r.client, []string{key}, runId).Err(); err != nil {
+		return fmt.Errorf("run release lease script: %w", err)
+	}
+	return nil
+}
+
+// popDueScript removes and returns up to ARGV[2] members whose score is less than or equal to ARGV[1].
+var popDueScript = redis.NewScript(`
+local due = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1], "LIMIT", 0, ARGV[2])
+if #due > 0 then
+	redis.call("ZREM", KEYS[1], unpack(due))
+end
+return due
+`)
+
+// AddDelayed holds the body until runAt, the score of a body is when it runs.
+func (r *Repository) AddDelayed(ctx context.Context, body []byte, runAt time.Time) error {
+	if err := r.client.ZAdd(ctx, "delayed", redis.Z{Score: float64(runAt.UnixMilli()), Member: body}).Err(); err != nil {
+		return fmt.Errorf("zadd: %w", err)
+	}
+	return nil
+}
+
+// PopDue atomically removes and returns up to limit bodies that are due at now, a body is only returned once.
+func (r *Repository) PopDue(ctx context.Context, now time.Time, limit int) ([][]byte, error) {
+	members, err := popDueScript.Run(ctx, r.client, []string{"delayed"}, now.UnixMilli(), limit).StringSlice()
+	if err != nil {
+		return nil, fmt.Errorf("run pop due script: %w", err)
+	}
+
+	bodies := make([][]byte, len(members))
+	for i, member := range members {
+		bodies[i] = []byte(member)
+	}
+	return bodies, nil
+}
+
+// Spill keeps the body at the end of the spilled list.
+func (r *Repository) Spill(ctx context.Context, body []byte) error {
+	if err := r.client.RPush(ctx, "spilled", body).Err(); err != nil {
+		return fmt.Errorf("rpush: %w", err)
+	}
+	return nil
+}
+
+// PopSpilled removes and returns up to limit bodies from the start of the spilled list.
+func (r *Repository) PopSpilled(ctx context.Context, limit int) ([][]byte, error) {
+	members, err := r.client.LPopCount(ctx, "spilled", limit).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("lpop: %w", err)
+	}
+
+	bodies := make([][]byte, len(members))
+	for i, member := range members {
+		bodies[i] = []byte(member)
+	}
+	return bodies, nil
+}
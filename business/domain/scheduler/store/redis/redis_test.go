@@ -4,16 +4,17 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	redisRepo "github.com/hamidoujand/task-scheduler/business/domain/scheduler/store/redis"
-	"github.com/hamidoujand/task-scheduler/business/redistest"
+	"github.com/hamidoujand/task-scheduler/business/testinfra"
 	"github.com/redis/go-redis/v9"
 )
 
 func TestCreate(t *testing.T) {
 	t.Parallel()
-	client := redistest.NewRedisClient(t, context.Background(), "test_redis_create")
+	client := testinfra.NewRedis(t)
 	repo := redisRepo.NewRepository(client)
 
 	taskId := uuid.NewString()
@@ -25,7 +26,7 @@ func TestCreate(t *testing.T) {
 
 func TestGet(t *testing.T) {
 	t.Parallel()
-	client := redistest.NewRedisClient(t, context.Background(), "test_redis_get")
+	client := testinfra.NewRedis(t)
 	repo := redisRepo.NewRepository(client)
 
 	taskId := uuid.NewString()
@@ -57,7 +58,7 @@ func TestGet(t *testing.T) {
 
 func TestUpdate(t *testing.T) {
 	t.Parallel()
-	client := redistest.NewRedisClient(t, context.Background(), "test_redis_update")
+	client := testinfra.NewRedis(t)
 	repo := redisRepo.NewRepository(client)
 
 	taskId := uuid.NewString()
@@ -83,7 +84,7 @@ func TestUpdate(t *testing.T) {
 
 func TestDelete(t *testing.T) {
 	t.Parallel()
-	client := redistest.NewRedisClient(t, context.Background(), "test_redis_delete")
+	client := testinfra.NewRedis(t)
 	repo := redisRepo.NewRepository(client)
 
 	taskId := uuid.NewString()
@@ -105,3 +106,251 @@ func TestDelete(t *testing.T) {
 		t.Fatalf("error = %v, got %v", redis.Nil, err)
 	}
 }
+
+func TestRetryExpiry(t *testing.T) {
+	t.Parallel()
+	client := testinfra.NewRedis(t)
+	repo := redisRepo.NewRepository(client)
+
+	taskId := uuid.NewString()
+	if _, _, err := repo.Increment(context.Background(), taskId, 3, time.Hour); err != nil {
+		t.Fatalf("expected to increment retries: %s", err)
+	}
+
+	ttl, err := client.PTTL(context.Background(), "tasks:"+taskId).Result()
+	if err != nil {
+		t.Fatalf("expected to read the expiry of the counter: %s", err)
+	}
+
+	if ttl <= 0 || ttl > time.Hour {
+		t.Errorf("expected the counter to expire within %s, got %s", time.Hour, ttl)
+	}
+
+	//counters of the past have no expiry
+	legacyId := uuid.NewString()
+	if err := repo.Create(context.Background(), legacyId); err != nil {
+		t.Fatalf("expected to insert a new record: %s", err)
+	}
+
+	counters, swept, err := repo.SweepRetries(context.Background(), time.Minute)
+	if err != nil {
+		t.Fatalf("expected to sweep the counters: %s", err)
+	}
+
+	if counters != 2 || swept != 1 {
+		t.Errorf("expected %d counters and %d swept, got %d and %d", 2, 1, counters, swept)
+	}
+
+	ttl, err = client.PTTL(context.Background(), "tasks:"+legacyId).Result()
+	if err != nil {
+		t.Fatalf("expected to read the expiry of the counter: %s", err)
+	}
+
+	if ttl <= 0 || ttl > time.Minute {
+		t.Errorf("expected the swept counter to expire within %s, got %s", time.Minute, ttl)
+	}
+
+	if err := repo.ClearRetries(context.Background(), taskId); err != nil {
+		t.Fatalf("expected to clear the retries: %s", err)
+	}
+
+	//clearing twice is fine
+	if err := repo.ClearRetries(context.Background(), taskId); err != nil {
+		t.Fatalf("expected to clear missing retries: %s", err)
+	}
+
+	if _, err := repo.Get(context.Background(), taskId); !errors.Is(err, redis.Nil) {
+		t.Fatalf("error = %v, got %v", redis.Nil, err)
+	}
+}
+
+func TestSlots(t *testing.T) {
+	t.Parallel()
+	client := testinfra.NewRedis(t)
+	repo := redisRepo.NewRepository(client)
+
+	ctx := context.Background()
+	const name = "postgres-backup"
+
+	for _, holder := range []string{"first", "second"} {
+		acquired, err := repo.AcquireSlot(ctx, name, holder, 2, time.Minute)
+		if err != nil {
+			t.Fatalf("expected to acquire slot: %s", err)
+		}
+		if !acquired {
+			t.Fatalf("expected %s to get a slot", holder)
+		}
+	}
+
+	acquired, err := repo.AcquireSlot(ctx, name, "third", 2, time.Minute)
+	if err != nil {
+		t.Fatalf("expected to acquire slot: %s", err)
+	}
+	if acquired {
+		t.Fatal("expected the third holder to not get a slot")
+	}
+
+	if err := repo.ReleaseSlot(ctx, name, "first"); err != nil {
+		t.Fatalf("expected to release slot: %s", err)
+	}
+
+	acquired, err = repo.AcquireSlot(ctx, name, "third", 2, time.Minute)
+	if err != nil {
+		t.Fatalf("expected to acquire slot: %s", err)
+	}
+	if !acquired {
+		t.Fatal("expected the third holder to get the released slot")
+	}
+
+	//slots of holders that died expire
+	other := "other-image"
+	if _, err := repo.AcquireSlot(ctx, other, "dead", 1, time.Millisecond); err != nil {
+		t.Fatalf("expected to acquire slot: %s", err)
+	}
+	time.Sleep(time.Millisecond * 10)
+
+	acquired, err = repo.AcquireSlot(ctx, other, "alive", 1, time.Minute)
+	if err != nil {
+		t.Fatalf("expected to acquire slot: %s", err)
+	}
+	if !acquired {
+		t.Fatal("expected the expired slot to be freed")
+	}
+}
+
+func TestClaims(t *testing.T) {
+	t.Parallel()
+	client := testinfra.NewRedis(t)
+	repo := redisRepo.NewRepository(client)
+
+	ctx := context.Background()
+	taskId := uuid.NewString()
+
+	claimed, err := repo.ClaimExecution(ctx, taskId, "first", time.Minute)
+	if err != nil {
+		t.Fatalf("expected to claim execution: %s", err)
+	}
+	if !claimed {
+		t.Fatal("expected the first run to claim the task")
+	}
+
+	claimed, err = repo.ClaimExecution(ctx, taskId, "second", time.Minute)
+	if err != nil {
+		t.Fatalf("expected to claim execution: %s", err)
+	}
+	if claimed {
+		t.Fatal("expected the second run to not claim the task")
+	}
+
+	//only the run that holds the claim releases it
+	if err := repo.ReleaseExecution(ctx, taskId, "second"); err != nil {
+		t.Fatalf("expected to release execution: %s", err)
+	}
+
+	claimed, err = repo.ClaimExecution(ctx, taskId, "second", time.Minute)
+	if err != nil {
+		t.Fatalf("expected to claim execution: %s", err)
+	}
+	if claimed {
+		t.Fatal("expected the claim of the first run to be kept")
+	}
+
+	if err := repo.ReleaseExecution(ctx, taskId, "first"); err != nil {
+		t.Fatalf("expected to release execution: %s", err)
+	}
+
+	claimed, err = repo.ClaimExecution(ctx, taskId, "second", time.Minute)
+	if err != nil {
+		t.Fatalf("expected to claim execution: %s", err)
+	}
+	if !claimed {
+		t.Fatal("expected the second run to claim the released task")
+	}
+
+	//claims of runs that died expire
+	other := uuid.NewString()
+	if _, err := repo.ClaimExecution(ctx, other, "dead", time.Millisecond); err != nil {
+		t.Fatalf("expected to claim execution: %s", err)
+	}
+	time.Sleep(time.Millisecond * 10)
+
+	claimed, err = repo.ClaimExecution(ctx, other, "alive", time.Minute)
+	if err != nil {
+		t.Fatalf("expected to claim execution: %s", err)
+	}
+	if !claimed {
+		t.Fatal("expected the expired claim to be freed")
+	}
+}
+
+func TestDelayed(t *testing.T) {
+	t.Parallel()
+	client := testinfra.NewRedis(t)
+	repo := redisRepo.NewRepository(client)
+
+	ctx := context.Background()
+	now := time.Now()
+
+	if err := repo.AddDelayed(ctx, []byte("due"), now.Add(-time.Second)); err != nil {
+		t.Fatalf("expected to add delayed body: %s", err)
+	}
+	if err := repo.AddDelayed(ctx, []byte("later"), now.Add(time.Hour)); err != nil {
+		t.Fatalf("expected to add delayed body: %s", err)
+	}
+
+	bodies, err := repo.PopDue(ctx, now, 10)
+	if err != nil {
+		t.Fatalf("expected to pop due bodies: %s", err)
+	}
+	if len(bodies) != 1 || string(bodies[0]) != "due" {
+		t.Fatalf("bodies= [due], got %q", bodies)
+	}
+
+	//popped bodies are gone
+	bodies, err = repo.PopDue(ctx, now, 10)
+	if err != nil {
+		t.Fatalf("expected to pop due bodies: %s", err)
+	}
+	if len(bodies) != 0 {
+		t.Errorf("expected no due bodies, got %q", bodies)
+	}
+}
+
+func TestSpilled(t *testing.T) {
+	t.Parallel()
+	client := testinfra.NewRedis(t)
+	repo := redisRepo.NewRepository(client)
+
+	ctx := context.Background()
+
+	for _, body := range []string{"first", "second", "third"} {
+		if err := repo.Spill(ctx, []byte(body)); err != nil {
+			t.Fatalf("expected to spill body: %s", err)
+		}
+	}
+
+	bodies, err := repo.PopSpilled(ctx, 2)
+	if err != nil {
+		t.Fatalf("expected to pop spilled bodies: %s", err)
+	}
+	if len(bodies) != 2 || string(bodies[0]) != "first" || string(bodies[1]) != "second" {
+		t.Fatalf("bodies= [first second], got %q", bodies)
+	}
+
+	bodies, err = repo.PopSpilled(ctx, 2)
+	if err != nil {
+		t.Fatalf("expected to pop spilled bodies: %s", err)
+	}
+	if len(bodies) != 1 || string(bodies[0]) != "third" {
+		t.Fatalf("bodies= [third], got %q", bodies)
+	}
+
+	//an empty list is not an error
+	bodies, err = repo.PopSpilled(ctx, 2)
+	if err != nil {
+		t.Fatalf("expected to pop spilled bodies: %s", err)
+	}
+	if len(bodies) != 0 {
+		t.Errorf("expected no spilled bodies, got %q", bodies)
+	}
+}
@@ -0,0 +1,40 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	"github.com/hamidoujand/task-scheduler/business/domain/usage"
+	"github.com/hamidoujand/task-scheduler/foundation/runtime"
+)
+
+// usageRecorder represents the behaviour required for accounting the resources consumed by executions.
+type usageRecorder interface {
+	Record(ctx context.Context, nr usage.NewRecord) (usage.Record, error)
+}
+
+// recordUsage stores the resources the execution of the task consumed under the id of its run, recording is best
+// effort and never fails the task.
+func (s *Scheduler) recordUsage(tsk task.Task, runId uuid.UUID, consumed *runtime.Usage) {
+	if s.usage == nil {
+		return
+	}
+
+	nr := usage.NewRecord{
+		RunId:      runId,
+		TaskId:     tsk.Id,
+		UserId:     tsk.UserId,
+		CPU:        consumed.CPU(),
+		MemoryPeak: consumed.MemoryPeak(),
+		Duration:   tsk.FinishedAt.Sub(tsk.StartedAt),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.maxTimeForUpdateOps)
+	defer cancel()
+
+	if _, err := s.usage.Record(ctx, nr); err != nil {
+		s.logger.Error("recordUsage", "status", fmt.Sprintf("failed to record usage of task %s", tsk.Id), "msg", err)
+	}
+}
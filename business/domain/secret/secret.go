@@ -0,0 +1,182 @@
+// Package secret provides the named secrets of users, they are encrypted at rest and only decrypted when a task
+// that references them is executed.
+package secret
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefPrefix marks an environment value as a reference to a secret, "DB_PASSWORD=secretRef:db-password" runs the
+// task with the value of the "db-password" secret of its owner.
+const RefPrefix = "secretRef:"
+
+var (
+	ErrSecretNotFound = errors.New("secret not found")
+	ErrInvalidName    = errors.New("secret name must be 1 to 64 letters, digits, '-' or '_'")
+)
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// store represents the storage of secrets, Get returns sql.ErrNoRows when the user has no secret with the name.
+type store interface {
+	Upsert(ctx context.Context, s Secret) error
+	Get(ctx context.Context, userId uuid.UUID, name string) (Secret, error)
+	List(ctx context.Context, userId uuid.UUID) ([]Secret, error)
+	Delete(ctx context.Context, userId uuid.UUID, name string) error
+}
+
+// Secret represents a named secret of a user, Value is the encrypted value and never leaves the service in clear.
+type Secret struct {
+	UserId    uuid.UUID
+	Name      string
+	Value     []byte
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Service represents set of APIs for managing secrets.
+type Service struct {
+	store store
+	aead  cipher.AEAD
+}
+
+// NewService creates a secret service, key is the 32 bytes AES-256 key the values are encrypted with.
+func NewService(store store, key []byte) (*Service, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("key must be 32 bytes: %d", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("new cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("new gcm: %w", err)
+	}
+
+	return &Service{
+		store: store,
+		aead:  aead,
+	}, nil
+}
+
+// SetSecret creates the secret or replaces its value when the user already has a secret with the name.
+func (s *Service) SetSecret(ctx context.Context, userId uuid.UUID, name string, value string) (Secret, error) {
+	if !validName.MatchString(name) {
+		return Secret{}, ErrInvalidName
+	}
+
+	now := time.Now()
+	sec := Secret{
+		UserId:    userId,
+		Name:      name,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	existing, err := s.store.Get(ctx, userId, name)
+	switch {
+	case err == nil:
+		sec.CreatedAt = existing.CreatedAt
+	case !errors.Is(err, sql.ErrNoRows):
+		return Secret{}, fmt.Errorf("get: %w", err)
+	}
+
+	sec.Value, err = s.encrypt(userId, name, value)
+	if err != nil {
+		return Secret{}, err
+	}
+
+	if err := s.store.Upsert(ctx, sec); err != nil {
+		return Secret{}, fmt.Errorf("upsert: %w", err)
+	}
+	return sec, nil
+}
+
+// ListSecrets returns the secrets of the user ordered by name.
+func (s *Service) ListSecrets(ctx context.Context, userId uuid.UUID) ([]Secret, error) {
+	secrets, err := s.store.List(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("list: %w", err)
+	}
+	return secrets, nil
+}
+
+// DeleteSecret deletes the secret of the user, returns ErrSecretNotFound when there is no secret with the name.
+func (s *Service) DeleteSecret(ctx context.Context, userId uuid.UUID, name string) error {
+	if err := s.store.Delete(ctx, userId, name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSecretNotFound
+		}
+		return fmt.Errorf("delete: %w", err)
+	}
+	return nil
+}
+
+// ResolveEnv replaces the values of env that reference a secret with the decrypted value of the secret of the user,
+// env is formatted like the environment of a task "KEY=value KEY2=value2".
+func (s *Service) ResolveEnv(ctx context.Context, userId uuid.UUID, env string) ([]string, error) {
+	vars := strings.Fields(env)
+	for i, v := range vars {
+		key, value, _ := strings.Cut(v, "=")
+		name, ok := strings.CutPrefix(value, RefPrefix)
+		if !ok {
+			continue
+		}
+
+		sec, err := s.store.Get(ctx, userId, name)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, fmt.Errorf("%s: %w", name, ErrSecretNotFound)
+			}
+			return nil, fmt.Errorf("get %s: %w", name, err)
+		}
+
+		plain, err := s.decrypt(sec)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt %s: %w", name, err)
+		}
+		vars[i] = key + "=" + plain
+	}
+	return vars, nil
+}
+
+// encrypt seals the value with a random nonce in front of it, the owner and name are authenticated so a value
+// can not be moved to another secret.
+func (s *Service) encrypt(userId uuid.UUID, name string, value string) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("read nonce: %w", err)
+	}
+	return s.aead.Seal(nonce, nonce, []byte(value), additionalData(userId, name)), nil
+}
+
+func (s *Service) decrypt(sec Secret) (string, error) {
+	if len(sec.Value) < s.aead.NonceSize() {
+		return "", errors.New("value too short")
+	}
+
+	nonce, sealed := sec.Value[:s.aead.NonceSize()], sec.Value[s.aead.NonceSize():]
+	plain, err := s.aead.Open(nil, nonce, sealed, additionalData(sec.UserId, sec.Name))
+	if err != nil {
+		return "", fmt.Errorf("open: %w", err)
+	}
+	return string(plain), nil
+}
+
+func additionalData(userId uuid.UUID, name string) []byte {
+	return []byte(userId.String() + "/" + name)
+}
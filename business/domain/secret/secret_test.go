@@ -0,0 +1,72 @@
+package secret_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/secret"
+	"github.com/hamidoujand/task-scheduler/business/domain/secret/store/memory"
+)
+
+func TestSecret(t *testing.T) {
+	t.Parallel()
+
+	repo := memory.Repository{}
+	service, err := secret.NewService(&repo, bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatalf("expected to create the service: %s", err)
+	}
+
+	ctx := context.Background()
+	userId := uuid.New()
+
+	sec, err := service.SetSecret(ctx, userId, "db-password", "s3cr3t value")
+	if err != nil {
+		t.Fatalf("expected to set the secret: %s", err)
+	}
+
+	//only the encrypted value is stored
+	if bytes.Contains(sec.Value, []byte("s3cr3t")) {
+		t.Errorf("expected the value to be encrypted, got %q", sec.Value)
+	}
+
+	env, err := service.ResolveEnv(ctx, userId, "MODE=prod DB_PASSWORD=secretRef:db-password")
+	if err != nil {
+		t.Fatalf("expected to resolve the environment: %s", err)
+	}
+
+	expected := []string{"MODE=prod", "DB_PASSWORD=s3cr3t value"}
+	if !slices.Equal(env, expected) {
+		t.Errorf("env= %q, got %q", expected, env)
+	}
+
+	//secrets of other users can not be referenced
+	_, err = service.ResolveEnv(ctx, uuid.New(), "DB_PASSWORD=secretRef:db-password")
+	if !errors.Is(err, secret.ErrSecretNotFound) {
+		t.Errorf("err= %v, got %v", secret.ErrSecretNotFound, err)
+	}
+
+	if _, err := service.SetSecret(ctx, userId, "db password", "value"); !errors.Is(err, secret.ErrInvalidName) {
+		t.Errorf("err= %v, got %v", secret.ErrInvalidName, err)
+	}
+
+	secrets, err := service.ListSecrets(ctx, userId)
+	if err != nil {
+		t.Fatalf("expected to list secrets: %s", err)
+	}
+	if len(secrets) != 1 {
+		t.Fatalf("len(secrets)= %d, got %d", 1, len(secrets))
+	}
+
+	if err := service.DeleteSecret(ctx, userId, "db-password"); err != nil {
+		t.Fatalf("expected to delete the secret: %s", err)
+	}
+
+	if err := service.DeleteSecret(ctx, userId, "db-password"); !errors.Is(err, secret.ErrSecretNotFound) {
+		t.Errorf("err= %v, got %v", secret.ErrSecretNotFound, err)
+	}
+}
@@ -0,0 +1,79 @@
+// Package memory provides an in memory repository used for testing.
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/secret"
+)
+
+type key struct {
+	userId uuid.UUID
+	name   string
+}
+
+// Repository represents an in-memory storage for testing.
+type Repository struct {
+	secrets map[key]secret.Secret
+	mu      sync.Mutex
+}
+
+// Upsert creates or replaces the secret.
+func (r *Repository) Upsert(ctx context.Context, s secret.Secret) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.secrets == nil {
+		r.secrets = make(map[key]secret.Secret)
+	}
+	r.secrets[key{userId: s.UserId, name: s.Name}] = s
+	return nil
+}
+
+// Get returns the secret of the user or sql.ErrNoRows.
+func (r *Repository) Get(ctx context.Context, userId uuid.UUID, name string) (secret.Secret, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.secrets[key{userId: userId, name: name}]
+	if !ok {
+		return secret.Secret{}, sql.ErrNoRows
+	}
+	return s, nil
+}
+
+// List returns the secrets of the user ordered by name.
+func (r *Repository) List(ctx context.Context, userId uuid.UUID) ([]secret.Secret, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var secrets []secret.Secret
+	for k, s := range r.secrets {
+		if k.userId == userId {
+			secrets = append(secrets, s)
+		}
+	}
+
+	slices.SortFunc(secrets, func(a, b secret.Secret) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return secrets, nil
+}
+
+// Delete deletes the secret of the user or returns sql.ErrNoRows.
+func (r *Repository) Delete(ctx context.Context, userId uuid.UUID, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := key{userId: userId, name: name}
+	if _, ok := r.secrets[k]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(r.secrets, k)
+	return nil
+}
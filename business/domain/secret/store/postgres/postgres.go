@@ -0,0 +1,120 @@
+// Package postgres provides the secret storage on top of postgres.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/database/postgres"
+	"github.com/hamidoujand/task-scheduler/business/domain/secret"
+)
+
+// Repository represents all of the APIs used for CRUD against postgres.
+type Repository struct {
+	client *postgres.Client
+}
+
+// NewRepository creates a new postgres repository.
+func NewRepository(client *postgres.Client) *Repository {
+	return &Repository{
+		client: client,
+	}
+}
+
+// Upsert creates or replaces the value of the secret.
+func (r *Repository) Upsert(ctx context.Context, s secret.Secret) error {
+	const q = `
+	INSERT INTO secrets
+		(user_id,name,value,created_at,updated_at)
+	VALUES
+		($1,$2,$3,$4,$5)
+	ON CONFLICT (user_id,name) DO UPDATE SET
+		value = EXCLUDED.value,
+		updated_at = EXCLUDED.updated_at
+	`
+
+	if _, err := r.client.Pool.Exec(ctx, q, s.UserId, s.Name, s.Value, s.CreatedAt.UTC(), s.UpdatedAt.UTC()); err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+	return nil
+}
+
+// Get returns the secret of the user, returns sql.ErrNoRows when there is no record.
+func (r *Repository) Get(ctx context.Context, userId uuid.UUID, name string) (secret.Secret, error) {
+	const q = `
+	SELECT
+		user_id,name,value,created_at,updated_at
+	FROM secrets
+	WHERE user_id = $1 AND name = $2
+	`
+
+	s, err := scanSecret(r.client.Pool.QueryRow(ctx, q, userId, name))
+	if err != nil {
+		return secret.Secret{}, fmt.Errorf("row scan: %w", postgres.NoRows(err))
+	}
+	return s, nil
+}
+
+// List returns the secrets of the user ordered by name.
+func (r *Repository) List(ctx context.Context, userId uuid.UUID) ([]secret.Secret, error) {
+	const q = `
+	SELECT
+		user_id,name,value,created_at,updated_at
+	FROM secrets
+	WHERE user_id = $1
+	ORDER BY name
+	`
+
+	rows, err := r.client.Pool.Query(ctx, q, userId)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	var secrets []secret.Secret
+	for rows.Next() {
+		s, err := scanSecret(rows)
+		if err != nil {
+			return nil, fmt.Errorf("rows scan: %w", err)
+		}
+		secrets = append(secrets, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+	return secrets, nil
+}
+
+// Delete deletes the secret of the user, returns sql.ErrNoRows when there is no record.
+func (r *Repository) Delete(ctx context.Context, userId uuid.UUID, name string) error {
+	const q = `DELETE FROM secrets WHERE user_id = $1 AND name = $2`
+
+	tag, err := r.client.Pool.Exec(ctx, q, userId, name)
+	if err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSecret(row rowScanner) (secret.Secret, error) {
+	var s secret.Secret
+	if err := row.Scan(&s.UserId, &s.Name, &s.Value, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		return secret.Secret{}, err
+	}
+
+	s.CreatedAt = s.CreatedAt.In(time.Local)
+	s.UpdatedAt = s.UpdatedAt.In(time.Local)
+	return s, nil
+}
@@ -0,0 +1,67 @@
+package task
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// cache represents a read-through cache of tasks in front of the store.
+type cache interface {
+	Get(ctx context.Context, taskId uuid.UUID) (Task, bool, error)
+	Set(ctx context.Context, task Task) error
+	Delete(ctx context.Context, taskIds ...uuid.UUID) error
+}
+
+// EnableCache makes GetTaskById read through c, a task is dropped from it whenever it changes through the service.
+// Every instance that changes tasks must enable it, changes made behind the service, like deleting the tasks of a
+// user, are visible once the entry expires.
+func (s *Service) EnableCache(c cache) {
+	s.cache = c
+}
+
+// cached returns the task from the cache, the cache is best effort so its errors are treated as a miss. Reads inside
+// of a transaction always go to the store.
+func (s *Service) cached(ctx context.Context, taskId uuid.UUID) (Task, bool) {
+	if s.cache == nil || s.inTran {
+		return Task{}, false
+	}
+
+	task, ok, err := s.cache.Get(ctx, taskId)
+	if err != nil {
+		return Task{}, false
+	}
+	return task, ok
+}
+
+func (s *Service) setCached(ctx context.Context, task Task) {
+	if s.cache == nil || s.inTran {
+		return
+	}
+	_ = s.cache.Set(ctx, task)
+}
+
+// invalidate drops the tasks from the cache, a failure leaves them stale until they expire. Inside of a transaction
+// the tasks are only dropped by Committed, a read before the commit would cache the old rows again.
+func (s *Service) invalidate(ctx context.Context, taskIds ...uuid.UUID) {
+	if s.cache == nil || len(taskIds) == 0 {
+		return
+	}
+
+	if s.inTran {
+		s.stale = append(s.stale, taskIds...)
+		return
+	}
+	_ = s.cache.Delete(ctx, taskIds...)
+}
+
+// Committed drops the tasks changed through the copy of WithTx from the cache, it must be called once the
+// transaction of the copy committed.
+func (s *Service) Committed(ctx context.Context) {
+	if s.cache == nil || len(s.stale) == 0 {
+		return
+	}
+
+	_ = s.cache.Delete(ctx, s.stale...)
+	s.stale = nil
+}
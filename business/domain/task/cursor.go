@@ -0,0 +1,57 @@
+package task
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidCursor is returned when a cursor token can not be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is the position of a task in the tasks of a user ordered by creation time, the id breaks ties between
+// tasks created at the same time.
+type Cursor struct {
+	CreatedAt time.Time
+	Id        uuid.UUID
+}
+
+// CursorOf returns the position of the task.
+func CursorOf(t Task) Cursor {
+	return Cursor{CreatedAt: t.CreatedAt, Id: t.Id}
+}
+
+// Encode returns the cursor as an opaque token.
+func (c Cursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "," + c.Id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCursor decodes a token returned by Encode.
+func ParseCursor(token string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	createdAt, id, ok := strings.Cut(string(raw), ",")
+	if !ok {
+		return Cursor{}, fmt.Errorf("%w: missing id", ErrInvalidCursor)
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	taskId, err := uuid.Parse(id)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	return Cursor{CreatedAt: t, Id: taskId}, nil
+}
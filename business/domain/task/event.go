@@ -0,0 +1,114 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/metrics"
+)
+
+// EventsExchange is the topic exchange every event of a task is published into, external systems bind their own
+// queues to it with patterns like "task.failed" or "task.*".
+const EventsExchange = "tasks"
+
+// EventKind represents what happened to a task on its timeline.
+type EventKind string
+
+const (
+	EventCreated   EventKind = "created"
+	EventQueued    EventKind = "queued"
+	EventStarted   EventKind = "started"
+	EventRetried   EventKind = "retried"
+	EventFailed    EventKind = "failed"
+	EventCompleted EventKind = "completed"
+	EventCanceled  EventKind = "canceled"
+	//EventLate is recorded once for a task that did not start long after it was due.
+	EventLate EventKind = "late"
+)
+
+// RoutingKey returns the routing key the events of the kind are published with, completed tasks are published as
+// "task.succeeded" and every other kind as "task.<kind>".
+func (k EventKind) RoutingKey() string {
+	if k == EventCompleted {
+		return "task.succeeded"
+	}
+	return "task." + string(k)
+}
+
+// EventSourceAPI is the source of the events recorded on behalf of a request.
+const EventSourceAPI = "api"
+
+// Event represents a single entry of the timeline of a task, Source is the api or the id of the scheduler instance
+// that recorded it.
+type Event struct {
+	Id        uuid.UUID
+	TaskId    uuid.UUID
+	Kind      EventKind
+	Message   string
+	Source    string
+	CreatedAt time.Time
+}
+
+// EventMessage represents the body of an event published into EventsExchange.
+type EventMessage struct {
+	Id        uuid.UUID `json:"id"`
+	TaskId    uuid.UUID `json:"taskId"`
+	Kind      EventKind `json:"kind"`
+	Message   string    `json:"message,omitempty"`
+	Source    string    `json:"source"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// NewEvent represents all of the required info for recording an event of a task.
+type NewEvent struct {
+	TaskId  uuid.UUID
+	Kind    EventKind
+	Message string
+	Source  string
+}
+
+// CreateEvent records an event on the timeline of a task and publishes it into EventsExchange, publishing is best
+// effort since the timeline already has the event.
+func (s *Service) CreateEvent(ctx context.Context, ne NewEvent) (Event, error) {
+	event := Event{
+		Id:        uuid.New(),
+		TaskId:    ne.TaskId,
+		Kind:      ne.Kind,
+		Message:   ne.Message,
+		Source:    ne.Source,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.store.CreateEvent(ctx, event); err != nil {
+		return Event{}, fmt.Errorf("create event: %w", err)
+	}
+
+	if err := s.publishEvent(ctx, event); err != nil {
+		metrics.AddUnpublishedEvent(string(event.Kind))
+	}
+	return event, nil
+}
+
+func (s *Service) publishEvent(ctx context.Context, event Event) error {
+	body, err := json.Marshal(EventMessage(event))
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	if err := s.broker.PublishTopicWithConfirm(ctx, EventsExchange, event.Kind.RoutingKey(), body); err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+	return nil
+}
+
+// GetEventsByTaskId returns the timeline of a task, oldest first.
+func (s *Service) GetEventsByTaskId(ctx context.Context, taskId uuid.UUID) ([]Event, error) {
+	events, err := s.store.GetEventsByTaskId(ctx, taskId)
+	if err != nil {
+		return nil, fmt.Errorf("get events by task id: %w", err)
+	}
+	return events, nil
+}
@@ -1,25 +1,86 @@
 package task
 
 import (
+	"fmt"
+	"slices"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
 
 // Task represents a task inside the systems.
 type Task struct {
-	Id          uuid.UUID
-	UserId      uuid.UUID
-	Image       string
-	Command     string
-	Args        []string
+	Id     uuid.UUID
+	UserId uuid.UUID
+	Image  string
+	//ImageDigest is the digest the image is pinned to on first dispatch, executions use it instead of the tag.
+	ImageDigest string
+	//FloatingTag opts out of digest pinning so every execution uses whatever the tag points to.
+	FloatingTag bool
+	//Preset is the name of the execution preset the task runs in, its image is copied to Image on creation while its
+	//environment, limits and network are applied on every execution.
+	Preset string
+	//NetworkMode is the network the task asked for, the default of the scheduler applies when it is empty.
+	NetworkMode NetworkMode
+	//PullPolicy is when the image is pulled before an execution, PullIfNotPresent applies when it is empty.
+	PullPolicy PullPolicy
+	//WorkDir is the working directory of the command, the one of the image when empty.
+	WorkDir string
+	//RunAsUser is the "uid[:gid]" or name the command runs as, it replaces the user of the security profile. The
+	//user of the profile applies when it is empty.
+	RunAsUser string
+	Command   string
+	Args      []string
+	//Steps are executed one after another inside of the same container instead of Command when they are set.
+	Steps []Step
+	//Input is piped to the command, or to every step, it reads nothing when it is empty.
+	Input       []byte
 	Environment string
 	Status      Status
 	Result      string
-	ErrMessage  string
+	//ResultRef is the key of the blob the result is offloaded to when it is too large to keep on the task, Result
+	//is empty until it is loaded with LoadResult. ResultSize is the size of the output before it was truncated.
+	ResultRef  string
+	ResultSize int
+	ErrMessage string
+	//ErrCategory is what made the last failed execution fail, empty until the task failed.
+	ErrCategory ErrorCategory
 	ScheduledAt time.Time
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	//EnqueuedAt is when the task was published to the tasks queue, zero until it is due.
+	EnqueuedAt time.Time
+	//StartedAt and FinishedAt are when the last execution started and finished, zero until the task ran.
+	StartedAt  time.Time
+	FinishedAt time.Time
+	//QueueLatency is how long the last execution waited after its scheduled time before it started.
+	QueueLatency time.Duration
+	//MaxRetries overrides the number of retries of the scheduler for this task when it is not nil.
+	MaxRetries *int
+	//RetryOn limits the retries to the executions that exited with one of the codes, any other failure fails the
+	//task right away. Every failure is retried when it is empty.
+	RetryOn []int
+	//DedupKey identifies the task among the tasks of its user, a task with the same key is returned instead of
+	//creating a duplicate while this one did not finish or shortly after it completed.
+	DedupKey string
+	//Version is incremented by every update, an update made against an older version fails with ErrVersionConflict.
+	Version int
+}
+
+// StatusSummary represents the status of a task without the rest of it, clients that track many tasks poll it.
+type StatusSummary struct {
+	Id        uuid.UUID
+	Status    Status
+	UpdatedAt time.Time
+}
+
+// DeleteFilter represents the finished tasks of a user that are deleted in bulk.
+type DeleteFilter struct {
+	//Statuses limits the deletion to the tasks with one of the statuses, every finished task matches when empty.
+	Statuses []Status
+	//Before limits the deletion to the tasks created before it, zero matches every task.
+	Before time.Time
 }
 
 // NewTask represents all of the required info for creating a new task.
@@ -28,13 +89,93 @@ type NewTask struct {
 	Command     string
 	Args        []string
 	Image       string
+	FloatingTag bool
+	Preset      string
+	NetworkMode NetworkMode
+	PullPolicy  PullPolicy
+	WorkDir     string
+	RunAsUser   string
 	Environment string
 	ScheduledAt time.Time
+	MaxRetries  *int
+	RetryOn     []int
+	Steps       []Step
+	Input       []byte
+	DedupKey    string
+}
+
+// Step represents one of the commands of a multi-step task, Output is what the command printed during the last
+// execution.
+type Step struct {
+	Command string
+	Args    []string
+	Output  string
 }
 
 // UpdateTask represents all of the data that can be update about a task.
 type UpdateTask struct {
-	Status     *Status
-	Result     *string
-	ErrMessage *string
+	Status      *Status
+	Result      *string
+	ResultSize  *int
+	ErrMessage  *string
+	ErrCategory *ErrorCategory
+	ImageDigest *string
+	Timings     *Timings
+	//Steps replaces the steps, nil leaves them as they are.
+	Steps []Step
+	//resultRef is set by the service when Result is offloaded.
+	resultRef *string
+}
+
+// BatchUpdate represents an update of UpdateTasks, Update is applied to Task like UpdateTask does.
+type BatchUpdate struct {
+	Task   Task
+	Update UpdateTask
+}
+
+// Timings represents when an execution of a task started and finished.
+type Timings struct {
+	StartedAt    time.Time
+	FinishedAt   time.Time
+	QueueLatency time.Duration
+}
+
+// Duration returns how long the last execution of the task ran, zero until the task ran.
+func (t Task) Duration() time.Duration {
+	if t.StartedAt.IsZero() || t.FinishedAt.IsZero() {
+		return 0
+	}
+	return t.FinishedAt.Sub(t.StartedAt)
+}
+
+// Retryable reports whether a failed execution that exited with code, or without one when ok is false, is retried.
+func (t Task) Retryable(code int, ok bool) bool {
+	if len(t.RetryOn) == 0 {
+		return true
+	}
+	return ok && slices.Contains(t.RetryOn, code)
+}
+
+// SetResult sets the output of the task as its result truncated to limit bytes and records the size of the output,
+// a limit less than or equal to 0 keeps all of it.
+func (t *Task) SetResult(output string, limit int) {
+	t.Result = Truncate(output, limit)
+	t.ResultSize = len(output)
+}
+
+// Truncate cuts the output to at most limit bytes with a marker at the end that tells it was truncated, a limit less
+// than or equal to 0 keeps all of it.
+func Truncate(output string, limit int) string {
+	if limit <= 0 || len(output) <= limit {
+		return output
+	}
+
+	marker := fmt.Sprintf("\n[truncated: output was %d bytes]", len(output))
+	keep := max(limit-len(marker), 0)
+
+	//do not cut a character in half, postgres rejects invalid utf8
+	for keep > 0 && !utf8.RuneStart(output[keep]) {
+		keep--
+	}
+	return output[:keep] + marker
 }
@@ -0,0 +1,76 @@
+package task
+
+import (
+	"fmt"
+	"slices"
+)
+
+// NetworkMode represents how the container of a task reaches the network.
+type NetworkMode string
+
+const (
+	//NetworkNone runs the container without a network, only loopback is available.
+	NetworkNone NetworkMode = "none"
+	//NetworkInternal attaches the container to a network that reaches other containers but not the outside.
+	NetworkInternal NetworkMode = "internal"
+	//NetworkEgress attaches the container to a network with a route to the outside.
+	NetworkEgress NetworkMode = "egress"
+)
+
+// NetworkModes are all of the network modes.
+var NetworkModes = []NetworkMode{NetworkNone, NetworkInternal, NetworkEgress}
+
+// ParseNetworkMode parses the network mode from its name.
+func ParseNetworkMode(name string) (NetworkMode, error) {
+	mode := NetworkMode(name)
+	if !slices.Contains(NetworkModes, mode) {
+		return "", fmt.Errorf("unknown network mode %q", name)
+	}
+	return mode, nil
+}
+
+// NetworkPolicy represents the network modes tasks may run with, the zero value runs every task without a network.
+type NetworkPolicy struct {
+	//Default is the mode of the tasks that do not ask for one, defaults to NetworkNone.
+	Default NetworkMode
+	//Allowed are the modes tasks may ask for, the default mode is always allowed.
+	Allowed []NetworkMode
+}
+
+// Mode returns the mode a task that asked for mode runs with, the default one when it did not ask for any.
+func (p NetworkPolicy) Mode(mode NetworkMode) NetworkMode {
+	if mode != "" {
+		return mode
+	}
+
+	if p.Default == "" {
+		return NetworkNone
+	}
+	return p.Default
+}
+
+// Allows reports whether tasks may run with the mode.
+func (p NetworkPolicy) Allows(mode NetworkMode) bool {
+	return mode == p.Mode("") || slices.Contains(p.Allowed, mode)
+}
+
+// ParseNetworkPolicy parses the policy from the names of its default and allowed modes.
+func ParseNetworkPolicy(def string, allowed []string) (NetworkPolicy, error) {
+	var policy NetworkPolicy
+	if def != "" {
+		mode, err := ParseNetworkMode(def)
+		if err != nil {
+			return NetworkPolicy{}, fmt.Errorf("default: %w", err)
+		}
+		policy.Default = mode
+	}
+
+	for _, name := range allowed {
+		mode, err := ParseNetworkMode(name)
+		if err != nil {
+			return NetworkPolicy{}, fmt.Errorf("allowed: %w", err)
+		}
+		policy.Allowed = append(policy.Allowed, mode)
+	}
+	return policy, nil
+}
@@ -51,7 +51,7 @@ var fieldNames = [...]string{"command", "status", "createdAt", "scheduledAt", "I
 
 // String implements stringer interface.
 func (f Field) String() string {
-	if f < FieldCommand || f > FieldScheduledAt {
+	if f < FieldCommand || f > FieldID {
 		return "UNKNOWN"
 	}
 	return fieldNames[f]
@@ -62,7 +62,7 @@ func ParseField(field string) (Field, error) {
 	field = strings.TrimSpace(field)
 
 	for i, f := range fieldNames {
-		if strings.ToLower(field) == f {
+		if strings.EqualFold(field, f) {
 			return Field(i), nil
 		}
 	}
@@ -74,3 +74,28 @@ type OrderBy struct {
 	Field     Field
 	Direction Direction
 }
+
+// String implements the stringer interface, the result is parsed back by ParseOrderBy.
+func (o OrderBy) String() string {
+	return o.Field.String() + "," + o.Direction.String()
+}
+
+// ParseOrderBy creates an order from "field" or "field,direction", the direction defaults to ASC.
+func ParseOrderBy(order string) (OrderBy, error) {
+	fieldString, dirString, hasDir := strings.Cut(order, ",")
+
+	field, err := ParseField(fieldString)
+	if err != nil {
+		return OrderBy{}, fmt.Errorf("unknown field: %q", fieldString)
+	}
+
+	if !hasDir {
+		return OrderBy{Field: field, Direction: DirectionASC}, nil
+	}
+
+	dir, err := ParseDirection(dirString)
+	if err != nil {
+		return OrderBy{}, fmt.Errorf("unknown direction: %q", dirString)
+	}
+	return OrderBy{Field: field, Direction: dir}, nil
+}
@@ -0,0 +1,37 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const queue = "queue_tasks"
+
+// outboxBatch is the max number of outbox messages relayed at once.
+const outboxBatch = 100
+
+// OutboxMessage is a message stored in the same transaction as the task it belongs to, it is published to the
+// broker by the relay afterwards so a crash between the two does not lose it.
+type OutboxMessage struct {
+	Id        uuid.UUID
+	TaskId    uuid.UUID
+	Queue     string
+	Payload   []byte
+	CreatedAt time.Time
+}
+
+// RelayOutbox publishes the pending outbox messages in the order they were created and returns the number of
+// published messages. A message is removed only after the broker confirmed it, so a crash in between publishes it
+// again.
+func (s *Service) RelayOutbox(ctx context.Context) (int, error) {
+	published, err := s.store.DrainOutbox(ctx, outboxBatch, func(msg OutboxMessage) error {
+		return s.broker.PublishWithConfirm(ctx, msg.Queue, msg.Payload)
+	})
+	if err != nil {
+		return published, fmt.Errorf("drain outbox: %w", err)
+	}
+	return published, nil
+}
@@ -0,0 +1,29 @@
+package task
+
+// PullPolicy represents when the image of a task is pulled before it runs.
+type PullPolicy string
+
+const (
+	//PullIfNotPresent pulls the image only when it is not available locally, it applies when a task has no policy.
+	PullIfNotPresent PullPolicy = "if-not-present"
+	//PullAlways pulls the image before every execution so a moved tag is picked up.
+	PullAlways PullPolicy = "always"
+)
+
+// ErrorCategory represents what made the last execution of a task fail.
+type ErrorCategory string
+
+const (
+	//ErrorSetup is a task that could not be prepared, like a missing secret or preset.
+	ErrorSetup ErrorCategory = "setup"
+	//ErrorImagePull is an image that could not be pulled.
+	ErrorImagePull ErrorCategory = "image_pull"
+	//ErrorExecution is a command that failed.
+	ErrorExecution ErrorCategory = "execution"
+	//ErrorTimeout is a command that did not finish in time, its container was killed.
+	ErrorTimeout ErrorCategory = "timeout"
+	//ErrorCanceled is a command that was stopped by the scheduler, like while it shut down.
+	ErrorCanceled ErrorCategory = "canceled"
+	//ErrorInternal is a task the scheduler panicked on while it handled it.
+	ErrorInternal ErrorCategory = "internal"
+)
@@ -0,0 +1,79 @@
+package task
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// blobStore represents the object storage large results are offloaded to.
+type blobStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+}
+
+type resultOffload struct {
+	blobs     blobStore
+	threshold int
+}
+
+// EnableResultOffload moves the results larger than threshold bytes into blobs and keeps only their key on the task,
+// without it every result is stored inline.
+func (s *Service) EnableResultOffload(blobs blobStore, threshold int) {
+	s.results = resultOffload{
+		blobs:     blobs,
+		threshold: threshold,
+	}
+}
+
+// LoadResult fetches the result of the task from the blob storage when it was offloaded.
+func (s *Service) LoadResult(ctx context.Context, task Task) (Task, error) {
+	if task.ResultRef == "" || s.results.blobs == nil {
+		return task, nil
+	}
+
+	data, err := s.results.blobs.Get(ctx, task.ResultRef)
+	if err != nil {
+		return Task{}, fmt.Errorf("get result %s: %w", task.ResultRef, err)
+	}
+
+	task.Result = string(data)
+	return task, nil
+}
+
+// offloadResult puts the result of the update into the blob storage when it is larger than the threshold, the key
+// only depends on the task so a new result replaces the previous one.
+func (s *Service) offloadResult(ctx context.Context, taskId uuid.UUID, ut *UpdateTask) error {
+	if s.results.blobs == nil || ut.Result == nil {
+		return nil
+	}
+
+	ref := ""
+	if len(*ut.Result) > s.results.threshold {
+		ref = resultKey(taskId)
+		if err := s.results.blobs.Put(ctx, ref, []byte(*ut.Result)); err != nil {
+			return fmt.Errorf("put result: %w", err)
+		}
+
+		empty := ""
+		ut.Result = &empty
+	}
+
+	ut.resultRef = &ref
+	return nil
+}
+
+// deleteResult deletes the offloaded result of a deleted task, a result whose deletion failed is only left behind
+// inside of the blob storage.
+func (s *Service) deleteResult(ctx context.Context, task Task) {
+	if task.ResultRef == "" || s.results.blobs == nil {
+		return
+	}
+	_ = s.results.blobs.Delete(ctx, task.ResultRef)
+}
+
+func resultKey(taskId uuid.UUID) string {
+	return "results/" + taskId.String()
+}
@@ -0,0 +1,97 @@
+package task
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrRunNotFound = errors.New("run not found")
+)
+
+// Environment represents the fingerprint of the executor that ran a task, so failures that only happen on some
+// attempts can be correlated with the environment they ran in.
+type Environment struct {
+	WorkerId      string
+	WorkerBuild   string
+	Runtime       string
+	DockerVersion string
+	OS            string
+	Arch          string
+	ImageDigest   string
+}
+
+// Run represents a single execution attempt of a task, Security is the hardening its container ran with.
+type Run struct {
+	Id          uuid.UUID
+	TaskId      uuid.UUID
+	Status      Status
+	ErrMessage  string
+	Environment Environment
+	Security    SecurityProfile
+	CreatedAt   time.Time
+}
+
+// NewRun represents all of the required info for recording an execution attempt.
+type NewRun struct {
+	//Id is optional, the scheduler passes the id it claimed the execution with.
+	Id          uuid.UUID
+	TaskId      uuid.UUID
+	Status      Status
+	ErrMessage  string
+	Environment Environment
+	Security    SecurityProfile
+}
+
+// CreateRun records an execution attempt of a task.
+func (s *Service) CreateRun(ctx context.Context, nr NewRun) (Run, error) {
+	run := Run{
+		Id:          uuid.New(),
+		TaskId:      nr.TaskId,
+		Status:      nr.Status,
+		ErrMessage:  nr.ErrMessage,
+		Environment: nr.Environment,
+		Security:    nr.Security,
+		CreatedAt:   time.Now(),
+	}
+
+	if nr.Id != uuid.Nil {
+		run.Id = nr.Id
+	}
+
+	if err := s.store.CreateRun(ctx, run); err != nil {
+		return Run{}, fmt.Errorf("create run: %w", err)
+	}
+	return run, nil
+}
+
+// GetRunsByTaskId returns all of the execution attempts of a task, oldest first.
+func (s *Service) GetRunsByTaskId(ctx context.Context, taskId uuid.UUID) ([]Run, error) {
+	runs, err := s.store.GetRunsByTaskId(ctx, taskId)
+	if err != nil {
+		return nil, fmt.Errorf("get runs by task id: %w", err)
+	}
+	return runs, nil
+}
+
+// GetRunById returns the execution attempt of the task, in case the run does not exist or belongs to another task
+// will return ErrRunNotFound.
+func (s *Service) GetRunById(ctx context.Context, taskId uuid.UUID, runId uuid.UUID) (Run, error) {
+	run, err := s.store.GetRunById(ctx, runId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Run{}, ErrRunNotFound
+		}
+		return Run{}, fmt.Errorf("get run by id: %w", err)
+	}
+
+	if run.TaskId != taskId {
+		return Run{}, ErrRunNotFound
+	}
+	return run, nil
+}
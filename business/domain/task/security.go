@@ -0,0 +1,39 @@
+package task
+
+import (
+	"errors"
+	"path"
+	"strings"
+)
+
+// SecurityProfile represents the hardening the container of a task runs with, the zero value applies none.
+type SecurityProfile struct {
+	//ReadOnly mounts the root filesystem of the container as read-only.
+	ReadOnly bool
+	//CapDropAll drops every linux capability of the container.
+	CapDropAll bool
+	//NoNewPrivileges stops the processes of the container from gaining privileges like through setuid binaries.
+	NoNewPrivileges bool
+	//User is the "uid[:gid]" or name the command runs as, the user of the image when empty.
+	User string
+	//Tmpfs is the path a writable tmpfs scratch directory is mounted at, none when empty.
+	Tmpfs string
+	//TmpfsSizeMB bounds the scratch directory, zero leaves it to docker.
+	TmpfsSizeMB int
+}
+
+// Validate reports whether the profile can be applied to a container.
+func (p SecurityProfile) Validate() error {
+	if strings.ContainsAny(p.User, " \t\n") {
+		return errors.New("user must not contain whitespace")
+	}
+
+	if p.Tmpfs != "" && (!path.IsAbs(p.Tmpfs) || strings.ContainsAny(p.Tmpfs, ": \t\n")) {
+		return errors.New("tmpfs must be an absolute path")
+	}
+
+	if p.TmpfsSizeMB < 0 {
+		return errors.New("tmpfs size must be greater than or equal to 0")
+	}
+	return nil
+}
@@ -12,17 +12,26 @@ const (
 	StatusPending Status = iota
 	StatusFailed
 	StatusCompleted
+	//StatusQueued is a due task that was published to the tasks queue and did not finish yet.
+	StatusQueued
+	//StatusCanceled is a pending or queued task that was canceled before it ran.
+	StatusCanceled
 )
 
-var statusNames = []string{"pending", "failed", "completed"}
+var statusNames = []string{"pending", "failed", "completed", "queued", "canceled"}
 
 func (s Status) String() string {
-	if s < StatusPending || s > StatusCompleted {
+	if s < StatusPending || s > StatusCanceled {
 		return "UNKNOWN"
 	}
 	return statusNames[s]
 }
 
+// Finished reports whether the task reached a status it does not leave on its own.
+func (s Status) Finished() bool {
+	return s == StatusFailed || s == StatusCompleted || s == StatusCanceled
+}
+
 // ParseStatus creates a status off of single string or return error if status is invalid.
 func ParseStatus(s string) (Status, error) {
 	for i, status := range statusNames {
@@ -2,8 +2,11 @@
 package memory
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,8 +16,11 @@ import (
 
 // Repository represent an in-memory storage for testing.
 type Repository struct {
-	Tasks map[uuid.UUID]task.Task
-	mu    sync.Mutex
+	Tasks  map[uuid.UUID]task.Task
+	runs   []task.Run
+	events []task.Event
+	outbox []task.OutboxMessage
+	mu     sync.Mutex
 }
 
 // Create is going to add a new task into repo or return error.
@@ -25,22 +31,146 @@ func (r *Repository) Create(ctx context.Context, task task.Task) error {
 	return nil
 }
 
-// Update is going to update a task inside repo or return error.
-func (r *Repository) Update(ctx context.Context, task task.Task) error {
+// CreateWithOutbox adds a new task and its outbox message into repo.
+func (r *Repository) CreateWithOutbox(ctx context.Context, task task.Task, msg task.OutboxMessage) error {
 	r.mu.Lock()
+	defer r.mu.Unlock()
 	r.Tasks[task.Id] = task
-	r.mu.Unlock()
+	r.outbox = append(r.outbox, msg)
+	return nil
+}
+
+// CreateMany adds the tasks into repo.
+func (r *Repository) CreateMany(ctx context.Context, tsks []task.Task) error {
+	return r.CreateManyWithOutbox(ctx, tsks, nil)
+}
+
+// CreateManyWithOutbox adds the tasks and the outbox messages of the due ones into repo.
+func (r *Repository) CreateManyWithOutbox(ctx context.Context, tsks []task.Task, msgs []task.OutboxMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, tsk := range tsks {
+		r.Tasks[tsk.Id] = tsk
+	}
+	r.outbox = append(r.outbox, msgs...)
+	return nil
+}
+
+// DrainOutbox hands up to limit of the oldest outbox messages to publish and removes the published ones, it stops
+// at the first failed publish.
+func (r *Repository) DrainOutbox(ctx context.Context, limit int, publish func(task.OutboxMessage) error) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var published int
+	for published < len(r.outbox) && published < limit {
+		if err := publish(r.outbox[published]); err != nil {
+			r.outbox = r.outbox[published:]
+			return published, err
+		}
+		published++
+	}
+	r.outbox = r.outbox[published:]
+	return published, nil
+}
+
+// Update is going to update a task inside repo and increment its version or return "task.ErrVersionConflict".
+func (r *Repository) Update(ctx context.Context, tsk task.Task) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if stored, ok := r.Tasks[tsk.Id]; ok && stored.Version != tsk.Version {
+		return task.ErrVersionConflict
+	}
+
+	tsk.Version++
+	r.Tasks[tsk.Id] = tsk
 	return nil
 }
 
+// UpdateMany updates the tasks like Update and returns the ids of the ones whose version did not match.
+func (r *Repository) UpdateMany(ctx context.Context, tsks []task.Task) ([]uuid.UUID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var conflicts []uuid.UUID
+	for _, tsk := range tsks {
+		if stored, ok := r.Tasks[tsk.Id]; ok && stored.Version != tsk.Version {
+			conflicts = append(conflicts, tsk.Id)
+			continue
+		}
+
+		tsk.Version++
+		r.Tasks[tsk.Id] = tsk
+	}
+	return conflicts, nil
+}
+
 // Delete is going to delete a task in repo or return error.
 func (r *Repository) Delete(ctx context.Context, task task.Task) error {
 	r.mu.Lock()
 	delete(r.Tasks, task.Id)
+	kept := r.outbox[:0]
+	for _, msg := range r.outbox {
+		if msg.TaskId != task.Id {
+			kept = append(kept, msg)
+		}
+	}
+	r.outbox = kept
 	r.mu.Unlock()
 	return nil
 }
 
+// DeleteByUserId is going to delete all of the tasks of the user.
+func (r *Repository) DeleteByUserId(ctx context.Context, userId uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, tsk := range r.Tasks {
+		if tsk.UserId == userId {
+			delete(r.Tasks, id)
+		}
+	}
+	return nil
+}
+
+// DeleteByUserIdFilter deletes the tasks of the user that match the filter and returns them.
+func (r *Repository) DeleteByUserIdFilter(ctx context.Context, userId uuid.UUID, filter task.DeleteFilter) ([]task.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted []task.Task
+	for id, tsk := range r.Tasks {
+		if tsk.UserId != userId || !slices.Contains(filter.Statuses, tsk.Status) {
+			continue
+		}
+		if !filter.Before.IsZero() && !tsk.CreatedAt.Before(filter.Before) {
+			continue
+		}
+		delete(r.Tasks, id)
+		deleted = append(deleted, tsk)
+	}
+	return deleted, nil
+}
+
+// CancelByUserId marks the pending and queued tasks of the user as canceled and returns their ids.
+func (r *Repository) CancelByUserId(ctx context.Context, userId uuid.UUID, canceledAt time.Time) ([]uuid.UUID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ids []uuid.UUID
+	for id, tsk := range r.Tasks {
+		if tsk.UserId != userId || (tsk.Status != task.StatusPending && tsk.Status != task.StatusQueued) {
+			continue
+		}
+		tsk.Status = task.StatusCanceled
+		tsk.UpdatedAt = canceledAt
+		tsk.Version++
+		r.Tasks[id] = tsk
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
 // GetById is going to get a task by id or return error "sql.ErrNoRows".
 func (r *Repository) GetById(ctx context.Context, taskId uuid.UUID) (task.Task, error) {
 	r.mu.Lock()
@@ -66,6 +196,98 @@ func (r *Repository) GetByUserId(ctx context.Context, userId uuid.UUID, rows int
 	return results, nil
 }
 
+// GetByUserIdAfter returns up to rows tasks of the user ordered by creation time and id that come after the cursor.
+func (r *Repository) GetByUserIdAfter(ctx context.Context, userId uuid.UUID, rows int, after *task.Cursor, dir task.Direction) ([]task.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	//ascending compare of two positions
+	compare := func(a, b task.Cursor) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.Id[:], b.Id[:])
+	}
+
+	if dir == task.DirectionDESC {
+		asc := compare
+		compare = func(a, b task.Cursor) int { return -asc(a, b) }
+	}
+
+	var results []task.Task
+	for _, tsk := range r.Tasks {
+		if tsk.UserId != userId {
+			continue
+		}
+		if after != nil && compare(task.CursorOf(tsk), *after) <= 0 {
+			continue
+		}
+		results = append(results, tsk)
+	}
+
+	slices.SortFunc(results, func(a, b task.Task) int { return compare(task.CursorOf(a), task.CursorOf(b)) })
+	if len(results) > rows {
+		results = results[:rows]
+	}
+	return results, nil
+}
+
+// Search returns the tasks of the user whose command, args, result or error message contain every word of the query.
+func (r *Repository) Search(ctx context.Context, userId uuid.UUID, query string, rows int, page int) ([]task.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	words := strings.Fields(strings.ToLower(query))
+
+	var results []task.Task
+	for _, tsk := range r.Tasks {
+		if tsk.UserId != userId {
+			continue
+		}
+
+		text := strings.ToLower(strings.Join([]string{tsk.Command, strings.Join(tsk.Args, " "), tsk.Result, tsk.ErrMessage}, " "))
+		matches := true
+		for _, word := range words {
+			if !strings.Contains(text, word) {
+				matches = false
+				break
+			}
+		}
+
+		if matches {
+			results = append(results, tsk)
+		}
+	}
+
+	slices.SortFunc(results, func(a, b task.Task) int { return b.CreatedAt.Compare(a.CreatedAt) })
+
+	offset := (page - 1) * rows
+	if offset >= len(results) {
+		return nil, nil
+	}
+	return results[offset:min(offset+rows, len(results))], nil
+}
+
+// GetScheduledBetween returns the pending tasks of the user scheduled in [from, to) ordered by their scheduled time.
+func (r *Repository) GetScheduledBetween(ctx context.Context, userId uuid.UUID, from time.Time, to time.Time) ([]task.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var results []task.Task
+	for _, tsk := range r.Tasks {
+		if tsk.UserId != userId || tsk.Status != task.StatusPending {
+			continue
+		}
+		if tsk.ScheduledAt.Before(from) || !tsk.ScheduledAt.Before(to) {
+			continue
+		}
+		results = append(results, tsk)
+	}
+
+	slices.SortFunc(results, func(a, b task.Task) int { return a.ScheduledAt.Compare(b.ScheduledAt) })
+	return results, nil
+}
+
 // GetDueTasks returns all tasks that has 1 min to execute.
 func (r *Repository) GetDueTasks(ctx context.Context, from time.Time) ([]task.Task, error) {
 	r.mu.Lock()
@@ -79,3 +301,231 @@ func (r *Repository) GetDueTasks(ctx context.Context, from time.Time) ([]task.Ta
 	}
 	return results, nil
 }
+
+// ClaimDueTasks marks the pending tasks scheduled at or before from as queued at enqueuedAt and returns them.
+func (r *Repository) ClaimDueTasks(ctx context.Context, from time.Time, enqueuedAt time.Time) ([]task.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var results []task.Task
+	for id, tsk := range r.Tasks {
+		if tsk.Status != task.StatusPending || tsk.ScheduledAt.After(from) {
+			continue
+		}
+		tsk.Status = task.StatusQueued
+		tsk.EnqueuedAt = enqueuedAt
+		tsk.Version++
+		r.Tasks[id] = tsk
+		results = append(results, tsk)
+	}
+	return results, nil
+}
+
+// RequeueStuck puts the tasks that are queued since before the given time back to pending.
+func (r *Repository) RequeueStuck(ctx context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var requeued int
+	for id, tsk := range r.Tasks {
+		if tsk.Status != task.StatusQueued || !tsk.EnqueuedAt.Before(before) {
+			continue
+		}
+		tsk.Status = task.StatusPending
+		tsk.EnqueuedAt = time.Time{}
+		tsk.Version++
+		r.Tasks[id] = tsk
+		requeued++
+	}
+	return requeued, nil
+}
+
+// GetLate returns up to limit pending or queued tasks that were scheduled before the given time and never started,
+// the oldest first. Tasks with a late event are left out.
+func (r *Repository) GetLate(ctx context.Context, scheduledBefore time.Time, limit int) ([]task.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	skipped := make(map[uuid.UUID]bool)
+	for _, e := range r.events {
+		if e.Kind == task.EventStarted || e.Kind == task.EventLate {
+			skipped[e.TaskId] = true
+		}
+	}
+
+	var results []task.Task
+	for _, tsk := range r.Tasks {
+		if tsk.Status != task.StatusPending && tsk.Status != task.StatusQueued {
+			continue
+		}
+		if !tsk.ScheduledAt.Before(scheduledBefore) || skipped[tsk.Id] {
+			continue
+		}
+		results = append(results, tsk)
+	}
+
+	slices.SortFunc(results, func(a, b task.Task) int {
+		return a.ScheduledAt.Compare(b.ScheduledAt)
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// CountByStatus returns the number of tasks of the user with the status.
+func (r *Repository) CountByStatus(ctx context.Context, userId uuid.UUID, status task.Status) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int
+	for _, t := range r.Tasks {
+		if t.UserId == userId && t.Status == status {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetRecentByStatus returns the last rows updated tasks with the status, the latest first.
+func (r *Repository) GetRecentByStatus(ctx context.Context, status task.Status, rows int) ([]task.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var results []task.Task
+	for _, tsk := range r.Tasks {
+		if tsk.Status == status {
+			results = append(results, tsk)
+		}
+	}
+
+	slices.SortFunc(results, func(a, b task.Task) int { return b.UpdatedAt.Compare(a.UpdatedAt) })
+	if len(results) > rows {
+		results = results[:rows]
+	}
+	return results, nil
+}
+
+// GetByIds returns the statuses of the tasks of the user among taskIds.
+func (r *Repository) GetByIds(ctx context.Context, userId uuid.UUID, taskIds []uuid.UUID) ([]task.StatusSummary, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var statuses []task.StatusSummary
+	for _, id := range taskIds {
+		if tsk, ok := r.Tasks[id]; ok && tsk.UserId == userId {
+			statuses = append(statuses, task.StatusSummary{Id: tsk.Id, Status: tsk.Status, UpdatedAt: tsk.UpdatedAt})
+		}
+	}
+	return statuses, nil
+}
+
+// GetByDedupKey returns the latest task of the user with the key that did not finish yet or completed at or after
+// completedSince, returns sql.ErrNoRows when there is none.
+func (r *Repository) GetByDedupKey(ctx context.Context, userId uuid.UUID, key string, completedSince time.Time) (task.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var (
+		latest task.Task
+		found  bool
+	)
+	for _, tsk := range r.Tasks {
+		if tsk.UserId != userId || tsk.DedupKey != key {
+			continue
+		}
+
+		finishedAt := tsk.FinishedAt
+		if finishedAt.IsZero() {
+			finishedAt = tsk.UpdatedAt
+		}
+
+		if !tsk.Status.Finished() || (tsk.Status == task.StatusCompleted && !finishedAt.Before(completedSince)) {
+			if !found || tsk.CreatedAt.After(latest.CreatedAt) {
+				latest, found = tsk, true
+			}
+		}
+	}
+
+	if !found {
+		return task.Task{}, sql.ErrNoRows
+	}
+	return latest, nil
+}
+
+// LockDedupKey does nothing, the repository has no transactions to serialize.
+func (r *Repository) LockDedupKey(ctx context.Context, userId uuid.UUID, key string) error {
+	return nil
+}
+
+// CreateRun is going to add a new run into repo or return error.
+func (r *Repository) CreateRun(ctx context.Context, run task.Run) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs = append(r.runs, run)
+	return nil
+}
+
+// GetRunsByTaskId returns all of the runs of the task in the order they are created.
+func (r *Repository) GetRunsByTaskId(ctx context.Context, taskId uuid.UUID) ([]task.Run, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var runs []task.Run
+	for _, run := range r.runs {
+		if run.TaskId == taskId {
+			runs = append(runs, run)
+		}
+	}
+	return runs, nil
+}
+
+// GetRunById returns the run or sql.ErrNoRows when it does not exist.
+func (r *Repository) GetRunById(ctx context.Context, runId uuid.UUID) (task.Run, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, run := range r.runs {
+		if run.Id == runId {
+			return run, nil
+		}
+	}
+	return task.Run{}, sql.ErrNoRows
+}
+
+// CreateEvent is going to add a new event into repo or return error.
+func (r *Repository) CreateEvent(ctx context.Context, event task.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+// GetEventsByTaskId returns all of the events of the task in the order they are created.
+func (r *Repository) GetEventsByTaskId(ctx context.Context, taskId uuid.UUID) ([]task.Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var events []task.Event
+	for _, event := range r.events {
+		if event.TaskId == taskId {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// CountFailedRuns returns the number of failed runs of every task with the given status.
+func (r *Repository) CountFailedRuns(ctx context.Context, status task.Status) (map[uuid.UUID]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counts := make(map[uuid.UUID]int)
+	for _, run := range r.runs {
+		if run.Status != task.StatusFailed || r.Tasks[run.TaskId].Status != status {
+			continue
+		}
+		counts[run.TaskId]++
+	}
+	return counts, nil
+}
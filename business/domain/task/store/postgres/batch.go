@@ -0,0 +1,163 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/database/postgres"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateMany inserts the tasks with a single copy, either all of them are created or none.
+func (s *Repository) CreateMany(ctx context.Context, tsks []task.Task) error {
+	return s.CreateManyWithOutbox(ctx, tsks, nil)
+}
+
+// CreateManyWithOutbox inserts the tasks and the outbox messages of the due ones inside of the same transaction, each
+// of them with a single copy.
+func (s *Repository) CreateManyWithOutbox(ctx context.Context, tsks []task.Task, msgs []task.OutboxMessage) error {
+	if len(tsks) == 0 {
+		return nil
+	}
+
+	rows := make([][]any, len(tsks))
+	for i, tsk := range tsks {
+		dbTask, err := toDBTask(tsk)
+		if err != nil {
+			return fmt.Errorf("toDBTask: %w", err)
+		}
+		rows[i] = dbTask.row()
+	}
+
+	outbox := make([][]any, len(msgs))
+	for i, msg := range msgs {
+		outbox[i] = []any{msg.Id, msg.TaskId, msg.Queue, msg.Payload, msg.CreatedAt}
+	}
+
+	return postgres.InTran(ctx, s.db, func(tx pgx.Tx) error {
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{"tasks"}, taskColumns, pgx.CopyFromRows(rows)); err != nil {
+			return fmt.Errorf("copy tasks: %w", err)
+		}
+
+		if len(outbox) == 0 {
+			return nil
+		}
+
+		columns := []string{"id", "task_id", "queue", "payload", "created_at"}
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{"task_outbox"}, columns, pgx.CopyFromRows(outbox)); err != nil {
+			return fmt.Errorf("copy outbox messages: %w", err)
+		}
+		return nil
+	})
+}
+
+// UpdateMany writes the tasks with a single multi-row UPDATE like Update writes one of them, the ids of the tasks
+// that were updated or deleted in between are returned as conflicts.
+func (s *Repository) UpdateMany(ctx context.Context, tsks []task.Task) ([]uuid.UUID, error) {
+	if len(tsks) == 0 {
+		return nil, nil
+	}
+
+	const q = `
+	UPDATE
+		tasks AS t
+	SET
+		status =       u.status,
+		result =       u.result,
+		error_msg =    u.error_msg,
+		image_digest = u.image_digest,
+		started_at =   u.started_at,
+		finished_at =  u.finished_at,
+		queue_latency_ms = u.queue_latency_ms,
+		steps =        u.steps::jsonb,
+		result_ref =   u.result_ref,
+		result_size =  u.result_size,
+		error_category = u.error_category,
+		version =      t.version + 1
+	FROM
+		unnest($1::uuid[], $2::int[], $3::text[], $4::text[], $5::text[], $6::text[], $7::timestamp[], $8::timestamp[],
+			$9::bigint[], $10::text[], $11::text[], $12::bigint[], $13::text[], $14::timestamp[])
+		AS u(id, version, status, result, error_msg, image_digest, started_at, finished_at, queue_latency_ms, steps,
+			result_ref, result_size, error_category, created_at)
+	WHERE
+		t.id = u.id AND t.created_at = u.created_at AND t.version = u.version
+	RETURNING
+		t.id
+	`
+
+	var (
+		ids            = make([]uuid.UUID, len(tsks))
+		versions       = make([]int, len(tsks))
+		statuses       = make([]string, len(tsks))
+		results        = make([]*string, len(tsks))
+		errMessages    = make([]*string, len(tsks))
+		imageDigests   = make([]*string, len(tsks))
+		startedAt      = make([]*time.Time, len(tsks))
+		finishedAt     = make([]*time.Time, len(tsks))
+		queueLatencies = make([]*int64, len(tsks))
+		steps          = make([]*string, len(tsks))
+		resultRefs     = make([]*string, len(tsks))
+		resultSizes    = make([]*int, len(tsks))
+		errCategories  = make([]*string, len(tsks))
+		createdAt      = make([]time.Time, len(tsks))
+	)
+
+	for i, tsk := range tsks {
+		dbTask, err := toDBTask(tsk)
+		if err != nil {
+			return nil, fmt.Errorf("toDBTask: %w", err)
+		}
+
+		ids[i] = dbTask.Id
+		versions[i] = dbTask.Version
+		statuses[i] = dbTask.Status
+		results[i] = dbTask.Result
+		errMessages[i] = dbTask.ErrorMessage
+		imageDigests[i] = dbTask.ImageDigest
+		startedAt[i] = dbTask.StartedAt
+		finishedAt[i] = dbTask.FinishedAt
+		queueLatencies[i] = dbTask.QueueLatency
+		resultRefs[i] = dbTask.ResultRef
+		resultSizes[i] = dbTask.ResultSize
+		errCategories[i] = dbTask.ErrorCategory
+		createdAt[i] = dbTask.CreatedAt
+
+		//json travels as text, an array of bytea can not be cast to jsonb
+		if dbTask.Steps != nil {
+			s := string(dbTask.Steps)
+			steps[i] = &s
+		}
+	}
+
+	rows, err := s.db.Query(ctx, q, ids, versions, statuses, results, errMessages, imageDigests, startedAt, finishedAt,
+		queueLatencies, steps, resultRefs, resultSizes, errCategories, createdAt)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	updated := make(map[uuid.UUID]bool, len(tsks))
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		updated[id] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+
+	//either updated in between or deleted, the caller finds out which by reading them again
+	var conflicts []uuid.UUID
+	for _, id := range ids {
+		if !updated[id] {
+			conflicts = append(conflicts, id)
+		}
+	}
+	return conflicts, nil
+}
@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	"github.com/jackc/pgx/v5"
+)
+
+// GetByUserIdAfter returns up to rows tasks of the user ordered by (created_at, id) that come after the cursor, it
+// seeks through the (user_id, created_at, id) index so the cost does not grow with the position like an offset.
+func (r *Repository) GetByUserIdAfter(ctx context.Context, userId uuid.UUID, rows int, after *task.Cursor, dir task.Direction) ([]task.Task, error) {
+	cmp := ">"
+	if dir == task.DirectionDESC {
+		cmp = "<"
+	}
+
+	//the comparison and direction come from a closed set so there is no risk of sql injection in here.
+	where := "user_id = $1"
+	args := []any{userId, rows}
+	if after != nil {
+		where += fmt.Sprintf(" AND (created_at, id) %s ($3, $4)", cmp)
+		args = append(args, after.CreatedAt.UTC(), after.Id)
+	}
+
+	q := fmt.Sprintf(`
+	SELECT
+		id,user_id,command,args,image,image_digest,floating_tag,environment,status,result,error_msg,scheduled_at,created_at,updated_at,version,started_at,finished_at,queue_latency_ms,max_retries,retry_on,enqueued_at,steps,result_ref,result_size,preset,network_mode,pull_policy,error_category,input,work_dir,run_as_user,dedup_key
+	FROM tasks
+	WHERE %s
+	ORDER BY created_at %s, id %s
+	LIMIT $2
+	`, where, dir.String(), dir.String())
+
+	result, err := r.db.Query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer result.Close()
+
+	tasks, err := scanTasks(result)
+	if err != nil {
+		return nil, fmt.Errorf("scanTasks: %w", err)
+	}
+	return tasks, nil
+}
+
+func scanTasks(rows pgx.Rows) ([]task.Task, error) {
+	var results []task.Task
+	for rows.Next() {
+		tsk, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, tsk)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+	return results, nil
+}
+
+// scanTask scans a row that selects the columns of a task in the order of the queries of this package.
+func scanTask(row pgx.Row) (task.Task, error) {
+	var dbTask Task
+	err := row.Scan(
+		&dbTask.Id,
+		&dbTask.UserId,
+		&dbTask.Command,
+		&dbTask.Args,
+		&dbTask.Image,
+		&dbTask.ImageDigest,
+		&dbTask.FloatingTag,
+		&dbTask.Environment,
+		&dbTask.Status,
+		&dbTask.Result,
+		&dbTask.ErrorMessage,
+		&dbTask.ScheduledAt,
+		&dbTask.CreatedAt,
+		&dbTask.UpdatedAt,
+		&dbTask.Version,
+		&dbTask.StartedAt,
+		&dbTask.FinishedAt,
+		&dbTask.QueueLatency,
+		&dbTask.MaxRetries,
+		&dbTask.RetryOn,
+		&dbTask.EnqueuedAt,
+		&dbTask.Steps,
+		&dbTask.ResultRef,
+		&dbTask.ResultSize,
+		&dbTask.Preset,
+		&dbTask.NetworkMode,
+		&dbTask.PullPolicy,
+		&dbTask.ErrorCategory,
+		&dbTask.Input,
+		&dbTask.WorkDir,
+		&dbTask.RunAsUser,
+		&dbTask.DedupKey,
+	)
+	if err != nil {
+		return task.Task{}, fmt.Errorf("scan: %w", err)
+	}
+
+	tsk, err := dbTask.toDomainTask()
+	if err != nil {
+		return task.Task{}, fmt.Errorf("toDomainTask: %w", err)
+	}
+	return tsk, nil
+}
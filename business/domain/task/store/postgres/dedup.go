@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/database/postgres"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+// GetByDedupKey returns the latest task of the user with the key that did not finish yet or completed at or after
+// completedSince, returns sql.ErrNoRows when there is none.
+func (r *Repository) GetByDedupKey(ctx context.Context, userId uuid.UUID, key string, completedSince time.Time) (task.Task, error) {
+	const q = `
+	SELECT
+		id,user_id,command,args,image,image_digest,floating_tag,environment,status,result,error_msg,scheduled_at,created_at,updated_at,version,started_at,finished_at,queue_latency_ms,max_retries,retry_on,enqueued_at,steps,result_ref,result_size,preset,network_mode,pull_policy,error_category,input,work_dir,run_as_user,dedup_key
+	FROM tasks
+	WHERE user_id = $1 AND dedup_key = $2 AND (
+		status IN ('pending', 'queued') OR
+		(status = 'completed' AND COALESCE(finished_at, updated_at) >= $3)
+	)
+	ORDER BY created_at DESC
+	FETCH NEXT 1 ROWS ONLY
+	`
+
+	//db is in UTC
+	tsk, err := scanTask(r.db.QueryRow(ctx, q, userId, key, completedSince.UTC()))
+	if err != nil {
+		return task.Task{}, fmt.Errorf("scanTask: %w", postgres.NoRows(err))
+	}
+	return tsk, nil
+}
+
+// LockDedupKey takes the advisory lock of the dedup key of the user, the lock is held until the transaction of the
+// store ends.
+func (r *Repository) LockDedupKey(ctx context.Context, userId uuid.UUID, key string) error {
+	const q = `SELECT pg_advisory_xact_lock(hashtext($1::text || '/' || $2))`
+
+	if _, err := r.db.Exec(ctx, q, userId, key); err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+	return nil
+}
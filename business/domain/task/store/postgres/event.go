@@ -0,0 +1,107 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+// Event represents an event of the timeline of a task inside of database.
+type Event struct {
+	Id        uuid.UUID
+	TaskId    uuid.UUID
+	Kind      string
+	Message   *string
+	Source    string
+	CreatedAt time.Time
+}
+
+func toDBEvent(e task.Event) Event {
+	return Event{
+		Id:        e.Id,
+		TaskId:    e.TaskId,
+		Kind:      string(e.Kind),
+		Message:   nullable(e.Message),
+		Source:    e.Source,
+		CreatedAt: e.CreatedAt.UTC(),
+	}
+}
+
+func (e Event) toDomainEvent() task.Event {
+	return task.Event{
+		Id:        e.Id,
+		TaskId:    e.TaskId,
+		Kind:      task.EventKind(e.Kind),
+		Message:   value(e.Message),
+		Source:    e.Source,
+		CreatedAt: e.CreatedAt.In(time.Local),
+	}
+}
+
+// CreateEvent inserts the event of a task.
+func (s *Repository) CreateEvent(ctx context.Context, event task.Event) error {
+	const q = `
+	INSERT INTO task_events
+		(id,task_id,kind,message,source,created_at)
+	VALUES
+		($1,$2,$3,$4,$5,$6);
+	`
+
+	dbEvent := toDBEvent(event)
+
+	_, err := s.db.Exec(ctx, q,
+		dbEvent.Id,
+		dbEvent.TaskId,
+		dbEvent.Kind,
+		dbEvent.Message,
+		dbEvent.Source,
+		dbEvent.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+	return nil
+}
+
+// GetEventsByTaskId fetches the timeline of a task, oldest first.
+func (s *Repository) GetEventsByTaskId(ctx context.Context, taskId uuid.UUID) ([]task.Event, error) {
+	const q = `
+	SELECT
+		id,task_id,kind,message,source,created_at
+	FROM
+		task_events
+	WHERE
+		task_id = $1
+	ORDER BY created_at ASC
+	`
+
+	rows, err := s.db.Query(ctx, q, taskId)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	var results []task.Event
+	for rows.Next() {
+		var dbEvent Event
+		if err := rows.Scan(
+			&dbEvent.Id,
+			&dbEvent.TaskId,
+			&dbEvent.Kind,
+			&dbEvent.Message,
+			&dbEvent.Source,
+			&dbEvent.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		results = append(results, dbEvent.toDomainEvent())
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+	return results, nil
+}
@@ -1,80 +1,238 @@
 package postgres
 
 import (
-	"database/sql"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/hamidoujand/task-scheduler/business/domain/task"
 )
 
-// Task represents a task object inside of database.
+// Task represents a task object inside of database, nil pointers are NULL columns.
 type Task struct {
 	Id           uuid.UUID
 	UserId       uuid.UUID
 	Command      string
-	Args         sql.Null[[]string]
+	Args         []string
 	Image        string
+	ImageDigest  *string
+	FloatingTag  bool
 	Environment  string
 	Status       string
-	Result       sql.Null[string]
-	ErrorMessage sql.Null[string]
+	Result       *string
+	ErrorMessage *string
 	ScheduledAt  time.Time
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
+	Version      int
+	StartedAt    *time.Time
+	FinishedAt   *time.Time
+	QueueLatency *int64
+	MaxRetries   *int
+	RetryOn      []int
+	EnqueuedAt   *time.Time
+	//Steps is the json of the steps, nil when the task has none.
+	Steps         []byte
+	ResultRef     *string
+	ResultSize    *int
+	Preset        string
+	NetworkMode   string
+	PullPolicy    string
+	ErrorCategory *string
+	//Input is NULL when the task has none.
+	Input     []byte
+	WorkDir   string
+	RunAsUser string
+	DedupKey  string
 }
 
-func toDBTask(t task.Task) Task {
-	return Task{
-		Id:      t.Id,
-		UserId:  t.UserId,
-		Command: t.Command,
-		Args: sql.Null[[]string]{
-			V:     t.Args,
-			Valid: t.Args != nil,
-		},
-		Image:        t.Image,
-		Environment:  t.Environment,
-		Status:       t.Status.String(),
-		Result:       sql.Null[string]{V: t.Result, Valid: t.Result != ""},
-		ErrorMessage: sql.Null[string]{V: t.ErrMessage, Valid: t.ErrMessage != ""},
-		ScheduledAt:  t.ScheduledAt.UTC(),
-		CreatedAt:    t.CreatedAt.UTC(),
-		UpdatedAt:    t.ScheduledAt.UTC(),
+// taskColumns are the columns of a task in the order of row.
+var taskColumns = []string{
+	"id", "user_id", "command", "args", "image", "image_digest", "floating_tag", "environment", "status", "result",
+	"error_msg", "scheduled_at", "created_at", "updated_at", "version", "max_retries", "retry_on", "enqueued_at",
+	"steps", "result_ref", "result_size", "preset", "network_mode", "pull_policy", "error_category", "input",
+	"work_dir", "run_as_user", "dedup_key",
+}
+
+// row returns the values of the task in the order of taskColumns.
+func (t Task) row() []any {
+	return []any{
+		t.Id,
+		t.UserId,
+		t.Command,
+		t.Args,
+		t.Image,
+		t.ImageDigest,
+		t.FloatingTag,
+		t.Environment,
+		t.Status,
+		t.Result,
+		t.ErrorMessage,
+		t.ScheduledAt,
+		t.CreatedAt,
+		t.UpdatedAt,
+		t.Version,
+		t.MaxRetries,
+		t.RetryOn,
+		t.EnqueuedAt,
+		t.Steps,
+		t.ResultRef,
+		t.ResultSize,
+		t.Preset,
+		t.NetworkMode,
+		t.PullPolicy,
+		t.ErrorCategory,
+		t.Input,
+		t.WorkDir,
+		t.RunAsUser,
+		t.DedupKey,
 	}
 }
 
-func (t Task) toDomainTask() task.Task {
-	result := ""
+// step represents a step of a task inside of the steps column.
+type step struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+	Output  string   `json:"output,omitempty"`
+}
 
-	if t.Result.Valid {
-		result = t.Result.V
+func toDBTask(t task.Task) (Task, error) {
+	dbTask := Task{
+		Id:            t.Id,
+		UserId:        t.UserId,
+		Command:       t.Command,
+		Args:          t.Args,
+		Image:         t.Image,
+		ImageDigest:   nullable(t.ImageDigest),
+		FloatingTag:   t.FloatingTag,
+		Environment:   t.Environment,
+		Status:        t.Status.String(),
+		Result:        nullable(t.Result),
+		ErrorMessage:  nullable(t.ErrMessage),
+		ScheduledAt:   t.ScheduledAt.UTC(),
+		CreatedAt:     t.CreatedAt.UTC(),
+		UpdatedAt:     t.ScheduledAt.UTC(),
+		Version:       t.Version,
+		StartedAt:     nullableTime(t.StartedAt),
+		FinishedAt:    nullableTime(t.FinishedAt),
+		MaxRetries:    t.MaxRetries,
+		RetryOn:       t.RetryOn,
+		EnqueuedAt:    nullableTime(t.EnqueuedAt),
+		ResultRef:     nullable(t.ResultRef),
+		ResultSize:    nullable(t.ResultSize),
+		Preset:        t.Preset,
+		NetworkMode:   string(t.NetworkMode),
+		PullPolicy:    string(t.PullPolicy),
+		ErrorCategory: nullable(string(t.ErrCategory)),
+		Input:         t.Input,
+		WorkDir:       t.WorkDir,
+		RunAsUser:     t.RunAsUser,
+		DedupKey:      t.DedupKey,
 	}
-	errMsgs := ""
-	if t.ErrorMessage.Valid {
-		errMsgs = t.ErrorMessage.V
+
+	if !t.StartedAt.IsZero() {
+		latency := t.QueueLatency.Milliseconds()
+		dbTask.QueueLatency = &latency
 	}
 
-	var args []string
-	if t.Args.Valid {
-		args = t.Args.V
+	if len(t.Steps) > 0 {
+		steps := make([]step, len(t.Steps))
+		for i, s := range t.Steps {
+			steps[i] = step{Command: s.Command, Args: s.Args, Output: s.Output}
+		}
+
+		bs, err := json.Marshal(steps)
+		if err != nil {
+			return Task{}, fmt.Errorf("marshal steps: %w", err)
+		}
+		dbTask.Steps = bs
 	}
+	return dbTask, nil
+}
 
+func (t Task) toDomainTask() (task.Task, error) {
 	status, _ := task.ParseStatus(t.Status)
 
+	var steps []task.Step
+	if t.Steps != nil {
+		var dbSteps []step
+		if err := json.Unmarshal(t.Steps, &dbSteps); err != nil {
+			return task.Task{}, fmt.Errorf("unmarshal steps: %w", err)
+		}
+
+		steps = make([]task.Step, len(dbSteps))
+		for i, s := range dbSteps {
+			steps[i] = task.Step{Command: s.Command, Args: s.Args, Output: s.Output}
+		}
+	}
+
 	return task.Task{
 		//must parse since we taking it out of db.
-		Id:          t.Id,
-		UserId:      t.UserId,
-		Command:     t.Command,
-		Args:        args,
-		Image:       t.Image,
-		Environment: t.Environment,
-		Status:      status,
-		Result:      result,
-		ErrMessage:  errMsgs,
-		ScheduledAt: t.ScheduledAt.In(time.Local),
-		CreatedAt:   t.CreatedAt.In(time.Local),
-		UpdatedAt:   t.UpdatedAt.In(time.Local),
+		Id:           t.Id,
+		UserId:       t.UserId,
+		Command:      t.Command,
+		Args:         t.Args,
+		Steps:        steps,
+		Image:        t.Image,
+		ImageDigest:  value(t.ImageDigest),
+		FloatingTag:  t.FloatingTag,
+		Environment:  t.Environment,
+		Status:       status,
+		Result:       value(t.Result),
+		ResultRef:    value(t.ResultRef),
+		ResultSize:   value(t.ResultSize),
+		ErrMessage:   value(t.ErrorMessage),
+		ScheduledAt:  t.ScheduledAt.In(time.Local),
+		CreatedAt:    t.CreatedAt.In(time.Local),
+		UpdatedAt:    t.UpdatedAt.In(time.Local),
+		EnqueuedAt:   localTime(t.EnqueuedAt),
+		Version:      t.Version,
+		StartedAt:    localTime(t.StartedAt),
+		FinishedAt:   localTime(t.FinishedAt),
+		QueueLatency: time.Duration(value(t.QueueLatency)) * time.Millisecond,
+		MaxRetries:   t.MaxRetries,
+		RetryOn:      t.RetryOn,
+		Preset:       t.Preset,
+		NetworkMode:  task.NetworkMode(t.NetworkMode),
+		PullPolicy:   task.PullPolicy(t.PullPolicy),
+		ErrCategory:  task.ErrorCategory(value(t.ErrorCategory)),
+		Input:        t.Input,
+		WorkDir:      t.WorkDir,
+		RunAsUser:    t.RunAsUser,
+		DedupKey:     t.DedupKey,
+	}, nil
+}
+
+// nullable returns nil for the zero value so it is stored as NULL.
+func nullable[T comparable](v T) *T {
+	var zero T
+	if v == zero {
+		return nil
+	}
+	return &v
+}
+
+// value returns the zero value for NULL.
+func value[T any](v *T) T {
+	if v == nil {
+		var zero T
+		return zero
+	}
+	return *v
+}
+
+func nullableTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
+func localTime(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
 	}
+	return t.In(time.Local)
 }
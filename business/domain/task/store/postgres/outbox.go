@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hamidoujand/task-scheduler/business/database/postgres"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateWithOutbox inserts the task and its outbox message inside of the same transaction.
+func (s *Repository) CreateWithOutbox(ctx context.Context, tsk task.Task, msg task.OutboxMessage) error {
+	const q = `
+	INSERT INTO task_outbox
+		(id,task_id,queue,payload,created_at)
+	VALUES
+		($1,$2,$3,$4,$5);
+	`
+
+	return postgres.InTran(ctx, s.db, func(tx pgx.Tx) error {
+		if err := insertTask(ctx, tx, tsk); err != nil {
+			return fmt.Errorf("insert task: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, q, msg.Id, msg.TaskId, msg.Queue, msg.Payload, msg.CreatedAt); err != nil {
+			return fmt.Errorf("insert outbox message: %w", err)
+		}
+		return nil
+	})
+}
+
+// DrainOutbox locks up to limit of the oldest outbox messages, messages locked by another relay are skipped, and
+// hands them to publish one by one. Published messages are removed, draining stops at the first failed publish.
+func (s *Repository) DrainOutbox(ctx context.Context, limit int, publish func(task.OutboxMessage) error) (int, error) {
+	const q = `
+	SELECT
+		id,task_id,queue,payload,created_at
+	FROM
+		task_outbox
+	ORDER BY created_at ASC
+	LIMIT $1
+	FOR UPDATE SKIP LOCKED
+	`
+
+	const del = `
+	DELETE FROM
+		task_outbox
+	WHERE
+		id = $1
+	`
+
+	var published int
+	var pubErr error
+
+	//the transaction is committed even when a publish fails to keep whatever was published so far
+	err := postgres.InTran(ctx, s.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, q, limit)
+		if err != nil {
+			return fmt.Errorf("query: %w", err)
+		}
+
+		var msgs []task.OutboxMessage
+		for rows.Next() {
+			var msg task.OutboxMessage
+			if err := rows.Scan(&msg.Id, &msg.TaskId, &msg.Queue, &msg.Payload, &msg.CreatedAt); err != nil {
+				rows.Close()
+				return fmt.Errorf("scan: %w", err)
+			}
+			msgs = append(msgs, msg)
+		}
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("rows: %w", err)
+		}
+
+		for _, msg := range msgs {
+			if err := publish(msg); err != nil {
+				pubErr = fmt.Errorf("publish %s: %w", msg.Id, err)
+				return nil
+			}
+
+			if _, err := tx.Exec(ctx, del, msg.Id); err != nil {
+				pubErr = fmt.Errorf("delete %s: %w", msg.Id, err)
+				return nil
+			}
+			published++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return published, pubErr
+}
@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/database/postgres"
+	"github.com/jackc/pgx/v5"
+)
+
+// partitionName returns the name of the monthly partition of tasks that holds month.
+func partitionName(month time.Time) string {
+	return "tasks_" + month.Format("200601")
+}
+
+// monthOf returns the first instant of the month of t in UTC.
+func monthOf(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// CreatePartitions makes sure the monthly partitions of tasks exist for the month of from and the months after it,
+// the names of the partitions that were missing are returned.
+func (s *Repository) CreatePartitions(ctx context.Context, from time.Time, months int) ([]string, error) {
+	var created []string
+
+	start := monthOf(from)
+	for i := range months {
+		month := start.AddDate(0, i, 0)
+		name := partitionName(month)
+
+		var exists bool
+		if err := s.db.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, name).Scan(&exists); err != nil {
+			return created, fmt.Errorf("lookup partition %s: %w", name, err)
+		}
+
+		if exists {
+			continue
+		}
+
+		//identifiers and bounds can not be bound as parameters, both are derived from the month only
+		q := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s PARTITION OF tasks FOR VALUES FROM ('%s') TO ('%s')`,
+			pgx.Identifier{name}.Sanitize(),
+			month.Format(time.DateOnly),
+			month.AddDate(0, 1, 0).Format(time.DateOnly),
+		)
+
+		if _, err := s.db.Exec(ctx, q); err != nil {
+			return created, fmt.Errorf("create partition %s: %w", name, err)
+		}
+		created = append(created, name)
+	}
+
+	return created, nil
+}
+
+// DropPartitions drops the monthly partitions of tasks that end before the month of before along with the runs,
+// events and outbox messages of their tasks, the names of the dropped partitions are returned. The default partition
+// is never dropped.
+func (s *Repository) DropPartitions(ctx context.Context, before time.Time) ([]string, error) {
+	const q = `
+	SELECT
+		c.relname
+	FROM
+		pg_inherits i
+	JOIN
+		pg_class c ON c.oid = i.inhrelid
+	WHERE
+		i.inhparent = 'tasks'::regclass AND c.relname ~ '^tasks_[0-9]{6}$' AND c.relname < $1
+	ORDER BY
+		c.relname
+	`
+
+	//names sort like their months, every partition below the one of before ends by the time it starts
+	rows, err := s.db.Query(ctx, q, partitionName(monthOf(before)))
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("collect: %w", err)
+	}
+
+	var dropped []string
+	for _, name := range names {
+		if err := s.dropPartition(ctx, name); err != nil {
+			return dropped, fmt.Errorf("drop partition %s: %w", name, err)
+		}
+		dropped = append(dropped, name)
+	}
+
+	return dropped, nil
+}
+
+// dropPartition removes the children of the tasks inside of the partition and then the partition itself, dropping a
+// partition does not fire the delete trigger of its rows.
+func (s *Repository) dropPartition(ctx context.Context, name string) error {
+	partition := pgx.Identifier{name}.Sanitize()
+
+	return postgres.InTran(ctx, s.db, func(tx pgx.Tx) error {
+		for _, child := range []string{"task_runs", "task_events", "task_outbox"} {
+			q := fmt.Sprintf(`DELETE FROM %s WHERE task_id IN (SELECT id FROM %s)`, child, partition)
+			if _, err := tx.Exec(ctx, q); err != nil {
+				return fmt.Errorf("delete %s: %w", child, err)
+			}
+		}
+
+		if _, err := tx.Exec(ctx, fmt.Sprintf(`DROP TABLE %s`, partition)); err != nil {
+			return fmt.Errorf("drop: %w", err)
+		}
+		return nil
+	})
+}
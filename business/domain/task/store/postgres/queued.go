@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	"github.com/jackc/pgx/v5"
+)
+
+// ClaimDueTasks marks the pending tasks scheduled at or before from as queued at enqueuedAt and returns them, rows
+// locked by a concurrent claim are skipped so a task is only claimed once.
+func (r *Repository) ClaimDueTasks(ctx context.Context, from time.Time, enqueuedAt time.Time) ([]task.Task, error) {
+	const q = `
+	UPDATE tasks
+	SET status = 'queued', enqueued_at = $2, version = version + 1
+	WHERE (id, created_at) IN (
+		SELECT id, created_at FROM tasks
+		WHERE scheduled_at <= $1 AND status = 'pending'
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING
+		id,user_id,command,args,image,image_digest,floating_tag,environment,status,result,error_msg,scheduled_at,created_at,updated_at,version,started_at,finished_at,queue_latency_ms,max_retries,retry_on,enqueued_at,steps,result_ref,result_size,preset,network_mode,pull_policy,error_category,input,work_dir,run_as_user,dedup_key
+	`
+
+	//db is in UTC
+	rows, err := r.db.Query(ctx, q, from.UTC(), enqueuedAt.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	tasks, err := scanTasks(rows)
+	if err != nil {
+		return nil, fmt.Errorf("scanTasks: %w", err)
+	}
+	return tasks, nil
+}
+
+// RequeueStuck puts the tasks that are queued since before the given time back to pending and returns how many.
+func (r *Repository) RequeueStuck(ctx context.Context, before time.Time) (int, error) {
+	const q = `
+	UPDATE tasks
+	SET status = 'pending', enqueued_at = NULL, version = version + 1
+	WHERE status = 'queued' AND enqueued_at < $1
+	`
+
+	tag, err := r.db.Exec(ctx, q, before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("exec: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// GetLate returns up to limit pending or queued tasks that were scheduled before the given time and never started,
+// the oldest first. Tasks with a late event are left out. Retries do not write the task, its started event tells
+// whether it ran.
+func (r *Repository) GetLate(ctx context.Context, scheduledBefore time.Time, limit int) ([]task.Task, error) {
+	const q = `
+	SELECT
+		id,user_id,command,args,image,image_digest,floating_tag,environment,status,result,error_msg,scheduled_at,created_at,updated_at,version,started_at,finished_at,queue_latency_ms,max_retries,retry_on,enqueued_at,steps,result_ref,result_size,preset,network_mode,pull_policy,error_category,input,work_dir,run_as_user,dedup_key
+	FROM
+		tasks t
+	WHERE
+		status IN ('pending', 'queued') AND scheduled_at < $1
+		AND NOT EXISTS (SELECT 1 FROM task_events e WHERE e.task_id = t.id AND e.kind IN ('started', 'late'))
+	ORDER BY
+		scheduled_at
+	LIMIT $2
+	`
+
+	//db is in UTC
+	rows, err := r.db.Query(ctx, q, scheduledBefore.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	tasks, err := scanTasks(rows)
+	if err != nil {
+		return nil, fmt.Errorf("scanTasks: %w", err)
+	}
+	return tasks, nil
+}
+
+// CancelByUserId marks the pending and queued tasks of the user as canceled at canceledAt in a single statement and
+// returns their ids.
+func (r *Repository) CancelByUserId(ctx context.Context, userId uuid.UUID, canceledAt time.Time) ([]uuid.UUID, error) {
+	const q = `
+	UPDATE tasks
+	SET status = 'canceled', updated_at = $2, version = version + 1
+	WHERE user_id = $1 AND status IN ('pending', 'queued')
+	RETURNING id
+	`
+
+	rows, err := r.db.Query(ctx, q, userId, canceledAt.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, fmt.Errorf("collect rows: %w", err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,232 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/database/postgres"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+// Run represents an execution attempt of a task inside of database.
+type Run struct {
+	Id           uuid.UUID
+	TaskId       uuid.UUID
+	Status       string
+	ErrorMessage *string
+	Environment  []byte
+	Security     []byte
+	CreatedAt    time.Time
+}
+
+// Environment represents the executor fingerprint stored as json.
+type Environment struct {
+	WorkerId      string `json:"workerId,omitempty"`
+	WorkerBuild   string `json:"workerBuild,omitempty"`
+	Runtime       string `json:"runtime,omitempty"`
+	DockerVersion string `json:"dockerVersion,omitempty"`
+	OS            string `json:"os,omitempty"`
+	Arch          string `json:"arch,omitempty"`
+	ImageDigest   string `json:"imageDigest,omitempty"`
+}
+
+// SecurityProfile represents the hardening of the container stored as json.
+type SecurityProfile struct {
+	ReadOnly        bool   `json:"readOnly,omitempty"`
+	CapDropAll      bool   `json:"capDropAll,omitempty"`
+	NoNewPrivileges bool   `json:"noNewPrivileges,omitempty"`
+	User            string `json:"user,omitempty"`
+	Tmpfs           string `json:"tmpfs,omitempty"`
+	TmpfsSizeMB     int    `json:"tmpfsSizeMb,omitempty"`
+}
+
+func toDBRun(r task.Run) (Run, error) {
+	env, err := json.Marshal(Environment(r.Environment))
+	if err != nil {
+		return Run{}, fmt.Errorf("marshal environment: %w", err)
+	}
+
+	security, err := json.Marshal(SecurityProfile(r.Security))
+	if err != nil {
+		return Run{}, fmt.Errorf("marshal security: %w", err)
+	}
+
+	return Run{
+		Id:           r.Id,
+		TaskId:       r.TaskId,
+		Status:       r.Status.String(),
+		ErrorMessage: nullable(r.ErrMessage),
+		Environment:  env,
+		Security:     security,
+		CreatedAt:    r.CreatedAt.UTC(),
+	}, nil
+}
+
+func (r Run) toDomainRun() (task.Run, error) {
+	var env Environment
+	if err := json.Unmarshal(r.Environment, &env); err != nil {
+		return task.Run{}, fmt.Errorf("unmarshal environment: %w", err)
+	}
+
+	var security SecurityProfile
+	if err := json.Unmarshal(r.Security, &security); err != nil {
+		return task.Run{}, fmt.Errorf("unmarshal security: %w", err)
+	}
+
+	status, _ := task.ParseStatus(r.Status)
+
+	return task.Run{
+		Id:          r.Id,
+		TaskId:      r.TaskId,
+		Status:      status,
+		ErrMessage:  value(r.ErrorMessage),
+		Environment: task.Environment(env),
+		Security:    task.SecurityProfile(security),
+		CreatedAt:   r.CreatedAt.In(time.Local),
+	}, nil
+}
+
+// CreateRun inserts the execution attempt of a task.
+func (s *Repository) CreateRun(ctx context.Context, run task.Run) error {
+	const q = `
+	INSERT INTO task_runs
+		(id,task_id,status,error_msg,environment,security,created_at)
+	VALUES
+		($1,$2,$3,$4,$5,$6,$7);
+	`
+
+	dbRun, err := toDBRun(run)
+	if err != nil {
+		return fmt.Errorf("toDBRun: %w", err)
+	}
+
+	_, err = s.db.Exec(ctx, q,
+		dbRun.Id,
+		dbRun.TaskId,
+		dbRun.Status,
+		dbRun.ErrorMessage,
+		dbRun.Environment,
+		dbRun.Security,
+		dbRun.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+	return nil
+}
+
+// GetRunsByTaskId fetches all of the execution attempts of a task, oldest first.
+func (s *Repository) GetRunsByTaskId(ctx context.Context, taskId uuid.UUID) ([]task.Run, error) {
+	const q = `
+	SELECT
+		id,task_id,status,error_msg,environment,security,created_at
+	FROM
+		task_runs
+	WHERE
+		task_id = $1
+	ORDER BY created_at ASC
+	`
+
+	rows, err := s.db.Query(ctx, q, taskId)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	var results []task.Run
+	for rows.Next() {
+		var dbRun Run
+		if err := rows.Scan(
+			&dbRun.Id,
+			&dbRun.TaskId,
+			&dbRun.Status,
+			&dbRun.ErrorMessage,
+			&dbRun.Environment,
+			&dbRun.Security,
+			&dbRun.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+
+		run, err := dbRun.toDomainRun()
+		if err != nil {
+			return nil, fmt.Errorf("toDomainRun: %w", err)
+		}
+		results = append(results, run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+	return results, nil
+}
+
+// GetRunById fetches the execution attempt, returns sql.ErrNoRows when it does not exist.
+func (s *Repository) GetRunById(ctx context.Context, runId uuid.UUID) (task.Run, error) {
+	const q = `
+	SELECT
+		id,task_id,status,error_msg,environment,security,created_at
+	FROM
+		task_runs
+	WHERE
+		id = $1
+	`
+
+	var dbRun Run
+	if err := s.db.QueryRow(ctx, q, runId).Scan(
+		&dbRun.Id,
+		&dbRun.TaskId,
+		&dbRun.Status,
+		&dbRun.ErrorMessage,
+		&dbRun.Environment,
+		&dbRun.Security,
+		&dbRun.CreatedAt,
+	); err != nil {
+		return task.Run{}, fmt.Errorf("row scan: %w", postgres.NoRows(err))
+	}
+
+	run, err := dbRun.toDomainRun()
+	if err != nil {
+		return task.Run{}, fmt.Errorf("toDomainRun: %w", err)
+	}
+	return run, nil
+}
+
+// CountFailedRuns returns the number of failed runs of every task with the given status.
+func (s *Repository) CountFailedRuns(ctx context.Context, status task.Status) (map[uuid.UUID]int, error) {
+	const q = `
+	SELECT
+		r.task_id, COUNT(*)
+	FROM task_runs r
+	JOIN tasks t ON t.id = r.task_id
+	WHERE t.status = $1 AND r.status = $2
+	GROUP BY r.task_id
+	`
+
+	rows, err := s.db.Query(ctx, q, status.String(), task.StatusFailed.String())
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var (
+			taskId uuid.UUID
+			count  int
+		)
+
+		if err := rows.Scan(&taskId, &count); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		counts[taskId] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+	return counts, nil
+}
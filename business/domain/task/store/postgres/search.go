@@ -0,0 +1,36 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+// Search returns the tasks of the user whose command, args, result or error message match the free text query,
+// best matches first. The search column is kept up to date by a trigger.
+func (r *Repository) Search(ctx context.Context, userId uuid.UUID, query string, rowsPerPage int, pageNumber int) ([]task.Task, error) {
+	const q = `
+	SELECT
+		id,user_id,command,args,image,image_digest,floating_tag,environment,status,result,error_msg,scheduled_at,created_at,updated_at,version,started_at,finished_at,queue_latency_ms,max_retries,retry_on,enqueued_at,steps,result_ref,result_size,preset,network_mode,pull_policy,error_category,input,work_dir,run_as_user,dedup_key
+	FROM tasks
+	WHERE user_id = $1 AND search @@ websearch_to_tsquery('simple', $2)
+	ORDER BY ts_rank(search, websearch_to_tsquery('simple', $2)) DESC, created_at DESC
+	OFFSET $3 ROWS FETCH NEXT $4 ROWS ONLY
+	`
+
+	offset := (pageNumber - 1) * rowsPerPage
+
+	rows, err := r.db.Query(ctx, q, userId, query, offset, rowsPerPage)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	tasks, err := scanTasks(rows)
+	if err != nil {
+		return nil, fmt.Errorf("scanTasks: %w", err)
+	}
+	return tasks, nil
+}
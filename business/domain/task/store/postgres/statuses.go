@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+// GetByIds returns the statuses of the tasks of the user among taskIds, only the columns of the status are read.
+func (r *Repository) GetByIds(ctx context.Context, userId uuid.UUID, taskIds []uuid.UUID) ([]task.StatusSummary, error) {
+	const q = `
+	SELECT
+		id,status,updated_at
+	FROM tasks
+	WHERE user_id = $1 AND id = ANY($2::uuid[])
+	`
+
+	ids := make([]string, len(taskIds))
+	for i, id := range taskIds {
+		ids[i] = id.String()
+	}
+
+	rows, err := r.db.Query(ctx, q, userId, ids)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	var statuses []task.StatusSummary
+	for rows.Next() {
+		var (
+			id        uuid.UUID
+			status    string
+			updatedAt time.Time
+		)
+
+		if err := rows.Scan(&id, &status, &updatedAt); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+
+		parsed, _ := task.ParseStatus(status)
+		statuses = append(statuses, task.StatusSummary{
+			Id:        id,
+			Status:    parsed,
+			UpdatedAt: updatedAt.In(time.Local),
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+	return statuses, nil
+}
@@ -2,14 +2,13 @@ package postgres
 
 import (
 	"context"
-	"database/sql"
-	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/hamidoujand/task-scheduler/business/database/postgres"
 	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	"github.com/jackc/pgx/v5"
 )
 
 var columnNames = map[task.Field]string{
@@ -21,125 +20,186 @@ var columnNames = map[task.Field]string{
 
 // Store represents apis used to interact with database.
 type Repository struct {
-	client *postgres.Client
+	db postgres.DB
 }
 
 // New creates a new store that uses *postgres.Client as its db client
 func NewRepository(clint *postgres.Client) *Repository {
 	return &Repository{
-		client: clint,
+		db: clint.Pool,
+	}
+}
+
+// NewWithTx creates a store whose queries run inside of the transaction.
+func NewWithTx(tx pgx.Tx) *Repository {
+	return &Repository{
+		db: tx,
 	}
 }
 
 func (s *Repository) Create(ctx context.Context, task task.Task) error {
+	return insertTask(ctx, s.db, task)
+}
+
+func insertTask(ctx context.Context, db postgres.DB, task task.Task) error {
 	const q = `
 	INSERT INTO tasks
-		(id,user_id,command,args,image,environment,status,result,error_msg,scheduled_at,created_at,updated_at)
+		(id,user_id,command,args,image,image_digest,floating_tag,environment,status,result,error_msg,scheduled_at,created_at,updated_at,version,max_retries,retry_on,enqueued_at,steps,result_ref,result_size,preset,network_mode,pull_policy,error_category,input,work_dir,run_as_user,dedup_key)
 	VALUES
-		($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12);
+		($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22,$23,$24,$25,$26,$27,$28,$29);
 	`
 
-	dbTask := toDBTask(task)
-	_, err := s.client.DB.ExecContext(ctx, q,
-		dbTask.Id,
-		dbTask.UserId,
-		dbTask.Command,
-		dbTask.Args,
-		dbTask.Image,
-		dbTask.Environment,
-		dbTask.Status,
-		dbTask.Result,
-		dbTask.ErrorMessage,
-		dbTask.ScheduledAt,
-		dbTask.CreatedAt,
-		dbTask.UpdatedAt,
-	)
+	dbTask, err := toDBTask(task)
 	if err != nil {
-		return fmt.Errorf("exec context: %w", err)
+		return fmt.Errorf("toDBTask: %w", err)
+	}
+
+	_, err = db.Exec(ctx, q, dbTask.row()...)
+	if err != nil {
+		return fmt.Errorf("exec: %w", err)
 	}
 	return nil
 }
 
-func (s *Repository) Update(ctx context.Context, task task.Task) error {
+// Update writes the task when it is still at the version of the given task and increments the version, returns
+// task.ErrVersionConflict otherwise.
+func (s *Repository) Update(ctx context.Context, tsk task.Task) error {
 	const q = `
 	UPDATE 
 		tasks
 	SET
-		status =    $1,
-		result =    $2,
-		error_msg = $3
+		status =       $1,
+		result =       $2,
+		error_msg =    $3,
+		image_digest = $4,
+		started_at =   $5,
+		finished_at =  $6,
+		queue_latency_ms = $7,
+		steps =        $8,
+		result_ref =   $9,
+		result_size =  $10,
+		error_category = $11,
+		version =      version + 1
 	WHERE
-		id = $4		
+		id = $12 AND created_at = $14 AND version = $13
 	`
-	dbTask := toDBTask(task)
+	dbTask, err := toDBTask(tsk)
+	if err != nil {
+		return fmt.Errorf("toDBTask: %w", err)
+	}
 
-	_, err := s.client.DB.ExecContext(ctx, q,
+	tag, err := s.db.Exec(ctx, q,
 		dbTask.Status,
 		dbTask.Result,
 		dbTask.ErrorMessage,
+		dbTask.ImageDigest,
+		dbTask.StartedAt,
+		dbTask.FinishedAt,
+		dbTask.QueueLatency,
+		dbTask.Steps,
+		dbTask.ResultRef,
+		dbTask.ResultSize,
+		dbTask.ErrorCategory,
 		dbTask.Id,
+		dbTask.Version,
+		//only the partition of the task is scanned
+		dbTask.CreatedAt,
 	)
 	if err != nil {
-		return fmt.Errorf("exec context: %w", err)
+		return fmt.Errorf("exec: %w", err)
 	}
 
+	//either updated in between or deleted, the caller finds out which by reading it again
+	if tag.RowsAffected() == 0 {
+		return task.ErrVersionConflict
+	}
 	return nil
 }
+
 func (s *Repository) Delete(ctx context.Context, task task.Task) error {
 	const q = `
 	DELETE FROM
 		tasks
-	WHERE 
-		id = $1	
-	
+	WHERE
+		id = $1 AND created_at = $2
 	`
-	_, err := s.client.DB.ExecContext(ctx, q, task.Id)
+	//db is in UTC and only the partition of the task is scanned
+	_, err := s.db.Exec(ctx, q, task.Id, task.CreatedAt.UTC())
 	if err != nil {
-		return fmt.Errorf("exec context: %w", err)
+		return fmt.Errorf("exec: %w", err)
 	}
 	return nil
 }
-func (s *Repository) GetById(ctx context.Context, taskId uuid.UUID) (task.Task, error) {
-	var dbTask Task
+
+// DeleteByUserId deletes all of the tasks of the user.
+func (s *Repository) DeleteByUserId(ctx context.Context, userId uuid.UUID) error {
 	const q = `
-	SELECT 
-		id,user_id,command,array_to_json(args) as args,image,environment,status,result,error_msg,scheduled_at,created_at,updated_at
-	FROM 
+	DELETE FROM
 		tasks
-	WHERE 
-		id = $1		
+	WHERE
+		user_id = $1
 	`
+	if _, err := s.db.Exec(ctx, q, userId); err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+	return nil
+}
+
+// DeleteByUserIdFilter deletes the tasks of the user that match the filter in a single statement and returns them.
+func (s *Repository) DeleteByUserIdFilter(ctx context.Context, userId uuid.UUID, filter task.DeleteFilter) ([]task.Task, error) {
+	statuses := make([]string, len(filter.Statuses))
+	for i, status := range filter.Statuses {
+		statuses[i] = status.String()
+	}
+
+	where := "user_id = $1 AND status = ANY($2)"
+	args := []any{userId, statuses}
 
-	row := s.client.DB.QueryRowContext(ctx, q, taskId.String())
-
-	var commandArgs any
-
-	if err := row.Scan(
-		&dbTask.Id,
-		&dbTask.UserId,
-		&dbTask.Command,
-		&commandArgs,
-		&dbTask.Image,
-		&dbTask.Environment,
-		&dbTask.Status,
-		&dbTask.Result,
-		&dbTask.ErrorMessage,
-		&dbTask.ScheduledAt,
-		&dbTask.CreatedAt,
-		&dbTask.UpdatedAt,
-	); err != nil {
-		return task.Task{}, fmt.Errorf("row scan: %w", err)
+	if !filter.Before.IsZero() {
+		//db is in UTC
+		where += " AND created_at < $3"
+		args = append(args, filter.Before.UTC())
 	}
 
-	args, err := parseArgs(commandArgs)
+	q := fmt.Sprintf(`
+	DELETE FROM
+		tasks
+	WHERE
+		%s
+	RETURNING
+		id,user_id,command,args,image,image_digest,floating_tag,environment,status,result,error_msg,scheduled_at,created_at,updated_at,version,started_at,finished_at,queue_latency_ms,max_retries,retry_on,enqueued_at,steps,result_ref,result_size,preset,network_mode,pull_policy,error_category,input,work_dir,run_as_user,dedup_key
+	`, where)
 
+	rows, err := s.db.Query(ctx, q, args...)
 	if err != nil {
-		return task.Task{}, fmt.Errorf("parseArgs: %w", err)
+		return nil, fmt.Errorf("query: %w", err)
 	}
+	defer rows.Close()
 
-	dbTask.Args = args
+	tasks, err := scanTasks(rows)
+	if err != nil {
+		return nil, fmt.Errorf("scanTasks: %w", err)
+	}
+	return tasks, nil
+}
+
+// GetById returns the task with the id. Callers only know the id, so the primary key of every partition is probed
+// instead of the one of its month, which stays an index lookup per partition as the key starts with id.
+func (s *Repository) GetById(ctx context.Context, taskId uuid.UUID) (task.Task, error) {
+	const q = `
+	SELECT 
+		id,user_id,command,args,image,image_digest,floating_tag,environment,status,result,error_msg,scheduled_at,created_at,updated_at,version,started_at,finished_at,queue_latency_ms,max_retries,retry_on,enqueued_at,steps,result_ref,result_size,preset,network_mode,pull_policy,error_category,input,work_dir,run_as_user,dedup_key
+	FROM 
+		tasks
+	WHERE 
+		id = $1		
+	`
 
-	return dbTask.toDomainTask(), nil
+	tsk, err := scanTask(s.db.QueryRow(ctx, q, taskId))
+	if err != nil {
+		return task.Task{}, fmt.Errorf("scanTask: %w", postgres.NoRows(err))
+	}
+	return tsk, nil
 }
 
 func (r *Repository) GetByUserId(ctx context.Context, userId uuid.UUID, rowsPerPage int, pageNumber int, order task.OrderBy) ([]task.Task, error) {
@@ -155,76 +215,63 @@ func (r *Repository) GetByUserId(ctx context.Context, userId uuid.UUID, rowsPerP
 
 	q := fmt.Sprintf(`
 	SELECT
-		id,user_id,command,array_to_json(args) as args,image,environment,status,result,error_msg,scheduled_at,created_at,updated_at
+		id,user_id,command,args,image,image_digest,floating_tag,environment,status,result,error_msg,scheduled_at,created_at,updated_at,version,started_at,finished_at,queue_latency_ms,max_retries,retry_on,enqueued_at,steps,result_ref,result_size,preset,network_mode,pull_policy,error_category,input,work_dir,run_as_user,dedup_key
 	FROM tasks
 	WHERE user_id = $1
 	ORDER BY %s %s OFFSET $2 ROWS FETCH NEXT $3 ROWS ONLY	
 	`, col, order.Direction.String())
 
-	rows, err := r.client.DB.QueryContext(ctx, q, userId, offset, rowsPerPage)
+	rows, err := r.db.Query(ctx, q, userId, offset, rowsPerPage)
 	if err != nil {
-		return nil, fmt.Errorf("queryContext: %w", err)
+		return nil, fmt.Errorf("query: %w", err)
 	}
+	defer rows.Close()
 
-	var results []task.Task
-	for rows.Next() {
-		var dbTask Task
-		var commandArgs any
-		err := rows.Scan(
-			&dbTask.Id,
-			&dbTask.UserId,
-			&dbTask.Command,
-			&commandArgs,
-			&dbTask.Image,
-			&dbTask.Environment,
-			&dbTask.Status,
-			&dbTask.Result,
-			&dbTask.ErrorMessage,
-			&dbTask.ScheduledAt,
-			&dbTask.CreatedAt,
-			&dbTask.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("scan: %w", err)
-		}
-
-		args, err := parseArgs(commandArgs)
-
-		if err != nil {
-			return nil, fmt.Errorf("parseArgs: %w", err)
-		}
-
-		dbTask.Args = args
-		results = append(results, dbTask.toDomainTask())
+	tasks, err := scanTasks(rows)
+	if err != nil {
+		return nil, fmt.Errorf("scanTasks: %w", err)
 	}
-
-	return results, nil
+	return tasks, nil
 }
 
-func parseArgs(raw any) (sql.Null[[]string], error) {
-	var args sql.Null[[]string]
+// CountByStatus returns the number of tasks of the user with the status.
+func (r *Repository) CountByStatus(ctx context.Context, userId uuid.UUID, status task.Status) (int, error) {
+	const q = `
+	SELECT
+		COUNT(*)
+	FROM tasks
+	WHERE user_id = $1 AND status = $2
+	`
 
-	switch val := raw.(type) {
-	case []byte:
-		// var args []string
-		if err := json.Unmarshal(val, &args.V); err != nil {
-			return sql.Null[[]string]{}, fmt.Errorf("unmarshalling args: %w", err)
-		}
+	var count int
+	if err := r.db.QueryRow(ctx, q, userId, status.String()).Scan(&count); err != nil {
+		return 0, fmt.Errorf("row scan: %w", postgres.NoRows(err))
+	}
+	return count, nil
+}
 
-	case nil:
-		return sql.Null[[]string]{
-			V:     nil,
-			Valid: true,
-		}, nil
+// GetRecentByStatus returns the last rows updated tasks of all of the users with the status, the latest first.
+func (r *Repository) GetRecentByStatus(ctx context.Context, status task.Status, rows int) ([]task.Task, error) {
+	const q = `
+	SELECT
+		id,user_id,command,args,image,image_digest,floating_tag,environment,status,result,error_msg,scheduled_at,created_at,updated_at,version,started_at,finished_at,queue_latency_ms,max_retries,retry_on,enqueued_at,steps,result_ref,result_size,preset,network_mode,pull_policy,error_category,input,work_dir,run_as_user,dedup_key
+	FROM tasks
+	WHERE status = $1
+	ORDER BY updated_at DESC
+	FETCH NEXT $2 ROWS ONLY
+	`
 
-	default:
-		return sql.Null[[]string]{}, fmt.Errorf("args scan: %T", args)
+	dbRows, err := r.db.Query(ctx, q, status.String(), rows)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
 	}
+	defer dbRows.Close()
 
-	//valid
-	args.Valid = args.V != nil
-
-	return args, nil
+	tasks, err := scanTasks(dbRows)
+	if err != nil {
+		return nil, fmt.Errorf("scanTasks: %w", err)
+	}
+	return tasks, nil
 }
 
 // GetDueTasks fetches all of the tasks that have less than or equal to 1 min to
@@ -232,7 +279,7 @@ func parseArgs(raw any) (sql.Null[[]string], error) {
 func (r *Repository) GetDueTasks(ctx context.Context, from time.Time) ([]task.Task, error) {
 	const q = `
 	SELECT 
-		id,user_id,command,array_to_json(args) as args,image,environment,status,result,error_msg,scheduled_at,created_at,updated_at
+		id,user_id,command,args,image,image_digest,floating_tag,environment,status,result,error_msg,scheduled_at,created_at,updated_at,version,started_at,finished_at,queue_latency_ms,max_retries,retry_on,enqueued_at,steps,result_ref,result_size,preset,network_mode,pull_policy,error_category,input,work_dir,run_as_user,dedup_key
 	FROM 
 		tasks
 	WHERE 
@@ -241,42 +288,15 @@ func (r *Repository) GetDueTasks(ctx context.Context, from time.Time) ([]task.Ta
 
 	from = from.Truncate(time.Second).UTC() //truncate by seconds and change int UTC
 	//since db is in UTC
-	rows, err := r.client.DB.QueryContext(ctx, q, from)
+	rows, err := r.db.Query(ctx, q, from)
 	if err != nil {
-		return nil, fmt.Errorf("querycontext: %w", err)
+		return nil, fmt.Errorf("query: %w", err)
 	}
+	defer rows.Close()
 
-	var results []task.Task
-	for rows.Next() {
-		var dbTask Task
-		var commandArgs any
-		err := rows.Scan(
-			&dbTask.Id,
-			&dbTask.UserId,
-			&dbTask.Command,
-			&commandArgs,
-			&dbTask.Image,
-			&dbTask.Environment,
-			&dbTask.Status,
-			&dbTask.Result,
-			&dbTask.ErrorMessage,
-			&dbTask.ScheduledAt,
-			&dbTask.CreatedAt,
-			&dbTask.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("scan: %w", err)
-		}
-
-		args, err := parseArgs(commandArgs)
-
-		if err != nil {
-			return nil, fmt.Errorf("parseArgs: %w", err)
-		}
-
-		dbTask.Args = args
-		results = append(results, dbTask.toDomainTask())
+	tasks, err := scanTasks(rows)
+	if err != nil {
+		return nil, fmt.Errorf("scanTasks: %w", err)
 	}
-
-	return results, nil
+	return tasks, nil
 }
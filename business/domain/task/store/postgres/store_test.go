@@ -7,19 +7,22 @@ import (
 	"fmt"
 	"math/rand"
 	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/hamidoujand/task-scheduler/business/dbtest"
 	"github.com/hamidoujand/task-scheduler/business/domain/task"
 	postgresRepo "github.com/hamidoujand/task-scheduler/business/domain/task/store/postgres"
+	"github.com/hamidoujand/task-scheduler/business/testinfra"
+	"github.com/jackc/pgx/v5"
 )
 
 func TestCreate(t *testing.T) {
 	t.Parallel()
 
-	client := dbtest.NewDatabaseClient(t, "test_task_create")
+	client := testinfra.NewDatabase(t)
 	store := postgresRepo.NewRepository(client)
 
 	//insert a new task
@@ -44,10 +47,89 @@ func TestCreate(t *testing.T) {
 	}
 }
 
+func TestCreateMany(t *testing.T) {
+	t.Parallel()
+
+	client := testinfra.NewDatabase(t)
+	store := postgresRepo.NewRepository(client)
+
+	now := time.Now()
+	userId := uuid.New()
+	tasks := make([]task.Task, 3)
+	for i := range tasks {
+		tasks[i] = task.Task{
+			Id:          uuid.New(),
+			UserId:      userId,
+			Command:     "ls",
+			Args:        []string{"-l"},
+			Steps:       []task.Step{{Command: "ls"}},
+			Image:       "alpine:3.20",
+			Environment: "APP_NAME=test",
+			Status:      task.StatusQueued,
+			ScheduledAt: now,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+			EnqueuedAt:  now,
+			Version:     1,
+		}
+	}
+
+	msg := task.OutboxMessage{
+		Id:        uuid.New(),
+		TaskId:    tasks[0].Id,
+		Queue:     "queue_tasks",
+		Payload:   []byte(`{}`),
+		CreatedAt: now,
+	}
+
+	if err := store.CreateManyWithOutbox(context.Background(), tasks, []task.OutboxMessage{msg}); err != nil {
+		t.Fatalf("creating tasks: %s", err)
+	}
+
+	for _, tt := range tasks {
+		created, err := store.GetById(context.Background(), tt.Id)
+		if err != nil {
+			t.Fatalf("should return task by id after creation: %s", err)
+		}
+
+		if created.Status != tt.Status {
+			t.Errorf("expected the Status to be %s, but got %s", tt.Status, created.Status)
+		}
+
+		if len(created.Steps) != 1 {
+			t.Errorf("expected %d steps, but got %d", 1, len(created.Steps))
+		}
+	}
+
+	var relayed []uuid.UUID
+	if _, err := store.DrainOutbox(context.Background(), 10, func(msg task.OutboxMessage) error {
+		relayed = append(relayed, msg.TaskId)
+		return nil
+	}); err != nil {
+		t.Fatalf("draining outbox: %s", err)
+	}
+
+	if len(relayed) != 1 || relayed[0] != tasks[0].Id {
+		t.Errorf("relayed= %v, got %v", []uuid.UUID{tasks[0].Id}, relayed)
+	}
+
+	//a task that already exists fails the whole batch
+	again := []task.Task{tasks[0]}
+	again[0].Id = uuid.New()
+	again = append(again, tasks[1])
+	if err := store.CreateMany(context.Background(), again); err == nil {
+		t.Fatal("expected a duplicated task to fail the batch")
+	}
+
+	if _, err := store.GetById(context.Background(), again[0].Id); err == nil {
+		t.Error("expected none of the tasks of the failed batch to be created")
+	}
+}
+
 func TestGetById(t *testing.T) {
 	t.Parallel()
 
-	client := dbtest.NewDatabaseClient(t, "test_task_getById")
+	client := testinfra.NewDatabase(t)
 	store := postgresRepo.NewRepository(client)
 
 	//insert a new task
@@ -61,6 +143,8 @@ func TestGetById(t *testing.T) {
 		Image:       "alpine:3.20",
 		Environment: "APP_NAME=test",
 		Args:        nil,
+		RetryOn:     []int{1, 137},
+		Steps:       []task.Step{{Command: "date"}, {Command: "ls", Args: []string{"-l"}, Output: "total 0"}},
 		Status:      task.StatusPending,
 		ScheduledAt: now.Add(time.Hour * 10),
 		CreatedAt:   now,
@@ -104,12 +188,20 @@ func TestGetById(t *testing.T) {
 	if tsk.Environment != tt.Environment {
 		t.Errorf("environment= %s, got %s", tt.Environment, tsk.Environment)
 	}
+
+	if !slices.Equal(tsk.RetryOn, tt.RetryOn) {
+		t.Errorf("retryOn= %v, got %v", tt.RetryOn, tsk.RetryOn)
+	}
+
+	if len(tsk.Steps) != len(tt.Steps) || tsk.Steps[1].Output != tt.Steps[1].Output {
+		t.Errorf("steps= %v, got %v", tt.Steps, tsk.Steps)
+	}
 }
 
 func TestUpdate(t *testing.T) {
 	t.Parallel()
 
-	client := dbtest.NewDatabaseClient(t, "test_task_update")
+	client := testinfra.NewDatabase(t)
 	store := postgresRepo.NewRepository(client)
 
 	//insert a new task
@@ -169,10 +261,95 @@ func TestUpdate(t *testing.T) {
 	}
 }
 
+func TestUpdateMany(t *testing.T) {
+	t.Parallel()
+
+	client := testinfra.NewDatabase(t)
+	store := postgresRepo.NewRepository(client)
+
+	now := time.Now()
+	tasks := make([]task.Task, 3)
+	for i := range tasks {
+		tasks[i] = task.Task{
+			Id:          uuid.New(),
+			UserId:      uuid.New(),
+			Command:     "ls",
+			Image:       "alpine:3.20",
+			Environment: "APP_NAME=test",
+			Status:      task.StatusQueued,
+			ScheduledAt: now,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+			Version:     1,
+		}
+
+		if err := store.Create(context.Background(), tasks[i]); err != nil {
+			t.Fatalf("creating task: %s", err)
+		}
+	}
+
+	tasks[0].Status = task.StatusCompleted
+	tasks[0].Result = "data"
+	tasks[0].Steps = []task.Step{{Command: "ls", Output: "data"}}
+
+	tasks[1].Status = task.StatusFailed
+	tasks[1].ErrMessage = "exit status 1"
+	tasks[1].ErrCategory = task.ErrorExecution
+
+	//the last one was updated by someone else in between
+	tasks[2].Status = task.StatusCompleted
+	tasks[2].Version = 2
+
+	conflicts, err := store.UpdateMany(context.Background(), tasks)
+	if err != nil {
+		t.Fatalf("should be able to update the tasks: %s", err)
+	}
+
+	if len(conflicts) != 1 || conflicts[0] != tasks[2].Id {
+		t.Errorf("conflicts= %v, got %v", []uuid.UUID{tasks[2].Id}, conflicts)
+	}
+
+	for _, tt := range tasks[:2] {
+		updated, err := store.GetById(context.Background(), tt.Id)
+		if err != nil {
+			t.Fatalf("should return task by id after update %s", err)
+		}
+
+		if updated.Status != tt.Status {
+			t.Errorf("expected the Status to be %s, but got %s", tt.Status, updated.Status)
+		}
+
+		if updated.Result != tt.Result {
+			t.Errorf("expected the Result to be %s, but got %s", tt.Result, updated.Result)
+		}
+
+		if updated.ErrCategory != tt.ErrCategory {
+			t.Errorf("expected the ErrCategory to be %s, but got %s", tt.ErrCategory, updated.ErrCategory)
+		}
+
+		if len(updated.Steps) != len(tt.Steps) {
+			t.Errorf("expected %d steps, but got %d", len(tt.Steps), len(updated.Steps))
+		}
+
+		if updated.Version != 2 {
+			t.Errorf("expected the Version to be %d, but got %d", 2, updated.Version)
+		}
+	}
+
+	untouched, err := store.GetById(context.Background(), tasks[2].Id)
+	if err != nil {
+		t.Fatalf("should return task by id: %s", err)
+	}
+
+	if untouched.Status != task.StatusQueued {
+		t.Errorf("expected the Status to be %s, but got %s", task.StatusQueued, untouched.Status)
+	}
+}
+
 func TestDelete(t *testing.T) {
 	t.Parallel()
 
-	client := dbtest.NewDatabaseClient(t, "test_task_delete")
+	client := testinfra.NewDatabase(t)
 	store := postgresRepo.NewRepository(client)
 
 	//insert a new task
@@ -215,7 +392,7 @@ func TestDelete(t *testing.T) {
 func TestGetByUserId(t *testing.T) {
 	t.Parallel()
 
-	client := dbtest.NewDatabaseClient(t, "test_task_getByUserId")
+	client := testinfra.NewDatabase(t)
 	repo := postgresRepo.NewRepository(client)
 
 	userId, commands := seedTasks(t, repo)
@@ -318,7 +495,7 @@ func TestGetByUserId(t *testing.T) {
 func TestGetDueTasks(t *testing.T) {
 	t.Parallel()
 
-	client := dbtest.NewDatabaseClient(t, "test_get_due_tasks")
+	client := testinfra.NewDatabase(t)
 	store := postgresRepo.NewRepository(client)
 
 	//seed it
@@ -416,3 +593,750 @@ func seedTasks(t *testing.T, repo *postgresRepo.Repository) (uuid.UUID, []string
 	}
 	return userId, commands
 }
+
+func TestRuns(t *testing.T) {
+	t.Parallel()
+
+	client := testinfra.NewDatabase(t)
+	store := postgresRepo.NewRepository(client)
+
+	now := time.Now()
+	tt := task.Task{
+		Id:          uuid.New(),
+		UserId:      uuid.New(),
+		Command:     "date",
+		Image:       "alpine:3.20",
+		Status:      task.StatusPending,
+		ScheduledAt: now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := store.Create(context.Background(), tt); err != nil {
+		t.Fatalf("creating task: %s", err)
+	}
+
+	run := task.Run{
+		Id:         uuid.New(),
+		TaskId:     tt.Id,
+		Status:     task.StatusFailed,
+		ErrMessage: "exit status 1",
+		Environment: task.Environment{
+			WorkerId:      uuid.NewString(),
+			WorkerBuild:   "0.0.1",
+			DockerVersion: "27.1.1",
+			OS:            "linux",
+			Arch:          "amd64",
+			ImageDigest:   "alpine@sha256:0a4eaa0eecf5f8c050e5bba433f58c052be7587ee8af3e8b3910ef9ab5fbe9f5",
+		},
+		Security: task.SecurityProfile{
+			CapDropAll:      true,
+			NoNewPrivileges: true,
+			Tmpfs:           "/tmp",
+			TmpfsSizeMB:     64,
+		},
+		CreatedAt: now,
+	}
+	if err := store.CreateRun(context.Background(), run); err != nil {
+		t.Fatalf("creating run: %s", err)
+	}
+
+	got, err := store.GetRunById(context.Background(), run.Id)
+	if err != nil {
+		t.Fatalf("expected to get the run: %s", err)
+	}
+
+	if got.Environment != run.Environment {
+		t.Errorf("environment= %+v, got %+v", run.Environment, got.Environment)
+	}
+
+	if got.Security != run.Security {
+		t.Errorf("security= %+v, got %+v", run.Security, got.Security)
+	}
+
+	if got.Status != run.Status || got.ErrMessage != run.ErrMessage {
+		t.Errorf("status= %s, got %s", run.Status, got.Status)
+	}
+
+	runs, err := store.GetRunsByTaskId(context.Background(), tt.Id)
+	if err != nil {
+		t.Fatalf("expected to get runs of task: %s", err)
+	}
+
+	if len(runs) != 1 {
+		t.Fatalf("len(runs)= %d, got %d", 1, len(runs))
+	}
+
+	//runs are removed along with their task
+	if err := store.Delete(context.Background(), tt); err != nil {
+		t.Fatalf("deleting task: %s", err)
+	}
+
+	_, err = store.GetRunById(context.Background(), run.Id)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("err= %v, got %v", sql.ErrNoRows, err)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	t.Parallel()
+
+	client := testinfra.NewDatabase(t)
+	store := postgresRepo.NewRepository(client)
+
+	now := time.Now()
+	tt := task.Task{
+		Id:          uuid.New(),
+		UserId:      uuid.New(),
+		Command:     "date",
+		Image:       "alpine:3.20",
+		Status:      task.StatusPending,
+		ScheduledAt: now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := store.Create(context.Background(), tt); err != nil {
+		t.Fatalf("creating task: %s", err)
+	}
+
+	events := []task.Event{
+		{Id: uuid.New(), TaskId: tt.Id, Kind: task.EventCreated, Source: task.EventSourceAPI, CreatedAt: now},
+		{Id: uuid.New(), TaskId: tt.Id, Kind: task.EventFailed, Message: "exit status 1", Source: uuid.NewString(), CreatedAt: now.Add(time.Second)},
+	}
+	for _, event := range events {
+		if err := store.CreateEvent(context.Background(), event); err != nil {
+			t.Fatalf("creating event: %s", err)
+		}
+	}
+
+	got, err := store.GetEventsByTaskId(context.Background(), tt.Id)
+	if err != nil {
+		t.Fatalf("expected to get events of task: %s", err)
+	}
+
+	if len(got) != len(events) {
+		t.Fatalf("len(events)= %d, got %d", len(events), len(got))
+	}
+
+	for i, event := range events {
+		if got[i].Kind != event.Kind || got[i].Message != event.Message || got[i].Source != event.Source {
+			t.Errorf("event[%d]= %+v, got %+v", i, event, got[i])
+		}
+	}
+
+	//events are removed along with their task
+	if err := store.Delete(context.Background(), tt); err != nil {
+		t.Fatalf("deleting task: %s", err)
+	}
+
+	got, err = store.GetEventsByTaskId(context.Background(), tt.Id)
+	if err != nil {
+		t.Fatalf("expected to get events of task: %s", err)
+	}
+
+	if len(got) != 0 {
+		t.Errorf("len(events)= %d, got %d", 0, len(got))
+	}
+}
+
+func TestOutbox(t *testing.T) {
+	t.Parallel()
+
+	client := testinfra.NewDatabase(t)
+	store := postgresRepo.NewRepository(client)
+
+	now := time.Now()
+	var msgs []task.OutboxMessage
+	for i := range 3 {
+		tt := task.Task{
+			Id:          uuid.New(),
+			UserId:      uuid.New(),
+			Command:     "date",
+			Image:       "alpine:3.20",
+			Status:      task.StatusPending,
+			ScheduledAt: now,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+
+		msg := task.OutboxMessage{
+			Id:        uuid.New(),
+			TaskId:    tt.Id,
+			Queue:     "queue_tasks",
+			Payload:   []byte(fmt.Sprintf(`{"n":%d}`, i)),
+			CreatedAt: now.Add(time.Duration(i) * time.Second),
+		}
+
+		if err := store.CreateWithOutbox(context.Background(), tt, msg); err != nil {
+			t.Fatalf("creating task with outbox: %s", err)
+		}
+		msgs = append(msgs, msg)
+	}
+
+	//the second publish fails, only the first message is removed
+	var got []uuid.UUID
+	published, err := store.DrainOutbox(context.Background(), 10, func(msg task.OutboxMessage) error {
+		got = append(got, msg.Id)
+		if len(got) == 2 {
+			return errors.New("broker down")
+		}
+		return nil
+	})
+	if err == nil {
+		t.Fatal("expected the failed publish to be returned")
+	}
+
+	if published != 1 {
+		t.Fatalf("published= %d, got %d", 1, published)
+	}
+
+	if got[0] != msgs[0].Id {
+		t.Errorf("first= %s, got %s", msgs[0].Id, got[0])
+	}
+
+	got = nil
+	published, err = store.DrainOutbox(context.Background(), 10, func(msg task.OutboxMessage) error {
+		got = append(got, msg.Id)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected to drain outbox: %s", err)
+	}
+
+	if published != 2 {
+		t.Fatalf("published= %d, got %d", 2, published)
+	}
+
+	if got[0] != msgs[1].Id || got[1] != msgs[2].Id {
+		t.Errorf("order= %v, got %v", []uuid.UUID{msgs[1].Id, msgs[2].Id}, got)
+	}
+
+	published, err = store.DrainOutbox(context.Background(), 10, func(msg task.OutboxMessage) error { return nil })
+	if err != nil {
+		t.Fatalf("expected to drain empty outbox: %s", err)
+	}
+
+	if published != 0 {
+		t.Errorf("published= %d, got %d", 0, published)
+	}
+}
+
+func TestWithinTran(t *testing.T) {
+	t.Parallel()
+
+	client := testinfra.NewDatabase(t)
+	store := postgresRepo.NewRepository(client)
+
+	now := time.Now()
+	tt := task.Task{
+		Id:          uuid.New(),
+		UserId:      uuid.New(),
+		Command:     "date",
+		Image:       "alpine:3.20",
+		Status:      task.StatusPending,
+		ScheduledAt: now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	//a failed transaction leaves nothing behind, including the outbox message
+	errRollback := errors.New("rollback")
+	err := client.WithinTran(context.Background(), func(tx pgx.Tx) error {
+		txStore := postgresRepo.NewWithTx(tx)
+		msg := task.OutboxMessage{Id: uuid.New(), TaskId: tt.Id, Queue: "queue_tasks", Payload: []byte("{}"), CreatedAt: now}
+		if err := txStore.CreateWithOutbox(context.Background(), tt, msg); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("err= %v, got %v", errRollback, err)
+	}
+
+	if _, err := store.GetById(context.Background(), tt.Id); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("err= %v, got %v", sql.ErrNoRows, err)
+	}
+
+	published, err := store.DrainOutbox(context.Background(), 10, func(msg task.OutboxMessage) error { return nil })
+	if err != nil {
+		t.Fatalf("expected to drain outbox: %s", err)
+	}
+
+	if published != 0 {
+		t.Errorf("published= %d, got %d", 0, published)
+	}
+
+	//a committed one keeps every change
+	err = client.WithinTran(context.Background(), func(tx pgx.Tx) error {
+		txStore := postgresRepo.NewWithTx(tx)
+		if err := txStore.Create(context.Background(), tt); err != nil {
+			return err
+		}
+		return txStore.DeleteByUserId(context.Background(), uuid.New())
+	})
+	if err != nil {
+		t.Fatalf("expected to commit: %s", err)
+	}
+
+	if _, err := store.GetById(context.Background(), tt.Id); err != nil {
+		t.Errorf("expected to find the committed task: %s", err)
+	}
+}
+
+func TestLockDedupKey(t *testing.T) {
+	t.Parallel()
+
+	client := testinfra.NewDatabase(t)
+	store := postgresRepo.NewRepository(client)
+
+	userId := uuid.New()
+	const key = "nightly-report"
+
+	//every request looks the key up and creates the task when it is not taken, only the first one may create it
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- client.WithinTran(context.Background(), func(tx pgx.Tx) error {
+				txStore := postgresRepo.NewWithTx(tx)
+				if err := txStore.LockDedupKey(context.Background(), userId, key); err != nil {
+					return err
+				}
+
+				_, err := txStore.GetByDedupKey(context.Background(), userId, key, time.Now().Add(-time.Hour))
+				if !errors.Is(err, sql.ErrNoRows) {
+					return err
+				}
+
+				now := time.Now()
+				return txStore.Create(context.Background(), task.Task{
+					Id:          uuid.New(),
+					UserId:      userId,
+					Command:     "date",
+					Image:       "alpine:3.20",
+					Status:      task.StatusPending,
+					ScheduledAt: now.Add(time.Hour),
+					CreatedAt:   now,
+					UpdatedAt:   now,
+					DedupKey:    key,
+				})
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("expected to run the transaction: %s", err)
+		}
+	}
+
+	tasks, err := store.GetByUserId(context.Background(), userId, 10, 1, task.OrderBy{Field: task.FieldCreatedAt, Direction: task.DirectionASC})
+	if err != nil {
+		t.Fatalf("expected to get the tasks of the user: %s", err)
+	}
+
+	if len(tasks) != 1 {
+		t.Errorf("tasks= %d, got %d", 1, len(tasks))
+	}
+}
+
+func TestSearch(t *testing.T) {
+	t.Parallel()
+
+	client := testinfra.NewDatabase(t)
+	store := postgresRepo.NewRepository(client)
+
+	userId := uuid.New()
+	now := time.Now()
+
+	tasks := map[string]task.Task{
+		"printed": {Command: "echo", Args: []string{"hello", "world"}, Result: "hello world"},
+		"failed":  {Command: "curl", Args: []string{"http://localhost"}, ErrMessage: "connection refused"},
+		"date":    {Command: "date"},
+	}
+
+	ids := make(map[string]uuid.UUID, len(tasks))
+	for name, tt := range tasks {
+		tt.Id = uuid.New()
+		tt.UserId = userId
+		tt.Image = "alpine:3.20"
+		tt.Status = task.StatusPending
+		tt.ScheduledAt = now
+		tt.CreatedAt = now
+		tt.UpdatedAt = now
+		if err := store.Create(context.Background(), tt); err != nil {
+			t.Fatalf("creating task %s: %s", name, err)
+		}
+		ids[name] = tt.Id
+	}
+
+	//results written by an update are searchable as well
+	updated, err := store.GetById(context.Background(), ids["date"])
+	if err != nil {
+		t.Fatalf("getting task: %s", err)
+	}
+	updated.Result = "Thu Oct 15 2026"
+	if err := store.Update(context.Background(), updated); err != nil {
+		t.Fatalf("updating task: %s", err)
+	}
+
+	queries := map[string]struct {
+		query    string
+		expected []uuid.UUID
+	}{
+		"result":         {query: "world", expected: []uuid.UUID{ids["printed"]}},
+		"error message":  {query: `"connection refused"`, expected: []uuid.UUID{ids["failed"]}},
+		"updated result": {query: "2026", expected: []uuid.UUID{ids["date"]}},
+		"no match":       {query: "nothing", expected: nil},
+	}
+
+	for name, test := range queries {
+		t.Run(name, func(t *testing.T) {
+			found, err := store.Search(context.Background(), userId, test.query, 10, 1)
+			if err != nil {
+				t.Fatalf("expected to search: %s", err)
+			}
+
+			var got []uuid.UUID
+			for _, tsk := range found {
+				got = append(got, tsk.Id)
+			}
+
+			if !slices.Equal(got, test.expected) {
+				t.Errorf("ids= %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestTimings(t *testing.T) {
+	t.Parallel()
+
+	client := testinfra.NewDatabase(t)
+	store := postgresRepo.NewRepository(client)
+
+	now := time.Now()
+	tt := task.Task{
+		Id:          uuid.New(),
+		UserId:      uuid.New(),
+		Command:     "date",
+		Image:       "alpine:3.20",
+		Status:      task.StatusPending,
+		ScheduledAt: now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := store.Create(context.Background(), tt); err != nil {
+		t.Fatalf("creating task: %s", err)
+	}
+
+	fetched, err := store.GetById(context.Background(), tt.Id)
+	if err != nil {
+		t.Fatalf("getting task: %s", err)
+	}
+
+	if !fetched.StartedAt.IsZero() || !fetched.FinishedAt.IsZero() || fetched.Duration() != 0 {
+		t.Errorf("expected a task that never ran to have no timings, got %s-%s", fetched.StartedAt, fetched.FinishedAt)
+	}
+
+	fetched.Status = task.StatusCompleted
+	fetched.StartedAt = now.Add(time.Second * 2)
+	fetched.FinishedAt = now.Add(time.Second * 5)
+	fetched.QueueLatency = time.Second * 2
+	if err := store.Update(context.Background(), fetched); err != nil {
+		t.Fatalf("updating task: %s", err)
+	}
+
+	fetched, err = store.GetById(context.Background(), tt.Id)
+	if err != nil {
+		t.Fatalf("getting task: %s", err)
+	}
+
+	if fetched.QueueLatency != time.Second*2 {
+		t.Errorf("queueLatency= %s, got %s", time.Second*2, fetched.QueueLatency)
+	}
+
+	if fetched.Duration() != time.Second*3 {
+		t.Errorf("duration= %s, got %s", time.Second*3, fetched.Duration())
+	}
+}
+
+func TestClaimDueTasks(t *testing.T) {
+	t.Parallel()
+
+	client := testinfra.NewDatabase(t)
+	store := postgresRepo.NewRepository(client)
+
+	ctx := context.Background()
+	now := time.Now()
+
+	due := task.Task{
+		Id:          uuid.New(),
+		UserId:      uuid.New(),
+		Command:     "date",
+		Image:       "alpine:3.20",
+		Status:      task.StatusPending,
+		ScheduledAt: now.Add(-time.Second),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Version:     1,
+	}
+	later := due
+	later.Id = uuid.New()
+	later.ScheduledAt = now.Add(time.Hour)
+
+	for _, tt := range []task.Task{due, later} {
+		if err := store.Create(ctx, tt); err != nil {
+			t.Fatalf("creating task: %s", err)
+		}
+	}
+
+	claimed, err := store.ClaimDueTasks(ctx, now, now)
+	if err != nil {
+		t.Fatalf("expected to claim due tasks: %s", err)
+	}
+	if len(claimed) != 1 || claimed[0].Id != due.Id {
+		t.Fatalf("expected only task %s to be claimed, got %d tasks", due.Id, len(claimed))
+	}
+	if claimed[0].Status != task.StatusQueued || claimed[0].EnqueuedAt.IsZero() {
+		t.Errorf("status= %s with enqueuedAt, got %s at %s", task.StatusQueued, claimed[0].Status, claimed[0].EnqueuedAt)
+	}
+
+	//a queued task is not claimed twice
+	claimed, err = store.ClaimDueTasks(ctx, now, now)
+	if err != nil {
+		t.Fatalf("expected to claim due tasks: %s", err)
+	}
+	if len(claimed) != 0 {
+		t.Errorf("expected no tasks to be claimed again, got %d", len(claimed))
+	}
+
+	//stuck tasks go back to pending
+	requeued, err := store.RequeueStuck(ctx, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("expected to requeue stuck tasks: %s", err)
+	}
+	if requeued != 1 {
+		t.Errorf("requeued= %d, got %d", 1, requeued)
+	}
+
+	fetched, err := store.GetById(ctx, due.Id)
+	if err != nil {
+		t.Fatalf("expected to get task: %s", err)
+	}
+	if fetched.Status != task.StatusPending || !fetched.EnqueuedAt.IsZero() {
+		t.Errorf("status= %s without enqueuedAt, got %s at %s", task.StatusPending, fetched.Status, fetched.EnqueuedAt)
+	}
+}
+
+func TestGetLate(t *testing.T) {
+	t.Parallel()
+
+	client := testinfra.NewDatabase(t)
+	store := postgresRepo.NewRepository(client)
+
+	ctx := context.Background()
+	now := time.Now()
+
+	late := task.Task{
+		Id:          uuid.New(),
+		UserId:      uuid.New(),
+		Command:     "date",
+		Image:       "alpine:3.20",
+		Status:      task.StatusQueued,
+		ScheduledAt: now.Add(-time.Hour),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Version:     1,
+	}
+	started := late
+	started.Id = uuid.New()
+	onTime := late
+	onTime.Id = uuid.New()
+	onTime.ScheduledAt = now
+	completed := late
+	completed.Id = uuid.New()
+	completed.Status = task.StatusCompleted
+
+	for _, tt := range []task.Task{late, started, onTime, completed} {
+		if err := store.Create(ctx, tt); err != nil {
+			t.Fatalf("creating task: %s", err)
+		}
+	}
+
+	//retries do not write the task, only its started event
+	event := task.Event{Id: uuid.New(), TaskId: started.Id, Kind: task.EventStarted, Source: "test", CreatedAt: now}
+	if err := store.CreateEvent(ctx, event); err != nil {
+		t.Fatalf("creating event: %s", err)
+	}
+
+	tsks, err := store.GetLate(ctx, now.Add(-time.Minute), 10)
+	if err != nil {
+		t.Fatalf("expected to get late tasks: %s", err)
+	}
+	if len(tsks) != 1 || tsks[0].Id != late.Id {
+		t.Fatalf("expected only task %s to be late, got %d tasks", late.Id, len(tsks))
+	}
+
+	//a task is reported late once
+	event = task.Event{Id: uuid.New(), TaskId: late.Id, Kind: task.EventLate, Source: "test", CreatedAt: now}
+	if err := store.CreateEvent(ctx, event); err != nil {
+		t.Fatalf("creating event: %s", err)
+	}
+
+	tsks, err = store.GetLate(ctx, now.Add(-time.Minute), 10)
+	if err != nil {
+		t.Fatalf("expected to get late tasks: %s", err)
+	}
+	if len(tsks) != 0 {
+		t.Errorf("expected no late tasks after reporting them, got %d", len(tsks))
+	}
+}
+
+func TestPartitions(t *testing.T) {
+	t.Parallel()
+
+	client := testinfra.NewDatabase(t)
+	store := postgresRepo.NewRepository(client)
+
+	now := time.Now().UTC()
+	old := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -6, 0)
+
+	created, err := store.CreatePartitions(context.Background(), old, 2)
+	if err != nil {
+		t.Fatalf("creating partitions: %s", err)
+	}
+
+	expected := []string{"tasks_" + old.Format("200601"), "tasks_" + old.AddDate(0, 1, 0).Format("200601")}
+	if !slices.Equal(created, expected) {
+		t.Fatalf("expected created partitions to be %v, but got %v", expected, created)
+	}
+
+	//existing partitions are skipped
+	created, err = store.CreatePartitions(context.Background(), old, 2)
+	if err != nil {
+		t.Fatalf("creating partitions again: %s", err)
+	}
+
+	if len(created) != 0 {
+		t.Fatalf("expected no partitions to be created again, but got %v", created)
+	}
+
+	newTask := func(createdAt time.Time) task.Task {
+		return task.Task{
+			Id:          uuid.New(),
+			UserId:      uuid.New(),
+			Command:     "ls",
+			Image:       "alpine:3.20",
+			Environment: "APP_NAME=test",
+			Status:      task.StatusPending,
+			ScheduledAt: createdAt,
+			CreatedAt:   createdAt,
+			UpdatedAt:   createdAt,
+		}
+	}
+
+	expired := newTask(old.Add(time.Hour))
+	kept := newTask(now)
+	if err := store.CreateMany(context.Background(), []task.Task{expired, kept}); err != nil {
+		t.Fatalf("creating tasks: %s", err)
+	}
+
+	//updates only scan the partition of the task
+	kept.Status = task.StatusCompleted
+	if err := store.Update(context.Background(), kept); err != nil {
+		t.Fatalf("should be able to update a task inside of a partition: %s", err)
+	}
+
+	dropped, err := store.DropPartitions(context.Background(), now.AddDate(0, -3, 0))
+	if err != nil {
+		t.Fatalf("dropping partitions: %s", err)
+	}
+
+	if !slices.Equal(dropped, expected) {
+		t.Fatalf("expected dropped partitions to be %v, but got %v", expected, dropped)
+	}
+
+	if _, err := store.GetById(context.Background(), expired.Id); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected error of an expired task to be %v but got %v", sql.ErrNoRows, err)
+	}
+
+	got, err := store.GetById(context.Background(), kept.Id)
+	if err != nil {
+		t.Fatalf("should keep the tasks of the retention: %s", err)
+	}
+
+	if got.Status != task.StatusCompleted {
+		t.Errorf("expected the Status to be %s, but got %s", task.StatusCompleted, got.Status)
+	}
+}
+
+func TestPartitionPlans(t *testing.T) {
+	t.Parallel()
+
+	client := testinfra.NewDatabase(t)
+	store := postgresRepo.NewRepository(client)
+
+	now := time.Now().UTC()
+	if _, err := store.CreatePartitions(context.Background(), now.AddDate(0, -2, 0), 5); err != nil {
+		t.Fatalf("creating partitions: %s", err)
+	}
+
+	rows, err := client.Pool.Query(context.Background(), `SELECT c.relname FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid WHERE i.inhparent = 'tasks'::regclass`)
+	if err != nil {
+		t.Fatalf("querying partitions: %s", err)
+	}
+
+	partitions, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		t.Fatalf("collecting partitions: %s", err)
+	}
+
+	explain := func(q string, args ...any) string {
+		t.Helper()
+
+		var plan []string
+		err := client.WithinTran(context.Background(), func(tx pgx.Tx) error {
+			//the partitions are empty, a sequential scan would be as cheap as the index otherwise
+			if _, err := tx.Exec(context.Background(), `SET LOCAL enable_seqscan = off`); err != nil {
+				return err
+			}
+
+			rows, err := tx.Query(context.Background(), "EXPLAIN "+q, args...)
+			if err != nil {
+				return err
+			}
+
+			plan, err = pgx.CollectRows(rows, pgx.RowTo[string])
+			return err
+		})
+		if err != nil {
+			t.Fatalf("explaining %q: %s", q, err)
+		}
+		return strings.Join(plan, "\n")
+	}
+
+	//a lookup by id probes the primary key of every partition
+	plan := explain(`SELECT id FROM tasks WHERE id = $1`, uuid.New())
+	if strings.Contains(plan, "Seq Scan") {
+		t.Errorf("expected a lookup by id to use the primary key of the partitions, got\n%s", plan)
+	}
+
+	for _, partition := range partitions {
+		if !strings.Contains(plan, " on "+partition) {
+			t.Errorf("expected a lookup by id to scan %s, got\n%s", partition, plan)
+		}
+	}
+
+	//with created_at only the partition of its month is scanned
+	plan = explain(`SELECT id FROM tasks WHERE id = $1 AND created_at = $2`, uuid.New(), now)
+	for _, partition := range partitions {
+		scanned := strings.Contains(plan, " on "+partition+" ")
+		if expected := partition == "tasks_"+now.Format("200601"); scanned != expected {
+			t.Errorf("expected scanning %s to be %t, got\n%s", partition, expected, plan)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+)
+
+// GetScheduledBetween returns the pending tasks of the user scheduled in [from, to) ordered by their scheduled time.
+func (r *Repository) GetScheduledBetween(ctx context.Context, userId uuid.UUID, from time.Time, to time.Time) ([]task.Task, error) {
+	const q = `
+	SELECT
+		id,user_id,command,args,image,image_digest,floating_tag,environment,status,result,error_msg,scheduled_at,created_at,updated_at,version,started_at,finished_at,queue_latency_ms,max_retries,retry_on,enqueued_at,steps,result_ref,result_size,preset,network_mode,pull_policy,error_category,input,work_dir,run_as_user,dedup_key
+	FROM tasks
+	WHERE user_id = $1 AND status = 'pending' AND scheduled_at >= $2 AND scheduled_at < $3
+	ORDER BY scheduled_at ASC, id ASC
+	`
+
+	//db is in UTC
+	rows, err := r.db.Query(ctx, q, userId, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	tasks, err := scanTasks(rows)
+	if err != nil {
+		return nil, fmt.Errorf("scanTasks: %w", err)
+	}
+	return tasks, nil
+}
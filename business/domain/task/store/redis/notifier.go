@@ -0,0 +1,68 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// finishedChannel is the pub/sub channel the ids of the tasks that finished are published to.
+const finishedChannel = entity + ":finished"
+
+// Notifier represents the redis pub/sub channel that announces the tasks that finished to every instance.
+type Notifier struct {
+	client *redis.Client
+}
+
+// NewNotifier creates a new notifier.
+func NewNotifier(c *redis.Client) *Notifier {
+	return &Notifier{
+		client: c,
+	}
+}
+
+// Publish announces that the task finished.
+func (n *Notifier) Publish(ctx context.Context, taskId uuid.UUID) error {
+	if err := n.client.Publish(ctx, finishedChannel, taskId.String()).Err(); err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+	return nil
+}
+
+// Listen subscribes to the channel and calls fn with every task announced on it until ctx is done, the subscription
+// is restored by the client after a connection failure.
+func (n *Notifier) Listen(ctx context.Context, fn func(taskId uuid.UUID)) error {
+	ps := n.client.Subscribe(ctx, finishedChannel)
+
+	//waits for the confirmation of the subscription
+	if _, err := ps.Receive(ctx); err != nil {
+		ps.Close()
+		return fmt.Errorf("subscribe: %w", err)
+	}
+
+	go func() {
+		defer ps.Close()
+
+		msgs := ps.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-msgs:
+				if !ok {
+					return
+				}
+
+				taskId, err := uuid.Parse(msg.Payload)
+				if err != nil {
+					continue
+				}
+				fn(taskId)
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,81 @@
+// Package redis provides the redis cache of tasks.
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	"github.com/hamidoujand/task-scheduler/business/metrics"
+	"github.com/redis/go-redis/v9"
+)
+
+const entity = "tasks"
+
+// Cache represents all of the APIs used for caching tasks inside of redis.
+type Cache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewCache creates a new redis cache whose entries expire after ttl.
+func NewCache(c *redis.Client, ttl time.Duration) *Cache {
+	return &Cache{
+		client: c,
+		ttl:    ttl,
+	}
+}
+
+// Get returns the cached task, returns false when it is not cached.
+func (c *Cache) Get(ctx context.Context, taskId uuid.UUID) (task.Task, bool, error) {
+	val, err := c.client.Get(ctx, key(taskId)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			metrics.AddCacheMiss(entity)
+			return task.Task{}, false, nil
+		}
+		return task.Task{}, false, fmt.Errorf("get: %w", err)
+	}
+
+	var t task.Task
+	if err := json.Unmarshal(val, &t); err != nil {
+		return task.Task{}, false, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	metrics.AddCacheHit(entity)
+	return t, true, nil
+}
+
+// Set caches the task.
+func (c *Cache) Set(ctx context.Context, t task.Task) error {
+	bs, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	if err := c.client.Set(ctx, key(t.Id), bs, c.ttl).Err(); err != nil {
+		return fmt.Errorf("set: %w", err)
+	}
+	return nil
+}
+
+// Delete drops the tasks from the cache.
+func (c *Cache) Delete(ctx context.Context, taskIds ...uuid.UUID) error {
+	keys := make([]string, len(taskIds))
+	for i, id := range taskIds {
+		keys[i] = key(id)
+	}
+
+	if err := c.client.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("del: %w", err)
+	}
+	return nil
+}
+
+func key(taskId uuid.UUID) string {
+	return entity + ":cache:" + taskId.String()
+}
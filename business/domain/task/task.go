@@ -5,78 +5,235 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/hamidoujand/task-scheduler/business/broker/rabbitmq"
+	"github.com/hamidoujand/task-scheduler/business/broker"
+	"github.com/hamidoujand/task-scheduler/business/broker/messages"
 )
 
 var (
 	ErrTaskNotFound = errors.New("task not found")
+	//ErrVersionConflict is returned by stores when the task was updated by someone else since it was read.
+	ErrVersionConflict = errors.New("task version conflict")
+	//ErrNotFinished is returned when tasks that did not finish are asked to be deleted in bulk.
+	ErrNotFinished = errors.New("only finished tasks can be deleted in bulk")
 )
 
+// maxUpdateAttempts is how many times an update is applied on top of the latest version of a task that keeps
+// changing underneath it.
+const maxUpdateAttempts = 3
+
 // store represents the decoupled store to interact with.
 type store interface {
 	Create(ctx context.Context, task Task) error
+	CreateWithOutbox(ctx context.Context, task Task, msg OutboxMessage) error
+	CreateMany(ctx context.Context, tasks []Task) error
+	CreateManyWithOutbox(ctx context.Context, tasks []Task, msgs []OutboxMessage) error
+	DrainOutbox(ctx context.Context, limit int, publish func(OutboxMessage) error) (int, error)
 	Update(ctx context.Context, task Task) error
+	UpdateMany(ctx context.Context, tasks []Task) ([]uuid.UUID, error)
 	Delete(ctx context.Context, task Task) error
+	DeleteByUserId(ctx context.Context, userId uuid.UUID) error
+	DeleteByUserIdFilter(ctx context.Context, userId uuid.UUID, filter DeleteFilter) ([]Task, error)
+	CancelByUserId(ctx context.Context, userId uuid.UUID, canceledAt time.Time) ([]uuid.UUID, error)
 	GetById(ctx context.Context, taskId uuid.UUID) (Task, error)
+	GetByDedupKey(ctx context.Context, userId uuid.UUID, key string, completedSince time.Time) (Task, error)
+	LockDedupKey(ctx context.Context, userId uuid.UUID, key string) error
+	GetByIds(ctx context.Context, userId uuid.UUID, taskIds []uuid.UUID) ([]StatusSummary, error)
 	GetByUserId(ctx context.Context, userId uuid.UUID, rows int, page int, order OrderBy) ([]Task, error)
+	GetByUserIdAfter(ctx context.Context, userId uuid.UUID, rows int, after *Cursor, dir Direction) ([]Task, error)
+	Search(ctx context.Context, userId uuid.UUID, query string, rows int, page int) ([]Task, error)
 	GetDueTasks(ctx context.Context, from time.Time) ([]Task, error)
+	ClaimDueTasks(ctx context.Context, from time.Time, enqueuedAt time.Time) ([]Task, error)
+	RequeueStuck(ctx context.Context, before time.Time) (int, error)
+	GetLate(ctx context.Context, scheduledBefore time.Time, limit int) ([]Task, error)
+	GetScheduledBetween(ctx context.Context, userId uuid.UUID, from time.Time, to time.Time) ([]Task, error)
+	CountByStatus(ctx context.Context, userId uuid.UUID, status Status) (int, error)
+	GetRecentByStatus(ctx context.Context, status Status, rows int) ([]Task, error)
+	CreateRun(ctx context.Context, run Run) error
+	GetRunsByTaskId(ctx context.Context, taskId uuid.UUID) ([]Run, error)
+	GetRunById(ctx context.Context, runId uuid.UUID) (Run, error)
+	CreateEvent(ctx context.Context, event Event) error
+	GetEventsByTaskId(ctx context.Context, taskId uuid.UUID) ([]Event, error)
 }
 
 // Service represents set of APIs for accessing tasks.
 type Service struct {
 	store   store
-	rClient *rabbitmq.Client
+	broker  broker.Broker
+	inTran  bool
+	results resultOffload
+	cache   cache
+	//waiters are shared by the copies of the service.
+	waiters  *waiters
+	notifier statusNotifier
+	//stale are the tasks changed inside of the transaction, dropped from the cache once it committed.
+	stale []uuid.UUID
 }
 
 // NewService creates *Service and returns it.
-func NewService(store store, rClient *rabbitmq.Client) (*Service, error) {
+func NewService(store store, broker broker.Broker) (*Service, error) {
 	//register queue
-	if err := rClient.DeclareQueue(queue); err != nil {
+	if err := broker.DeclareQueue(queue); err != nil {
 		return nil, fmt.Errorf("declare queue: %w", err)
 	}
 
+	if err := broker.DeclareTopic(EventsExchange); err != nil {
+		return nil, fmt.Errorf("declare events exchange: %w", err)
+	}
+
 	return &Service{
 		store:   store,
-		rClient: rClient,
+		broker:  broker,
+		waiters: newWaiters(),
 	}, nil
 }
 
+// WithTx returns a copy of the service that uses st, st is created with NewWithTx of a store so the calls of the
+// copy run inside of a transaction.
+func (s *Service) WithTx(st store) *Service {
+	svc := *s
+	svc.store = st
+	svc.inTran = true
+	svc.stale = nil
+	return &svc
+}
+
 func (s *Service) CreateTask(ctx context.Context, nt NewTask) (Task, error) {
 	now := time.Now()
+	task := newTask(nt, now)
 
+	//tasks due in less than 1 min are enqueued into rabbitmq through the outbox, the rest by the scheduler
+	if !dueSoon(task, now) {
+		if err := s.store.Create(ctx, task); err != nil {
+			return Task{}, fmt.Errorf("task creation: %w", err)
+		}
+		return task, nil
+	}
+
+	msg, err := enqueue(&task, now)
+	if err != nil {
+		return Task{}, err
+	}
+
+	if err := s.store.CreateWithOutbox(ctx, task, msg); err != nil {
+		return Task{}, fmt.Errorf("task creation: %w", err)
+	}
+
+	//the task is safe at this point, whatever is not relayed now is relayed by the scheduler. Inside of a
+	//transaction the message is not committed yet and is left to the scheduler as well.
+	if !s.inTran {
+		_, _ = s.RelayOutbox(ctx)
+	}
+
+	return task, nil
+}
+
+// CreateTasks creates all of the tasks in a single round trip, either all of them are created or none. Dedup keys
+// are not looked up, callers that need them create the tasks one by one with CreateTaskOnce.
+func (s *Service) CreateTasks(ctx context.Context, nts []NewTask) ([]Task, error) {
+	now := time.Now()
+
+	tasks := make([]Task, len(nts))
+	var msgs []OutboxMessage
+	for i, nt := range nts {
+		tasks[i] = newTask(nt, now)
+		if !dueSoon(tasks[i], now) {
+			continue
+		}
+
+		msg, err := enqueue(&tasks[i], now)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+
+	if len(msgs) == 0 {
+		if err := s.store.CreateMany(ctx, tasks); err != nil {
+			return nil, fmt.Errorf("tasks creation: %w", err)
+		}
+		return tasks, nil
+	}
+
+	if err := s.store.CreateManyWithOutbox(ctx, tasks, msgs); err != nil {
+		return nil, fmt.Errorf("tasks creation: %w", err)
+	}
+
+	if !s.inTran {
+		_, _ = s.RelayOutbox(ctx)
+	}
+
+	return tasks, nil
+}
+
+// newTask returns the pending task of nt.
+func newTask(nt NewTask, now time.Time) Task {
 	task := Task{
 		Id:          uuid.New(),
 		UserId:      nt.UserId,
 		Command:     nt.Command,
 		Args:        nt.Args,
 		Image:       nt.Image,
+		FloatingTag: nt.FloatingTag,
+		Preset:      nt.Preset,
+		NetworkMode: nt.NetworkMode,
+		PullPolicy:  nt.PullPolicy,
+		WorkDir:     nt.WorkDir,
+		RunAsUser:   nt.RunAsUser,
+		MaxRetries:  nt.MaxRetries,
+		RetryOn:     nt.RetryOn,
+		Steps:       nt.Steps,
+		Input:       nt.Input,
+		DedupKey:    nt.DedupKey,
 		Environment: nt.Environment,
 		Status:      StatusPending,
 		ScheduledAt: nt.ScheduledAt,
 		CreatedAt:   now,
 		UpdatedAt:   now,
+		Version:     1,
 	}
 
-	err := s.store.Create(ctx, task)
-	if err != nil {
-		return Task{}, fmt.Errorf("task creation: %w", err)
+	//images referenced by digest are already pinned
+	if strings.Contains(task.Image, "@sha256:") {
+		task.ImageDigest = task.Image
 	}
+	return task
+}
 
-	//now check deadline, less than 1 min will be enqueued into rabbitmq
-	difference := task.ScheduledAt.Sub(now)
-	if difference <= time.Minute {
-		if err := publishTask(s.rClient, task); err != nil {
-			return Task{}, fmt.Errorf("publish: %w", err)
-		}
+// dueSoon reports whether the task is due in less than a minute.
+func dueSoon(task Task, now time.Time) bool {
+	return task.ScheduledAt.Sub(now) <= time.Minute
+}
+
+// enqueue marks the task as queued and returns its outbox message, the outbox publishes it so the monitor does not
+// publish it again.
+func enqueue(task *Task, now time.Time) (OutboxMessage, error) {
+	task.Status = StatusQueued
+	task.EnqueuedAt = now
+
+	bs, err := messages.Marshal(messages.TypeTask, task)
+	if err != nil {
+		return OutboxMessage{}, fmt.Errorf("marshal: %w", err)
 	}
 
-	return task, nil
+	return OutboxMessage{
+		Id:        uuid.New(),
+		TaskId:    task.Id,
+		Queue:     queue,
+		Payload:   bs,
+		CreatedAt: now,
+	}, nil
 }
 
 func (s *Service) GetTaskById(ctx context.Context, taskId uuid.UUID) (Task, error) {
+	if task, ok := s.cached(ctx, taskId); ok {
+		return task, nil
+	}
+
 	task, err := s.store.GetById(ctx, taskId)
 	if err != nil {
 		//some issue or not found
@@ -86,18 +243,250 @@ func (s *Service) GetTaskById(ctx context.Context, taskId uuid.UUID) (Task, erro
 		return Task{}, fmt.Errorf("get task by id: %w", err)
 	}
 
+	s.setCached(ctx, task)
 	return task, nil
 }
 
+// GetStatusesByIds returns the statuses of the tasks of the user among taskIds in a single read, the ids of tasks
+// that do not exist or belong to someone else are left out.
+func (s *Service) GetStatusesByIds(ctx context.Context, userId uuid.UUID, taskIds []uuid.UUID) ([]StatusSummary, error) {
+	statuses, err := s.store.GetByIds(ctx, userId, taskIds)
+	if err != nil {
+		return nil, fmt.Errorf("get by ids: %w", err)
+	}
+	return statuses, nil
+}
+
+// GetTaskByDedupKey returns the task of the user with the dedup key that did not finish yet or completed within the
+// window, in case there is none will return ErrTaskNotFound.
+func (s *Service) GetTaskByDedupKey(ctx context.Context, userId uuid.UUID, key string, window time.Duration) (Task, error) {
+	task, err := s.store.GetByDedupKey(ctx, userId, key, time.Now().Add(-window))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Task{}, ErrTaskNotFound
+		}
+		return Task{}, fmt.Errorf("get by dedup key: %w", err)
+	}
+	return task, nil
+}
+
+// CreateTaskOnce creates the task unless the user has a task with its dedup key that did not finish yet or completed
+// within the window, that task is returned instead and created is false. Inside of a transaction the lookup and the
+// creation are serialized per key, so requests that race with the same key create a single task.
+func (s *Service) CreateTaskOnce(ctx context.Context, nt NewTask, window time.Duration) (task Task, created bool, err error) {
+	if err := s.store.LockDedupKey(ctx, nt.UserId, nt.DedupKey); err != nil {
+		return Task{}, false, fmt.Errorf("lock dedup key: %w", err)
+	}
+
+	existing, err := s.GetTaskByDedupKey(ctx, nt.UserId, nt.DedupKey, window)
+	if err == nil {
+		return existing, false, nil
+	}
+	if !errors.Is(err, ErrTaskNotFound) {
+		return Task{}, false, err
+	}
+
+	task, err = s.CreateTask(ctx, nt)
+	if err != nil {
+		return Task{}, false, err
+	}
+	return task, true, nil
+}
+
 func (s *Service) DeleteTask(ctx context.Context, task Task) error {
 	if err := s.store.Delete(ctx, task); err != nil {
 		return fmt.Errorf("delete task: %w", err)
 	}
+
+	s.invalidate(ctx, task.Id)
+	s.deleteResult(ctx, task)
+	s.announce(ctx, task.Id)
+	return nil
+}
+
+// DeleteTasksByUserId deletes all of the tasks of the user.
+func (s *Service) DeleteTasksByUserId(ctx context.Context, userId uuid.UUID) error {
+	if err := s.store.DeleteByUserId(ctx, userId); err != nil {
+		return fmt.Errorf("delete by user id: %w", err)
+	}
 	return nil
 }
 
+// DeleteFinishedTasks deletes the finished tasks of the user that match the filter and returns how many, the
+// statuses of the filter must be finished ones otherwise ErrNotFinished is returned.
+func (s *Service) DeleteFinishedTasks(ctx context.Context, userId uuid.UUID, filter DeleteFilter) (int, error) {
+	if len(filter.Statuses) == 0 {
+		filter.Statuses = []Status{StatusFailed, StatusCompleted, StatusCanceled}
+	}
+
+	for _, status := range filter.Statuses {
+		if !status.Finished() {
+			return 0, ErrNotFinished
+		}
+	}
+
+	deleted, err := s.store.DeleteByUserIdFilter(ctx, userId, filter)
+	if err != nil {
+		return 0, fmt.Errorf("delete by user id filter: %w", err)
+	}
+
+	ids := make([]uuid.UUID, len(deleted))
+	for i, tsk := range deleted {
+		ids[i] = tsk.Id
+		s.deleteResult(ctx, tsk)
+	}
+	s.invalidate(ctx, ids...)
+
+	return len(deleted), nil
+}
+
+// CancelTasksByUserId cancels the pending and queued tasks of the user and returns how many, canceled tasks that
+// were already published are dropped by the workers that receive them. The cancellation is recorded on the timeline
+// of every task.
+func (s *Service) CancelTasksByUserId(ctx context.Context, userId uuid.UUID) (int, error) {
+	ids, err := s.store.CancelByUserId(ctx, userId, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("cancel by user id: %w", err)
+	}
+
+	for _, id := range ids {
+		ne := NewEvent{
+			TaskId:  id,
+			Kind:    EventCanceled,
+			Message: "canceled along with the tasks of the user",
+			Source:  EventSourceAPI,
+		}
+		if _, err := s.CreateEvent(ctx, ne); err != nil {
+			return 0, err
+		}
+	}
+
+	s.invalidate(ctx, ids...)
+	s.announce(ctx, ids...)
+	return len(ids), nil
+}
+
+// UpdateTask applies the update to the task, when the task was updated by someone else in between the update is
+// applied again on top of its latest version.
 func (s *Service) UpdateTask(ctx context.Context, task Task, ut UpdateTask) (Task, error) {
+	if err := s.offloadResult(ctx, task.Id, &ut); err != nil {
+		return Task{}, err
+	}
+	return s.update(ctx, task, ut)
+}
 
+// UpdateTasks applies the updates in a single round trip and returns their errors in the same order, an update of a
+// task that was updated by someone else in between is applied again on its own like UpdateTask does.
+func (s *Service) UpdateTasks(ctx context.Context, updates []BatchUpdate) []error {
+	//the offloaded results are kept on a copy, the updates of the caller stay as they are
+	updates = slices.Clone(updates)
+	errs := make([]error, len(updates))
+
+	var (
+		tasks []Task
+		//batched is the index of the update of every task inside of tasks.
+		batched []int
+		//later updates of a task that is already inside of the batch are applied on their own.
+		single []int
+		seen   = make(map[uuid.UUID]bool, len(updates))
+	)
+
+	for i := range updates {
+		if err := s.offloadResult(ctx, updates[i].Task.Id, &updates[i].Update); err != nil {
+			errs[i] = err
+			continue
+		}
+
+		if seen[updates[i].Task.Id] {
+			single = append(single, i)
+			continue
+		}
+		seen[updates[i].Task.Id] = true
+
+		tasks = append(tasks, applyUpdate(updates[i].Task, updates[i].Update))
+		batched = append(batched, i)
+	}
+
+	ids := make([]uuid.UUID, len(tasks))
+	for i, tsk := range tasks {
+		ids[i] = tsk.Id
+	}
+
+	conflicts, err := s.store.UpdateMany(ctx, tasks)
+
+	//the cached tasks are stale whether or not the update went through
+	s.invalidate(ctx, ids...)
+
+	if err != nil {
+		for _, i := range batched {
+			errs[i] = fmt.Errorf("updating tasks: %w", err)
+		}
+		return errs
+	}
+
+	conflicted := make(map[uuid.UUID]bool, len(conflicts))
+	for _, id := range conflicts {
+		conflicted[id] = true
+	}
+
+	var finished []uuid.UUID
+	for j, i := range batched {
+		if conflicted[tasks[j].Id] {
+			single = append(single, i)
+			continue
+		}
+
+		if tasks[j].Status.Finished() {
+			finished = append(finished, tasks[j].Id)
+		}
+	}
+	s.announce(ctx, finished...)
+
+	for _, i := range single {
+		latest, err := s.GetTaskById(ctx, updates[i].Task.Id)
+		if err != nil {
+			errs[i] = fmt.Errorf("reading latest version: %w", err)
+			continue
+		}
+
+		if _, err := s.update(ctx, latest, updates[i].Update); err != nil {
+			errs[i] = err
+		}
+	}
+
+	return errs
+}
+
+// update applies the update whose result is already offloaded, see UpdateTask.
+func (s *Service) update(ctx context.Context, task Task, ut UpdateTask) (Task, error) {
+	for attempt := 1; ; attempt++ {
+		updated := applyUpdate(task, ut)
+
+		err := s.store.Update(ctx, updated)
+
+		//on a conflict the cached task is stale as well
+		s.invalidate(ctx, task.Id)
+
+		if err == nil {
+			updated.Version++
+			if updated.Status.Finished() {
+				s.announce(ctx, updated.Id)
+			}
+			return updated, nil
+		}
+
+		if !errors.Is(err, ErrVersionConflict) || attempt == maxUpdateAttempts {
+			return Task{}, fmt.Errorf("updating task: %w", err)
+		}
+
+		task, err = s.GetTaskById(ctx, task.Id)
+		if err != nil {
+			return Task{}, fmt.Errorf("reading latest version: %w", err)
+		}
+	}
+}
+
+func applyUpdate(task Task, ut UpdateTask) Task {
 	if ut.Status != nil {
 		task.Status = *ut.Status
 	}
@@ -106,17 +495,38 @@ func (s *Service) UpdateTask(ctx context.Context, task Task, ut UpdateTask) (Tas
 		task.ErrMessage = *ut.ErrMessage
 	}
 
+	if ut.ErrCategory != nil {
+		task.ErrCategory = *ut.ErrCategory
+	}
+
 	if ut.Result != nil {
 		task.Result = *ut.Result
 	}
 
-	task.UpdatedAt = time.Now()
+	if ut.ResultSize != nil {
+		task.ResultSize = *ut.ResultSize
+	}
 
-	if err := s.store.Update(ctx, task); err != nil {
-		return Task{}, fmt.Errorf("updating task: %w", err)
+	if ut.resultRef != nil {
+		task.ResultRef = *ut.resultRef
 	}
 
-	return task, nil
+	if ut.ImageDigest != nil {
+		task.ImageDigest = *ut.ImageDigest
+	}
+
+	if ut.Steps != nil {
+		task.Steps = ut.Steps
+	}
+
+	if ut.Timings != nil {
+		task.StartedAt = ut.Timings.StartedAt
+		task.FinishedAt = ut.Timings.FinishedAt
+		task.QueueLatency = ut.Timings.QueueLatency
+	}
+
+	task.UpdatedAt = time.Now()
+	return task
 }
 
 // GetTaskByUserId queries all of the taks belong to a user and retunrs them or possible error.
@@ -128,6 +538,33 @@ func (s *Service) GetTasksByUserId(ctx context.Context, userId uuid.UUID, rowsPe
 	return tasks, nil
 }
 
+// GetTasksByUserIdAfter returns up to rows tasks of the user that come after the cursor in the order of their
+// creation, a nil cursor starts from the first task. The returned token points to the next page and is empty on the
+// last one.
+func (s *Service) GetTasksByUserIdAfter(ctx context.Context, userId uuid.UUID, rows int, after *Cursor, dir Direction) ([]Task, string, error) {
+	//one extra row tells whether there is a next page
+	tasks, err := s.store.GetByUserIdAfter(ctx, userId, rows+1, after, dir)
+	if err != nil {
+		return nil, "", fmt.Errorf("getByUserIdAfter: %w", err)
+	}
+
+	if len(tasks) <= rows {
+		return tasks, "", nil
+	}
+
+	tasks = tasks[:rows]
+	return tasks, CursorOf(tasks[rows-1]).Encode(), nil
+}
+
+// SearchTasks returns the tasks of the user whose command, args, result or error message match the free text query.
+func (s *Service) SearchTasks(ctx context.Context, userId uuid.UUID, query string, rowsPerPage int, page int) ([]Task, error) {
+	tasks, err := s.store.Search(ctx, userId, query, rowsPerPage, page)
+	if err != nil {
+		return nil, fmt.Errorf("search: %w", err)
+	}
+	return tasks, nil
+}
+
 // GetAllDueTasks fetches all of the tasks from repo that have less than 1 min to their
 // scheduled time.
 func (s *Service) GetAllDueTasks(ctx context.Context) ([]Task, error) {
@@ -138,3 +575,68 @@ func (s *Service) GetAllDueTasks(ctx context.Context) ([]Task, error) {
 	}
 	return tsks, nil
 }
+
+// ClaimDueTasks marks the pending tasks that are due as queued and returns them, the caller publishes them. A task
+// is only claimed once, tasks that never finish are put back by RequeueStuckTasks.
+func (s *Service) ClaimDueTasks(ctx context.Context) ([]Task, error) {
+	now := time.Now()
+	tsks, err := s.store.ClaimDueTasks(ctx, now, now)
+	if err != nil {
+		return nil, fmt.Errorf("claim due tasks: %w", err)
+	}
+
+	ids := make([]uuid.UUID, len(tsks))
+	for i, tsk := range tsks {
+		ids[i] = tsk.Id
+	}
+	s.invalidate(ctx, ids...)
+
+	return tsks, nil
+}
+
+// RequeueStuckTasks puts the tasks that are queued for longer than timeout back to pending so they are claimed
+// again, for example because publishing them failed or the instance that ran them died. Returns how many.
+func (s *Service) RequeueStuckTasks(ctx context.Context, timeout time.Duration) (int, error) {
+	requeued, err := s.store.RequeueStuck(ctx, time.Now().Add(-timeout))
+	if err != nil {
+		return 0, fmt.Errorf("requeue stuck: %w", err)
+	}
+	return requeued, nil
+}
+
+// GetLateTasks returns up to limit tasks that are still pending or queued and never started although they were due
+// more than after ago, the oldest first. Tasks that were already reported by an EventLate are left out.
+func (s *Service) GetLateTasks(ctx context.Context, after time.Duration, limit int) ([]Task, error) {
+	tasks, err := s.store.GetLate(ctx, time.Now().Add(-after), limit)
+	if err != nil {
+		return nil, fmt.Errorf("get late: %w", err)
+	}
+	return tasks, nil
+}
+
+// GetUpcomingTasks returns the pending tasks of the user scheduled in [from, to) ordered by their scheduled time.
+func (s *Service) GetUpcomingTasks(ctx context.Context, userId uuid.UUID, from time.Time, to time.Time) ([]Task, error) {
+	tasks, err := s.store.GetScheduledBetween(ctx, userId, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("get scheduled between: %w", err)
+	}
+	return tasks, nil
+}
+
+// RecentFailures returns the last rows tasks of all of the users that failed, the latest first.
+func (s *Service) RecentFailures(ctx context.Context, rows int) ([]Task, error) {
+	tasks, err := s.store.GetRecentByStatus(ctx, StatusFailed, rows)
+	if err != nil {
+		return nil, fmt.Errorf("get recent by status: %w", err)
+	}
+	return tasks, nil
+}
+
+// CountTasks returns the number of tasks of the user with the given status.
+func (s *Service) CountTasks(ctx context.Context, userId uuid.UUID, status Status) (int, error) {
+	count, err := s.store.CountByStatus(ctx, userId, status)
+	if err != nil {
+		return 0, fmt.Errorf("count by status: %w", err)
+	}
+	return count, nil
+}
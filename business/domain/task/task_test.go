@@ -4,13 +4,18 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/broker/messages"
 	"github.com/hamidoujand/task-scheduler/business/brokertest"
 	"github.com/hamidoujand/task-scheduler/business/domain/task"
 	"github.com/hamidoujand/task-scheduler/business/domain/task/store/memory"
+	"github.com/hamidoujand/task-scheduler/business/testinfra"
+	"github.com/hamidoujand/task-scheduler/foundation/blob"
 )
 
 func TestCreateTask(t *testing.T) {
@@ -19,7 +24,7 @@ func TestCreateTask(t *testing.T) {
 		Tasks: make(map[uuid.UUID]task.Task),
 	}
 
-	rClient := brokertest.NewTestClient(t, context.Background(), "test_create_task")
+	rClient := testinfra.NewRabbitMQ(t)
 
 	service, err := task.NewService(&store, rClient)
 	if err != nil {
@@ -41,8 +46,13 @@ func TestCreateTask(t *testing.T) {
 		t.Fatalf("expected the task to be saved: %s", err)
 	}
 
-	if tsk.Status != task.StatusPending {
-		t.Errorf("expected status to be %q, but got %q", task.StatusPending, tsk.Status)
+	//due within a minute, so it is published through the outbox right away
+	if tsk.Status != task.StatusQueued {
+		t.Errorf("expected status to be %q, but got %q", task.StatusQueued, tsk.Status)
+	}
+
+	if tsk.EnqueuedAt.IsZero() {
+		t.Error("expected the enqueuedAt field to not be zero value")
 	}
 
 	if tsk.Image != nt.Image {
@@ -76,7 +86,7 @@ func TestCreateTask(t *testing.T) {
 	}
 
 	var queueTask task.Task
-	if err := json.Unmarshal(d.Body, &queueTask); err != nil {
+	if err := messages.Unmarshal(d.Body, messages.TypeTask, &queueTask); err != nil {
 		t.Fatalf("expected to unmarshal task: %s", err)
 	}
 
@@ -89,6 +99,89 @@ func TestCreateTask(t *testing.T) {
 	}
 }
 
+// failingBatchStore fails every batch insert like a batch with a row the database rejects.
+type failingBatchStore struct {
+	*memory.Repository
+}
+
+func (s failingBatchStore) CreateMany(ctx context.Context, tsks []task.Task) error {
+	return errors.New("violates check constraint")
+}
+
+func (s failingBatchStore) CreateManyWithOutbox(ctx context.Context, tsks []task.Task, msgs []task.OutboxMessage) error {
+	return errors.New("violates check constraint")
+}
+
+func TestCreateTasks(t *testing.T) {
+	t.Parallel()
+
+	userId := uuid.New()
+	nts := []task.NewTask{
+		{Command: "date", Image: "alpine", UserId: userId, ScheduledAt: time.Now()},
+		{Command: "ls", Image: "alpine", UserId: userId, ScheduledAt: time.Now().Add(time.Hour)},
+		{Command: "pwd", Image: "alpine", UserId: userId, ScheduledAt: time.Now().Add(time.Second)},
+	}
+
+	store := memory.Repository{Tasks: make(map[uuid.UUID]task.Task)}
+	b := brokertest.NewMemoryClient(t)
+	service, err := task.NewService(&store, b)
+	if err != nil {
+		t.Fatalf("expected to create service: %s", err)
+	}
+
+	tsks, err := service.CreateTasks(context.Background(), nts)
+	if err != nil {
+		t.Fatalf("expected to create the tasks: %s", err)
+	}
+
+	if len(tsks) != len(nts) || len(store.Tasks) != len(nts) {
+		t.Fatalf("tasks= %d, got %d returned and %d stored", len(nts), len(tsks), len(store.Tasks))
+	}
+
+	//the tasks keep the order of the batch, the due ones are enqueued right away
+	for i, tsk := range tsks {
+		if tsk.Command != nts[i].Command {
+			t.Errorf("tasks[%d].command= %s, got %s", i, nts[i].Command, tsk.Command)
+		}
+	}
+
+	if tsks[0].Status != task.StatusQueued || tsks[1].Status != task.StatusPending || tsks[2].Status != task.StatusQueued {
+		t.Errorf("expected the due tasks to be queued, got %s, %s and %s", tsks[0].Status, tsks[1].Status, tsks[2].Status)
+	}
+
+	stats, err := b.QueueStats("queue_tasks")
+	if err != nil {
+		t.Fatalf("expected to get the stats of the tasks queue: %s", err)
+	}
+	if stats.Messages != 2 {
+		t.Errorf("messages= %d, got %d", 2, stats.Messages)
+	}
+
+	//a batch the store rejects leaves neither tasks nor messages behind
+	failing := failingBatchStore{&memory.Repository{Tasks: make(map[uuid.UUID]task.Task)}}
+	fb := brokertest.NewMemoryClient(t)
+	service, err = task.NewService(failing, fb)
+	if err != nil {
+		t.Fatalf("expected to create service: %s", err)
+	}
+
+	if _, err := service.CreateTasks(context.Background(), nts); err == nil {
+		t.Fatal("expected the batch to fail")
+	}
+
+	if len(failing.Tasks) != 0 {
+		t.Errorf("tasks= %d, got %d", 0, len(failing.Tasks))
+	}
+
+	stats, err = fb.QueueStats("queue_tasks")
+	if err != nil {
+		t.Fatalf("expected to get the stats of the tasks queue: %s", err)
+	}
+	if stats.Messages != 0 {
+		t.Errorf("messages= %d, got %d", 0, stats.Messages)
+	}
+}
+
 func TestGetTaskById(t *testing.T) {
 	t.Parallel()
 
@@ -114,7 +207,7 @@ func TestGetTaskById(t *testing.T) {
 		},
 	}
 
-	rClient := brokertest.NewTestClient(t, context.Background(), "test_get_task_by_id")
+	rClient := brokertest.NewMemoryClient(t)
 
 	service, err := task.NewService(&store, rClient)
 	if err != nil {
@@ -178,7 +271,7 @@ func TestDeleteTask(t *testing.T) {
 		},
 	}
 
-	rClient := brokertest.NewTestClient(t, context.Background(), "test_delete_task")
+	rClient := brokertest.NewMemoryClient(t)
 
 	service, err := task.NewService(&store, rClient)
 	if err != nil {
@@ -223,7 +316,7 @@ func TestUpdateTask(t *testing.T) {
 		},
 	}
 
-	rClient := brokertest.NewTestClient(t, context.Background(), "test_update_task")
+	rClient := brokertest.NewMemoryClient(t)
 
 	service, err := task.NewService(&store, rClient)
 	if err != nil {
@@ -256,6 +349,232 @@ func TestUpdateTask(t *testing.T) {
 	}
 }
 
+func TestWaitForTask(t *testing.T) {
+	t.Parallel()
+
+	id := uuid.New()
+	now := time.Now()
+	store := memory.Repository{
+		Tasks: map[uuid.UUID]task.Task{
+			id: {
+				Id:          id,
+				Command:     "date",
+				Status:      task.StatusQueued,
+				ScheduledAt: now,
+				CreatedAt:   now,
+				UpdatedAt:   now,
+			},
+		},
+	}
+
+	service, err := task.NewService(&store, brokertest.NewMemoryClient(t))
+	if err != nil {
+		t.Fatalf("expected to create service: %s", err)
+	}
+
+	//a task that does not finish is returned as is once the timeout elapses
+	tsk, err := service.WaitForTask(context.Background(), id, time.Millisecond*50)
+	if err != nil {
+		t.Fatalf("expected to wait for the task: %s", err)
+	}
+
+	if tsk.Status != task.StatusQueued {
+		t.Errorf("status= %s, got %s", task.StatusQueued, tsk.Status)
+	}
+
+	//a timeout of zero reads the task once instead of failing on the elapsed timeout
+	tsk, err = service.WaitForTask(context.Background(), id, 0)
+	if err != nil {
+		t.Fatalf("expected to read the task: %s", err)
+	}
+
+	if tsk.Status != task.StatusQueued {
+		t.Errorf("status= %s, got %s", task.StatusQueued, tsk.Status)
+	}
+
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		status := task.StatusCompleted
+		if _, err := service.UpdateTask(context.Background(), tsk, task.UpdateTask{Status: &status}); err != nil {
+			t.Errorf("should be able to update the task: %s", err)
+		}
+	}()
+
+	//the update wakes the waiter up long before the poll interval
+	started := time.Now()
+	tsk, err = service.WaitForTask(context.Background(), id, time.Minute)
+	if err != nil {
+		t.Fatalf("expected to wait for the task: %s", err)
+	}
+
+	if tsk.Status != task.StatusCompleted {
+		t.Errorf("status= %s, got %s", task.StatusCompleted, tsk.Status)
+	}
+
+	if took := time.Since(started); took > time.Second {
+		t.Errorf("expected the waiter to be woken up, took %s", took)
+	}
+
+	_, err = service.WaitForTask(context.Background(), uuid.New(), time.Second)
+	if !errors.Is(err, task.ErrTaskNotFound) {
+		t.Errorf("err= %v, got %v", task.ErrTaskNotFound, err)
+	}
+
+	//a task deleted while waiting is not found instead of returned empty once the timeout elapses
+	pendingId := uuid.New()
+	pending := task.Task{
+		Id:          pendingId,
+		Command:     "date",
+		Status:      task.StatusQueued,
+		ScheduledAt: now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := store.Create(context.Background(), pending); err != nil {
+		t.Fatalf("expected to create the task: %s", err)
+	}
+
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		if err := service.DeleteTask(context.Background(), pending); err != nil {
+			t.Errorf("should be able to delete the task: %s", err)
+		}
+	}()
+
+	tsk, err = service.WaitForTask(context.Background(), pendingId, time.Minute)
+	if !errors.Is(err, task.ErrTaskNotFound) {
+		t.Errorf("err= %v, got %v with status %q", task.ErrTaskNotFound, err, tsk.Status)
+	}
+}
+
+func TestResultOffload(t *testing.T) {
+	t.Parallel()
+
+	id := uuid.New()
+	now := time.Now()
+	store := memory.Repository{
+		Tasks: map[uuid.UUID]task.Task{
+			id: {
+				Id:          id,
+				Command:     "cat",
+				Status:      task.StatusPending,
+				ScheduledAt: now.Add(time.Hour * 2),
+				CreatedAt:   now,
+				UpdatedAt:   now,
+			},
+		},
+	}
+
+	rClient := brokertest.NewMemoryClient(t)
+
+	service, err := task.NewService(&store, rClient)
+	if err != nil {
+		t.Fatalf("expected to create service: %s", err)
+	}
+
+	blobs, err := blob.NewDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("expected to create blob store: %s", err)
+	}
+	service.EnableResultOffload(blobs, 8)
+
+	tsk, err := service.GetTaskById(context.Background(), id)
+	if err != nil {
+		t.Fatalf("should be able to find the task by id: %s", err)
+	}
+
+	result := "more than eight bytes"
+	tsk, err = service.UpdateTask(context.Background(), tsk, task.UpdateTask{Result: &result})
+	if err != nil {
+		t.Fatalf("should be able to update the task: %s", err)
+	}
+
+	stored, err := service.GetTaskById(context.Background(), id)
+	if err != nil {
+		t.Fatalf("should be able to find the task by id: %s", err)
+	}
+
+	if stored.Result != "" || stored.ResultRef == "" {
+		t.Fatalf("expected the result to be offloaded, got result %q and ref %q", stored.Result, stored.ResultRef)
+	}
+
+	loaded, err := service.LoadResult(context.Background(), stored)
+	if err != nil {
+		t.Fatalf("should be able to load the result: %s", err)
+	}
+
+	if loaded.Result != result {
+		t.Errorf("result= %q, got %q", result, loaded.Result)
+	}
+
+	//small results stay inline
+	small := "small"
+	tsk, err = service.UpdateTask(context.Background(), tsk, task.UpdateTask{Result: &small})
+	if err != nil {
+		t.Fatalf("should be able to update the task: %s", err)
+	}
+
+	if tsk.Result != small || tsk.ResultRef != "" {
+		t.Errorf("expected the result to be inline, got result %q and ref %q", tsk.Result, tsk.ResultRef)
+	}
+}
+
+func TestUpdateTaskVersionConflict(t *testing.T) {
+	t.Parallel()
+
+	id := uuid.New()
+	now := time.Now()
+	store := memory.Repository{
+		Tasks: map[uuid.UUID]task.Task{
+			id: {
+				Id:          id,
+				Command:     "date",
+				Status:      task.StatusPending,
+				ScheduledAt: now,
+				CreatedAt:   now,
+				UpdatedAt:   now,
+				Version:     1,
+			},
+		},
+	}
+
+	rClient := brokertest.NewMemoryClient(t)
+
+	service, err := task.NewService(&store, rClient)
+	if err != nil {
+		t.Fatalf("expected to create service: %s", err)
+	}
+
+	stale, err := service.GetTaskById(context.Background(), id)
+	if err != nil {
+		t.Fatalf("should be able to find the task by id: %s", err)
+	}
+
+	digest := "alpine@sha256:0a4eaa0eecf5f8c050e5bba433f58c052be7587ee8af3e8b3910ef9ab5fbe9f5"
+	if _, err := service.UpdateTask(context.Background(), stale, task.UpdateTask{ImageDigest: &digest}); err != nil {
+		t.Fatalf("should be able to update the task: %s", err)
+	}
+
+	//the stale copy is applied on top of the latest version instead of overwriting the digest
+	status := task.StatusCompleted
+	updated, err := service.UpdateTask(context.Background(), stale, task.UpdateTask{Status: &status})
+	if err != nil {
+		t.Fatalf("should be able to update the stale task: %s", err)
+	}
+
+	if updated.ImageDigest != digest {
+		t.Errorf("imageDigest= %s, got %s", digest, updated.ImageDigest)
+	}
+
+	if updated.Status != status {
+		t.Errorf("status= %s, got %s", status, updated.Status)
+	}
+
+	if updated.Version != 3 {
+		t.Errorf("version= %d, got %d", 3, updated.Version)
+	}
+}
+
 func TestGetTasksByUserId(t *testing.T) {
 	t.Parallel()
 
@@ -288,7 +607,7 @@ func TestGetTasksByUserId(t *testing.T) {
 		},
 	}
 
-	rClient := brokertest.NewTestClient(t, context.Background(), "test_tasks_by_user_id")
+	rClient := brokertest.NewMemoryClient(t)
 
 	service, err := task.NewService(&store, rClient)
 	if err != nil {
@@ -360,6 +679,142 @@ func TestParseField(t *testing.T) {
 
 }
 
+func TestRetryable(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		retryOn  []int
+		code     int
+		exited   bool
+		expected bool
+	}{
+		"no codes": {
+			code:     1,
+			exited:   true,
+			expected: true,
+		},
+		"no codes without exit code": {
+			expected: true,
+		},
+		"listed code": {
+			retryOn:  []int{1, 137},
+			code:     137,
+			exited:   true,
+			expected: true,
+		},
+		"unlisted code": {
+			retryOn:  []int{1, 137},
+			code:     2,
+			exited:   true,
+			expected: false,
+		},
+		"without exit code": {
+			retryOn:  []int{1},
+			expected: false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tsk := task.Task{RetryOn: test.retryOn}
+			if got := tsk.Retryable(test.code, test.exited); got != test.expected {
+				t.Errorf("retryable= %t, got %t", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestNetworkPolicy(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		policy   task.NetworkPolicy
+		mode     task.NetworkMode
+		expected task.NetworkMode
+		allowed  bool
+	}{
+		"zero policy": {
+			expected: task.NetworkNone,
+			allowed:  true,
+		},
+		"zero policy with mode": {
+			mode:     task.NetworkEgress,
+			expected: task.NetworkEgress,
+			allowed:  false,
+		},
+		"default mode": {
+			policy:   task.NetworkPolicy{Default: task.NetworkInternal},
+			mode:     task.NetworkInternal,
+			expected: task.NetworkInternal,
+			allowed:  true,
+		},
+		"allowed mode": {
+			policy:   task.NetworkPolicy{Allowed: []task.NetworkMode{task.NetworkEgress}},
+			mode:     task.NetworkEgress,
+			expected: task.NetworkEgress,
+			allowed:  true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mode := test.policy.Mode(test.mode)
+			if mode != test.expected {
+				t.Errorf("mode= %s, got %s", test.expected, mode)
+			}
+
+			if got := test.policy.Allows(mode); got != test.allowed {
+				t.Errorf("allows= %t, got %t", test.allowed, got)
+			}
+		})
+	}
+
+	if _, err := task.ParseNetworkPolicy("none", []string{"host"}); err == nil {
+		t.Error("expected to not parse an unknown network mode")
+	}
+}
+
+func TestSecurityProfileValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		profile task.SecurityProfile
+		valid   bool
+	}{
+		"zero profile": {
+			valid: true,
+		},
+		"hardened": {
+			profile: task.SecurityProfile{
+				ReadOnly:        true,
+				CapDropAll:      true,
+				NoNewPrivileges: true,
+				User:            "65534:65534",
+				Tmpfs:           "/tmp",
+				TmpfsSizeMB:     64,
+			},
+			valid: true,
+		},
+		"relative tmpfs": {
+			profile: task.SecurityProfile{Tmpfs: "tmp"},
+		},
+		"tmpfs with options": {
+			profile: task.SecurityProfile{Tmpfs: "/tmp:exec"},
+		},
+		"user with whitespace": {
+			profile: task.SecurityProfile{User: "root --privileged"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := test.profile.Validate(); (err == nil) != test.valid {
+				t.Errorf("valid= %t, got %v", test.valid, err)
+			}
+		})
+	}
+}
+
 func TestParseDirection(t *testing.T) {
 	t.Parallel()
 
@@ -402,3 +857,331 @@ func TestParseDirection(t *testing.T) {
 	}
 
 }
+
+func TestGetTasksByUserIdAfter(t *testing.T) {
+	t.Parallel()
+
+	userId := uuid.New()
+	now := time.Now()
+
+	store := memory.Repository{
+		Tasks: make(map[uuid.UUID]task.Task),
+	}
+
+	var ids []uuid.UUID
+	for i := range 5 {
+		id := uuid.New()
+		store.Tasks[id] = task.Task{
+			Id:        id,
+			UserId:    userId,
+			Command:   "date",
+			Status:    task.StatusPending,
+			CreatedAt: now.Add(time.Duration(i) * time.Second),
+		}
+		ids = append(ids, id)
+	}
+
+	//another user
+	other := uuid.New()
+	store.Tasks[other] = task.Task{Id: other, UserId: uuid.New(), CreatedAt: now}
+
+	rClient := brokertest.NewMemoryClient(t)
+
+	service, err := task.NewService(&store, rClient)
+	if err != nil {
+		t.Fatalf("expected to create service: %s", err)
+	}
+
+	var got []uuid.UUID
+	var after *task.Cursor
+	pages := 0
+	for {
+		tasks, next, err := service.GetTasksByUserIdAfter(context.Background(), userId, 2, after, task.DirectionASC)
+		if err != nil {
+			t.Fatalf("expected to get a page: %s", err)
+		}
+		pages++
+
+		for _, tsk := range tasks {
+			got = append(got, tsk.Id)
+		}
+
+		if next == "" {
+			break
+		}
+
+		cursor, err := task.ParseCursor(next)
+		if err != nil {
+			t.Fatalf("expected to parse the next cursor: %s", err)
+		}
+		after = &cursor
+	}
+
+	if pages != 3 {
+		t.Errorf("pages= %d, got %d", 3, pages)
+	}
+
+	if !slices.Equal(got, ids) {
+		t.Errorf("ids= %v, got %v", ids, got)
+	}
+
+	if _, err := task.ParseCursor("not-a-cursor"); !errors.Is(err, task.ErrInvalidCursor) {
+		t.Errorf("err= %v, got %v", task.ErrInvalidCursor, err)
+	}
+}
+
+func TestSetResult(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		output string
+		limit  int
+		result string
+	}{
+		"unlimited": {
+			output: strings.Repeat("a", 100),
+			limit:  0,
+			result: strings.Repeat("a", 100),
+		},
+		"under the limit": {
+			output: "hello",
+			limit:  100,
+			result: "hello",
+		},
+		"over the limit": {
+			output: strings.Repeat("a", 100),
+			limit:  50,
+			result: strings.Repeat("a", 16) + "\n[truncated: output was 100 bytes]",
+		},
+		"multi-byte character": {
+			output: strings.Repeat("é", 50),
+			limit:  50,
+			result: strings.Repeat("é", 8) + "\n[truncated: output was 100 bytes]",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var tsk task.Task
+			tsk.SetResult(test.output, test.limit)
+
+			if tsk.Result != test.result {
+				t.Errorf("result= %q, got %q", test.result, tsk.Result)
+			}
+
+			if test.limit > 0 && len(tsk.Result) > test.limit {
+				t.Errorf("expected the result to be at most %d bytes, got %d", test.limit, len(tsk.Result))
+			}
+
+			if tsk.ResultSize != len(test.output) {
+				t.Errorf("resultSize= %d, got %d", len(test.output), tsk.ResultSize)
+			}
+		})
+	}
+}
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+
+	id := uuid.New()
+	now := time.Now()
+	store := memory.Repository{
+		Tasks: map[uuid.UUID]task.Task{
+			id: {
+				Id:          id,
+				Command:     "docker",
+				Status:      task.StatusPending,
+				ScheduledAt: now.Add(time.Hour * 2),
+				CreatedAt:   now,
+				UpdatedAt:   now,
+				Version:     1,
+			},
+		},
+	}
+
+	service, err := task.NewService(&store, brokertest.NewMemoryClient(t))
+	if err != nil {
+		t.Fatalf("expected to create service: %s", err)
+	}
+
+	cache := mapCache{}
+	service.EnableCache(cache)
+
+	tsk, err := service.GetTaskById(context.Background(), id)
+	if err != nil {
+		t.Fatalf("should be able to find the task by id: %s", err)
+	}
+
+	if _, ok := cache[id]; !ok {
+		t.Fatal("expected the task to be cached after the first read")
+	}
+
+	status := task.StatusCompleted
+	if _, err := service.UpdateTask(context.Background(), tsk, task.UpdateTask{Status: &status}); err != nil {
+		t.Fatalf("should be able to update the task: %s", err)
+	}
+
+	if _, ok := cache[id]; ok {
+		t.Error("expected the task to be dropped from the cache on update")
+	}
+
+	tsk, err = service.GetTaskById(context.Background(), id)
+	if err != nil {
+		t.Fatalf("should be able to find the task by id: %s", err)
+	}
+
+	if tsk.Status != status {
+		t.Errorf("status= %s, got %s", status, tsk.Status)
+	}
+
+	//inside of a transaction the tasks are dropped once it committed, a read in between caches the old rows again
+	pending := task.Task{
+		Id:          uuid.New(),
+		UserId:      uuid.New(),
+		Command:     "docker",
+		Status:      task.StatusPending,
+		ScheduledAt: now.Add(time.Hour * 2),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Version:     1,
+	}
+	store.Tasks[pending.Id] = pending
+
+	if _, err := service.GetTaskById(context.Background(), pending.Id); err != nil {
+		t.Fatalf("should be able to find the task by id: %s", err)
+	}
+
+	tx := service.WithTx(&store)
+	if _, err := tx.CancelTasksByUserId(context.Background(), pending.UserId); err != nil {
+		t.Fatalf("should be able to cancel the tasks of the user: %s", err)
+	}
+
+	if _, ok := cache[pending.Id]; !ok {
+		t.Error("expected the task to stay cached until the transaction committed")
+	}
+
+	tx.Committed(context.Background())
+	if _, ok := cache[pending.Id]; ok {
+		t.Error("expected the task to be dropped from the cache once the transaction committed")
+	}
+
+	if err := service.DeleteTask(context.Background(), tsk); err != nil {
+		t.Fatalf("should be able to delete the task: %s", err)
+	}
+
+	if _, err := service.GetTaskById(context.Background(), id); !errors.Is(err, task.ErrTaskNotFound) {
+		t.Errorf("err= %v, got %v", task.ErrTaskNotFound, err)
+	}
+}
+
+// mapCache is a cache of tasks inside of a map.
+type mapCache map[uuid.UUID]task.Task
+
+func (c mapCache) Get(ctx context.Context, taskId uuid.UUID) (task.Task, bool, error) {
+	tsk, ok := c[taskId]
+	return tsk, ok, nil
+}
+
+func (c mapCache) Set(ctx context.Context, tsk task.Task) error {
+	c[tsk.Id] = tsk
+	return nil
+}
+
+func (c mapCache) Delete(ctx context.Context, taskIds ...uuid.UUID) error {
+	for _, id := range taskIds {
+		delete(c, id)
+	}
+	return nil
+}
+
+func TestRecentFailures(t *testing.T) {
+	t.Parallel()
+	store := memory.Repository{
+		Tasks: make(map[uuid.UUID]task.Task),
+	}
+
+	now := time.Now()
+	statuses := []task.Status{task.StatusFailed, task.StatusCompleted, task.StatusFailed, task.StatusFailed}
+	for i, status := range statuses {
+		tsk := task.Task{Id: uuid.New(), UserId: uuid.New(), Status: status, UpdatedAt: now.Add(time.Duration(i) * time.Minute)}
+		store.Tasks[tsk.Id] = tsk
+	}
+
+	service, err := task.NewService(&store, brokertest.NewMemoryClient(t))
+	if err != nil {
+		t.Fatalf("expected to create service: %s", err)
+	}
+
+	failures, err := service.RecentFailures(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("expected to get the recent failures: %s", err)
+	}
+
+	if len(failures) != 2 {
+		t.Fatalf("len(failures)= %d, got %d", 2, len(failures))
+	}
+
+	//the latest first
+	if !failures[0].UpdatedAt.Equal(now.Add(3*time.Minute)) || !failures[1].UpdatedAt.Equal(now.Add(2*time.Minute)) {
+		t.Errorf("expected the last two failures, got %v and %v", failures[0].UpdatedAt, failures[1].UpdatedAt)
+	}
+}
+
+func TestPublishEvents(t *testing.T) {
+	t.Parallel()
+
+	store := memory.Repository{
+		Tasks: make(map[uuid.UUID]task.Task),
+	}
+
+	b := brokertest.NewMemoryClient(t)
+	service, err := task.NewService(&store, b)
+	if err != nil {
+		t.Fatalf("expected to create service: %s", err)
+	}
+
+	//an external consumer of the failures only
+	if err := b.DeclareQueue("queue_failures"); err != nil {
+		t.Fatalf("expected to declare queue: %s", err)
+	}
+
+	if err := b.BindQueue("queue_failures", task.EventsExchange, "task.failed"); err != nil {
+		t.Fatalf("expected to bind queue: %s", err)
+	}
+
+	taskId := uuid.New()
+	for _, kind := range []task.EventKind{task.EventCreated, task.EventFailed, task.EventCompleted} {
+		ne := task.NewEvent{TaskId: taskId, Kind: kind, Message: "exit code 1", Source: task.EventSourceAPI}
+		if _, err := service.CreateEvent(context.Background(), ne); err != nil {
+			t.Fatalf("expected to create %s event: %s", kind, err)
+		}
+	}
+
+	msgs, err := b.Consume("queue_failures", "")
+	if err != nil {
+		t.Fatalf("expected to consume: %s", err)
+	}
+
+	d := <-msgs
+	var msg task.EventMessage
+	if err := json.Unmarshal(d.Body, &msg); err != nil {
+		t.Fatalf("expected to unmarshal event: %s", err)
+	}
+
+	if msg.TaskId != taskId || msg.Kind != task.EventFailed {
+		t.Errorf("expected the failed event of task %s, got %s of %s", taskId, msg.Kind, msg.TaskId)
+	}
+
+	stats, err := b.QueueStats("queue_failures")
+	if err != nil {
+		t.Fatalf("expected to get the stats: %s", err)
+	}
+
+	if stats.Messages != 0 {
+		t.Errorf("expected only the failed event to be routed, got %d more", stats.Messages)
+	}
+
+	if got := task.EventCompleted.RoutingKey(); got != "task.succeeded" {
+		t.Errorf("routing key= %s, got %s", "task.succeeded", got)
+	}
+}
@@ -0,0 +1,142 @@
+package task
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// waitPollInterval is how often a waiting task is read again while there is no notifier shared by the instances,
+// with one it is only read again every waitSafetyInterval in case a notification got lost.
+const (
+	waitPollInterval   = time.Second * 2
+	waitSafetyInterval = time.Second * 30
+)
+
+// statusNotifier represents what announces the tasks that finished to every instance.
+type statusNotifier interface {
+	Publish(ctx context.Context, taskId uuid.UUID) error
+	//Listen calls fn with the tasks announced by any instance until ctx is done.
+	Listen(ctx context.Context, fn func(taskId uuid.UUID)) error
+}
+
+// waiters represents the requests of this instance that wait for tasks to finish.
+type waiters struct {
+	mu    sync.Mutex
+	chans map[uuid.UUID]map[chan struct{}]struct{}
+}
+
+func newWaiters() *waiters {
+	return &waiters{chans: make(map[uuid.UUID]map[chan struct{}]struct{})}
+}
+
+func (w *waiters) add(taskId uuid.UUID) (chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.chans[taskId] == nil {
+		w.chans[taskId] = make(map[chan struct{}]struct{})
+	}
+	w.chans[taskId][ch] = struct{}{}
+
+	remove := func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		delete(w.chans[taskId], ch)
+		if len(w.chans[taskId]) == 0 {
+			delete(w.chans, taskId)
+		}
+	}
+	return ch, remove
+}
+
+// wake tells the waiters of the task to read it again.
+func (w *waiters) wake(taskId uuid.UUID) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for ch := range w.chans[taskId] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// EnableStatusNotifier shares the tasks that finished on this instance with the other ones through n until ctx is
+// done, without it only the requests of the same instance are woken up right away and the others notice within a
+// few seconds.
+func (s *Service) EnableStatusNotifier(ctx context.Context, n statusNotifier) error {
+	if err := n.Listen(ctx, s.waiters.wake); err != nil {
+		return err
+	}
+	s.notifier = n
+	return nil
+}
+
+// announce wakes up the requests waiting for the tasks, a lost notification only delays them. Changes inside of a
+// transaction are not visible yet so they are left to polling.
+func (s *Service) announce(ctx context.Context, taskIds ...uuid.UUID) {
+	if s.inTran {
+		return
+	}
+
+	for _, id := range taskIds {
+		s.waiters.wake(id)
+		if s.notifier != nil {
+			_ = s.notifier.Publish(ctx, id)
+		}
+	}
+}
+
+// WaitForTask returns the task once it finished or the timeout elapsed, whichever comes first. The task is returned
+// as is after the timeout, so its status tells the two apart, and a timeout of zero reads it once. A task deleted
+// while waiting returns ErrTaskNotFound.
+func (s *Service) WaitForTask(ctx context.Context, taskId uuid.UUID, timeout time.Duration) (Task, error) {
+	//registered before the first read so a task that finishes in between is not missed
+	woken, remove := s.waiters.add(taskId)
+	defer remove()
+
+	task, err := s.GetTaskById(ctx, taskId)
+	if err != nil || task.Status.Finished() || timeout <= 0 {
+		return task, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	interval := waitPollInterval
+	if s.notifier != nil {
+		interval = waitSafetyInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return task, nil
+		case <-woken:
+		case <-ticker.C:
+		}
+
+		latest, err := s.GetTaskById(ctx, taskId)
+		if err != nil {
+			//the timeout elapsed during the read
+			if ctx.Err() != nil {
+				return task, nil
+			}
+			return Task{}, err
+		}
+
+		task = latest
+		if task.Status.Finished() {
+			return task, nil
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Package memory provides an in memory repository used for testing.
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/usage"
+)
+
+// Repository represents an in-memory storage for testing.
+type Repository struct {
+	Records []usage.Record
+	Budgets map[uuid.UUID]usage.Budget
+	mu      sync.Mutex
+}
+
+// Create adds the usage of an execution, recording the same run twice keeps the first record.
+func (r *Repository) Create(ctx context.Context, rec usage.Record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.Records {
+		if existing.RunId == rec.RunId {
+			return nil
+		}
+	}
+	r.Records = append(r.Records, rec)
+	return nil
+}
+
+// GetMonthly aggregates the usage of the user per month in [from, to), a nil userId aggregates every user.
+func (r *Repository) GetMonthly(ctx context.Context, userId uuid.UUID, from time.Time, to time.Time) ([]usage.Monthly, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	type key struct {
+		userId uuid.UUID
+		month  time.Time
+	}
+
+	monthly := make(map[key]usage.Monthly)
+	for _, rec := range r.Records {
+		if userId != uuid.Nil && rec.UserId != userId {
+			continue
+		}
+		if rec.CreatedAt.Before(from) || !rec.CreatedAt.Before(to) {
+			continue
+		}
+
+		k := key{userId: rec.UserId, month: usage.MonthOf(rec.CreatedAt)}
+		m := monthly[k]
+		m.UserId = k.userId
+		m.Month = k.month
+		m.Executions++
+		m.CPU += rec.CPU
+		m.MemoryPeak = max(m.MemoryPeak, rec.MemoryPeak)
+		m.Duration += rec.Duration
+		monthly[k] = m
+	}
+
+	results := make([]usage.Monthly, 0, len(monthly))
+	for _, m := range monthly {
+		results = append(results, m)
+	}
+
+	slices.SortFunc(results, func(a, b usage.Monthly) int {
+		if c := a.Month.Compare(b.Month); c != 0 {
+			return c
+		}
+		return slices.Compare(a.UserId[:], b.UserId[:])
+	})
+	return results, nil
+}
+
+// GetConsumed returns the execution time the user consumed during the month.
+func (r *Repository) GetConsumed(ctx context.Context, userId uuid.UUID, month time.Time) (time.Duration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var consumed time.Duration
+	for _, rec := range r.Records {
+		if rec.UserId == userId && usage.MonthOf(rec.CreatedAt).Equal(month) {
+			consumed += rec.Duration
+		}
+	}
+	return consumed, nil
+}
+
+// GetBudget returns the budget of the user or sql.ErrNoRows.
+func (r *Repository) GetBudget(ctx context.Context, userId uuid.UUID) (usage.Budget, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, ok := r.Budgets[userId]
+	if !ok {
+		return usage.Budget{}, sql.ErrNoRows
+	}
+	return b, nil
+}
+
+// UpsertBudget creates or replaces the budget of the user.
+func (r *Repository) UpsertBudget(ctx context.Context, b usage.Budget) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Budgets == nil {
+		r.Budgets = make(map[uuid.UUID]usage.Budget)
+	}
+	r.Budgets[b.UserId] = b
+	return nil
+}
@@ -0,0 +1,169 @@
+// Package postgres provides the usage storage on top of postgres.
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/database/postgres"
+	"github.com/hamidoujand/task-scheduler/business/domain/usage"
+)
+
+// Repository represents all of the APIs used for CRUD against postgres.
+type Repository struct {
+	client *postgres.Client
+}
+
+// NewRepository creates a new postgres repository.
+func NewRepository(client *postgres.Client) *Repository {
+	return &Repository{
+		client: client,
+	}
+}
+
+// Create inserts the usage of an execution and adds its duration to the consumption of the month, recording the
+// same run twice keeps the first record.
+func (r *Repository) Create(ctx context.Context, rec usage.Record) error {
+	const q = `
+	WITH inserted AS (
+		INSERT INTO task_usage
+			(run_id,task_id,user_id,cpu_ms,memory_peak_bytes,duration_ms,created_at)
+		VALUES
+			($1,$2,$3,$4,$5,$6,$7)
+		ON CONFLICT (run_id) DO NOTHING
+		RETURNING user_id, created_at, duration_ms
+	)
+	INSERT INTO usage_consumption
+		(user_id,month,duration_ms)
+	SELECT
+		user_id, date_trunc('month', created_at), duration_ms
+	FROM
+		inserted
+	ON CONFLICT (user_id, month) DO UPDATE SET
+		duration_ms = usage_consumption.duration_ms + EXCLUDED.duration_ms
+	`
+
+	if _, err := r.client.Pool.Exec(ctx, q,
+		rec.RunId,
+		rec.TaskId,
+		rec.UserId,
+		rec.CPU.Milliseconds(),
+		rec.MemoryPeak,
+		rec.Duration.Milliseconds(),
+		rec.CreatedAt.UTC(),
+	); err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+	return nil
+}
+
+// GetMonthly aggregates the usage of the user per month in [from, to), a nil userId aggregates every user.
+func (r *Repository) GetMonthly(ctx context.Context, userId uuid.UUID, from time.Time, to time.Time) ([]usage.Monthly, error) {
+	const q = `
+	SELECT
+		user_id,
+		date_trunc('month', created_at) AS month,
+		count(*),
+		sum(cpu_ms)::bigint,
+		max(memory_peak_bytes),
+		sum(duration_ms)::bigint
+	FROM
+		task_usage
+	WHERE
+		($1 = '00000000-0000-0000-0000-000000000000'::uuid OR user_id = $1) AND created_at >= $2 AND created_at < $3
+	GROUP BY
+		user_id, month
+	ORDER BY
+		month, user_id
+	`
+
+	//db is in UTC
+	rows, err := r.client.Pool.Query(ctx, q, userId, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	var results []usage.Monthly
+	for rows.Next() {
+		var m usage.Monthly
+		var cpuMs, durationMs int64
+		if err := rows.Scan(&m.UserId, &m.Month, &m.Executions, &cpuMs, &m.MemoryPeak, &durationMs); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+
+		m.Month = time.Date(m.Month.Year(), m.Month.Month(), 1, 0, 0, 0, 0, time.UTC)
+		m.CPU = time.Duration(cpuMs) * time.Millisecond
+		m.Duration = time.Duration(durationMs) * time.Millisecond
+		results = append(results, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+	return results, nil
+}
+
+// GetConsumed returns the execution time the user consumed during the month.
+func (r *Repository) GetConsumed(ctx context.Context, userId uuid.UUID, month time.Time) (time.Duration, error) {
+	const q = `
+	SELECT
+		COALESCE(sum(duration_ms), 0)::bigint
+	FROM
+		usage_consumption
+	WHERE
+		user_id = $1 AND month = $2
+	`
+
+	var durationMs int64
+	if err := r.client.Pool.QueryRow(ctx, q, userId, month.UTC()).Scan(&durationMs); err != nil {
+		return 0, fmt.Errorf("row scan: %w", err)
+	}
+	return time.Duration(durationMs) * time.Millisecond, nil
+}
+
+// GetBudget returns the budget of the user, returns sql.ErrNoRows when there is no record.
+func (r *Repository) GetBudget(ctx context.Context, userId uuid.UUID) (usage.Budget, error) {
+	const q = `
+	SELECT
+		user_id,limit_ms,hard,updated_at
+	FROM user_budgets
+	WHERE user_id = $1
+	`
+
+	var b usage.Budget
+	var limitMs int64
+	if err := r.client.Pool.QueryRow(ctx, q, userId).Scan(
+		&b.UserId,
+		&limitMs,
+		&b.Hard,
+		&b.UpdatedAt,
+	); err != nil {
+		return usage.Budget{}, fmt.Errorf("row scan: %w", postgres.NoRows(err))
+	}
+
+	b.Limit = time.Duration(limitMs) * time.Millisecond
+	b.UpdatedAt = b.UpdatedAt.In(time.Local)
+	return b, nil
+}
+
+// UpsertBudget creates or replaces the budget of the user.
+func (r *Repository) UpsertBudget(ctx context.Context, b usage.Budget) error {
+	const q = `
+	INSERT INTO user_budgets
+		(user_id,limit_ms,hard,updated_at)
+	VALUES
+		($1,$2,$3,$4)
+	ON CONFLICT (user_id) DO UPDATE SET
+		limit_ms = EXCLUDED.limit_ms,
+		hard = EXCLUDED.hard,
+		updated_at = EXCLUDED.updated_at
+	`
+
+	if _, err := r.client.Pool.Exec(ctx, q, b.UserId, b.Limit.Milliseconds(), b.Hard, b.UpdatedAt.UTC()); err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+	return nil
+}
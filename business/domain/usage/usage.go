@@ -0,0 +1,214 @@
+// Package usage provides the accounting of the resources consumed by the executions of tasks.
+package usage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidRange  = errors.New("usage range must end after it starts")
+	ErrInvalidBudget = errors.New("budget limit must be greater or equal to 0")
+)
+
+// WarningRatio is the share of its budget a user consumed from which on creating a task warns.
+const WarningRatio = 0.8
+
+// store represents the storage of the usage records and budgets, GetBudget returns sql.ErrNoRows when the user has
+// no budget.
+type store interface {
+	Create(ctx context.Context, r Record) error
+	GetMonthly(ctx context.Context, userId uuid.UUID, from time.Time, to time.Time) ([]Monthly, error)
+	GetConsumed(ctx context.Context, userId uuid.UUID, month time.Time) (time.Duration, error)
+	GetBudget(ctx context.Context, userId uuid.UUID) (Budget, error)
+	UpsertBudget(ctx context.Context, b Budget) error
+}
+
+// Record represents the resources consumed by a single execution of a task, CPU and MemoryPeak are zero when the
+// runtime could not measure them.
+type Record struct {
+	RunId      uuid.UUID
+	TaskId     uuid.UUID
+	UserId     uuid.UUID
+	CPU        time.Duration
+	MemoryPeak int64
+	Duration   time.Duration
+	CreatedAt  time.Time
+}
+
+// NewRecord represents all of the required info for recording the usage of an execution.
+type NewRecord struct {
+	RunId      uuid.UUID
+	TaskId     uuid.UUID
+	UserId     uuid.UUID
+	CPU        time.Duration
+	MemoryPeak int64
+	Duration   time.Duration
+}
+
+// Monthly represents the usage of a user aggregated over a calendar month in UTC, MemoryPeak is the highest peak of
+// a single execution.
+type Monthly struct {
+	UserId     uuid.UUID
+	Month      time.Time
+	Executions int
+	CPU        time.Duration
+	MemoryPeak int64
+	Duration   time.Duration
+}
+
+// Budget represents the execution time a user may consume per month, a zero limit means unlimited. Once a hard
+// budget is used up the user can not create tasks until the next month, a soft one only warns.
+type Budget struct {
+	UserId    uuid.UUID
+	Limit     time.Duration
+	Hard      bool
+	UpdatedAt time.Time
+}
+
+// UpdateBudget represents the parts of a budget that can be updated.
+type UpdateBudget struct {
+	Limit *time.Duration
+	Hard  *bool
+}
+
+// BudgetStatus represents how much of their budget a user consumed during Month.
+type BudgetStatus struct {
+	Budget
+	Month    time.Time
+	Consumed time.Duration
+}
+
+// Exceeded reports whether the budget is used up.
+func (bs BudgetStatus) Exceeded() bool {
+	return bs.Limit > 0 && bs.Consumed >= bs.Limit
+}
+
+// Warning reports whether at least WarningRatio of the budget is used up.
+func (bs BudgetStatus) Warning() bool {
+	return bs.Limit > 0 && float64(bs.Consumed) >= float64(bs.Limit)*WarningRatio
+}
+
+// Ratio returns the share of the budget that is used up, zero for an unlimited budget.
+func (bs BudgetStatus) Ratio() float64 {
+	if bs.Limit <= 0 {
+		return 0
+	}
+	return float64(bs.Consumed) / float64(bs.Limit)
+}
+
+// Service represents set of APIs for accounting usage.
+type Service struct {
+	store store
+}
+
+// NewService creates a usage service.
+func NewService(store store) *Service {
+	return &Service{
+		store: store,
+	}
+}
+
+// Record stores the usage of an execution.
+func (s *Service) Record(ctx context.Context, nr NewRecord) (Record, error) {
+	r := Record{
+		RunId:      nr.RunId,
+		TaskId:     nr.TaskId,
+		UserId:     nr.UserId,
+		CPU:        nr.CPU,
+		MemoryPeak: nr.MemoryPeak,
+		Duration:   nr.Duration,
+		CreatedAt:  time.Now(),
+	}
+
+	if err := s.store.Create(ctx, r); err != nil {
+		return Record{}, fmt.Errorf("create: %w", err)
+	}
+	return r, nil
+}
+
+// GetMonthly returns the usage of the user for every month between the month of from and the month of to, both
+// included, oldest first. Months without executions are left out and a nil userId returns the usage of every user.
+func (s *Service) GetMonthly(ctx context.Context, userId uuid.UUID, from time.Time, to time.Time) ([]Monthly, error) {
+	from, to = MonthOf(from), MonthOf(to).AddDate(0, 1, 0)
+	if !to.After(from) {
+		return nil, ErrInvalidRange
+	}
+
+	monthly, err := s.store.GetMonthly(ctx, userId, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("get monthly: %w", err)
+	}
+	return monthly, nil
+}
+
+// GetBudget returns the budget of the user, users without a budget are unlimited and get a hard budget once a limit
+// is set.
+func (s *Service) GetBudget(ctx context.Context, userId uuid.UUID) (Budget, error) {
+	b, err := s.store.GetBudget(ctx, userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Budget{UserId: userId, Hard: true}, nil
+		}
+		return Budget{}, fmt.Errorf("get budget: %w", err)
+	}
+	return b, nil
+}
+
+// SetBudget updates the budget of the user.
+func (s *Service) SetBudget(ctx context.Context, userId uuid.UUID, ub UpdateBudget) (Budget, error) {
+	b, err := s.GetBudget(ctx, userId)
+	if err != nil {
+		return Budget{}, err
+	}
+
+	if ub.Limit != nil {
+		b.Limit = *ub.Limit
+	}
+
+	if ub.Hard != nil {
+		b.Hard = *ub.Hard
+	}
+
+	if b.Limit < 0 {
+		return Budget{}, ErrInvalidBudget
+	}
+
+	b.UpdatedAt = time.Now()
+	if err := s.store.UpsertBudget(ctx, b); err != nil {
+		return Budget{}, fmt.Errorf("upsert budget: %w", err)
+	}
+	return b, nil
+}
+
+// GetBudgetStatus returns the budget of the user along with the execution time they consumed during the current
+// month. The consumption of the executions still running is added once they finish.
+func (s *Service) GetBudgetStatus(ctx context.Context, userId uuid.UUID) (BudgetStatus, error) {
+	b, err := s.GetBudget(ctx, userId)
+	if err != nil {
+		return BudgetStatus{}, err
+	}
+
+	month := MonthOf(time.Now())
+	consumed, err := s.store.GetConsumed(ctx, userId, month)
+	if err != nil {
+		return BudgetStatus{}, fmt.Errorf("get consumed: %w", err)
+	}
+
+	return BudgetStatus{
+		Budget:   b,
+		Month:    month,
+		Consumed: consumed,
+	}, nil
+}
+
+// MonthOf returns the first instant of the month of t in UTC.
+func MonthOf(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
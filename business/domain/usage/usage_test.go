@@ -0,0 +1,136 @@
+package usage_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/usage"
+	"github.com/hamidoujand/task-scheduler/business/domain/usage/store/memory"
+)
+
+func TestMonthly(t *testing.T) {
+	t.Parallel()
+
+	repo := memory.Repository{}
+	service := usage.NewService(&repo)
+	ctx := context.Background()
+
+	alice, bob := uuid.New(), uuid.New()
+	march := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	april := time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)
+
+	repo.Records = []usage.Record{
+		{RunId: uuid.New(), UserId: alice, CPU: time.Second, MemoryPeak: 100, Duration: time.Second * 2, CreatedAt: march},
+		{RunId: uuid.New(), UserId: alice, CPU: time.Second * 3, MemoryPeak: 300, Duration: time.Second * 4, CreatedAt: march},
+		{RunId: uuid.New(), UserId: alice, CPU: time.Second, MemoryPeak: 50, Duration: time.Second, CreatedAt: april},
+		{RunId: uuid.New(), UserId: bob, CPU: time.Second, MemoryPeak: 10, Duration: time.Second, CreatedAt: march},
+	}
+
+	//a run is recorded once
+	if _, err := service.Record(ctx, usage.NewRecord{RunId: repo.Records[0].RunId, UserId: alice, CPU: time.Hour}); err != nil {
+		t.Fatalf("expected to record usage: %s", err)
+	}
+
+	monthly, err := service.GetMonthly(ctx, alice, march, march)
+	if err != nil {
+		t.Fatalf("expected to get monthly usage: %s", err)
+	}
+
+	if len(monthly) != 1 {
+		t.Fatalf("len(monthly)= %d, got %d", 1, len(monthly))
+	}
+
+	m := monthly[0]
+	if !m.Month.Equal(usage.MonthOf(march)) || m.Executions != 2 {
+		t.Errorf("month= %s with %d executions, got %s with %d", usage.MonthOf(march), 2, m.Month, m.Executions)
+	}
+
+	if m.CPU != time.Second*4 || m.Duration != time.Second*6 || m.MemoryPeak != 300 {
+		t.Errorf("cpu/duration/peak= %s/%s/%d, got %s/%s/%d", time.Second*4, time.Second*6, 300, m.CPU, m.Duration, m.MemoryPeak)
+	}
+
+	//every user, both months
+	monthly, err = service.GetMonthly(ctx, uuid.Nil, march, april)
+	if err != nil {
+		t.Fatalf("expected to get monthly usage: %s", err)
+	}
+
+	if len(monthly) != 3 {
+		t.Errorf("len(monthly)= %d, got %d", 3, len(monthly))
+	}
+
+	if _, err := service.GetMonthly(ctx, alice, april, march); !errors.Is(err, usage.ErrInvalidRange) {
+		t.Errorf("err= %v, got %v", usage.ErrInvalidRange, err)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	t.Parallel()
+
+	repo := memory.Repository{}
+	service := usage.NewService(&repo)
+	ctx := context.Background()
+
+	alice := uuid.New()
+
+	//without a budget nothing is exceeded
+	status, err := service.GetBudgetStatus(ctx, alice)
+	if err != nil {
+		t.Fatalf("expected to get budget status: %s", err)
+	}
+
+	if status.Limit != 0 || !status.Hard || status.Exceeded() || status.Warning() {
+		t.Errorf("expected an unlimited hard budget, got %+v", status.Budget)
+	}
+
+	limit := time.Minute
+	if _, err := service.SetBudget(ctx, alice, usage.UpdateBudget{Limit: &limit}); err != nil {
+		t.Fatalf("expected to set budget: %s", err)
+	}
+
+	if _, err := service.Record(ctx, usage.NewRecord{RunId: uuid.New(), UserId: alice, Duration: time.Second * 50}); err != nil {
+		t.Fatalf("expected to record usage: %s", err)
+	}
+
+	status, err = service.GetBudgetStatus(ctx, alice)
+	if err != nil {
+		t.Fatalf("expected to get budget status: %s", err)
+	}
+
+	if status.Consumed != time.Second*50 || !status.Warning() || status.Exceeded() {
+		t.Errorf("expected a warning for %s of %s, got consumed %s warning %t exceeded %t", time.Second*50, limit,
+			status.Consumed, status.Warning(), status.Exceeded())
+	}
+
+	if _, err := service.Record(ctx, usage.NewRecord{RunId: uuid.New(), UserId: alice, Duration: time.Second * 10}); err != nil {
+		t.Fatalf("expected to record usage: %s", err)
+	}
+
+	status, err = service.GetBudgetStatus(ctx, alice)
+	if err != nil {
+		t.Fatalf("expected to get budget status: %s", err)
+	}
+
+	if !status.Exceeded() {
+		t.Errorf("expected budget to be exceeded with %s of %s", status.Consumed, limit)
+	}
+
+	//a soft budget keeps its limit
+	soft := false
+	b, err := service.SetBudget(ctx, alice, usage.UpdateBudget{Hard: &soft})
+	if err != nil {
+		t.Fatalf("expected to set budget: %s", err)
+	}
+
+	if b.Hard || b.Limit != limit {
+		t.Errorf("expected a soft budget of %s, got hard %t of %s", limit, b.Hard, b.Limit)
+	}
+
+	negative := -time.Second
+	if _, err := service.SetBudget(ctx, alice, usage.UpdateBudget{Limit: &negative}); !errors.Is(err, usage.ErrInvalidBudget) {
+		t.Errorf("err= %v, got %v", usage.ErrInvalidBudget, err)
+	}
+}
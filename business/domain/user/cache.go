@@ -0,0 +1,67 @@
+package user
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// cache represents a read-through cache of users in front of the repository.
+type cache interface {
+	Get(ctx context.Context, userId uuid.UUID) (User, bool, error)
+	Set(ctx context.Context, usr User) error
+	Delete(ctx context.Context, userId uuid.UUID) error
+}
+
+// EnableCache makes GetUserById read through c, a user is dropped from it whenever they change through the service.
+func (s *Service) EnableCache(c cache) {
+	s.cache = c
+}
+
+// cached returns the user from the cache, the cache is best effort so its errors are treated as a miss. Reads inside
+// of a transaction always go to the repository.
+func (s *Service) cached(ctx context.Context, userId uuid.UUID) (User, bool) {
+	if s.cache == nil || s.inTran {
+		return User{}, false
+	}
+
+	usr, ok, err := s.cache.Get(ctx, userId)
+	if err != nil {
+		return User{}, false
+	}
+	return usr, ok
+}
+
+func (s *Service) setCached(ctx context.Context, usr User) {
+	if s.cache == nil || s.inTran {
+		return
+	}
+	_ = s.cache.Set(ctx, usr)
+}
+
+// invalidate drops the user from the cache, a failure leaves them stale until they expire. Inside of a transaction
+// the user is only dropped by Committed, a read before the commit would cache the old row again.
+func (s *Service) invalidate(ctx context.Context, userId uuid.UUID) {
+	if s.cache == nil {
+		return
+	}
+
+	if s.inTran {
+		s.stale = append(s.stale, userId)
+		return
+	}
+	_ = s.cache.Delete(ctx, userId)
+}
+
+// Committed drops the users changed through the copy of WithTx from the cache, it must be called once the
+// transaction of the copy committed.
+func (s *Service) Committed(ctx context.Context) {
+	if s.cache == nil {
+		return
+	}
+
+	for _, userId := range s.stale {
+		_ = s.cache.Delete(ctx, userId)
+	}
+	s.stale = nil
+}
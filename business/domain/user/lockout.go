@@ -0,0 +1,167 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrAccountLocked   = errors.New("account is temporarily locked")
+	ErrTooManyAttempts = errors.New("too many failed login attempts")
+)
+
+// attemptStore represents the storage of failed login attempts and temporary locks.
+type attemptStore interface {
+	AddFailure(ctx context.Context, key string, window time.Duration) (int, error)
+	ClearFailures(ctx context.Context, key string) error
+	Lock(ctx context.Context, key string, ttl time.Duration) error
+	LockedFor(ctx context.Context, key string) (time.Duration, bool, error)
+	Unlock(ctx context.Context, key string) error
+}
+
+// Lockout represents the policy used for throttling logins, failures are counted per email and per client ip
+// inside of a window as long as the lock duration.
+type Lockout struct {
+	Store        attemptStore
+	MaxFailures  int
+	MaxPerIP     int
+	LockDuration time.Duration
+}
+
+// LockedError is returned by Login when either the account or the client ip is locked.
+type LockedError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (le *LockedError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", le.Err, le.RetryAfter)
+}
+
+func (le *LockedError) Unwrap() error {
+	return le.Err
+}
+
+// EnableLockout turns on login throttling, without it login attempts are not limited. It can be called again while
+// the service is in use to change the limits.
+func (s *Service) EnableLockout(l Lockout) {
+	if l.MaxFailures <= 0 {
+		l.MaxFailures = 5
+	}
+
+	if l.MaxPerIP <= 0 {
+		l.MaxPerIP = l.MaxFailures * 4
+	}
+
+	if l.LockDuration <= 0 {
+		l.LockDuration = time.Minute * 15
+	}
+
+	s.lockout.Store(&l)
+}
+
+// Lockout returns the policy logins are throttled with and false when throttling is not enabled.
+func (s *Service) Lockout() (Lockout, bool) {
+	l := s.lockout.Load()
+	if l == nil {
+		return Lockout{}, false
+	}
+	return *l, true
+}
+
+// Unlock removes the lock and the failed attempts of the user.
+func (s *Service) Unlock(ctx context.Context, usr User) error {
+	lockout := s.lockout.Load()
+	if lockout == nil {
+		return nil
+	}
+
+	if err := lockout.Store.Unlock(ctx, emailKey(usr.Email.Address)); err != nil {
+		return fmt.Errorf("unlock: %w", err)
+	}
+	return nil
+}
+
+// checkLocked returns a LockedError when the ip or the email can not attempt a login.
+func (s *Service) checkLocked(ctx context.Context, email string, ip string) error {
+	lockout := s.lockout.Load()
+	if lockout == nil {
+		return nil
+	}
+
+	if ip != "" {
+		ttl, locked, err := lockout.Store.LockedFor(ctx, ipKey(ip))
+		if err != nil {
+			return fmt.Errorf("locked for ip: %w", err)
+		}
+		if locked {
+			return &LockedError{Err: ErrTooManyAttempts, RetryAfter: ttl}
+		}
+	}
+
+	ttl, locked, err := lockout.Store.LockedFor(ctx, emailKey(email))
+	if err != nil {
+		return fmt.Errorf("locked for email: %w", err)
+	}
+	if locked {
+		return &LockedError{Err: ErrAccountLocked, RetryAfter: ttl}
+	}
+	return nil
+}
+
+// recordFailure counts the failed attempt and locks the email or the ip once they reach their limit.
+func (s *Service) recordFailure(ctx context.Context, email string, ip string) error {
+	lockout := s.lockout.Load()
+	if lockout == nil {
+		return nil
+	}
+
+	failures, err := lockout.Store.AddFailure(ctx, emailKey(email), lockout.LockDuration)
+	if err != nil {
+		return fmt.Errorf("add failure for email: %w", err)
+	}
+
+	if failures >= lockout.MaxFailures {
+		if err := lockout.Store.Lock(ctx, emailKey(email), lockout.LockDuration); err != nil {
+			return fmt.Errorf("lock email: %w", err)
+		}
+	}
+
+	if ip == "" {
+		return nil
+	}
+
+	failures, err = lockout.Store.AddFailure(ctx, ipKey(ip), lockout.LockDuration)
+	if err != nil {
+		return fmt.Errorf("add failure for ip: %w", err)
+	}
+
+	if failures >= lockout.MaxPerIP {
+		if err := lockout.Store.Lock(ctx, ipKey(ip), lockout.LockDuration); err != nil {
+			return fmt.Errorf("lock ip: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *Service) clearFailures(ctx context.Context, email string) error {
+	lockout := s.lockout.Load()
+	if lockout == nil {
+		return nil
+	}
+
+	if err := lockout.Store.ClearFailures(ctx, emailKey(email)); err != nil {
+		return fmt.Errorf("clear failures: %w", err)
+	}
+	return nil
+}
+
+func emailKey(email string) string {
+	return "email:" + email
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
@@ -15,8 +15,11 @@ type User struct {
 	Roles        []Role
 	PasswordHash []byte
 	Enabled      bool
+	Verified     bool
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
+	//TokensValidAfter rejects the tokens issued before it, zero accepts every token.
+	TokensValidAfter time.Time
 }
 
 // NewUser represents all required data to create a new user in system.
@@ -25,6 +28,8 @@ type NewUser struct {
 	Email    mail.Address
 	Roles    []Role
 	Password string
+	//Verified skips the email verification, used for users created by operators.
+	Verified bool
 }
 
 // UpdateUser represents all the data that can be updated on a user type.
@@ -34,4 +39,7 @@ type UpdateUser struct {
 	Roles    []Role
 	Password *string
 	Enabled  *bool
+	Verified *bool
+	//RevokeTokens rejects every token issued to the user before the update.
+	RevokeTokens bool
 }
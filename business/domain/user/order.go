@@ -0,0 +1,81 @@
+package user
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Direction represents direction ASC and DESC in ordering rows.
+type Direction int
+
+const (
+	DirectionASC Direction = iota
+	DirectionDESC
+)
+
+var directionNames = [...]string{"ASC", "DESC"}
+
+// String implements the stringer interface.
+func (d Direction) String() string {
+	if d < DirectionASC || d > DirectionDESC {
+		return "UNKNOWN"
+	}
+	return directionNames[d]
+}
+
+// ParseDirection creates a direction from a string or return possible errors.
+func ParseDirection(dir string) (Direction, error) {
+	dir = strings.TrimSpace(dir)
+
+	for i, d := range directionNames {
+		if strings.ToUpper(dir) == d {
+			return Direction(i), nil
+		}
+	}
+	return Direction(-1), fmt.Errorf("%q is invalid direction", dir)
+}
+
+// Field represents the name of fields that client can apply ordering.
+type Field int
+
+const (
+	FieldName Field = iota
+	FieldCreatedAt
+)
+
+// these are general names, store layer must map these to real columns in db.
+var fieldNames = [...]string{"name", "createdAt"}
+
+// String implements stringer interface.
+func (f Field) String() string {
+	if f < FieldName || f > FieldCreatedAt {
+		return "UNKNOWN"
+	}
+	return fieldNames[f]
+}
+
+// ParseField creates a field from a string also returns possible errors.
+func ParseField(field string) (Field, error) {
+	field = strings.TrimSpace(field)
+
+	for i, f := range fieldNames {
+		if strings.EqualFold(field, f) {
+			return Field(i), nil
+		}
+	}
+	return Field(-1), fmt.Errorf("%q, invalid field name", field)
+}
+
+// OrderBy represents the field and direction to order based on it.
+type OrderBy struct {
+	Field     Field
+	Direction Direction
+}
+
+// Filter represents the conditions users are listed with, zero values match every user.
+type Filter struct {
+	Role    *Role
+	Enabled *bool
+	//Email matches the users whose email contains it, case insensitive.
+	Email string
+}
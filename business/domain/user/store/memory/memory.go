@@ -4,7 +4,10 @@ package memory
 import (
 	"context"
 	"database/sql"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/hamidoujand/task-scheduler/business/domain/user"
@@ -12,8 +15,21 @@ import (
 )
 
 type Repository struct {
-	Users map[uuid.UUID]user.User
-	mu    sync.Mutex
+	Users    map[uuid.UUID]user.User
+	tokens   map[string]token
+	failures map[string]counter
+	locks    map[string]time.Time
+	mu       sync.Mutex
+}
+
+type counter struct {
+	count     int
+	expiresAt time.Time
+}
+
+type token struct {
+	userId    uuid.UUID
+	expiresAt time.Time
 }
 
 // Create adds a new user into the repo and return possible error.
@@ -72,3 +88,144 @@ func (r *Repository) GetByEmail(ctx context.Context, email string) (user.User, e
 	}
 	return user.User{}, sql.ErrNoRows
 }
+
+// GetAll returns a page of the users that match the filter.
+func (r *Repository) GetAll(ctx context.Context, filter user.Filter, order user.OrderBy, rowsPerPage int, page int) ([]user.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var usrs []user.User
+	for _, usr := range r.Users {
+		if filter.Role != nil && !slices.Contains(usr.Roles, *filter.Role) {
+			continue
+		}
+		if filter.Enabled != nil && usr.Enabled != *filter.Enabled {
+			continue
+		}
+		if filter.Email != "" && !strings.Contains(strings.ToLower(usr.Email.Address), strings.ToLower(filter.Email)) {
+			continue
+		}
+		usrs = append(usrs, usr)
+	}
+
+	slices.SortFunc(usrs, func(a, b user.User) int {
+		var c int
+		switch order.Field {
+		case user.FieldName:
+			c = strings.Compare(a.Name, b.Name)
+		default:
+			c = a.CreatedAt.Compare(b.CreatedAt)
+		}
+		if c == 0 {
+			c = strings.Compare(a.Id.String(), b.Id.String())
+		}
+		if order.Direction == user.DirectionDESC {
+			return -c
+		}
+		return c
+	})
+
+	start := (page - 1) * rowsPerPage
+	if start >= len(usrs) {
+		return nil, nil
+	}
+	return usrs[start:min(start+rowsPerPage, len(usrs))], nil
+}
+
+// SaveToken stores a single-use token for the user.
+func (r *Repository) SaveToken(ctx context.Context, key string, userId uuid.UUID, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.tokens == nil {
+		r.tokens = make(map[string]token)
+	}
+
+	r.tokens[key] = token{userId: userId, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+// TakeToken returns the user of the token and deletes it, returns false when token does not exist or expired.
+func (r *Repository) TakeToken(ctx context.Context, key string) (uuid.UUID, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tkn, ok := r.tokens[key]
+	if !ok {
+		return uuid.UUID{}, false, nil
+	}
+	delete(r.tokens, key)
+
+	if time.Now().After(tkn.expiresAt) {
+		return uuid.UUID{}, false, nil
+	}
+	return tkn.userId, true, nil
+}
+
+// AddFailure increments the failed attempts of the key, the counter expires window after the first failure.
+func (r *Repository) AddFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.failures == nil {
+		r.failures = make(map[string]counter)
+	}
+
+	c, ok := r.failures[key]
+	if !ok || time.Now().After(c.expiresAt) {
+		c = counter{expiresAt: time.Now().Add(window)}
+	}
+	c.count++
+	r.failures[key] = c
+	return c.count, nil
+}
+
+// ClearFailures removes the failed attempts of the key.
+func (r *Repository) ClearFailures(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.failures, key)
+	return nil
+}
+
+// Lock locks the key for ttl.
+func (r *Repository) Lock(ctx context.Context, key string, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.locks == nil {
+		r.locks = make(map[string]time.Time)
+	}
+
+	r.locks[key] = time.Now().Add(ttl)
+	return nil
+}
+
+// LockedFor returns the remaining time of the lock of the key, returns false when key is not locked.
+func (r *Repository) LockedFor(ctx context.Context, key string) (time.Duration, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	until, ok := r.locks[key]
+	if !ok {
+		return 0, false, nil
+	}
+
+	remaining := time.Until(until)
+	if remaining <= 0 {
+		delete(r.locks, key)
+		return 0, false, nil
+	}
+	return remaining, true, nil
+}
+
+// Unlock removes the lock and the failed attempts of the key.
+func (r *Repository) Unlock(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.locks, key)
+	delete(r.failures, key)
+	return nil
+}
@@ -15,29 +15,39 @@ type User struct {
 	Roles        []string
 	PasswordHash []byte
 	Enabled      bool
+	Verified     bool
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
+	//TokensValidAfter is null until the tokens of the user are revoked.
+	TokensValidAfter *time.Time
 }
 
 // ToPostgresUser creates a User that will be saved inside of postgres.
 func ToPostgresUser(u user.User) User {
-	return User{
+	pg := User{
 		Id:           u.Id,
 		Name:         u.Name,
 		Email:        u.Email.Address,
 		Roles:        user.EncodeRoles(u.Roles),
 		PasswordHash: u.PasswordHash,
 		Enabled:      u.Enabled,
+		Verified:     u.Verified,
 		CreatedAt:    u.CreatedAt,
 		UpdatedAt:    u.UpdatedAt,
 	}
+
+	if !u.TokensValidAfter.IsZero() {
+		validAfter := u.TokensValidAfter
+		pg.TokensValidAfter = &validAfter
+	}
+	return pg
 }
 
 func (u User) ToServiceUser() user.User {
 	//since we getting them from db which already validated
 	roles, _ := user.ParseRoles(u.Roles)
 
-	return user.User{
+	usr := user.User{
 		Id:   u.Id,
 		Name: u.Name,
 		Email: mail.Address{
@@ -47,7 +57,13 @@ func (u User) ToServiceUser() user.User {
 		Roles:        roles,
 		PasswordHash: u.PasswordHash,
 		Enabled:      u.Enabled,
+		Verified:     u.Verified,
 		CreatedAt:    u.CreatedAt,
 		UpdatedAt:    u.UpdatedAt,
 	}
+
+	if u.TokensValidAfter != nil {
+		usr.TokensValidAfter = *u.TokensValidAfter
+	}
+	return usr
 }
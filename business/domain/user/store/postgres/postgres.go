@@ -2,23 +2,36 @@ package postgres
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/hamidoujand/task-scheduler/business/database/postgres"
 	"github.com/hamidoujand/task-scheduler/business/domain/user"
+	"github.com/jackc/pgx/v5"
 )
 
+var columnNames = map[user.Field]string{
+	user.FieldName:      "name",
+	user.FieldCreatedAt: "created_at",
+}
+
 // Repository represents set of APIs used to interact with postgres.
 type Repository struct {
-	client *postgres.Client
+	db postgres.DB
 }
 
 // NewRepository provides APIs to interact with store.
 func NewRepository(pgClient *postgres.Client) *Repository {
 	return &Repository{
-		client: pgClient,
+		db: pgClient.Pool,
+	}
+}
+
+// NewWithTx provides APIs to interact with store inside of the transaction.
+func NewWithTx(tx pgx.Tx) *Repository {
+	return &Repository{
+		db: tx,
 	}
 }
 
@@ -26,24 +39,25 @@ func NewRepository(pgClient *postgres.Client) *Repository {
 func (r *Repository) Create(ctx context.Context, usr user.User) error {
 	const q = `
 	INSERT INTO users 
-		(id,name,email,roles,password_hash,enabled,created_at,updated_at)
+		(id,name,email,roles,password_hash,enabled,verified,created_at,updated_at)
 	VALUES
-		($1,$2,$3,$4,$5,$6,$7,$8)	
+		($1,$2,$3,$4,$5,$6,$7,$8,$9)	
 	`
 	pgUser := ToPostgresUser(usr)
 
-	_, err := r.client.DB.ExecContext(ctx, q,
+	_, err := r.db.Exec(ctx, q,
 		pgUser.Id,
 		pgUser.Name,
 		pgUser.Email,
 		pgUser.Roles,
 		pgUser.PasswordHash,
 		pgUser.Enabled,
+		pgUser.Verified,
 		pgUser.CreatedAt,
 		pgUser.UpdatedAt,
 	)
 	if err != nil {
-		return fmt.Errorf("exec context: %w", err)
+		return fmt.Errorf("exec: %w", err)
 	}
 	return nil
 }
@@ -51,36 +65,28 @@ func (r *Repository) Create(ctx context.Context, usr user.User) error {
 func (r *Repository) GetById(ctx context.Context, id uuid.UUID) (user.User, error) {
 	const q = `
 	SELECT 
-		id,name,email,array_to_json(roles),password_hash,enabled,created_at,updated_at
+		id,name,email,roles,password_hash,enabled,verified,created_at,updated_at,tokens_valid_after
 	FROM users
 	WHERE id = $1	
 	`
 
-	row := r.client.DB.QueryRowContext(ctx, q, id.String())
-	var roles any
+	row := r.db.QueryRow(ctx, q, id)
 	var usr User
 
 	err := row.Scan(
 		&usr.Id,
 		&usr.Name,
 		&usr.Email,
-		&roles,
+		&usr.Roles,
 		&usr.PasswordHash,
 		&usr.Enabled,
+		&usr.Verified,
 		&usr.CreatedAt,
 		&usr.UpdatedAt,
+		&usr.TokensValidAfter,
 	)
 	if err != nil {
-		return user.User{}, fmt.Errorf("scanning row: %w", err)
-	}
-
-	switch t := roles.(type) {
-	case []byte:
-		if err := json.Unmarshal(t, &usr.Roles); err != nil {
-			return user.User{}, fmt.Errorf("unmarshalling roles: %w", err)
-		}
-	default:
-		return user.User{}, fmt.Errorf("roles scanning: %T", roles)
+		return user.User{}, fmt.Errorf("scanning row: %w", postgres.NoRows(err))
 	}
 	return usr.ToServiceUser(), nil
 }
@@ -97,19 +103,23 @@ func (r *Repository) Update(ctx context.Context, usr user.User) error {
 		roles = $3,
 		password_hash = $4,
 		enabled = $5,
-		updated_at = $6
-	WHERE id = $7	
+		verified = $6,
+		updated_at = $7,
+		tokens_valid_after = $8
+	WHERE id = $9	
 	`
-	if _, err := r.client.DB.ExecContext(ctx, q,
+	if _, err := r.db.Exec(ctx, q,
 		pgUser.Name,
 		pgUser.Email,
 		pgUser.Roles,
 		pgUser.PasswordHash,
 		pgUser.Enabled,
+		pgUser.Verified,
 		pgUser.UpdatedAt,
+		pgUser.TokensValidAfter,
 		pgUser.Id,
 	); err != nil {
-		return fmt.Errorf("execContext: %w", err)
+		return fmt.Errorf("exec: %w", err)
 	}
 	return nil
 }
@@ -118,8 +128,8 @@ func (r *Repository) Delete(ctx context.Context, usr user.User) error {
 	const q = `
 		DELETE FROM users WHERE id = $1
 	`
-	if _, err := r.client.DB.ExecContext(ctx, q, usr.Id.String()); err != nil {
-		return fmt.Errorf("execContext: %w", err)
+	if _, err := r.db.Exec(ctx, q, usr.Id); err != nil {
+		return fmt.Errorf("exec: %w", err)
 	}
 	return nil
 }
@@ -127,35 +137,105 @@ func (r *Repository) Delete(ctx context.Context, usr user.User) error {
 func (r *Repository) GetByEmail(ctx context.Context, email string) (user.User, error) {
 	const q = `
 	SELECT 
-		id,name,email,array_to_json(roles),password_hash,enabled,created_at,updated_at
+		id,name,email,roles,password_hash,enabled,verified,created_at,updated_at,tokens_valid_after
 	FROM users
 	WHERE email = $1	
 	`
-	row := r.client.DB.QueryRowContext(ctx, q, email)
-	var roles any
+	row := r.db.QueryRow(ctx, q, email)
 	var usr User
 
 	err := row.Scan(
 		&usr.Id,
 		&usr.Name,
 		&usr.Email,
-		&roles,
+		&usr.Roles,
 		&usr.PasswordHash,
 		&usr.Enabled,
+		&usr.Verified,
 		&usr.CreatedAt,
 		&usr.UpdatedAt,
+		&usr.TokensValidAfter,
 	)
 	if err != nil {
-		return user.User{}, fmt.Errorf("scanning row: %w", err)
+		return user.User{}, fmt.Errorf("scanning row: %w", postgres.NoRows(err))
+	}
+	return usr.ToServiceUser(), nil
+}
+
+// GetAll returns a page of the users that match the filter.
+func (r *Repository) GetAll(ctx context.Context, filter user.Filter, order user.OrderBy, rowsPerPage int, page int) ([]user.User, error) {
+	col, exist := columnNames[order.Field]
+	if !exist {
+		return nil, fmt.Errorf("invalid column name %q", order.Field)
 	}
 
-	switch t := roles.(type) {
-	case []byte:
-		if err := json.Unmarshal(t, &usr.Roles); err != nil {
-			return user.User{}, fmt.Errorf("unmarshalling roles: %w", err)
+	var (
+		where []string
+		args  []any
+	)
+
+	if filter.Role != nil {
+		args = append(args, filter.Role.String())
+		where = append(where, fmt.Sprintf("$%d = ANY(roles)", len(args)))
+	}
+
+	if filter.Enabled != nil {
+		args = append(args, *filter.Enabled)
+		where = append(where, fmt.Sprintf("enabled = $%d", len(args)))
+	}
+
+	if filter.Email != "" {
+		args = append(args, filter.Email)
+		where = append(where, fmt.Sprintf("email ILIKE '%%' || $%d || '%%'", len(args)))
+	}
+
+	clause := ""
+	if len(where) > 0 {
+		clause = "WHERE " + strings.Join(where, " AND ")
+	}
+
+	offset := (page - 1) * rowsPerPage
+	args = append(args, offset, rowsPerPage)
+
+	// column names come from the map and the direction is an enum, the values of the filter are passed as
+	// arguments so there is no risk of sql injection in here.
+	q := fmt.Sprintf(`
+	SELECT
+		id,name,email,roles,password_hash,enabled,verified,created_at,updated_at,tokens_valid_after
+	FROM users
+	%s
+	ORDER BY %s %s, id
+	OFFSET $%d ROWS FETCH NEXT $%d ROWS ONLY
+	`, clause, col, order.Direction, len(args)-1, len(args))
+
+	rows, err := r.db.Query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	var usrs []user.User
+	for rows.Next() {
+		var usr User
+		if err := rows.Scan(
+			&usr.Id,
+			&usr.Name,
+			&usr.Email,
+			&usr.Roles,
+			&usr.PasswordHash,
+			&usr.Enabled,
+			&usr.Verified,
+			&usr.CreatedAt,
+			&usr.UpdatedAt,
+			&usr.TokensValidAfter,
+		); err != nil {
+			return nil, fmt.Errorf("scanning row: %w", err)
 		}
-	default:
-		return user.User{}, fmt.Errorf("roles scanning: %T", roles)
+		usrs = append(usrs, usr.ToServiceUser())
 	}
-	return usr.ToServiceUser(), nil
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+	return usrs, nil
 }
@@ -11,16 +11,16 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/hamidoujand/task-scheduler/business/dbtest"
 	"github.com/hamidoujand/task-scheduler/business/domain/user"
 	"github.com/hamidoujand/task-scheduler/business/domain/user/store/postgres"
+	"github.com/hamidoujand/task-scheduler/business/testinfra"
 	"golang.org/x/crypto/bcrypt"
 )
 
 func TestCreate(t *testing.T) {
 	t.Parallel()
 
-	pgClient := dbtest.NewDatabaseClient(t, "test_create_user")
+	pgClient := testinfra.NewDatabase(t)
 	repo := postgres.NewRepository(pgClient)
 	now := time.Now()
 	id := uuid.New()
@@ -53,7 +53,7 @@ func TestCreate(t *testing.T) {
 func TestGetById(t *testing.T) {
 	t.Parallel()
 
-	pgClient := dbtest.NewDatabaseClient(t, "test_getById_user")
+	pgClient := testinfra.NewDatabase(t)
 	repo := postgres.NewRepository(pgClient)
 
 	//insert one
@@ -111,7 +111,7 @@ func TestGetById(t *testing.T) {
 func TestUpdate(t *testing.T) {
 	t.Parallel()
 
-	pgClient := dbtest.NewDatabaseClient(t, "test_update_user")
+	pgClient := testinfra.NewDatabase(t)
 	repo := postgres.NewRepository(pgClient)
 
 	//insert one
@@ -193,7 +193,7 @@ func TestUpdate(t *testing.T) {
 func TestDelete(t *testing.T) {
 	t.Parallel()
 
-	pgClient := dbtest.NewDatabaseClient(t, "test_delete_user")
+	pgClient := testinfra.NewDatabase(t)
 	repo := postgres.NewRepository(pgClient)
 
 	//insert one
@@ -242,7 +242,7 @@ func TestDelete(t *testing.T) {
 func TestGetByEmail(t *testing.T) {
 	t.Parallel()
 
-	pgClient := dbtest.NewDatabaseClient(t, "test_getByEmail_user")
+	pgClient := testinfra.NewDatabase(t)
 	repo := postgres.NewRepository(pgClient)
 
 	//insert one
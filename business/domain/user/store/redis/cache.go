@@ -0,0 +1,71 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/user"
+	"github.com/hamidoujand/task-scheduler/business/metrics"
+	"github.com/redis/go-redis/v9"
+)
+
+const cached = "users:cache"
+
+// Cache represents all of the APIs used for caching users inside of redis.
+type Cache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewCache creates a new redis cache whose entries expire after ttl.
+func NewCache(c *redis.Client, ttl time.Duration) *Cache {
+	return &Cache{
+		client: c,
+		ttl:    ttl,
+	}
+}
+
+// Get returns the cached user, returns false when they are not cached.
+func (c *Cache) Get(ctx context.Context, userId uuid.UUID) (user.User, bool, error) {
+	val, err := c.client.Get(ctx, cached+":"+userId.String()).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			metrics.AddCacheMiss("users")
+			return user.User{}, false, nil
+		}
+		return user.User{}, false, fmt.Errorf("get: %w", err)
+	}
+
+	var usr user.User
+	if err := json.Unmarshal(val, &usr); err != nil {
+		return user.User{}, false, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	metrics.AddCacheHit("users")
+	return usr, true, nil
+}
+
+// Set caches the user.
+func (c *Cache) Set(ctx context.Context, usr user.User) error {
+	bs, err := json.Marshal(usr)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	if err := c.client.Set(ctx, cached+":"+usr.Id.String(), bs, c.ttl).Err(); err != nil {
+		return fmt.Errorf("set: %w", err)
+	}
+	return nil
+}
+
+// Delete drops the user from the cache.
+func (c *Cache) Delete(ctx context.Context, userId uuid.UUID) error {
+	if err := c.client.Del(ctx, cached+":"+userId.String()).Err(); err != nil {
+		return fmt.Errorf("del: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Package redis provides the single-use token and login attempt storage of user domain.
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	entity   = "users:tokens"
+	failures = "users:failures"
+	locks    = "users:locks"
+)
+
+// Repository represents all of the APIs used for storing tokens and login attempts inside of redis.
+type Repository struct {
+	client *redis.Client
+}
+
+// NewRepository creates a new redis repository.
+func NewRepository(c *redis.Client) *Repository {
+	return &Repository{
+		client: c,
+	}
+}
+
+// SaveToken stores a single-use token for the user that expires after ttl.
+func (r *Repository) SaveToken(ctx context.Context, key string, userId uuid.UUID, ttl time.Duration) error {
+	if err := r.client.Set(ctx, entity+":"+key, userId.String(), ttl).Err(); err != nil {
+		return fmt.Errorf("set: %w", err)
+	}
+	return nil
+}
+
+// TakeToken returns the user of the token and deletes it atomically, returns false when token does not exist.
+func (r *Repository) TakeToken(ctx context.Context, key string) (uuid.UUID, bool, error) {
+	val, err := r.client.GetDel(ctx, entity+":"+key).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return uuid.UUID{}, false, nil
+		}
+		return uuid.UUID{}, false, fmt.Errorf("getdel: %w", err)
+	}
+
+	userId, err := uuid.Parse(val)
+	if err != nil {
+		return uuid.UUID{}, false, fmt.Errorf("parse user id: %w", err)
+	}
+	return userId, true, nil
+}
+
+// AddFailure increments the failed attempts of the key, the counter expires window after the first failure.
+func (r *Repository) AddFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	pipe := r.client.TxPipeline()
+	incr := pipe.Incr(ctx, failures+":"+key)
+	pipe.ExpireNX(ctx, failures+":"+key, window)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("exec: %w", err)
+	}
+	return int(incr.Val()), nil
+}
+
+// ClearFailures removes the failed attempts of the key.
+func (r *Repository) ClearFailures(ctx context.Context, key string) error {
+	if err := r.client.Del(ctx, failures+":"+key).Err(); err != nil {
+		return fmt.Errorf("del: %w", err)
+	}
+	return nil
+}
+
+// Lock locks the key for ttl.
+func (r *Repository) Lock(ctx context.Context, key string, ttl time.Duration) error {
+	if err := r.client.Set(ctx, locks+":"+key, 1, ttl).Err(); err != nil {
+		return fmt.Errorf("set: %w", err)
+	}
+	return nil
+}
+
+// LockedFor returns the remaining time of the lock of the key, returns false when key is not locked.
+func (r *Repository) LockedFor(ctx context.Context, key string) (time.Duration, bool, error) {
+	ttl, err := r.client.PTTL(ctx, locks+":"+key).Result()
+	if err != nil {
+		return 0, false, fmt.Errorf("pttl: %w", err)
+	}
+
+	//negative values mean the key does not exist or has no expiry, locks are always set with one
+	if ttl <= 0 {
+		return 0, false, nil
+	}
+	return ttl, true, nil
+}
+
+// Unlock removes the lock and the failed attempts of the key.
+func (r *Repository) Unlock(ctx context.Context, key string) error {
+	if err := r.client.Del(ctx, locks+":"+key, failures+":"+key).Err(); err != nil {
+		return fmt.Errorf("del: %w", err)
+	}
+	return nil
+}
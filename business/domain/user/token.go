@@ -0,0 +1,143 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// VerificationTokenTTL is how long an email verification token is valid.
+	VerificationTokenTTL = time.Hour * 24
+	// PasswordResetTokenTTL is how long a password reset token is valid.
+	PasswordResetTokenTTL = time.Hour
+)
+
+var (
+	ErrInvalidToken   = errors.New("invalid or expired token")
+	ErrTokensDisabled = errors.New("token store is not configured")
+)
+
+type purpose string
+
+const (
+	purposeVerify purpose = "verify"
+	purposeReset  purpose = "reset"
+)
+
+// tokenStore represents the storage of single-use tokens, TakeToken must delete the token it returns.
+type tokenStore interface {
+	SaveToken(ctx context.Context, key string, userId uuid.UUID, ttl time.Duration) error
+	TakeToken(ctx context.Context, key string) (uuid.UUID, bool, error)
+}
+
+// RequestVerification generates a single-use token that verifies the email of the user.
+func (s *Service) RequestVerification(ctx context.Context, usr User) (string, error) {
+	return s.newToken(ctx, purposeVerify, usr.Id, VerificationTokenTTL)
+}
+
+// Verify consumes the verification token and marks its user as verified.
+func (s *Service) Verify(ctx context.Context, token string) (User, error) {
+	usr, err := s.takeToken(ctx, purposeVerify, token)
+	if err != nil {
+		return User{}, err
+	}
+
+	if usr.Verified {
+		return usr, nil
+	}
+
+	verified := true
+	updated, err := s.UpdateUser(ctx, UpdateUser{Verified: &verified}, usr)
+	if err != nil {
+		return User{}, fmt.Errorf("update user: %w", err)
+	}
+	return updated, nil
+}
+
+// RequestPasswordReset generates a single-use token that resets the password of the user with the given email.
+func (s *Service) RequestPasswordReset(ctx context.Context, email mail.Address) (User, string, error) {
+	usr, err := s.GetByEmail(ctx, email)
+	if err != nil {
+		return User{}, "", fmt.Errorf("get by email: %w", err)
+	}
+
+	token, err := s.newToken(ctx, purposeReset, usr.Id, PasswordResetTokenTTL)
+	if err != nil {
+		return User{}, "", err
+	}
+	return usr, token, nil
+}
+
+// ResetPassword consumes the password reset token and sets the new password, since the user proved
+// they own the email the user is verified as well. The tokens issued before the reset are revoked, whoever knew the
+// old password may still hold one.
+func (s *Service) ResetPassword(ctx context.Context, token string, password string) (User, error) {
+	usr, err := s.takeToken(ctx, purposeReset, token)
+	if err != nil {
+		return User{}, err
+	}
+
+	verified := true
+	updated, err := s.UpdateUser(ctx, UpdateUser{Password: &password, Verified: &verified, RevokeTokens: true}, usr)
+	if err != nil {
+		return User{}, fmt.Errorf("update user: %w", err)
+	}
+	return updated, nil
+}
+
+func (s *Service) newToken(ctx context.Context, p purpose, userId uuid.UUID, ttl time.Duration) (string, error) {
+	if s.tokens == nil {
+		return "", ErrTokensDisabled
+	}
+
+	bs := make([]byte, 32)
+	if _, err := rand.Read(bs); err != nil {
+		return "", fmt.Errorf("read random: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(bs)
+
+	if err := s.tokens.SaveToken(ctx, tokenKey(p, token), userId, ttl); err != nil {
+		return "", fmt.Errorf("save token: %w", err)
+	}
+	return token, nil
+}
+
+func (s *Service) takeToken(ctx context.Context, p purpose, token string) (User, error) {
+	if s.tokens == nil {
+		return User{}, ErrTokensDisabled
+	}
+
+	userId, ok, err := s.tokens.TakeToken(ctx, tokenKey(p, token))
+	if err != nil {
+		return User{}, fmt.Errorf("take token: %w", err)
+	}
+
+	if !ok {
+		return User{}, ErrInvalidToken
+	}
+
+	usr, err := s.GetUserById(ctx, userId)
+	if err != nil {
+		//user is deleted after the token is issued
+		if errors.Is(err, ErrUserNotFound) {
+			return User{}, ErrInvalidToken
+		}
+		return User{}, fmt.Errorf("get user by id: %w", err)
+	}
+	return usr, nil
+}
+
+// tokenKey only keeps the hash of the token so a leaked store can not be used to take over accounts.
+func tokenKey(p purpose, token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return string(p) + ":" + hex.EncodeToString(sum[:])
+}
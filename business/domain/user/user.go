@@ -6,6 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"net/mail"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -29,19 +31,40 @@ type repository interface {
 	GetById(ctx context.Context, usrId uuid.UUID) (User, error)
 	GetByEmail(ctx context.Context, email string) (User, error)
 	Delete(ctx context.Context, usr User) error
+	GetAll(ctx context.Context, filter Filter, order OrderBy, rowsPerPage int, page int) ([]User, error)
 }
 
 // Service represents the set of APIs that needed to interact with user domain.
 type Service struct {
 	userRepo repository
+	tokens   tokenStore
+	lockout  *atomic.Pointer[Lockout]
+	cache    cache
+	inTran   bool
+	//stale are the users changed inside of the transaction, dropped from the cache once it committed.
+	stale []uuid.UUID
 }
 
-func NewService(repo repository) *Service {
+// NewService creates a user service, tokens is optional and without it email verification and password
+// reset are disabled and new users are created as verified.
+func NewService(repo repository, tokens tokenStore) *Service {
 	return &Service{
 		userRepo: repo,
+		tokens:   tokens,
+		lockout:  new(atomic.Pointer[Lockout]),
 	}
 }
 
+// WithTx returns a copy of the service that uses repo, repo is created with NewWithTx of a store so the calls of
+// the copy run inside of a transaction.
+func (s *Service) WithTx(repo repository) *Service {
+	svc := *s
+	svc.userRepo = repo
+	svc.inTran = true
+	svc.stale = nil
+	return &svc
+}
+
 // CreateUser creates a new user into repositoy and returns possible errors in case of duplicated email will return
 // ErrDuplicatedEmail.
 func (s *Service) CreateUser(ctx context.Context, nu NewUser) (User, error) {
@@ -60,6 +83,7 @@ func (s *Service) CreateUser(ctx context.Context, nu NewUser) (User, error) {
 		Roles:        nu.Roles,
 		PasswordHash: hashed,
 		Enabled:      true,
+		Verified:     nu.Verified || s.tokens == nil,
 		CreatedAt:    now,
 		UpdatedAt:    now,
 	}
@@ -83,6 +107,10 @@ func (s *Service) CreateUser(ctx context.Context, nu NewUser) (User, error) {
 // GetUserById queries the repo for the user with id and returns possible errors, in case of "ErrNoRows" will return
 // with ErrUserNotFound error.
 func (s *Service) GetUserById(ctx context.Context, id uuid.UUID) (User, error) {
+	if usr, ok := s.cached(ctx, id); ok {
+		return usr, nil
+	}
+
 	usr, err := s.userRepo.GetById(ctx, id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -90,18 +118,32 @@ func (s *Service) GetUserById(ctx context.Context, id uuid.UUID) (User, error) {
 		}
 		return User{}, fmt.Errorf("get by id: %w", err)
 	}
+
+	s.setCached(ctx, usr)
 	return usr, nil
 }
 
+// QueryUsers returns a page of the users that match the filter in the given order.
+func (s *Service) QueryUsers(ctx context.Context, filter Filter, order OrderBy, rowsPerPage int, page int) ([]User, error) {
+	usrs, err := s.userRepo.GetAll(ctx, filter, order, rowsPerPage, page)
+	if err != nil {
+		return nil, fmt.Errorf("getAll: %w", err)
+	}
+	return usrs, nil
+}
+
 // DeleteUser deletes the given user from repo and return possible errors.
 func (s *Service) DeleteUser(ctx context.Context, usr User) error {
 	if err := s.userRepo.Delete(ctx, usr); err != nil {
 		return fmt.Errorf("delete: %w", err)
 	}
+
+	s.invalidate(ctx, usr.Id)
 	return nil
 }
 
-// UpdateUser updates the user based on given updates and return the updated user back and possible errors.
+// UpdateUser updates the user based on given updates and return the updated user back and possible errors, a new
+// email marks the user as unverified when verification is enabled.
 func (s *Service) UpdateUser(ctx context.Context, uu UpdateUser, usr User) (User, error) {
 
 	if uu.Name != nil {
@@ -109,6 +151,10 @@ func (s *Service) UpdateUser(ctx context.Context, uu UpdateUser, usr User) (User
 	}
 
 	if uu.Email != nil {
+		//the new address has to be verified again, unless verification is disabled
+		if s.tokens != nil && !strings.EqualFold(uu.Email.Address, usr.Email.Address) {
+			usr.Verified = false
+		}
 		usr.Email = *uu.Email
 	}
 
@@ -116,6 +162,10 @@ func (s *Service) UpdateUser(ctx context.Context, uu UpdateUser, usr User) (User
 		usr.Enabled = *uu.Enabled
 	}
 
+	if uu.Verified != nil {
+		usr.Verified = *uu.Verified
+	}
+
 	if uu.Password != nil {
 		hashed, err := bcrypt.GenerateFromPassword([]byte(*uu.Password), bcrypt.DefaultCost)
 		if err != nil {
@@ -130,9 +180,30 @@ func (s *Service) UpdateUser(ctx context.Context, uu UpdateUser, usr User) (User
 
 	now := time.Now()
 	usr.UpdatedAt = now
+	if uu.RevokeTokens {
+		usr.TokensValidAfter = now
+	}
+
 	if err := s.userRepo.Update(ctx, usr); err != nil {
 		return User{}, fmt.Errorf("update: %w", err)
 	}
+
+	s.invalidate(ctx, usr.Id)
+	return usr, nil
+}
+
+// DisableUser disables the user and revokes every token issued to them so far.
+func (s *Service) DisableUser(ctx context.Context, usr User) (User, error) {
+	now := time.Now()
+	usr.Enabled = false
+	usr.TokensValidAfter = now
+	usr.UpdatedAt = now
+
+	if err := s.userRepo.Update(ctx, usr); err != nil {
+		return User{}, fmt.Errorf("update: %w", err)
+	}
+
+	s.invalidate(ctx, usr.Id)
 	return usr, nil
 }
 
@@ -151,18 +222,36 @@ func (s *Service) GetByEmail(ctx context.Context, email mail.Address) (User, err
 	return usr, nil
 }
 
-// Login gets the user by email and checks the password
-func (s *Service) Login(ctx context.Context, email mail.Address, password string) (User, error) {
+// Login gets the user by email and checks the password, ip is the address of the client and is used for
+// throttling when lockout is enabled. Returns a LockedError when the account or the ip is locked.
+func (s *Service) Login(ctx context.Context, email mail.Address, password string, ip string) (User, error) {
+	if err := s.checkLocked(ctx, email.Address, ip); err != nil {
+		return User{}, err
+	}
+
 	usr, err := s.GetByEmail(ctx, email)
 	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			//unknown emails count as well so they can not be used for guessing
+			if err := s.recordFailure(ctx, email.Address, ip); err != nil {
+				return User{}, fmt.Errorf("record failure: %w", err)
+			}
+		}
 		return User{}, fmt.Errorf("getByEmail[%s]: %w", email.Address, err)
 	}
 
 	//compare passwords
 	err = bcrypt.CompareHashAndPassword(usr.PasswordHash, []byte(password))
 	if err != nil {
+		if err := s.recordFailure(ctx, email.Address, ip); err != nil {
+			return User{}, fmt.Errorf("record failure: %w", err)
+		}
 		return User{}, ErrLoginFailed
 	}
 
+	if err := s.clearFailures(ctx, email.Address); err != nil {
+		return User{}, fmt.Errorf("clear failures: %w", err)
+	}
+
 	return usr, nil
 }
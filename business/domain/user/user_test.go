@@ -3,9 +3,11 @@ package user_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/mail"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/hamidoujand/task-scheduler/business/domain/user"
@@ -60,7 +62,7 @@ func TestCreateUser(t *testing.T) {
 		Users: make(map[uuid.UUID]user.User),
 	}
 
-	service := user.NewService(&repo)
+	service := user.NewService(&repo, &repo)
 
 	got, err := service.CreateUser(context.Background(), nu)
 	if err != nil {
@@ -114,7 +116,7 @@ func TestGetUserById(t *testing.T) {
 		Users: make(map[uuid.UUID]user.User),
 	}
 
-	service := user.NewService(&repo)
+	service := user.NewService(&repo, &repo)
 
 	usr, err := service.CreateUser(context.Background(), nu)
 	if err != nil {
@@ -162,7 +164,7 @@ func TestDeleteUser(t *testing.T) {
 		Users: make(map[uuid.UUID]user.User),
 	}
 
-	service := user.NewService(&repo)
+	service := user.NewService(&repo, &repo)
 
 	usr, err := service.CreateUser(context.Background(), nu)
 	if err != nil {
@@ -202,7 +204,7 @@ func TestUpdateUser(t *testing.T) {
 		Users: make(map[uuid.UUID]user.User),
 	}
 
-	service := user.NewService(&repo)
+	service := user.NewService(&repo, &repo)
 
 	usr, err := service.CreateUser(context.Background(), nu)
 	if err != nil {
@@ -280,7 +282,7 @@ func TestGetByEmail(t *testing.T) {
 		Users: make(map[uuid.UUID]user.User),
 	}
 
-	service := user.NewService(&repo)
+	service := user.NewService(&repo, &repo)
 
 	usr, err := service.CreateUser(context.Background(), nu)
 
@@ -350,14 +352,14 @@ func TestLogin(t *testing.T) {
 		Users: make(map[uuid.UUID]user.User),
 	}
 
-	service := user.NewService(&repo)
+	service := user.NewService(&repo, &repo)
 
 	usr, err := service.CreateUser(context.Background(), nu)
 	if err != nil {
 		t.Fatalf("expected the user to be saved with valid data: %s", err)
 	}
 
-	got, err := service.Login(context.Background(), email, pass)
+	got, err := service.Login(context.Background(), email, pass, "")
 	if err != nil {
 		t.Fatalf("expected to login with the valid credentials: %s", err)
 	}
@@ -366,7 +368,7 @@ func TestLogin(t *testing.T) {
 		t.Fatal("expected the user from login to be the same we got from create")
 	}
 
-	_, err = service.Login(context.Background(), email, "pass")
+	_, err = service.Login(context.Background(), email, "pass", "")
 	if err == nil {
 		t.Fatal("expected to get an error while using invalid credentials")
 	}
@@ -379,7 +381,7 @@ func TestLogin(t *testing.T) {
 		Name:    "jane",
 		Address: "jane@hotmail.com",
 	}
-	_, err = service.Login(context.Background(), email, pass)
+	_, err = service.Login(context.Background(), email, pass, "")
 	if err == nil {
 		t.Fatalf("expected the login to fail when using random email")
 	}
@@ -388,3 +390,264 @@ func TestLogin(t *testing.T) {
 		t.Errorf("error= %v, want %v", err, user.ErrUserNotFound)
 	}
 }
+
+func TestLockout(t *testing.T) {
+	t.Parallel()
+	pass := "test1234"
+	email := mail.Address{
+		Name:    "john",
+		Address: "john@gmail.com",
+	}
+
+	repo := memory.Repository{
+		Users: make(map[uuid.UUID]user.User),
+	}
+
+	service := user.NewService(&repo, &repo)
+	service.EnableLockout(user.Lockout{
+		Store:        &repo,
+		MaxFailures:  3,
+		MaxPerIP:     5,
+		LockDuration: time.Minute,
+	})
+
+	usr, err := service.CreateUser(context.Background(), user.NewUser{
+		Name:     "john",
+		Email:    email,
+		Roles:    []user.Role{user.RoleUser},
+		Password: pass,
+	})
+	if err != nil {
+		t.Fatalf("expected the user to be saved with valid data: %s", err)
+	}
+
+	for range 3 {
+		if _, err := service.Login(context.Background(), email, "wrong1234", "10.0.0.1"); !errors.Is(err, user.ErrLoginFailed) {
+			t.Fatalf("err= %v, got %v", user.ErrLoginFailed, err)
+		}
+	}
+
+	//even the valid password is rejected once locked
+	_, err = service.Login(context.Background(), email, pass, "10.0.0.2")
+	if !errors.Is(err, user.ErrAccountLocked) {
+		t.Fatalf("err= %v, got %v", user.ErrAccountLocked, err)
+	}
+
+	var lockedErr *user.LockedError
+	if !errors.As(err, &lockedErr) || lockedErr.RetryAfter <= 0 {
+		t.Errorf("expected a locked error with retry after, got %v", err)
+	}
+
+	if err := service.Unlock(context.Background(), usr); err != nil {
+		t.Fatalf("expected to unlock the user: %s", err)
+	}
+
+	if _, err := service.Login(context.Background(), email, pass, "10.0.0.2"); err != nil {
+		t.Fatalf("expected to login after unlock: %s", err)
+	}
+
+	//ip gets throttled across emails
+	for i := range 5 {
+		other := mail.Address{Address: fmt.Sprintf("user%d@gmail.com", i)}
+		if _, err := service.Login(context.Background(), other, pass, "10.0.0.3"); !errors.Is(err, user.ErrUserNotFound) {
+			t.Fatalf("err= %v, got %v", user.ErrUserNotFound, err)
+		}
+	}
+
+	if _, err := service.Login(context.Background(), email, pass, "10.0.0.3"); !errors.Is(err, user.ErrTooManyAttempts) {
+		t.Errorf("err= %v, got %v", user.ErrTooManyAttempts, err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	repo := memory.Repository{
+		Users: make(map[uuid.UUID]user.User),
+	}
+	service := user.NewService(&repo, &repo)
+
+	usr, err := service.CreateUser(context.Background(), user.NewUser{
+		Name:     "john",
+		Email:    mail.Address{Name: "john", Address: "john@gmail.com"},
+		Roles:    []user.Role{user.RoleUser},
+		Password: "test1234",
+	})
+	if err != nil {
+		t.Fatalf("expected to create user: %s", err)
+	}
+
+	if usr.Verified {
+		t.Fatal("expected new user to be unverified")
+	}
+
+	token, err := service.RequestVerification(context.Background(), usr)
+	if err != nil {
+		t.Fatalf("expected to generate verification token: %s", err)
+	}
+
+	verified, err := service.Verify(context.Background(), token)
+	if err != nil {
+		t.Fatalf("expected to verify user: %s", err)
+	}
+
+	if !verified.Verified {
+		t.Error("expected user to be verified")
+	}
+
+	//single use
+	if _, err := service.Verify(context.Background(), token); !errors.Is(err, user.ErrInvalidToken) {
+		t.Errorf("err= %v, got %v", user.ErrInvalidToken, err)
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	t.Parallel()
+
+	repo := memory.Repository{
+		Users: make(map[uuid.UUID]user.User),
+	}
+	service := user.NewService(&repo, &repo)
+
+	email := mail.Address{Name: "john", Address: "john@gmail.com"}
+	if _, err := service.CreateUser(context.Background(), user.NewUser{
+		Name:     "john",
+		Email:    email,
+		Roles:    []user.Role{user.RoleUser},
+		Password: "test1234",
+	}); err != nil {
+		t.Fatalf("expected to create user: %s", err)
+	}
+
+	_, token, err := service.RequestPasswordReset(context.Background(), email)
+	if err != nil {
+		t.Fatalf("expected to generate reset token: %s", err)
+	}
+
+	//verification tokens can not reset passwords
+	if _, err := service.Verify(context.Background(), token); !errors.Is(err, user.ErrInvalidToken) {
+		t.Errorf("err= %v, got %v", user.ErrInvalidToken, err)
+	}
+
+	reset, err := service.ResetPassword(context.Background(), token, "newpass1234")
+	if err != nil {
+		t.Fatalf("expected to reset password: %s", err)
+	}
+
+	//tokens issued before the reset are revoked
+	if reset.TokensValidAfter.IsZero() || !reset.TokensValidAfter.Equal(repo.Users[reset.Id].TokensValidAfter) {
+		t.Errorf("expected the tokens of the user to be revoked, got %s", repo.Users[reset.Id].TokensValidAfter)
+	}
+
+	if _, err := service.Login(context.Background(), email, "newpass1234", ""); err != nil {
+		t.Errorf("expected to login with the new password: %s", err)
+	}
+
+	if _, err := service.Login(context.Background(), email, "test1234", ""); !errors.Is(err, user.ErrLoginFailed) {
+		t.Errorf("err= %v, got %v", user.ErrLoginFailed, err)
+	}
+
+	//without a token store
+	disabled := user.NewService(&repo, nil)
+	if _, _, err := disabled.RequestPasswordReset(context.Background(), email); !errors.Is(err, user.ErrTokensDisabled) {
+		t.Errorf("err= %v, got %v", user.ErrTokensDisabled, err)
+	}
+}
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+
+	repo := memory.Repository{
+		Users: make(map[uuid.UUID]user.User),
+	}
+	cache := mapCache{}
+
+	service := user.NewService(&repo, &repo)
+	service.EnableCache(cache)
+
+	usr, err := service.CreateUser(context.Background(), user.NewUser{
+		Name:     "john",
+		Email:    mail.Address{Name: "john", Address: "john@gmail.com"},
+		Roles:    []user.Role{user.RoleUser},
+		Password: "test1234",
+	})
+	if err != nil {
+		t.Fatalf("expected to create user: %s", err)
+	}
+
+	if _, err := service.GetUserById(context.Background(), usr.Id); err != nil {
+		t.Fatalf("expected to get the user: %s", err)
+	}
+
+	if _, ok := cache[usr.Id]; !ok {
+		t.Fatal("expected the user to be cached after the first read")
+	}
+
+	//reads are served by the cache even when the repository changes behind it
+	stale := repo.Users[usr.Id]
+	stale.Name = "jane"
+	repo.Users[usr.Id] = stale
+
+	fetched, err := service.GetUserById(context.Background(), usr.Id)
+	if err != nil {
+		t.Fatalf("expected to get the user: %s", err)
+	}
+
+	if fetched.Name != "john" {
+		t.Errorf("name= %s, got %s", "john", fetched.Name)
+	}
+
+	enabled := false
+	if _, err := service.UpdateUser(context.Background(), user.UpdateUser{Enabled: &enabled}, fetched); err != nil {
+		t.Fatalf("expected to update the user: %s", err)
+	}
+
+	if _, ok := cache[usr.Id]; ok {
+		t.Error("expected the user to be dropped from the cache on update")
+	}
+
+	//inside of a transaction the user is dropped once it committed, a read in between caches the old row again
+	if _, err := service.GetUserById(context.Background(), usr.Id); err != nil {
+		t.Fatalf("expected to get the user: %s", err)
+	}
+
+	tx := service.WithTx(&repo)
+	if _, err := tx.DisableUser(context.Background(), fetched); err != nil {
+		t.Fatalf("expected to disable the user: %s", err)
+	}
+
+	if _, ok := cache[usr.Id]; !ok {
+		t.Error("expected the user to stay cached until the transaction committed")
+	}
+
+	tx.Committed(context.Background())
+	if _, ok := cache[usr.Id]; ok {
+		t.Error("expected the user to be dropped from the cache once the transaction committed")
+	}
+
+	if err := service.DeleteUser(context.Background(), usr); err != nil {
+		t.Fatalf("expected to delete the user: %s", err)
+	}
+
+	if _, err := service.GetUserById(context.Background(), usr.Id); !errors.Is(err, user.ErrUserNotFound) {
+		t.Errorf("err= %v, got %v", user.ErrUserNotFound, err)
+	}
+}
+
+// mapCache is a cache of users inside of a map.
+type mapCache map[uuid.UUID]user.User
+
+func (c mapCache) Get(ctx context.Context, userId uuid.UUID) (user.User, bool, error) {
+	usr, ok := c[userId]
+	return usr, ok, nil
+}
+
+func (c mapCache) Set(ctx context.Context, usr user.User) error {
+	c[usr.Id] = usr
+	return nil
+}
+
+func (c mapCache) Delete(ctx context.Context, userId uuid.UUID) error {
+	delete(c, userId)
+	return nil
+}
@@ -0,0 +1,80 @@
+// Package memory provides an in memory repository used for testing.
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/view"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Repository represents an in-memory storage for testing.
+type Repository struct {
+	Views []view.View
+	mu    sync.Mutex
+}
+
+// Create stores the view or returns a unique violation when the user already has a view with the name.
+func (r *Repository) Create(ctx context.Context, v view.View) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.Views {
+		if existing.UserId == v.UserId && existing.Name == v.Name {
+			return &pgconn.PgError{Code: "23505"}
+		}
+	}
+
+	r.Views = append(r.Views, v)
+	return nil
+}
+
+// Get returns the view of the user with the name or sql.ErrNoRows.
+func (r *Repository) Get(ctx context.Context, userId uuid.UUID, name string) (view.View, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, v := range r.Views {
+		if v.UserId == userId && v.Name == name {
+			return v, nil
+		}
+	}
+	return view.View{}, sql.ErrNoRows
+}
+
+// GetByUserId returns the views of the user ordered by name.
+func (r *Repository) GetByUserId(ctx context.Context, userId uuid.UUID) ([]view.View, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var views []view.View
+	for _, v := range r.Views {
+		if v.UserId == userId {
+			views = append(views, v)
+		}
+	}
+
+	slices.SortFunc(views, func(a, b view.View) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return views, nil
+}
+
+// Delete deletes the view of the user with the name or returns sql.ErrNoRows.
+func (r *Repository) Delete(ctx context.Context, userId uuid.UUID, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, v := range r.Views {
+		if v.UserId == userId && v.Name == name {
+			r.Views = append(r.Views[:i], r.Views[i+1:]...)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
@@ -0,0 +1,133 @@
+// Package postgres provides the view storage on top of postgres.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/database/postgres"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	"github.com/hamidoujand/task-scheduler/business/domain/view"
+)
+
+// Repository represents all of the APIs used for CRUD against postgres.
+type Repository struct {
+	client *postgres.Client
+}
+
+// NewRepository creates a new postgres repository.
+func NewRepository(client *postgres.Client) *Repository {
+	return &Repository{
+		client: client,
+	}
+}
+
+// Create stores the view, a view with the same name for the user fails with a unique violation.
+func (r *Repository) Create(ctx context.Context, v view.View) error {
+	const q = `
+	INSERT INTO task_views
+		(id,user_id,name,search,order_by,rows,created_at)
+	VALUES
+		($1,$2,$3,$4,$5,$6,$7)
+	`
+
+	if _, err := r.client.Pool.Exec(ctx, q,
+		v.Id,
+		v.UserId,
+		v.Name,
+		v.Search,
+		v.Order.String(),
+		v.Rows,
+		v.CreatedAt.UTC(),
+	); err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+	return nil
+}
+
+// Get returns the view of the user, returns sql.ErrNoRows when there is no record.
+func (r *Repository) Get(ctx context.Context, userId uuid.UUID, name string) (view.View, error) {
+	const q = `
+	SELECT
+		id,user_id,name,search,order_by,rows,created_at
+	FROM task_views
+	WHERE user_id = $1 AND name = $2
+	`
+
+	v, err := scanView(r.client.Pool.QueryRow(ctx, q, userId, name))
+	if err != nil {
+		return view.View{}, fmt.Errorf("row scan: %w", postgres.NoRows(err))
+	}
+	return v, nil
+}
+
+// GetByUserId returns the views of the user ordered by name.
+func (r *Repository) GetByUserId(ctx context.Context, userId uuid.UUID) ([]view.View, error) {
+	const q = `
+	SELECT
+		id,user_id,name,search,order_by,rows,created_at
+	FROM task_views
+	WHERE user_id = $1
+	ORDER BY name
+	`
+
+	rows, err := r.client.Pool.Query(ctx, q, userId)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	var views []view.View
+	for rows.Next() {
+		v, err := scanView(rows)
+		if err != nil {
+			return nil, fmt.Errorf("rows scan: %w", err)
+		}
+		views = append(views, v)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+	return views, nil
+}
+
+// Delete deletes the view of the user, returns sql.ErrNoRows when there is no record.
+func (r *Repository) Delete(ctx context.Context, userId uuid.UUID, name string) error {
+	const q = `DELETE FROM task_views WHERE user_id = $1 AND name = $2`
+
+	tag, err := r.client.Pool.Exec(ctx, q, userId, name)
+	if err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanView(row rowScanner) (view.View, error) {
+	var v view.View
+	var order string
+
+	if err := row.Scan(&v.Id, &v.UserId, &v.Name, &v.Search, &order, &v.Rows, &v.CreatedAt); err != nil {
+		return view.View{}, err
+	}
+
+	o, err := task.ParseOrderBy(order)
+	if err != nil {
+		return view.View{}, fmt.Errorf("parse order: %w", err)
+	}
+
+	v.Order = o
+	v.CreatedAt = v.CreatedAt.In(time.Local)
+	return v, nil
+}
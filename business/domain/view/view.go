@@ -0,0 +1,139 @@
+// Package view provides the saved views of users, a view is a named search, order and page size of a task list so
+// clients list their tasks with "?view=name" instead of repeating the query string.
+package view
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamidoujand/task-scheduler/business/domain/task"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const uniqueViolation = "23505"
+
+// maxViews is how many views a user may have.
+const maxViews = 50
+
+var (
+	ErrViewNotFound = errors.New("view not found")
+	ErrViewExists   = errors.New("a view with the name already exists")
+	ErrTooManyViews = fmt.Errorf("a user can have at most %d views", maxViews)
+	ErrInvalidName  = errors.New("view name must be 1 to 64 letters, digits, '-' or '_'")
+)
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// store represents the storage of views, Get and Delete return sql.ErrNoRows when the user has no view with the
+// name, Create returns a unique violation when it has one.
+type store interface {
+	Create(ctx context.Context, v View) error
+	Get(ctx context.Context, userId uuid.UUID, name string) (View, error)
+	GetByUserId(ctx context.Context, userId uuid.UUID) ([]View, error)
+	Delete(ctx context.Context, userId uuid.UUID, name string) error
+}
+
+// View represents a named task list of a user. Search is the full text search of the list, Order its order and Rows
+// its page size, a zero Rows leaves the page size to the request.
+type View struct {
+	Id        uuid.UUID
+	UserId    uuid.UUID
+	Name      string
+	Search    string
+	Order     task.OrderBy
+	Rows      int
+	CreatedAt time.Time
+}
+
+// NewView represents the data required for creating a view.
+type NewView struct {
+	UserId uuid.UUID
+	Name   string
+	Search string
+	Order  task.OrderBy
+	Rows   int
+}
+
+// Service represents set of APIs for managing views.
+type Service struct {
+	store store
+}
+
+// NewService creates a view service.
+func NewService(store store) *Service {
+	return &Service{
+		store: store,
+	}
+}
+
+// CreateView creates a view for the user, returns ErrViewExists when the user already has a view with the name.
+func (s *Service) CreateView(ctx context.Context, nv NewView) (View, error) {
+	if !validName.MatchString(nv.Name) {
+		return View{}, ErrInvalidName
+	}
+
+	views, err := s.store.GetByUserId(ctx, nv.UserId)
+	if err != nil {
+		return View{}, fmt.Errorf("get by user id: %w", err)
+	}
+
+	if len(views) >= maxViews {
+		return View{}, ErrTooManyViews
+	}
+
+	v := View{
+		Id:        uuid.New(),
+		UserId:    nv.UserId,
+		Name:      nv.Name,
+		Search:    nv.Search,
+		Order:     nv.Order,
+		Rows:      nv.Rows,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.store.Create(ctx, v); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return View{}, ErrViewExists
+		}
+		return View{}, fmt.Errorf("create: %w", err)
+	}
+	return v, nil
+}
+
+// GetView returns the view of the user with the name, returns ErrViewNotFound when there is none.
+func (s *Service) GetView(ctx context.Context, userId uuid.UUID, name string) (View, error) {
+	v, err := s.store.Get(ctx, userId, name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return View{}, ErrViewNotFound
+		}
+		return View{}, fmt.Errorf("get: %w", err)
+	}
+	return v, nil
+}
+
+// Views returns the views of the user ordered by name.
+func (s *Service) Views(ctx context.Context, userId uuid.UUID) ([]View, error) {
+	views, err := s.store.GetByUserId(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("get by user id: %w", err)
+	}
+	return views, nil
+}
+
+// DeleteView deletes the view of the user with the name, returns ErrViewNotFound when there is none.
+func (s *Service) DeleteView(ctx context.Context, userId uuid.UUID, name string) error {
+	if err := s.store.Delete(ctx, userId, name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrViewNotFound
+		}
+		return fmt.Errorf("delete: %w", err)
+	}
+	return nil
+}
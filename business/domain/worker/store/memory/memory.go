@@ -0,0 +1,80 @@
+// Package memory provides an in memory repository used for testing.
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/domain/worker"
+)
+
+type entry struct {
+	worker    worker.Worker
+	expiresAt time.Time
+}
+
+// Repository represents an in-memory storage for testing.
+type Repository struct {
+	workers map[string]entry
+	mu      sync.Mutex
+}
+
+// Upsert creates or refreshes the worker.
+func (r *Repository) Upsert(ctx context.Context, w worker.Worker, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.workers == nil {
+		r.workers = make(map[string]entry)
+	}
+
+	//the load only changes through AddLoad
+	w.Load = r.workers[w.Id].worker.Load
+	r.workers[w.Id] = entry{worker: w, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+// AddLoad changes the load of the worker, the load never goes below 0 and unknown workers are ignored.
+func (r *Repository) AddLoad(ctx context.Context, id string, delta int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.workers[id]
+	if !ok {
+		return nil
+	}
+
+	e.worker.Load = max(e.worker.Load+delta, 0)
+	r.workers[id] = e
+	return nil
+}
+
+// Delete removes the worker.
+func (r *Repository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.workers, id)
+	return nil
+}
+
+// GetAll returns the workers that are not expired ordered by id.
+func (r *Repository) GetAll(ctx context.Context) ([]worker.Worker, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	workers := make([]worker.Worker, 0, len(r.workers))
+	for id, e := range r.workers {
+		if now.After(e.expiresAt) {
+			delete(r.workers, id)
+			continue
+		}
+		workers = append(workers, e.worker)
+	}
+
+	sort.Slice(workers, func(i, j int) bool { return workers[i].Id < workers[j].Id })
+	return workers, nil
+}
@@ -0,0 +1,145 @@
+// Package redis provides the worker registry inside of redis.
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/domain/worker"
+	"github.com/redis/go-redis/v9"
+)
+
+const entity = "workers"
+
+// Repository represents all of the APIs used for keeping workers inside of redis.
+type Repository struct {
+	client *redis.Client
+}
+
+// NewRepository creates a new redis repository.
+func NewRepository(c *redis.Client) *Repository {
+	return &Repository{
+		client: c,
+	}
+}
+
+// Upsert stores the worker as a hash that expires after ttl and adds it to the set of known workers, the "load"
+// field is left as is.
+func (r *Repository) Upsert(ctx context.Context, w worker.Worker, ttl time.Duration) error {
+	key := entity + ":" + w.Id
+
+	pipe := r.client.TxPipeline()
+	pipe.HSet(ctx, key,
+		"id", w.Id,
+		"hostname", w.Hostname,
+		"build", w.Build,
+		"maxRunning", w.MaxRunning,
+		"running", w.Running,
+		"status", string(w.Status),
+		"startedAt", w.StartedAt.UnixMilli(),
+		"heartbeatAt", w.HeartbeatAt.UnixMilli(),
+	)
+	pipe.Expire(ctx, key, ttl)
+	pipe.SAdd(ctx, entity, w.Id)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+	return nil
+}
+
+// addLoadScript changes the load of a worker that still exists and keeps it from going below 0.
+var addLoadScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+local load = redis.call("HINCRBY", KEYS[1], "load", ARGV[1])
+if load < 0 then
+	redis.call("HSET", KEYS[1], "load", 0)
+end
+return 1
+`)
+
+// AddLoad atomically changes the load of the worker, unknown workers are ignored.
+func (r *Repository) AddLoad(ctx context.Context, id string, delta int) error {
+	if err := addLoadScript.Run(ctx, r.client, []string{entity + ":" + id}, delta).Err(); err != nil {
+		return fmt.Errorf("run add load script: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the worker.
+func (r *Repository) Delete(ctx context.Context, id string) error {
+	pipe := r.client.TxPipeline()
+	pipe.Del(ctx, entity+":"+id)
+	pipe.SRem(ctx, entity, id)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+	return nil
+}
+
+// GetAll returns the workers whose hash did not expire yet, expired ones are removed from the set of known workers.
+func (r *Repository) GetAll(ctx context.Context) ([]worker.Worker, error) {
+	ids, err := r.client.SMembers(ctx, entity).Result()
+	if err != nil {
+		return nil, fmt.Errorf("smembers: %w", err)
+	}
+
+	workers := make([]worker.Worker, 0, len(ids))
+	for _, id := range ids {
+		fields, err := r.client.HGetAll(ctx, entity+":"+id).Result()
+		if err != nil {
+			return nil, fmt.Errorf("hgetall: %w", err)
+		}
+
+		//expired
+		if len(fields) == 0 {
+			if err := r.client.SRem(ctx, entity, id).Err(); err != nil {
+				return nil, fmt.Errorf("srem: %w", err)
+			}
+			continue
+		}
+
+		w, err := toWorker(fields)
+		if err != nil {
+			return nil, fmt.Errorf("worker %s: %w", id, err)
+		}
+		workers = append(workers, w)
+	}
+
+	sort.Slice(workers, func(i, j int) bool { return workers[i].Id < workers[j].Id })
+	return workers, nil
+}
+
+func toWorker(fields map[string]string) (worker.Worker, error) {
+	ints := make(map[string]int64, 5)
+	for _, name := range [...]string{"maxRunning", "running", "load", "startedAt", "heartbeatAt"} {
+		val, ok := fields[name]
+		if !ok {
+			continue
+		}
+
+		n, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return worker.Worker{}, fmt.Errorf("parse %s: %w", name, err)
+		}
+		ints[name] = n
+	}
+
+	return worker.Worker{
+		Id:          fields["id"],
+		Hostname:    fields["hostname"],
+		Build:       fields["build"],
+		MaxRunning:  int(ints["maxRunning"]),
+		Running:     int(ints["running"]),
+		Load:        int(ints["load"]),
+		Status:      worker.Status(fields["status"]),
+		StartedAt:   time.UnixMilli(ints["startedAt"]),
+		HeartbeatAt: time.UnixMilli(ints["heartbeatAt"]),
+	}, nil
+}
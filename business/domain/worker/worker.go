@@ -0,0 +1,211 @@
+// Package worker provides the registry of the worker processes that execute tasks.
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+var (
+	ErrInvalidInterval = errors.New("heartbeat interval must be greater than 0")
+	ErrNoWorkers       = errors.New("no active workers")
+)
+
+// store represents the storage of workers, a worker that is not refreshed within ttl is dropped by the store.
+// Upsert never touches the load of a worker, it only changes through AddLoad which ignores unknown workers.
+type store interface {
+	Upsert(ctx context.Context, w Worker, ttl time.Duration) error
+	AddLoad(ctx context.Context, id string, delta int) error
+	Delete(ctx context.Context, id string) error
+	GetAll(ctx context.Context) ([]Worker, error)
+}
+
+// Status represents whether a worker accepts new tasks.
+type Status string
+
+const (
+	StatusActive Status = "active"
+	//StatusPaused is reported while the executor of the worker is down.
+	StatusPaused Status = "paused"
+)
+
+// Worker represents a process that executes tasks.
+type Worker struct {
+	Id         string
+	Hostname   string
+	Build      string
+	MaxRunning int
+	//Running is the number of tasks the worker executed at its last heartbeat.
+	Running int
+	//Load is the number of tasks assigned to the worker that it did not finish yet.
+	Load        int
+	Status      Status
+	StartedAt   time.Time
+	HeartbeatAt time.Time
+}
+
+// Report represents what a worker sends with every heartbeat.
+type Report struct {
+	Running int
+	Status  Status
+}
+
+// Service represents set of APIs for managing workers.
+type Service struct {
+	store store
+}
+
+// NewService creates a worker service.
+func NewService(store store) *Service {
+	return &Service{
+		store: store,
+	}
+}
+
+// Register adds the worker to the registry, the worker is dropped unless it sends a heartbeat within ttl.
+func (s *Service) Register(ctx context.Context, w Worker, ttl time.Duration) (Worker, error) {
+	now := time.Now()
+	w.Status = StatusActive
+	w.StartedAt = now
+	w.HeartbeatAt = now
+
+	if err := s.store.Upsert(ctx, w, ttl); err != nil {
+		return Worker{}, fmt.Errorf("upsert: %w", err)
+	}
+	return w, nil
+}
+
+// Heartbeat refreshes the worker along with its report.
+func (s *Service) Heartbeat(ctx context.Context, w Worker, r Report, ttl time.Duration) (Worker, error) {
+	w.Running = r.Running
+	w.Status = r.Status
+	w.HeartbeatAt = time.Now()
+
+	if err := s.store.Upsert(ctx, w, ttl); err != nil {
+		return Worker{}, fmt.Errorf("upsert: %w", err)
+	}
+	return w, nil
+}
+
+// Deregister removes the worker from the registry.
+func (s *Service) Deregister(ctx context.Context, id string) error {
+	if err := s.store.Delete(ctx, id); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	return nil
+}
+
+// GetAll returns every worker that is still sending heartbeats.
+func (s *Service) GetAll(ctx context.Context) ([]Worker, error) {
+	workers, err := s.store.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get all: %w", err)
+	}
+	return workers, nil
+}
+
+// Assign picks the worker for a new task and adds the task to its load. The preferred worker is picked when it is
+// active, otherwise the active worker with the lowest load relative to its capacity, ErrNoWorkers is returned when
+// no worker is active.
+func (s *Service) Assign(ctx context.Context, preferred string) (Worker, error) {
+	workers, err := s.GetAll(ctx)
+	if err != nil {
+		return Worker{}, err
+	}
+
+	var (
+		picked Worker
+		found  bool
+	)
+
+	for _, w := range workers {
+		if w.Status != StatusActive {
+			continue
+		}
+
+		if w.Id == preferred {
+			picked, found = w, true
+			break
+		}
+
+		if !found || lessLoaded(w, picked) {
+			picked, found = w, true
+		}
+	}
+
+	if !found {
+		return Worker{}, ErrNoWorkers
+	}
+
+	if err := s.AddLoad(ctx, picked.Id, 1); err != nil {
+		return Worker{}, err
+	}
+	picked.Load++
+	return picked, nil
+}
+
+// AddLoad atomically changes the load of the worker, unknown workers are ignored.
+func (s *Service) AddLoad(ctx context.Context, id string, delta int) error {
+	if err := s.store.AddLoad(ctx, id, delta); err != nil {
+		return fmt.Errorf("add load: %w", err)
+	}
+	return nil
+}
+
+// lessLoaded reports whether a has a lower load than b relative to their capacity.
+func lessLoaded(a, b Worker) bool {
+	//a/capA < b/capB without dividing
+	left := a.Load * max(b.MaxRunning, 1)
+	right := b.Load * max(a.MaxRunning, 1)
+	if left != right {
+		return left < right
+	}
+	return a.Id < b.Id
+}
+
+// KeepAlive registers the worker and sends a heartbeat every interval until ctx is canceled, then the worker
+// is deregistered. A worker misses three heartbeats before it is dropped, failed heartbeats are logged and retried
+// on the next tick.
+func (s *Service) KeepAlive(ctx context.Context, logger *slog.Logger, w Worker, interval time.Duration, report func() Report) error {
+	if interval <= 0 {
+		return ErrInvalidInterval
+	}
+
+	ttl := interval * 3
+
+	w, err := s.Register(ctx, w, ttl)
+	if err != nil {
+		return fmt.Errorf("register: %w", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			//ctx is already canceled
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			defer cancel()
+
+			if err := s.Deregister(ctx, w.Id); err != nil {
+				return fmt.Errorf("deregister: %w", err)
+			}
+			return nil
+
+		case <-ticker.C:
+			hbCtx, cancel := context.WithTimeout(ctx, interval)
+			updated, err := s.Heartbeat(hbCtx, w, report(), ttl)
+			cancel()
+
+			if err != nil {
+				logger.Error("worker", "status", "failed to send heartbeat", "msg", err)
+				continue
+			}
+			w = updated
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/domain/worker"
+	"github.com/hamidoujand/task-scheduler/business/domain/worker/store/memory"
+)
+
+func TestWorker(t *testing.T) {
+	t.Parallel()
+
+	repo := memory.Repository{}
+	service := worker.NewService(&repo)
+
+	w, err := service.Register(context.Background(), worker.Worker{Id: "worker-1", MaxRunning: 4}, time.Minute)
+	if err != nil {
+		t.Fatalf("expected to register worker: %s", err)
+	}
+
+	if _, err := service.Heartbeat(context.Background(), w, worker.Report{Running: 2, Status: worker.StatusActive}, time.Minute); err != nil {
+		t.Fatalf("expected to send heartbeat: %s", err)
+	}
+
+	//a worker that stops sending heartbeats is dropped
+	if _, err := service.Register(context.Background(), worker.Worker{Id: "worker-2"}, time.Millisecond); err != nil {
+		t.Fatalf("expected to register worker: %s", err)
+	}
+	time.Sleep(time.Millisecond * 5)
+
+	workers, err := service.GetAll(context.Background())
+	if err != nil {
+		t.Fatalf("expected to get workers: %s", err)
+	}
+
+	if len(workers) != 1 {
+		t.Fatalf("workers= %d, got %d", 1, len(workers))
+	}
+
+	if workers[0].Running != 2 {
+		t.Errorf("running= %d, got %d", 2, workers[0].Running)
+	}
+
+	if err := service.Deregister(context.Background(), "worker-1"); err != nil {
+		t.Fatalf("expected to deregister worker: %s", err)
+	}
+
+	workers, err = service.GetAll(context.Background())
+	if err != nil {
+		t.Fatalf("expected to get workers: %s", err)
+	}
+
+	if len(workers) != 0 {
+		t.Errorf("workers= %d, got %d", 0, len(workers))
+	}
+}
+
+func TestKeepAlive(t *testing.T) {
+	t.Parallel()
+
+	repo := memory.Repository{}
+	service := worker.NewService(&repo)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- service.KeepAlive(ctx, logger, worker.Worker{Id: "worker-1"}, time.Millisecond*10, func() worker.Report {
+			return worker.Report{Running: 3, Status: worker.StatusActive}
+		})
+	}()
+
+	//wait for a few heartbeats
+	deadline := time.Now().Add(time.Second)
+	for {
+		workers, err := service.GetAll(context.Background())
+		if err != nil {
+			t.Fatalf("expected to get workers: %s", err)
+		}
+
+		if len(workers) == 1 && workers[0].Running == 3 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the worker to report its running tasks, got %+v", workers)
+		}
+		time.Sleep(time.Millisecond * 5)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("expected keep alive to stop cleanly: %s", err)
+	}
+
+	workers, err := service.GetAll(context.Background())
+	if err != nil {
+		t.Fatalf("expected to get workers: %s", err)
+	}
+
+	if len(workers) != 0 {
+		t.Errorf("expected the worker to be deregistered, got %+v", workers)
+	}
+}
+
+func TestAssign(t *testing.T) {
+	t.Parallel()
+
+	repo := memory.Repository{}
+	service := worker.NewService(&repo)
+
+	if _, err := service.Assign(context.Background(), ""); !errors.Is(err, worker.ErrNoWorkers) {
+		t.Fatalf("expected %v, got %v", worker.ErrNoWorkers, err)
+	}
+
+	workers := []worker.Worker{
+		{Id: "small", MaxRunning: 1},
+		{Id: "big", MaxRunning: 4},
+		{Id: "paused", MaxRunning: 100},
+	}
+
+	for _, w := range workers {
+		registered, err := service.Register(context.Background(), w, time.Minute)
+		if err != nil {
+			t.Fatalf("expected to register worker: %s", err)
+		}
+
+		if w.Id == "paused" {
+			report := worker.Report{Status: worker.StatusPaused}
+			if _, err := service.Heartbeat(context.Background(), registered, report, time.Minute); err != nil {
+				t.Fatalf("expected to send heartbeat: %s", err)
+			}
+		}
+	}
+
+	//the big worker takes 4 tasks for each task of the small one
+	got := make(map[string]int)
+	for range 5 {
+		w, err := service.Assign(context.Background(), "")
+		if err != nil {
+			t.Fatalf("expected to assign task: %s", err)
+		}
+		got[w.Id]++
+	}
+
+	if got["big"] != 4 || got["small"] != 1 {
+		t.Errorf("assignments= %v, got %v", map[string]int{"big": 4, "small": 1}, got)
+	}
+
+	//an active preferred worker is picked regardless of its load
+	w, err := service.Assign(context.Background(), "small")
+	if err != nil {
+		t.Fatalf("expected to assign task: %s", err)
+	}
+
+	if w.Id != "small" || w.Load != 2 {
+		t.Errorf("worker= small with load 2, got %s with load %d", w.Id, w.Load)
+	}
+
+	//paused workers are never picked
+	w, err = service.Assign(context.Background(), "paused")
+	if err != nil {
+		t.Fatalf("expected to assign task: %s", err)
+	}
+
+	if w.Id == "paused" {
+		t.Error("expected paused worker to be skipped")
+	}
+
+	//finished tasks release the load
+	if err := service.AddLoad(context.Background(), "small", -5); err != nil {
+		t.Fatalf("expected to release load: %s", err)
+	}
+
+	all, err := service.GetAll(context.Background())
+	if err != nil {
+		t.Fatalf("expected to get workers: %s", err)
+	}
+
+	for _, w := range all {
+		if w.Id == "small" && w.Load != 0 {
+			t.Errorf("load= %d, got %d", 0, w.Load)
+		}
+	}
+}
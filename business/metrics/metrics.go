@@ -0,0 +1,175 @@
+// Package metrics provides the application metrics, all of them are published using expvar
+// and can be read from "/debug/vars" of the debug server.
+package metrics
+
+import (
+	"expvar"
+	"sync"
+
+	"github.com/hamidoujand/task-scheduler/foundation/breaker"
+)
+
+var (
+	promotions      = expvar.NewInt("scheduler_promotions")
+	demotions       = expvar.NewInt("scheduler_demotions")
+	schedulerLeader = expvar.NewInt("scheduler_leader")
+	breakerTrips    = expvar.NewInt("scheduler_breaker_trips")
+	breakerOpen     = expvar.NewInt("scheduler_breaker_open")
+	queueDepth      = expvar.NewInt("scheduler_queue_tasks_depth")
+	backlogAlerts   = expvar.NewInt("scheduler_backlog_alerts")
+	panics          = expvar.NewMap("scheduler_panics")
+	handlerQueue    = expvar.NewMap("scheduler_handler_queue")
+	retryCounters   = expvar.NewInt("scheduler_retry_counters")
+	retryCleared    = expvar.NewInt("scheduler_retry_counters_cleared")
+	retrySwept      = expvar.NewInt("scheduler_retry_counters_swept")
+	claimConflicts  = expvar.NewInt("scheduler_claim_conflicts")
+	lateTasks       = expvar.NewInt("scheduler_late_tasks")
+	unpublished     = expvar.NewMap("task_events_unpublished")
+	cacheHits       = expvar.NewMap("cache_hits")
+	cacheMisses     = expvar.NewMap("cache_misses")
+	warmHits        = expvar.NewMap("scheduler_warm_hits")
+	warmMisses      = expvar.NewMap("scheduler_warm_misses")
+	warmRecycles    = expvar.NewMap("scheduler_warm_recycles")
+	warmIdle        = expvar.NewMap("scheduler_warm_idle")
+	depBreakers     = expvar.NewMap("dependency_breakers")
+	depBreakerTrips = expvar.NewMap("dependency_breaker_trips")
+
+	dbPoolOnce sync.Once
+)
+
+// AddPromotion records that this instance acquired the leader lease and started dispatching.
+func AddPromotion() {
+	promotions.Add(1)
+}
+
+// AddDemotion records that this instance lost its lease and stopped dispatching.
+func AddDemotion() {
+	demotions.Add(1)
+}
+
+// SetLeader reports whether this instance is currently dispatching tasks.
+func SetLeader(leader bool) {
+	if leader {
+		schedulerLeader.Set(1)
+		return
+	}
+	schedulerLeader.Set(0)
+}
+
+// AddBreakerTrip records that dispatch was paused because of executor infrastructure failures.
+func AddBreakerTrip() {
+	breakerTrips.Add(1)
+}
+
+// SetBreakerOpen reports whether dispatch is currently paused by the breaker.
+func SetBreakerOpen(open bool) {
+	if open {
+		breakerOpen.Set(1)
+		return
+	}
+	breakerOpen.Set(0)
+}
+
+// SetQueueDepth reports the number of messages waiting inside of the tasks queue.
+func SetQueueDepth(messages int) {
+	queueDepth.Set(int64(messages))
+}
+
+// AddBacklogAlert records that the tasks queue stayed above the backlog threshold.
+func AddBacklogAlert() {
+	backlogAlerts.Add(1)
+}
+
+// AddPanic records a panic of a goroutine of the scheduler that was recovered, where is the goroutine like
+// "executer".
+func AddPanic(where string) {
+	panics.Add(where, 1)
+}
+
+// SetHandlerQueue reports the number of messages of the queue that wait for a free handler.
+func SetHandlerQueue(queue string, messages int) {
+	v := new(expvar.Int)
+	v.Set(int64(messages))
+	handlerQueue.Set(queue, v)
+}
+
+// SetRetryCounters reports the number of retry counters kept inside of the retry store.
+func SetRetryCounters(counters int) {
+	retryCounters.Set(int64(counters))
+}
+
+// AddRetryCounterCleared records that the retry counter of a task was deleted once the task finished.
+func AddRetryCounterCleared() {
+	retryCleared.Add(1)
+}
+
+// AddRetryCountersSwept records the retry counters the sweeper expired or deleted.
+func AddRetryCountersSwept(counters int) {
+	retrySwept.Add(int64(counters))
+}
+
+// AddClaimConflict records a task that was delivered while its execution was claimed by another executer.
+func AddClaimConflict() {
+	claimConflicts.Add(1)
+}
+
+// AddLateTasks records the tasks that were reported for not starting in time.
+func AddLateTasks(tasks int) {
+	lateTasks.Add(int64(tasks))
+}
+
+// AddUnpublishedEvent records an event of a task of the kind that was recorded but could not be published.
+func AddUnpublishedEvent(kind string) {
+	unpublished.Add(kind, 1)
+}
+
+// AddCacheHit records a read of entity that was served by the cache.
+func AddCacheHit(entity string) {
+	cacheHits.Add(entity, 1)
+}
+
+// AddCacheMiss records a read of entity that had to go to the database.
+func AddCacheMiss(entity string) {
+	cacheMisses.Add(entity, 1)
+}
+
+// AddWarmHit records a task of the image that ran inside of a warm container.
+func AddWarmHit(image string) {
+	warmHits.Add(image, 1)
+}
+
+// AddWarmMiss records a task of a pooled image that had to start its own container.
+func AddWarmMiss(image string) {
+	warmMisses.Add(image, 1)
+}
+
+// AddWarmRecycle records a warm container of the image that was replaced.
+func AddWarmRecycle(image string) {
+	warmRecycles.Add(image, 1)
+}
+
+// SetWarmIdle reports the number of idle warm containers of the image.
+func SetWarmIdle(image string, idle int) {
+	v := new(expvar.Int)
+	v.Set(int64(idle))
+	warmIdle.Set(image, v)
+}
+
+// SetDependencyBreaker reports the state of the breaker of the dependency, every time it opens counts as a trip.
+func SetDependencyBreaker(name string, state breaker.State) {
+	v := new(expvar.String)
+	v.Set(state.String())
+	depBreakers.Set(name, v)
+
+	if state == breaker.Open {
+		depBreakerTrips.Add(name, 1)
+	}
+}
+
+// PublishDBPool publishes the stats of the database connection pool as "db_pool", stats is called on every read.
+// Only the first call takes effect.
+func PublishDBPool(stats func() any) {
+	dbPoolOnce.Do(func() {
+		expvar.Publish("db_pool", expvar.Func(stats))
+	})
+}
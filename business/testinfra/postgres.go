@@ -0,0 +1,121 @@
+package testinfra
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/database/postgres"
+)
+
+// templateDB is migrated once, the database of every test is a copy of it.
+const templateDB = "testinfra_template"
+
+// pgServer represents the shared postgres container.
+type pgServer struct {
+	host   string
+	master *postgres.Client
+	//create serializes the copies of the template, postgres refuses to copy a database that is in use.
+	create sync.Mutex
+}
+
+var pg shared[*pgServer]
+
+// NewDatabase returns a client of a migrated database of its own, the database is dropped once the test is done.
+func NewDatabase(t *testing.T) *postgres.Client {
+	t.Helper()
+
+	srv := pg.get(t, startPostgres)
+
+	name := randomName("test_")
+
+	srv.create.Lock()
+	_, err := srv.master.Pool.Exec(context.Background(), "CREATE DATABASE "+name+" TEMPLATE "+templateDB)
+	srv.create.Unlock()
+	if err != nil {
+		t.Fatalf("testinfra: create database %s: %s", name, err)
+	}
+
+	client, err := postgres.NewClient(postgres.Config{
+		User:        "postgres",
+		Password:    "password",
+		Host:        srv.host,
+		Name:        name,
+		DisableTLS:  true,
+		MaxOpenConn: 4,
+	})
+	if err != nil {
+		t.Fatalf("testinfra: create client of database %s: %s", name, err)
+	}
+
+	t.Cleanup(func() {
+		client.Close()
+
+		//the database can not be dropped while connections to it are left
+		if _, err := srv.master.Pool.Exec(context.Background(), "DROP DATABASE "+name+" WITH (FORCE)"); err != nil {
+			t.Errorf("testinfra: drop database %s: %s", name, err)
+		}
+	})
+
+	return client
+}
+
+func startPostgres() (*pgServer, error) {
+	c, err := startContainer("postgres:16.3", "5432",
+		[]string{"-e", "POSTGRES_PASSWORD=password"},
+		//every test of the binary connects to the same server
+		[]string{"-c", "max_connections=500"},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	master, err := connect(c.HostPort, "postgres")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := master.Pool.Exec(context.Background(), "CREATE DATABASE "+templateDB); err != nil {
+		return nil, fmt.Errorf("create template database: %w", err)
+	}
+
+	template, err := connect(c.HostPort, templateDB)
+	if err != nil {
+		return nil, err
+	}
+
+	err = template.Migrate()
+	//copies of the template can only be made once nobody is connected to it
+	template.Close()
+	if err != nil {
+		return nil, fmt.Errorf("migrate template database: %w", err)
+	}
+
+	return &pgServer{host: c.HostPort, master: master}, nil
+}
+
+// connect returns a client of the database once the server accepts connections.
+func connect(host string, name string) (*postgres.Client, error) {
+	client, err := postgres.NewClient(postgres.Config{
+		User:       "postgres",
+		Password:   "password",
+		Host:       host,
+		Name:       name,
+		DisableTLS: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create client of database %s: %w", name, err)
+	}
+
+	//slow machine
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*5)
+	defer cancel()
+
+	if err := client.StatusCheck(ctx); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("status check of database %s: %w", name, err)
+	}
+	return client, nil
+}
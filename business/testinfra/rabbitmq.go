@@ -0,0 +1,94 @@
+package testinfra
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/broker/rabbitmq"
+	"github.com/hamidoujand/task-scheduler/foundation/docker"
+)
+
+// rabbitServer represents the shared rabbitmq container.
+type rabbitServer struct {
+	container docker.Container
+}
+
+var rabbit shared[*rabbitServer]
+
+// NewRabbitMQ returns a client of a vhost of its own, the vhost is deleted along with its queues once the test is
+// done.
+func NewRabbitMQ(t *testing.T) *rabbitmq.Client {
+	t.Helper()
+	return NewRabbitMQWithConfigs(t, rabbitmq.Configs{})
+}
+
+// NewRabbitMQWithConfigs is NewRabbitMQ for a client with a queue prefix or an exchange, the connection settings of
+// conf are replaced by the ones of the vhost.
+func NewRabbitMQWithConfigs(t *testing.T, conf rabbitmq.Configs) *rabbitmq.Client {
+	t.Helper()
+
+	srv := rabbit.get(t, startRabbitMQ)
+
+	vhost := randomName("test_")
+	if _, err := srv.container.Exec("rabbitmqctl", "add_vhost", vhost); err != nil {
+		t.Fatalf("testinfra: add vhost %s: %s", vhost, err)
+	}
+
+	if _, err := srv.container.Exec("rabbitmqctl", "set_permissions", "-p", vhost, "guest", ".*", ".*", ".*"); err != nil {
+		t.Fatalf("testinfra: set permissions of vhost %s: %s", vhost, err)
+	}
+
+	conf.Host = srv.container.HostPort
+	conf.VHost = vhost
+	client, err := connectRabbitMQ(conf)
+	if err != nil {
+		t.Fatalf("testinfra: %s", err)
+	}
+
+	t.Cleanup(func() {
+		if err := client.Close(); err != nil {
+			t.Errorf("testinfra: close rabbitmq client: %s", err)
+		}
+
+		if _, err := srv.container.Exec("rabbitmqctl", "delete_vhost", vhost); err != nil {
+			t.Errorf("testinfra: delete vhost %s: %s", vhost, err)
+		}
+	})
+
+	return client
+}
+
+func startRabbitMQ() (*rabbitServer, error) {
+	c, err := startContainer("rabbitmq:3.13.6", "5672", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	//the broker is ready once it accepts connections
+	client, err := connectRabbitMQ(rabbitmq.Configs{Host: c.HostPort})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := client.Close(); err != nil {
+		return nil, fmt.Errorf("close rabbitmq client: %w", err)
+	}
+
+	return &rabbitServer{container: c}, nil
+}
+
+func connectRabbitMQ(conf rabbitmq.Configs) (*rabbitmq.Client, error) {
+	//slow machine
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*2)
+	defer cancel()
+
+	conf.User = "guest"
+	conf.Password = "guest"
+	client, err := rabbitmq.NewClient(ctx, conf)
+	if err != nil {
+		return nil, fmt.Errorf("create rabbitmq client of vhost %q: %w", conf.VHost, err)
+	}
+	return client, nil
+}
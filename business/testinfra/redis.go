@@ -0,0 +1,83 @@
+package testinfra
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisDatabases is how many db indexes the shared redis has, tests wait for one once all of them are taken.
+const redisDatabases = 256
+
+// redisServer represents the shared redis container.
+type redisServer struct {
+	host string
+	//free holds the db indexes that are not used by a test.
+	free chan int
+}
+
+var rds shared[*redisServer]
+
+// NewRedis returns a client of a db index of its own, the index is flushed and handed to another test once the test
+// is done.
+func NewRedis(t *testing.T) *redis.Client {
+	t.Helper()
+
+	srv := rds.get(t, startRedis)
+
+	idx := <-srv.free
+	client := redis.NewClient(&redis.Options{
+		Addr: srv.host,
+		DB:   idx,
+	})
+
+	t.Cleanup(func() {
+		if err := client.FlushDB(context.Background()).Err(); err != nil {
+			t.Errorf("testinfra: flush redis db %d: %s", idx, err)
+		}
+
+		if err := client.Close(); err != nil {
+			t.Errorf("testinfra: close redis client: %s", err)
+		}
+		srv.free <- idx
+	})
+
+	return client
+}
+
+func startRedis() (*redisServer, error) {
+	c, err := startContainer("redis:7.4.0", "6379", nil, []string{"--databases", strconv.Itoa(redisDatabases)})
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: c.HostPort})
+	defer client.Close()
+
+	//slow machine
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*2)
+	defer cancel()
+
+	for attempt := 1; ; attempt++ {
+		pingErr := client.Ping(ctx).Err()
+		if pingErr == nil {
+			break
+		}
+
+		time.Sleep(time.Millisecond * 100 * time.Duration(attempt))
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("ping redis: %w", pingErr)
+		}
+	}
+
+	free := make(chan int, redisDatabases)
+	for idx := range redisDatabases {
+		free <- idx
+	}
+
+	return &redisServer{host: c.HostPort, free: free}, nil
+}
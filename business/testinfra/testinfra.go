@@ -0,0 +1,97 @@
+// Package testinfra starts a single postgres, redis and rabbitmq container per test binary and isolates the tests on
+// top of them, every test gets a database, a redis db index and a rabbitmq vhost of its own. A container is only
+// started once the first test asks for it and they are all removed when the binary exits, so the packages that use
+// it must run their tests through Main:
+//
+//	func TestMain(m *testing.M) {
+//		testinfra.Main(m)
+//	}
+package testinfra
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/hamidoujand/task-scheduler/foundation/docker"
+)
+
+var (
+	mu sync.Mutex
+	//running is true while the tests run inside of Main.
+	running    bool
+	containers []docker.Container
+)
+
+// Main runs the tests of the binary and removes the containers they started.
+func Main(m *testing.M) {
+	mu.Lock()
+	running = true
+	mu.Unlock()
+
+	code := m.Run()
+
+	mu.Lock()
+	for _, c := range containers {
+		if err := c.Stop(); err != nil {
+			fmt.Fprintf(os.Stderr, "testinfra: failed to stop container %s: %s\n", c.Id, err)
+		}
+	}
+	containers = nil
+	mu.Unlock()
+
+	os.Exit(code)
+}
+
+// shared represents a container that is started once by the first test that needs it.
+type shared[T any] struct {
+	once  sync.Once
+	value T
+	err   error
+}
+
+// get starts the container with start unless it was started already, the tests fail when it could not be started.
+func (s *shared[T]) get(t *testing.T, start func() (T, error)) T {
+	t.Helper()
+
+	mu.Lock()
+	ok := running
+	mu.Unlock()
+
+	if !ok {
+		t.Fatal("testinfra: the tests of the package must run through testinfra.Main inside of TestMain")
+	}
+
+	s.once.Do(func() {
+		s.value, s.err = start()
+	})
+
+	if s.err != nil {
+		t.Fatalf("testinfra: %s", s.err)
+	}
+	return s.value
+}
+
+// startContainer starts a container of the image with a name unique to this binary and keeps it for Main to remove.
+func startContainer(image string, port string, dockerArgs []string, imageArgs []string) (docker.Container, error) {
+	c, err := docker.StartContainer(image, randomName("testinfra_"), port, dockerArgs, imageArgs)
+	if err != nil {
+		return docker.Container{}, fmt.Errorf("start container of %s: %w", image, err)
+	}
+
+	mu.Lock()
+	containers = append(containers, c)
+	mu.Unlock()
+
+	return c, nil
+}
+
+// randomName returns the prefix followed by random lowercase hex characters.
+func randomName(prefix string) string {
+	bs := make([]byte, 8)
+	rand.Read(bs)
+	return prefix + hex.EncodeToString(bs)
+}
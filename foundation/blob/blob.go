@@ -0,0 +1,90 @@
+// Package blob provides storage for large objects that do not belong inside of the database, Dir keeps them on
+// the local filesystem and the s3 package inside of an S3 compatible object storage.
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotFound is returned when there is no object with the key.
+var ErrNotFound = errors.New("blob not found")
+
+// Dir represents a blob storage inside of a directory, keys are paths relative to it.
+type Dir struct {
+	root string
+}
+
+// NewDir creates the directory when it does not exist and returns a storage on top of it.
+func NewDir(root string) (*Dir, error) {
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("mkdir: %w", err)
+	}
+	return &Dir{root: root}, nil
+}
+
+// Put stores the data under the key, replacing what was stored before.
+func (d *Dir) Put(ctx context.Context, key string, data []byte) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("mkdir: %w", err)
+	}
+
+	//readers never see a partially written object
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o640); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("rename: %w", err)
+	}
+	return nil
+}
+
+// Get returns the data stored under the key or ErrNotFound.
+func (d *Dir) Get(ctx context.Context, key string) ([]byte, error) {
+	path, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("read: %w", err)
+	}
+	return data, nil
+}
+
+// Delete deletes the data stored under the key, deleting a missing key is not an error.
+func (d *Dir) Delete(ctx context.Context, key string) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("remove: %w", err)
+	}
+	return nil
+}
+
+// path returns the file of the key, keys can not escape the root.
+func (d *Dir) path(key string) (string, error) {
+	if !fs.ValidPath(key) || strings.HasSuffix(key, ".tmp") {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	return filepath.Join(d.root, filepath.FromSlash(key)), nil
+}
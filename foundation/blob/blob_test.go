@@ -0,0 +1,45 @@
+package blob_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hamidoujand/task-scheduler/foundation/blob"
+)
+
+func TestDir(t *testing.T) {
+	t.Parallel()
+
+	store, err := blob.NewDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("expected to create the store: %s", err)
+	}
+
+	ctx := context.Background()
+	key := "results/task-1"
+
+	if err := store.Put(ctx, key, []byte("large output")); err != nil {
+		t.Fatalf("expected to put the object: %s", err)
+	}
+
+	data, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("expected to get the object: %s", err)
+	}
+	if string(data) != "large output" {
+		t.Errorf("data= %s, got %s", "large output", data)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("expected to delete the object: %s", err)
+	}
+
+	if _, err := store.Get(ctx, key); !errors.Is(err, blob.ErrNotFound) {
+		t.Errorf("err= %v, got %v", blob.ErrNotFound, err)
+	}
+
+	if err := store.Put(ctx, "../escape", nil); err == nil {
+		t.Error("expected keys outside of the root to be rejected")
+	}
+}
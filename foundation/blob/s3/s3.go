@@ -0,0 +1,217 @@
+// Package s3 provides a blob storage on top of an S3 compatible object storage. Requests are signed with signature
+// version 4 directly to avoid pulling the whole aws sdk for three api calls.
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/foundation/blob"
+)
+
+// Config represents all of the configuration required to talk to the object storage.
+type Config struct {
+	Bucket          string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	//Endpoint overrides the regional endpoint, useful for minio and other S3 compatible storages. Objects are
+	//addressed with the bucket inside of the path.
+	Endpoint string
+	Timeout  time.Duration
+}
+
+// Store represents the objects of a bucket.
+type Store struct {
+	conf     Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+// New creates a store for the bucket.
+func New(conf Config) (*Store, error) {
+	if conf.Bucket == "" {
+		return nil, errors.New("s3 bucket is required")
+	}
+
+	if conf.Region == "" {
+		return nil, errors.New("s3 region is required")
+	}
+
+	if conf.AccessKeyID == "" || conf.SecretAccessKey == "" {
+		return nil, errors.New("s3 credentials are required")
+	}
+
+	endpoint := conf.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", conf.Region)
+	}
+
+	u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("parse endpoint: %w", err)
+	}
+
+	if conf.Timeout <= 0 {
+		conf.Timeout = time.Second * 30
+	}
+
+	return &Store{
+		conf:     conf,
+		endpoint: u,
+		client:   &http.Client{Timeout: conf.Timeout},
+	}, nil
+}
+
+// Put stores the data under the key, replacing what was stored before.
+func (s *Store) Put(ctx context.Context, key string, data []byte) error {
+	resp, err := s.do(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return fmt.Errorf("put object: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseErr(resp)
+	}
+	return nil
+}
+
+// Get returns the data stored under the key or blob.ErrNotFound.
+func (s *Store) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, fmt.Errorf("get object: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, blob.ErrNotFound
+	default:
+		return nil, responseErr(resp)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	return data, nil
+}
+
+// Delete deletes the data stored under the key, deleting a missing key is not an error.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return fmt.Errorf("delete object: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return responseErr(resp)
+	}
+	return nil
+}
+
+func (s *Store) do(ctx context.Context, method string, key string, body []byte) (*http.Response, error) {
+	u := *s.endpoint
+	u.Path = u.Path + "/" + s.conf.Bucket + "/" + strings.TrimPrefix(key, "/")
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+
+	s.signRequest(req, body)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do: %w", err)
+	}
+	return resp, nil
+}
+
+// signRequest adds the signature version 4 authorization header to the request.
+func (s *Store) signRequest(req *http.Request, body []byte) {
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	payloadHash := sha256.Sum256(body)
+	contentHash := hex.EncodeToString(payloadHash[:])
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", contentHash)
+	if s.conf.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.conf.SessionToken)
+	}
+
+	//headers must be sorted by name
+	headers := [][2]string{
+		{"host", req.URL.Host},
+		{"x-amz-content-sha256", contentHash},
+		{"x-amz-date", amzDate},
+	}
+	if s.conf.SessionToken != "" {
+		headers = append(headers, [2]string{"x-amz-security-token", s.conf.SessionToken})
+	}
+
+	var canonicalHeaders strings.Builder
+	names := make([]string, 0, len(headers))
+	for _, h := range headers {
+		canonicalHeaders.WriteString(h[0] + ":" + strings.TrimSpace(h[1]) + "\n")
+		names = append(names, h[0])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		"",
+		canonicalHeaders.String(),
+		signedHeaders,
+		contentHash,
+	}, "\n")
+
+	scope := date + "/" + s.conf.Region + "/s3/aws4_request"
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(canonicalHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.conf.SecretAccessKey), date)
+	key = hmacSHA256(key, s.conf.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	authorization := fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.conf.AccessKeyID, scope, signedHeaders, signature)
+	req.Header.Set("Authorization", authorization)
+}
+
+func responseErr(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	return fmt.Errorf("s3 responded with %d: %s", resp.StatusCode, body)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,88 @@
+package s3_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hamidoujand/task-scheduler/foundation/blob"
+	"github.com/hamidoujand/task-scheduler/foundation/blob/s3"
+)
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	objects := make(map[string][]byte)
+
+	//fake object storage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, "/eu-west-1/s3/aws4_request") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		if !strings.HasPrefix(r.URL.Path, "/results-bucket/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.Method {
+		case http.MethodPut:
+			data, _ := io.ReadAll(r.Body)
+			objects[r.URL.Path] = data
+		case http.MethodGet:
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	store, err := s3.New(s3.Config{
+		Bucket:          "results-bucket",
+		Region:          "eu-west-1",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		Endpoint:        srv.URL,
+	})
+	if err != nil {
+		t.Fatalf("expected to create the store: %s", err)
+	}
+
+	ctx := context.Background()
+	if err := store.Put(ctx, "results/task-1", []byte("large output")); err != nil {
+		t.Fatalf("expected to put the object: %s", err)
+	}
+
+	data, err := store.Get(ctx, "results/task-1")
+	if err != nil {
+		t.Fatalf("expected to get the object: %s", err)
+	}
+	if string(data) != "large output" {
+		t.Errorf("data= %s, got %s", "large output", data)
+	}
+
+	if err := store.Delete(ctx, "results/task-1"); err != nil {
+		t.Fatalf("expected to delete the object: %s", err)
+	}
+
+	if _, err := store.Get(ctx, "results/task-1"); !errors.Is(err, blob.ErrNotFound) {
+		t.Errorf("err= %v, got %v", blob.ErrNotFound, err)
+	}
+}
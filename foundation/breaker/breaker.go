@@ -0,0 +1,187 @@
+// Package breaker provides a circuit breaker for the calls to an external dependency. After repeated failures the
+// calls fail fast instead of piling up while the dependency is down, a single call is let through once in a while
+// to find out whether it is back.
+package breaker
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/foundation/clock"
+)
+
+// ErrOpen is returned instead of calling the dependency while the breaker is open.
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State represents the state of a breaker.
+type State int
+
+const (
+	//Closed lets every call through.
+	Closed State = iota
+	//Open fails every call fast.
+	Open
+	//HalfOpen lets a single call through to probe the dependency.
+	HalfOpen
+)
+
+// String returns the name of the state.
+func (s State) String() string {
+	switch s {
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// Config represents the configuration of a breaker.
+type Config struct {
+	//Threshold is how many calls have to fail in a row to open the breaker, defaults to 5.
+	Threshold int
+	//Cooldown is how long the breaker stays open before a call probes the dependency, defaults to 10s.
+	Cooldown time.Duration
+	//IsFailure is optional and tells the errors that point to an outage apart from the ones of a healthy
+	//dependency, like a missing record. Defaults to every error.
+	IsFailure func(err error) bool
+	//OnStateChange is optional and is called with every transition, without holding the breaker.
+	OnStateChange func(name string, from State, to State)
+	//Clock defaults to the real clock.
+	Clock clock.Clock
+}
+
+// Breaker represents a circuit breaker around a single dependency, it is safe for concurrent use.
+type Breaker struct {
+	name          string
+	threshold     int
+	cooldown      time.Duration
+	isFailure     func(err error) bool
+	onStateChange func(name string, from State, to State)
+	clock         clock.Clock
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	//probeAt is when the call that probes the dependency was let through.
+	probeAt time.Time
+}
+
+// New creates a closed breaker for the dependency with the given name.
+func New(name string, conf Config) *Breaker {
+	if conf.Threshold <= 0 {
+		conf.Threshold = 5
+	}
+
+	if conf.Cooldown <= 0 {
+		conf.Cooldown = time.Second * 10
+	}
+
+	if conf.IsFailure == nil {
+		conf.IsFailure = func(err error) bool { return err != nil }
+	}
+
+	if conf.Clock == nil {
+		conf.Clock = clock.New()
+	}
+
+	return &Breaker{
+		name:          name,
+		threshold:     conf.Threshold,
+		cooldown:      conf.Cooldown,
+		isFailure:     conf.IsFailure,
+		onStateChange: conf.OnStateChange,
+		clock:         conf.Clock,
+	}
+}
+
+// Name returns the name of the dependency.
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Allow reports whether a call may go to the dependency, it returns an error wrapping ErrOpen when it may not.
+// Every allowed call must be followed by Done with its result.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	from := b.state
+	now := b.clock.Now()
+
+	switch {
+	case b.state == Closed:
+		b.mu.Unlock()
+		return nil
+
+	case b.state == Open && now.Sub(b.openedAt) < b.cooldown:
+		b.mu.Unlock()
+		return fmt.Errorf("%s: %w", b.name, ErrOpen)
+
+	//a probe that never reported back does not keep the breaker half open forever
+	case b.state == HalfOpen && now.Sub(b.probeAt) < b.cooldown:
+		b.mu.Unlock()
+		return fmt.Errorf("%s: %w", b.name, ErrOpen)
+	}
+
+	b.state = HalfOpen
+	b.probeAt = now
+	b.mu.Unlock()
+
+	b.changed(from, HalfOpen)
+	return nil
+}
+
+// Done reports the result of a call that was allowed.
+func (b *Breaker) Done(err error) {
+	b.mu.Lock()
+	from := b.state
+
+	if !b.isFailure(err) {
+		b.failures = 0
+		b.state = Closed
+		b.mu.Unlock()
+
+		b.changed(from, Closed)
+		return
+	}
+
+	b.failures++
+	if b.state == Closed && b.failures < b.threshold {
+		b.mu.Unlock()
+		return
+	}
+
+	//a failed probe opens the breaker for another cooldown
+	b.state = Open
+	b.openedAt = b.clock.Now()
+	b.mu.Unlock()
+
+	b.changed(from, Open)
+}
+
+// Do calls fn unless the breaker is open and reports its result.
+func (b *Breaker) Do(fn func() error) error {
+	if err := b.Allow(); err != nil {
+		return err
+	}
+
+	err := fn()
+	b.Done(err)
+	return err
+}
+
+func (b *Breaker) changed(from State, to State) {
+	if from != to && b.onStateChange != nil {
+		b.onStateChange(b.name, from, to)
+	}
+}
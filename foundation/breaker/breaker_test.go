@@ -0,0 +1,113 @@
+package breaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/foundation/breaker"
+	"github.com/hamidoujand/task-scheduler/foundation/clock"
+	"github.com/redis/go-redis/v9"
+)
+
+var errDown = errors.New("connection refused")
+
+func TestBreaker(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+
+	var transitions []string
+	b := breaker.New("postgres", breaker.Config{
+		Threshold: 3,
+		Cooldown:  time.Second * 10,
+		Clock:     fake,
+		OnStateChange: func(name string, from breaker.State, to breaker.State) {
+			transitions = append(transitions, from.String()+"->"+to.String())
+		},
+	})
+
+	fail := func() error { return errDown }
+	succeed := func() error { return nil }
+
+	//a success in between resets the count
+	b.Do(fail)
+	b.Do(fail)
+	b.Do(succeed)
+	b.Do(fail)
+	b.Do(fail)
+	if b.State() != breaker.Closed {
+		t.Fatalf("state= %s, got %s", breaker.Closed, b.State())
+	}
+
+	if err := b.Do(fail); !errors.Is(err, errDown) {
+		t.Fatalf("err= %v, got %v", errDown, err)
+	}
+	if b.State() != breaker.Open {
+		t.Fatalf("state= %s, got %s", breaker.Open, b.State())
+	}
+
+	//calls fail fast without reaching the dependency
+	called := false
+	err := b.Do(func() error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, breaker.ErrOpen) || called {
+		t.Fatalf("expected the call to fail fast with %v, got %v", breaker.ErrOpen, err)
+	}
+
+	//a failed probe opens the breaker again
+	fake.Advance(time.Second * 10)
+	if err := b.Do(fail); !errors.Is(err, errDown) {
+		t.Fatalf("expected the probe to reach the dependency, got %v", err)
+	}
+	if err := b.Do(succeed); !errors.Is(err, breaker.ErrOpen) {
+		t.Fatalf("err= %v, got %v", breaker.ErrOpen, err)
+	}
+
+	//only one probe goes through at a time
+	fake.Advance(time.Second * 10)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("expected the probe to be allowed: %s", err)
+	}
+	if err := b.Allow(); !errors.Is(err, breaker.ErrOpen) {
+		t.Fatalf("err= %v, got %v", breaker.ErrOpen, err)
+	}
+	b.Done(nil)
+
+	if b.State() != breaker.Closed {
+		t.Fatalf("state= %s, got %s", breaker.Closed, b.State())
+	}
+
+	expected := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	if len(transitions) != len(expected) {
+		t.Fatalf("transitions= %v, got %v", expected, transitions)
+	}
+	for i := range expected {
+		if transitions[i] != expected[i] {
+			t.Errorf("transitions= %v, got %v", expected, transitions)
+			break
+		}
+	}
+}
+
+func TestIsRedisFailure(t *testing.T) {
+	tests := map[string]struct {
+		err     error
+		failure bool
+	}{
+		"nil":         {err: nil, failure: false},
+		"missing key": {err: redis.Nil, failure: false},
+		"connection":  {err: errDown, failure: true},
+		"canceled":    {err: context.Canceled, failure: false},
+		"closed":      {err: redis.ErrClosed, failure: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := breaker.IsRedisFailure(test.err); got != test.failure {
+				t.Errorf("failure= %t, got %t", test.failure, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisHook runs the commands of a redis client through the breaker.
+type redisHook struct {
+	b *Breaker
+}
+
+// NewRedisHook returns the hook that runs the commands of a redis client through b, it is added to the client with
+// AddHook. Replies like redis.Nil or the errors of a command come from a healthy server and do not count as failures.
+func NewRedisHook(b *Breaker) redis.Hook {
+	return redisHook{b: b}
+}
+
+// IsRedisFailure reports whether err points to an outage of redis.
+func IsRedisFailure(err error) bool {
+	if err == nil || errors.Is(err, redis.Nil) || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var replyErr redis.Error
+	return !errors.As(err, &replyErr)
+}
+
+func (h redisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h redisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if err := h.b.Allow(); err != nil {
+			cmd.SetErr(err)
+			return err
+		}
+
+		err := next(ctx, cmd)
+		h.done(err)
+		return err
+	}
+}
+
+func (h redisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if err := h.b.Allow(); err != nil {
+			for _, cmd := range cmds {
+				cmd.SetErr(err)
+			}
+			return err
+		}
+
+		err := next(ctx, cmds)
+		h.done(err)
+		return err
+	}
+}
+
+func (h redisHook) done(err error) {
+	if !IsRedisFailure(err) {
+		err = nil
+	}
+	h.b.Done(err)
+}
@@ -0,0 +1,184 @@
+// Package clock provides an abstraction over time so time dependent code can be driven by tests
+// without real waits.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock represents the set of time functionality used by the application.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker represents a ticker created by a Clock.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Real is the Clock backed by the time package.
+type Real struct{}
+
+// New creates a real clock.
+func New() Real {
+	return Real{}
+}
+
+// Now returns the current time.
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// After waits for the duration to elapse and then sends the current time on the returned channel.
+func (Real) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// NewTicker returns a new ticker that ticks every d.
+func (Real) NewTicker(d time.Duration) Ticker {
+	return realTicker{ticker: time.NewTicker(d)}
+}
+
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+func (rt realTicker) C() <-chan time.Time {
+	return rt.ticker.C
+}
+
+func (rt realTicker) Stop() {
+	rt.ticker.Stop()
+}
+
+// =============================================================================
+
+// Fake is a Clock that only moves when Advance or Set is called, timers and tickers fire
+// synchronously during the call that moves the time past their deadline.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*waiter
+}
+
+type waiter struct {
+	deadline time.Time
+	period   time.Duration
+	ch       chan time.Time
+	stopped  bool
+}
+
+// NewFake creates a fake clock that starts at the given time.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now returns the current fake time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// After returns a channel that receives the fake time once the clock is advanced by d.
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w := waiter{
+		deadline: f.now.Add(d),
+		ch:       make(chan time.Time, 1),
+	}
+
+	//already due
+	if d <= 0 {
+		w.ch <- f.now
+		return w.ch
+	}
+
+	f.waiters = append(f.waiters, &w)
+	return w.ch
+}
+
+// NewTicker returns a ticker that ticks every d of fake time, like time.Ticker ticks are dropped
+// when the receiver is not keeping up.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w := waiter{
+		deadline: f.now.Add(d),
+		period:   d,
+		ch:       make(chan time.Time, 1),
+	}
+	f.waiters = append(f.waiters, &w)
+
+	return &fakeTicker{clock: f, waiter: &w}
+}
+
+// Advance moves the clock forward by d and fires all of the timers and tickers that became due.
+func (f *Fake) Advance(d time.Duration) {
+	f.Set(f.Now().Add(d))
+}
+
+// Set moves the clock to the given time and fires all of the timers and tickers that became due,
+// moving the clock backwards is ignored.
+func (f *Fake) Set(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if now.Before(f.now) {
+		return
+	}
+	f.now = now
+
+	pending := f.waiters[:0]
+	for _, w := range f.waiters {
+		if w.stopped {
+			continue
+		}
+
+		if w.deadline.After(now) {
+			pending = append(pending, w)
+			continue
+		}
+
+		select {
+		case w.ch <- now:
+		default:
+			//receiver is behind, drop the tick
+		}
+
+		//tickers stay registered
+		if w.period > 0 {
+			for !w.deadline.After(now) {
+				w.deadline = w.deadline.Add(w.period)
+			}
+			pending = append(pending, w)
+		}
+	}
+	f.waiters = pending
+}
+
+type fakeTicker struct {
+	clock  *Fake
+	waiter *waiter
+}
+
+func (ft *fakeTicker) C() <-chan time.Time {
+	return ft.waiter.ch
+}
+
+func (ft *fakeTicker) Stop() {
+	ft.clock.mu.Lock()
+	defer ft.clock.mu.Unlock()
+	ft.waiter.stopped = true
+}
@@ -0,0 +1,62 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/foundation/clock"
+)
+
+func TestFakeAfter(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+
+	ch := fake.After(time.Minute)
+
+	fake.Advance(time.Second * 59)
+	select {
+	case <-ch:
+		t.Fatal("expected timer to not fire before its deadline")
+	default:
+	}
+
+	fake.Advance(time.Second)
+	select {
+	case got := <-ch:
+		if want := start.Add(time.Minute); !got.Equal(want) {
+			t.Errorf("firedAt= %s, got %s", want, got)
+		}
+	default:
+		t.Fatal("expected timer to fire at its deadline")
+	}
+
+	if want := start.Add(time.Minute); !fake.Now().Equal(want) {
+		t.Errorf("now= %s, got %s", want, fake.Now())
+	}
+}
+
+func TestFakeTicker(t *testing.T) {
+	t.Parallel()
+
+	fake := clock.NewFake(time.Now())
+	ticker := fake.NewTicker(time.Minute)
+
+	for i := range 3 {
+		fake.Advance(time.Minute)
+		select {
+		case <-ticker.C():
+		default:
+			t.Fatalf("expected tick %d", i+1)
+		}
+	}
+
+	ticker.Stop()
+	fake.Advance(time.Minute)
+	select {
+	case <-ticker.C():
+		t.Error("expected stopped ticker to not tick")
+	default:
+	}
+}
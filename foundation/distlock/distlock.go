@@ -0,0 +1,103 @@
+// Package distlock provides a lock inside of redis that is held by one process at a time, it expires on its own
+// when the process that holds it dies.
+package distlock
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// acquireScript sets the lock when it is free with SET NX or extends it when the caller already owns it.
+var acquireScript = redis.NewScript(`
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return 1
+end
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// releaseScript only deletes the lock when the caller owns it.
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// Lock represents a named lock, it must be renewed by calling Acquire again before its ttl passes to keep it.
+type Lock struct {
+	client *redis.Client
+	key    string
+	owner  string
+	ttl    time.Duration
+
+	mu        sync.Mutex
+	renewedAt time.Time
+}
+
+// New creates a lock with the given name, every Lock is a different owner even with the same name.
+func New(client *redis.Client, name string, ttl time.Duration) *Lock {
+	return &Lock{
+		client: client,
+		key:    "locks:" + name,
+		owner:  uuid.NewString(),
+		ttl:    ttl,
+	}
+}
+
+// TTL returns how long the lock is held after it was acquired or renewed.
+func (l *Lock) TTL() time.Duration {
+	return l.ttl
+}
+
+// Acquire acquires the lock or renews it when it is already held by this owner, returns false when another owner
+// holds it.
+func (l *Lock) Acquire(ctx context.Context) (bool, error) {
+	started := time.Now()
+
+	acquired, err := acquireScript.Run(ctx, l.client, []string{l.key}, l.owner, l.ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("run acquire script: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if acquired != 1 {
+		l.renewedAt = time.Time{}
+		return false, nil
+	}
+
+	//the ttl started before the script ran
+	l.renewedAt = started
+	return true, nil
+}
+
+// Held reports whether the lock is held by this owner, it stays held until its ttl passes when renewing it
+// failed, for example because redis was not reachable.
+func (l *Lock) Held() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return !l.renewedAt.IsZero() && time.Since(l.renewedAt) < l.ttl
+}
+
+// Release releases the lock if this owner still holds it.
+func (l *Lock) Release(ctx context.Context) error {
+	l.mu.Lock()
+	l.renewedAt = time.Time{}
+	l.mu.Unlock()
+
+	if err := releaseScript.Run(ctx, l.client, []string{l.key}, l.owner).Err(); err != nil {
+		return fmt.Errorf("run release script: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package distlock_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/business/testinfra"
+	"github.com/hamidoujand/task-scheduler/foundation/distlock"
+)
+
+func TestLock(t *testing.T) {
+	t.Parallel()
+	client := testinfra.NewRedis(t)
+
+	ctx := context.Background()
+	leader := distlock.New(client, "monitor", time.Second)
+	follower := distlock.New(client, "monitor", time.Second)
+
+	acquired, err := leader.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("expected to acquire lock: %s", err)
+	}
+	if !acquired || !leader.Held() {
+		t.Fatal("expected the leader to hold the lock")
+	}
+
+	acquired, err = follower.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("expected to try the lock: %s", err)
+	}
+	if acquired || follower.Held() {
+		t.Fatal("expected the follower to not get the lock while the leader holds it")
+	}
+
+	//renewing by the owner keeps it
+	acquired, err = leader.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("expected to renew lock: %s", err)
+	}
+	if !acquired {
+		t.Fatal("expected the leader to renew the lock")
+	}
+
+	//the leader dies and stops renewing
+	time.Sleep(time.Second + time.Millisecond*100)
+	if leader.Held() {
+		t.Error("expected the lock to not be held after its ttl")
+	}
+
+	acquired, err = follower.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("expected to acquire lock: %s", err)
+	}
+	if !acquired {
+		t.Fatal("expected the follower to take over the expired lock")
+	}
+
+	//releasing a lock of someone else does nothing
+	if err := leader.Release(ctx); err != nil {
+		t.Fatalf("expected to release lock: %s", err)
+	}
+	if acquired, _ := leader.Acquire(ctx); acquired {
+		t.Fatal("expected the follower to still hold the lock")
+	}
+
+	if err := follower.Release(ctx); err != nil {
+		t.Fatalf("expected to release lock: %s", err)
+	}
+	if acquired, _ := leader.Acquire(ctx); !acquired {
+		t.Fatal("expected the released lock to be free")
+	}
+}
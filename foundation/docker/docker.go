@@ -3,7 +3,6 @@ package docker
 
 import (
 	"bytes"
-	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -101,6 +100,15 @@ func stopContainer(id string) error {
 	return nil
 }
 
+// Exec runs the command inside of the container and returns its combined output.
+func (c Container) Exec(command string, args ...string) ([]byte, error) {
+	output, err := exec.Command("docker", append([]string{"exec", c.Id, command}, args...)...).CombinedOutput()
+	if err != nil {
+		return output, fmt.Errorf("exec %s inside of container %s: %s: %w", command, c.Id, output, err)
+	}
+	return output, nil
+}
+
 // DumpLogs is going to return the combined logs of the current container, from both stderr and stdout.
 func (c Container) DumpLogs() []byte {
 	logs, err := exec.Command("docker", "logs", c.Id).CombinedOutput()
@@ -168,28 +176,3 @@ func exists(id string, port string) (Container, error) {
 	}
 	return c, nil
 }
-
-// RunCommand is going to create a container and execute one-time commands inside of it
-// for services use "StartContainer".
-func RunCommand(ctx context.Context, image string, command string, dockerArgs []string, cmdArgs []string) (string, error) {
-	args := []string{"run", "--rm"}
-	args = append(args, dockerArgs...)
-	args = append(args, image)
-	args = append(args, command)
-	args = append(args, cmdArgs...)
-
-	cmd := exec.CommandContext(ctx, "docker", args...)
-
-	var stdout bytes.Buffer
-	var stderr bytes.Buffer
-
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
-	if err != nil {
-		return "", fmt.Errorf("command execution failed:stderr:%s:%w", stderr.String(), err)
-	}
-
-	return stdout.String(), nil
-}
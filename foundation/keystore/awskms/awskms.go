@@ -0,0 +1,288 @@
+// Package awskms provides a keystore on top of AWS KMS asymmetric keys, tokens are signed inside of kms
+// so private keys never leave it. Requests are signed with signature version 4 directly to avoid pulling
+// the whole aws sdk for two api calls.
+package awskms
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config represents all of the configuration required to talk to kms.
+type Config struct {
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	//Endpoint overrides the regional endpoint, useful for vpc endpoints and local emulators.
+	Endpoint string
+	//Keys are the kms keys tokens are accepted from, any other kid is rejected without asking kms.
+	Keys    []string
+	Timeout time.Duration
+}
+
+// failureTTL is how long a failed lookup of a public key is remembered, so tokens with a bad kid do not reach kms on
+// every request.
+const failureTTL = time.Minute
+
+// KeyStore represents a keystore that uses kms keys, the key id is the kms key id, arn or alias and the
+// key must have the "SIGN_VERIFY" usage with an RSA key spec.
+type KeyStore struct {
+	conf     Config
+	endpoint string
+	client   *http.Client
+
+	mu         sync.RWMutex
+	publicKeys map[string]publicKey
+}
+
+// publicKey is a cached lookup, a failed one is kept until expires.
+type publicKey struct {
+	pem     string
+	err     error
+	expires time.Time
+}
+
+// New creates a kms keystore.
+func New(conf Config) (*KeyStore, error) {
+	if conf.Region == "" {
+		return nil, errors.New("kms region is required")
+	}
+
+	if conf.AccessKeyID == "" || conf.SecretAccessKey == "" {
+		return nil, errors.New("kms credentials are required")
+	}
+
+	if len(conf.Keys) == 0 {
+		return nil, errors.New("at least one kms key is required")
+	}
+
+	endpoint := conf.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://kms.%s.amazonaws.com", conf.Region)
+	}
+
+	if conf.Timeout <= 0 {
+		conf.Timeout = time.Second * 5
+	}
+
+	return &KeyStore{
+		conf:       conf,
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		client:     &http.Client{Timeout: conf.Timeout},
+		publicKeys: make(map[string]publicKey),
+	}, nil
+}
+
+// Sign asks kms to sign the sha256 digest with RSASSA_PKCS1_V1_5_SHA_256.
+func (ks *KeyStore) Sign(ctx context.Context, kid string, digest []byte) ([]byte, error) {
+	if !slices.Contains(ks.conf.Keys, kid) {
+		return nil, fmt.Errorf("key with id %q is not configured", kid)
+	}
+
+	body := struct {
+		KeyId            string
+		Message          string
+		MessageType      string
+		SigningAlgorithm string
+	}{
+		KeyId:            kid,
+		Message:          base64.StdEncoding.EncodeToString(digest),
+		MessageType:      "DIGEST",
+		SigningAlgorithm: "RSASSA_PKCS1_V1_5_SHA_256",
+	}
+
+	var resp struct {
+		Signature string
+	}
+
+	if err := ks.do(ctx, "TrentService.Sign", body, &resp); err != nil {
+		return nil, fmt.Errorf("sign: %w", err)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(resp.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("decode signature: %w", err)
+	}
+	return signature, nil
+}
+
+// PublicKey fetches the public pem of the kms key, public keys are cached after the first lookup and failed lookups
+// for a minute.
+func (ks *KeyStore) PublicKey(kid string) (string, error) {
+	if !slices.Contains(ks.conf.Keys, kid) {
+		return "", fmt.Errorf("key with id %q is not configured", kid)
+	}
+
+	ks.mu.RLock()
+	key, ok := ks.publicKeys[kid]
+	ks.mu.RUnlock()
+
+	if ok && (key.expires.IsZero() || time.Now().Before(key.expires)) {
+		return key.pem, key.err
+	}
+
+	publicPEM, err := ks.getPublicKey(kid)
+
+	ks.mu.Lock()
+	if err != nil {
+		ks.publicKeys[kid] = publicKey{err: err, expires: time.Now().Add(failureTTL)}
+	} else {
+		ks.publicKeys[kid] = publicKey{pem: publicPEM}
+	}
+	ks.mu.Unlock()
+
+	return publicPEM, err
+}
+
+// getPublicKey asks kms for the public key of kid.
+func (ks *KeyStore) getPublicKey(kid string) (string, error) {
+	body := struct {
+		KeyId string
+	}{
+		KeyId: kid,
+	}
+
+	var resp struct {
+		PublicKey string
+	}
+
+	if err := ks.do(context.Background(), "TrentService.GetPublicKey", body, &resp); err != nil {
+		return "", fmt.Errorf("get public key: %w", err)
+	}
+
+	//kms returns DER encoded SubjectPublicKeyInfo
+	der, err := base64.StdEncoding.DecodeString(resp.PublicKey)
+	if err != nil {
+		return "", fmt.Errorf("decode public key: %w", err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+func (ks *KeyStore) do(ctx context.Context, target string, body any, v any) error {
+	bs, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ks.endpoint+"/", bytes.NewReader(bs))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", target)
+
+	if err := ks.signRequest(req, bs); err != nil {
+		return fmt.Errorf("sign request: %w", err)
+	}
+
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return fmt.Errorf("read body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("kms responded with %d: %s", resp.StatusCode, respBody)
+	}
+
+	if err := json.Unmarshal(respBody, v); err != nil {
+		return fmt.Errorf("unmarshal: %w", err)
+	}
+	return nil
+}
+
+// signRequest adds the signature version 4 authorization header to the request.
+func (ks *KeyStore) signRequest(req *http.Request, body []byte) error {
+	u, err := url.Parse(ks.endpoint)
+	if err != nil {
+		return fmt.Errorf("parse endpoint: %w", err)
+	}
+
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if ks.conf.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", ks.conf.SessionToken)
+	}
+
+	//headers must be sorted by name
+	headers := [][2]string{
+		{"content-type", req.Header.Get("Content-Type")},
+		{"host", u.Host},
+		{"x-amz-date", amzDate},
+	}
+	if ks.conf.SessionToken != "" {
+		headers = append(headers, [2]string{"x-amz-security-token", ks.conf.SessionToken})
+	}
+	headers = append(headers, [2]string{"x-amz-target", req.Header.Get("X-Amz-Target")})
+
+	var canonicalHeaders strings.Builder
+	names := make([]string, 0, len(headers))
+	for _, h := range headers {
+		canonicalHeaders.WriteString(h[0] + ":" + strings.TrimSpace(h[1]) + "\n")
+		names = append(names, h[0])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		http.MethodPost,
+		"/",
+		"",
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + ks.conf.Region + "/kms/aws4_request"
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(canonicalHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+ks.conf.SecretAccessKey), date)
+	key = hmacSHA256(key, ks.conf.Region)
+	key = hmacSHA256(key, "kms")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	authorization := fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		ks.conf.AccessKeyID, scope, signedHeaders, signature)
+	req.Header.Set("Authorization", authorization)
+
+	return nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
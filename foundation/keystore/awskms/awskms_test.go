@@ -0,0 +1,132 @@
+package awskms_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hamidoujand/task-scheduler/foundation/keystore/awskms"
+)
+
+func TestKeyStore(t *testing.T) {
+	t.Parallel()
+
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("expected to generate a key: %s", err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&private.PublicKey)
+	if err != nil {
+		t.Fatalf("expected to marshal public key: %s", err)
+	}
+
+	//fake kms
+	var requests atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, "/eu-west-1/kms/aws4_request") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		var body struct {
+			KeyId   string
+			Message string
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if body.KeyId != "alias/jwt" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.Sign":
+			digest, _ := base64.StdEncoding.DecodeString(body.Message)
+			signature, _ := rsa.SignPKCS1v15(rand.Reader, private, crypto.SHA256, digest)
+			json.NewEncoder(w).Encode(map[string]string{"Signature": base64.StdEncoding.EncodeToString(signature)})
+		case "TrentService.GetPublicKey":
+			json.NewEncoder(w).Encode(map[string]string{"PublicKey": base64.StdEncoding.EncodeToString(der)})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	ks, err := awskms.New(awskms.Config{
+		Region:          "eu-west-1",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		Endpoint:        srv.URL,
+		Keys:            []string{"alias/jwt", "alias/deleted"},
+	})
+	if err != nil {
+		t.Fatalf("expected to create the keystore: %s", err)
+	}
+
+	digest := sha256.Sum256([]byte("header.payload"))
+	signature, err := ks.Sign(context.Background(), "alias/jwt", digest[:])
+	if err != nil {
+		t.Fatalf("expected to sign the digest: %s", err)
+	}
+
+	if err := rsa.VerifyPKCS1v15(&private.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+		t.Errorf("expected a valid signature: %s", err)
+	}
+
+	publicPEM, err := ks.PublicKey("alias/jwt")
+	if err != nil {
+		t.Fatalf("expected to fetch the public key: %s", err)
+	}
+
+	block, _ := pem.Decode([]byte(publicPEM))
+	if block == nil || block.Type != "PUBLIC KEY" {
+		t.Fatalf("expected a public key pem block, got %s", publicPEM)
+	}
+
+	if string(block.Bytes) != string(der) {
+		t.Error("expected the public key to match the kms key")
+	}
+
+	//kids of the tokens are untrusted, keys that are not configured never reach kms
+	before := requests.Load()
+	if _, err := ks.PublicKey("alias/other"); err == nil {
+		t.Error("expected an error when the key is not configured")
+	}
+
+	if _, err := ks.Sign(context.Background(), "alias/other", digest[:]); err == nil {
+		t.Error("expected an error when the key is not configured")
+	}
+
+	if got := requests.Load(); got != before {
+		t.Errorf("requests= %d, got %d", before, got)
+	}
+
+	//a failed lookup is remembered
+	for range 3 {
+		if _, err := ks.PublicKey("alias/deleted"); err == nil {
+			t.Error("expected an error when kms has no such key")
+		}
+	}
+
+	if got := requests.Load(); got != before+1 {
+		t.Errorf("requests= %d, got %d", before+1, got)
+	}
+}
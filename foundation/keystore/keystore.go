@@ -2,7 +2,11 @@
 package keystore
 
 import (
+	"context"
+	"crypto"
+	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
@@ -17,6 +21,7 @@ import (
 type key struct {
 	PrivatePEM string
 	PublicPEM  string
+	private    *rsa.PrivateKey
 }
 
 // KeyStore represents an in-memory key store.
@@ -89,6 +94,7 @@ func LoadFromFS(fsys fs.FS) (*KeyStore, error) {
 		store[strings.TrimSuffix(path, ".pem")] = key{
 			PrivatePEM: string(private),
 			PublicPEM:  builder.String(),
+			private:    privateRSA,
 		}
 		return nil
 	}
@@ -117,3 +123,30 @@ func (ks *KeyStore) PublicKey(kid string) (string, error) {
 	}
 	return key.PublicPEM, nil
 }
+
+// Sign signs the sha256 digest using the private key of the given key id with RSASSA-PKCS1-v1_5.
+func (ks *KeyStore) Sign(ctx context.Context, kid string, digest []byte) ([]byte, error) {
+	key, ok := ks.store[kid]
+	if !ok {
+		return nil, fmt.Errorf("private key with id %q not found", kid)
+	}
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key.private, crypto.SHA256, digest)
+	if err != nil {
+		return nil, fmt.Errorf("sign: %w", err)
+	}
+	return signature, nil
+}
+
+// EncryptionKey derives a 32 bytes symmetric key from the private key of the given key id, it is used for
+// encrypting data at rest and changes when the key is rotated.
+func (ks *KeyStore) EncryptionKey(kid string) ([]byte, error) {
+	key, ok := ks.store[kid]
+	if !ok {
+		return nil, fmt.Errorf("private key with id %q not found", kid)
+	}
+
+	//a label keeps the derived key apart from any other use of the private key
+	sum := sha256.Sum256(append([]byte("task-scheduler/encryption:"), key.private.D.Bytes()...))
+	return sum[:], nil
+}
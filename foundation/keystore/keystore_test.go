@@ -85,6 +85,18 @@ func TestKeystore(t *testing.T) {
 	if fetchedPublic != generatedPublic {
 		t.Errorf("public= %s, got %s", generatedPublic, fetchedPublic)
 	}
+
+	encKey, err := ks.EncryptionKey(kid)
+	if err != nil {
+		t.Fatalf("expected to derive encryption key: %s", err)
+	}
+	if len(encKey) != 32 {
+		t.Errorf("len(encryptionKey)= %d, got %d", 32, len(encKey))
+	}
+
+	if _, err := ks.EncryptionKey(uuid.NewString()); err == nil {
+		t.Error("expected an error for an unknown key id")
+	}
 }
 
 func generatePublic(privatePEM string) (string, error) {
@@ -0,0 +1,285 @@
+// Package vault provides a keystore on top of the hashicorp vault transit secrets engine, tokens are
+// signed inside of vault so private keys never leave it.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// failureTTL is how long a failed lookup of a public key is remembered, so tokens with a bad kid do not reach vault
+// on every request.
+const failureTTL = time.Minute
+
+// Config represents all of the configuration required to talk to vault.
+type Config struct {
+	Address   string
+	Token     string
+	MountPath string
+	//Keys are the names of the transit keys tokens are accepted from, any other kid is rejected without asking vault.
+	Keys    []string
+	Timeout time.Duration
+}
+
+// KeyStore represents a keystore that uses vault transit keys, the key must be of type "rsa-2048" or bigger. A kid
+// is the name of the transit key followed by the version that signed like "jwt:v2", a kid without a version stands
+// for the latest one.
+type KeyStore struct {
+	address   string
+	token     string
+	mountPath string
+	keys      []string
+	client    *http.Client
+
+	mu         sync.RWMutex
+	publicKeys map[string]publicKey
+}
+
+// publicKey is a cached lookup, a failed one is kept until expires.
+type publicKey struct {
+	pem     string
+	err     error
+	expires time.Time
+}
+
+// New creates a vault keystore.
+func New(conf Config) (*KeyStore, error) {
+	if conf.Address == "" {
+		return nil, errors.New("vault address is required")
+	}
+
+	if conf.Token == "" {
+		return nil, errors.New("vault token is required")
+	}
+
+	if len(conf.Keys) == 0 {
+		return nil, errors.New("at least one transit key is required")
+	}
+
+	if conf.MountPath == "" {
+		conf.MountPath = "transit"
+	}
+
+	if conf.Timeout <= 0 {
+		conf.Timeout = time.Second * 5
+	}
+
+	return &KeyStore{
+		address:    strings.TrimSuffix(conf.Address, "/"),
+		token:      conf.Token,
+		mountPath:  strings.Trim(conf.MountPath, "/"),
+		keys:       conf.Keys,
+		client:     &http.Client{Timeout: conf.Timeout},
+		publicKeys: make(map[string]publicKey),
+	}, nil
+}
+
+// SigningKid returns the kid of the latest version of the transit key, tokens carry it so they are checked against
+// the version that signed them after the key is rotated.
+func (ks *KeyStore) SigningKid(ctx context.Context, kid string) (string, error) {
+	name, _, err := ks.parseKid(kid)
+	if err != nil {
+		return "", err
+	}
+
+	latest, err := ks.readKey(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("read key: %w", err)
+	}
+	return versionedKid(name, latest), nil
+}
+
+// Sign asks vault to sign the sha256 digest with RSASSA-PKCS1-v1_5 using the version of the transit key in kid, or
+// the latest version when kid has none.
+func (ks *KeyStore) Sign(ctx context.Context, kid string, digest []byte) ([]byte, error) {
+	name, version, err := ks.parseKid(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	body := struct {
+		Input              string `json:"input"`
+		Prehashed          bool   `json:"prehashed"`
+		SignatureAlgorithm string `json:"signature_algorithm"`
+		KeyVersion         int    `json:"key_version,omitempty"`
+	}{
+		Input:              base64.StdEncoding.EncodeToString(digest),
+		Prehashed:          true,
+		SignatureAlgorithm: "pkcs1v15",
+		KeyVersion:         version,
+	}
+
+	var resp struct {
+		Data struct {
+			Signature string `json:"signature"`
+		} `json:"data"`
+	}
+
+	path := fmt.Sprintf("/v1/%s/sign/%s/sha2-256", ks.mountPath, url.PathEscape(name))
+	if err := ks.do(ctx, http.MethodPost, path, body, &resp); err != nil {
+		return nil, fmt.Errorf("sign: %w", err)
+	}
+
+	//signature format is "vault:v<version>:<base64>"
+	parts := strings.Split(resp.Data.Signature, ":")
+	if len(parts) != 3 || parts[0] != "vault" {
+		return nil, fmt.Errorf("unexpected signature format %q", resp.Data.Signature)
+	}
+
+	if version > 0 && parts[1] != "v"+strconv.Itoa(version) {
+		return nil, fmt.Errorf("signed with %s instead of v%d", parts[1], version)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("decode signature: %w", err)
+	}
+	return signature, nil
+}
+
+// PublicKey returns the public pem of the version of the transit key in kid, or of the latest version when kid has
+// none. Every version of the key is cached on the first lookup, failed lookups are cached for a minute.
+func (ks *KeyStore) PublicKey(kid string) (string, error) {
+	name, _, err := ks.parseKid(kid)
+	if err != nil {
+		return "", err
+	}
+
+	if key, ok := ks.cached(kid); ok {
+		return key.pem, key.err
+	}
+
+	latest, err := ks.readKey(context.Background(), name)
+	if err != nil {
+		err = fmt.Errorf("read key: %w", err)
+		ks.cache(kid, publicKey{err: err, expires: time.Now().Add(failureTTL)})
+		return "", err
+	}
+
+	//a kid without a version stands for the latest one
+	if key, ok := ks.cached(versionedKid(name, latest)); ok && kid == name {
+		ks.cache(kid, key)
+	}
+
+	if key, ok := ks.cached(kid); ok {
+		return key.pem, key.err
+	}
+
+	err = fmt.Errorf("public key with id %q not found", kid)
+	ks.cache(kid, publicKey{err: err, expires: time.Now().Add(failureTTL)})
+	return "", err
+}
+
+// readKey caches the public keys of every version of the transit key and returns the latest version.
+func (ks *KeyStore) readKey(ctx context.Context, name string) (int, error) {
+	var resp struct {
+		Data struct {
+			LatestVersion int `json:"latest_version"`
+			Keys          map[string]struct {
+				PublicKey string `json:"public_key"`
+			} `json:"keys"`
+		} `json:"data"`
+	}
+
+	path := fmt.Sprintf("/v1/%s/keys/%s", ks.mountPath, url.PathEscape(name))
+	if err := ks.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return 0, err
+	}
+
+	for version, key := range resp.Data.Keys {
+		if key.PublicKey != "" {
+			ks.cache(name+":v"+version, publicKey{pem: key.PublicKey})
+		}
+	}
+	return resp.Data.LatestVersion, nil
+}
+
+// parseKid splits kid into the name of the transit key and its version, 0 when kid has none. Only configured keys
+// are accepted.
+func (ks *KeyStore) parseKid(kid string) (string, int, error) {
+	name, version, versioned := strings.Cut(kid, ":v")
+	if !slices.Contains(ks.keys, name) {
+		return "", 0, fmt.Errorf("key with id %q is not configured", kid)
+	}
+
+	if !versioned {
+		return name, 0, nil
+	}
+
+	v, err := strconv.Atoi(version)
+	if err != nil || v <= 0 {
+		return "", 0, fmt.Errorf("key with id %q has an invalid version", kid)
+	}
+	return name, v, nil
+}
+
+func (ks *KeyStore) cached(kid string) (publicKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	key, ok := ks.publicKeys[kid]
+	if !ok || (!key.expires.IsZero() && time.Now().After(key.expires)) {
+		return publicKey{}, false
+	}
+	return key, true
+}
+
+func (ks *KeyStore) cache(kid string, key publicKey) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.publicKeys[kid] = key
+}
+
+func versionedKid(name string, version int) string {
+	return name + ":v" + strconv.Itoa(version)
+}
+
+func (ks *KeyStore) do(ctx context.Context, method string, path string, body any, v any) error {
+	var reader io.Reader
+	if body != nil {
+		bs, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("marshal: %w", err)
+		}
+		reader = bytes.NewReader(bs)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, ks.address+path, reader)
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", ks.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bs, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return fmt.Errorf("read body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("vault responded with %d: %s", resp.StatusCode, bs)
+	}
+
+	if err := json.Unmarshal(bs, v); err != nil {
+		return fmt.Errorf("unmarshal: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package vault_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hamidoujand/task-scheduler/foundation/keystore/vault"
+)
+
+// transit is a fake transit engine with a single "jwt" key that can be rotated.
+type transit struct {
+	t        *testing.T
+	requests atomic.Int64
+
+	mu       sync.Mutex
+	versions []*rsa.PrivateKey
+}
+
+func (tr *transit) rotate() {
+	tr.t.Helper()
+
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		tr.t.Fatalf("expected to generate a key: %s", err)
+	}
+
+	tr.mu.Lock()
+	tr.versions = append(tr.versions, private)
+	tr.mu.Unlock()
+}
+
+func (tr *transit) key(version int) *rsa.PrivateKey {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return tr.versions[version-1]
+}
+
+func (tr *transit) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/transit/sign/jwt/sha2-256", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		var body struct {
+			Input      string `json:"input"`
+			KeyVersion int    `json:"key_version"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		tr.mu.Lock()
+		version := len(tr.versions)
+		tr.mu.Unlock()
+		if body.KeyVersion > 0 {
+			version = body.KeyVersion
+		}
+
+		digest, _ := base64.StdEncoding.DecodeString(body.Input)
+		signature, _ := rsa.SignPKCS1v15(rand.Reader, tr.key(version), crypto.SHA256, digest)
+
+		resp := map[string]any{
+			"data": map[string]any{"signature": "vault:v" + strconv.Itoa(version) + ":" + base64.StdEncoding.EncodeToString(signature)},
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	mux.HandleFunc("GET /v1/transit/keys/jwt", func(w http.ResponseWriter, r *http.Request) {
+		tr.mu.Lock()
+		defer tr.mu.Unlock()
+
+		keys := make(map[string]any)
+		for i, private := range tr.versions {
+			publicBytes, _ := x509.MarshalPKIXPublicKey(&private.PublicKey)
+			keys[strconv.Itoa(i+1)] = map[string]any{"public_key": string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicBytes}))}
+		}
+
+		resp := map[string]any{
+			"data": map[string]any{
+				"latest_version": len(tr.versions),
+				"keys":           keys,
+			},
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tr.requests.Add(1)
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func TestKeyStore(t *testing.T) {
+	t.Parallel()
+
+	tr := transit{t: t}
+	tr.rotate()
+
+	srv := httptest.NewServer(tr.handler())
+	t.Cleanup(srv.Close)
+
+	ks, err := vault.New(vault.Config{Address: srv.URL, Token: "root", Keys: []string{"jwt", "deleted"}})
+	if err != nil {
+		t.Fatalf("expected to create the keystore: %s", err)
+	}
+
+	digest := sha256.Sum256([]byte("header.payload"))
+
+	//sign signs with the version of the kid and the public key of that version verifies it
+	verify := func(expectedKid string) {
+		t.Helper()
+
+		kid, err := ks.SigningKid(context.Background(), "jwt")
+		if err != nil {
+			t.Fatalf("expected to resolve the signing kid: %s", err)
+		}
+
+		if kid != expectedKid {
+			t.Errorf("kid= %s, got %s", expectedKid, kid)
+		}
+
+		signature, err := ks.Sign(context.Background(), kid, digest[:])
+		if err != nil {
+			t.Fatalf("expected to sign the digest: %s", err)
+		}
+
+		publicPEM, err := ks.PublicKey(kid)
+		if err != nil {
+			t.Fatalf("expected to fetch the public key: %s", err)
+		}
+
+		block, _ := pem.Decode([]byte(publicPEM))
+		public, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			t.Fatalf("expected to parse the public key: %s", err)
+		}
+
+		if err := rsa.VerifyPKCS1v15(public.(*rsa.PublicKey), crypto.SHA256, digest[:], signature); err != nil {
+			t.Errorf("expected a valid signature: %s", err)
+		}
+	}
+
+	verify("jwt:v1")
+
+	//tokens signed after a rotation are checked against the new version, the old one keeps verifying its tokens
+	tr.rotate()
+	verify("jwt:v2")
+
+	old, err := ks.PublicKey("jwt:v1")
+	if err != nil {
+		t.Fatalf("expected to fetch the public key of the old version: %s", err)
+	}
+
+	publicBytes, _ := x509.MarshalPKIXPublicKey(&tr.key(1).PublicKey)
+	if expected := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicBytes})); old != expected {
+		t.Errorf("publicKey= %s, got %s", expected, old)
+	}
+
+	//kids of the tokens are untrusted, keys that are not configured never reach vault
+	before := tr.requests.Load()
+	for _, kid := range []string{"unknown", "../../sys/raw", "jwt:vx", "jwt:v0"} {
+		if _, err := ks.PublicKey(kid); err == nil {
+			t.Errorf("expected an error for kid %q", kid)
+		}
+	}
+
+	if _, err := ks.Sign(context.Background(), "unknown", digest[:]); err == nil {
+		t.Error("expected an error when the transit key is not configured")
+	}
+
+	if got := tr.requests.Load(); got != before {
+		t.Errorf("requests= %d, got %d", before, got)
+	}
+
+	//a failed lookup is remembered
+	for range 3 {
+		if _, err := ks.PublicKey("deleted"); err == nil {
+			t.Error("expected an error when the transit key does not exist")
+		}
+	}
+
+	if got := tr.requests.Load(); got != before+1 {
+		t.Errorf("requests= %d, got %d", before+1, got)
+	}
+}
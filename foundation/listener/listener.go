@@ -0,0 +1,140 @@
+// Package listener creates the listening sockets of the servers so a new process can serve before the old one
+// drains. The socket is either inherited through systemd socket activation, which keeps it open while the service
+// restarts, or bound with SO_REUSEPORT so the new process can bind the same address while the old one still holds
+// it.
+package listener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// listenFdsStart is the first file descriptor systemd passes, the ones before it are stdin, stdout and stderr.
+const listenFdsStart = 3
+
+// ErrReusePortUnsupported is returned when SO_REUSEPORT is asked for on a platform without it.
+var ErrReusePortUnsupported = errors.New("SO_REUSEPORT is not supported on this platform")
+
+// Config represents how the socket of an address is created.
+type Config struct {
+	//Name picks the inherited socket by the FileDescriptorName of its systemd socket unit, an empty name picks
+	//the first one.
+	Name string
+	//ReusePort binds the address with SO_REUSEPORT when no socket is inherited.
+	ReusePort bool
+}
+
+// socket represents a socket passed by systemd.
+type socket struct {
+	name string
+	file *os.File
+}
+
+var (
+	once    sync.Once
+	mu      sync.Mutex
+	sockets []socket
+)
+
+// Listen returns the inherited socket of the config when systemd passed one, otherwise it binds the tcp address.
+func Listen(addr string, conf Config) (net.Listener, error) {
+	ln, ok, err := inherited(conf.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if ok {
+		return ln, nil
+	}
+
+	var lc net.ListenConfig
+	if conf.ReusePort {
+		lc.Control = func(network string, address string, c syscall.RawConn) error {
+			var sockErr error
+			err := c.Control(func(fd uintptr) {
+				sockErr = reusePort(fd)
+			})
+			return errors.Join(err, sockErr)
+		}
+	}
+
+	ln, err = lc.Listen(context.Background(), "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen: %s: %w", addr, err)
+	}
+	return ln, nil
+}
+
+// inherited returns the socket with the name out of the ones systemd passed to this process, every socket can only
+// be taken once.
+func inherited(name string) (net.Listener, bool, error) {
+	once.Do(func() {
+		sockets = activated()
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	for i, s := range sockets {
+		if name != "" && s.name != name {
+			continue
+		}
+
+		sockets = append(sockets[:i], sockets[i+1:]...)
+
+		//the listener holds a duplicate of the descriptor that is closed on exec, so the processes of tasks do not
+		//inherit it
+		ln, err := net.FileListener(s.file)
+		s.file.Close()
+		if err != nil {
+			return nil, false, fmt.Errorf("inherited socket %q: %w", s.name, err)
+		}
+		return ln, true, nil
+	}
+
+	return nil, false, nil
+}
+
+// activated returns the sockets systemd passed to this process and removes the variables that describe them, so
+// they are not passed on to the processes this one starts.
+func activated() []socket {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	//the variables are meant for another process when they were inherited from a parent that did not clean them up
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil
+	}
+
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil
+	}
+
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	found := make([]socket, count)
+	for i := range count {
+		fd := listenFdsStart + i
+		closeOnExec(fd)
+
+		name := "LISTEN_FD_" + strconv.Itoa(fd)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		found[i] = socket{name: name, file: os.NewFile(uintptr(fd), name)}
+	}
+	return found
+}
@@ -0,0 +1,120 @@
+package listener_test
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/hamidoujand/task-scheduler/foundation/listener"
+)
+
+func TestListenReusePort(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("SO_REUSEPORT is only tested on linux")
+	}
+	t.Parallel()
+
+	first, err := listener.Listen("127.0.0.1:0", listener.Config{ReusePort: true})
+	if err != nil {
+		t.Fatalf("expected to listen: %s", err)
+	}
+	defer first.Close()
+
+	//a new process binds the address while the old one still serves
+	second, err := listener.Listen(first.Addr().String(), listener.Config{ReusePort: true})
+	if err != nil {
+		t.Fatalf("expected to bind the address again with SO_REUSEPORT: %s", err)
+	}
+	second.Close()
+
+	if _, err := listener.Listen(first.Addr().String(), listener.Config{}); err == nil {
+		t.Fatal("expected binding the address without SO_REUSEPORT to fail")
+	}
+}
+
+// TestListenInherited starts the test binary with a socket the way systemd passes it, the child process checks that
+// it serves on it.
+func TestListenInherited(t *testing.T) {
+	if os.Getenv("LISTENER_TEST_CHILD") == "1" {
+		inheritedChild()
+		return
+	}
+
+	if runtime.GOOS != "linux" {
+		t.Skip("socket activation is only tested on linux")
+	}
+	t.Parallel()
+
+	tcpLn, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("expected to listen: %s", err)
+	}
+	defer tcpLn.Close()
+
+	file, err := tcpLn.File()
+	if err != nil {
+		t.Fatalf("expected the file of the listener: %s", err)
+	}
+	defer file.Close()
+
+	//LISTEN_PID must be the pid of the process that reads it, exec keeps the pid of the shell
+	cmd := exec.Command("sh", "-c", `LISTEN_PID=$$ exec "$0" -test.run=^TestListenInherited$`, os.Args[0])
+	cmd.Env = append(os.Environ(),
+		"LISTENER_TEST_CHILD=1",
+		"LISTEN_FDS=2",
+		"LISTEN_FDNAMES=redirect:api",
+		"LISTENER_TEST_ADDR="+tcpLn.Addr().String(),
+	)
+	//fd 3 and 4, the api is the second one
+	cmd.ExtraFiles = []*os.File{os.Stdin, file}
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("expected the child to serve on the inherited socket: %s: %s", err, out)
+	}
+
+	if !strings.Contains(string(out), "inherited ok") {
+		t.Errorf("expected the child to report the inherited socket, got %s", out)
+	}
+}
+
+func inheritedChild() {
+	want := os.Getenv("LISTENER_TEST_ADDR")
+
+	//the address is already bound by the parent, only the inherited socket can serve on it
+	ln, err := listener.Listen(want, listener.Config{Name: "api"})
+	if err != nil {
+		fmt.Println("listen:", err)
+		os.Exit(1)
+	}
+	defer ln.Close()
+
+	if ln.Addr().String() != want {
+		fmt.Printf("addr= %s, got %s\n", want, ln.Addr())
+		os.Exit(1)
+	}
+
+	if os.Getenv("LISTEN_FDS") != "" {
+		fmt.Println("expected the systemd variables to be removed")
+		os.Exit(1)
+	}
+
+	//the socket can only be taken once
+	again, err := listener.Listen("127.0.0.1:0", listener.Config{Name: "api"})
+	if err != nil {
+		fmt.Println("listen:", err)
+		os.Exit(1)
+	}
+	defer again.Close()
+
+	if again.Addr().String() == want {
+		fmt.Println("expected the socket to be taken only once")
+		os.Exit(1)
+	}
+
+	fmt.Println("inherited ok")
+}
@@ -0,0 +1,6 @@
+//go:build linux && (amd64 || 386 || arm)
+
+package listener
+
+// soReusePort is missing from the syscall package of these platforms.
+const soReusePort = 0xf
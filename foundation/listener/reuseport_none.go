@@ -0,0 +1,6 @@
+//go:build solaris || (openbsd && mips64)
+
+package listener
+
+// soReusePort is zero where SO_REUSEPORT does not exist.
+const soReusePort = 0
@@ -0,0 +1,7 @@
+//go:build aix || darwin || dragonfly || freebsd || netbsd || (openbsd && !mips64) || (linux && !(amd64 || 386 || arm))
+
+package listener
+
+import "syscall"
+
+const soReusePort = syscall.SO_REUSEPORT
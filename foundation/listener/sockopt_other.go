@@ -0,0 +1,10 @@
+//go:build !unix
+
+package listener
+
+func reusePort(fd uintptr) error {
+	return ErrReusePortUnsupported
+}
+
+// closeOnExec is a no-op, systemd only passes sockets on unix.
+func closeOnExec(fd int) {}
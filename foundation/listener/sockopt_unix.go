@@ -0,0 +1,16 @@
+//go:build unix
+
+package listener
+
+import "syscall"
+
+func reusePort(fd uintptr) error {
+	if soReusePort == 0 {
+		return ErrReusePortUnsupported
+	}
+	return syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, soReusePort, 1)
+}
+
+func closeOnExec(fd int) {
+	syscall.CloseOnExec(fd)
+}
@@ -3,13 +3,20 @@ package logger
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
 )
 
-// NewCustomLogger is going to setup a *slog.Logger and return it.
-func NewCustomLogger(level slog.Level, isProd bool, attrs ...slog.Attr) *slog.Logger {
+// NewCustomLogger is going to setup a *slog.Logger and return it, pass a *slog.LevelVar as level to change it while
+// the logger is in use.
+func NewCustomLogger(level slog.Leveler, isProd bool, attrs ...slog.Attr) *slog.Logger {
+	return New(os.Stdout, level, isProd, attrs...)
+}
+
+// New is like NewCustomLogger but writes the logs into w.
+func New(w io.Writer, level slog.Leveler, isProd bool, attrs ...slog.Attr) *slog.Logger {
 	//setup logger
 	replacer := func(groups []string, a slog.Attr) slog.Attr {
 		//we do not want that long file path, just the file name and line number
@@ -33,8 +40,8 @@ func NewCustomLogger(level slog.Level, isProd bool, attrs ...slog.Attr) *slog.Lo
 		Level:       level,
 		ReplaceAttr: replacer,
 	}
-	devHandler := slog.NewTextHandler(os.Stdout, opts).WithAttrs(attrs)
-	prodHandler := slog.NewJSONHandler(os.Stdout, opts).WithAttrs(attrs)
+	devHandler := slog.NewTextHandler(w, opts).WithAttrs(attrs)
+	prodHandler := slog.NewJSONHandler(w, opts).WithAttrs(attrs)
 
 	customHandler := newCustomLogHandler(prodHandler, devHandler, isProd)
 
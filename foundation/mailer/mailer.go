@@ -0,0 +1,140 @@
+// Package mailer provides support for sending emails over SMTP.
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Config represents all of the configuration required to send emails.
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     mail.Address
+	Timeout  time.Duration
+}
+
+// Mailer represents an SMTP client that sends plain text emails.
+type Mailer struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     mail.Address
+	timeout  time.Duration
+}
+
+// New creates a mailer.
+func New(conf Config) (*Mailer, error) {
+	if conf.Host == "" {
+		return nil, errors.New("smtp host is required")
+	}
+
+	if conf.From.Address == "" {
+		return nil, errors.New("from address is required")
+	}
+
+	if conf.Port <= 0 {
+		conf.Port = 587
+	}
+
+	if conf.Timeout <= 0 {
+		conf.Timeout = time.Second * 10
+	}
+
+	return &Mailer{
+		addr:     net.JoinHostPort(conf.Host, fmt.Sprint(conf.Port)),
+		host:     conf.Host,
+		username: conf.Username,
+		password: conf.Password,
+		from:     conf.From,
+		timeout:  conf.Timeout,
+	}, nil
+}
+
+// Send sends a plain text email, STARTTLS is used whenever the server supports it.
+func (m *Mailer) Send(ctx context.Context, to mail.Address, subject string, body string) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.timeout)
+		defer cancel()
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+
+	//smtp package does not support ctx, deadline on conn covers the whole conversation
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return fmt.Errorf("set deadline: %w", err)
+		}
+	}
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		return fmt.Errorf("new client: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+
+	if m.username != "" {
+		auth := smtp.PlainAuth("", m.username, m.password, m.host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	if err := client.Mail(m.from.Address); err != nil {
+		return fmt.Errorf("mail: %w", err)
+	}
+
+	if err := client.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("rcpt: %w", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("data: %w", err)
+	}
+
+	if _, err := w.Write(message(m.from, to, subject, body)); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("close data: %w", err)
+	}
+
+	return client.Quit()
+}
+
+func message(from mail.Address, to mail.Address, subject string, body string) []byte {
+	var builder strings.Builder
+	builder.WriteString("From: " + from.String() + "\r\n")
+	builder.WriteString("To: " + to.String() + "\r\n")
+	builder.WriteString("Subject: " + strings.ReplaceAll(subject, "\n", " ") + "\r\n")
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	builder.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return []byte(builder.String())
+}
@@ -0,0 +1,252 @@
+// Package reload applies a subset of the settings of a running service again when it receives SIGHUP or when the
+// file they are kept in changes, so they can be changed without a restart.
+package reload
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ardanlabs/conf/v3"
+)
+
+// Setting represents a setting that can change while the service runs.
+type Setting struct {
+	//Key is the name of the setting inside of the file and the environment like TASKS_LOG_LEVEL.
+	Key string
+	//Apply parses the value and applies it, the old value is kept when it returns an error.
+	Apply func(value string) error
+}
+
+// Config represents where the settings are read from.
+type Config struct {
+	//File is optional and holds KEY=VALUE lines like an env file, its values take precedence over the environment.
+	File string
+	//Interval is how often the file is checked for changes, defaults to 5 seconds.
+	Interval time.Duration
+	Logger   *slog.Logger
+	Settings []Setting
+}
+
+// Watcher applies the settings again whenever they change.
+type Watcher struct {
+	conf     Config
+	mu       sync.Mutex
+	applied  map[string]string
+	modTime  time.Time
+	size     int64
+	signals  chan os.Signal
+	shutdown chan struct{}
+	wg       sync.WaitGroup
+}
+
+// New creates a watcher, the values the settings have inside of the environment are the ones the service was
+// configured with at startup so they count as applied.
+func New(conf Config) *Watcher {
+	if conf.Interval <= 0 {
+		conf.Interval = time.Second * 5
+	}
+
+	applied := make(map[string]string)
+	for _, s := range conf.Settings {
+		if v, ok := os.LookupEnv(s.Key); ok {
+			applied[s.Key] = v
+		}
+	}
+
+	return &Watcher{
+		conf:     conf,
+		applied:  applied,
+		signals:  make(chan os.Signal, 1),
+		shutdown: make(chan struct{}),
+	}
+}
+
+// Start applies the file once and then reloads the settings on SIGHUP and whenever the file changes until Stop is
+// called.
+func (w *Watcher) Start() {
+	if err := w.Reload(); err != nil {
+		w.conf.Logger.Error("reload", "status", "failed", "msg", err)
+	}
+
+	signal.Notify(w.signals, syscall.SIGHUP)
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		ticker := time.NewTicker(w.conf.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.shutdown:
+				return
+			case <-w.signals:
+				w.conf.Logger.Info("reload", "status", "started", "trigger", "SIGHUP")
+			case <-ticker.C:
+				if !w.fileChanged() {
+					continue
+				}
+				w.conf.Logger.Info("reload", "status", "started", "trigger", "file", "file", w.conf.File)
+			}
+
+			if err := w.Reload(); err != nil {
+				w.conf.Logger.Error("reload", "status", "failed", "msg", err)
+			}
+		}
+	}()
+}
+
+// Stop stops watching for changes.
+func (w *Watcher) Stop() {
+	signal.Stop(w.signals)
+	close(w.shutdown)
+	w.wg.Wait()
+}
+
+// Reload reads the settings and applies the ones whose value changed since they were applied last. Settings that are
+// neither inside of the file nor the environment keep their value, a setting that fails to apply does not keep the
+// others from being applied.
+func (w *Watcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	values, err := w.readFile()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, s := range w.conf.Settings {
+		value, ok := values[s.Key]
+		if !ok {
+			value, ok = os.LookupEnv(s.Key)
+		}
+
+		if !ok {
+			continue
+		}
+
+		if old, ok := w.applied[s.Key]; ok && old == value {
+			continue
+		}
+
+		if err := s.Apply(value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.Key, err))
+			continue
+		}
+
+		w.applied[s.Key] = value
+		w.conf.Logger.Info("reload", "status", "applied", "key", s.Key, "value", value)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Applied returns the values of the settings by their key as they were applied last.
+func (w *Watcher) Applied() map[string]string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	applied := make(map[string]string, len(w.applied))
+	for k, v := range w.applied {
+		applied[k] = v
+	}
+	return applied
+}
+
+// Effective returns every setting of cfg, the conf tagged struct the service was configured with, by the name of
+// its environment variable with the values that were reloaded since. Fields tagged with mask are masked.
+func (w *Watcher) Effective(prefix string, cfg any) (map[string]string, error) {
+	s, err := conf.String(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("config string: %w", err)
+	}
+
+	effective := make(map[string]string)
+	for _, line := range strings.Split(s, "\n") {
+		flag, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+
+		//--api-host becomes TASKS_API_HOST
+		key := strings.ToUpper(strings.ReplaceAll(strings.TrimPrefix(flag, "--"), "-", "_"))
+		effective[prefix+"_"+key] = value
+	}
+
+	for k, v := range w.Applied() {
+		effective[k] = v
+	}
+	return effective, nil
+}
+
+// fileChanged reports whether the file was modified since it was read last.
+func (w *Watcher) fileChanged() bool {
+	if w.conf.File == "" {
+		return false
+	}
+
+	info, err := os.Stat(w.conf.File)
+	if err != nil {
+		return false
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !info.ModTime().Equal(w.modTime) || info.Size() != w.size
+}
+
+// readFile returns the values inside of the file, a file that does not exist yet has none.
+func (w *Watcher) readFile() (map[string]string, error) {
+	values := make(map[string]string)
+	if w.conf.File == "" {
+		return values, nil
+	}
+
+	info, err := os.Stat(w.conf.File)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return values, nil
+		}
+		return nil, fmt.Errorf("stat: %w", err)
+	}
+
+	data, err := os.ReadFile(w.conf.File)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+
+	w.modTime = info.ModTime()
+	w.size = info.Size()
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", w.conf.File, n)
+		}
+
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		values[strings.TrimSpace(key)] = value
+	}
+
+	return values, nil
+}
@@ -0,0 +1,202 @@
+package reload_test
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/foundation/reload"
+)
+
+func TestReload(t *testing.T) {
+	t.Setenv("RELOAD_TEST_LEVEL", "info")
+	t.Setenv("RELOAD_TEST_RETRIES", "1")
+
+	file := filepath.Join(t.TempDir(), "reload.env")
+	if err := os.WriteFile(file, []byte("# changed by ops\nRELOAD_TEST_RETRIES=3\n"), 0o600); err != nil {
+		t.Fatalf("expected to write the file: %s", err)
+	}
+
+	var level string
+	var retries, applies int
+	w := reload.New(reload.Config{
+		File:   file,
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Settings: []reload.Setting{
+			{Key: "RELOAD_TEST_LEVEL", Apply: func(value string) error {
+				applies++
+				level = value
+				return nil
+			}},
+			{Key: "RELOAD_TEST_RETRIES", Apply: func(value string) error {
+				n, err := strconv.Atoi(value)
+				if err != nil {
+					return err
+				}
+				applies++
+				retries = n
+				return nil
+			}},
+		},
+	})
+
+	if err := w.Reload(); err != nil {
+		t.Fatalf("expected to reload: %s", err)
+	}
+
+	//the environment was applied at startup, only the file changed a setting
+	if applies != 1 {
+		t.Errorf("applies= %d, got %d", 1, applies)
+	}
+
+	if retries != 3 {
+		t.Errorf("retries= %d, got %d", 3, retries)
+	}
+
+	//an invalid value keeps the old one and does not keep the others from being applied
+	if err := os.WriteFile(file, []byte("RELOAD_TEST_RETRIES=many\nexport RELOAD_TEST_LEVEL=\"debug\"\n"), 0o600); err != nil {
+		t.Fatalf("expected to write the file: %s", err)
+	}
+
+	if err := w.Reload(); err == nil {
+		t.Fatal("expected an error for the invalid retries")
+	}
+
+	if level != "debug" {
+		t.Errorf("level= %s, got %s", "debug", level)
+	}
+
+	applied := w.Applied()
+	if applied["RELOAD_TEST_RETRIES"] != "3" {
+		t.Errorf("applied retries= %s, got %s", "3", applied["RELOAD_TEST_RETRIES"])
+	}
+}
+
+func TestReloadInvalidFile(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), "reload.env")
+	if err := os.WriteFile(file, []byte("RELOAD_TEST_LEVEL\n"), 0o600); err != nil {
+		t.Fatalf("expected to write the file: %s", err)
+	}
+
+	w := reload.New(reload.Config{
+		File:   file,
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Settings: []reload.Setting{
+			{Key: "RELOAD_TEST_LEVEL", Apply: func(value string) error {
+				return errors.New("not expected to be applied")
+			}},
+		},
+	})
+
+	if err := w.Reload(); err == nil {
+		t.Fatal("expected an error for the line without a value")
+	}
+}
+
+// TestWatch checks that both a change of the file and SIGHUP reload the settings.
+func TestWatch(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "reload.env")
+
+	var value atomic.Value
+	value.Store("")
+	w := reload.New(reload.Config{
+		File:     file,
+		Interval: time.Millisecond * 10,
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Settings: []reload.Setting{
+			{Key: "RELOAD_TEST_WATCH", Apply: func(v string) error {
+				value.Store(v)
+				return nil
+			}},
+		},
+	})
+	w.Start()
+	defer w.Stop()
+
+	waitFor := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second * 5)
+		for time.Now().Before(deadline) {
+			if value.Load() == want {
+				return
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+		t.Fatalf("value= %s, got %s", want, value.Load())
+	}
+
+	if err := os.WriteFile(file, []byte("RELOAD_TEST_WATCH=file\n"), 0o600); err != nil {
+		t.Fatalf("expected to write the file: %s", err)
+	}
+	waitFor("file")
+
+	//the environment is read again on SIGHUP, the file no longer has the setting
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatalf("expected to write the file: %s", err)
+	}
+	t.Setenv("RELOAD_TEST_WATCH", "signal")
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatalf("expected to send SIGHUP: %s", err)
+	}
+	waitFor("signal")
+}
+
+func TestEffective(t *testing.T) {
+	t.Setenv("RELOAD_TEST_LOGIN_MAXFAILURES", "5")
+
+	cfg := struct {
+		Login struct {
+			MaxFailures int
+		}
+		DB struct {
+			Host     string
+			Password string `conf:"mask"`
+		}
+	}{}
+	cfg.Login.MaxFailures = 5
+	cfg.DB.Host = "localhost:5432"
+	cfg.DB.Password = "password"
+
+	file := filepath.Join(t.TempDir(), "reload.env")
+	if err := os.WriteFile(file, []byte("RELOAD_TEST_LOGIN_MAXFAILURES=10\n"), 0o600); err != nil {
+		t.Fatalf("expected to write the file: %s", err)
+	}
+
+	w := reload.New(reload.Config{
+		File:   file,
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Settings: []reload.Setting{
+			{Key: "RELOAD_TEST_LOGIN_MAXFAILURES", Apply: func(value string) error { return nil }},
+		},
+	})
+
+	if err := w.Reload(); err != nil {
+		t.Fatalf("expected to reload: %s", err)
+	}
+
+	effective, err := w.Effective("RELOAD_TEST", &cfg)
+	if err != nil {
+		t.Fatalf("expected the effective config: %s", err)
+	}
+
+	tests := map[string]string{
+		"RELOAD_TEST_LOGIN_MAXFAILURES": "10",
+		"RELOAD_TEST_DB_HOST":           "localhost:5432",
+		"RELOAD_TEST_DB_PASSWORD":       "xxxxxx",
+	}
+
+	for key, want := range tests {
+		if effective[key] != want {
+			t.Errorf("%s= %s, got %s", key, want, effective[key])
+		}
+	}
+}
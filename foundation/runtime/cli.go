@@ -0,0 +1,335 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxTimeForRemove bounds removing the container of a run whose context is done.
+const maxTimeForRemove = time.Second * 10
+
+// infraFailures are the stderr messages of the runtimes that point to an outage instead of an invalid image or
+// command.
+var infraFailures = []string{
+	"cannot connect to the docker daemon",
+	"cannot connect to podman",
+	"unable to connect to podman socket",
+	"cannot access containerd socket",
+	"containerd.sock",
+	"error during connect",
+	"connection refused",
+	"connection reset by peer",
+	"i/o timeout",
+	"tls handshake timeout",
+	"no such host",
+	"service unavailable",
+	"bad gateway",
+	"toomanyrequests",
+	"no space left on device",
+}
+
+// CLI represents a runtime driven through its docker compatible command line.
+type CLI struct {
+	name   string
+	binary string
+	//infraExitCode is the exit code of "run" when the error is from the runtime and not from the container.
+	infraExitCode int
+	//sharedExitCode is true when containers may exit with infraExitCode as well.
+	sharedExitCode bool
+	//versionArgs print "version os arch" of the runtime.
+	versionArgs []string
+}
+
+// NewDocker creates the runner of the docker daemon.
+func NewDocker() *CLI {
+	return &CLI{
+		name:          "docker",
+		binary:        "docker",
+		infraExitCode: 125,
+		versionArgs:   []string{"version", "--format", "{{.Server.Version}} {{.Server.Os}} {{.Server.Arch}}"},
+	}
+}
+
+// NewPodman creates the runner of podman, it runs containers without a daemon.
+func NewPodman() *CLI {
+	return &CLI{
+		name:          "podman",
+		binary:        "podman",
+		infraExitCode: 125,
+		versionArgs:   []string{"info", "--format", "{{.Version.Version}} {{.Host.OS}} {{.Host.Arch}}"},
+	}
+}
+
+// NewNerdctl creates the runner of containerd through nerdctl. nerdctl exits with 1 on its own errors, so a
+// container exiting with 1 is only told apart from an outage by the fatal lines nerdctl writes to stderr.
+func NewNerdctl() *CLI {
+	return &CLI{
+		name:           "containerd",
+		binary:         "nerdctl",
+		infraExitCode:  1,
+		sharedExitCode: true,
+		versionArgs:    []string{"info", "--format", "{{.ServerVersion}} {{.OSType}} {{.Architecture}}"},
+	}
+}
+
+// Name returns the name of the runtime.
+func (c *CLI) Name() string {
+	return c.name
+}
+
+// RunCommand creates a container that runs the command once with stdin piped to it and returns its stdout. Once ctx
+// is done the container is removed, the error of the run wraps the error of ctx then.
+func (c *CLI) RunCommand(ctx context.Context, image string, command string, runArgs []string, cmdArgs []string, stdin []byte) (string, error) {
+	name := containerName()
+	args := []string{"run", "--rm"}
+	if len(stdin) > 0 {
+		args = append(args, "--interactive")
+	}
+	args = append(args, "--name", name)
+	args = append(args, runArgs...)
+	args = append(args, image)
+	args = append(args, command)
+	args = append(args, cmdArgs...)
+
+	cmd := c.command(ctx, name, args)
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	stopSampling := c.sampleUsage(ctx, name)
+	err := cmd.Run()
+	stopSampling()
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", fmt.Errorf("command execution failed:stderr:%s:%w: %w", stderr.String(), ctxErr, err)
+		}
+		if c.isInfraFailure(err, stderr.String()) {
+			return "", fmt.Errorf("command execution failed:stderr:%s:%w: %w", stderr.String(), ErrInfrastructure, err)
+		}
+		return "", fmt.Errorf("command execution failed:stderr:%s:%w", stderr.String(), err)
+	}
+
+	return stdout.String(), nil
+}
+
+// command returns the command of the runtime that creates the container with the name, killing the runtime client
+// leaves the container running so it is removed as well once ctx is done.
+func (c *CLI) command(ctx context.Context, name string, args []string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, c.binary, args...)
+	cmd.Cancel = func() error {
+		err := cmd.Process.Kill()
+
+		//the container does not exist yet while its image is pulled
+		rmCtx, cancel := context.WithTimeout(context.Background(), maxTimeForRemove)
+		defer cancel()
+		exec.CommandContext(rmCtx, c.binary, "rm", "-f", name).Run()
+
+		return err
+	}
+	return cmd
+}
+
+// containerName returns a unique name for the container of a run.
+func containerName() string {
+	return "task-" + uuid.NewString()
+}
+
+// ExitCode returns the code the container of a failed run exited with, ok is false when the run failed without the
+// container exiting, like when the runtime itself failed or the context killed it.
+func (c *CLI) ExitCode(err error) (code int, ok bool) {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return 0, false
+	}
+
+	code = exitErr.ExitCode()
+	if code < 0 || errors.Is(err, ErrInfrastructure) {
+		return 0, false
+	}
+
+	if code == c.infraExitCode && !c.sharedExitCode {
+		return 0, false
+	}
+	return code, true
+}
+
+// Ping checks that the runtime is reachable.
+func (c *CLI) Ping(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, c.binary, c.versionArgs...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s:stderr:%s:%w", c.binary, c.versionArgs[0], stderr.String(), err)
+	}
+	return nil
+}
+
+// Version returns the version and platform of the runtime.
+func (c *CLI) Version(ctx context.Context) (Version, error) {
+	output, err := exec.CommandContext(ctx, c.binary, c.versionArgs...).Output()
+	if err != nil {
+		return Version{}, fmt.Errorf("%s %s: %w", c.binary, c.versionArgs[0], err)
+	}
+
+	fields := strings.Fields(string(output))
+	if len(fields) != 3 {
+		return Version{}, fmt.Errorf("unexpected %s %s output %q", c.binary, c.versionArgs[0], output)
+	}
+
+	return Version{
+		Version: fields[0],
+		OS:      fields[1],
+		Arch:    fields[2],
+	}, nil
+}
+
+// ImageDigest returns the repo digest of the local image, images that are only built locally do not have a repo
+// digest so their image id is returned instead.
+func (c *CLI) ImageDigest(ctx context.Context, image string) (string, error) {
+	cmd := exec.CommandContext(ctx, c.binary, "image", "inspect", "--format", "{{json .RepoDigests}} {{.Id}}", image)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s image inspect %s: %w", c.binary, image, err)
+	}
+
+	digests, id, ok := strings.Cut(strings.TrimSpace(string(output)), " ")
+	if !ok {
+		return "", fmt.Errorf("unexpected %s image inspect output %q", c.binary, output)
+	}
+
+	var repoDigests []string
+	if err := json.Unmarshal([]byte(digests), &repoDigests); err != nil {
+		return "", fmt.Errorf("unmarshal repo digests: %w", err)
+	}
+
+	if len(repoDigests) > 0 {
+		return repoDigests[0], nil
+	}
+	return id, nil
+}
+
+// ResolveDigest returns the digest of the image, pulling it first when it is not available locally.
+func (c *CLI) ResolveDigest(ctx context.Context, image string) (string, error) {
+	if digest, err := c.ImageDigest(ctx, image); err == nil {
+		return digest, nil
+	}
+
+	if err := c.PullImage(ctx, image, nil); err != nil {
+		return "", err
+	}
+
+	return c.ImageDigest(ctx, image)
+}
+
+// PullImage pulls the image from its registry even when it is available locally, progress is optional and is called
+// with every line the runtime reports while pulling.
+func (c *CLI) PullImage(ctx context.Context, image string, progress func(line string)) error {
+	cmd := exec.CommandContext(ctx, c.binary, "pull", image)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	//some runtimes report the progress on stderr
+	if progress != nil {
+		lines := &lineWriter{fn: progress}
+		cmd.Stdout = lines
+		cmd.Stderr = io.MultiWriter(&stderr, lines)
+	}
+
+	if err := cmd.Run(); err != nil {
+		//unlike "run", every failure of "pull" comes from the runtime itself
+		if errors.Is(err, exec.ErrNotFound) || matchesInfraFailure(stderr.String()) {
+			return fmt.Errorf("%s pull %s:stderr:%s:%w: %w", c.binary, image, stderr.String(), ErrInfrastructure, err)
+		}
+		return fmt.Errorf("%s pull %s:stderr:%s:%w", c.binary, image, stderr.String(), err)
+	}
+
+	return nil
+}
+
+// lineWriter calls fn with every non empty line written to it, stdout and stderr may write to it at the same time.
+type lineWriter struct {
+	fn func(line string)
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx < 0 {
+			break
+		}
+
+		line := strings.TrimSpace(string(w.buf[:idx]))
+		w.buf = w.buf[idx+1:]
+		if line != "" {
+			w.fn(line)
+		}
+	}
+	return len(p), nil
+}
+
+func (c *CLI) isInfraFailure(err error, stderr string) bool {
+	//the binary is missing
+	if errors.Is(err, exec.ErrNotFound) {
+		return true
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != c.infraExitCode {
+		return false
+	}
+
+	//stderr holds the output of the container as well, which may report an outage of its own
+	if c.sharedExitCode {
+		stderr = fatalLines(stderr)
+	}
+
+	return matchesInfraFailure(stderr)
+}
+
+// fatalLines returns the lines of stderr that the runtime logged as fatal, "FATA[0000] msg" on a terminal and
+// `level=fatal msg="..."` otherwise.
+func fatalLines(stderr string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(stderr, "\n") {
+		if strings.HasPrefix(line, "FATA[") || strings.Contains(line, "level=fatal") {
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+func matchesInfraFailure(stderr string) bool {
+	stderr = strings.ToLower(stderr)
+	for _, msg := range infraFailures {
+		if strings.Contains(stderr, msg) {
+			return true
+		}
+	}
+	return false
+}
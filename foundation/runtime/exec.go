@@ -0,0 +1,383 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	goruntime "runtime"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrCommandNotAllowed is returned by the exec runner for commands outside of its allowlist.
+var ErrCommandNotAllowed = errors.New("command is not allowed")
+
+// ErrEnvNotAllowed is returned by the exec runner for environment variables that change which program runs or what it
+// loads, they would get around the allowlist.
+var ErrEnvNotAllowed = errors.New("environment variable is not allowed")
+
+// deniedEnv are the environment variables tasks may not set with the exec runner, deniedEnvPrefixes the prefixes.
+var (
+	deniedEnv         = []string{"PATH", "HOME", "ENV", "BASH_ENV", "SHELLOPTS", "BASHOPTS", "IFS"}
+	deniedEnvPrefixes = []string{"LD_", "DYLD_"}
+)
+
+// ExecConfig represents the configuration of the exec runner.
+type ExecConfig struct {
+	//Allowed are the names of the commands tasks may run, nothing runs when it is empty.
+	Allowed []string
+	//WorkDir is where the temporary working directory of every run is created, defaults to the temp dir of the host.
+	//It is relative to Chroot when that is set.
+	WorkDir string
+	//Chroot is optional, with it commands run inside of the directory as their root and are looked up in its
+	//"/usr/local/bin", "/usr/bin" and "/bin". It requires the scheduler to run as root and User to be set.
+	Chroot string
+	//User is the unprivileged "uid:gid" commands run as, the user of the scheduler when empty. Without it the
+	//commands of a chroot would run as root.
+	User string
+	//Limits are applied to every command, zero leaves the resource unlimited. They are only supported on linux and
+	//require "/bin/sh", inside of Chroot when that is set.
+	Limits ExecLimits
+}
+
+// ExecLimits represents the resource limits of a command run by the exec runner.
+type ExecLimits struct {
+	CPUSeconds int
+	MemoryMB   int
+	OpenFiles  int
+	Processes  int
+}
+
+// Exec represents a runner that executes the commands of tasks directly on the host without a container, it is
+// meant for trusted environments only. Images are ignored and out of the run arguments only the environment ("-e")
+// is applied, so networks, presets limits and the container hardening have no effect. Tasks can not set PATH, HOME
+// or the variables that make the loader or a shell run something else.
+type Exec struct {
+	allowed []string
+	workDir string
+	chroot  string
+	cred    *credential
+	limits  ExecLimits
+}
+
+// credential represents the user and group a command runs as.
+type credential struct {
+	uid uint32
+	gid uint32
+}
+
+// NewExec creates the exec runner.
+func NewExec(conf ExecConfig) (*Exec, error) {
+	if len(conf.Allowed) == 0 {
+		return nil, errors.New("exec runtime requires at least one allowed command")
+	}
+
+	for _, name := range conf.Allowed {
+		if name == "" || strings.ContainsRune(name, '/') {
+			return nil, fmt.Errorf("allowed command %q must be a name without a path", name)
+		}
+	}
+
+	if conf.Limits.CPUSeconds < 0 || conf.Limits.MemoryMB < 0 || conf.Limits.OpenFiles < 0 || conf.Limits.Processes < 0 {
+		return nil, errors.New("exec limits must be greater than or equal to 0")
+	}
+
+	cred, err := parseCredential(conf.User)
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.Chroot != "" && cred == nil {
+		return nil, errors.New("exec chroot requires a user to run commands as")
+	}
+
+	if conf.WorkDir == "" {
+		conf.WorkDir = os.TempDir()
+		if conf.Chroot != "" {
+			conf.WorkDir = "/tmp"
+		}
+	}
+
+	return &Exec{
+		allowed: conf.Allowed,
+		workDir: conf.WorkDir,
+		chroot:  conf.Chroot,
+		cred:    cred,
+		limits:  conf.Limits,
+	}, nil
+}
+
+// parseCredential parses the "uid:gid" of an unprivileged user, it returns nil when user is empty.
+func parseCredential(user string) (*credential, error) {
+	if user == "" {
+		return nil, nil
+	}
+
+	uidStr, gidStr, ok := strings.Cut(user, ":")
+	if !ok {
+		return nil, fmt.Errorf("exec user %q must be uid:gid", user)
+	}
+
+	uid, err := strconv.ParseUint(uidStr, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("exec user %q must be uid:gid: %w", user, err)
+	}
+
+	gid, err := strconv.ParseUint(gidStr, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("exec user %q must be uid:gid: %w", user, err)
+	}
+
+	if uid == 0 || gid == 0 {
+		return nil, fmt.Errorf("exec user %q must not be root", user)
+	}
+	return &credential{uid: uint32(uid), gid: uint32(gid)}, nil
+}
+
+// Name returns the name of the runtime.
+func (e *Exec) Name() string {
+	return "exec"
+}
+
+// RunCommand runs the command inside of a temporary working directory with stdin piped to it and returns its stdout.
+func (e *Exec) RunCommand(ctx context.Context, image string, command string, runArgs []string, cmdArgs []string, stdin []byte) (string, error) {
+	dir, cleanup, err := e.tempDir()
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	env, err := envOf(runArgs)
+	if err != nil {
+		return "", err
+	}
+
+	return e.run(ctx, dir, env, command, cmdArgs, stdin)
+}
+
+// RunSteps runs the steps one after another inside of the same temporary working directory, it stops at the first
+// step that fails and returns the outputs of the steps that succeeded along with the error of the failed one.
+func (e *Exec) RunSteps(ctx context.Context, image string, runArgs []string, steps []Step) ([]string, error) {
+	dir, cleanup, err := e.tempDir()
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	env, err := envOf(runArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]string, 0, len(steps))
+	for i, step := range steps {
+		output, err := e.run(ctx, dir, env, step.Command, step.Args, step.Stdin)
+		if err != nil {
+			return outputs, fmt.Errorf("step %d: %w", i+1, err)
+		}
+		outputs = append(outputs, output)
+	}
+
+	return outputs, nil
+}
+
+// ExitCode returns the code the command of a failed run exited with, ok is false when it did not exit on its own.
+func (e *Exec) ExitCode(err error) (code int, ok bool) {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return 0, false
+	}
+
+	code = exitErr.ExitCode()
+	if code < 0 {
+		return 0, false
+	}
+	return code, true
+}
+
+// Ping always succeeds, the host is the runtime.
+func (e *Exec) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Version returns the platform of the host.
+func (e *Exec) Version(ctx context.Context) (Version, error) {
+	return Version{
+		Version: goruntime.Version(),
+		OS:      goruntime.GOOS,
+		Arch:    goruntime.GOARCH,
+	}, nil
+}
+
+// ImageDigest returns the image as is, there are no images to inspect.
+func (e *Exec) ImageDigest(ctx context.Context, image string) (string, error) {
+	return image, nil
+}
+
+// ResolveDigest returns the image as is, there are no images to pull.
+func (e *Exec) ResolveDigest(ctx context.Context, image string) (string, error) {
+	return image, nil
+}
+
+// PullImage does nothing, there are no images to pull.
+func (e *Exec) PullImage(ctx context.Context, image string, progress func(line string)) error {
+	return nil
+}
+
+// EnsureNetwork does nothing, commands share the network of the host.
+func (e *Exec) EnsureNetwork(ctx context.Context, name string, internal bool) error {
+	return nil
+}
+
+func (e *Exec) run(ctx context.Context, dir string, env []string, command string, args []string, stdin []byte) (string, error) {
+	path, err := e.lookPath(command)
+	if err != nil {
+		return "", err
+	}
+
+	cmd := exec.CommandContext(ctx, path, args...)
+
+	//with limits a shell waits on fd 3 until they are set and is then replaced by the command, so the command never
+	//runs without them
+	var gate *os.File
+	if e.limits != (ExecLimits{}) {
+		r, w, err := os.Pipe()
+		if err != nil {
+			return "", fmt.Errorf("pipe: %w: %w", ErrInfrastructure, err)
+		}
+		defer r.Close()
+		defer w.Close()
+
+		cmd = exec.CommandContext(ctx, "/bin/sh", append([]string{"-c", `read _ <&3; exec 3<&-; exec "$0" "$@"`, path}, args...)...)
+		cmd.ExtraFiles = []*os.File{r}
+		gate = w
+	}
+
+	cmd.Dir = dir
+	//the fixed ones go last, the last value of a key wins
+	cmd.Env = append(slices.Clip(env), "PATH=/usr/local/bin:/usr/bin:/bin", "HOME="+dir)
+	if err := setProcessGroup(cmd, e.chroot, e.cred); err != nil {
+		return "", fmt.Errorf("process group: %w: %w", ErrInfrastructure, err)
+	}
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("start command:%w: %w", ErrInfrastructure, err)
+	}
+
+	if gate != nil {
+		if err := setLimits(cmd.Process.Pid, e.limits); err != nil {
+			killProcessGroup(cmd)
+			cmd.Wait()
+			return "", fmt.Errorf("set limits: %w: %w", ErrInfrastructure, err)
+		}
+		gate.Write([]byte("\n"))
+		gate.Close()
+	}
+
+	err = cmd.Wait()
+	addProcessUsage(ctx, cmd)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", fmt.Errorf("command execution failed:stderr:%s:%w: %w", stderr.String(), ctxErr, err)
+		}
+		return "", fmt.Errorf("command execution failed:stderr:%s:%w", stderr.String(), err)
+	}
+
+	return stdout.String(), nil
+}
+
+// lookPath returns the path of the allowed command, inside of the chroot when there is one.
+func (e *Exec) lookPath(command string) (string, error) {
+	if !slices.Contains(e.allowed, command) {
+		return "", fmt.Errorf("%w: %s", ErrCommandNotAllowed, command)
+	}
+
+	if e.chroot == "" {
+		path, err := exec.LookPath(command)
+		if err != nil {
+			return "", fmt.Errorf("look path %s: %w", command, err)
+		}
+		return path, nil
+	}
+
+	for _, dir := range []string{"/usr/local/bin", "/usr/bin", "/bin"} {
+		path := filepath.Join(dir, command)
+		if info, err := os.Stat(filepath.Join(e.chroot, path)); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("command %s not found inside of %s", command, e.chroot)
+}
+
+// tempDir creates the working directory of a run and returns it as seen by the command.
+func (e *Exec) tempDir() (string, func(), error) {
+	root := filepath.Join(e.chroot, e.workDir)
+
+	dir, err := os.MkdirTemp(root, "task-")
+	if err != nil {
+		return "", nil, fmt.Errorf("create workdir: %w: %w", ErrInfrastructure, err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	//the directory is created by the user of the scheduler
+	if e.cred != nil {
+		if err := os.Chown(dir, int(e.cred.uid), int(e.cred.gid)); err != nil {
+			cleanup()
+			return "", nil, fmt.Errorf("chown workdir: %w: %w", ErrInfrastructure, err)
+		}
+	}
+
+	if e.chroot == "" {
+		return dir, cleanup, nil
+	}
+
+	rel, err := filepath.Rel(e.chroot, dir)
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("workdir inside of chroot: %w", err)
+	}
+	return "/" + rel, cleanup, nil
+}
+
+// envOf returns the environment out of the "-e KEY=value" run arguments, it fails for the denied variables.
+func envOf(runArgs []string) ([]string, error) {
+	var env []string
+	for i := 0; i < len(runArgs)-1; i++ {
+		if runArgs[i] == "-e" {
+			kv := runArgs[i+1]
+			key, _, _ := strings.Cut(kv, "=")
+			if envDenied(key) {
+				return nil, fmt.Errorf("%w: %s", ErrEnvNotAllowed, key)
+			}
+			env = append(env, kv)
+			i++
+		}
+	}
+	return env, nil
+}
+
+func envDenied(key string) bool {
+	key = strings.ToUpper(key)
+	if slices.Contains(deniedEnv, key) {
+		return true
+	}
+
+	for _, prefix := range deniedEnvPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package runtime
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// rlimitNproc is RLIMIT_NPROC, the syscall package does not define it. It counts every process of the user the
+// command runs as.
+const rlimitNproc = 6
+
+// setLimits applies the limits to the process with prlimit.
+func setLimits(pid int, limits ExecLimits) error {
+	set := func(resource int, value uint64) error {
+		rlimit := syscall.Rlimit{Cur: value, Max: value}
+		_, _, errno := syscall.RawSyscall6(syscall.SYS_PRLIMIT64, uintptr(pid), uintptr(resource), uintptr(unsafe.Pointer(&rlimit)), 0, 0, 0)
+		if errno != 0 {
+			return fmt.Errorf("prlimit %d: %w", resource, errno)
+		}
+		return nil
+	}
+
+	if limits.CPUSeconds > 0 {
+		if err := set(syscall.RLIMIT_CPU, uint64(limits.CPUSeconds)); err != nil {
+			return err
+		}
+	}
+
+	if limits.MemoryMB > 0 {
+		if err := set(syscall.RLIMIT_AS, uint64(limits.MemoryMB)<<20); err != nil {
+			return err
+		}
+	}
+
+	if limits.OpenFiles > 0 {
+		if err := set(syscall.RLIMIT_NOFILE, uint64(limits.OpenFiles)); err != nil {
+			return err
+		}
+	}
+
+	if limits.Processes > 0 {
+		if err := set(rlimitNproc, uint64(limits.Processes)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
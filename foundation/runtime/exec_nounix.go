@@ -0,0 +1,24 @@
+//go:build !unix
+
+package runtime
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// setProcessGroup leaves the command as is, process groups, chroot and the user are only supported on unix.
+func setProcessGroup(cmd *exec.Cmd, chroot string, cred *credential) error {
+	if chroot != "" {
+		return errors.New("exec chroot is only supported on unix")
+	}
+	if cred != nil {
+		return errors.New("exec user is only supported on unix")
+	}
+	return nil
+}
+
+// killProcessGroup kills the started command only, its children are left running.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
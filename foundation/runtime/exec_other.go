@@ -0,0 +1,10 @@
+//go:build !linux
+
+package runtime
+
+import "errors"
+
+// setLimits is only supported on linux.
+func setLimits(pid int, limits ExecLimits) error {
+	return errors.New("exec limits are only supported on linux")
+}
@@ -0,0 +1,32 @@
+//go:build unix
+
+package runtime
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup runs the command as the leader of its own process group, inside of chroot and as cred when they are
+// set, so the whole group goes once the context is done.
+func setProcessGroup(cmd *exec.Cmd, chroot string, cred *credential) error {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if chroot != "" {
+		cmd.SysProcAttr.Chroot = chroot
+	}
+
+	//the supplementary groups of the scheduler are dropped along with its user
+	if cred != nil {
+		cmd.SysProcAttr.Credential = &syscall.Credential{Uid: cred.uid, Gid: cred.gid, Groups: []uint32{}}
+	}
+
+	cmd.Cancel = func() error {
+		return killProcessGroup(cmd)
+	}
+	return nil
+}
+
+// killProcessGroup kills the process group of the started command.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,42 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// EnsureNetwork creates the bridge network with the name when it does not exist yet, an internal network has no
+// route to the outside.
+func (c *CLI) EnsureNetwork(ctx context.Context, name string, internal bool) error {
+	if err := exec.CommandContext(ctx, c.binary, "network", "inspect", name).Run(); err == nil {
+		return nil
+	}
+
+	args := []string{"network", "create", "--driver", "bridge"}
+	if internal {
+		args = append(args, "--internal")
+	}
+	args = append(args, name)
+
+	cmd := exec.CommandContext(ctx, c.binary, args...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		//another instance created it in between
+		if strings.Contains(stderr.String(), "already exists") {
+			return nil
+		}
+
+		if errors.Is(err, exec.ErrNotFound) || matchesInfraFailure(stderr.String()) {
+			return fmt.Errorf("%s network create %s:stderr:%s:%w: %w", c.binary, name, stderr.String(), ErrInfrastructure, err)
+		}
+		return fmt.Errorf("%s network create %s:stderr:%s:%w", c.binary, name, stderr.String(), err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+// Package runtime provides the container runtimes tasks are executed with, every runtime is driven through its
+// docker compatible command line so the scheduler can run on hosts without a docker daemon.
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrInfrastructure is wrapped by the runners when a command could not run because of the runtime itself, like an
+// unreachable daemon or registry, and not because of the command.
+var ErrInfrastructure = errors.New("container runtime infrastructure failure")
+
+// Runner represents a container runtime.
+type Runner interface {
+	//Name returns the name of the runtime like "docker".
+	Name() string
+	//RunCommand creates a container that runs the command once with stdin piped to it and returns its stdout.
+	RunCommand(ctx context.Context, image string, command string, runArgs []string, cmdArgs []string, stdin []byte) (string, error)
+	//RunSteps creates a container and executes the steps inside of it one after another, it stops at the first step
+	//that fails and returns the outputs of the steps that succeeded along with the error of the failed one.
+	RunSteps(ctx context.Context, image string, runArgs []string, steps []Step) ([]string, error)
+	//ExitCode returns the code the container of a failed run exited with, ok is false when the run failed without
+	//the container exiting, like when the runtime itself failed or the context killed it.
+	ExitCode(err error) (code int, ok bool)
+	//Ping checks that the runtime is reachable.
+	Ping(ctx context.Context) error
+	//Version returns the version and platform of the runtime.
+	Version(ctx context.Context) (Version, error)
+	//ImageDigest returns the repo digest of the local image, or its image id when it was only built locally.
+	ImageDigest(ctx context.Context, image string) (string, error)
+	//ResolveDigest returns the digest of the image, pulling it first when it is not available locally.
+	ResolveDigest(ctx context.Context, image string) (string, error)
+	//PullImage pulls the image from its registry even when it is available locally, progress is optional and is
+	//called with every line the runtime reports while pulling.
+	PullImage(ctx context.Context, image string, progress func(line string)) error
+	//EnsureNetwork creates the bridge network with the name when it does not exist yet, an internal network has no
+	//route to the outside.
+	EnsureNetwork(ctx context.Context, name string, internal bool) error
+}
+
+// Step represents a command executed by RunSteps.
+type Step struct {
+	Command string
+	Args    []string
+	//Stdin is piped to the command, it reads nothing when it is empty.
+	Stdin []byte
+}
+
+// Version represents the version and platform of a runtime.
+type Version struct {
+	Version string
+	OS      string
+	Arch    string
+}
+
+// Kinds are the names of the supported runtimes.
+var Kinds = []string{"docker", "podman", "containerd"}
+
+// New returns the runner of the runtime with the name, containerd is driven through nerdctl.
+func New(kind string) (Runner, error) {
+	switch kind {
+	case "docker":
+		return NewDocker(), nil
+	case "podman":
+		return NewPodman(), nil
+	case "containerd":
+		return NewNerdctl(), nil
+	}
+	return nil, fmt.Errorf("unknown runtime %q, must be one of %v", kind, Kinds)
+}
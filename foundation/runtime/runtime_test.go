@@ -0,0 +1,518 @@
+package runtime_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	goruntime "runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/foundation/runtime"
+)
+
+func TestRunCommandInfrastructureFailure(t *testing.T) {
+	tests := map[string]struct {
+		kind     string
+		script   string
+		infra    bool
+		exitCode int
+		exited   bool
+	}{
+		"daemon unreachable": {
+			script: "echo 'docker: Cannot connect to the Docker daemon at unix:///var/run/docker.sock.' >&2; exit 125",
+			infra:  true,
+		},
+		"registry down": {
+			script: "echo 'docker: Error response from daemon: Get \"https://registry-1.docker.io/v2/\": dial tcp: i/o timeout.' >&2; exit 125",
+			infra:  true,
+		},
+		"invalid image": {
+			script: "echo 'docker: invalid reference format.' >&2; exit 125",
+			infra:  false,
+		},
+		"command failed": {
+			script:   "echo 'ls: cannot access' >&2; exit 2",
+			infra:    false,
+			exitCode: 2,
+			exited:   true,
+		},
+		"podman socket unreachable": {
+			kind:   "podman",
+			script: "echo 'Error: unable to connect to Podman socket: dial unix /run/podman/podman.sock: connect: no such file or directory' >&2; exit 125",
+			infra:  true,
+		},
+		"containerd unreachable": {
+			kind:   "containerd",
+			script: "echo 'FATA[0000] cannot access containerd socket /run/containerd/containerd.sock' >&2; exit 1",
+			infra:  true,
+		},
+		"containerd unreachable without a terminal": {
+			kind:   "containerd",
+			script: "echo 'time=\"2024-09-01T10:00:00Z\" level=fatal msg=\"failed to dial \\\"/run/containerd/containerd.sock\\\": connection refused\"' >&2; exit 1",
+			infra:  true,
+		},
+		"containerd command reports an outage": {
+			kind:     "containerd",
+			script:   "echo 'curl: (7) Failed to connect to api port 443: Connection refused' >&2; echo 'nslookup: no such host' >&2; exit 1",
+			infra:    false,
+			exitCode: 1,
+			exited:   true,
+		},
+		"containerd command failed": {
+			kind:     "containerd",
+			script:   "echo 'ls: cannot access' >&2; exit 1",
+			infra:    false,
+			exitCode: 1,
+			exited:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			kind := test.kind
+			if kind == "" {
+				kind = "docker"
+			}
+
+			runner, err := runtime.New(kind)
+			if err != nil {
+				t.Fatalf("expected to create the runner: %s", err)
+			}
+
+			//the fake stands in for every binary so the runner picks its own
+			dir := t.TempDir()
+			fake := "#!/bin/sh\n" + test.script + "\n"
+			for _, binary := range []string{"docker", "podman", "nerdctl"} {
+				if err := os.WriteFile(filepath.Join(dir, binary), []byte(fake), 0o755); err != nil {
+					t.Fatalf("expected to write fake %s: %s", binary, err)
+				}
+			}
+			t.Setenv("PATH", dir)
+
+			_, err = runner.RunCommand(context.Background(), "alpine", "ls", nil, nil, nil)
+			if err == nil {
+				t.Fatal("expected the command to fail")
+			}
+
+			if got := errors.Is(err, runtime.ErrInfrastructure); got != test.infra {
+				t.Errorf("infra= %t, got %t: %s", test.infra, got, err)
+			}
+
+			code, exited := runner.ExitCode(err)
+			if exited != test.exited || code != test.exitCode {
+				t.Errorf("exitCode= %d/%t, got %d/%t", test.exitCode, test.exited, code, exited)
+			}
+		})
+	}
+
+	t.Run("unknown runtime", func(t *testing.T) {
+		if _, err := runtime.New("lxc"); err == nil {
+			t.Error("expected to not create a runner of an unknown runtime")
+		}
+	})
+
+	t.Run("docker missing", func(t *testing.T) {
+		t.Setenv("PATH", t.TempDir())
+
+		_, err := runtime.NewDocker().RunCommand(context.Background(), "alpine", "ls", nil, nil, nil)
+		if !errors.Is(err, runtime.ErrInfrastructure) {
+			t.Errorf("expected an infrastructure failure, got %v", err)
+		}
+	})
+}
+
+func TestRunCommandStdin(t *testing.T) {
+	//the fake echoes its stdin back only when it is asked to keep it open
+	dir := t.TempDir()
+	fake := `#!/bin/sh
+[ "$3" = "--interactive" ] && cat
+exit 0
+`
+	if err := os.WriteFile(filepath.Join(dir, "docker"), []byte(fake), 0o755); err != nil {
+		t.Fatalf("expected to write fake docker: %s", err)
+	}
+	t.Setenv("PATH", dir+":/bin:/usr/bin")
+
+	input := []byte{0x00, 0x01, 'h', 'i', 0xff}
+	output, err := runtime.NewDocker().RunCommand(context.Background(), "alpine", "cat", nil, nil, input)
+	if err != nil {
+		t.Fatalf("expected to run the command: %s", err)
+	}
+
+	if output != string(input) {
+		t.Errorf("output= %q, got %q", input, output)
+	}
+}
+
+func TestResolveDigest(t *testing.T) {
+	//the fake only knows the image after it has been pulled
+	dir := t.TempDir()
+	fake := `#!/bin/sh
+case "$1" in
+image)
+	[ -f "` + dir + `/pulled" ] || { echo 'Error: No such image: alpine:3.20' >&2; exit 1; }
+	echo '["alpine@sha256:abc"] sha256:def'
+	;;
+pull)
+	touch "` + dir + `/pulled"
+	;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(dir, "docker"), []byte(fake), 0o755); err != nil {
+		t.Fatalf("expected to write fake docker: %s", err)
+	}
+	t.Setenv("PATH", dir+":/bin:/usr/bin")
+
+	digest, err := runtime.NewDocker().ResolveDigest(context.Background(), "alpine:3.20")
+	if err != nil {
+		t.Fatalf("expected to resolve digest: %s", err)
+	}
+
+	if digest != "alpine@sha256:abc" {
+		t.Errorf("digest= %s, got %s", "alpine@sha256:abc", digest)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "pulled")); err != nil {
+		t.Errorf("expected the image to be pulled: %s", err)
+	}
+}
+
+func TestPullImage(t *testing.T) {
+	//the fake reports the progress like docker does without a terminal
+	dir := t.TempDir()
+	fake := `#!/bin/sh
+[ "$2" = "alpine:missing" ] && { echo 'Error response from daemon: manifest for alpine:missing not found: manifest unknown' >&2; exit 1; }
+echo '3.20: Pulling from library/alpine'
+echo 'c6a83fedfae6: Pull complete'
+echo 'Status: Downloaded newer image for alpine:3.20'
+`
+	if err := os.WriteFile(filepath.Join(dir, "docker"), []byte(fake), 0o755); err != nil {
+		t.Fatalf("expected to write fake docker: %s", err)
+	}
+	t.Setenv("PATH", dir+":/bin:/usr/bin")
+
+	runner := runtime.NewDocker()
+
+	var lines []string
+	if err := runner.PullImage(context.Background(), "alpine:3.20", func(line string) { lines = append(lines, line) }); err != nil {
+		t.Fatalf("expected to pull the image: %s", err)
+	}
+
+	if len(lines) != 3 || lines[2] != "Status: Downloaded newer image for alpine:3.20" {
+		t.Errorf("expected every line of the progress, got %q", lines)
+	}
+
+	err := runner.PullImage(context.Background(), "alpine:missing", nil)
+	if err == nil {
+		t.Fatal("expected the pull of a missing image to fail")
+	}
+
+	if errors.Is(err, runtime.ErrInfrastructure) {
+		t.Errorf("expected a missing image to not be an infrastructure failure: %s", err)
+	}
+}
+
+func TestRunSteps(t *testing.T) {
+	//the fake runs the steps on the host
+	dir := t.TempDir()
+	fake := `#!/bin/sh
+case "$1" in
+run)
+	echo 'c0ffee'
+	;;
+exec)
+	shift 2
+	exec "$@"
+	;;
+rm)
+	touch "` + dir + `/removed"
+	;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(dir, "docker"), []byte(fake), 0o755); err != nil {
+		t.Fatalf("expected to write fake docker: %s", err)
+	}
+	t.Setenv("PATH", dir+":/bin:/usr/bin")
+
+	steps := []runtime.Step{
+		{Command: "echo", Args: []string{"first"}},
+		{Command: "sh", Args: []string{"-c", "exit 3"}},
+		{Command: "echo", Args: []string{"never"}},
+	}
+
+	runner := runtime.NewDocker()
+	outputs, err := runner.RunSteps(context.Background(), "alpine", nil, steps)
+	if err == nil {
+		t.Fatal("expected the second step to fail")
+	}
+
+	if len(outputs) != 1 || outputs[0] != "first\n" {
+		t.Errorf("outputs= %q, got %q", []string{"first\n"}, outputs)
+	}
+
+	if code, ok := runner.ExitCode(err); !ok || code != 3 {
+		t.Errorf("exitCode= %d, got %d/%t: %s", 3, code, ok, err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "removed")); err != nil {
+		t.Errorf("expected the container to be removed: %s", err)
+	}
+}
+
+func TestWarmer(t *testing.T) {
+	//the fake records the arguments of every call
+	dir := t.TempDir()
+	fake := `#!/bin/sh
+echo "$@" >> "` + dir + `/calls"
+[ "$1" = run ] && echo 'c0ffee'
+exit 0
+`
+	if err := os.WriteFile(filepath.Join(dir, "docker"), []byte(fake), 0o755); err != nil {
+		t.Fatalf("expected to write fake docker: %s", err)
+	}
+	t.Setenv("PATH", dir+":/bin:/usr/bin")
+
+	var warmer runtime.Warmer = runtime.NewDocker()
+
+	id, err := warmer.StartIdle(context.Background(), "alpine", []string{"--network", "none"})
+	if err != nil {
+		t.Fatalf("expected to start the container: %s", err)
+	}
+
+	if id != "c0ffee" {
+		t.Errorf("id= %s, got %s", "c0ffee", id)
+	}
+
+	if _, err := warmer.Exec(context.Background(), id, []string{"GREETING=hi"}, "echo", []string{"$GREETING"}, nil); err != nil {
+		t.Fatalf("expected to exec into the container: %s", err)
+	}
+
+	if err := warmer.Remove(id); err != nil {
+		t.Fatalf("expected to remove the container: %s", err)
+	}
+
+	calls, err := os.ReadFile(filepath.Join(dir, "calls"))
+	if err != nil {
+		t.Fatalf("expected the calls to be recorded: %s", err)
+	}
+
+	//every container has a name of its own
+	got := regexp.MustCompile(`--name task-[0-9a-f-]{36} `).ReplaceAllString(string(calls), "--name task ")
+
+	expected := "run -d --rm --name task --entrypoint sleep --network none alpine 2147483647\n" +
+		"exec -e GREETING=hi c0ffee echo $GREETING\n" +
+		"rm -f c0ffee\n"
+	if got != expected {
+		t.Errorf("calls= %q, got %q", expected, got)
+	}
+}
+
+func TestRunCommandCanceled(t *testing.T) {
+	//the fake keeps running like a container would until it is removed by its name
+	dir := t.TempDir()
+	fake := `#!/bin/sh
+echo "$@" >> "` + dir + `/calls"
+[ "$1" = run ] && exec sleep 30
+exit 0
+`
+	if err := os.WriteFile(filepath.Join(dir, "docker"), []byte(fake), 0o755); err != nil {
+		t.Fatalf("expected to write fake docker: %s", err)
+	}
+	t.Setenv("PATH", dir+":/bin:/usr/bin")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*200)
+	defer cancel()
+
+	_, err := runtime.NewDocker().RunCommand(ctx, "alpine", "sleep", nil, []string{"60"}, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err= %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	if _, exited := runtime.NewDocker().ExitCode(err); exited {
+		t.Error("expected a killed run to not report an exit code")
+	}
+
+	calls, err := os.ReadFile(filepath.Join(dir, "calls"))
+	if err != nil {
+		t.Fatalf("expected the calls to be recorded: %s", err)
+	}
+
+	name := regexp.MustCompile(`--name (task-[0-9a-f-]{36}) `).FindStringSubmatch(string(calls))
+	if name == nil {
+		t.Fatalf("expected the container to be named, got %q", calls)
+	}
+
+	if !strings.Contains(string(calls), "rm -f "+name[1]+"\n") {
+		t.Errorf("expected container %s to be removed, got %q", name[1], calls)
+	}
+}
+
+func TestEnsureNetwork(t *testing.T) {
+	//the fake records the arguments the network is created with
+	dir := t.TempDir()
+	fake := `#!/bin/sh
+case "$2" in
+inspect)
+	[ -f "` + dir + `/created" ] || { echo 'Error: No such network: tasks-internal' >&2; exit 1; }
+	;;
+create)
+	echo "$@" > "` + dir + `/created"
+	;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(dir, "docker"), []byte(fake), 0o755); err != nil {
+		t.Fatalf("expected to write fake docker: %s", err)
+	}
+	t.Setenv("PATH", dir+":/bin:/usr/bin")
+
+	runner := runtime.NewDocker()
+	if err := runner.EnsureNetwork(context.Background(), "tasks-internal", true); err != nil {
+		t.Fatalf("expected to create the network: %s", err)
+	}
+
+	created, err := os.ReadFile(filepath.Join(dir, "created"))
+	if err != nil {
+		t.Fatalf("expected the network to be created: %s", err)
+	}
+
+	expected := "network create --driver bridge --internal tasks-internal\n"
+	if string(created) != expected {
+		t.Errorf("args= %q, got %q", expected, created)
+	}
+
+	//an existing network is left alone
+	if err := runner.EnsureNetwork(context.Background(), "tasks-internal", true); err != nil {
+		t.Errorf("expected to find the network: %s", err)
+	}
+}
+
+func TestExec(t *testing.T) {
+	runner, err := runtime.NewExec(runtime.ExecConfig{
+		Allowed: []string{"sh", "echo"},
+		WorkDir: t.TempDir(),
+		Limits:  runtime.ExecLimits{OpenFiles: 64},
+	})
+	if err != nil {
+		t.Fatalf("expected to create the exec runner: %s", err)
+	}
+
+	output, err := runner.RunCommand(context.Background(), "ignored", "sh", []string{"--network", "none", "-e", "GREETING=hi"}, []string{"-c", "echo $GREETING $(ulimit -n)"}, nil)
+	if err != nil {
+		t.Fatalf("expected to run the command: %s", err)
+	}
+
+	if output != "hi 64\n" {
+		t.Errorf("output= %q, got %q", "hi 64\n", output)
+	}
+
+	//steps share the working directory
+	steps := []runtime.Step{
+		{Command: "sh", Args: []string{"-c", "echo first > out"}},
+		{Command: "sh", Args: []string{"-c", "cat out; exit 4"}},
+	}
+
+	outputs, err := runner.RunSteps(context.Background(), "ignored", nil, steps)
+	if err == nil {
+		t.Fatal("expected the second step to fail")
+	}
+
+	if len(outputs) != 1 {
+		t.Errorf("len(outputs)= %d, got %d", 1, len(outputs))
+	}
+
+	if code, ok := runner.ExitCode(err); !ok || code != 4 {
+		t.Errorf("exitCode= %d, got %d/%t: %s", 4, code, ok, err)
+	}
+
+	if _, err := runner.RunCommand(context.Background(), "ignored", "rm", nil, []string{"-rf", "/"}, nil); !errors.Is(err, runtime.ErrCommandNotAllowed) {
+		t.Errorf("err= %v, got %v", runtime.ErrCommandNotAllowed, err)
+	}
+
+	for _, kv := range []string{"PATH=/tmp", "LD_PRELOAD=/tmp/evil.so", "BASH_ENV=/tmp/rc", "ENV=/tmp/rc"} {
+		if _, err := runner.RunCommand(context.Background(), "ignored", "sh", []string{"-e", kv}, []string{"-c", "true"}, nil); !errors.Is(err, runtime.ErrEnvNotAllowed) {
+			t.Errorf("%s: err= %v, got %v", kv, runtime.ErrEnvNotAllowed, err)
+		}
+	}
+
+	if _, err := runtime.NewExec(runtime.ExecConfig{Allowed: []string{"/bin/sh"}}); err == nil {
+		t.Error("expected to not allow a command by its path")
+	}
+
+	//commands inside of a chroot would run as root without a user
+	for _, user := range []string{"", "0:0", "1000:0", "1000", "nobody:nogroup"} {
+		if _, err := runtime.NewExec(runtime.ExecConfig{Allowed: []string{"sh"}, Chroot: "/srv/jail", User: user}); err == nil {
+			t.Errorf("expected to not create a chroot runner with user %q", user)
+		}
+	}
+
+	if _, err := runtime.NewExec(runtime.ExecConfig{Allowed: []string{"sh"}, Chroot: "/srv/jail", User: "65534:65534"}); err != nil {
+		t.Errorf("expected to create a chroot runner with an unprivileged user: %s", err)
+	}
+}
+
+func TestExecUser(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("running commands as another user requires root")
+	}
+
+	//the user has to reach its working directory
+	workDir := t.TempDir()
+	for _, dir := range []string{filepath.Dir(workDir), workDir} {
+		if err := os.Chmod(dir, 0o755); err != nil {
+			t.Fatalf("expected to open up the workdir: %s", err)
+		}
+	}
+
+	runner, err := runtime.NewExec(runtime.ExecConfig{
+		Allowed: []string{"sh"},
+		WorkDir: workDir,
+		User:    "65534:65534",
+	})
+	if err != nil {
+		t.Fatalf("expected to create the exec runner: %s", err)
+	}
+
+	//the working directory belongs to the user so it can write into it
+	output, err := runner.RunCommand(context.Background(), "ignored", "sh", nil, []string{"-c", "touch out && echo $(id -u):$(id -g)"}, nil)
+	if err != nil {
+		t.Fatalf("expected to run the command: %s", err)
+	}
+
+	if got := strings.TrimSpace(output); got != "65534:65534" {
+		t.Errorf("user= %s, got %s", "65534:65534", got)
+	}
+}
+
+func TestExecUsage(t *testing.T) {
+	runner, err := runtime.NewExec(runtime.ExecConfig{
+		Allowed: []string{"sh"},
+		WorkDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("expected to create the exec runner: %s", err)
+	}
+
+	ctx, usage := runtime.WithUsage(context.Background())
+
+	//both steps are counted, the peak is the one of the biggest
+	steps := []runtime.Step{
+		{Command: "sh", Args: []string{"-c", "i=0; while [ $i -lt 20000 ]; do i=$((i+1)); done"}},
+		{Command: "sh", Args: []string{"-c", "true"}},
+	}
+
+	if _, err := runner.RunSteps(ctx, "ignored", nil, steps); err != nil {
+		t.Fatalf("expected to run the steps: %s", err)
+	}
+
+	if usage.CPU() <= 0 {
+		t.Errorf("expected the cpu time of the steps to be counted, got %s", usage.CPU())
+	}
+
+	if goruntime.GOOS == "linux" && usage.MemoryPeak() <= 0 {
+		t.Errorf("expected the memory peak of the steps to be counted, got %d", usage.MemoryPeak())
+	}
+}
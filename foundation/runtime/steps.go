@@ -0,0 +1,114 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Warmer is implemented by the runners that can keep a container running and execute commands inside of it later.
+type Warmer interface {
+	//StartIdle creates a container that runs nothing but "sleep", so the image must provide it, and returns its id.
+	StartIdle(ctx context.Context, image string, runArgs []string) (string, error)
+	//Exec executes the command inside of the container with the environment ("KEY=value") and stdin piped to it, it
+	//returns the stdout of the command.
+	Exec(ctx context.Context, id string, env []string, command string, args []string, stdin []byte) (string, error)
+	//Remove removes the container even when it is still running.
+	Remove(id string) error
+}
+
+// RunSteps creates a container and executes the steps inside of it one after another, it stops at the first step
+// that fails and returns the outputs of the steps that succeeded along with the error of the failed one. The
+// container is kept alive with "sleep" between the steps so the image must provide it.
+func (c *CLI) RunSteps(ctx context.Context, image string, runArgs []string, steps []Step) ([]string, error) {
+	id, err := c.StartIdle(ctx, image, runArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	//the ctx may be canceled already, the container must go anyway
+	defer c.Remove(id)
+	defer c.sampleUsage(ctx, id)()
+
+	outputs := make([]string, 0, len(steps))
+	for i, step := range steps {
+		output, err := c.Exec(ctx, id, nil, step.Command, step.Args, step.Stdin)
+		if err != nil {
+			return outputs, fmt.Errorf("step %d: %w", i+1, err)
+		}
+		outputs = append(outputs, output)
+	}
+
+	return outputs, nil
+}
+
+// StartIdle creates a container that runs nothing but "sleep" and returns its id, it is removed once it stops.
+func (c *CLI) StartIdle(ctx context.Context, image string, runArgs []string) (string, error) {
+	name := containerName()
+	args := []string{"run", "-d", "--rm", "--name", name, "--entrypoint", "sleep"}
+	args = append(args, runArgs...)
+	args = append(args, image, "2147483647")
+
+	cmd := c.command(ctx, name, args)
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if c.isInfraFailure(err, stderr.String()) {
+			return "", fmt.Errorf("start container failed:stderr:%s:%w: %w", stderr.String(), ErrInfrastructure, err)
+		}
+		return "", fmt.Errorf("start container failed:stderr:%s:%w", stderr.String(), err)
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// Exec executes the command inside of the container with the environment and stdin piped to it, it returns the stdout
+// of the command.
+func (c *CLI) Exec(ctx context.Context, id string, env []string, command string, cmdArgs []string, stdin []byte) (string, error) {
+	args := []string{"exec"}
+	if len(stdin) > 0 {
+		args = append(args, "--interactive")
+	}
+	for _, v := range env {
+		args = append(args, "-e", v)
+	}
+	args = append(args, id, command)
+	args = append(args, cmdArgs...)
+
+	cmd := exec.CommandContext(ctx, c.binary, args...)
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		//the command keeps running inside of the container, the container is removed by whoever owns it
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", fmt.Errorf("command execution failed:stderr:%s:%w: %w", stderr.String(), ctxErr, err)
+		}
+		if c.isInfraFailure(err, stderr.String()) {
+			return "", fmt.Errorf("command execution failed:stderr:%s:%w: %w", stderr.String(), ErrInfrastructure, err)
+		}
+		return "", fmt.Errorf("command execution failed:stderr:%s:%w", stderr.String(), err)
+	}
+
+	return stdout.String(), nil
+}
+
+// Remove removes the container even when it is still running.
+func (c *CLI) Remove(id string) error {
+	if err := exec.Command(c.binary, "rm", "-f", id).Run(); err != nil {
+		return fmt.Errorf("%s rm %s: %w", c.binary, id, err)
+	}
+	return nil
+}
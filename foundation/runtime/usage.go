@@ -0,0 +1,133 @@
+package runtime
+
+import (
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// usageInterval is how often the cgroup of a container is sampled while it runs, the cpu time used after the last
+// sample is not counted.
+const usageInterval = time.Millisecond * 500
+
+// Usage collects the resources consumed by the runs of a context returned by WithUsage, the resources a runtime can
+// not measure stay zero.
+type Usage struct {
+	mu         sync.Mutex
+	cpu        time.Duration
+	memoryPeak int64
+}
+
+type usageKey struct{}
+
+// WithUsage returns a context whose runs report the resources they consumed into the returned usage.
+func WithUsage(ctx context.Context) (context.Context, *Usage) {
+	u := &Usage{}
+	return context.WithValue(ctx, usageKey{}, u), u
+}
+
+// CPU returns the cpu time consumed by all of the runs.
+func (u *Usage) CPU() time.Duration {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.cpu
+}
+
+// MemoryPeak returns the highest memory usage of a single run in bytes.
+func (u *Usage) MemoryPeak() int64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.memoryPeak
+}
+
+func (u *Usage) add(cpu time.Duration, memoryPeak int64) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.cpu += cpu
+	u.memoryPeak = max(u.memoryPeak, memoryPeak)
+}
+
+func usageOf(ctx context.Context) *Usage {
+	u, _ := ctx.Value(usageKey{}).(*Usage)
+	return u
+}
+
+// addProcessUsage reports the cpu time and the max resident set size of the command once it exited.
+func addProcessUsage(ctx context.Context, cmd *exec.Cmd) {
+	u := usageOf(ctx)
+	if u == nil || cmd.ProcessState == nil {
+		return
+	}
+	u.add(cmd.ProcessState.UserTime()+cmd.ProcessState.SystemTime(), maxRSS(cmd.ProcessState))
+}
+
+// sampleUsage samples the cgroup of the container until the returned function is called, which reports the last
+// sample. Nothing is sampled when the context does not collect usage or the cgroup of the container can not be read,
+// like when the runtime runs on another host.
+func (c *CLI) sampleUsage(ctx context.Context, container string) func() {
+	u := usageOf(ctx)
+	if u == nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+
+		ticker := time.NewTicker(usageInterval)
+		defer ticker.Stop()
+
+		var cgroup string
+		var cpu time.Duration
+		var memoryPeak int64
+
+		for {
+			select {
+			case <-done:
+				u.add(cpu, memoryPeak)
+				return
+			case <-ticker.C:
+			}
+
+			//the container does not exist while its image is pulled
+			if cgroup == "" {
+				cgroup = c.cgroupOf(ctx, container)
+				if cgroup == "" {
+					continue
+				}
+			}
+
+			//the cgroup is gone once the container stopped
+			sampledCPU, sampledMemory, err := readCgroup(cgroup)
+			if err != nil {
+				continue
+			}
+			cpu = sampledCPU
+			memoryPeak = max(memoryPeak, sampledMemory)
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// cgroupOf returns the cgroup directory of the running container, empty when it can not be found.
+func (c *CLI) cgroupOf(ctx context.Context, container string) string {
+	output, err := exec.CommandContext(ctx, c.binary, "inspect", "--format", "{{.State.Pid}}", container).Output()
+	if err != nil {
+		return ""
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(output)))
+	if err != nil || pid <= 0 {
+		return ""
+	}
+	return cgroupOfPid(pid)
+}
@@ -0,0 +1,77 @@
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// cgroupRoot is where the unified cgroup hierarchy is mounted.
+const cgroupRoot = "/sys/fs/cgroup"
+
+// maxRSS returns the max resident set size of the exited process in bytes.
+func maxRSS(state *os.ProcessState) int64 {
+	rusage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return 0
+	}
+	//linux reports it in kilobytes
+	return rusage.Maxrss << 10
+}
+
+// cgroupOfPid returns the directory of the cgroup v2 of the process, empty when the process is not visible or the
+// host runs cgroup v1.
+func cgroupOfPid(pid int) string {
+	bs, err := os.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return ""
+	}
+
+	for _, line := range strings.Split(string(bs), "\n") {
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			return filepath.Join(cgroupRoot, path)
+		}
+	}
+	return ""
+}
+
+// readCgroup returns the cpu time used by the cgroup and its memory peak, or its current memory usage on kernels
+// that do not track the peak.
+func readCgroup(dir string) (time.Duration, int64, error) {
+	stat, err := os.ReadFile(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return 0, 0, fmt.Errorf("read cpu.stat: %w", err)
+	}
+
+	var cpu time.Duration
+	scanner := bufio.NewScanner(bytes.NewReader(stat))
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "usage_usec "); ok {
+			usec, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return 0, 0, fmt.Errorf("parse usage_usec: %w", err)
+			}
+			cpu = time.Duration(usec) * time.Microsecond
+		}
+	}
+
+	memory, err := os.ReadFile(filepath.Join(dir, "memory.peak"))
+	if err != nil {
+		memory, err = os.ReadFile(filepath.Join(dir, "memory.current"))
+		if err != nil {
+			return 0, 0, fmt.Errorf("read memory.current: %w", err)
+		}
+	}
+
+	peak, err := strconv.ParseInt(strings.TrimSpace(string(memory)), 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("parse memory: %w", err)
+	}
+	return cpu, peak, nil
+}
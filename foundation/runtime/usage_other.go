@@ -0,0 +1,24 @@
+//go:build !linux
+
+package runtime
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// maxRSS is only reported on linux.
+func maxRSS(state *os.ProcessState) int64 {
+	return 0
+}
+
+// cgroupOfPid is only supported on linux.
+func cgroupOfPid(pid int) string {
+	return ""
+}
+
+// readCgroup is only supported on linux.
+func readCgroup(dir string) (time.Duration, int64, error) {
+	return 0, 0, errors.New("cgroups are only supported on linux")
+}
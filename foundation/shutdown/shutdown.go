@@ -0,0 +1,40 @@
+// Package shutdown stops the parts of a service one after another within a single deadline, so a part is only
+// stopped once nothing that depends on it is running anymore.
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Phase represents a part of the service that is stopped.
+type Phase struct {
+	Name string
+	//Stop stops the part, it should give up once the context is done.
+	Stop func(ctx context.Context) error
+}
+
+// Run stops the phases in order. A phase that fails or runs out of time does not keep the ones after it from running,
+// their connections still have to be closed, the errors of all of them are returned together. Phases that are left
+// once the deadline passed still run with a context that is already done.
+func Run(ctx context.Context, logger *slog.Logger, phases ...Phase) error {
+	var errs []error
+
+	for _, phase := range phases {
+		start := time.Now()
+		logger.Info("shutdown", "phase", phase.Name, "status", "started")
+
+		if err := phase.Stop(ctx); err != nil {
+			logger.Error("shutdown", "phase", phase.Name, "status", "failed", "took", time.Since(start), "msg", err)
+			errs = append(errs, fmt.Errorf("%s: %w", phase.Name, err))
+			continue
+		}
+
+		logger.Info("shutdown", "phase", phase.Name, "status", "completed", "took", time.Since(start))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,82 @@
+package shutdown_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/foundation/shutdown"
+)
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	errHTTP := errors.New("http server did not drain")
+
+	var order []string
+	phase := func(name string, err error) shutdown.Phase {
+		return shutdown.Phase{
+			Name: name,
+			Stop: func(ctx context.Context) error {
+				order = append(order, name)
+				return err
+			},
+		}
+	}
+
+	err := shutdown.Run(context.Background(), logger,
+		phase("http", errHTTP),
+		phase("scheduler", nil),
+		phase("database", nil),
+	)
+
+	//the connections are closed even though the server did not drain
+	want := []string{"http", "scheduler", "database"}
+	if !slices.Equal(order, want) {
+		t.Errorf("order= %v, got %v", want, order)
+	}
+
+	if !errors.Is(err, errHTTP) {
+		t.Errorf("err= %v, got %v", errHTTP, err)
+	}
+}
+
+func TestRunDeadline(t *testing.T) {
+	t.Parallel()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	var closed bool
+	err := shutdown.Run(ctx, logger,
+		shutdown.Phase{
+			Name: "scheduler",
+			Stop: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		},
+		shutdown.Phase{
+			Name: "database",
+			Stop: func(ctx context.Context) error {
+				closed = true
+				return nil
+			},
+		},
+	)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err= %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	if !closed {
+		t.Error("expected the phases after the deadline to run")
+	}
+}
@@ -0,0 +1,80 @@
+// Package tlsconf selects how a server serves tls, either with certificate files or with certificates requested from
+// lets encrypt, and redirects its plain http listener to https.
+package tlsconf
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Config represents where the certificates of a server come from, a zero config serves plain http.
+type Config struct {
+	CertFile string
+	KeyFile  string
+	//AutocertDomains are requested from lets encrypt, they can not be combined with the certificate files.
+	AutocertDomains  []string
+	AutocertEmail    string
+	AutocertCacheDir string
+}
+
+// New returns the tls config of the server, nil when it serves plain http. challenges wraps the handler of the plain
+// http listener so it answers the http-01 challenges of lets encrypt, without autocert it returns the handler as is.
+func New(conf Config) (tlsConf *tls.Config, challenges func(http.Handler) http.Handler, err error) {
+	passthrough := func(h http.Handler) http.Handler { return h }
+
+	switch {
+	case conf.CertFile != "" || conf.KeyFile != "":
+		if len(conf.AutocertDomains) > 0 {
+			return nil, nil, errors.New("certificate files and autocert domains are mutually exclusive")
+		}
+
+		cert, err := tls.LoadX509KeyPair(conf.CertFile, conf.KeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("load key pair: %w", err)
+		}
+
+		return &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{cert},
+			NextProtos:   []string{"h2", "http/1.1"},
+		}, passthrough, nil
+
+	case len(conf.AutocertDomains) > 0:
+		m := autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(conf.AutocertDomains...),
+			Cache:      autocert.DirCache(conf.AutocertCacheDir),
+			Email:      conf.AutocertEmail,
+		}
+
+		//includes h2 and the alpn protocol of the tls-alpn-01 challenges
+		tlsConf := m.TLSConfig()
+		tlsConf.MinVersion = tls.VersionTLS12
+		return tlsConf, m.HTTPHandler, nil
+	}
+
+	return nil, passthrough, nil
+}
+
+// RedirectHTTPS redirects every request to the same url over https on the port of host, the host https is served on.
+func RedirectHTTPS(host string) http.Handler {
+	_, port, _ := net.SplitHostPort(host)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			target = h
+		}
+
+		if port != "" && port != "443" {
+			target = net.JoinHostPort(target, port)
+		}
+
+		http.Redirect(w, r, "https://"+target+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,175 @@
+package tlsconf_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hamidoujand/task-scheduler/foundation/tlsconf"
+)
+
+// writeKeyPair writes a self signed certificate and its key into dir.
+func writeKeyPair(t *testing.T, dir string) (certFile string, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("expected to generate a key: %s", err)
+	}
+
+	tmpl := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("expected to create a certificate: %s", err)
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("expected to marshal the key: %s", err)
+	}
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("expected to write the certificate: %s", err)
+	}
+
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatalf("expected to write the key: %s", err)
+	}
+	return certFile, keyFile
+}
+
+// fallback answers every request that is not a challenge.
+var fallback = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusTeapot)
+})
+
+func TestNew(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeKeyPair(t, dir)
+
+	//without certificates the server stays on plain http
+	conf, challenges, err := tlsconf.New(tlsconf.Config{})
+	if err != nil {
+		t.Fatalf("expected to select plain http: %s", err)
+	}
+
+	if conf != nil {
+		t.Errorf("expected no tls config, got %+v", conf)
+	}
+
+	w := httptest.NewRecorder()
+	challenges(fallback).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/.well-known/acme-challenge/token", nil))
+	if w.Code != http.StatusTeapot {
+		t.Errorf("status= %d, got %d", http.StatusTeapot, w.Code)
+	}
+
+	//static certificates are loaded from the files
+	conf, challenges, err = tlsconf.New(tlsconf.Config{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("expected to load the key pair: %s", err)
+	}
+
+	if len(conf.Certificates) != 1 || conf.MinVersion != tls.VersionTLS12 || !slices.Contains(conf.NextProtos, "h2") {
+		t.Errorf("expected a tls 1.2 config with the certificate and h2, got %d certificates, %d, %v", len(conf.Certificates), conf.MinVersion, conf.NextProtos)
+	}
+
+	w = httptest.NewRecorder()
+	challenges(fallback).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/.well-known/acme-challenge/token", nil))
+	if w.Code != http.StatusTeapot {
+		t.Errorf("status= %d, got %d", http.StatusTeapot, w.Code)
+	}
+
+	if _, _, err := tlsconf.New(tlsconf.Config{CertFile: filepath.Join(dir, "missing.pem"), KeyFile: keyFile}); err == nil {
+		t.Error("expected a missing certificate to fail")
+	}
+
+	if _, _, err := tlsconf.New(tlsconf.Config{CertFile: certFile, KeyFile: keyFile, AutocertDomains: []string{"example.com"}}); err == nil {
+		t.Error("expected certificate files and autocert domains to be mutually exclusive")
+	}
+
+	//autocert answers the tls-alpn-01 and http-01 challenges and leaves every other request to the handler
+	conf, challenges, err = tlsconf.New(tlsconf.Config{
+		AutocertDomains:  []string{"example.com"},
+		AutocertCacheDir: filepath.Join(dir, "autocert"),
+	})
+	if err != nil {
+		t.Fatalf("expected to select autocert: %s", err)
+	}
+
+	if conf.GetCertificate == nil || conf.MinVersion != tls.VersionTLS12 || !slices.Contains(conf.NextProtos, "acme-tls/1") {
+		t.Errorf("expected a tls 1.2 config that requests certificates, got %d, %v", conf.MinVersion, conf.NextProtos)
+	}
+
+	w = httptest.NewRecorder()
+	challenges(fallback).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://example.com/api/tasks", nil))
+	if w.Code != http.StatusTeapot {
+		t.Errorf("status= %d, got %d", http.StatusTeapot, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	challenges(fallback).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://example.com/.well-known/acme-challenge/token", nil))
+	if w.Code == http.StatusTeapot {
+		t.Error("expected the challenge to be answered by autocert")
+	}
+}
+
+func TestRedirectHTTPS(t *testing.T) {
+	tests := map[string]struct {
+		host     string
+		url      string
+		expected string
+	}{
+		"default port": {
+			host:     "0.0.0.0:443",
+			url:      "http://example.com/v1/api/tasks?rows=10",
+			expected: "https://example.com/v1/api/tasks?rows=10",
+		},
+		"custom port": {
+			host:     "0.0.0.0:8443",
+			url:      "http://example.com:8080/v1/readiness",
+			expected: "https://example.com:8443/v1/readiness",
+		},
+		"host without port": {
+			host:     "",
+			url:      "http://example.com:8080/",
+			expected: "https://example.com/",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tlsconf.RedirectHTTPS(test.host).ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.url, nil))
+
+			if w.Code != http.StatusMovedPermanently {
+				t.Errorf("status= %d, got %d", http.StatusMovedPermanently, w.Code)
+			}
+
+			if location := w.Header().Get("Location"); location != test.expected {
+				t.Errorf("location= %s, got %s", test.expected, location)
+			}
+		})
+	}
+}
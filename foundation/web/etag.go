@@ -0,0 +1,45 @@
+package web
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ETag returns an entity tag derived from parts, like the id and the version of a resource.
+func ETag(parts ...any) string {
+	sum := sha256.Sum256([]byte(fmt.Sprint(parts...)))
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// RespondETag is like Respond for a resource tagged with etag, when the If-None-Match header of the request already
+// holds the tag only 304 is sent.
+func RespondETag(ctx context.Context, w http.ResponseWriter, r *http.Request, statusCode int, etag string, data any) error {
+	w.Header().Set("ETag", etag)
+
+	if NotModified(r, etag) {
+		setStatusCode(ctx, http.StatusNotModified)
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+	return Respond(ctx, w, statusCode, data)
+}
+
+// NotModified reports whether the If-None-Match header of the request matches etag, tags are compared weakly.
+func NotModified(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
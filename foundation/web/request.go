@@ -0,0 +1,57 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Decode reads the json body of the request into v, unknown fields and anything after the value are rejected. The
+// returned error describes what is wrong with the body so it can be sent back to the client.
+func Decode(r *http.Request, v any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		return decodeErr(err)
+	}
+
+	//a body holds a single value
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		if err == nil {
+			return errors.New("body must only contain a single json value")
+		}
+		return decodeErr(err)
+	}
+	return nil
+}
+
+func decodeErr(err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &syntaxErr):
+		return fmt.Errorf("malformed json at offset %d", syntaxErr.Offset)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return errors.New("malformed json")
+	case errors.As(err, &typeErr):
+		if typeErr.Field != "" {
+			return fmt.Errorf("field %q must be of type %s", typeErr.Field, typeErr.Type)
+		}
+		return fmt.Errorf("json value at offset %d must be of type %s", typeErr.Offset, typeErr.Type)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		//the decoder has no typed error for unknown fields
+		return fmt.Errorf("unknown field %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
+	case errors.Is(err, io.EOF):
+		return errors.New("body must not be empty")
+	case errors.As(err, &maxBytesErr):
+		return fmt.Errorf("body must not be larger than %d bytes", maxBytesErr.Limit)
+	default:
+		return err
+	}
+}
@@ -9,6 +9,11 @@ import (
 )
 
 func Respond(ctx context.Context, w http.ResponseWriter, statusCode int, data any) error {
+	return RespondAs(ctx, w, statusCode, "application/json", data)
+}
+
+// RespondAs is like Respond but sends data with the given content type.
+func RespondAs(ctx context.Context, w http.ResponseWriter, statusCode int, contentType string, data any) error {
 	//if ctx is cancelled, that means client is disconnect
 	if err := ctx.Err(); err != nil {
 		if errors.Is(err, context.Canceled) {
@@ -24,7 +29,7 @@ func Respond(ctx context.Context, w http.ResponseWriter, statusCode int, data an
 		return nil
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", contentType)
 	w.WriteHeader(statusCode)
 	err := json.NewEncoder(w).Encode(data)
 	if err != nil {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,6 +19,8 @@ type App struct {
 	mux               *http.ServeMux
 	shutdown          chan<- os.Signal
 	globalMiddlewares []Middleware
+	basePath          string
+	host              string
 }
 
 // NewApp factory function that setup and return a *App value
@@ -29,6 +32,30 @@ func NewApp(shutdown chan<- os.Signal, mids ...Middleware) *App {
 	}
 }
 
+// EnableBasePath serves the routes registered after it under prefix, like "/scheduler", so the app can share a
+// gateway with other services.
+func (a *App) EnableBasePath(prefix string) {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		a.basePath = ""
+		return
+	}
+	a.basePath = "/" + prefix
+}
+
+// EnableHost only matches the routes registered after it for requests to host, requests to other hosts get 404.
+func (a *App) EnableHost(host string) {
+	a.host = host
+}
+
+// Path returns the path a route of version is served at.
+func (a *App) Path(version string, path string) string {
+	if version != "" {
+		path = "/" + version + path
+	}
+	return a.basePath + path
+}
+
 // HandleFunc is the custom version of *ServeMux.HandleFunc with extended features
 func (a *App) HandleFunc(method string, version string, path string, handler Handler, mids ...Middleware) {
 	//apply the middlewares before calling handler
@@ -52,13 +79,7 @@ func (a *App) HandleFunc(method string, version string, path string, handler Han
 			//TODO handle this
 		}
 	}
-	finalPath := path
-
-	if version != "" {
-		finalPath = "/" + version + path
-	}
-
-	finalPath = fmt.Sprintf("%s %s", method, finalPath)
+	finalPath := fmt.Sprintf("%s %s%s", method, a.host, a.Path(version, path))
 	//delegate it to default HandleFunc
 
 	a.mux.HandleFunc(finalPath, h)
@@ -0,0 +1,44 @@
+package web_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hamidoujand/task-scheduler/foundation/web"
+)
+
+func TestBasePathAndHost(t *testing.T) {
+	app := web.NewApp(nil)
+	app.EnableBasePath("/scheduler/")
+	app.EnableHost("api.example.com")
+
+	app.HandleFunc(http.MethodGet, "v1", "/api/tasks/", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(ctx, w, http.StatusOK, nil)
+	})
+
+	if path := app.Path("v1", "/api/tasks/"); path != "/scheduler/v1/api/tasks/" {
+		t.Errorf("path= %s, got %s", "/scheduler/v1/api/tasks/", path)
+	}
+
+	tests := map[string]struct {
+		url        string
+		statusCode int
+	}{
+		"under the base path": {url: "http://api.example.com/scheduler/v1/api/tasks/", statusCode: http.StatusOK},
+		"without base path":   {url: "http://api.example.com/v1/api/tasks/", statusCode: http.StatusNotFound},
+		"another host":        {url: "http://example.com/scheduler/v1/api/tasks/", statusCode: http.StatusNotFound},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.url, nil))
+
+			if w.Code != test.statusCode {
+				t.Errorf("status= %d, got %d", test.statusCode, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+// Package webhook provides a client that posts json payloads to an http endpoint.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Client represents a webhook endpoint.
+type Client struct {
+	url    string
+	client *http.Client
+}
+
+// New creates a client for the endpoint, requests time out after timeout which defaults to 10s.
+func New(url string, timeout time.Duration) (*Client, error) {
+	if url == "" {
+		return nil, errors.New("webhook url is required")
+	}
+
+	if timeout <= 0 {
+		timeout = time.Second * 10
+	}
+
+	return &Client{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Post sends the payload as json, any response other than 2xx is an error.
+func (c *Client) Post(ctx context.Context, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4*1024))
+		return fmt.Errorf("webhook responded with %d: %s", resp.StatusCode, msg)
+	}
+	return nil
+}
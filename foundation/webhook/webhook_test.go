@@ -0,0 +1,51 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hamidoujand/task-scheduler/foundation/webhook"
+)
+
+func TestPost(t *testing.T) {
+	t.Parallel()
+
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("contentType= %s, got %s", "application/json", r.Header.Get("Content-Type"))
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("expected a json body: %s", err)
+		}
+
+		if got["event"] == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	client, err := webhook.New(srv.URL, 0)
+	if err != nil {
+		t.Fatalf("expected to create the client: %s", err)
+	}
+
+	ctx := context.Background()
+	if err := client.Post(ctx, map[string]string{"event": "backlog"}); err != nil {
+		t.Fatalf("expected to post the payload: %s", err)
+	}
+
+	if got["event"] != "backlog" {
+		t.Errorf("event= %s, got %s", "backlog", got["event"])
+	}
+
+	if err := client.Post(ctx, map[string]string{"event": "fail"}); err == nil {
+		t.Error("expected an error when the endpoint fails")
+	}
+}